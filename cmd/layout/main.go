@@ -0,0 +1,100 @@
+// Package main the offline tool for migrating the tenants of a simple file storage from one layout into another,
+// e.g. from the legacy v1 layout into the v2 layout.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/factory"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+)
+
+var (
+	configFile string
+	rootPath   string
+	reportFile string
+	noSync     bool
+	opts       simplefile.LayoutOptions
+)
+
+func init() {
+	flag.StringVarP(&configFile, "config", "c", "", "the config file of the service, the root path of the simple file storage is taken from there")
+	flag.StringVarP(&rootPath, "root", "r", "", "the root path of the simple file storage, instead of the config file")
+	flag.StringVarP(&opts.From, "from", "f", simplefile.LayoutV1, fmt.Sprintf("the source layout, one of %s", strings.Join(simplefile.Layouts(), ", ")))
+	flag.StringVarP(&opts.To, "to", "t", simplefile.LayoutV2, fmt.Sprintf("the target layout, one of %s", strings.Join(simplefile.Layouts(), ", ")))
+	flag.StringSliceVar(&opts.Tenants, "tenant", nil, "the tenants to migrate, default all tenants")
+	flag.BoolVarP(&opts.DryRun, "dryrun", "d", false, "only checking, what would be migrated")
+	flag.BoolVar(&opts.Verify, "verify", true, "verifying the hash of every binary against the description")
+	flag.BoolVar(&noSync, "nosync", false, "don't sync the files to disk, faster but not crash safe")
+	flag.StringVarP(&reportFile, "report", "o", "", "file for the json report, default stdout")
+}
+
+func main() {
+	flag.Parse()
+	mig, err := migrator()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	mig.Sync = !noSync
+
+	// an interrupted migration can simply be started again
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "migrating %s from layout %s to %s, dry run: %t\n", mig.RootPath, opts.From, opts.To, opts.DryRun)
+	report, err := mig.Run(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	failed := 0
+	for _, t := range report.Tenants {
+		fmt.Fprintf(os.Stderr, "tenant %s: total %d, converted %d, skipped %d, failed %d, bytes %d\n", t.Tenant, t.Total, t.Converted, t.Skipped, t.Failed, t.Bytes)
+		failed += t.Failed
+	}
+	if err := writeReport(report); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing report: %v\n", err)
+		os.Exit(2)
+	}
+	if report.LastError != "" {
+		fmt.Fprintf(os.Stderr, "error: %s\n", report.LastError)
+		os.Exit(1)
+	}
+	if failed > 0 || report.Cancelled {
+		os.Exit(1)
+	}
+}
+
+func migrator() (*simplefile.LayoutMigrator, error) {
+	if rootPath != "" {
+		return &simplefile.LayoutMigrator{RootPath: rootPath}, nil
+	}
+	if configFile == "" {
+		return nil, fmt.Errorf("either the root path or the config file must be given")
+	}
+	config.File = configFile
+	if err := config.Load(); err != nil {
+		return nil, err
+	}
+	return factory.CreateLayoutMigrator(config.Get().Engine.Storage)
+}
+
+func writeReport(report simplefile.LayoutReport) error {
+	js, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if reportFile == "" {
+		fmt.Println(string(js))
+		return nil
+	}
+	return os.WriteFile(reportFile, js, 0644)
+}
@@ -11,6 +11,9 @@ const APIKeyHeaderKey = "apikey"
 // RetentionHeaderKey is the header for defining a retention time
 const RetentionHeaderKey = "retention"
 
+// RetentionModeHeaderKey is the header for defining the retention mode (creation or lastaccess)
+const RetentionModeHeaderKey = "retentionmode"
+
 // FilenameKey key for the headermapping for the file name
 const FilenameKey = "filename"
 
@@ -1,8 +1,11 @@
 package apiv1
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/willie68/GoBlobStore/internal/api"
 	services "github.com/willie68/GoBlobStore/internal/services"
@@ -11,6 +14,7 @@ import (
 	"github.com/go-chi/render"
 	"github.com/willie68/GoBlobStore/internal/serror"
 	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
 // AdminRoutes getting all routes for the admin endpoint
@@ -18,8 +22,41 @@ func AdminRoutes() (string, *chi.Mux) {
 	router := chi.NewRouter()
 	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/check", GetCheck)
 	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Post("/check", PostCheck)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/check/reports", GetCheckReports)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/check/reports/{id}", GetCheckReport)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Delete("/check/reports/{id}", DeleteCheckReport)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/check/reports/{id}/export", GetCheckReportExport)
 	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/restore", GetRestore)
 	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Post("/restore", PostRestore)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/audit", GetAudit)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/audit/export", GetAuditExport)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Post("/bulk", PostBulk)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/bulk", GetBulk)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Delete("/bulk", DeleteBulk)
+	router.With(api.RoleCheck([]api.Role{api.RoleTenantAdmin})).Get("/bulk/report", GetBulkReport)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/search", PostAdminSearch)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/scrubber", GetScrubber)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/volumes", GetVolumes)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/volumes/rebalance", PostVolumesRebalance)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/volumes/job", GetVolumesJob)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Delete("/volumes/job", DeleteVolumesJob)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/volumes/{name}/state", PostVolumeState)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/volumes/{name}/drain", PostVolumeDrain)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/layout", PostLayout)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/layout", GetLayout)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Delete("/layout", DeleteLayout)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/migration", PostStorageMigration)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/migration", GetStorageMigration)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Delete("/migration", DeleteStorageMigration)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/cache", GetCache)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Delete("/cache", DeleteCache)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/cache/entries", GetCacheEntries)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/cache/warm", PostCacheWarm)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Get("/cache/warm", GetCacheWarm)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Delete("/cache/warm", DeleteCacheWarm)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Delete("/cache/{id}", DeleteCacheBlob)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Post("/cache/{id}/pin", PostCacheBlobPin)
+	router.With(api.RoleCheck([]api.Role{api.RoleAdmin})).Delete("/cache/{id}/pin", DeleteCacheBlobPin)
 	return BaseURL + adminSubpath, router
 }
 
@@ -170,3 +207,228 @@ func GetRestore(response http.ResponseWriter, request *http.Request) {
 	render.Status(request, http.StatusCreated)
 	render.JSON(response, request, res)
 }
+
+// GetAudit getting the entries of the deletion audit journal of this tenant
+// @Summary getting the entries of the deletion audit journal of this tenant
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param from query int false "start of the time range in ms"
+// @Param to query int false "end of the time range in ms"
+// @Param reason query string false "only entries with this deletion reason"
+// @Param offset query int false "index of the first entry"
+// @Param limit query int false "maximum count of entries, default 1000"
+// @Success 200 {array} model.AuditEntry "list of audit entries as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/audit [get]
+func GetAudit(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	jnl, err := services.GetAuditJournal()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	from, to, reason, err := getAuditFilter(request)
+	if err != nil {
+		httputils.Err(response, request, err)
+		return
+	}
+	offset, err := httputils.QueryInt(request, "offset", 0)
+	if err != nil {
+		httputils.Err(response, request, err)
+		return
+	}
+	limit, err := httputils.QueryInt(request, "limit", 1000)
+	if err != nil {
+		httputils.Err(response, request, err)
+		return
+	}
+	if offset < 0 || limit < 0 {
+		httputils.Err(response, request, serror.BadRequest(nil, "wrong-param", "offset and limit must not be negative"))
+		return
+	}
+	entries := make([]model.AuditEntry, 0)
+	if limit == 0 {
+		render.JSON(response, request, entries)
+		return
+	}
+	var index int64
+	err = jnl.Query(tenant, from, to, func(e model.AuditEntry) bool {
+		if reason != "" && e.Reason != reason {
+			return true
+		}
+		if index >= offset {
+			entries = append(entries, e)
+		}
+		index++
+		return int64(len(entries)) < limit
+	})
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	render.JSON(response, request, entries)
+}
+
+// GetAuditExport exporting the full deletion audit journal of this tenant
+// @Summary exporting the full deletion audit journal of this tenant as json, ndjson or csv
+// @Tags configs
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param format query string false "json (default), ndjson or csv"
+// @Param from query int false "start of the time range in ms"
+// @Param to query int false "end of the time range in ms"
+// @Param reason query string false "only entries with this deletion reason"
+// @Success 200 {array} model.AuditEntry "list of audit entries"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/audit/export [get]
+func GetAuditExport(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	jnl, err := services.GetAuditJournal()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	from, to, reason, err := getAuditFilter(request)
+	if err != nil {
+		httputils.Err(response, request, err)
+		return
+	}
+	values := request.URL.Query()
+	format := values.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	// the body is written only after all headers and the status are set
+	var start func() error
+	var write func(e model.AuditEntry) error
+	var finish func() error
+	switch format {
+	case "json":
+		response.Header().Set("Content-Type", "application/json")
+		first := true
+		start = func() error {
+			_, err := response.Write([]byte("["))
+			return err
+		}
+		write = func(e model.AuditEntry) error {
+			js, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if !first {
+				_, _ = response.Write([]byte(","))
+			}
+			first = false
+			_, err = response.Write(js)
+			return err
+		}
+		finish = func() error {
+			_, err := response.Write([]byte("]"))
+			return err
+		}
+	case "ndjson":
+		response.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(response)
+		start = func() error { return nil }
+		write = func(e model.AuditEntry) error {
+			return enc.Encode(e)
+		}
+		finish = func() error { return nil }
+	case "csv":
+		response.Header().Set("Content-Type", "text/csv")
+		cw := csv.NewWriter(response)
+		start = func() error {
+			return cw.Write([]string{"timestamp", "tenantID", "blobID", "filename", "hash", "contentLength", "creationDate", "retention", "reason", "node"})
+		}
+		write = func(e model.AuditEntry) error {
+			return cw.Write([]string{
+				strconv.FormatInt(e.Timestamp, 10),
+				e.TenantID,
+				e.BlobID,
+				e.Filename,
+				e.Hash,
+				strconv.FormatInt(e.ContentLength, 10),
+				strconv.FormatInt(e.CreationDate, 10),
+				strconv.FormatInt(e.Retention, 10),
+				string(e.Reason),
+				e.Node,
+			})
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		httputils.Err(response, request, serror.BadRequest(nil, "wrong-format", "format must be json, ndjson or csv"))
+		return
+	}
+	response.Header().Set("Content-Disposition", "attachment; filename=audit_"+tenant+"."+format)
+	response.WriteHeader(http.StatusOK)
+	if err := start(); err != nil {
+		logger.Errorf("audit export: error starting export: %v", err)
+		return
+	}
+	err = jnl.Query(tenant, from, to, func(e model.AuditEntry) bool {
+		if reason != "" && e.Reason != reason {
+			return true
+		}
+		if err := write(e); err != nil {
+			logger.Errorf("audit export: error writing entry: %v", err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		logger.Errorf("audit export: error reading journal of tenant %s: %v", tenant, err)
+	}
+	if err := finish(); err != nil {
+		logger.Errorf("audit export: error finishing export: %v", err)
+	}
+}
+
+// getAuditFilter getting the time range and the reason of the query params, a wrong time is a bad request
+func getAuditFilter(request *http.Request) (int64, int64, model.DeletionReason, error) {
+	from, err := httputils.QueryInt(request, "from", 0)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	to, err := httputils.QueryInt(request, "to", 0)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return from, to, model.DeletionReason(request.URL.Query().Get("reason")), nil
+}
+
+// GetScrubber getting the status of the background scrubber
+// @Summary getting the status of the background scrubber, the actual cycle, the position and the counts of checked and unhealthy blobs
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Success 200 {object} model.ScrubberStatus "the status of the scrubber as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/scrubber [get]
+func GetScrubber(response http.ResponseWriter, request *http.Request) {
+	scrub, err := services.GetScrubber()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	render.JSON(response, request, scrub.Status())
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/willie68/GoBlobStore/internal/serror"
 	services "github.com/willie68/GoBlobStore/internal/services"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
 	"github.com/willie68/GoBlobStore/internal/utils/httputils"
 	"github.com/willie68/GoBlobStore/pkg/model"
 )
@@ -44,6 +45,8 @@ func BlobRoutes() (string, *chi.Mux) {
 func SearchRoutes() (string, *chi.Mux) {
 	router := chi.NewRouter()
 	router.With(api.RoleCheck([]api.Role{api.RoleObjectReader})).Post("/", SearchBlobs)
+	router.With(api.RoleCheck([]api.Role{api.RoleObjectReader})).Post("/_explain", PostSearchExplain)
+	router.With(api.RoleCheck([]api.Role{api.RoleObjectReader})).Post("/_validate", PostSearchValidate)
 	return BaseURL + searchSubpath, router
 }
 
@@ -60,6 +63,8 @@ func TenantStoresRoutes() (string, *chi.Mux) {
 	router.With(api.RoleCheck([]api.Role{api.RoleObjectAdmin}), api.TenantCheck()).Get(tenantURL("/{id}/check"), GetBlobCheck)
 	router.With(api.RoleCheck([]api.Role{api.RoleObjectAdmin}), api.TenantCheck()).Post(tenantURL("/{id}/check"), PostBlobCheck)
 	router.With(api.RoleCheck([]api.Role{api.RoleObjectReader}), api.TenantCheck()).Post(fmt.Sprintf("/{%s}%s", api.URLParamTenantID, searchSubpath), SearchBlobs)
+	router.With(api.RoleCheck([]api.Role{api.RoleObjectReader}), api.TenantCheck()).Post(fmt.Sprintf("/{%s}%s/_explain", api.URLParamTenantID, searchSubpath), PostSearchExplain)
+	router.With(api.RoleCheck([]api.Role{api.RoleObjectReader}), api.TenantCheck()).Post(fmt.Sprintf("/{%s}%s/_validate", api.URLParamTenantID, searchSubpath), PostSearchValidate)
 	return BaseURL + storesSubpath, router
 }
 
@@ -138,7 +143,12 @@ func GetBlob(response http.ResponseWriter, request *http.Request) {
 
 	response.WriteHeader(http.StatusOK)
 
-	err = storage.RetrieveBlob(idStr, response)
+	// bulk clients like exports can keep the blob out of the cache with Cache-Control: no-store
+	hint := interfaces.CacheDefault
+	if strings.Contains(strings.ToLower(request.Header.Get("Cache-Control")), "no-store") {
+		hint = interfaces.CacheBypass
+	}
+	err = utils.RetrieveBlob(storage, idStr, response, hint)
 
 	if err != nil {
 		httputils.Err(response, request, serror.InternalServerError(err))
@@ -302,9 +312,6 @@ func GetBlobs(response http.ResponseWriter, request *http.Request) {
 		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
 		return
 	}
-	url := request.URL
-	values := url.Query()
-
 	stgf, err := services.GetStorageFactory()
 	if err != nil {
 		httputils.Err(response, request, serror.InternalServerError(err))
@@ -317,13 +324,9 @@ func GetBlobs(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	offset := 0
-	if values.Get("offset") != "" {
-		offset, _ = strconv.Atoi(values.Get("offset"))
-	}
-	limit := 1000
-	if values.Get("limit") != "" {
-		limit, _ = strconv.Atoi(values.Get("limit"))
+	offset, limit, ok := paging(response, request)
+	if !ok {
+		return
 	}
 	blobs := make([]string, 0)
 	index := 0
@@ -391,6 +394,11 @@ func PostBlob(response http.ResponseWriter, request *http.Request) {
 
 	// retention given via headers
 	retentionHeader, retentionTime := getRetention(request.Header)
+	retentionMode, err := getRetentionMode(request.Header)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(nil, "wrong-retentionmode", err.Error()))
+		return
+	}
 
 	// blobID given via headers
 	blobID := getBlobID(request.Header)
@@ -405,6 +413,7 @@ func PostBlob(response http.ResponseWriter, request *http.Request) {
 		ContentLength: cntLength,
 		ContentType:   mimeType,
 		Retention:     retentionTime,
+		RetentionMode: retentionMode,
 		Filename:      filename,
 		Properties:    metadata,
 		CreationDate:  time.Now().UnixMilli(),
@@ -467,6 +476,19 @@ func getRetention(header http.Header) (string, int64) {
 	return retentionHeader, retentionTime
 }
 
+func getRetentionMode(header http.Header) (string, error) {
+	retentionModeHeader, ok := config.Get().HeaderMapping[api.RetentionModeHeaderKey]
+	if !ok {
+		return "", nil
+	}
+	mode := strings.ToLower(header.Get(retentionModeHeader))
+	switch mode {
+	case "", model.RetentionModeCreation, model.RetentionModeLastAccess:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown retention mode: %s", mode)
+}
+
 func getMetadata(header http.Header) map[string]any {
 	metadata := make(map[string]any)
 	headerPrefix, ok := config.Get().HeaderMapping[api.HeaderPrefixKey]
@@ -561,9 +583,6 @@ func SearchBlobs(response http.ResponseWriter, request *http.Request) {
 		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
 		return
 	}
-	url := request.URL
-	values := url.Query()
-
 	stgf, err := services.GetStorageFactory()
 	if err != nil {
 		httputils.Err(response, request, serror.InternalServerError(err))
@@ -576,13 +595,9 @@ func SearchBlobs(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	offset := 0
-	if values["offset"] != nil {
-		offset, _ = strconv.Atoi(values["offset"][0])
-	}
-	limit := 1000
-	if values["limit"] != nil {
-		limit, _ = strconv.Atoi(values["limit"][0])
+	offset, limit, ok := paging(response, request)
+	if !ok {
+		return
 	}
 	b, err := io.ReadAll(request.Body)
 	if err != nil {
@@ -707,3 +722,22 @@ func getTenantStore(tenant string) (interfaces.BlobStorage, error) {
 	}
 	return storage, nil
 }
+
+// paging getting the offset and the limit (default 1000) of a paged request, on errors the response is written
+func paging(response http.ResponseWriter, request *http.Request) (int, int, bool) {
+	offset, err := httputils.QueryInt(request, "offset", 0)
+	if err != nil {
+		httputils.Err(response, request, err)
+		return 0, 0, false
+	}
+	limit, err := httputils.QueryInt(request, "limit", 1000)
+	if err != nil {
+		httputils.Err(response, request, err)
+		return 0, 0, false
+	}
+	if offset < 0 || limit < 0 {
+		httputils.Err(response, request, serror.BadRequest(nil, "wrong-param", "offset and limit must not be negative"))
+		return 0, 0, false
+	}
+	return int(offset), int(limit), true
+}
@@ -0,0 +1,156 @@
+package apiv1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/willie68/GoBlobStore/internal/serror"
+	services "github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// PostBulk starting a bulk job on all blobs of the tenant matching a query
+// @Summary starting a bulk job, deleting, changing the retention or merging properties of all blobs of the tenant matching the query, throttled in the background
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param payload body model.BulkRequest true "the query and the operation"
+// @Success 201 {object} model.BulkJob "the status of the bulk job as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/bulk [post]
+func PostBulk(response http.ResponseWriter, request *http.Request) {
+	bo, _, ok := getBulkOperator(response, request)
+	if !ok {
+		return
+	}
+	var req model.BulkRequest
+	err := httputils.Decode(request, &req)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	if err := utils.CheckRate(req.Rate); err != nil {
+		httputils.Err(response, request, serror.BadRequest(err, "wrong-param", err.Error()))
+		return
+	}
+	status, err := bo.StartBulk(req)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.Status(request, http.StatusCreated)
+	render.JSON(response, request, status)
+}
+
+// GetBulk getting the status of the actual or last bulk job of the tenant
+// @Summary getting the status of the actual or last bulk job of the tenant
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {object} model.BulkJob "the status of the bulk job as json"
+// @Failure 404 {object} serror.Serr "no bulk job found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/bulk [get]
+func GetBulk(response http.ResponseWriter, request *http.Request) {
+	bo, tenant, ok := getBulkOperator(response, request)
+	if !ok {
+		return
+	}
+	status, ok := bo.GetBulk()
+	if !ok {
+		httputils.Err(response, request, serror.NotFound("bulk job", tenant))
+		return
+	}
+	render.JSON(response, request, status)
+}
+
+// DeleteBulk cancelling the running bulk job of the tenant
+// @Summary cancelling the running bulk job of the tenant, the blobs already processed stay changed
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {object} model.BulkJob "the status of the bulk job as json"
+// @Failure 400 {object} serror.Serr "no bulk job running"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/bulk [delete]
+func DeleteBulk(response http.ResponseWriter, request *http.Request) {
+	bo, _, ok := getBulkOperator(response, request)
+	if !ok {
+		return
+	}
+	status, err := bo.CancelBulk()
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.JSON(response, request, status)
+}
+
+// GetBulkReport getting the results of the single blobs of the actual or last bulk job of the tenant
+// @Summary getting the results of the already processed blobs of the actual or last bulk job of the tenant
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param offset query int false "index of the first result"
+// @Param limit query int false "maximum count of results, default 1000"
+// @Success 200 {array} model.BulkResult "list of results as json"
+// @Failure 404 {object} serror.Serr "no bulk job found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/bulk/report [get]
+func GetBulkReport(response http.ResponseWriter, request *http.Request) {
+	bo, tenant, ok := getBulkOperator(response, request)
+	if !ok {
+		return
+	}
+	offset, limit, ok := paging(response, request)
+	if !ok {
+		return
+	}
+	results, ok := bo.GetBulkReport()
+	if !ok {
+		httputils.Err(response, request, serror.NotFound("bulk job", tenant))
+		return
+	}
+	start := min(offset, len(results))
+	end := start + min(limit, len(results)-start)
+	render.JSON(response, request, results[start:end])
+}
+
+// getBulkOperator getting the storage of the tenant as bulk operator, on errors the response is written
+func getBulkOperator(response http.ResponseWriter, request *http.Request) (interfaces.BulkOperator, string, bool) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return nil, "", false
+	}
+	stgf, err := services.GetStorageFactory()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return nil, "", false
+	}
+	storage, err := stgf.GetStorage(tenant)
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return nil, "", false
+	}
+	bo, ok := storage.(interfaces.BulkOperator)
+	if !ok {
+		httputils.Err(response, request, serror.InternalServerError(errors.New("storage doesn't support bulk jobs")))
+		return nil, "", false
+	}
+	return bo, tenant, true
+}
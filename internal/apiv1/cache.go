@@ -0,0 +1,314 @@
+package apiv1
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/willie68/GoBlobStore/internal/serror"
+	services "github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/business"
+	"github.com/willie68/GoBlobStore/internal/services/factory"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// GetCache getting the statistics of all cache partitions
+// @Summary getting the statistics of all cache partitions, count, bytes, bytes in memory, hits, misses and evictions
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {array} model.CacheStats "the statistics of the partitions as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache [get]
+func GetCache(response http.ResponseWriter, request *http.Request) {
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	render.JSON(response, request, cm.GetCacheStats())
+}
+
+// DeleteCache removing all blobs of the tenant from the cache
+// @Summary removing all blobs of the tenant from the cache, the blobs in the storage are not affected
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {object} int "count of removed blobs"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache [delete]
+func DeleteCache(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	count, err := cm.ClearCache(tenant)
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	render.JSON(response, request, count)
+}
+
+// GetCacheEntries getting the blobs of the tenant in the cache
+// @Summary getting the blobs of the tenant in the cache, with order=hot the most used first, with order=old the least recently used first
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param order query string false "hot or old"
+// @Param limit query int false "maximum count of entries, default 100"
+// @Success 200 {array} model.CacheEntry "the entries as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache/entries [get]
+func GetCacheEntries(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	limit, err := httputils.QueryInt(request, "limit", 100)
+	if err != nil {
+		httputils.Err(response, request, err)
+		return
+	}
+	if limit < 0 {
+		httputils.Err(response, request, serror.BadRequest(nil, "wrong-param", "limit must not be negative"))
+		return
+	}
+	entries, err := cm.GetCacheEntries(tenant, request.URL.Query().Get("order"), int(limit))
+	if err != nil {
+		cacheErr(response, request, tenant, err)
+		return
+	}
+	render.JSON(response, request, entries)
+}
+
+// DeleteCacheBlob evicting a single blob of the tenant from the cache
+// @Summary evicting a single blob of the tenant from the cache, the blob in the storage is not affected
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param id path string true "Id of the blob"
+// @Success 200 "blob evicted"
+// @Failure 404 {object} serror.Serr "blob not in the cache"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache/{id} [delete]
+func DeleteCacheBlob(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	id := chi.URLParam(request, "id")
+	err = cm.EvictCacheBlob(tenant, id)
+	if err != nil {
+		cacheErr(response, request, id, err)
+		return
+	}
+	render.JSON(response, request, id)
+}
+
+// PostCacheBlobPin pinning a blob of the tenant in the cache
+// @Summary pinning a blob of the tenant in the cache, a pinned blob is never evicted. A blob not in the cache is loaded first.
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param id path string true "Id of the blob"
+// @Success 201 "blob pinned"
+// @Failure 404 {object} serror.Serr "blob not found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache/{id}/pin [post]
+func PostCacheBlobPin(response http.ResponseWriter, request *http.Request) {
+	pinCacheBlob(response, request, true)
+}
+
+// DeleteCacheBlobPin unpinning a blob of the tenant in the cache
+// @Summary unpinning a blob of the tenant in the cache, the blob can be evicted again
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param id path string true "Id of the blob"
+// @Success 200 "blob unpinned"
+// @Failure 404 {object} serror.Serr "blob not in the cache"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache/{id}/pin [delete]
+func DeleteCacheBlobPin(response http.ResponseWriter, request *http.Request) {
+	pinCacheBlob(response, request, false)
+}
+
+func pinCacheBlob(response http.ResponseWriter, request *http.Request, pin bool) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	id := chi.URLParam(request, "id")
+	err = cm.PinCacheBlob(tenant, id, pin)
+	if err != nil {
+		cacheErr(response, request, id, err)
+		return
+	}
+	if pin {
+		render.Status(request, http.StatusCreated)
+	}
+	render.JSON(response, request, id)
+}
+
+// PostCacheWarm starting the warming of the cache of the tenant
+// @Summary starting the warming of the cache of the tenant, the blobs are given by ids or by a search query and loaded throttled in the background
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param payload body model.CacheWarmRequest true "the blobs to load"
+// @Success 201 {object} model.CacheWarming "the status of the warming as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache/warm [post]
+func PostCacheWarm(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	var req model.CacheWarmRequest
+	err = httputils.Decode(request, &req)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	if err := utils.CheckRate(req.Rate); err != nil {
+		httputils.Err(response, request, serror.BadRequest(err, "wrong-param", err.Error()))
+		return
+	}
+	status, err := cm.StartCacheWarming(tenant, req)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.Status(request, http.StatusCreated)
+	render.JSON(response, request, status)
+}
+
+// GetCacheWarm getting the status of the actual or last warming of the cache of the tenant
+// @Summary getting the status of the actual or last warming of the cache of the tenant
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {object} model.CacheWarming "the status of the warming as json"
+// @Failure 404 {object} serror.Serr "no warming found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache/warm [get]
+func GetCacheWarm(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	status, ok := cm.GetCacheWarming(tenant)
+	if !ok {
+		httputils.Err(response, request, serror.NotFound("cache warming", tenant))
+		return
+	}
+	render.JSON(response, request, status)
+}
+
+// DeleteCacheWarm cancelling the running warming of the cache of the tenant
+// @Summary cancelling the running warming of the cache of the tenant, the blobs already loaded stay in the cache
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {object} model.CacheWarming "the status of the warming as json"
+// @Failure 400 {object} serror.Serr "no warming running"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/cache/warm [delete]
+func DeleteCacheWarm(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	cm, err := services.GetCacheManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	status, err := cm.CancelCacheWarming(tenant)
+	if err != nil {
+		cacheErr(response, request, tenant, err)
+		return
+	}
+	render.JSON(response, request, status)
+}
+
+// cacheErr mapping the errors of the cache manager to the http status
+func cacheErr(response http.ResponseWriter, request *http.Request, id string, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		httputils.Err(response, request, serror.NotFound("blob", id, err))
+	case errors.Is(err, factory.ErrNoFastcache), errors.Is(err, business.ErrNoCache), errors.Is(err, business.ErrNoWarming):
+		httputils.Err(response, request, serror.BadRequest(err))
+	default:
+		httputils.Err(response, request, serror.InternalServerError(err))
+	}
+}
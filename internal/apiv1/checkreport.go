@@ -0,0 +1,242 @@
+package apiv1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/willie68/GoBlobStore/internal/serror"
+	services "github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/migration"
+	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// GetCheckReports getting the history of the check runs of the tenant
+// @Summary getting the summaries of the persisted check reports of the tenant, the newest first
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {array} model.CheckReport "list of check reports as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/check/reports [get]
+func GetCheckReports(response http.ResponseWriter, request *http.Request) {
+	rs, tenant, ok := getReportStore(response, request)
+	if !ok {
+		return
+	}
+	rps, err := rs.List(tenant)
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	render.JSON(response, request, rps)
+}
+
+// GetCheckReport getting the summary of a check run of the tenant
+// @Summary getting the summary of a check run of the tenant, with start and end time and the counts
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param id path string true "the id of the check"
+// @Success 200 {object} model.CheckReport "the check report as json"
+// @Failure 404 {object} serror.Serr "no check report found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/check/reports/{id} [get]
+func GetCheckReport(response http.ResponseWriter, request *http.Request) {
+	rs, tenant, ok := getReportStore(response, request)
+	if !ok {
+		return
+	}
+	id := chi.URLParam(request, "id")
+	rp, err := rs.Get(tenant, id)
+	if err != nil {
+		checkReportErr(response, request, id, err)
+		return
+	}
+	render.JSON(response, request, rp)
+}
+
+// DeleteCheckReport removing a check report of the tenant
+// @Summary removing a check report of the tenant
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param id path string true "the id of the check"
+// @Success 200 {object} model.CheckReport "the removed check report as json"
+// @Failure 400 {object} serror.Serr "the check is running"
+// @Failure 404 {object} serror.Serr "no check report found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/check/reports/{id} [delete]
+func DeleteCheckReport(response http.ResponseWriter, request *http.Request) {
+	rs, tenant, ok := getReportStore(response, request)
+	if !ok {
+		return
+	}
+	id := chi.URLParam(request, "id")
+	rp, err := rs.Get(tenant, id)
+	if err != nil {
+		checkReportErr(response, request, id, err)
+		return
+	}
+	if rp.State == model.CheckStateRunning {
+		httputils.Err(response, request, serror.BadRequest(errors.New("check is running")))
+		return
+	}
+	err = rs.Delete(tenant, id)
+	if err != nil {
+		checkReportErr(response, request, id, err)
+		return
+	}
+	render.JSON(response, request, rp)
+}
+
+// GetCheckReportExport downloading the full report of a check run of the tenant
+// @Summary downloading the result lines of every checked blob of a check run as json, ndjson or csv
+// @Tags configs
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param id path string true "the id of the check"
+// @Param format query string false "json (default), ndjson or csv"
+// @Param errors query bool false "only the blobs with errors"
+// @Success 200 {array} model.CheckResultLine "list of result lines"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 404 {object} serror.Serr "no check report found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/check/reports/{id}/export [get]
+func GetCheckReportExport(response http.ResponseWriter, request *http.Request) {
+	rs, tenant, ok := getReportStore(response, request)
+	if !ok {
+		return
+	}
+	id := chi.URLParam(request, "id")
+	if _, err := rs.Get(tenant, id); err != nil {
+		checkReportErr(response, request, id, err)
+		return
+	}
+	values := request.URL.Query()
+	errorsOnly, _ := strconv.ParseBool(values.Get("errors"))
+	format := values.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	// the body is written only after all headers and the status are set
+	var start func() error
+	var write func(l model.CheckResultLine) error
+	var finish func() error
+	switch format {
+	case "json":
+		response.Header().Set("Content-Type", "application/json")
+		first := true
+		start = func() error {
+			_, err := response.Write([]byte("["))
+			return err
+		}
+		write = func(l model.CheckResultLine) error {
+			js, err := json.Marshal(l)
+			if err != nil {
+				return err
+			}
+			if !first {
+				_, _ = response.Write([]byte(","))
+			}
+			first = false
+			_, err = response.Write(js)
+			return err
+		}
+		finish = func() error {
+			_, err := response.Write([]byte("]"))
+			return err
+		}
+	case "ndjson":
+		response.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(response)
+		start = func() error { return nil }
+		write = func(l model.CheckResultLine) error {
+			return enc.Encode(l)
+		}
+		finish = func() error { return nil }
+	case "csv":
+		response.Header().Set("Content-Type", "text/csv")
+		cw := csv.NewWriter(response)
+		start = func() error {
+			return cw.Write([]string{"storage", "id", "filename", "inCache", "inBackup", "primaryHashOK", "backupHashOK", "hasError", "messages"})
+		}
+		write = func(l model.CheckResultLine) error {
+			return cw.Write([]string{
+				l.Storage,
+				l.ID,
+				l.Filename,
+				strconv.FormatBool(l.InCache),
+				strconv.FormatBool(l.InBackup),
+				strconv.FormatBool(l.PrimaryHashOK),
+				strconv.FormatBool(l.BackupHashOK),
+				strconv.FormatBool(l.HasError),
+				strings.Join(l.Messages, "; "),
+			})
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		httputils.Err(response, request, serror.BadRequest(nil, "wrong-format", "format must be json, ndjson or csv"))
+		return
+	}
+	response.Header().Set("Content-Disposition", "attachment; filename=check_"+id+"."+format)
+	response.WriteHeader(http.StatusOK)
+	if err := start(); err != nil {
+		logger.Errorf("check report export: error starting export: %v", err)
+		return
+	}
+	err := rs.Lines(tenant, id, errorsOnly, func(l model.CheckResultLine) bool {
+		if err := write(l); err != nil {
+			logger.Errorf("check report export: error writing line: %v", err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		logger.Errorf("check report export: error reading report %s of tenant %s: %v", id, tenant, err)
+	}
+	if err := finish(); err != nil {
+		logger.Errorf("check report export: error finishing export: %v", err)
+	}
+}
+
+// getReportStore getting the store of the check reports and the tenant, on errors the response is written
+func getReportStore(response http.ResponseWriter, request *http.Request) (*migration.ReportStore, string, bool) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return nil, "", false
+	}
+	cMan, err := services.GetMigrationManagement()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return nil, "", false
+	}
+	return cMan.Reports, tenant, true
+}
+
+func checkReportErr(response http.ResponseWriter, request *http.Request, id string, err error) {
+	if errors.Is(err, migration.ErrReportNotFound) {
+		httputils.Err(response, request, serror.NotFound("check report", id))
+		return
+	}
+	httputils.Err(response, request, serror.InternalServerError(err))
+}
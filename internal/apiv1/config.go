@@ -87,7 +87,9 @@ func GetTenantConfig(response http.ResponseWriter, request *http.Request) {
 	if tntCnf != nil {
 		rsp.Backup = tntCnf.Backup
 		rsp.Properties = tntCnf.Properties
-		rsp.Backup.Properties["secretKey"] = "*"
+		if rsp.Backup.Properties != nil {
+			rsp.Backup.Properties["secretKey"] = "*"
+		}
 	}
 	render.JSON(response, request, rsp)
 }
@@ -140,15 +142,22 @@ func PostCreateTenant(response http.ResponseWriter, request *http.Request) {
 	}
 
 	if config.Get().Engine.AllowTntBackup && cfg.Storageclass != "" {
-		if !strings.EqualFold(cfg.Storageclass, factory.STGClassS3) {
+		if !strings.EqualFold(cfg.Storageclass, factory.STGClassS3) && !strings.EqualFold(cfg.Storageclass, factory.STGClassGoBlob) {
 			err := fmt.Errorf("storage class \"%s\" is not allowed", cfg.Storageclass)
 			httputils.Err(response, request, serror.BadRequest(err))
 			return
 		}
-		tntcfg := interfaces.TenantConfig{
-			Backup: cfg,
+		// the other parts of the config, e.g. the primary storage, must be kept
+		tntcfg, err := tntsrv.GetConfig(tenant)
+		if err != nil {
+			httputils.Err(response, request, serror.InternalServerError(err))
+			return
+		}
+		if tntcfg == nil {
+			tntcfg = &interfaces.TenantConfig{}
 		}
-		err = tntsrv.SetConfig(tenant, tntcfg)
+		tntcfg.Backup = cfg
+		err = tntsrv.SetConfig(tenant, *tntcfg)
 		if err != nil {
 			httputils.Err(response, request, serror.InternalServerError(err))
 			return
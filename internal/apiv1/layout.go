@@ -0,0 +1,118 @@
+package apiv1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+	"github.com/willie68/GoBlobStore/internal/serror"
+	services "github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+)
+
+// PostLayout starting the migration of the simple file storage from one layout into another
+// @Summary starting the migration of the simple file storage from one layout into another, e.g. from v1 to v2
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param from query string false "source layout, default v1"
+// @Param to query string false "target layout, default v2"
+// @Param tenant query []string false "tenants to migrate, default all tenants"
+// @Param dryrun query bool false "only checking, what would be migrated"
+// @Param verify query bool false "verifying the hashes of the binaries, default true"
+// @Success 201 {object} simplefile.LayoutReport "the started job as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/layout [post]
+func PostLayout(response http.ResponseWriter, request *http.Request) {
+	mig, err := services.GetLayoutMigrator()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	values := request.URL.Query()
+	opts := simplefile.LayoutOptions{
+		From:    simplefile.LayoutV1,
+		To:      simplefile.LayoutV2,
+		Tenants: values["tenant"],
+		Verify:  true,
+	}
+	if values.Get("from") != "" {
+		opts.From = values.Get("from")
+	}
+	if values.Get("to") != "" {
+		opts.To = values.Get("to")
+	}
+	if values.Get("dryrun") != "" {
+		opts.DryRun, err = strconv.ParseBool(values.Get("dryrun"))
+		if err != nil {
+			httputils.Err(response, request, serror.BadRequest(err))
+			return
+		}
+	}
+	if values.Get("verify") != "" {
+		opts.Verify, err = strconv.ParseBool(values.Get("verify"))
+		if err != nil {
+			httputils.Err(response, request, serror.BadRequest(err))
+			return
+		}
+	}
+	job, err := mig.Start(opts)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.Status(request, http.StatusCreated)
+	render.JSON(response, request, job)
+}
+
+// GetLayout getting the report of the actual or last layout migration
+// @Summary getting the report of the actual or last layout migration
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Success 200 {object} simplefile.LayoutReport "the report as json"
+// @Failure 404 {object} serror.Serr "no migration found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/layout [get]
+func GetLayout(response http.ResponseWriter, request *http.Request) {
+	mig, err := services.GetLayoutMigrator()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	job, ok := mig.Job()
+	if !ok {
+		httputils.Err(response, request, serror.NotFound("layout migration", ""))
+		return
+	}
+	render.JSON(response, request, job)
+}
+
+// DeleteLayout cancelling the running layout migration
+// @Summary cancelling the running layout migration, it can be resumed by starting it again
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Success 200 {object} simplefile.LayoutReport "the report as json"
+// @Failure 400 {object} serror.Serr "no migration running"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/layout [delete]
+func DeleteLayout(response http.ResponseWriter, request *http.Request) {
+	mig, err := services.GetLayoutMigrator()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	if !mig.Cancel() {
+		httputils.Err(response, request, serror.BadRequest(errors.New("no layout migration running")))
+		return
+	}
+	job, _ := mig.Job()
+	render.JSON(response, request, job)
+}
@@ -0,0 +1,155 @@
+package apiv1
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/willie68/GoBlobStore/internal/serror"
+	services "github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+	"github.com/willie68/GoBlobStore/pkg/model/query"
+)
+
+// PostSearchExplain explaining a search query
+// @Summary parsing the query in the body and returning the parsed query, the query the index of the tenant would run and the parse errors with positions and suggestions
+// @Tags configs
+// @Accept  plain
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param payload body string true "the query"
+// @Success 200 {object} model.QueryExplain "the explanation of the query as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/search/_explain [post]
+func PostSearchExplain(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	b, err := io.ReadAll(request.Body)
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	qe, q := explainQuery(string(b))
+	if qe.Valid && !qe.Native {
+		explainIndex(tenant, q, &qe)
+	}
+	render.JSON(response, request, qe)
+}
+
+// PostSearchValidate validating a search query
+// @Summary parsing the query in the body and returning if the query is valid, with the parse errors with positions and suggestions, the index is not used
+// @Tags configs
+// @Accept  plain
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param payload body string true "the query"
+// @Success 200 {object} model.QueryExplain "the result of the validation as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/search/_validate [post]
+func PostSearchValidate(response http.ResponseWriter, request *http.Request) {
+	b, err := io.ReadAll(request.Body)
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	qe, _ := explainQuery(string(b))
+	render.JSON(response, request, qe)
+}
+
+// PostAdminSearch searching the blobs of all tenants
+// @Summary searching the blobs of all tenants with the query in the body, the hits are ordered by tenant and blob id and contain the tenant
+// @Tags configs
+// @Accept  plain
+// @Produce  json
+// @Security api_key
+// @Param offset query int false "index of the first hit"
+// @Param limit query int false "maximum count of hits, default 1000"
+// @Param payload body string true "the query"
+// @Success 200 {object} model.TenantSearch "the page of hits as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/search [post]
+func PostAdminSearch(response http.ResponseWriter, request *http.Request) {
+	ts, err := services.GetTenantSearcher()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	offset, limit, ok := paging(response, request)
+	if !ok {
+		return
+	}
+	b, err := io.ReadAll(request.Body)
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	qs := string(b)
+	if strings.TrimSpace(qs) == "" {
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-query", "query missing"))
+		return
+	}
+	res, err := ts.SearchAllTenants(qs, offset, limit)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.JSON(response, request, res)
+}
+
+// explainQuery parsing the query, native queries are not parsed
+func explainQuery(qs string) (model.QueryExplain, query.Query) {
+	qe := model.QueryExplain{
+		Query: qs,
+	}
+	if strings.HasPrefix(qs, "#") {
+		qe.Valid = true
+		qe.Native = true
+		return qe, query.Query{}
+	}
+	q, err := query.ParseQuery(qs)
+	if err != nil {
+		qe.Errors = query.ParseErrors(err)
+		qe.Suggestions = query.Suggestions(qs, qe.Errors)
+		return qe, q
+	}
+	qe.Valid = true
+	qe.Normalized = q.String()
+	qe.AST = q.Condition
+	return qe, q
+}
+
+// explainIndex adding the query the index of the tenant would run
+func explainIndex(tenant string, q query.Query, qe *model.QueryExplain) {
+	stgf, err := services.GetStorageFactory()
+	if err != nil {
+		qe.IndexError = err.Error()
+		return
+	}
+	storage, err := stgf.GetStorage(tenant)
+	if err != nil {
+		qe.IndexError = err.Error()
+		return
+	}
+	ex, ok := storage.(interfaces.QueryExplainer)
+	if !ok {
+		qe.IndexError = "storage can't explain queries"
+		return
+	}
+	idx, native, err := ex.ExplainQuery(q)
+	qe.Index = idx
+	qe.IndexQuery = native
+	if err != nil {
+		qe.IndexError = err.Error()
+	}
+}
@@ -0,0 +1,123 @@
+package apiv1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/serror"
+	services "github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/factory"
+	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+)
+
+// PostStorageMigration starting the live migration of the primary storage of the tenant
+// @Summary starting the live migration of the primary storage of the tenant to the storage given in the body. While migrating, all writes go to both storages, at the end the tenant is switched to the new storage.
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Param payload body config.Storage true "the target storage"
+// @Success 201 {object} model.StorageMigration "the status of the migration as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/migration [post]
+func PostStorageMigration(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	mig, err := services.GetStorageMigrator()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	var target config.Storage
+	err = httputils.Decode(request, &target)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	if target.Storageclass == "" {
+		httputils.Err(response, request, serror.BadRequest(errors.New("no storage class given")))
+		return
+	}
+	logger.Infof("starting storage migration of tenant %s to %s", tenant, target.Storageclass)
+	status, err := mig.StartMigration(tenant, target)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.Status(request, http.StatusCreated)
+	render.JSON(response, request, status)
+}
+
+// GetStorageMigration getting the status of the actual or last storage migration of the tenant
+// @Summary getting the status of the actual or last storage migration of the tenant
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {object} model.StorageMigration "the status of the migration as json"
+// @Failure 404 {object} serror.Serr "no migration found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/migration [get]
+func GetStorageMigration(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	mig, err := services.GetStorageMigrator()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	status, ok := mig.GetMigration(tenant)
+	if !ok {
+		httputils.Err(response, request, serror.NotFound("storage migration", tenant))
+		return
+	}
+	render.JSON(response, request, status)
+}
+
+// DeleteStorageMigration cancelling the running storage migration of the tenant
+// @Summary cancelling the running storage migration of the tenant, the tenant stays on the source storage
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param tenant header string true "Tenant"
+// @Success 200 {object} model.StorageMigration "the status of the migration as json"
+// @Failure 400 {object} serror.Serr "no migration running"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/migration [delete]
+func DeleteStorageMigration(response http.ResponseWriter, request *http.Request) {
+	tenant, err := httputils.TenantID(request)
+	if err != nil {
+		msg := "tenant header missing"
+		httputils.Err(response, request, serror.BadRequest(nil, "missing-tenant", msg))
+		return
+	}
+	mig, err := services.GetStorageMigrator()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	status, err := mig.CancelMigration(tenant)
+	if err != nil {
+		if errors.Is(err, factory.ErrNoMigration) {
+			httputils.Err(response, request, serror.BadRequest(err))
+			return
+		}
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	render.JSON(response, request, status)
+}
@@ -0,0 +1,166 @@
+package apiv1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/willie68/GoBlobStore/internal/serror"
+	services "github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/volume"
+	"github.com/willie68/GoBlobStore/internal/utils/httputils"
+)
+
+// GetVolumes getting the infos of all volumes of the multi volume storage
+// @Summary getting the infos of all volumes of the multi volume storage
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Success 200 {array} volume.Info "list of volumes as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/volumes [get]
+func GetVolumes(response http.ResponseWriter, request *http.Request) {
+	volMan, _, err := services.GetVolumeManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	render.JSON(response, request, volMan.Volumes())
+}
+
+// PostVolumeState setting the state of a volume (active, readonly, draining)
+// @Summary setting the state of a volume, a volume in state readonly or draining will get no new blobs
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param name path string true "name of the volume"
+// @Param state query string true "new state of the volume: active, readonly or draining"
+// @Success 200 {object} volume.Info "the volume info as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/volumes/{name}/state [post]
+func PostVolumeState(response http.ResponseWriter, request *http.Request) {
+	volMan, _, err := services.GetVolumeManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	name := chi.URLParam(request, "name")
+	err = volMan.SetState(name, request.URL.Query().Get("state"))
+	if err != nil {
+		if errors.Is(err, volume.ErrUnknownVolume) {
+			httputils.Err(response, request, serror.NotFound("volume", name, err))
+			return
+		}
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.JSON(response, request, volMan.Info(name))
+}
+
+// PostVolumeDrain starting to move all blobs of a volume to the other volumes
+// @Summary starting to move all blobs of a volume to the other volumes, the volume is set to draining
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Param name path string true "name of the volume"
+// @Success 201 {object} volume.JobStatus "the started job as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/volumes/{name}/drain [post]
+func PostVolumeDrain(response http.ResponseWriter, request *http.Request) {
+	volMan, mover, err := services.GetVolumeManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	name := chi.URLParam(request, "name")
+	job, err := volMan.Drain(mover, name)
+	if err != nil {
+		if errors.Is(err, volume.ErrUnknownVolume) {
+			httputils.Err(response, request, serror.NotFound("volume", name, err))
+			return
+		}
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.Status(request, http.StatusCreated)
+	render.JSON(response, request, job)
+}
+
+// PostVolumesRebalance starting to move blobs between the volumes towards equal utilisation
+// @Summary starting to move blobs between the volumes towards equal utilisation
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Success 201 {object} volume.JobStatus "the started job as json"
+// @Failure 400 {object} serror.Serr "client error information as json"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/volumes/rebalance [post]
+func PostVolumesRebalance(response http.ResponseWriter, request *http.Request) {
+	volMan, mover, err := services.GetVolumeManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	job, err := volMan.Rebalance(mover)
+	if err != nil {
+		httputils.Err(response, request, serror.BadRequest(err))
+		return
+	}
+	render.Status(request, http.StatusCreated)
+	render.JSON(response, request, job)
+}
+
+// GetVolumesJob getting the progress of the actual or last volume job
+// @Summary getting the progress of the actual or last volume job
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Success 200 {object} volume.JobStatus "the job as json"
+// @Failure 404 {object} serror.Serr "no job found"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/volumes/job [get]
+func GetVolumesJob(response http.ResponseWriter, request *http.Request) {
+	volMan, _, err := services.GetVolumeManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	job, ok := volMan.Job()
+	if !ok {
+		httputils.Err(response, request, serror.NotFound("volume job", ""))
+		return
+	}
+	render.JSON(response, request, job)
+}
+
+// DeleteVolumesJob cancelling the running volume job
+// @Summary cancelling the running volume job
+// @Tags configs
+// @Accept  json
+// @Produce  json
+// @Security api_key
+// @Success 200 {object} volume.JobStatus "the job as json"
+// @Failure 400 {object} serror.Serr "no job running"
+// @Failure 500 {object} serror.Serr "server error information as json"
+// @Router /api/v1/admin/volumes/job [delete]
+func DeleteVolumesJob(response http.ResponseWriter, request *http.Request) {
+	volMan, _, err := services.GetVolumeManager()
+	if err != nil {
+		httputils.Err(response, request, serror.InternalServerError(err))
+		return
+	}
+	if !volMan.CancelJob() {
+		httputils.Err(response, request, serror.BadRequest(errors.New("no volume job running")))
+		return
+	}
+	job, _ := volMan.Job()
+	render.JSON(response, request, job)
+}
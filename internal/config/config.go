@@ -86,15 +86,53 @@ type Authentication struct {
 
 // Engine configuration
 type Engine struct {
-	RetentionManager string    `yaml:"retentionManager"`
-	Tenantautoadd    bool      `yaml:"tenantautoadd"`
-	BackupSyncmode   bool      `yaml:"backupsyncmode"`
-	AllowTntBackup   bool      `yaml:"allowtntbackup"`
-	Storage          Storage   `yaml:"storage"`
-	Backup           Storage   `yaml:"backup"`
-	Cache            Storage   `yaml:"cache"`
-	Index            Storage   `yaml:"index"`
-	Extractor        Extractor `yaml:"extractor"`
+	RetentionManager string       `yaml:"retentionManager"`
+	Tenantautoadd    bool         `yaml:"tenantautoadd"`
+	BackupSyncmode   bool         `yaml:"backupsyncmode"`
+	AllowTntBackup   bool         `yaml:"allowtntbackup"`
+	AutoRepair       bool         `yaml:"autorepair"`
+	Storage          Storage      `yaml:"storage"`
+	Backup           Storage      `yaml:"backup"`
+	Cache            Storage      `yaml:"cache"`
+	Index            Storage      `yaml:"index"`
+	Audit            Storage      `yaml:"audit"`
+	Extractor        Extractor    `yaml:"extractor"`
+	LastAccess       LastAccess   `yaml:"lastaccess"`
+	Scrubber         Scrubber     `yaml:"scrubber"`
+	CheckReports     CheckReports `yaml:"checkreports"`
+}
+
+// CheckReports configuration of the persistent reports of the checks of the tenants
+type CheckReports struct {
+	// root path of the reports, defaults to the folder _checks in the root path of the storage or to the temp folder
+	RootPath string `yaml:"rootpath"`
+	// count of reports kept per tenant, 0 for the default
+	Keep int `yaml:"keep"`
+	// maximum age of the reports in days, 0 for no limit
+	MaxAge int `yaml:"maxage"`
+}
+
+// Scrubber configuration of the background verification of the hashes of all blobs
+type Scrubber struct {
+	Enable bool `yaml:"enable"`
+	// time of one cycle over all blobs of all tenants in hours, counted from the start of the last cycle
+	CycleTime int `yaml:"cycletime"`
+	// maximum count of blobs checked per second
+	Rate int `yaml:"rate"`
+	// maximum count of bytes read per second, 0 for no limit
+	ByteRate int64 `yaml:"byterate"`
+	// time of day windows, in which the scrubber is running, like "22:00-06:00", empty for all day
+	Windows []string `yaml:"windows"`
+	// file for the position of the scrubber, to resume the cycle after a restart
+	StateFile string `yaml:"statefile"`
+}
+
+// LastAccess configuration of the write behind of the last access time stamps
+type LastAccess struct {
+	// flush interval in seconds
+	FlushInterval int `yaml:"flushinterval"`
+	// maximum count of pending updates, before a flush is forced
+	BatchSize int `yaml:"batchsize"`
 }
 
 // Extractor defining config for full text extraction services
@@ -103,6 +141,14 @@ type Extractor struct {
 	Properties map[string]any `yaml:"properties"`
 }
 
+// CacheQuota limits of a cache partition, 0 for the default
+type CacheQuota struct {
+	// maximum count of blobs in the partition
+	MaxCount int64 `yaml:"maxcount" json:"maxcount"`
+	// maximum size of all blobs in the partition in bytes
+	MaxSize int64 `yaml:"maxsize" json:"maxsize"`
+}
+
 // Storage configuration
 type Storage struct {
 	Storageclass string         `yaml:"storageclass"`
@@ -125,7 +171,7 @@ type Profiling struct {
 	Enable bool `yaml:"enable"`
 }
 
-var defaultHeaderMapping = map[string]string{api.TenantHeaderKey: "X-tenant", api.RetentionHeaderKey: "X-retention", api.APIKeyHeaderKey: "X-apikey", api.FilenameKey: "X-filename", api.BlobIDHeaderKey: "X-blobid", api.HeaderPrefixKey: "X-"}
+var defaultHeaderMapping = map[string]string{api.TenantHeaderKey: "X-tenant", api.RetentionHeaderKey: "X-retention", api.RetentionModeHeaderKey: "X-retentionmode", api.APIKeyHeaderKey: "X-apikey", api.FilenameKey: "X-filename", api.BlobIDHeaderKey: "X-blobid", api.HeaderPrefixKey: "X-"}
 
 // DefaultConfig default configuration
 var DefaultConfig = Config{
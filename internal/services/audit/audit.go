@@ -0,0 +1,191 @@
+// Package audit contains the append only journal for recording all deletions of blobs
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/logging"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// FileJournalName name of the file based audit journal
+const FileJournalName = "file"
+
+const (
+	journalPrefix = "audit_"
+	journalExt    = ".ndjson"
+	monthLayout   = "2006-01"
+	maxLineSize   = 1024 * 1024
+)
+
+var (
+	_      interfaces.AuditJournal = &FileJournal{}
+	logger                         = logging.New().WithName("audit")
+)
+
+// FileJournal a file based audit journal. Every tenant gets it's own folder with one append only file per month.
+// The journal is stored independently of the blob storage, so removing blobs or tenants will not touch the journal.
+type FileJournal struct {
+	RootPath string // root path of the journal, should not be part of the blob storage
+	Node     string // name of this node, if empty, the host name is used
+	jm       sync.Mutex
+}
+
+// Init initialize the journal
+func (f *FileJournal) Init() error {
+	if f.RootPath == "" {
+		return errors.New("audit: root path should not be empty")
+	}
+	err := os.MkdirAll(f.RootPath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	if f.Node == "" {
+		f.Node, err = os.Hostname()
+		if err != nil {
+			logger.Errorf("audit: can't get host name: %v", err)
+			f.Node = "n.n."
+		}
+	}
+	return nil
+}
+
+// Append appending a new entry to the journal of the tenant
+func (f *FileJournal) Append(tenant string, e model.AuditEntry) error {
+	if tenant == "" {
+		return errors.New("audit: tenant should not be empty")
+	}
+	if e.TenantID == "" {
+		e.TenantID = tenant
+	}
+	if e.Node == "" {
+		e.Node = f.Node
+	}
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().UnixMilli()
+	}
+	js, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tntPath := filepath.Join(f.RootPath, tenant)
+	err = os.MkdirAll(tntPath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	fn := filepath.Join(tntPath, journalFilename(e.Timestamp))
+
+	f.jm.Lock()
+	defer f.jm.Unlock()
+	file, err := os.OpenFile(fn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(js, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	// the entry should be on the disk, before the deletion happens
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Query walking thru all entries of the tenant in the time range [from, to], to = 0 means open end
+func (f *FileJournal) Query(tenant string, from, to int64, callback func(e model.AuditEntry) bool) error {
+	tntPath := filepath.Join(f.RootPath, tenant)
+	files, err := os.ReadDir(tntPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	names := make([]string, 0)
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasPrefix(fi.Name(), journalPrefix) || !strings.HasSuffix(fi.Name(), journalExt) {
+			continue
+		}
+		if !monthInRange(fi.Name(), from, to) {
+			continue
+		}
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		next, err := f.queryFile(filepath.Join(tntPath, n), from, to, callback)
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *FileJournal) queryFile(fn string, from, to int64, callback func(e model.AuditEntry) bool) (bool, error) {
+	file, err := os.Open(fn)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e model.AuditEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			logger.Errorf("audit: can't read entry in %s: %v", fn, err)
+			continue
+		}
+		if e.Timestamp < from || (to > 0 && e.Timestamp > to) {
+			continue
+		}
+		if !callback(e) {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
+
+// Close closing the journal
+func (f *FileJournal) Close() error {
+	return nil
+}
+
+func journalFilename(ts int64) string {
+	return fmt.Sprintf("%s%s%s", journalPrefix, time.UnixMilli(ts).UTC().Format(monthLayout), journalExt)
+}
+
+// monthInRange checking if the month of the journal file overlaps the time range
+func monthInRange(name string, from, to int64) bool {
+	ms := strings.TrimSuffix(strings.TrimPrefix(name, journalPrefix), journalExt)
+	start, err := time.Parse(monthLayout, ms)
+	if err != nil {
+		return false
+	}
+	end := start.AddDate(0, 1, 0)
+	if end.UnixMilli() <= from {
+		return false
+	}
+	if to > 0 && start.UnixMilli() > to {
+		return false
+	}
+	return true
+}
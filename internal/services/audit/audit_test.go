@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const (
+	rootpath = "../../../testdata/audit"
+	tenant   = "test"
+)
+
+func initTest(t *testing.T) *FileJournal {
+	ast := assert.New(t)
+	err := os.RemoveAll(rootpath)
+	ast.Nil(err)
+	j := &FileJournal{
+		RootPath: rootpath,
+		Node:     "node01",
+	}
+	err = j.Init()
+	ast.Nil(err)
+	return j
+}
+
+func TestAppendQuery(t *testing.T) {
+	ast := assert.New(t)
+	j := initTest(t)
+
+	now := time.Now()
+	old := now.AddDate(0, -2, 0)
+	b := model.BlobDescription{
+		BlobID:        "12345678",
+		TenantID:      tenant,
+		Filename:      "test.txt",
+		Hash:          "sha-256:1234",
+		ContentLength: 123,
+		CreationDate:  old.UnixMilli(),
+		Retention:     1,
+	}
+	e := model.AuditEntryFromBlobDescription(b, model.ReasonRetention)
+	e.Timestamp = old.UnixMilli()
+	ast.Nil(j.Append(tenant, e))
+
+	b.BlobID = "87654321"
+	ast.Nil(j.Append(tenant, model.AuditEntryFromBlobDescription(b, model.ReasonAPI)))
+
+	entries := make([]model.AuditEntry, 0)
+	err := j.Query(tenant, 0, 0, func(e model.AuditEntry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(2, len(entries))
+	ast.Equal("12345678", entries[0].BlobID)
+	ast.Equal(model.ReasonRetention, entries[0].Reason)
+	ast.Equal("node01", entries[0].Node)
+	ast.Equal("sha-256:1234", entries[0].Hash)
+	ast.Equal(int64(123), entries[0].ContentLength)
+	ast.Equal(model.ReasonAPI, entries[1].Reason)
+	ast.True(entries[1].Timestamp >= now.UnixMilli())
+
+	// only the newer one
+	entries = make([]model.AuditEntry, 0)
+	err = j.Query(tenant, now.AddDate(0, 0, -1).UnixMilli(), 0, func(e model.AuditEntry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(1, len(entries))
+	ast.Equal("87654321", entries[0].BlobID)
+
+	// unknown tenant
+	count := 0
+	err = j.Query("unknown", 0, 0, func(e model.AuditEntry) bool {
+		count++
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(0, count)
+	ast.Nil(j.Close())
+}
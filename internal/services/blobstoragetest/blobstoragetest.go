@@ -0,0 +1,239 @@
+// Package blobstoragetest contains the test scenarios, which every blob storage implementation has to pass.
+// The scenarios are working on the test data of testdata/mig.zip for the tenant MCS.
+package blobstoragetest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/internal/utils/slicesutils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// Tenant the tenant of the test data
+const Tenant = "MCS"
+
+const content = "this is a blob content"
+
+// NewStorage creating an initialised storage of the tenant for the test, with withData the test data is loaded first
+type NewStorage func(t *testing.T, withData bool) interfaces.BlobStorage
+
+// Run running all scenarios as sub tests
+func Run(t *testing.T, newStorage NewStorage) {
+	scenarios := []struct {
+		name string
+		f    func(t *testing.T, newStorage NewStorage)
+	}{
+		{"NotFound", notFound},
+		{"List", list},
+		{"Info", info},
+		{"CRUD", crud},
+		{"CRUDWithGivenID", crudWithGivenID},
+		{"Retention", retention},
+		{"BlobCheck", blobCheck},
+	}
+	for _, sc := range scenarios {
+		f := sc.f
+		t.Run(sc.name, func(t *testing.T) {
+			f(t, newStorage)
+		})
+	}
+}
+
+// TestBlob getting a blob description for the content "this is a blob content"
+func TestBlob() model.BlobDescription {
+	b := model.BlobDescription{
+		StoreID:       Tenant,
+		TenantID:      Tenant,
+		ContentLength: int64(len(content)),
+		ContentType:   "text/plain",
+		CreationDate:  time.Now().UnixMilli(),
+		Filename:      "test.txt",
+		LastAccess:    time.Now().UnixMilli(),
+		Retention:     180000,
+		Properties:    make(map[string]any),
+	}
+	b.Properties["X-user"] = []string{"Hallo", "Hallo2"}
+	b.Properties["X-retention"] = []int{123456}
+	b.Properties["X-tenant"] = Tenant
+	return b
+}
+
+func notFound(t *testing.T, newStorage NewStorage) {
+	ast := assert.New(t)
+	srv := newStorage(t, false)
+	defer srv.Close()
+
+	ok, err := srv.HasBlob("wrongid")
+	ast.Nil(err)
+	ast.False(ok)
+
+	_, err = srv.GetBlobDescription("wrongid")
+	ast.NotNil(err)
+
+	var b bytes.Buffer
+	ast.NotNil(srv.RetrieveBlob("wrongid", &b))
+}
+
+func list(t *testing.T, newStorage NewStorage) {
+	ast := assert.New(t)
+	srv := newStorage(t, true)
+	defer srv.Close()
+
+	blobs := make([]string, 0)
+	err := srv.GetBlobs(func(id string) bool {
+		blobs = append(blobs, id)
+		return true
+	})
+	ast.Nil(err)
+
+	ast.Equal(7, len(blobs))
+	ast.True(slicesutils.Contains(blobs, "004b4987-42fb-43e4-8e13-d6994ce0e6f1"))
+	ast.True(slicesutils.Contains(blobs, "0000fc02-050a-418a-a701-efd814aa6b36"))
+}
+
+func info(t *testing.T, newStorage NewStorage) {
+	ast := assert.New(t)
+	srv := newStorage(t, true)
+	defer srv.Close()
+
+	for _, id := range []string{"004b4987-42fb-43e4-8e13-d6994ce0e6f1", "0000fc02-050a-418a-a701-efd814aa6b36"} {
+		ok, err := srv.HasBlob(id)
+		ast.Nil(err)
+		ast.True(ok)
+
+		info, err := srv.GetBlobDescription(id)
+		ast.Nil(err)
+		if ast.NotNil(info) {
+			ast.Equal(id, info.BlobID)
+		}
+	}
+}
+
+func crud(t *testing.T, newStorage NewStorage) {
+	ast := assert.New(t)
+	srv := newStorage(t, false)
+	defer srv.Close()
+
+	b := TestBlob()
+	id, err := srv.StoreBlob(&b, strings.NewReader(content))
+	ast.Nil(err)
+	ast.NotEmpty(id)
+	ast.Equal(id, b.BlobID)
+
+	info, err := srv.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(id, info.BlobID)
+
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal(content, buf.String())
+
+	b.Properties["X-tenant"] = "MCS_2"
+	ast.Nil(srv.UpdateBlobDescription(id, &b))
+
+	info, err = srv.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(id, info.BlobID)
+	ast.Equal("MCS_2", info.Properties["X-tenant"])
+
+	ast.Nil(srv.DeleteBlob(id))
+	ok, err := srv.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+}
+
+func crudWithGivenID(t *testing.T, newStorage NewStorage) {
+	ast := assert.New(t)
+	srv := newStorage(t, false)
+	defer srv.Close()
+
+	uuid := utils.GenerateID()
+	b := TestBlob()
+	b.BlobID = uuid
+	id, err := srv.StoreBlob(&b, strings.NewReader(content))
+	ast.Nil(err)
+	ast.Equal(uuid, id)
+
+	info, err := srv.GetBlobDescription(uuid)
+	ast.Nil(err)
+	ast.Equal(uuid, info.BlobID)
+
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(uuid, &buf))
+	ast.Equal(content, buf.String())
+
+	ast.Nil(srv.DeleteBlob(uuid))
+}
+
+func retention(t *testing.T, newStorage NewStorage) {
+	ast := assert.New(t)
+	srv := newStorage(t, true)
+	defer srv.Close()
+
+	b := TestBlob()
+	b.Retention = 1
+	id, err := srv.StoreBlob(&b, strings.NewReader(content))
+	ast.Nil(err)
+
+	ret := model.RetentionEntry{
+		Filename:      "test.txt",
+		TenantID:      Tenant,
+		BlobID:        id,
+		CreationDate:  b.CreationDate,
+		Retention:     1,
+		RetentionBase: 0,
+	}
+	ast.Nil(srv.AddRetention(&ret))
+
+	rets := make([]model.RetentionEntry, 0)
+	err = srv.GetAllRetentions(func(r model.RetentionEntry) bool {
+		rets = append(rets, r)
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(8, len(rets))
+
+	retDst, err := srv.GetRetention(id)
+	ast.Nil(err)
+	ast.Equal(ret.BlobID, retDst.BlobID)
+	ast.Equal(ret.CreationDate, retDst.CreationDate)
+	ast.Equal(ret.Filename, retDst.Filename)
+	ast.Equal(ret.Retention, retDst.Retention)
+	ast.Equal(ret.RetentionBase, retDst.RetentionBase)
+
+	ast.Nil(srv.ResetRetention(id))
+	retDst, err = srv.GetRetention(id)
+	ast.Nil(err)
+	ast.True(retDst.RetentionBase > 0)
+
+	ast.Nil(srv.DeleteRetention(id))
+	_, err = srv.GetRetention(id)
+	ast.NotNil(err)
+
+	ast.Nil(srv.DeleteBlob(id))
+}
+
+func blobCheck(t *testing.T, newStorage NewStorage) {
+	ast := assert.New(t)
+	srv := newStorage(t, true)
+	defer srv.Close()
+
+	healthy := map[string]bool{
+		"001a7543-cb7a-4c2c-9c23-1bb6b248034c": false,
+		"0000fc02-050a-418a-a701-efd814aa6b36": true,
+		"004b4987-42fb-43e4-8e13-d6994ce0e6f1": true,
+	}
+	for id, ok := range healthy {
+		res, err := srv.CheckBlob(id)
+		ast.Nil(err)
+		if ast.NotNil(res) {
+			ast.Equal(ok, res.Healthy, "id: %s: %s", id, res.Message)
+		}
+	}
+}
@@ -26,9 +26,10 @@ import (
 const BlugeIndex = "bluge"
 
 var (
-	_      interfaces.Index      = &Index{}
-	_      interfaces.IndexBatch = &IndexBatch{}
-	logger                       = logging.New().WithName("bluge")
+	_      interfaces.Index          = &Index{}
+	_      interfaces.QueryExplainer = &Index{}
+	_      interfaces.IndexBatch     = &IndexBatch{}
+	logger                           = logging.New().WithName("bluge")
 )
 
 // Index a tenant based single indexer
@@ -37,7 +38,6 @@ type Index struct {
 	rootpath string
 	config   bluge.Config
 	wsync    sync.Mutex
-	qsync    sync.Mutex
 }
 
 // IndexBatch for bulk indexing
@@ -146,18 +146,20 @@ func (m *Index) buildQuery(qry string) (bluge.Query, error) {
 	return bq, nil
 }
 
+// ExplainQuery getting a description of the bluge query for a parsed query
+func (m *Index) ExplainQuery(q query.Query) (string, any, error) {
+	bq, err := toBlugeQuery(q)
+	if err != nil {
+		return BlugeIndex, nil, err
+	}
+	return BlugeIndex, describe(bq), nil
+}
+
 func (m *Index) buildAST(q string) (*query.Query, error) {
-	m.qsync.Lock()
-	defer m.qsync.Unlock()
-	query.N.Reset()
-	res, err := query.Parse("query", []byte(q))
+	qu, err := query.ParseQuery(q)
 	if err != nil {
 		return nil, err
 	}
-	qu, ok := res.(query.Query)
-	if !ok {
-		return nil, errors.New("unknown result")
-	}
 	return &qu, nil
 }
 
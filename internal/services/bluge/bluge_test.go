@@ -1,6 +1,7 @@
 package bluge
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/willie68/GoBlobStore/pkg/model"
+	"github.com/willie68/GoBlobStore/pkg/model/query"
 )
 
 const blugefolder = "bluge"
@@ -157,12 +159,94 @@ var tests = []struct {
 		id: "12340",
 		n:  100,
 	},
+	{
+		q:  `intfield:IN(1201, 1234, 1299, 1300)`,
+		id: "12341",
+		n:  3,
+	},
+	{
+		q:  `tenant:IN(ABC, MCS)`,
+		id: "12340",
+		n:  100,
+	},
+	{
+		q:  `intfield:[1210~1220]`,
+		id: "123410",
+		n:  11,
+	},
+	{
+		q:  `!intfield:[1210~1220]`,
+		id: "12340",
+		n:  89,
+	},
+	{
+		q:  `intfield:[1220~1220]`,
+		id: "123420",
+		n:  1,
+	},
+	{
+		q:  `EXISTS(intfield)`,
+		id: "12340",
+		n:  100,
+	},
+	{
+		q:  `EXISTS(user)`,
+		id: "12340",
+		n:  100,
+	},
+	{
+		q:  `EXISTS(unknown)`,
+		id: "12340",
+		n:  0,
+	},
+	{
+		q:  `NOT EXISTS(unknown) AND intfield:=1234`,
+		id: "123434",
+		n:  1,
+	},
+	{
+		q:  `creationDate:>now-1h`,
+		id: "12340",
+		n:  100,
+	},
+	{
+		q:  `creationDate:[now-1h~now+1h]`,
+		id: "12340",
+		n:  100,
+	},
+	{
+		q:  `creationDate:<now-1d`,
+		id: "12340",
+		n:  0,
+	},
+	{
+		q:  `user:~HALLO`,
+		id: "12340",
+		n:  100,
+	},
+	{
+		q:  `user:~HAL*`,
+		id: "12340",
+		n:  100,
+	},
+	{
+		q:  `NOT (intfield:<1210 OR intfield:>=1220)`,
+		id: "123410",
+		n:  10,
+	},
+	{
+		q:  `(intfield:<1210 OR intfield:>=1290) AND tenant:MCS`,
+		id: "12340",
+		n:  20,
+	},
+	{
+		q:  `tenant:MCS AND NOT (intfield:>1200 AND intfield:<1299)`,
+		id: "12340",
+		n:  2,
+	},
 }
 
 func TestQueryConvertion(t *testing.T) {
-	// TODO skip the skip
-	t.SkipNow()
-	// The test intfield:!=1234 is throwing an error
 	ast := assert.New(t)
 
 	InitT(t)
@@ -219,3 +303,30 @@ func getBlobDescription(id string, num int) model.BlobDescription {
 	b.Properties["X-intfield"] = num
 	return b
 }
+
+func TestExplainQuery(t *testing.T) {
+	ast := assert.New(t)
+	idx := Index{Tenant: "MCS"}
+
+	q, err := query.ParseQuery(`intfield:>=1234 AND NOT (user:H* OR tenant:IN(MCS, ABC))`)
+	ast.Nil(err)
+	name, native, err := idx.ExplainQuery(q)
+	ast.Nil(err)
+	ast.Equal(BlugeIndex, name)
+	js, err := json.Marshal(native)
+	ast.Nil(err)
+	ast.Equal(`{"bool":{"must":[{"bool":{"must":[{"numericRange":{"field":"intfield","min":1234,"minInclusive":true}}]}},{"bool":{"mustNot":[{"bool":{"minShould":1,"should":[{"bool":{"must":[{"wildcard":{"field":"user","wildcard":"h*"}}]}},{"bool":{"must":[{"bool":{"minShould":1,"should":[{"bool":{"must":[{"match":{"field":"tenant","match":"MCS"}}]}},{"bool":{"must":[{"match":{"field":"tenant","match":"ABC"}}]}}]}}]}}]}}]}}]}}`, string(js))
+
+	q, err = query.ParseQuery(`c:[2017-10-29T00:00:00Z~2017-10-30T00:00:00Z]`)
+	ast.Nil(err)
+	_, native, err = idx.ExplainQuery(q)
+	ast.Nil(err)
+	js, err = json.Marshal(native)
+	ast.Nil(err)
+	ast.Equal(`{"bool":{"must":[{"dateRange":{"end":"2017-10-30T00:00:00Z","endInclusive":true,"field":"c","start":"2017-10-29T00:00:00Z","startInclusive":true}}]}}`, string(js))
+
+	_, err = query.ParseQuery(`c:["a"~1]`)
+	ast.NotNil(err)
+	_, _, err = idx.ExplainQuery(query.Query{Condition: query.Condition{Field: "c", Operator: query.BT, Value: query.Range{From: "a", To: 1.0}}})
+	ast.NotNil(err)
+}
@@ -2,15 +2,17 @@ package bluge
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blugelabs/bluge"
 	"github.com/willie68/GoBlobStore/pkg/model/query"
 )
 
 func toBlugeQuery(q query.Query) (bluge.Query, error) {
-	c := q.Condition
+	c := q.Resolved(time.Now()).Condition
 	bq, err := xToBq(c)
 	return bq, err
 }
@@ -44,6 +46,12 @@ func nToBq(n query.Node) (bluge.Query, error) {
 			bq.AddShould(q)
 		}
 	}
+	if n.Operator == query.OROP {
+		bq.SetMinShould(1)
+	}
+	if n.Invert {
+		return bluge.NewBooleanQuery().AddMustNot(bq), nil
+	}
 	return bq, nil
 }
 
@@ -89,6 +97,39 @@ func cToBq(c query.Condition) (bluge.Query, error) {
 			return nil, err
 		}
 		q = bluge.NewNumericRangeInclusiveQuery(bluge.MinNumeric, v, false, true).SetField(c.Field)
+	case query.IN:
+		vs, ok := c.Value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("missing list of values for field %s", c.Field)
+		}
+		iq := bluge.NewBooleanQuery()
+		for _, v := range vs {
+			vq, err := cToBq(query.Condition{Field: c.Field, Operator: query.EQ, Value: v})
+			if err != nil {
+				return nil, err
+			}
+			iq.AddShould(vq)
+		}
+		q = iq.SetMinShould(1)
+	case query.BT:
+		r, ok := c.Value.(query.Range)
+		if !ok {
+			return nil, fmt.Errorf("missing range for field %s", c.Field)
+		}
+		rq, err := rToBq(c.Field, r)
+		if err != nil {
+			return nil, err
+		}
+		q = rq
+	case query.EX:
+		// text fields are matched by the wildcard, numeric and date fields by the full range
+		q = bluge.NewBooleanQuery().
+			AddShould(bluge.NewWildcardQuery("*").SetField(c.Field)).
+			AddShould(bluge.NewNumericRangeInclusiveQuery(bluge.MinNumeric, bluge.MaxNumeric, true, true).SetField(c.Field)).
+			SetMinShould(1)
+	case query.CI:
+		// text fields are analyzed in lower case, so this is already case insensitive
+		q = processWildcard(c)
 	default:
 		q = processWildcard(c)
 	}
@@ -111,6 +152,87 @@ func processWildcard(c query.Condition) (q bluge.Query) {
 	return q
 }
 
+// rToBq converting a range into a numeric or a date range query
+func rToBq(field string, r query.Range) (bluge.Query, error) {
+	f, ferr := vToFloat(r.From)
+	t, terr := vToFloat(r.To)
+	if ferr == nil && terr == nil {
+		return bluge.NewNumericRangeInclusiveQuery(f, t, true, true).SetField(field), nil
+	}
+	fd, ferr := time.Parse(time.RFC3339, fmt.Sprintf("%v", r.From))
+	td, terr := time.Parse(time.RFC3339, fmt.Sprintf("%v", r.To))
+	if ferr != nil || terr != nil {
+		return nil, fmt.Errorf("invalid range for field %s: %s", field, query.ValueToString(r))
+	}
+	return bluge.NewDateRangeInclusiveQuery(fd, td, true, true).SetField(field), nil
+}
+
+func vToFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
+
+// describe converting a bluge query into a json serializable structure
+func describe(bq bluge.Query) any {
+	switch v := bq.(type) {
+	case *bluge.BooleanQuery:
+		d := make(map[string]any)
+		if qs := v.Musts(); len(qs) > 0 {
+			d["must"] = describeAll(qs)
+		}
+		if qs := v.Shoulds(); len(qs) > 0 {
+			d["should"] = describeAll(qs)
+			d["minShould"] = v.MinShould()
+		}
+		if qs := v.MustNots(); len(qs) > 0 {
+			d["mustNot"] = describeAll(qs)
+		}
+		return map[string]any{"bool": d}
+	case *bluge.MatchQuery:
+		return map[string]any{"match": map[string]any{"field": v.Field(), "match": v.Match()}}
+	case *bluge.WildcardQuery:
+		return map[string]any{"wildcard": map[string]any{"field": v.Field(), "wildcard": v.Wildcard()}}
+	case *bluge.NumericRangeQuery:
+		d := map[string]any{"field": v.Field()}
+		if mn, inc := v.Min(); !math.IsInf(mn, 0) {
+			d["min"] = mn
+			d["minInclusive"] = inc
+		}
+		if mx, inc := v.Max(); !math.IsInf(mx, 0) {
+			d["max"] = mx
+			d["maxInclusive"] = inc
+		}
+		return map[string]any{"numericRange": d}
+	case *bluge.DateRangeQuery:
+		d := map[string]any{"field": v.Field()}
+		if st, inc := v.Start(); !st.IsZero() {
+			d["start"] = st
+			d["startInclusive"] = inc
+		}
+		if en, inc := v.End(); !en.IsZero() {
+			d["end"] = en
+			d["endInclusive"] = inc
+		}
+		return map[string]any{"dateRange": d}
+	}
+	return fmt.Sprintf("%T", bq)
+}
+
+func describeAll(qs []bluge.Query) []any {
+	ds := make([]any, len(qs))
+	for i, q := range qs {
+		ds[i] = describe(q)
+	}
+	return ds
+}
+
 func cToFloat(c query.Condition) (float64, error) {
 	switch v := c.Value.(type) {
 	case float64:
@@ -0,0 +1,245 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/api"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// DefaultBulkRate default count of blobs processed per second by a bulk job
+const DefaultBulkRate = 50
+
+var (
+	// ErrBulkRunning there is already a bulk job running for the tenant
+	ErrBulkRunning = errors.New("a bulk job is already running for this tenant")
+	// ErrNoBulk there is no bulk job running for the tenant
+	ErrNoBulk = errors.New("no bulk job running for this tenant")
+)
+
+// testing interface compatibility
+var _ interfaces.BulkOperator = &MainStorage{}
+
+// bulkRunner running an operation on all blobs matching a query in the background, throttled to a rate of blobs per second
+type bulkRunner struct {
+	jobRunner
+	m       *MainStorage
+	status  model.BulkJob
+	results []model.BulkResult
+}
+
+// StartBulk starting the bulk job of the request in the background.
+// The ids of the query are resolved before processing the first blob, so the job doesn't see blobs added later.
+func (m *MainStorage) StartBulk(req model.BulkRequest) (model.BulkJob, error) {
+	if err := checkBulkRequest(req); err != nil {
+		return model.BulkJob{}, err
+	}
+	req.Properties = propertyKeys(req.Properties)
+	bl := m.bulk
+	if bl.isRunning() {
+		return model.BulkJob{}, ErrBulkRunning
+	}
+
+	ids := make([]string, 0)
+	err := m.SearchBlobs(req.Query, func(id string) bool {
+		ids = append(ids, id)
+		return true
+	})
+	if err != nil {
+		return model.BulkJob{}, err
+	}
+	rate := req.Rate
+	if rate <= 0 {
+		rate = DefaultBulkRate
+	}
+
+	// a dry run doesn't load the storage, so no need to throttle
+	if req.DryRun {
+		rate = 0
+	}
+
+	var status model.BulkJob
+	ok := bl.start(func() {
+		bl.results = make([]model.BulkResult, 0, len(ids))
+		bl.status = model.BulkJob{
+			ID:        utils.GenerateID(),
+			Tenant:    m.Tenant,
+			Operation: req.Operation,
+			Query:     req.Query,
+			DryRun:    req.DryRun,
+			State:     model.BulkStateRunning,
+			Running:   true,
+			Started:   time.Now(),
+			Total:     len(ids),
+		}
+		status = bl.status
+	}, func(ctx context.Context) func() {
+		return bl.run(ctx, req, ids, rate)
+	})
+	if !ok {
+		return model.BulkJob{}, ErrBulkRunning
+	}
+	return status, nil
+}
+
+// checkBulkRequest checking the request before searching the blobs
+func checkBulkRequest(req model.BulkRequest) error {
+	if req.Query == "" {
+		return errors.New("no query given")
+	}
+	switch req.Operation {
+	case model.BulkOpDelete:
+	case model.BulkOpRetention:
+		if req.Retention <= 0 {
+			return errors.New("the retention must be greater than 0")
+		}
+		switch req.RetentionMode {
+		case "", model.RetentionModeCreation, model.RetentionModeLastAccess:
+		default:
+			return fmt.Errorf("unknown retention mode: %s", req.RetentionMode)
+		}
+	case model.BulkOpProperties:
+		if len(req.Properties) == 0 {
+			return errors.New("no properties given")
+		}
+	default:
+		return fmt.Errorf("unknown operation: %s", req.Operation)
+	}
+	return nil
+}
+
+// propertyKeys mapping the keys of the properties like the headers of an upload, with the header prefix and in the canonical form,
+// so the properties are found and delivered like the uploaded ones
+func propertyKeys(props map[string]any) map[string]any {
+	if len(props) == 0 {
+		return props
+	}
+	prefix := config.Get().HeaderMapping[api.HeaderPrefixKey]
+	res := make(map[string]any, len(props))
+	for k, v := range props {
+		if !strings.HasPrefix(strings.ToLower(k), strings.ToLower(prefix)) {
+			k = prefix + k
+		}
+		res[http.CanonicalHeaderKey(k)] = v
+	}
+	return res
+}
+
+// GetBulk getting the status of the actual or last bulk job
+func (m *MainStorage) GetBulk() (model.BulkJob, bool) {
+	bl := m.bulk
+	bl.jm.Lock()
+	defer bl.jm.Unlock()
+	return bl.status, bl.status.ID != ""
+}
+
+// GetBulkReport getting the results of the already processed blobs of the actual or last bulk job
+func (m *MainStorage) GetBulkReport() ([]model.BulkResult, bool) {
+	bl := m.bulk
+	bl.jm.Lock()
+	defer bl.jm.Unlock()
+	rs := make([]model.BulkResult, len(bl.results))
+	copy(rs, bl.results)
+	return rs, bl.status.ID != ""
+}
+
+// CancelBulk cancelling the running bulk job, the blob in work is finished
+func (m *MainStorage) CancelBulk() (model.BulkJob, error) {
+	if !m.bulk.cancelJob() {
+		return model.BulkJob{}, ErrNoBulk
+	}
+	st, _ := m.GetBulk()
+	return st, nil
+}
+
+// bulkBlob executing the operation of the request on a single blob, a dry run only checks the blob
+func (m *MainStorage) bulkBlob(req model.BulkRequest, id string) error {
+	b, err := m.StgSrv.GetBlobDescription(id)
+	if err != nil {
+		return err
+	}
+	if req.DryRun {
+		return nil
+	}
+	switch req.Operation {
+	case model.BulkOpDelete:
+		return m.DeleteBlobWithReason(id, model.ReasonBulk)
+	case model.BulkOpRetention:
+		b.Retention = req.Retention
+		if req.RetentionMode != "" {
+			b.RetentionMode = req.RetentionMode
+		}
+		err = m.UpdateBlobDescription(id, b)
+		if err != nil {
+			return err
+		}
+		if m.RtnMng != nil {
+			r := model.RetentionEntryFromBlobDescription(*b)
+			return m.RtnMng.AddRetention(m.Tenant, &r)
+		}
+		return nil
+	case model.BulkOpProperties:
+		if b.Properties == nil {
+			b.Properties = make(map[string]any)
+		}
+		for k, v := range req.Properties {
+			// a stored key may differ in the case
+			for sk := range b.Properties {
+				if strings.EqualFold(sk, k) {
+					k = sk
+					break
+				}
+			}
+			if v == nil {
+				delete(b.Properties, k)
+			} else {
+				b.Properties[k] = v
+			}
+		}
+		return m.UpdateBlobDescription(id, b)
+	}
+	return fmt.Errorf("unknown operation: %s", req.Operation)
+}
+
+// run processing the blobs, returning the function for the final status
+func (bl *bulkRunner) run(ctx context.Context, req model.BulkRequest, ids []string, rate int) func() {
+	state := model.BulkStateFinished
+	if !each(ctx, ids, rate, func(id string) {
+		err := bl.m.bulkBlob(req, id)
+		res := model.BulkResult{BlobID: id, Result: model.BulkResultDone}
+		if req.DryRun {
+			res.Result = model.BulkResultMatched
+		}
+		bl.jm.Lock()
+		defer bl.jm.Unlock()
+		bl.status.Processed++
+		if err != nil {
+			res.Result = model.BulkResultFailed
+			res.Error = err.Error()
+			bl.status.Failed++
+			bl.status.LastError = err.Error()
+		} else {
+			bl.status.Succeeded++
+		}
+		bl.results = append(bl.results, res)
+	}) {
+		state = model.BulkStateCancelled
+	}
+	return func() {
+		if state == model.BulkStateFinished && bl.status.Failed > 0 && bl.status.Succeeded == 0 {
+			state = model.BulkStateFailed
+		}
+		bl.status.State = state
+		bl.status.Running = false
+		bl.status.Finished = time.Now()
+		logger.Infof("main: tenant %s, bulk %s %s (dry run: %t) %s, succeeded %d, failed %d of %d", bl.status.Tenant, bl.status.ID, bl.status.Operation, bl.status.DryRun, state, bl.status.Succeeded, bl.status.Failed, bl.status.Total)
+	}
+}
@@ -0,0 +1,142 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// DefaultWarmRate default count of blobs loaded per second while warming the cache
+const DefaultWarmRate = 10
+
+var (
+	// ErrNoCache the tenant storage has no cache configured
+	ErrNoCache = errors.New("no cache configured")
+	// ErrWarmingRunning there is already a warming running for the tenant
+	ErrWarmingRunning = errors.New("a cache warming is already running for this tenant")
+	// ErrNoWarming there is no warming running for the tenant
+	ErrNoWarming = errors.New("no cache warming running for this tenant")
+)
+
+// testing interface compatibility
+var _ interfaces.CacheWarmer = &MainStorage{}
+
+// cacheWarmer pre-loading blobs into the cache in the background, throttled to a rate of blobs per second
+type cacheWarmer struct {
+	jobRunner
+	m      *MainStorage
+	status model.CacheWarming
+}
+
+// WarmBlob loading a single blob from the storage into the cache, returning true if the blob was not in the cache before
+func (m *MainStorage) WarmBlob(id string) (bool, error) {
+	if m.CchSrv == nil {
+		return false, ErrNoCache
+	}
+	b, err := m.StgSrv.GetBlobDescription(id)
+	if err != nil {
+		return false, err
+	}
+	return m.cacheFile(b)
+}
+
+// StartWarming starting the warming of the cache with the blobs of the request in the background.
+// The ids of a query are resolved before loading the first blob.
+func (m *MainStorage) StartWarming(req model.CacheWarmRequest) (model.CacheWarming, error) {
+	if m.CchSrv == nil {
+		return model.CacheWarming{}, ErrNoCache
+	}
+	if len(req.IDs) == 0 && req.Query == "" {
+		return model.CacheWarming{}, errors.New("no ids or query given")
+	}
+	w := m.warmer
+	if w.isRunning() {
+		return model.CacheWarming{}, ErrWarmingRunning
+	}
+
+	ids := req.IDs
+	if req.Query != "" {
+		ids = make([]string, 0)
+		err := m.SearchBlobs(req.Query, func(id string) bool {
+			ids = append(ids, id)
+			return true
+		})
+		if err != nil {
+			return model.CacheWarming{}, err
+		}
+	}
+	rate := req.Rate
+	if rate <= 0 {
+		rate = DefaultWarmRate
+	}
+
+	var status model.CacheWarming
+	ok := w.start(func() {
+		w.status = model.CacheWarming{
+			ID:      utils.GenerateID(),
+			Tenant:  m.Tenant,
+			State:   model.WarmingStateRunning,
+			Running: true,
+			Started: time.Now(),
+			Total:   len(ids),
+		}
+		status = w.status
+	}, func(ctx context.Context) func() {
+		return w.run(ctx, ids, rate)
+	})
+	if !ok {
+		return model.CacheWarming{}, ErrWarmingRunning
+	}
+	return status, nil
+}
+
+// GetWarming getting the status of the actual or last warming of the cache
+func (m *MainStorage) GetWarming() (model.CacheWarming, bool) {
+	w := m.warmer
+	w.jm.Lock()
+	defer w.jm.Unlock()
+	return w.status, w.status.ID != ""
+}
+
+// CancelWarming cancelling the running warming of the cache, the blob in work is finished
+func (m *MainStorage) CancelWarming() (model.CacheWarming, error) {
+	if !m.warmer.cancelJob() {
+		return model.CacheWarming{}, ErrNoWarming
+	}
+	st, _ := m.GetWarming()
+	return st, nil
+}
+
+// run loading the blobs, returning the function for the final status
+func (w *cacheWarmer) run(ctx context.Context, ids []string, rate int) func() {
+	state := model.WarmingStateFinished
+	if !each(ctx, ids, rate, func(id string) {
+		loaded, err := w.m.WarmBlob(id)
+		w.jm.Lock()
+		defer w.jm.Unlock()
+		switch {
+		case err != nil:
+			w.status.Failed++
+			w.status.LastError = err.Error()
+		case loaded:
+			w.status.Loaded++
+		default:
+			w.status.Skipped++
+		}
+	}) {
+		state = model.WarmingStateCancelled
+	}
+	return func() {
+		if state == model.WarmingStateFinished && w.status.Failed > 0 && w.status.Loaded+w.status.Skipped == 0 {
+			state = model.WarmingStateFailed
+		}
+		w.status.State = state
+		w.status.Running = false
+		w.status.Finished = time.Now()
+		logger.Infof("main: tenant %s, cache warming %s %s, loaded %d, skipped %d, failed %d", w.status.Tenant, w.status.ID, state, w.status.Loaded, w.status.Skipped, w.status.Failed)
+	}
+}
@@ -0,0 +1,80 @@
+package business
+
+import (
+	"io"
+
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// keys of the coalesced backend operations, only one operation per key and blob is in flight
+const (
+	flightCache   = "cache:"
+	flightDesc    = "desc:"
+	flightRestore = "restore:"
+)
+
+// clientWriter the writer of a reader streamed while filling the cache. After the first error all writes are ignored,
+// so a gone client is not breaking the cache fill.
+type clientWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *clientWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	if c.err == nil {
+		_, c.err = c.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// retrieveCoalesced retrieving a blob not in the cache. Only one reader per blob is reading from the storage, filling the cache
+// and getting the blob streamed at the same time. The other readers are waiting for the fill and are delivered from the cache.
+// Returning false, if the blob couldn't be delivered this way, so the reader should try the storage.
+func (m *MainStorage) retrieveCoalesced(id string, w io.Writer) (bool, error) {
+	b, err := m.loadDescription(id)
+	if err != nil {
+		return false, nil
+	}
+	cw := &clientWriter{w: w}
+	leader := false
+	v, err, _ := m.flight.Do(flightCache+id, func() (any, error) {
+		leader = true
+		return m.fillCache(b, cw)
+	})
+	if leader {
+		loaded, _ := v.(bool)
+		if cw.n > 0 || loaded {
+			if err == nil {
+				err = cw.err
+			}
+			return true, err
+		}
+		if err != nil {
+			return false, nil
+		}
+	}
+	return m.retrieveFromCache(id, w, interfaces.CacheDefault), nil
+}
+
+// loadDescription loading the description from the storage, concurrent loads of the same description are coalesced
+func (m *MainStorage) loadDescription(id string) (*model.BlobDescription, error) {
+	v, err, shared := m.flight.Do(flightDesc+id, func() (any, error) {
+		return m.StgSrv.GetBlobDescription(id)
+	})
+	b, _ := v.(*model.BlobDescription)
+	if err != nil || !shared || b == nil {
+		return b, err
+	}
+	// every reader gets its own copy
+	bd := *b
+	if b.Properties != nil {
+		bd.Properties = make(map[string]any, len(b.Properties))
+		for k, p := range b.Properties {
+			bd.Properties[k] = p
+		}
+	}
+	return &bd, nil
+}
@@ -0,0 +1,101 @@
+package business
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+)
+
+// jobRunner running one background job of a tenant at a time, e.g. a cache warming or a bulk job.
+// The mutex is guarding the runner and the status of the job.
+type jobRunner struct {
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	jm      sync.Mutex
+}
+
+// isRunning checking if a job is running
+func (j *jobRunner) isRunning() bool {
+	j.jm.Lock()
+	defer j.jm.Unlock()
+	return j.running
+}
+
+// start starting the job in the background, returning false if a job is already running.
+// init is called under the lock before the job starts, the finish function returned by the job is called under the lock after the job.
+func (j *jobRunner) start(init func(), job func(ctx context.Context) func()) bool {
+	j.jm.Lock()
+	defer j.jm.Unlock()
+	if j.running {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j.running = true
+	j.cancel = cancel
+	j.done = make(chan struct{})
+	init()
+	go func(done chan struct{}) {
+		defer close(done)
+		defer cancel()
+		finish := job(ctx)
+		j.jm.Lock()
+		defer j.jm.Unlock()
+		j.running = false
+		finish()
+	}(j.done)
+	return true
+}
+
+// cancelJob cancelling the running job and waiting for its end, returning false if no job is running
+func (j *jobRunner) cancelJob() bool {
+	j.jm.Lock()
+	if !j.running {
+		j.jm.Unlock()
+		return false
+	}
+	j.cancel()
+	j.jm.Unlock()
+	j.wait()
+	return true
+}
+
+// stop cancelling a running job and waiting for its end
+func (j *jobRunner) stop() {
+	j.cancelJob()
+}
+
+func (j *jobRunner) wait() {
+	j.jm.Lock()
+	done := j.done
+	j.jm.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// each calling the function for every id, throttled to the rate of ids per second, with a rate <= 0 unthrottled.
+// Returning false, if the job was cancelled.
+func each(ctx context.Context, ids []string, rate int, f func(id string)) bool {
+	var tick <-chan time.Time
+	if rate > 0 {
+		t := time.NewTicker(utils.RateInterval(rate))
+		defer t.Stop()
+		tick = t.C
+	}
+	for _, id := range ids {
+		if tick != nil {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-tick:
+			}
+		} else if ctx.Err() != nil {
+			return false
+		}
+		f(id)
+	}
+	return true
+}
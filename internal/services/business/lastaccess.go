@@ -0,0 +1,150 @@
+package business
+
+import (
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// default values for the write behind of the last access
+const (
+	defaultLAFlushInterval = 10 * time.Second
+	defaultLABatchSize     = 1000
+)
+
+// lastAccessWriter collecting the last access time stamps of the blobs and writing them in batches to the storage,
+// so reading a blob will not result in a write to the storage
+type lastAccessWriter struct {
+	m             *MainStorage
+	flushInterval time.Duration
+	batchSize     int
+	pending       map[string]int64
+	pm            sync.Mutex
+	fm            sync.Mutex
+	kick          chan struct{} // signaling a full batch to the background task
+	quit          chan struct{}
+	done          chan struct{}
+	once          sync.Once
+}
+
+func newLastAccessWriter(m *MainStorage, flushInterval time.Duration, batchSize int) *lastAccessWriter {
+	if flushInterval <= 0 {
+		flushInterval = defaultLAFlushInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultLABatchSize
+	}
+	l := &lastAccessWriter{
+		m:             m,
+		flushInterval: flushInterval,
+		batchSize:     batchSize,
+		pending:       make(map[string]int64),
+		kick:          make(chan struct{}, 1),
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// run the background task, flushing in the interval or if a batch is full, until the writer is closed
+func (l *lastAccessWriter) run() {
+	defer close(l.done)
+	tick := time.NewTicker(l.flushInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			l.flush()
+		case <-l.kick:
+			l.flush()
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// touch registering an access of the blob
+func (l *lastAccessWriter) touch(id string, ts int64) {
+	l.pm.Lock()
+	if l.pending[id] < ts {
+		l.pending[id] = ts
+	}
+	full := len(l.pending) >= l.batchSize
+	l.pm.Unlock()
+	if full {
+		// a flush is already signaled, if the channel is full
+		select {
+		case l.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// get the pending last access of a blob, if present
+func (l *lastAccessWriter) get(id string) (int64, bool) {
+	l.pm.Lock()
+	defer l.pm.Unlock()
+	ts, ok := l.pending[id]
+	return ts, ok
+}
+
+// remove a pending last access, e.g. if the blob is deleted
+func (l *lastAccessWriter) remove(id string) {
+	l.pm.Lock()
+	defer l.pm.Unlock()
+	delete(l.pending, id)
+}
+
+// flush writing all pending last access time stamps to the storage
+func (l *lastAccessWriter) flush() {
+	l.fm.Lock()
+	defer l.fm.Unlock()
+	l.pm.Lock()
+	batch := l.pending
+	l.pending = make(map[string]int64)
+	l.pm.Unlock()
+	for id, ts := range batch {
+		if err := l.m.writeLastAccess(id, ts); err != nil {
+			logger.Debugf("main: last access: can't update blob: %s, %v", id, err)
+		}
+	}
+}
+
+// close stopping the background task and flushing all pending entries, closing twice is possible
+func (l *lastAccessWriter) close() {
+	l.once.Do(func() {
+		close(l.quit)
+	})
+	<-l.done
+	l.flush()
+}
+
+// writeLastAccess writing the last access to the description of the blob and, for sliding retentions, to the retention entry.
+// The description is updated on all storages and the index, like every other change of the description.
+func (m *MainStorage) writeLastAccess(id string, ts int64) error {
+	unlock := m.locks.Lock(id)
+	defer unlock()
+	bd, err := m.StgSrv.GetBlobDescription(id)
+	if err != nil {
+		return err
+	}
+	if bd.LastAccess >= ts {
+		return nil
+	}
+	bd.LastAccess = ts
+	err = m.updateDescription(id, bd)
+	if err != nil {
+		return err
+	}
+	if bd.RetentionMode == model.RetentionModeLastAccess {
+		r, err := m.StgSrv.GetRetention(id)
+		if err != nil {
+			return err
+		}
+		r.LastAccess = ts
+		return m.StgSrv.AddRetention(&r)
+	}
+	return nil
+}
@@ -11,11 +11,20 @@ import (
 	"time"
 
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
 	"github.com/willie68/GoBlobStore/pkg/model"
+	"github.com/willie68/GoBlobStore/pkg/model/query"
+	"golang.org/x/sync/singleflight"
 )
 
 // testing interface compatibility
-var _ interfaces.BlobStorage = &MainStorage{}
+var (
+	_ interfaces.BlobStorage    = &MainStorage{}
+	_ interfaces.AuditDeleter   = &MainStorage{}
+	_ interfaces.HintedStorage  = &MainStorage{}
+	_ interfaces.QueryExplainer = &MainStorage{}
+	_ interfaces.BlobScrubber   = &MainStorage{}
+)
 
 // MainStorage the main service for the business rules
 type MainStorage struct {
@@ -26,10 +35,20 @@ type MainStorage struct {
 	IdxSrv      interfaces.Index
 	TntBckSrv   interfaces.BlobStorage
 	TntMgr      interfaces.TenantManager
+	Audit       interfaces.AuditJournal
 	Bcksyncmode bool
+	AutoRepair  bool // repairing unhealthy blobs found by a check
 	Tenant      string
 	hasIdx      bool
 	TntError    error
+	// write behind of the last access, flush interval and batch size, 0 for defaults
+	LAFlushInterval time.Duration
+	LABatchSize     int
+	law             *lastAccessWriter
+	warmer          *cacheWarmer
+	bulk            *bulkRunner
+	flight          singleflight.Group // coalescing concurrent backend operations of the same blob
+	locks           utils.IDLocks      // locking a blob for read-modify-write changes of the description
 }
 
 // Init initialize this service
@@ -37,9 +56,33 @@ func (m *MainStorage) Init() error {
 	// all storages should be initialized before adding to this business class
 	// there for only specific initialization for this class is required
 	m.hasIdx = m.IdxSrv != nil
+	if m.law != nil {
+		// initialized again, the old background task should not keep running
+		m.law.close()
+	}
+	m.law = newLastAccessWriter(m, m.LAFlushInterval, m.LABatchSize)
+	m.warmer = &cacheWarmer{m: m}
+	m.bulk = &bulkRunner{m: m}
+	if ts, ok := m.StgSrv.(interfaces.TieredStorage); ok {
+		ts.SetMoveListener(m.tierMoved)
+	}
 	return nil
 }
 
+// tierMoved a blob has been moved to another tier, so index and cache should know the new tier
+func (m *MainStorage) tierMoved(id string, b *model.BlobDescription) {
+	if m.hasIdx {
+		if err := m.IdxSrv.Index(id, *b); err != nil {
+			logger.Errorf("main: tier moved: error indexing blob: %s, %v", id, err)
+		}
+	}
+	if m.CchSrv != nil {
+		if ok, _ := m.CchSrv.HasBlob(id); ok {
+			m.CchSrv.UpdateBlobDescription(id, b)
+		}
+	}
+}
+
 // GetTenant return the id of the tenant
 func (m *MainStorage) GetTenant() string {
 	return m.Tenant
@@ -100,6 +143,13 @@ func (m *MainStorage) StoreBlob(b *model.BlobDescription, f io.Reader) (string,
 
 // UpdateBlobDescription updating the blob description
 func (m *MainStorage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
+	unlock := m.locks.Lock(id)
+	defer unlock()
+	return m.updateDescription(id, b)
+}
+
+// updateDescription updating the description on all storages and the index, the blob should be locked
+func (m *MainStorage) updateDescription(id string, b *model.BlobDescription) error {
 	err := m.StgSrv.UpdateBlobDescription(id, b)
 	if err != nil {
 		return err
@@ -144,28 +194,55 @@ func (m *MainStorage) cacheFileByID(id string) {
 	}
 }
 
-func (m *MainStorage) cacheFile(b *model.BlobDescription) {
-	if m.CchSrv != nil {
-		ok, err := m.CchSrv.HasBlob(b.BlobID)
+// cacheFile loading the blob into the cache, returning true if the blob was not in the cache before.
+// Concurrent fills of the same blob are coalesced, so only one read of the storage is in flight.
+func (m *MainStorage) cacheFile(b *model.BlobDescription) (bool, error) {
+	if m.CchSrv == nil {
+		return false, nil
+	}
+	v, err, _ := m.flight.Do(flightCache+b.BlobID, func() (any, error) {
+		return m.fillCache(b, nil)
+	})
+	loaded, _ := v.(bool)
+	return loaded, err
+}
+
+// fillCache reading the blob from the storage into the cache, with a writer the blob is streamed to it at the same time
+func (m *MainStorage) fillCache(b *model.BlobDescription, w io.Writer) (bool, error) {
+	ok, err := m.CchSrv.HasBlob(b.BlobID)
+	if err != nil {
+		logger.Errorf("main: cacheFile: check blob: %s, %v", b.BlobID, err)
+		return false, err
+	}
+	if ok {
+		return false, nil
+	}
+	rd, wr := io.Pipe()
+	go func() {
+		err := m.StgSrv.RetrieveBlob(b.BlobID, wr)
 		if err != nil {
-			logger.Errorf("main: cacheFile: check blob: %s, %v", b.BlobID, err)
-			return
+			logger.Errorf("main: cacheFile: retrieve, error getting blob: %s, %v", b.BlobID, err)
 		}
-		if !ok {
-			rd, wr := io.Pipe()
-			go func() {
-				defer wr.Close()
-				if err := m.StgSrv.RetrieveBlob(b.BlobID, wr); err != nil {
-					logger.Errorf("main: cacheFile: retrieve, error getting blob: %s, %v", b.BlobID, err)
-				}
-				// close the writer, so the reader knows there's no more data
-			}()
-			defer rd.Close()
-			if _, err := m.CchSrv.StoreBlob(b, rd); err != nil {
-				logger.Errorf("main: cacheFile: store, error getting blob: %s, %v", b.BlobID, err)
+		// close the writer, so the reader knows there's no more data, an error prevents an incomplete blob in the cache
+		wr.CloseWithError(err)
+	}()
+	defer rd.Close()
+	var r io.Reader = rd
+	if w != nil {
+		r = io.TeeReader(rd, w)
+	}
+	if _, err := m.CchSrv.StoreBlob(b, r); err != nil {
+		logger.Errorf("main: cacheFile: store, error getting blob: %s, %v", b.BlobID, err)
+		if w != nil {
+			// the reader should get the blob anyway, an error of the storage is returned again
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				return false, err
 			}
+			return false, nil
 		}
+		return false, err
 	}
+	return true, nil
 }
 
 func (m *MainStorage) tntBackupFile(b *model.BlobDescription, id string) {
@@ -205,6 +282,21 @@ func (m *MainStorage) bckFile(srv interfaces.BlobStorage, b *model.BlobDescripti
 }
 
 func (m *MainStorage) restoreFile(b *model.BlobDescription) {
+	m.restoreFileWithHint(b, interfaces.CacheDefault)
+}
+
+// restoreFileWithHint restoring the blob from the backup to the storage, concurrent restores of the same blob are coalesced
+func (m *MainStorage) restoreFileWithHint(b *model.BlobDescription, hint interfaces.CacheHint) {
+	if m.BckSrv == nil {
+		return
+	}
+	_, _, _ = m.flight.Do(flightRestore+b.BlobID, func() (any, error) {
+		m.restore(b, hint)
+		return nil, nil
+	})
+}
+
+func (m *MainStorage) restore(b *model.BlobDescription, hint interfaces.CacheHint) {
 	if m.BckSrv != nil {
 		id := b.BlobID
 		ok, err := m.BckSrv.HasBlob(id)
@@ -225,7 +317,9 @@ func (m *MainStorage) restoreFile(b *model.BlobDescription) {
 			if _, err := m.StgSrv.StoreBlob(b, rd); err != nil {
 				logger.Errorf("main: restoreFile: store, error getting blob: %s, %v", id, err)
 			}
-			go m.cacheFileByID(id)
+			if hint != interfaces.CacheBypass {
+				go m.cacheFileByID(id)
+			}
 		}
 	}
 }
@@ -277,42 +371,81 @@ func (m *MainStorage) GetBlobDescription(id string) (*model.BlobDescription, err
 		b, err := m.CchSrv.GetBlobDescription(id)
 		if err == nil {
 			if b.TenantID == m.Tenant {
-				return b, nil
+				return m.withLastAccess(b), nil
 			}
 		}
 	}
-	b, err := m.StgSrv.GetBlobDescription(id)
+	b, err := m.loadDescription(id)
 	if err != nil {
 		if m.BckSrv != nil {
 			bb, berr := m.BckSrv.GetBlobDescription(id)
 			if berr == nil {
 				go m.restoreFile(bb)
-				return bb, nil
+				return m.withLastAccess(bb), nil
 			}
 		}
+		return b, err
+	}
+	return m.withLastAccess(b), nil
+}
+
+// withLastAccess adding a not yet written last access to the description
+func (m *MainStorage) withLastAccess(b *model.BlobDescription) *model.BlobDescription {
+	if m.law != nil && b != nil {
+		if ts, ok := m.law.get(b.BlobID); ok && ts > b.LastAccess {
+			b.LastAccess = ts
+		}
+	}
+	return b
+}
+
+// touch marking the blob as accessed, the last access will be written behind
+func (m *MainStorage) touch(id string) {
+	if m.law != nil {
+		m.law.touch(id, time.Now().UnixMilli())
 	}
-	return b, err
 }
 
 // RetrieveBlob retrieving the binary data from the storage system
 func (m *MainStorage) RetrieveBlob(id string, w io.Writer) error {
+	return m.RetrieveBlobWithHint(id, w, interfaces.CacheDefault)
+}
+
+// RetrieveBlobWithHint retrieving the binary data from the storage system, with CacheBypass a blob found in the cache is delivered from there,
+// but a blob not found in the cache is not added to it
+func (m *MainStorage) RetrieveBlobWithHint(id string, w io.Writer, hint interfaces.CacheHint) error {
 	// check cache
-	ok := m.retrieveFromCache(id, w)
+	ok := m.retrieveFromCache(id, w, hint)
 	if ok {
+		m.touch(id)
 		return nil
 	}
+	if m.CchSrv != nil && hint != interfaces.CacheBypass {
+		ok, err := m.retrieveCoalesced(id, w)
+		if ok {
+			// a failed read is no access
+			if err == nil {
+				m.touch(id)
+			}
+			return err
+		}
+	}
 
 	err := m.StgSrv.RetrieveBlob(id, w)
 	if err == nil {
-		go m.cacheFileByID(id)
+		m.touch(id)
+		if hint != interfaces.CacheBypass {
+			go m.cacheFileByID(id)
+		}
 		return nil
 	}
 
 	if m.BckSrv != nil {
 		berr := m.BckSrv.RetrieveBlob(id, w)
 		if berr == nil {
+			m.touch(id)
 			if bb, berr := m.BckSrv.GetBlobDescription(id); berr == nil {
-				go m.restoreFile(bb)
+				go m.restoreFileWithHint(bb, hint)
 			}
 			return nil
 		}
@@ -321,31 +454,62 @@ func (m *MainStorage) RetrieveBlob(id string, w io.Writer) error {
 	return nil
 }
 
-func (m *MainStorage) retrieveFromCache(id string, w io.Writer) bool {
+// entryCache a cache, which is giving the info of a blob without counting an access
+type entryCache interface {
+	CacheEntry(id string) (model.CacheEntry, bool)
+}
+
+func (m *MainStorage) retrieveFromCache(id string, w io.Writer, hint interfaces.CacheHint) bool {
 	if m.CchSrv != nil {
-		if ok, _ := m.CchSrv.HasBlob(id); ok {
-			b, err := m.CchSrv.GetBlobDescription(id)
-			if err == nil && b.TenantID == m.Tenant {
-				err := m.CchSrv.RetrieveBlob(id, w)
-				if err == nil {
-					return true
-				}
-			}
+		if tenant, ok := m.cacheTenant(id, hint); ok && tenant != m.Tenant {
+			return false
 		}
+		// a blob not in the cache is counted as a miss of the cache, a bypass isn't counted at all
+		return utils.RetrieveBlob(m.CchSrv, id, w, hint) == nil
 	}
 	return false
 }
 
+// cacheTenant getting the tenant of a cached blob, with CacheBypass without counting an access, if the cache supports it
+func (m *MainStorage) cacheTenant(id string, hint interfaces.CacheHint) (string, bool) {
+	if ec, ok := m.CchSrv.(entryCache); ok && hint == interfaces.CacheBypass {
+		ce, ok := ec.CacheEntry(id)
+		return ce.TenantID, ok
+	}
+	b, err := m.CchSrv.GetBlobDescription(id)
+	if err != nil {
+		return "", false
+	}
+	return b.TenantID, true
+}
+
 // DeleteBlob removing a blob from the storage system
 func (m *MainStorage) DeleteBlob(id string) error {
+	return m.DeleteBlobWithReason(id, model.ReasonAPI)
+}
+
+// DeleteBlobWithReason removing a blob from the storage system, recording the deletion into the audit journal
+func (m *MainStorage) DeleteBlobWithReason(id string, reason model.DeletionReason) error {
+	unlock := m.locks.Lock(id)
+	defer unlock()
 	bd, err := m.StgSrv.GetBlobDescription(id)
 	if err != nil {
 		return err
 	}
+	// the audit entry must be written before the deletion, otherwise there is no proof
+	if m.Audit != nil {
+		err = m.Audit.Append(m.Tenant, model.AuditEntryFromBlobDescription(*bd, reason))
+		if err != nil {
+			return fmt.Errorf("main: delete blob: audit: %s, %v", id, err)
+		}
+	}
 	err = m.StgSrv.DeleteBlob(id)
 	if err != nil {
 		return err
 	}
+	if m.law != nil {
+		m.law.remove(id)
+	}
 	go m.subStorageSize(bd)
 	if m.BckSrv != nil {
 		if err = m.BckSrv.DeleteBlob(id); err != nil {
@@ -376,8 +540,54 @@ func (m *MainStorage) SearchBlobs(q string, callback func(id string) bool) error
 	return nil
 }
 
+// ExplainQuery getting the native query, the index of the tenant would run for the parsed query
+func (m *MainStorage) ExplainQuery(q query.Query) (string, any, error) {
+	if !m.hasIdx {
+		return "", nil, errors.New("index not configured")
+	}
+	ex, ok := m.IdxSrv.(interfaces.QueryExplainer)
+	if !ok {
+		return "", nil, errors.New("index can't explain queries")
+	}
+	return ex.ExplainQuery(q)
+}
+
 // CheckBlob checking a single blob from the storage system
 func (m *MainStorage) CheckBlob(id string) (*model.CheckInfo, error) {
+	bd, err := m.ScrubBlob(id)
+	if err != nil {
+		return nil, err
+	}
+	return bd.Check.Store, nil
+}
+
+// ScrubBlob checking the hash of a single blob on the main and the backup storage, the merged result is written into the blob descriptions.
+// With auto repair an unhealthy blob is repaired and checked again.
+func (m *MainStorage) ScrubBlob(id string) (*model.BlobDescription, error) {
+	bd, err := m.checkBlob(id)
+	if !m.AutoRepair || (err == nil && bd.Check.Healthy) {
+		return bd, err
+	}
+	repaired, rerr := m.RepairBlob(id)
+	if repaired != "" {
+		bd, err = m.checkBlob(id)
+	}
+	if err != nil {
+		if rerr != nil {
+			return nil, rerr
+		}
+		return nil, err
+	}
+	bd.Check.Repaired = repaired
+	if rerr != nil {
+		bd.Check.Action = rerr.Error()
+	}
+	m.writeCheck(id, bd)
+	return bd, nil
+}
+
+// checkBlob checking the hash of a single blob on the main and the backup storage
+func (m *MainStorage) checkBlob(id string) (*model.BlobDescription, error) {
 	// check blob on main storage
 	stgCI, err := m.StgSrv.CheckBlob(id)
 	if err != nil {
@@ -398,43 +608,61 @@ func (m *MainStorage) CheckBlob(id string) (*model.CheckInfo, error) {
 		m.checkBck(id, &ri, bd)
 	}
 	bd.Check = &ri
+	m.writeCheck(id, bd)
+	return bd, nil
+}
+
+// writeCheck writing the description with the check result into the main storage and the cache
+func (m *MainStorage) writeCheck(id string, bd *model.BlobDescription) {
 	m.StgSrv.UpdateBlobDescription(id, bd)
-	return stgCI, nil
+	if m.CchSrv != nil {
+		m.CchSrv.UpdateBlobDescription(id, bd)
+	}
 }
 
 func (m *MainStorage) checkBck(id string, ri *model.Check, bd *model.BlobDescription) {
 	bckDI, err := m.BckSrv.CheckBlob(id)
 	if err != nil {
 		logger.Errorf("error checking blob on backup: %v", err)
+		ri.Healthy = false
+		ri.Message = joinMessage(ri.Message, fmt.Sprintf("backup: %v", err))
+		return
 	}
 	bckBd, err := m.BckSrv.GetBlobDescription(id)
 	if err != nil {
 		logger.Errorf("error getting blob description on backup: %v", err)
+		ri.Healthy = false
+		ri.Message = joinMessage(ri.Message, fmt.Sprintf("backup: %v", err))
+		return
 	}
 	// merge stgCI and bckCI
 	ri.Backup = bckDI
 	ri.Healthy = ri.Healthy && bckDI.Healthy
-	msg := bckDI.Message
-	if ri.Message != "" && msg != "" {
-		msg = fmt.Sprintf("%s, %s", ri.Message, msg)
-	}
-	if msg != "" {
-		ri.Message = msg
-	}
+	ri.Message = joinMessage(ri.Message, bckDI.Message)
 
-	// checking if both hashes are equal
+	// checking if both hashes and descriptions are equal
 	if bd.Hash != bckBd.Hash {
 		ri.Healthy = false
-		msg := "hashes are not equal"
-		if ri.Message != "" {
-			msg = fmt.Sprintf("%s, %s", ri.Message, msg)
-		}
-		ri.Message = msg
+		ri.Message = joinMessage(ri.Message, "hashes are not equal")
+	} else if !sameDescription(bd, bckBd) {
+		ri.Healthy = false
+		ri.Message = joinMessage(ri.Message, "descriptions are not equal")
 	}
 	bckBd.Check = ri
 	m.BckSrv.UpdateBlobDescription(id, bckBd)
 }
 
+// joinMessage appending a message of a check to the former messages
+func joinMessage(msg, add string) string {
+	if msg == "" {
+		return add
+	}
+	if add == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s, %s", msg, add)
+}
+
 // GetAllRetentions for every retention entry for this Tenant we call this this function, you can stop the listing by returning a false
 func (m *MainStorage) GetAllRetentions(callback func(r model.RetentionEntry) bool) error {
 	return m.StgSrv.GetAllRetentions(callback)
@@ -485,6 +713,16 @@ func (m *MainStorage) GetLastError() error {
 
 // Close closing the blob storage
 func (m *MainStorage) Close() error {
+	if m.warmer != nil {
+		m.warmer.stop()
+	}
+	if m.bulk != nil {
+		m.bulk.stop()
+	}
+	if m.law != nil {
+		m.law.close()
+		m.law = nil
+	}
 	err := m.StgSrv.Close()
 	if m.BckSrv != nil {
 		if err1 := m.BckSrv.Close(); err1 != nil {
@@ -3,16 +3,22 @@ package business
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/audit"
 	"github.com/willie68/GoBlobStore/internal/services/fastcache"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
 	"github.com/willie68/GoBlobStore/internal/services/simplefile"
@@ -404,3 +410,560 @@ func TestAutoRestoreByHasId(t *testing.T) {
 	ast.Nil(err)
 	ast.True(ok)
 }
+
+func TestDeleteWithAudit(t *testing.T) {
+	clear(t)
+	initTest(t)
+	ast := assert.New(t)
+
+	jnl := &audit.FileJournal{
+		RootPath: filepath.Join(rootFilePrefix, "audit"),
+		Node:     "node01",
+	}
+	ast.Nil(jnl.Init())
+	bMain, ok := main.(*MainStorage)
+	ast.True(ok)
+	bMain.Audit = jnl
+
+	b1, err := createBlob(ast, "01")
+	ast.Nil(err)
+	b2, err := createBlob(ast, "02")
+	ast.Nil(err)
+
+	ast.Nil(bMain.DeleteBlob(b1.BlobID))
+	ast.Nil(bMain.DeleteBlobWithReason(b2.BlobID, model.ReasonRetention))
+
+	entries := make([]model.AuditEntry, 0)
+	err = jnl.Query(tenant, 0, 0, func(e model.AuditEntry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(2, len(entries))
+	ast.Equal(b1.BlobID, entries[0].BlobID)
+	ast.Equal(model.ReasonAPI, entries[0].Reason)
+	ast.Equal(b1.Hash, entries[0].Hash)
+	ast.Equal(b1.Filename, entries[0].Filename)
+	ast.Equal(b2.BlobID, entries[1].BlobID)
+	ast.Equal(model.ReasonRetention, entries[1].Reason)
+	ast.Equal("node01", entries[1].Node)
+}
+
+func TestLastAccessWriteBehind(t *testing.T) {
+	clear(t)
+	initTest(t)
+	ast := assert.New(t)
+
+	bMain, ok := main.(*MainStorage)
+	ast.True(ok)
+	bMain.Bcksyncmode = true
+
+	old := time.Now().Add(-1 * time.Hour).UnixMilli()
+	b := createBlobDescription("01")
+	b.Retention = 10
+	b.RetentionMode = model.RetentionModeLastAccess
+	payload := "this is a blob content of 01"
+	b.ContentLength = int64(len(payload))
+	id, err := main.StoreBlob(&b, strings.NewReader(payload))
+	ast.Nil(err)
+	// simulate a blob, which was not used for a long time
+	b.LastAccess = old
+	b.CreationDate = old
+	ast.Nil(bMain.StgSrv.UpdateBlobDescription(id, &b))
+	r := model.RetentionEntryFromBlobDescription(b)
+	ast.Nil(bMain.StgSrv.AddRetention(&r))
+	ast.True(r.GetRetentionTimestampMS() < time.Now().UnixMilli())
+
+	var buf bytes.Buffer
+	ast.Nil(main.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.String())
+
+	// not written yet, but visible
+	sb, err := bMain.StgSrv.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(old, sb.LastAccess)
+	mb, err := main.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.True(mb.LastAccess > old)
+
+	bMain.law.flush()
+
+	sb, err = bMain.StgSrv.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.True(sb.LastAccess > old)
+	r, err = bMain.StgSrv.GetRetention(id)
+	ast.Nil(err)
+	ast.Equal(sb.LastAccess, r.LastAccess)
+	ast.True(r.GetRetentionTimestampMS() > time.Now().UnixMilli())
+	// the backup gets the last access as well
+	bb, err := bMain.BckSrv.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(sb.LastAccess, bb.LastAccess)
+
+	ast.Nil(main.Close())
+}
+
+func TestLastAccessBatchFull(t *testing.T) {
+	clear(t)
+	initTest(t)
+	ast := assert.New(t)
+
+	bMain, ok := main.(*MainStorage)
+	ast.True(ok)
+	bMain.LAFlushInterval = time.Hour
+	bMain.LABatchSize = 2
+	ast.Nil(bMain.Init())
+
+	bs := make([]model.BlobDescription, 0)
+	for x := 0; x < 2; x++ {
+		b, err := createBlob(ast, strconv.Itoa(x))
+		ast.Nil(err)
+		bs = append(bs, b)
+	}
+	old := time.Now().Add(-1 * time.Hour).UnixMilli()
+	for _, b := range bs {
+		b.LastAccess = old
+		ast.Nil(bMain.StgSrv.UpdateBlobDescription(b.BlobID, &b))
+		// many touches of a full batch are only signaling the background task
+		for x := 0; x < 100; x++ {
+			bMain.touch(b.BlobID)
+		}
+	}
+
+	// a full batch is flushed without waiting for the interval
+	for _, b := range bs {
+		var sb *model.BlobDescription
+		var err error
+		for x := 0; x < 100; x++ {
+			sb, err = bMain.StgSrv.GetBlobDescription(b.BlobID)
+			ast.Nil(err)
+			if sb.LastAccess > old {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		ast.True(sb.LastAccess > old)
+	}
+
+	// closing twice is possible
+	ast.Nil(main.Close())
+	bMain.law = newLastAccessWriter(bMain, time.Hour, 2)
+	bMain.law.close()
+	bMain.law.close()
+}
+
+func TestRetrieveCacheBypass(t *testing.T) {
+	initTest(t)
+	ast := assert.New(t)
+	bMain := main.(*MainStorage)
+
+	b := createBlobDescription("bypass")
+	id, err := bMain.StgSrv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+
+	// a bulk read doesn't add the blob to the cache
+	var buf bytes.Buffer
+	ast.Nil(utils.RetrieveBlob(main, id, &buf, interfaces.CacheBypass))
+	ast.Equal("this is a blob content", buf.String())
+	time.Sleep(100 * time.Millisecond)
+	ok, err := bMain.CchSrv.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+
+	buf.Reset()
+	ast.Nil(main.RetrieveBlob(id, &buf))
+	ast.Equal("this is a blob content", buf.String())
+	for x := 0; x < 50 && !ok; x++ {
+		time.Sleep(10 * time.Millisecond)
+		ok, _ = bMain.CchSrv.HasBlob(id)
+	}
+	ast.True(ok)
+}
+
+// slowStorage a storage counting and slowing down the reads, so concurrent readers are overlapping
+type slowStorage struct {
+	interfaces.BlobStorage
+	reads atomic.Int32
+	descs atomic.Int32
+}
+
+func (s *slowStorage) GetBlobDescription(id string) (*model.BlobDescription, error) {
+	s.descs.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return s.BlobStorage.GetBlobDescription(id)
+}
+
+func (s *slowStorage) RetrieveBlob(id string, w io.Writer) error {
+	s.reads.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return s.BlobStorage.RetrieveBlob(id, w)
+}
+
+func TestRetrieveCoalesced(t *testing.T) {
+	initTest(t)
+	ast := assert.New(t)
+	bMain := main.(*MainStorage)
+	stg := &slowStorage{BlobStorage: bMain.StgSrv}
+	bMain.StgSrv = stg
+
+	b := createBlobDescription("coalesced")
+	id, err := stg.BlobStorage.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	bufs := make([]bytes.Buffer, 20)
+	for x := range bufs {
+		wg.Add(1)
+		go func(buf *bytes.Buffer) {
+			defer wg.Done()
+			<-start
+			ast.Nil(main.RetrieveBlob(id, buf))
+		}(&bufs[x])
+	}
+	close(start)
+	wg.Wait()
+
+	for _, buf := range bufs {
+		ast.Equal("this is a blob content", buf.String())
+	}
+	ast.Equal(int32(1), stg.reads.Load())
+	ast.Equal(int32(1), stg.descs.Load())
+	ok, err := bMain.CchSrv.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+
+	// concurrent fills of the cache are coalesced too
+	ast.Nil(bMain.CchSrv.DeleteBlob(id))
+	for x := 0; x < 10; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := bMain.cacheFile(&b)
+			ast.Nil(err)
+		}()
+	}
+	wg.Wait()
+	ast.Equal(int32(2), stg.reads.Load())
+	ast.Nil(main.Close())
+}
+
+// brokenStorage a storage, which is failing after a part of the blob is read
+type brokenStorage struct {
+	interfaces.BlobStorage
+}
+
+func (s *brokenStorage) RetrieveBlob(_ string, w io.Writer) error {
+	_, _ = w.Write([]byte("this is"))
+	return errors.New("read failed")
+}
+
+func TestRetrieveCoalescedFailed(t *testing.T) {
+	clear(t)
+	initTest(t)
+	ast := assert.New(t)
+	bMain := main.(*MainStorage)
+	bMain.StgSrv = &brokenStorage{BlobStorage: bMain.StgSrv}
+
+	old := time.Now().Add(-1 * time.Hour).UnixMilli()
+	b := createBlobDescription("broken")
+	b.LastAccess = old
+	id, err := bMain.StgSrv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+
+	// a failed read is no access
+	var buf bytes.Buffer
+	ast.NotNil(main.RetrieveBlob(id, &buf))
+	mb, err := main.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(old, mb.LastAccess)
+	ast.Nil(main.Close())
+}
+
+// idsIndex an index returning the given ids for every query
+type idsIndex struct {
+	ids []string
+}
+
+func (i *idsIndex) Init() error { return nil }
+
+func (i *idsIndex) Search(_ string, callback func(id string) bool) error {
+	for _, id := range i.ids {
+		if !callback(id) {
+			break
+		}
+	}
+	return nil
+}
+
+func (i *idsIndex) Index(_ string, _ model.BlobDescription) error { return nil }
+
+func (i *idsIndex) NewBatch() interfaces.IndexBatch { return nil }
+
+func initBulkTest(t *testing.T, count int) (*MainStorage, []string) {
+	clear(t)
+	initTest(t)
+	ast := assert.New(t)
+	bMain, ok := main.(*MainStorage)
+	ast.True(ok)
+	// the blobs must be in the backup, before the bulk job starts
+	bMain.Bcksyncmode = true
+	ids := make([]string, 0)
+	for x := 0; x < count; x++ {
+		b, err := createBlob(ast, strconv.Itoa(x))
+		ast.Nil(err)
+		ids = append(ids, b.BlobID)
+	}
+	bMain.IdxSrv = &idsIndex{ids: ids}
+	bMain.hasIdx = true
+	return bMain, ids
+}
+
+func waitBulk(ast *assert.Assertions, m *MainStorage) model.BulkJob {
+	for x := 0; x < 500; x++ {
+		st, ok := m.GetBulk()
+		ast.True(ok)
+		if !st.Running {
+			return st
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ast.Fail("bulk job not finished")
+	return model.BulkJob{}
+}
+
+func TestBulkDelete(t *testing.T) {
+	ast := assert.New(t)
+	bMain, ids := initBulkTest(t, 5)
+
+	_, ok := bMain.GetBulk()
+	ast.False(ok)
+
+	// dry run changes nothing
+	st, err := bMain.StartBulk(model.BulkRequest{Query: "test", Operation: model.BulkOpDelete, DryRun: true})
+	ast.Nil(err)
+	ast.Equal(5, st.Total)
+	st = waitBulk(ast, bMain)
+	ast.Equal(model.BulkStateFinished, st.State)
+	ast.Equal(5, st.Succeeded)
+	rs, ok := bMain.GetBulkReport()
+	ast.True(ok)
+	ast.Equal(5, len(rs))
+	for _, r := range rs {
+		ast.Equal(model.BulkResultMatched, r.Result)
+	}
+	for _, id := range ids {
+		ok, err := bMain.HasBlob(id)
+		ast.Nil(err)
+		ast.True(ok)
+	}
+
+	// deleting the blobs, the last blob is already gone
+	ast.Nil(bMain.DeleteBlob(ids[4]))
+	_, err = bMain.StartBulk(model.BulkRequest{Query: "test", Operation: model.BulkOpDelete, Rate: 100})
+	ast.Nil(err)
+	st = waitBulk(ast, bMain)
+	ast.Equal(model.BulkStateFinished, st.State)
+	ast.Equal(5, st.Processed)
+	ast.Equal(4, st.Succeeded)
+	ast.Equal(1, st.Failed)
+	rs, _ = bMain.GetBulkReport()
+	ast.Equal(5, len(rs))
+	ast.Equal(model.BulkResultFailed, rs[4].Result)
+	ast.Equal(ids[4], rs[4].BlobID)
+	for _, id := range ids {
+		ok, err := bMain.HasBlob(id)
+		ast.Nil(err)
+		ast.False(ok)
+	}
+	ast.Nil(main.Close())
+}
+
+func TestBulkUpdate(t *testing.T) {
+	ast := assert.New(t)
+	bMain, ids := initBulkTest(t, 3)
+
+	_, err := bMain.StartBulk(model.BulkRequest{
+		Query:      "test",
+		Operation:  model.BulkOpProperties,
+		Properties: map[string]any{"import": "fixed", "x-externalid": nil},
+		Rate:       100,
+	})
+	ast.Nil(err)
+	st := waitBulk(ast, bMain)
+	ast.Equal(3, st.Succeeded)
+
+	_, err = bMain.StartBulk(model.BulkRequest{
+		Query:         "test",
+		Operation:     model.BulkOpRetention,
+		Retention:     60,
+		RetentionMode: model.RetentionModeLastAccess,
+		Rate:          100,
+	})
+	ast.Nil(err)
+	st = waitBulk(ast, bMain)
+	ast.Equal(model.BulkOpRetention, st.Operation)
+	ast.Equal(3, st.Succeeded)
+
+	for _, id := range ids {
+		b, err := bMain.GetBlobDescription(id)
+		ast.Nil(err)
+		// the keys are mapped like the headers of an upload
+		ast.Equal("fixed", b.Properties["X-Import"])
+		_, ok := b.Properties["X-externalid"]
+		ast.False(ok)
+		ast.Equal(tenant, b.Properties["X-tenant"])
+		ast.Equal(int64(60), b.Retention)
+		ast.Equal(model.RetentionModeLastAccess, b.RetentionMode)
+	}
+	ast.Nil(main.Close())
+}
+
+func TestBulkCancel(t *testing.T) {
+	ast := assert.New(t)
+	bMain, ids := initBulkTest(t, 5)
+
+	_, err := bMain.CancelBulk()
+	ast.ErrorIs(err, ErrNoBulk)
+
+	_, err = bMain.StartBulk(model.BulkRequest{Query: "test", Operation: model.BulkOpDelete, Rate: 2})
+	ast.Nil(err)
+	_, err = bMain.StartBulk(model.BulkRequest{Query: "test", Operation: model.BulkOpDelete})
+	ast.ErrorIs(err, ErrBulkRunning)
+
+	st, err := bMain.CancelBulk()
+	ast.Nil(err)
+	ast.Equal(model.BulkStateCancelled, st.State)
+	ast.False(st.Running)
+	ast.True(st.Processed < len(ids))
+	rs, _ := bMain.GetBulkReport()
+	ast.Equal(st.Processed, len(rs))
+	ast.Nil(main.Close())
+}
+
+func TestBulkRequestCheck(t *testing.T) {
+	ast := assert.New(t)
+	bMain, _ := initBulkTest(t, 0)
+
+	reqs := []model.BulkRequest{
+		{Operation: model.BulkOpDelete},
+		{Query: "test", Operation: "move"},
+		{Query: "test", Operation: model.BulkOpRetention},
+		{Query: "test", Operation: model.BulkOpRetention, Retention: 10, RetentionMode: "never"},
+		{Query: "test", Operation: model.BulkOpProperties},
+	}
+	for _, req := range reqs {
+		_, err := bMain.StartBulk(req)
+		ast.NotNil(err, "request: %v", req)
+	}
+	_, ok := bMain.GetBulk()
+	ast.False(ok)
+	ast.Nil(main.Close())
+}
+
+// corruptFile overwriting the content of the blob in the storage
+func corruptFile(ast *assert.Assertions, root, id string) {
+	found := false
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && d.Name() == id+".bin" {
+			found = true
+			return os.WriteFile(path, []byte("corrupted content"), 0o644)
+		}
+		return err
+	})
+	ast.Nil(err)
+	ast.True(found)
+}
+
+// garbageStorage a storage, which is storing a wrong content
+type garbageStorage struct {
+	interfaces.BlobStorage
+}
+
+func (s *garbageStorage) StoreBlob(b *model.BlobDescription, _ io.Reader) (string, error) {
+	return s.BlobStorage.StoreBlob(b, strings.NewReader("garbage"))
+}
+
+func TestRepair(t *testing.T) {
+	clear(t)
+	initTest(t)
+	ast := assert.New(t)
+	bMain, ok := main.(*MainStorage)
+	ast.True(ok)
+	bMain.Bcksyncmode = true
+
+	bs := make([]model.BlobDescription, 0)
+	for x := 0; x < 6; x++ {
+		b, err := createBlob(ast, strconv.Itoa(x))
+		ast.Nil(err)
+		bs = append(bs, b)
+	}
+	// waiting for the background tasks of storing, they would restore a deleted primary
+	time.Sleep(100 * time.Millisecond)
+	corruptFile(ast, blbPath, bs[0].BlobID)
+	corruptFile(ast, bckPath, bs[1].BlobID)
+	ast.Nil(bMain.BckSrv.DeleteBlob(bs[2].BlobID))
+	corruptFile(ast, blbPath, bs[3].BlobID)
+	corruptFile(ast, bckPath, bs[3].BlobID)
+	ast.Nil(bMain.StgSrv.DeleteBlob(bs[4].BlobID))
+	bd, err := bMain.BckSrv.GetBlobDescription(bs[5].BlobID)
+	ast.Nil(err)
+	bd.Filename = "wrong.txt"
+	ast.Nil(bMain.BckSrv.UpdateBlobDescription(bs[5].BlobID, bd))
+
+	// without auto repair only the check is written
+	bd, err = bMain.ScrubBlob(bs[0].BlobID)
+	ast.Nil(err)
+	ast.False(bd.Check.Healthy)
+	ast.Empty(bd.Check.Repaired)
+	bd, err = bMain.ScrubBlob(bs[5].BlobID)
+	ast.Nil(err)
+	ast.False(bd.Check.Healthy)
+	ast.Contains(bd.Check.Message, "descriptions are not equal")
+
+	bMain.AutoRepair = true
+	repairs := []string{RepairPrimaryRestored, RepairBackupRecreated, RepairBackupRecreated, "", RepairPrimaryRestored, RepairBackupDesc}
+	for x, b := range bs {
+		bd, err := bMain.ScrubBlob(b.BlobID)
+		if x == 3 {
+			// no healthy copy left
+			ast.Nil(err)
+			ast.False(bd.Check.Healthy)
+			ast.Contains(bd.Check.Action, ErrUnrepairable.Error())
+			continue
+		}
+		ast.Nil(err, x)
+		ast.True(bd.Check.Healthy, x)
+		ast.Equal(repairs[x], bd.Check.Repaired, x)
+		ast.Empty(bd.Check.Action, x)
+		checkBlob(ast, b)
+		bd, err = bMain.BckSrv.GetBlobDescription(b.BlobID)
+		ast.Nil(err)
+		ast.Equal(b.Hash, bd.Hash)
+		ast.Equal(b.Filename, bd.Filename)
+	}
+
+	// a healthy blob isn't repaired again
+	bd, err = bMain.ScrubBlob(bs[0].BlobID)
+	ast.Nil(err)
+	ast.True(bd.Check.Healthy)
+	ast.Empty(bd.Check.Repaired)
+
+	// a blob missing on both storages can't be repaired
+	ast.Nil(bMain.StgSrv.DeleteBlob(bs[3].BlobID))
+	ast.Nil(bMain.BckSrv.DeleteBlob(bs[3].BlobID))
+	_, err = bMain.ScrubBlob(bs[3].BlobID)
+	ast.ErrorIs(err, ErrUnrepairable)
+
+	// a corrupt copy doesn't replace the former blob
+	corruptFile(ast, bckPath, bs[0].BlobID)
+	ast.NotNil(replaceBlob(bs[0].BlobID, bMain.BckSrv, bMain.StgSrv, &bs[0], bs[0].Hash))
+	checkBlob(ast, bs[0])
+
+	// a broken stored copy is removed
+	ast.NotNil(replaceBlob(bs[1].BlobID, bMain.StgSrv, &garbageStorage{BlobStorage: bMain.BckSrv}, &bs[1], bs[1].Hash))
+	ok, err = bMain.BckSrv.HasBlob(bs[1].BlobID)
+	ast.Nil(err)
+	ast.False(ok)
+	ast.Nil(main.Close())
+}
@@ -2,12 +2,22 @@ package business
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
 	"github.com/willie68/GoBlobStore/internal/utils/slicesutils"
+	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
+// auditRetries count of tries to write an audit entry of a tenant removal, before the removal is stopped
+const auditRetries = 3
+
+// auditRetryDelay delay between two tries to write an audit entry
+var auditRetryDelay = time.Second
+
 // this type is doing all the stuff for managing different tenants in the system.
 // It will use the underlying tenant services for the storage part.
 
@@ -16,11 +26,13 @@ var _ interfaces.TenantManager = &MainTenant{}
 
 // MainTenant the business object for doing all tenant based operations
 type MainTenant struct {
-	TntSrv  interfaces.TenantManager
-	BckSrv  interfaces.TenantManager
-	hasBck  bool
-	rmTnt   []string
-	rmtSync sync.Mutex
+	TntSrv     interfaces.TenantManager
+	BckSrv     interfaces.TenantManager
+	Audit      interfaces.AuditJournal
+	StgFactory interfaces.StorageFactory
+	hasBck     bool
+	rmTnt      []string
+	rmtSync    sync.Mutex
 }
 
 // Init initialize this service
@@ -41,7 +53,7 @@ func (m *MainTenant) Init() error {
 // GetTenants walk thru all configured tenants and get the id back
 func (m *MainTenant) GetTenants(callback func(tenant string) bool) error {
 	return m.TntSrv.GetTenants(func(t string) bool {
-		if !slicesutils.Contains(m.rmTnt, t) {
+		if !m.inRemoval(t) {
 			callback(t)
 		}
 		return true
@@ -50,7 +62,7 @@ func (m *MainTenant) GetTenants(callback func(tenant string) bool) error {
 
 // AddTenant adding a new tenant
 func (m *MainTenant) AddTenant(tenant string) error {
-	if slicesutils.Contains(m.rmTnt, tenant) {
+	if m.inRemoval(tenant) {
 		return errors.New("can't add tenant, it's in removal state")
 	}
 	err := m.TntSrv.AddTenant(tenant)
@@ -62,35 +74,107 @@ func (m *MainTenant) AddTenant(tenant string) error {
 
 // RemoveTenant removing a tenant, deleting all data async, return the process id for this
 func (m *MainTenant) RemoveTenant(tenant string) (string, error) {
-	if slicesutils.Contains(m.rmTnt, tenant) {
+	if m.inRemoval(tenant) {
 		return "", errors.New("tenant is already in removal state")
 	}
 	if !m.HasTenant(tenant) {
 		return "", errors.New("tenant not exists")
 	}
+	// the storage must be retrieved before the tenant is in removal state
+	var stg interfaces.BlobStorage
+	if m.Audit != nil && m.StgFactory != nil {
+		var err error
+		stg, err = m.StgFactory.GetStorage(tenant)
+		if err != nil {
+			return "", fmt.Errorf("can't get storage for audit of tenant %s: %v", tenant, err)
+		}
+	}
 	m.rmtSync.Lock()
 	m.rmTnt = append(m.rmTnt, tenant)
 	m.rmtSync.Unlock()
-	go m.removeTnt(tenant)
-	if m.hasBck {
-		go m.BckSrv.RemoveTenant(tenant)
-	}
+	go m.removeTnt(tenant, stg)
 	return "", nil
 }
 
-func (m *MainTenant) removeTnt(tenant string) {
+// removeTnt removing the storage and the tenant on the main and the backup storage.
+// If the audit entries of the blobs can't be written, the removal is stopped and the tenant stays.
+func (m *MainTenant) removeTnt(tenant string, stg interfaces.BlobStorage) {
+	defer func() {
+		m.rmtSync.Lock()
+		m.rmTnt = slicesutils.RemoveString(m.rmTnt, tenant)
+		m.rmtSync.Unlock()
+	}()
+	if stg != nil {
+		if err := m.auditTnt(tenant, stg); err != nil {
+			logger.Errorf("removal of tenant %s stopped, audit entries can't be written: %v", tenant, err)
+			return
+		}
+		if err := m.StgFactory.RemoveStorage(tenant); err != nil {
+			logger.Errorf("error removing storage of tenant %s: %v", tenant, err)
+		}
+	}
 	_, err := m.TntSrv.RemoveTenant(tenant)
 	if err != nil {
 		logger.Errorf("error removing tenant %s: %v", tenant, err)
 	}
+	if m.hasBck {
+		if _, err := m.BckSrv.RemoveTenant(tenant); err != nil {
+			logger.Errorf("error removing tenant %s on backup: %v", tenant, err)
+		}
+	}
+}
+
+// inRemoval checking if the tenant is in removal state
+func (m *MainTenant) inRemoval(tenant string) bool {
 	m.rmtSync.Lock()
-	m.rmTnt = slicesutils.RemoveString(m.rmTnt, tenant)
-	m.rmtSync.Unlock()
+	defer m.rmtSync.Unlock()
+	return slicesutils.Contains(m.rmTnt, tenant)
+}
+
+// auditTnt writing an audit entry for every blob of the tenant, which will be removed. Returning an error, if an entry can't be written.
+func (m *MainTenant) auditTnt(tenant string, stg interfaces.BlobStorage) error {
+	var aerr error
+	err := stg.GetBlobs(func(id string) bool {
+		bd, err := stg.GetBlobDescription(id)
+		if err != nil {
+			// the blob is removed anyway, so at least the id is recorded
+			logger.Errorf("audit: can't get description for tenant %s, blob %s: %v", tenant, id, err)
+			bd = &model.BlobDescription{BlobID: id, TenantID: tenant}
+		}
+		aerr = m.appendAudit(tenant, model.AuditEntryFromBlobDescription(*bd, model.ReasonTenantRemoval))
+		if aerr != nil {
+			aerr = fmt.Errorf("blob %s: %w", id, aerr)
+		}
+		return aerr == nil
+	})
+	if aerr != nil {
+		return aerr
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("walking blobs: %w", err)
+	}
+	return nil
+}
+
+// appendAudit appending the entry to the journal, retrying a failed append
+func (m *MainTenant) appendAudit(tenant string, e model.AuditEntry) error {
+	var err error
+	for x := 0; x < auditRetries; x++ {
+		if x > 0 {
+			time.Sleep(auditRetryDelay)
+		}
+		err = m.Audit.Append(tenant, e)
+		if err == nil {
+			return nil
+		}
+		logger.Errorf("audit: can't write entry for tenant %s, blob %s: %v", tenant, e.BlobID, err)
+	}
+	return err
 }
 
 // HasTenant checking if a tenant is present
 func (m *MainTenant) HasTenant(tenant string) bool {
-	if slicesutils.Contains(m.rmTnt, tenant) {
+	if m.inRemoval(tenant) {
 		return false
 	}
 	return m.TntSrv.HasTenant(tenant)
@@ -122,7 +206,7 @@ func (m *MainTenant) GetConfig(tenant string) (*interfaces.TenantConfig, error)
 
 // GetSize getting the overall storage size for this tenant
 func (m *MainTenant) GetSize(tenant string) int64 {
-	if slicesutils.Contains(m.rmTnt, tenant) {
+	if m.inRemoval(tenant) {
 		return -1
 	}
 	return m.TntSrv.GetSize(tenant)
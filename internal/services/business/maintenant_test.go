@@ -1,13 +1,19 @@
 package business
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
 	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
 // testing the tenant managment business part
@@ -120,3 +126,72 @@ func TestNilTntSrv(t *testing.T) {
 	err := tnt.Init()
 	ast.NotNil(err)
 }
+
+// failingJournal an audit journal, which can't write any entry
+type failingJournal struct {
+	appends atomic.Int32
+}
+
+func (j *failingJournal) Init() error { return nil }
+
+func (j *failingJournal) Append(_ string, _ model.AuditEntry) error {
+	j.appends.Add(1)
+	return errors.New("journal not writable")
+}
+
+func (j *failingJournal) Query(_ string, _, _ int64, _ func(e model.AuditEntry) bool) error {
+	return nil
+}
+
+func (j *failingJournal) Close() error { return nil }
+
+// tntStgFactory a storage factory with a single storage
+type tntStgFactory struct {
+	stg interfaces.BlobStorage
+}
+
+func (f *tntStgFactory) Init(_ config.Engine, _ interfaces.RetentionManager) error { return nil }
+
+func (f *tntStgFactory) GetStorage(_ string) (interfaces.BlobStorage, error) { return f.stg, nil }
+
+func (f *tntStgFactory) RemoveStorage(_ string) error { return nil }
+
+func (f *tntStgFactory) Close() error { return nil }
+
+func TestRemoveTenantAuditFails(t *testing.T) {
+	ast := assert.New(t)
+	initTntTest(ast)
+	auditRetryDelay = time.Millisecond
+	defer func() {
+		auditRetryDelay = time.Second
+	}()
+	ast.Nil(tnt.AddTenant(tenant))
+	stg := &simplefile.BlobStorage{
+		RootPath: tntPath,
+		Tenant:   tenant,
+	}
+	ast.Nil(stg.Init())
+	b := model.BlobDescription{
+		ContentType: "text/plain",
+		Properties:  make(map[string]any),
+	}
+	id, err := stg.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+
+	jnl := &failingJournal{}
+	tnt.Audit = jnl
+	tnt.StgFactory = &tntStgFactory{stg: stg}
+	_, err = tnt.RemoveTenant(tenant)
+	ast.Nil(err)
+
+	// the removal is stopped, the tenant and the blob stay
+	ast.Eventually(func() bool {
+		return tnt.HasTenant(tenant)
+	}, 5*time.Second, 10*time.Millisecond)
+	ast.Equal(int32(auditRetries), jnl.appends.Load())
+	ok, err := stg.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+	ast.True(tnt.BckSrv.HasTenant(tenant))
+	closeTntTest(ast)
+}
@@ -0,0 +1,215 @@
+package business
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// ErrUnrepairable the blob can't be repaired automatically, a manual action is needed
+var ErrUnrepairable = errors.New("manual action needed")
+
+// blob repairs
+const (
+	RepairPrimaryRestored = "primary restored from backup"
+	RepairBackupRecreated = "backup recreated from primary"
+	RepairBackupDesc      = "backup description reconciled"
+)
+
+// copyState the state of the copy of a blob on one storage
+type copyState struct {
+	desc *model.BlobDescription
+	hash string // the hash of the content, empty if the content can't be read
+}
+
+// healthy the content is matching the hash of the description
+func (c copyState) healthy() bool {
+	return c.desc != nil && c.hash != "" && c.hash == c.desc.Hash
+}
+
+// RepairBlob repairing a blob with the healthy copy. A primary blob, whose content isn't matching the hash of the description, is restored
+// from a healthy backup, a missing or corrupt backup is recreated from a healthy primary and an inconsistent backup description is replaced
+// by the primary description. Returning the done repairs, if no healthy copy is present an error wrapping ErrUnrepairable is returned.
+// The blob is locked while repairing, so a concurrent deletion isn't undone.
+func (m *MainStorage) RepairBlob(id string) (string, error) {
+	unlock := m.locks.Lock(id)
+	defer unlock()
+	pri := readCopy(id, m.StgSrv)
+	if pri.healthy() {
+		if m.BckSrv == nil {
+			return "", nil
+		}
+		bck := readCopy(id, m.BckSrv)
+		switch {
+		case bck.hash != pri.desc.Hash:
+			if err := replaceBlob(id, m.StgSrv, m.BckSrv, pri.desc, pri.desc.Hash); err != nil {
+				return m.unrepairable(id, fmt.Sprintf("recreating backup: %v", err))
+			}
+			return m.repaired(id, RepairBackupRecreated), nil
+		case !sameDescription(pri.desc, bck.desc):
+			bd := *pri.desc
+			bd.Check = bck.desc.Check
+			if err := m.BckSrv.UpdateBlobDescription(id, &bd); err != nil {
+				return m.unrepairable(id, fmt.Sprintf("updating backup description: %v", err))
+			}
+			return m.repaired(id, RepairBackupDesc), nil
+		}
+		return "", nil
+	}
+
+	if m.BckSrv == nil {
+		return m.unrepairable(id, "primary corrupt or missing, no backup configured")
+	}
+	bck := readCopy(id, m.BckSrv)
+	if !bck.healthy() {
+		return m.unrepairable(id, "no healthy copy on primary and backup")
+	}
+	// the primary description is kept, only a missing description is taken from the backup
+	bd := pri.desc
+	if bd == nil {
+		bd = bck.desc
+	}
+	if err := replaceBlob(id, m.BckSrv, m.StgSrv, bd, bck.desc.Hash); err != nil {
+		return m.unrepairable(id, fmt.Sprintf("restoring primary: %v", err))
+	}
+	if m.CchSrv != nil {
+		// the cache may hold the corrupt content
+		if ok, _ := m.CchSrv.HasBlob(id); ok {
+			if err := m.CchSrv.DeleteBlob(id); err != nil {
+				logger.Errorf("repair: error evicting blob %s from cache: %v", id, err)
+			}
+		}
+	}
+	return m.repaired(id, RepairPrimaryRestored), nil
+}
+
+func (m *MainStorage) repaired(id, repair string) string {
+	logger.Infof("repair: tenant %s, blob %s: %s", m.Tenant, id, repair)
+	return repair
+}
+
+func (m *MainStorage) unrepairable(id, msg string) (string, error) {
+	logger.Alertf("repair: tenant %s, blob %s: manual action needed: %s", m.Tenant, id, msg)
+	return "", fmt.Errorf("%w: %s", ErrUnrepairable, msg)
+}
+
+// readCopy reading the description and building the hash of the content of a blob on a storage
+func readCopy(id string, stg interfaces.BlobStorage) copyState {
+	var c copyState
+	bd, err := stg.GetBlobDescription(id)
+	if err != nil {
+		return c
+	}
+	c.desc = bd
+	if ok, _ := stg.HasBlob(id); ok {
+		c.hash, _ = utils.BuildHash(id, stg)
+	}
+	return c
+}
+
+// sameDescription checking if the main fields of both descriptions are equal
+func sameDescription(a, b *model.BlobDescription) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash &&
+		a.ContentLength == b.ContentLength &&
+		a.ContentType == b.ContentType &&
+		a.Filename == b.Filename &&
+		a.TenantID == b.TenantID &&
+		a.Retention == b.Retention &&
+		strings.EqualFold(a.RetentionMode, b.RetentionMode)
+}
+
+// replaceBlob copying the blob from one storage into the other, the retention entry is kept. The content is copied into a temporary file
+// and verified against the hash first, so a former blob is only replaced by a healthy copy. The copy is stored over the former blob,
+// only a storage refusing this gets the former blob deleted before. A broken copy is removed. The blob should be locked.
+func replaceBlob(id string, from, to interfaces.BlobStorage, b *model.BlobDescription, hash string) error {
+	f, err := stageBlob(id, from, hash)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	r, rerr := to.GetRetention(id)
+	if rerr != nil {
+		r, rerr = from.GetRetention(id)
+	}
+	err = storeCopy(id, to, b, f, hash)
+	if err != nil {
+		if ok, _ := to.HasBlob(id); ok {
+			logger.Errorf("repair: error storing blob %s, retrying after deleting the former blob: %v", id, err)
+			deleteCopy(id, to)
+			if _, err = f.Seek(0, io.SeekStart); err == nil {
+				err = storeCopy(id, to, b, f, hash)
+			}
+		}
+	}
+	if err != nil {
+		if ok, _ := to.HasBlob(id); ok {
+			deleteCopy(id, to)
+		}
+		return err
+	}
+	if rerr == nil {
+		return to.AddRetention(&r)
+	}
+	return nil
+}
+
+// storeCopy storing the content into the storage and verifying the stored copy against the hash
+func storeCopy(id string, to interfaces.BlobStorage, b *model.BlobDescription, r io.Reader, hash string) error {
+	bd := *b
+	bd.Check = nil
+	if _, err := to.StoreBlob(&bd, r); err != nil {
+		return err
+	}
+	h, err := utils.BuildHash(id, to)
+	if err != nil {
+		return err
+	}
+	if h != hash {
+		return fmt.Errorf("hash of the stored copy %s isn't matching %s", h, hash)
+	}
+	return nil
+}
+
+// deleteCopy deleting a broken copy of the blob
+func deleteCopy(id string, to interfaces.BlobStorage) {
+	if err := to.DeleteBlob(id); err != nil {
+		logger.Errorf("repair: error deleting blob %s: %v", id, err)
+	}
+}
+
+// stageBlob copying the content of the blob into a temporary file, returning the file positioned at the start, if the content is matching the hash
+func stageBlob(id string, from interfaces.BlobStorage, hash string) (*os.File, error) {
+	f, err := os.CreateTemp("", "repair-*.bin")
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	err = utils.RetrieveBlob(from, id, io.MultiWriter(f, h), interfaces.CacheBypass)
+	if err == nil {
+		if hs := fmt.Sprintf("sha-256:%x", h.Sum(nil)); hs != hash {
+			err = fmt.Errorf("hash of the copy %s isn't matching %s", hs, hash)
+		}
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
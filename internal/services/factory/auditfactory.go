@@ -0,0 +1,34 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/audit"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+)
+
+// CreateAuditJournal creates a new audit journal depending on the configuration
+func CreateAuditJournal(stg config.Storage) (interfaces.AuditJournal, error) {
+	stgcl := strings.ToLower(stg.Storageclass)
+	switch stgcl {
+	case audit.FileJournalName:
+		rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+		if err != nil {
+			return nil, err
+		}
+		// node is optional, defaults to the host name
+		node, _ := config.GetConfigValueAsString(stg.Properties, "node")
+		jnl := &audit.FileJournal{
+			RootPath: rootpath,
+			Node:     node,
+		}
+		err = jnl.Init()
+		if err != nil {
+			return nil, err
+		}
+		return jnl, nil
+	}
+	return nil, fmt.Errorf("no audit journal class implementation for \"%s\" found", stg.Storageclass)
+}
@@ -0,0 +1,202 @@
+package factory
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/business"
+	"github.com/willie68/GoBlobStore/internal/services/fastcache"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/memory"
+	"github.com/willie68/GoBlobStore/internal/services/noindex"
+	"github.com/willie68/GoBlobStore/internal/services/retentionmanager"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+func TestCachePartitions(t *testing.T) {
+	ast := assert.New(t)
+	ast.Nil(os.RemoveAll(cchPath))
+	memory.Clear("cch-tnt")
+	memory.Clear("cch-stg")
+	tntMgr := &memory.TenantManager{Namespace: "cch-tnt"}
+	ast.Nil(tntMgr.Init())
+	for _, tnt := range []string{"t1", "t2", "a", "b"} {
+		ast.Nil(tntMgr.AddTenant(tnt))
+	}
+	ast.Nil(tntMgr.SetConfig("t2", interfaces.TenantConfig{Cache: &config.CacheQuota{MaxCount: 2}}))
+	stgf := &DefaultStorageFactory{
+		TenantMgr: tntMgr,
+	}
+	rtnMgr := &retentionmanager.NoRetention{}
+	ast.Nil(rtnMgr.Init(stgf))
+	ast.Nil(stgf.Init(config.Engine{
+		Storage: memoryStg("cch-stg"),
+		Cache: config.Storage{
+			Storageclass: STGClassFastcache,
+			Properties: map[string]any{
+				"rootpath":    cchPath,
+				"maxcount":    10,
+				"maxramusage": 1024 * 1024,
+				"partitions": map[string]any{
+					"maxcount": 5,
+					"groups": []any{
+						map[string]any{
+							"name":    "ab",
+							"tenants": []any{"a", "b"},
+						},
+					},
+				},
+			},
+		},
+		Index: config.Storage{
+			Storageclass: noindex.NoIndexName,
+		},
+	}, rtnMgr))
+
+	caches := make(map[string]*fastcache.FastCache)
+	for _, tnt := range []string{"t1", "t2", "a", "b"} {
+		stg, err := stgf.GetStorage(tnt)
+		ast.Nil(err)
+		ms, ok := stg.(*business.MainStorage)
+		ast.True(ok)
+		fc, ok := ms.CchSrv.(*fastcache.FastCache)
+		ast.True(ok)
+		caches[tnt] = fc
+	}
+	ast.NotSame(caches["t1"], caches["t2"])
+	ast.Same(caches["a"], caches["b"])
+
+	for _, tnt := range []string{"t1", "t2", "a"} {
+		for x := 0; x < 3; x++ {
+			b := model.BlobDescription{BlobID: strings.Repeat(tnt, 2) + string(rune('0'+x)), TenantID: tnt}
+			_, err := caches[tnt].StoreBlob(&b, strings.NewReader("this is a blob content"))
+			ast.Nil(err)
+		}
+	}
+
+	sts := stgf.GetCacheStats()
+	ast.Len(sts, 3)
+	for _, st := range sts {
+		switch st.Partition {
+		case "t1":
+			ast.Equal(3, st.Count)
+			ast.Equal(int64(5), st.MaxCount)
+		case "t2":
+			ast.Equal(2, st.Count)
+			ast.Equal(int64(2), st.MaxCount)
+			ast.Equal(int64(1), st.Evictions)
+		case fastcache.GroupPrefix + "ab":
+			ast.Equal(3, st.Count)
+			ast.Equal(int64(5), st.MaxCount)
+		default:
+			ast.Fail("unknown partition", st.Partition)
+		}
+	}
+
+	n, err := stgf.ClearCache("t1")
+	ast.Nil(err)
+	ast.Equal(3, n)
+	ast.Equal(0, caches["t1"].Stats().Count)
+	ast.Equal(2, caches["t2"].Stats().Count)
+	ast.Nil(stgf.Close())
+}
+
+func TestCacheAdmin(t *testing.T) {
+	ast := assert.New(t)
+	ast.Nil(os.RemoveAll(cchPath))
+	memory.Clear("cch-tnt")
+	memory.Clear("cch-stg")
+	tntMgr := &memory.TenantManager{Namespace: "cch-tnt"}
+	ast.Nil(tntMgr.Init())
+	ast.Nil(tntMgr.AddTenant("t1"))
+	ast.Nil(tntMgr.AddTenant("t2"))
+	stgf := &DefaultStorageFactory{
+		TenantMgr: tntMgr,
+	}
+	rtnMgr := &retentionmanager.NoRetention{}
+	ast.Nil(rtnMgr.Init(stgf))
+	ast.Nil(stgf.Init(config.Engine{
+		Storage: memoryStg("cch-stg"),
+		Cache: config.Storage{
+			Storageclass: STGClassFastcache,
+			Properties: map[string]any{
+				"rootpath":    cchPath,
+				"maxcount":    3,
+				"maxramusage": 1024 * 1024,
+			},
+		},
+		Index: config.Storage{
+			Storageclass: noindex.NoIndexName,
+		},
+	}, rtnMgr))
+
+	stg, err := stgf.GetStorage("t1")
+	ast.Nil(err)
+	ms, ok := stg.(*business.MainStorage)
+	ast.True(ok)
+	ids := make([]string, 0)
+	for x := 0; x < 4; x++ {
+		b := model.BlobDescription{BlobID: "blob" + string(rune('0'+x)), TenantID: "t1", ContentLength: 22}
+		id, err := ms.StgSrv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+		ast.Nil(err)
+		ids = append(ids, id)
+	}
+
+	_, ok = stgf.GetCacheWarming("t1")
+	ast.False(ok)
+	_, err = stgf.StartCacheWarming("t1", model.CacheWarmRequest{})
+	ast.NotNil(err)
+	// a rate above a blob per millisecond is throttled to one
+	st, err := stgf.StartCacheWarming("t1", model.CacheWarmRequest{IDs: []string{ids[0], ids[1], "unknown"}, Rate: 2_000_000_000})
+	ast.Nil(err)
+	ast.Equal(3, st.Total)
+	for st.Running {
+		time.Sleep(10 * time.Millisecond)
+		st, ok = stgf.GetCacheWarming("t1")
+		ast.True(ok)
+	}
+	ast.Equal(model.WarmingStateFinished, st.State)
+	ast.Equal(2, st.Loaded)
+	ast.Equal(1, st.Failed)
+	_, err = stgf.CancelCacheWarming("t1")
+	ast.ErrorIs(err, business.ErrNoWarming)
+
+	ces, err := stgf.GetCacheEntries("t1", fastcache.OrderHot, 0)
+	ast.Nil(err)
+	ast.Len(ces, 2)
+	ces, err = stgf.GetCacheEntries("t2", fastcache.OrderHot, 0)
+	ast.Nil(err)
+	ast.Len(ces, 0)
+
+	// pinning a blob not in the cache loads it
+	ast.Nil(stgf.PinCacheBlob("t1", ids[3], true))
+	ast.ErrorIs(stgf.PinCacheBlob("t2", ids[3], true), os.ErrNotExist)
+	ast.Nil(stgf.PinCacheBlob("t1", ids[0], true))
+	fc := ms.CchSrv.(*fastcache.FastCache)
+	ast.Equal(2, fc.Stats().Pinned)
+
+	// the pinned blobs are staying in the cache
+	ok, err = ms.WarmBlob(ids[2])
+	ast.Nil(err)
+	ast.True(ok)
+	ces, err = stgf.GetCacheEntries("t1", "", 0)
+	ast.Nil(err)
+	ast.Len(ces, 3)
+	ast.Equal(ids[0], ces[0].BlobID)
+	ast.True(ces[0].Pinned)
+	ast.Equal(ids[3], ces[2].BlobID)
+	ast.True(ces[2].Pinned)
+
+	ast.ErrorIs(stgf.EvictCacheBlob("t2", ids[0]), os.ErrNotExist)
+	ast.Nil(stgf.EvictCacheBlob("t1", ids[0]))
+	ast.ErrorIs(stgf.EvictCacheBlob("t1", ids[0]), os.ErrNotExist)
+	ast.Equal(1, fc.Stats().Pinned)
+	ok, err = ms.HasBlob(ids[0])
+	ast.Nil(err)
+	ast.True(ok)
+	ast.Nil(stgf.Close())
+}
@@ -0,0 +1,123 @@
+package factory
+
+import (
+	"errors"
+	"os"
+
+	"github.com/willie68/GoBlobStore/internal/services/business"
+	"github.com/willie68/GoBlobStore/internal/services/fastcache"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// ErrNoFastcache the tenant has no fastcache, the cache administration is only working with a fastcache
+var ErrNoFastcache = errors.New("no fastcache configured for this tenant")
+
+// GetCacheEntries getting the blobs of the tenant in the cache, ordered by fastcache.OrderHot or fastcache.OrderOld
+func (d *DefaultStorageFactory) GetCacheEntries(tenant, order string, limit int) ([]model.CacheEntry, error) {
+	fc, err := d.tenantCache(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return fc.CacheEntries(tenant, order, limit), nil
+}
+
+// EvictCacheBlob removing a single blob of the tenant from the cache, the blob in the storage is not affected
+func (d *DefaultStorageFactory) EvictCacheBlob(tenant, id string) error {
+	fc, err := d.tenantCache(tenant)
+	if err != nil {
+		return err
+	}
+	if err := checkCacheTenant(fc, tenant, id); err != nil {
+		return err
+	}
+	return fc.DeleteBlob(id)
+}
+
+// PinCacheBlob pinning or unpinning a blob of the tenant, a blob to pin, which is not in the cache, is loaded first
+func (d *DefaultStorageFactory) PinCacheBlob(tenant, id string, pin bool) error {
+	fc, err := d.tenantCache(tenant)
+	if err != nil {
+		return err
+	}
+	err = checkCacheTenant(fc, tenant, id)
+	if errors.Is(err, os.ErrNotExist) && pin {
+		cw, cerr := d.cacheWarmer(tenant)
+		if cerr != nil {
+			return cerr
+		}
+		if _, err = cw.WarmBlob(id); err != nil {
+			return err
+		}
+		err = checkCacheTenant(fc, tenant, id)
+	}
+	if err != nil {
+		return err
+	}
+	return fc.Pin(id, pin)
+}
+
+// StartCacheWarming starting the pre-loading of the blobs of the tenant into the cache
+func (d *DefaultStorageFactory) StartCacheWarming(tenant string, req model.CacheWarmRequest) (model.CacheWarming, error) {
+	cw, err := d.cacheWarmer(tenant)
+	if err != nil {
+		return model.CacheWarming{}, err
+	}
+	return cw.StartWarming(req)
+}
+
+// GetCacheWarming getting the status of the actual or last warming of the cache of the tenant
+func (d *DefaultStorageFactory) GetCacheWarming(tenant string) (model.CacheWarming, bool) {
+	cw, err := d.cacheWarmer(tenant)
+	if err != nil {
+		return model.CacheWarming{}, false
+	}
+	return cw.GetWarming()
+}
+
+// CancelCacheWarming cancelling the running warming of the cache of the tenant
+func (d *DefaultStorageFactory) CancelCacheWarming(tenant string) (model.CacheWarming, error) {
+	cw, err := d.cacheWarmer(tenant)
+	if err != nil {
+		return model.CacheWarming{}, err
+	}
+	return cw.CancelWarming()
+}
+
+func (d *DefaultStorageFactory) cacheWarmer(tenant string) (interfaces.CacheWarmer, error) {
+	stg, err := d.GetStorage(tenant)
+	if err != nil {
+		return nil, err
+	}
+	cw, ok := stg.(interfaces.CacheWarmer)
+	if !ok {
+		return nil, business.ErrNoCache
+	}
+	return cw, nil
+}
+
+// tenantCache getting the fastcache or the cache partition of the tenant
+func (d *DefaultStorageFactory) tenantCache(tenant string) (*fastcache.FastCache, error) {
+	stg, err := d.GetStorage(tenant)
+	if err != nil {
+		return nil, err
+	}
+	ms, ok := stg.(*business.MainStorage)
+	if !ok {
+		return nil, ErrNoFastcache
+	}
+	fc, ok := ms.CchSrv.(*fastcache.FastCache)
+	if !ok {
+		return nil, ErrNoFastcache
+	}
+	return fc, nil
+}
+
+// checkCacheTenant checking that the blob is in the cache and belongs to the tenant, a not partitioned cache is shared by all tenants
+func checkCacheTenant(fc *fastcache.FastCache, tenant, id string) error {
+	ce, ok := fc.CacheEntry(id)
+	if !ok || ce.TenantID != tenant {
+		return os.ErrNotExist
+	}
+	return nil
+}
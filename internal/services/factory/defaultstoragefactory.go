@@ -5,16 +5,24 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/willie68/GoBlobStore/internal/config"
 	"github.com/willie68/GoBlobStore/internal/services/bluge"
 	"github.com/willie68/GoBlobStore/internal/services/business"
 	"github.com/willie68/GoBlobStore/internal/services/fastcache"
+	"github.com/willie68/GoBlobStore/internal/services/goblobstore"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/kvstore"
+	"github.com/willie68/GoBlobStore/internal/services/livemigration"
+	"github.com/willie68/GoBlobStore/internal/services/memory"
 	"github.com/willie68/GoBlobStore/internal/services/mongodb"
 	"github.com/willie68/GoBlobStore/internal/services/noindex"
 	"github.com/willie68/GoBlobStore/internal/services/s3"
 	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/internal/services/sqlite"
+	"github.com/willie68/GoBlobStore/internal/services/tiering"
+	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
 // name of storage classes
@@ -23,20 +31,31 @@ const (
 	STGClassS3         = "s3storage"
 	STGClassFastcache  = "fastcache"
 	STGClassSFMV       = "sfmv"
+	STGClassTiered     = tiering.TieredStorageName
+	STGClassKVStore    = kvstore.KVStorageName
+	STGClassMemory     = memory.MemoryStorageName
+	STGClassGoBlob     = goblobstore.GoBlobStoreName
 )
 
 // ErrNoStg error for no storage class given
 var ErrNoStg = errors.New("no storage class given")
 
 // just to check interface compatibility
-var _ interfaces.StorageFactory = &DefaultStorageFactory{}
+var (
+	_ interfaces.StorageFactory = &DefaultStorageFactory{}
+	_ interfaces.CacheManager   = &DefaultStorageFactory{}
+)
 
 // DefaultStorageFactory the struct for the default storage factory
 type DefaultStorageFactory struct {
 	TenantMgr    interfaces.TenantManager
 	RtnMgr       interfaces.RetentionManager
 	CchSrv       interfaces.BlobStorage
+	cchParts     *fastcache.Partitions // the partitions of a partitioned fastcache
+	Audit        interfaces.AuditJournal
 	tenantStores sync.Map
+	migrations   sync.Map   // running and finished storage migrations by tenant
+	sm           sync.Mutex // serializing the creation and replacement of tenant storages
 	cnfg         config.Engine
 }
 
@@ -57,6 +76,11 @@ func (d *DefaultStorageFactory) Init(storage config.Engine, rtnm interfaces.Rete
 // GetStorage return the storage for the desired tenant
 func (d *DefaultStorageFactory) GetStorage(tenant string) (interfaces.BlobStorage, error) {
 	srv, ok := d.tenantStores.Load(tenant)
+	if !ok {
+		d.sm.Lock()
+		defer d.sm.Unlock()
+		srv, ok = d.tenantStores.Load(tenant)
+	}
 	if !ok {
 		stgsrv, err := d.createStorage(tenant)
 		if err != nil {
@@ -75,6 +99,9 @@ func (d *DefaultStorageFactory) GetStorage(tenant string) (interfaces.BlobStorag
 
 // RemoveStorage removes a tenant storage from the cache
 func (d *DefaultStorageFactory) RemoveStorage(tenant string) error {
+	d.sm.Lock()
+	defer d.sm.Unlock()
+	d.cancelMigration(tenant)
 	srv, ok := d.tenantStores.Load(tenant)
 	if ok {
 		stgsrv, ok := srv.(*interfaces.BlobStorage)
@@ -101,17 +128,35 @@ func (d *DefaultStorageFactory) createStorage(tenant string) (interfaces.BlobSto
 			return nil, err
 		}
 	}
-	srv, err := d.getImplStg(d.cnfg.Storage, tenant)
+	tntCfg, err := d.TenantMgr.GetConfig(tenant)
+	if err != nil {
+		return nil, err
+	}
+	stgCfg := d.cnfg.Storage
+	if tntCfg != nil && tntCfg.Storage != nil {
+		stgCfg = *tntCfg.Storage
+	}
+	srv, err := d.getImplStg(stgCfg, tenant)
 	if err != nil {
 		return nil, err
 	}
+	// a running migration of the primary storage, continued after a restart
+	var mig *livemigration.Migration
+	if tntCfg != nil && tntCfg.Target != nil {
+		mig, err = d.createMigration(tenant, srv, stgCfg, *tntCfg.Target)
+		if err != nil {
+			_ = srv.Close()
+			return nil, err
+		}
+		srv = mig.Storage
+	}
 
 	bcksrv, err := d.getImplStg(d.cnfg.Backup, tenant)
 	if err != nil && !errors.Is(err, ErrNoStg) {
 		return nil, err
 	}
 
-	cchsrv, err := d.getImplStg(d.cnfg.Cache, "blbstg")
+	cchsrv, err := d.getCache(tenant, tntCfg)
 	if err != nil && !errors.Is(err, ErrNoStg) {
 		return nil, err
 	}
@@ -125,13 +170,14 @@ func (d *DefaultStorageFactory) createStorage(tenant string) (interfaces.BlobSto
 	// an error in this part should prevent the startup of the service,
 	// so the last error will be stored into the tenant main storage service
 	var lasterror error
-	tntBckSrv, err := d.getTntBck(tenant)
+	tntBckSrv, err := d.getTntBck(tenant, tntCfg)
 	if err != nil {
 		lasterror = err
 	}
 
 	msrv := &business.MainStorage{
 		Bcksyncmode: d.cnfg.BackupSyncmode,
+		AutoRepair:  d.cnfg.AutoRepair,
 		RtnMng:      d.RtnMgr,
 		StgSrv:      srv,
 		BckSrv:      bcksrv,
@@ -141,11 +187,20 @@ func (d *DefaultStorageFactory) createStorage(tenant string) (interfaces.BlobSto
 		TntBckSrv:   tntBckSrv,
 		TntError:    lasterror,
 		TntMgr:      d.TenantMgr,
+		Audit:       d.Audit,
+
+		LAFlushInterval: time.Duration(d.cnfg.LastAccess.FlushInterval) * time.Second,
+		LABatchSize:     d.cnfg.LastAccess.BatchSize,
 	}
 	err = msrv.Init()
 	if err != nil {
 		return nil, err
 	}
+	if mig != nil {
+		d.cancelMigration(tenant)
+		d.migrations.Store(tenant, mig)
+		mig.Start()
+	}
 	return msrv, nil
 }
 
@@ -171,6 +226,14 @@ func (d *DefaultStorageFactory) getImplIdx(stg config.Storage, tenant string) (i
 			if err != nil {
 				return nil, err
 			}
+		case sqlite.SQLiteIndex:
+			srv = &sqlite.Index{
+				Tenant: tenant,
+			}
+			err := srv.Init()
+			if err != nil {
+				return nil, err
+			}
 		case noindex.NoIndexName:
 			srv = &noindex.Index{}
 		}
@@ -183,15 +246,14 @@ func (d *DefaultStorageFactory) getImplIdx(stg config.Storage, tenant string) (i
 	return srv, nil
 }
 
-func (d *DefaultStorageFactory) getTntBck(tenant string) (interfaces.BlobStorage, error) {
-	tntCfg, err := d.TenantMgr.GetConfig(tenant)
-	if err != nil {
-		return nil, err
-	}
-
+func (d *DefaultStorageFactory) getTntBck(tenant string, tntCfg *interfaces.TenantConfig) (interfaces.BlobStorage, error) {
+	var err error
 	var lasterror error
 	var tntBckSrv interfaces.BlobStorage
-	if tntCfg != nil {
+	if tntCfg != nil && tntCfg.Backup.Storageclass != "" {
+		if tntCfg.Backup.Properties == nil {
+			tntCfg.Backup.Properties = make(map[string]any)
+		}
 		// we have to set a password and client side encryption is not supported
 		tntCfg.Backup.Properties["password"] = tenant
 		tntCfg.Backup.Properties["insecure"] = true
@@ -213,14 +275,10 @@ func (d *DefaultStorageFactory) getImplStg(stg config.Storage, tenant string) (i
 	stgcl := strings.ToLower(stg.Storageclass)
 	switch stgcl {
 	case STGClassSFMV:
-		rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+		srv, err = getSFMVStorage(stg, tenant)
 		if err != nil {
 			return nil, err
 		}
-		srv = &simplefile.MultiVolumeStorage{
-			RootPath: rootpath,
-			Tenant:   tenant,
-		}
 		err = srv.Init()
 		if err != nil {
 			return nil, err
@@ -230,9 +288,23 @@ func (d *DefaultStorageFactory) getImplStg(stg config.Storage, tenant string) (i
 		if err != nil {
 			return nil, err
 		}
+		fsync, recovery, err := getFileOptions(stg)
+		if err != nil {
+			return nil, err
+		}
+		disableV1 := false
+		if _, ok := stg.Properties["disablev1"]; ok {
+			disableV1, err = config.GetConfigValueAsBool(stg.Properties, "disablev1")
+			if err != nil {
+				return nil, err
+			}
+		}
 		srv = &simplefile.BlobStorage{
-			RootPath: rootpath,
-			Tenant:   tenant,
+			RootPath:        rootpath,
+			Tenant:          tenant,
+			Sync:            fsync,
+			DisableRecovery: !recovery,
+			DisableV1:       disableV1,
 		}
 		err = srv.Init()
 		if err != nil {
@@ -248,7 +320,47 @@ func (d *DefaultStorageFactory) getImplStg(stg config.Storage, tenant string) (i
 			return nil, err
 		}
 	case STGClassFastcache:
-		srv, err = d.getFastcache(stg, tenant)
+		srv, err = d.getFastcache(stg, tenant, nil)
+		if err != nil {
+			return nil, err
+		}
+	case STGClassTiered:
+		srv, err = d.getTieredStorage(stg, tenant)
+		if err != nil {
+			return nil, err
+		}
+		err = srv.Init()
+		if err != nil {
+			return nil, err
+		}
+	case STGClassKVStore:
+		srv, err = getKVStorage(stg, tenant)
+		if err != nil {
+			return nil, err
+		}
+		err = srv.Init()
+		if err != nil {
+			return nil, err
+		}
+	case STGClassMemory:
+		srv, err = getMemoryStorage(stg, tenant)
+		if err != nil {
+			return nil, err
+		}
+		err = srv.Init()
+		if err != nil {
+			return nil, err
+		}
+	case STGClassGoBlob:
+		clt, err := getGoBlobStoreClient(stg)
+		if err != nil {
+			return nil, err
+		}
+		srv = &goblobstore.BlobStorage{
+			Client: clt,
+			Tenant: tenant,
+		}
+		err = srv.Init()
 		if err != nil {
 			return nil, err
 		}
@@ -298,37 +410,413 @@ func (d *DefaultStorageFactory) getS3Storage(stg config.Storage, tenant string)
 	}, nil
 }
 
-func (d *DefaultStorageFactory) getFastcache(stg config.Storage, _ string) (interfaces.BlobStorage, error) {
+func (d *DefaultStorageFactory) getTieredStorage(stg config.Storage, tenant string) (*tiering.TieredStorage, error) {
+	hotCfg, err := subStorage(stg.Properties, "hot")
+	if err != nil {
+		return nil, err
+	}
+	coldCfg, err := subStorage(stg.Properties, "cold")
+	if err != nil {
+		return nil, err
+	}
+	hot, err := d.getImplStg(hotCfg, tenant)
+	if err != nil {
+		return nil, err
+	}
+	cold, err := d.getImplStg(coldCfg, tenant)
+	if err != nil {
+		return nil, err
+	}
+	ts := &tiering.TieredStorage{
+		Hot:    hot,
+		Cold:   cold,
+		Tenant: tenant,
+	}
+	if _, ok := stg.Properties["promote"]; ok {
+		ts.Promote, err = config.GetConfigValueAsBool(stg.Properties, "promote")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := stg.Properties["interval"]; ok {
+		interval, err := config.GetConfigValueAsInt(stg.Properties, "interval")
+		if err != nil {
+			return nil, err
+		}
+		ts.Interval = time.Duration(interval) * time.Second
+	}
+	if rc, ok := stg.Properties["rules"]; ok {
+		rcl, ok := rc.([]any)
+		if !ok {
+			return nil, errors.New("config value for rules is not a list")
+		}
+		ts.Rules, err = tiering.ParseRules(rcl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
+
+// getFileOptions reading the write options of the file based storages, the recovery scan is on by default
+func getFileOptions(stg config.Storage) (fsync, recovery bool, err error) {
+	recovery = true
+	if _, ok := stg.Properties["sync"]; ok {
+		fsync, err = config.GetConfigValueAsBool(stg.Properties, "sync")
+		if err != nil {
+			return false, false, err
+		}
+	}
+	if _, ok := stg.Properties["recovery"]; ok {
+		recovery, err = config.GetConfigValueAsBool(stg.Properties, "recovery")
+		if err != nil {
+			return false, false, err
+		}
+	}
+	return fsync, recovery, nil
+}
+
+func getSFMVStorage(stg config.Storage, tenant string) (*simplefile.MultiVolumeStorage, error) {
+	rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+	if err != nil {
+		return nil, err
+	}
+	// the location index is stored on the tenant path
+	tenantpath, err := config.GetConfigValueAsString(stg.Properties, "tenantpath")
+	if err != nil {
+		return nil, err
+	}
+	srv := &simplefile.MultiVolumeStorage{
+		RootPath:   rootpath,
+		Tenant:     tenant,
+		TenantPath: tenantpath,
+	}
+	fsync, recovery, err := getFileOptions(stg)
+	if err != nil {
+		return nil, err
+	}
+	srv.Sync = fsync
+	srv.DisableRecovery = !recovery
+	if _, ok := stg.Properties["redundancy"]; !ok {
+		return srv, nil
+	}
+	srv.Redundancy, err = config.GetConfigValueAsString(stg.Properties, "redundancy")
+	if err != nil {
+		return nil, err
+	}
+	ints := map[string]*int{
+		"replicas":     &srv.Replicas,
+		"datashards":   &srv.DataShards,
+		"parityshards": &srv.ParityShards,
+	}
+	for key, val := range ints {
+		if _, ok := stg.Properties[key]; !ok {
+			continue
+		}
+		v, err := config.GetConfigValueAsInt(stg.Properties, key)
+		if err != nil {
+			return nil, err
+		}
+		*val = int(v)
+	}
+	return srv, nil
+}
+
+func getKVStorage(stg config.Storage, tenant string) (*kvstore.BlobStorage, error) {
+	rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+	if err != nil {
+		return nil, err
+	}
+	srv := &kvstore.BlobStorage{
+		RootPath: rootpath,
+		Tenant:   tenant,
+	}
+	if _, ok := stg.Properties["snapshotpath"]; ok {
+		srv.SnapshotPath, err = config.GetConfigValueAsString(stg.Properties, "snapshotpath")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := stg.Properties["chunksize"]; ok {
+		cs, err := config.GetConfigValueAsInt(stg.Properties, "chunksize")
+		if err != nil {
+			return nil, err
+		}
+		srv.ChunkSize = int(cs)
+	}
+	// intervals are configured in seconds
+	durs := map[string]*time.Duration{
+		"compactinterval":  &srv.CompactInterval,
+		"snapshotinterval": &srv.SnapshotInterval,
+	}
+	for key, val := range durs {
+		if _, ok := stg.Properties[key]; !ok {
+			continue
+		}
+		v, err := config.GetConfigValueAsInt(stg.Properties, key)
+		if err != nil {
+			return nil, err
+		}
+		*val = time.Duration(v) * time.Second
+	}
+	return srv, nil
+}
+
+func getMemoryStorage(stg config.Storage, tenant string) (*memory.BlobStorage, error) {
+	srv := &memory.BlobStorage{
+		Namespace: memoryNamespace(stg),
+		Tenant:    tenant,
+	}
+	var err error
+	if _, ok := stg.Properties["maxsize"]; ok {
+		srv.MaxSize, err = config.GetConfigValueAsInt(stg.Properties, "maxsize")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := stg.Properties["maxcount"]; ok {
+		mc, err := config.GetConfigValueAsInt(stg.Properties, "maxcount")
+		if err != nil {
+			return nil, err
+		}
+		srv.MaxCount = int(mc)
+	}
+	if _, ok := stg.Properties["lru"]; ok {
+		srv.LRU, err = config.GetConfigValueAsBool(stg.Properties, "lru")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return srv, nil
+}
+
+// memoryNamespace the namespace of a memory storage, storages with the same namespace share the data
+func memoryNamespace(stg config.Storage) string {
+	ns, _ := config.GetConfigValueAsString(stg.Properties, "namespace")
+	return ns
+}
+
+func getGoBlobStoreClient(stg config.Storage) (*goblobstore.Client, error) {
+	url, err := config.GetConfigValueAsString(stg.Properties, "url")
+	if err != nil {
+		return nil, err
+	}
+	clt := &goblobstore.Client{
+		URL:           url,
+		HeaderMapping: make(map[string]string),
+	}
+	strs := map[string]*string{
+		"apikey": &clt.APIKey,
+		"token":  &clt.Token,
+		"cacert": &clt.CACert,
+	}
+	for key, val := range strs {
+		if _, ok := stg.Properties[key]; !ok {
+			continue
+		}
+		*val, err = config.GetConfigValueAsString(stg.Properties, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := stg.Properties["skipverify"]; ok {
+		clt.SkipVerify, err = config.GetConfigValueAsBool(stg.Properties, "skipverify")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := stg.Properties["retries"]; ok {
+		retries, err := config.GetConfigValueAsInt(stg.Properties, "retries")
+		if err != nil {
+			return nil, err
+		}
+		clt.Retries = int(retries)
+	}
+	// the retry delay is given in milliseconds, the timeout in seconds
+	durs := map[string]struct {
+		val  *time.Duration
+		unit time.Duration
+	}{
+		"retrydelay": {&clt.RetryDelay, time.Millisecond},
+		"timeout":    {&clt.Timeout, time.Second},
+	}
+	for key, d := range durs {
+		if _, ok := stg.Properties[key]; !ok {
+			continue
+		}
+		v, err := config.GetConfigValueAsInt(stg.Properties, key)
+		if err != nil {
+			return nil, err
+		}
+		*d.val = time.Duration(v) * d.unit
+	}
+	if hm, ok := stg.Properties["headermapping"].(map[string]any); ok {
+		for k, v := range hm {
+			clt.HeaderMapping[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	return clt, nil
+}
+
+// subStorage getting a storage configuration out of the properties of another storage
+func subStorage(properties map[string]any, key string) (config.Storage, error) {
+	sub, ok := properties[key].(map[string]any)
+	if !ok {
+		return config.Storage{}, fmt.Errorf("missing storage config value for %s", key)
+	}
+	stgcl, err := config.GetConfigValueAsString(sub, "storageclass")
+	if err != nil {
+		return config.Storage{}, err
+	}
+	props, _ := sub["properties"].(map[string]any)
+	return config.Storage{
+		Storageclass: stgcl,
+		Properties:   props,
+	}, nil
+}
+
+// getCache getting the cache for the tenant, a partitioned fastcache has a partition per tenant or tenant group
+func (d *DefaultStorageFactory) getCache(tenant string, tntCfg *interfaces.TenantConfig) (interfaces.BlobStorage, error) {
+	if strings.ToLower(d.cnfg.Cache.Storageclass) == STGClassFastcache {
+		var quota *config.CacheQuota
+		if tntCfg != nil {
+			quota = tntCfg.Cache
+		}
+		return d.getFastcache(d.cnfg.Cache, tenant, quota)
+	}
+	return d.getImplStg(d.cnfg.Cache, "blbstg")
+}
+
+func (d *DefaultStorageFactory) getFastcache(stg config.Storage, tenant string, quota *config.CacheQuota) (interfaces.BlobStorage, error) {
+	if pc, ok := stg.Properties["partitions"]; ok {
+		return d.getCachePartition(stg, pc, tenant, quota)
+	}
 	// as cache there will be always the same instance delivered
 	if d.CchSrv == nil {
-		rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+		cch, err := getFastcacheConfig(stg)
 		if err != nil {
 			return nil, err
 		}
-		maxcount, err := config.GetConfigValueAsInt(stg.Properties, "maxcount")
+		err = cch.Init()
 		if err != nil {
 			return nil, err
 		}
-		ramusage, err := config.GetConfigValueAsInt(stg.Properties, "maxramusage")
+		d.CchSrv = cch
+	}
+	return d.CchSrv, nil
+}
+
+// getCachePartition getting the partition of the tenant, the partitions are sharing the settings of the cache
+func (d *DefaultStorageFactory) getCachePartition(stg config.Storage, pc any, tenant string, quota *config.CacheQuota) (interfaces.BlobStorage, error) {
+	if d.cchParts == nil {
+		pcfg, ok := pc.(map[string]any)
+		if !ok {
+			return nil, errors.New("config value for partitions is not a map")
+		}
+		def, groups, err := fastcache.ParsePartitions(pcfg)
 		if err != nil {
 			return nil, err
 		}
-		mffrs, err := config.GetConfigValueAsInt(stg.Properties, "maxfilesizeforram")
+		cch, err := getFastcacheConfig(stg)
 		if err != nil {
-			mffrs = fastcache.Defaultmffrs
+			return nil, err
 		}
-		d.CchSrv = &fastcache.FastCache{
-			RootPath:          rootpath,
-			MaxCount:          maxcount,
-			MaxRAMSize:        ramusage,
-			MaxFileSizeForRAM: mffrs,
+		if def.MaxCount == 0 {
+			def.MaxCount = cch.MaxCount
 		}
-		err = d.CchSrv.Init()
+		parts := &fastcache.Partitions{
+			RootPath:          cch.RootPath,
+			MaxRAMSize:        cch.MaxRAMSize,
+			MaxFileSizeForRAM: cch.MaxFileSizeForRAM,
+			Persistent:        cch.Persistent,
+			Policy:            cch.Policy,
+			Default:           def,
+			Groups:            groups,
+		}
+		err = parts.Init()
 		if err != nil {
 			return nil, err
 		}
+		d.cchParts = parts
 	}
-	return d.CchSrv, nil
+	return d.cchParts.Get(tenant, quota)
+}
+
+// getFastcacheConfig reading the settings of the fastcache, the cache is not initialized
+func getFastcacheConfig(stg config.Storage) (*fastcache.FastCache, error) {
+	rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+	if err != nil {
+		return nil, err
+	}
+	maxcount, err := config.GetConfigValueAsInt(stg.Properties, "maxcount")
+	if err != nil {
+		return nil, err
+	}
+	ramusage, err := config.GetConfigValueAsInt(stg.Properties, "maxramusage")
+	if err != nil {
+		return nil, err
+	}
+	mffrs, err := config.GetConfigValueAsInt(stg.Properties, "maxfilesizeforram")
+	if err != nil {
+		mffrs = fastcache.Defaultmffrs
+	}
+	// the cache survives restarts by default
+	persistent := true
+	if _, ok := stg.Properties["persistent"]; ok {
+		persistent, err = config.GetConfigValueAsBool(stg.Properties, "persistent")
+		if err != nil {
+			return nil, err
+		}
+	}
+	policy := fastcache.PolicyLRU
+	if _, ok := stg.Properties["policy"]; ok {
+		policy, err = config.GetConfigValueAsString(stg.Properties, "policy")
+		if err != nil {
+			return nil, err
+		}
+	}
+	var maxsize int64
+	if _, ok := stg.Properties["maxsize"]; ok {
+		maxsize, err = config.GetConfigValueAsInt(stg.Properties, "maxsize")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &fastcache.FastCache{
+		RootPath:          rootpath,
+		MaxCount:          maxcount,
+		MaxSize:           maxsize,
+		MaxRAMSize:        ramusage,
+		MaxFileSizeForRAM: mffrs,
+		Persistent:        persistent,
+		Policy:            policy,
+	}, nil
+}
+
+// GetCacheStats getting the statistics of the cache partitions
+func (d *DefaultStorageFactory) GetCacheStats() []model.CacheStats {
+	d.sm.Lock()
+	defer d.sm.Unlock()
+	if d.cchParts != nil {
+		return d.cchParts.Stats()
+	}
+	if fc, ok := d.CchSrv.(*fastcache.FastCache); ok {
+		return []model.CacheStats{fc.Stats()}
+	}
+	return []model.CacheStats{}
+}
+
+// ClearCache removing all blobs of the tenant from the cache, the blobs of other tenants are not affected
+func (d *DefaultStorageFactory) ClearCache(tenant string) (int, error) {
+	d.sm.Lock()
+	defer d.sm.Unlock()
+	if d.cchParts != nil {
+		return d.cchParts.Clear(tenant)
+	}
+	if fc, ok := d.CchSrv.(*fastcache.FastCache); ok {
+		return fc.Clear(tenant)
+	}
+	return 0, nil
 }
 
 func (d *DefaultStorageFactory) initIndex(cnfg config.Storage) error {
@@ -340,6 +828,8 @@ func (d *DefaultStorageFactory) initIndex(cnfg config.Storage) error {
 		bluge.InitBluge(cnfg.Properties)
 	case mongodb.MongoIndex:
 		mongodb.InitMongoDB(cnfg.Properties)
+	case sqlite.SQLiteIndex:
+		return sqlite.InitSQLite(cnfg.Properties)
 	case noindex.NoIndexName:
 		// nothing to do here
 	}
@@ -348,6 +838,12 @@ func (d *DefaultStorageFactory) initIndex(cnfg config.Storage) error {
 
 // Close closing this default storage factory
 func (d *DefaultStorageFactory) Close() error {
+	d.migrations.Range(func(_, v any) bool {
+		if mig, ok := v.(*livemigration.Migration); ok {
+			mig.Cancel()
+		}
+		return true
+	})
 	d.tenantStores.Range(func(key, v any) bool {
 		tSrv, ok := v.(*interfaces.BlobStorage)
 		if ok {
@@ -358,5 +854,18 @@ func (d *DefaultStorageFactory) Close() error {
 		}
 		return true
 	})
+	if d.cchParts != nil {
+		if err := d.cchParts.Close(); err != nil {
+			logger.Errorf("error closing cache partitions: %v", err)
+		}
+	}
+	if d.CchSrv != nil {
+		if err := d.CchSrv.Close(); err != nil {
+			logger.Errorf("error closing cache: %v", err)
+		}
+	}
+	if strings.ToLower(d.cnfg.Index.Storageclass) == sqlite.SQLiteIndex {
+		sqlite.CloseSQLite()
+	}
 	return nil
 }
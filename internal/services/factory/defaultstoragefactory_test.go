@@ -3,13 +3,18 @@ package factory
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/business"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/kvstore"
+	"github.com/willie68/GoBlobStore/internal/services/memory"
 	"github.com/willie68/GoBlobStore/internal/services/noindex"
 	"github.com/willie68/GoBlobStore/internal/services/retentionmanager"
 	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/internal/services/tiering"
 )
 
 const (
@@ -99,3 +104,122 @@ func TestTenantBckStg(t *testing.T) {
 	err = stgf.Close()
 	ast.Nil(err)
 }
+
+func TestTieredStg(t *testing.T) {
+	ast := assert.New(t)
+	stgf := &DefaultStorageFactory{}
+
+	stg := config.Storage{
+		Storageclass: STGClassTiered,
+		Properties: map[string]any{
+			"hot": map[string]any{
+				"storageclass": STGClassSimpleFile,
+				"properties": map[string]any{
+					"rootpath": filepath.Join(rootFilePrefix, "hot"),
+				},
+			},
+			"cold": map[string]any{
+				"storageclass": STGClassSimpleFile,
+				"properties": map[string]any{
+					"rootpath": filepath.Join(rootFilePrefix, "cold"),
+				},
+			},
+			"promote":  true,
+			"interval": 60,
+			"rules": []any{
+				map[string]any{"lastaccess": 60},
+			},
+		},
+	}
+	srv, err := stgf.getImplStg(stg, tenant)
+	ast.Nil(err)
+	ts, ok := srv.(*tiering.TieredStorage)
+	ast.True(ok)
+	ast.True(ts.Promote)
+	ast.Equal(1, len(ts.Rules))
+	ast.Nil(ts.Close())
+
+	delete(stg.Properties, "cold")
+	_, err = stgf.getImplStg(stg, tenant)
+	ast.NotNil(err)
+}
+
+func TestKVStoreStg(t *testing.T) {
+	ast := assert.New(t)
+	stgf := &DefaultStorageFactory{}
+
+	stg := config.Storage{
+		Storageclass: STGClassKVStore,
+		Properties: map[string]any{
+			"rootpath":        filepath.Join(rootFilePrefix, "kvstore"),
+			"chunksize":       1024,
+			"compactinterval": 3600,
+		},
+	}
+	srv, err := stgf.getImplStg(stg, tenant)
+	ast.Nil(err)
+	kv, ok := srv.(*kvstore.BlobStorage)
+	ast.True(ok)
+	ast.Equal(1024, kv.ChunkSize)
+	ast.Equal(time.Hour, kv.CompactInterval)
+
+	// the same database can be used twice, e.g. as storage and as cache
+	srv2, err := stgf.getImplStg(stg, tenant)
+	ast.Nil(err)
+	ast.Nil(srv.Close())
+	_, err = srv2.HasBlob("unknown")
+	ast.Nil(err)
+	ast.Nil(srv2.Close())
+
+	delete(stg.Properties, "rootpath")
+	_, err = stgf.getImplStg(stg, tenant)
+	ast.NotNil(err)
+}
+
+func TestMemoryStg(t *testing.T) {
+	ast := assert.New(t)
+	stg := func(ns string) config.Storage {
+		return config.Storage{
+			Storageclass: STGClassMemory,
+			Properties: map[string]any{
+				"namespace": ns,
+				"maxcount":  100,
+				"lru":       true,
+			},
+		}
+	}
+	tntMgr, err := CreateTenantManager(stg("main"))
+	ast.Nil(err)
+	stgf := &DefaultStorageFactory{
+		TenantMgr: tntMgr,
+	}
+	rtnMgr := &retentionmanager.NoRetention{}
+	ast.Nil(rtnMgr.Init(stgf))
+	ast.Nil(stgf.Init(config.Engine{
+		Tenantautoadd: true,
+		Storage:       stg("main"),
+		Backup:        stg("backup"),
+		Cache:         stg("cache"),
+		Index: config.Storage{
+			Storageclass: noindex.NoIndexName,
+		},
+	}, rtnMgr))
+	ast.Nil(tntMgr.AddTenant(tenant))
+
+	bs, err := stgf.GetStorage(tenant)
+	ast.Nil(err)
+	ast.NotNil(bs)
+	ms, ok := bs.(*business.MainStorage)
+	ast.True(ok)
+	mem, ok := ms.StgSrv.(*memory.BlobStorage)
+	ast.True(ok)
+	ast.Equal(100, mem.MaxCount)
+	ast.True(mem.LRU)
+	_, ok = ms.BckSrv.(*memory.BlobStorage)
+	ast.True(ok)
+	_, ok = ms.CchSrv.(*memory.BlobStorage)
+	ast.True(ok)
+
+	ast.Nil(stgf.RemoveStorage(tenant))
+	ast.Nil(stgf.Close())
+}
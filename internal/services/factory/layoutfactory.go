@@ -0,0 +1,28 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+)
+
+// CreateLayoutMigrator creating the migrator for converting the tenants of a simple file storage between the layouts
+func CreateLayoutMigrator(stg config.Storage) (*simplefile.LayoutMigrator, error) {
+	if strings.ToLower(stg.Storageclass) != STGClassSimpleFile {
+		return nil, fmt.Errorf("storage class \"%s\" has no layouts", stg.Storageclass)
+	}
+	rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+	if err != nil {
+		return nil, err
+	}
+	fsync, _, err := getFileOptions(stg)
+	if err != nil {
+		return nil, err
+	}
+	return &simplefile.LayoutMigrator{
+		RootPath: rootpath,
+		Sync:     fsync,
+	}, nil
+}
@@ -0,0 +1,212 @@
+package factory
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/livemigration"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// StorageCloseDelay a replaced tenant storage is closed after this delay, so running requests can be finished
+var StorageCloseDelay = time.Minute
+
+// ErrMigrationRunning there is already a migration running for the tenant
+var ErrMigrationRunning = errors.New("a storage migration is already running for this tenant")
+
+// ErrNoMigration there is no migration running for the tenant
+var ErrNoMigration = errors.New("no storage migration running for this tenant")
+
+// just to check interface compatibility
+var _ interfaces.StorageMigrator = &DefaultStorageFactory{}
+
+// StartMigration starting the migration of the primary storage of the tenant to the target storage.
+// The target is saved in the tenant config, so the migration is continued after a restart.
+func (d *DefaultStorageFactory) StartMigration(tenant string, target config.Storage) (model.StorageMigration, error) {
+	d.sm.Lock()
+	defer d.sm.Unlock()
+	if mig, ok := d.migration(tenant); ok && mig.Status().Running {
+		return model.StorageMigration{}, ErrMigrationRunning
+	}
+	if !d.TenantMgr.HasTenant(tenant) {
+		return model.StorageMigration{}, errors.New("tenant not exists")
+	}
+	if strings.EqualFold(target.Storageclass, STGClassFastcache) {
+		return model.StorageMigration{}, errors.New("fastcache can't be used as primary storage")
+	}
+	// checking the target configuration
+	tgt, err := d.getImplStg(target, tenant)
+	if err != nil {
+		return model.StorageMigration{}, err
+	}
+	if err := tgt.Close(); err != nil {
+		logger.Errorf("error closing target storage of tenant %s: %v", tenant, err)
+	}
+
+	tntCfg, err := d.TenantMgr.GetConfig(tenant)
+	if err != nil {
+		return model.StorageMigration{}, err
+	}
+	if tntCfg == nil {
+		tntCfg = &interfaces.TenantConfig{}
+	}
+	if tntCfg.Target != nil {
+		return model.StorageMigration{}, ErrMigrationRunning
+	}
+	src := d.cnfg.Storage
+	if tntCfg.Storage != nil {
+		src = *tntCfg.Storage
+	}
+	if reflect.DeepEqual(src, target) {
+		return model.StorageMigration{}, errors.New("target is the actual storage of the tenant")
+	}
+	tntCfg.Target = &target
+	err = d.TenantMgr.SetConfig(tenant, *tntCfg)
+	if err != nil {
+		return model.StorageMigration{}, err
+	}
+	err = d.replaceStorage(tenant)
+	if err != nil {
+		tntCfg.Target = nil
+		if serr := d.TenantMgr.SetConfig(tenant, *tntCfg); serr != nil {
+			logger.Errorf("error resetting config of tenant %s: %v", tenant, serr)
+		}
+		return model.StorageMigration{}, err
+	}
+	logger.Infof("storage migration of tenant %s to %s started", tenant, target.Storageclass)
+	mig, _ := d.migration(tenant)
+	return mig.Status(), nil
+}
+
+// GetMigration getting the status of the actual or last migration of the tenant
+func (d *DefaultStorageFactory) GetMigration(tenant string) (model.StorageMigration, bool) {
+	mig, ok := d.migration(tenant)
+	if !ok {
+		return model.StorageMigration{}, false
+	}
+	return mig.Status(), true
+}
+
+// CancelMigration cancelling the running migration of the tenant, the tenant stays on the source storage.
+// The already copied blobs are not removed from the target storage.
+func (d *DefaultStorageFactory) CancelMigration(tenant string) (model.StorageMigration, error) {
+	err := d.stopMigration(tenant)
+	if err != nil {
+		return model.StorageMigration{}, err
+	}
+	logger.Infof("storage migration of tenant %s cancelled", tenant)
+	mig, ok := d.migration(tenant)
+	if !ok {
+		return model.StorageMigration{}, nil
+	}
+	// the copier may wait for the storage lock to switch the tenant, so waiting is only possible without the lock
+	mig.Wait()
+	return mig.Status(), nil
+}
+
+// stopMigration removing the target from the tenant config and replacing the storage of the tenant
+func (d *DefaultStorageFactory) stopMigration(tenant string) error {
+	d.sm.Lock()
+	defer d.sm.Unlock()
+	tntCfg, err := d.TenantMgr.GetConfig(tenant)
+	if err != nil {
+		return err
+	}
+	if tntCfg == nil || tntCfg.Target == nil {
+		return ErrNoMigration
+	}
+	d.cancelMigration(tenant)
+	tntCfg.Target = nil
+	err = d.TenantMgr.SetConfig(tenant, *tntCfg)
+	if err != nil {
+		return err
+	}
+	return d.replaceStorage(tenant)
+}
+
+// createMigration creating the migration storage and the copier for the tenant
+func (d *DefaultStorageFactory) createMigration(tenant string, src interfaces.BlobStorage, srcCfg, tgtCfg config.Storage) (*livemigration.Migration, error) {
+	tgt, err := d.getImplStg(tgtCfg, tenant)
+	if err != nil {
+		return nil, err
+	}
+	stg := &livemigration.Storage{
+		Source: src,
+		Target: tgt,
+		Tenant: tenant,
+	}
+	err = stg.Init()
+	if err != nil {
+		return nil, err
+	}
+	return &livemigration.Migration{
+		Storage: stg,
+		Source:  srcCfg.Storageclass,
+		Target:  tgtCfg.Storageclass,
+		OnFinish: func() error {
+			return d.finishMigration(tenant)
+		},
+	}, nil
+}
+
+// finishMigration switching the tenant to the target storage
+func (d *DefaultStorageFactory) finishMigration(tenant string) error {
+	d.sm.Lock()
+	defer d.sm.Unlock()
+	tntCfg, err := d.TenantMgr.GetConfig(tenant)
+	if err != nil {
+		return err
+	}
+	if tntCfg == nil || tntCfg.Target == nil {
+		return errors.New("storage migration was cancelled")
+	}
+	tntCfg.Storage = tntCfg.Target
+	tntCfg.Target = nil
+	err = d.TenantMgr.SetConfig(tenant, *tntCfg)
+	if err != nil {
+		return err
+	}
+	logger.Infof("storage migration of tenant %s finished, switching to %s", tenant, tntCfg.Storage.Storageclass)
+	return d.replaceStorage(tenant)
+}
+
+// replaceStorage creating a new storage for the tenant with the actual configuration. The old storage is closed after a delay.
+// The caller must hold the storage lock.
+func (d *DefaultStorageFactory) replaceStorage(tenant string) error {
+	stgsrv, err := d.createStorage(tenant)
+	if err != nil {
+		return err
+	}
+	old, ok := d.tenantStores.Swap(tenant, &stgsrv)
+	if !ok {
+		return nil
+	}
+	if oldsrv, ok := old.(*interfaces.BlobStorage); ok {
+		time.AfterFunc(StorageCloseDelay, func() {
+			if err := (*oldsrv).Close(); err != nil {
+				logger.Errorf("can't close replaced storage for tenant: %s\n %v", tenant, err)
+			}
+		})
+	}
+	return nil
+}
+
+// cancelMigration cancelling the background copier of the tenant, the caller must hold the storage lock
+func (d *DefaultStorageFactory) cancelMigration(tenant string) {
+	if mig, ok := d.migration(tenant); ok {
+		mig.Cancel()
+	}
+}
+
+func (d *DefaultStorageFactory) migration(tenant string) (*livemigration.Migration, bool) {
+	v, ok := d.migrations.Load(tenant)
+	if !ok {
+		return nil, false
+	}
+	mig, ok := v.(*livemigration.Migration)
+	return mig, ok
+}
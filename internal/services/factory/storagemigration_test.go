@@ -0,0 +1,123 @@
+package factory
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/memory"
+	"github.com/willie68/GoBlobStore/internal/services/noindex"
+	"github.com/willie68/GoBlobStore/internal/services/retentionmanager"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+func memoryStg(ns string) config.Storage {
+	return config.Storage{
+		Storageclass: STGClassMemory,
+		Properties: map[string]any{
+			"namespace": ns,
+		},
+	}
+}
+
+func initMigrationTest(t *testing.T) (*DefaultStorageFactory, *memory.TenantManager) {
+	ast := assert.New(t)
+	for _, ns := range []string{"mig-tnt", "mig-src", "mig-dst"} {
+		memory.Clear(ns)
+	}
+	tntMgr := &memory.TenantManager{Namespace: "mig-tnt"}
+	ast.Nil(tntMgr.Init())
+	ast.Nil(tntMgr.AddTenant(tenant))
+	stgf := &DefaultStorageFactory{
+		TenantMgr: tntMgr,
+	}
+	rtnMgr := &retentionmanager.NoRetention{}
+	ast.Nil(rtnMgr.Init(stgf))
+	ast.Nil(stgf.Init(config.Engine{
+		Storage: memoryStg("mig-src"),
+		Index: config.Storage{
+			Storageclass: noindex.NoIndexName,
+		},
+	}, rtnMgr))
+	return stgf, tntMgr
+}
+
+func waitMigration(ast *assert.Assertions, stgf *DefaultStorageFactory) model.StorageMigration {
+	st, ok := stgf.GetMigration(tenant)
+	ast.True(ok)
+	for x := 0; x < 500 && st.Running; x++ {
+		time.Sleep(10 * time.Millisecond)
+		st, _ = stgf.GetMigration(tenant)
+	}
+	return st
+}
+
+func TestStorageMigration(t *testing.T) {
+	ast := assert.New(t)
+	stgf, tntMgr := initMigrationTest(t)
+	StorageCloseDelay = 0
+
+	stg, err := stgf.GetStorage(tenant)
+	ast.Nil(err)
+	ids := make([]string, 0)
+	for x := 0; x < 10; x++ {
+		b := model.BlobDescription{
+			ContentType: "text/plain",
+			Properties:  make(map[string]any),
+		}
+		id, err := stg.StoreBlob(&b, strings.NewReader("this is a blob content"))
+		ast.Nil(err)
+		ids = append(ids, id)
+	}
+
+	_, err = stgf.StartMigration(tenant, memoryStg("mig-src"))
+	ast.NotNil(err)
+	_, err = stgf.StartMigration(tenant, config.Storage{Storageclass: STGClassFastcache})
+	ast.NotNil(err)
+
+	_, err = stgf.StartMigration(tenant, memoryStg("mig-dst"))
+	ast.Nil(err)
+	st := waitMigration(ast, stgf)
+	ast.Equal(model.MigrationStateFinished, st.State)
+	ast.Equal(10, st.Copied)
+
+	// the tenant is switched to the target
+	cfg, err := tntMgr.GetConfig(tenant)
+	ast.Nil(err)
+	ast.Nil(cfg.Target)
+	ast.Equal(memoryStg("mig-dst"), *cfg.Storage)
+
+	memory.Clear("mig-src")
+	stg, err = stgf.GetStorage(tenant)
+	ast.Nil(err)
+	for _, id := range ids {
+		var buf bytes.Buffer
+		ast.Nil(stg.RetrieveBlob(id, &buf))
+		ast.Equal("this is a blob content", buf.String())
+	}
+
+	_, err = stgf.CancelMigration(tenant)
+	ast.ErrorIs(err, ErrNoMigration)
+	ast.Nil(stgf.Close())
+}
+
+func TestStorageMigrationResume(t *testing.T) {
+	ast := assert.New(t)
+	stgf, tntMgr := initMigrationTest(t)
+
+	// a migration in the config is started with the storage of the tenant
+	dst := memoryStg("mig-dst")
+	ast.Nil(tntMgr.SetConfig(tenant, interfaces.TenantConfig{Target: &dst}))
+	_, err := stgf.GetStorage(tenant)
+	ast.Nil(err)
+	st := waitMigration(ast, stgf)
+	ast.Equal(model.MigrationStateFinished, st.State)
+	cfg, err := tntMgr.GetConfig(tenant)
+	ast.Nil(err)
+	ast.Equal(dst, *cfg.Storage)
+	ast.Nil(stgf.Close())
+}
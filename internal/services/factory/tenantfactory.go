@@ -5,7 +5,9 @@ import (
 	"strings"
 
 	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/goblobstore"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/memory"
 	"github.com/willie68/GoBlobStore/internal/services/s3"
 	"github.com/willie68/GoBlobStore/internal/services/simplefile"
 )
@@ -27,7 +29,7 @@ func CreateTenantManager(stg config.Storage) (interfaces.TenantManager, error) {
 			return nil, err
 		}
 		return srv, nil
-	case STGClassSimpleFile:
+	case STGClassSimpleFile, STGClassKVStore:
 		rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
 		if err != nil {
 			return nil, err
@@ -40,6 +42,33 @@ func CreateTenantManager(stg config.Storage) (interfaces.TenantManager, error) {
 			return nil, err
 		}
 		return srv, nil
+	case STGClassMemory:
+		srv := &memory.TenantManager{
+			Namespace: memoryNamespace(stg),
+		}
+		err := srv.Init()
+		if err != nil {
+			return nil, err
+		}
+		return srv, nil
+	case STGClassGoBlob:
+		clt, err := getGoBlobStoreClient(stg)
+		if err != nil {
+			return nil, err
+		}
+		srv := &goblobstore.TenantManager{
+			Client: clt,
+		}
+		if tl, ok := stg.Properties["tenants"].([]any); ok {
+			for _, t := range tl {
+				srv.Tenants = append(srv.Tenants, fmt.Sprintf("%v", t))
+			}
+		}
+		err = srv.Init()
+		if err != nil {
+			return nil, err
+		}
+		return srv, nil
 	case STGClassS3:
 		srv, err := getS3TenantManager(stg)
 		if err != nil {
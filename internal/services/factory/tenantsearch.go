@@ -0,0 +1,136 @@
+package factory
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/mongodb"
+	"github.com/willie68/GoBlobStore/internal/services/sqlite"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// DefaultSearchParallelism count of tenants searched at a time, if the index can't search all tenants with one query
+const DefaultSearchParallelism = 4
+
+// just to check interface compatibility
+var _ interfaces.TenantSearcher = &DefaultStorageFactory{}
+
+// tenantHits collecting the hits of one page of a search over all tenants
+type tenantHits struct {
+	res   *model.TenantSearch
+	index int
+}
+
+// add adding a hit, returning false if the page is full
+func (h *tenantHits) add(tenant, id string) bool {
+	if len(h.res.Hits) >= h.res.Limit {
+		h.res.More = true
+		return false
+	}
+	if h.index >= h.res.Offset {
+		h.res.Hits = append(h.res.Hits, model.TenantSearchHit{Tenant: tenant, BlobID: id})
+	}
+	h.index++
+	return true
+}
+
+// SearchAllTenants searching the blobs of all tenants. An index with a shared query searches all tenants at once,
+// otherwise the tenants are searched one by one with a bounded parallelism. The hits are ordered by the lower case tenant and blob id,
+// so the pages are consistent.
+func (d *DefaultStorageFactory) SearchAllTenants(q string, offset, limit int) (model.TenantSearch, error) {
+	res := model.TenantSearch{
+		Query:  q,
+		Offset: max(offset, 0),
+		Limit:  max(limit, 0),
+		Hits:   make([]model.TenantSearchHit, 0),
+	}
+	tenants, err := d.sortedTenants()
+	if err != nil {
+		return res, err
+	}
+	res.Tenants = len(tenants)
+	hits := &tenantHits{res: &res}
+	if idx := d.sharedIndex(); idx != nil {
+		res.Shared = true
+		err = idx.SearchTenants(q, tenants, hits.add)
+		return res, err
+	}
+
+	for start := 0; start < len(tenants); start += DefaultSearchParallelism {
+		chunk := tenants[start:min(start+DefaultSearchParallelism, len(tenants))]
+		ids := make([][]string, len(chunk))
+		errs := make([]error, len(chunk))
+		var wg sync.WaitGroup
+		for i, t := range chunk {
+			wg.Add(1)
+			go func(i int, t string) {
+				defer wg.Done()
+				ids[i], errs[i] = d.searchTenant(t, q)
+			}(i, t)
+		}
+		wg.Wait()
+		for i, t := range chunk {
+			if errs[i] != nil {
+				if res.Errors == nil {
+					res.Errors = make(map[string]string)
+				}
+				res.Errors[t] = errs[i].Error()
+				continue
+			}
+			for _, id := range ids[i] {
+				if !hits.add(t, id) {
+					return res, nil
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// searchTenant getting all ids of the tenant matching the query, ordered by the id
+func (d *DefaultStorageFactory) searchTenant(tenant, q string) ([]string, error) {
+	stg, err := d.GetStorage(tenant)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0)
+	err = stg.SearchBlobs(q, func(id string) bool {
+		ids = append(ids, id)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// sortedTenants getting all tenants ordered by the lower case name
+func (d *DefaultStorageFactory) sortedTenants() ([]string, error) {
+	tenants := make([]string, 0)
+	err := d.TenantMgr.GetTenants(func(t string) bool {
+		tenants = append(tenants, t)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tenants, func(i, j int) bool {
+		return strings.ToLower(tenants[i]) < strings.ToLower(tenants[j])
+	})
+	return tenants, nil
+}
+
+// sharedIndex getting the configured index, if it can search all tenants with one query
+func (d *DefaultStorageFactory) sharedIndex() interfaces.MultiTenantIndex {
+	switch strings.ToLower(d.cnfg.Index.Storageclass) {
+	case mongodb.MongoIndex:
+		return &mongodb.Index{}
+	case sqlite.SQLiteIndex:
+		return &sqlite.Index{}
+	}
+	return nil
+}
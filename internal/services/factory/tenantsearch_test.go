@@ -0,0 +1,127 @@
+package factory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/bluge"
+	"github.com/willie68/GoBlobStore/internal/services/retentionmanager"
+	"github.com/willie68/GoBlobStore/internal/services/sqlite"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+func initTenantSearch(ast *assert.Assertions, ns string, idx config.Storage) *DefaultStorageFactory {
+	ast.Nil(os.RemoveAll(idx.Properties["rootpath"].(string)))
+	stg := config.Storage{
+		Storageclass: STGClassMemory,
+		Properties: map[string]any{
+			"namespace": ns + utils.GenerateID(),
+		},
+	}
+	tntMgr, err := CreateTenantManager(stg)
+	ast.Nil(err)
+	stgf := &DefaultStorageFactory{
+		TenantMgr: tntMgr,
+	}
+	rtnMgr := &retentionmanager.NoRetention{}
+	ast.Nil(rtnMgr.Init(stgf))
+	ast.Nil(stgf.Init(config.Engine{
+		Tenantautoadd: true,
+		Storage:       stg,
+		Index:         idx,
+	}, rtnMgr))
+
+	// every tenant has two faulty blobs and one good blob
+	for _, t := range []string{"tntc", "TntA", "tntb"} {
+		ast.Nil(tntMgr.AddTenant(t))
+		bs, err := stgf.GetStorage(t)
+		ast.Nil(err)
+		for i, state := range []string{"faulty", "good", "faulty"} {
+			payload := fmt.Sprintf("blob %d of %s", i, t)
+			b := model.BlobDescription{
+				BlobID:        fmt.Sprintf("%s-%d", strings.ToLower(t), i),
+				TenantID:      t,
+				StoreID:       t,
+				ContentType:   "text/plain",
+				ContentLength: int64(len(payload)),
+				Filename:      fmt.Sprintf("blob_%d.txt", i),
+				Properties:    map[string]any{"X-import": state},
+			}
+			_, err := bs.StoreBlob(&b, strings.NewReader(payload))
+			ast.Nil(err)
+		}
+	}
+	return stgf
+}
+
+func hitIDs(hits []model.TenantSearchHit) []string {
+	ids := make([]string, 0)
+	for _, h := range hits {
+		ids = append(ids, h.Tenant+"/"+h.BlobID)
+	}
+	return ids
+}
+
+func testTenantSearch(ast *assert.Assertions, stgf *DefaultStorageFactory, shared bool) {
+	res, err := stgf.SearchAllTenants(`import:"faulty"`, 0, 1000)
+	ast.Nil(err)
+	ast.Equal(shared, res.Shared)
+	ast.Equal(3, res.Tenants)
+	ast.False(res.More)
+	ast.Empty(res.Errors)
+	ast.Equal([]string{"TntA/tnta-0", "TntA/tnta-2", "tntb/tntb-0", "tntb/tntb-2", "tntc/tntc-0", "tntc/tntc-2"}, hitIDs(res.Hits))
+
+	// the pages are consistent
+	res, err = stgf.SearchAllTenants(`import:"faulty"`, 1, 2)
+	ast.Nil(err)
+	ast.True(res.More)
+	ast.Equal([]string{"TntA/tnta-2", "tntb/tntb-0"}, hitIDs(res.Hits))
+	res, err = stgf.SearchAllTenants(`import:"faulty"`, 3, 2)
+	ast.Nil(err)
+	ast.True(res.More)
+	ast.Equal([]string{"tntb/tntb-2", "tntc/tntc-0"}, hitIDs(res.Hits))
+	res, err = stgf.SearchAllTenants(`import:"faulty"`, 5, 2)
+	ast.Nil(err)
+	ast.False(res.More)
+	ast.Equal([]string{"tntc/tntc-2"}, hitIDs(res.Hits))
+}
+
+func TestTenantSearchShared(t *testing.T) {
+	ast := assert.New(t)
+	stgf := initTenantSearch(ast, "tsshared", config.Storage{
+		Storageclass: sqlite.SQLiteIndex,
+		Properties: map[string]any{
+			"rootpath": filepath.Join(rootFilePrefix, "tssqlite"),
+		},
+	})
+	testTenantSearch(ast, stgf, true)
+
+	_, err := stgf.SearchAllTenants(`import:(`, 0, 1000)
+	ast.NotNil(err)
+	ast.Nil(stgf.Close())
+	sqlite.CloseSQLite()
+}
+
+func TestTenantSearchFanOut(t *testing.T) {
+	ast := assert.New(t)
+	stgf := initTenantSearch(ast, "tsfanout", config.Storage{
+		Storageclass: bluge.BlugeIndex,
+		Properties: map[string]any{
+			"rootpath": filepath.Join(rootFilePrefix, "tsbluge"),
+		},
+	})
+	testTenantSearch(ast, stgf, false)
+
+	// a failing tenant doesn't fail the search
+	res, err := stgf.SearchAllTenants(`import:(`, 0, 1000)
+	ast.Nil(err)
+	ast.Equal(3, len(res.Errors))
+	ast.Empty(res.Hits)
+	ast.Nil(stgf.Close())
+}
@@ -0,0 +1,35 @@
+package factory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/internal/services/volume"
+)
+
+// CreateVolumeManager creating the volume manager and the mover for administration of the volumes of a multi volume storage.
+// The manager is shared with the storages of the tenants, the mover works thru the tenant storages of the factory.
+func CreateVolumeManager(stg config.Storage, stgf interfaces.StorageFactory) (*volume.Manager, volume.Mover, error) {
+	if strings.ToLower(stg.Storageclass) != STGClassSFMV {
+		return nil, nil, fmt.Errorf("storage class \"%s\" has no volumes", stg.Storageclass)
+	}
+	rootpath, err := config.GetConfigValueAsString(stg.Properties, "rootpath")
+	if err != nil {
+		return nil, nil, err
+	}
+	volMan, err := volume.GetManager(rootpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	mover := &simplefile.VolumeMover{
+		RootPath: rootpath,
+		Load: func(tenant string) error {
+			_, err := stgf.GetStorage(tenant)
+			return err
+		},
+	}
+	return volMan, mover, nil
+}
@@ -0,0 +1,235 @@
+package fastcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const (
+	// DescriptionExt extension for the description files
+	DescriptionExt = ".json"
+	// CheckpointFile name of the checkpoint file in the root path
+	CheckpointFile = "checkpoint.json"
+
+	checkpointVersion = 1
+)
+
+// checkpoint the persisted state of the cache entries, the descriptions are saved beside the binaries.
+// The bloom filter is derived from the entries, so it's rebuilt from the validated entries.
+type checkpoint struct {
+	Version int               `json:"version"`
+	Created time.Time         `json:"created"`
+	Entries []checkpointEntry `json:"entries"`
+}
+
+type checkpointEntry struct {
+	ID         string    `json:"id"`
+	LastAccess time.Time `json:"lastAccess"`
+	Size       int64     `json:"size"`
+	Hits       int64     `json:"hits,omitempty"`
+	Pinned     bool      `json:"pinned,omitempty"`
+}
+
+// Checkpoint writing the state of the cache entries to the cache volume
+func (f *FastCache) Checkpoint() error {
+	f.cpm.Lock()
+	defer f.cpm.Unlock()
+	f.dirty.Store(false)
+	es := f.entries.Entries()
+	cp := checkpoint{
+		Version: checkpointVersion,
+		Created: time.Now(),
+		Entries: make([]checkpointEntry, len(es)),
+	}
+	for x, e := range es {
+		cp.Entries[x] = checkpointEntry{
+			ID:         e.Description.BlobID,
+			LastAccess: e.LastAccess,
+			Size:       e.Size,
+			Hits:       e.Hits,
+			Pinned:     e.Pinned,
+		}
+	}
+	js, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	cpFile := filepath.Join(f.RootPath, CheckpointFile)
+	tmpFile := cpFile + ".tmp"
+	err = os.WriteFile(tmpFile, js, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, cpFile)
+}
+
+// load restoring the cache from the checkpoint, without a valid checkpoint the cache files are rescanned.
+// Entries with missing or corrupt files are dropped, files without a valid entry are removed.
+func (f *FastCache) load() error {
+	es, err := f.readCheckpoint()
+	if err != nil {
+		logger.Infof("fastcache: no valid checkpoint, rescanning %s: %v", f.RootPath, err)
+		es, err = f.rescan()
+		if err != nil {
+			return err
+		}
+	}
+	// adding in access order restores the state of the policy
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].LastAccess.Before(es[j].LastAccess)
+	})
+	valid := make(map[string]bool)
+	for _, e := range es {
+		f.entries.Add(e)
+		valid[e.Description.BlobID] = true
+	}
+	removed, err := f.removeOrphans(valid)
+	if err != nil {
+		return err
+	}
+	f.bfDirty = true
+	f.rebuildBloomFilter()
+	// the limits may have been changed
+	f.evict()
+	f.rebuildBloomFilter()
+	logger.Infof("fastcache: loaded %d entries, removed %d files", f.entries.Size(), removed)
+	return nil
+}
+
+// readCheckpoint reading the checkpoint and validating every entry against the files on disk
+func (f *FastCache) readCheckpoint() ([]Entry, error) {
+	js, err := os.ReadFile(filepath.Join(f.RootPath, CheckpointFile))
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	err = json.Unmarshal(js, &cp)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unknown checkpoint version %d", cp.Version)
+	}
+	es := make([]Entry, 0, len(cp.Entries))
+	for _, ce := range cp.Entries {
+		e, err := f.loadEntry(ce.ID)
+		if err != nil || e.Size != ce.Size {
+			logger.Debugf("fastcache: dropping entry %s: %v", ce.ID, err)
+			continue
+		}
+		e.LastAccess = ce.LastAccess
+		e.Hits = ce.Hits
+		e.Pinned = ce.Pinned
+		es = append(es, e)
+	}
+	return es, nil
+}
+
+// rescan building the entries from the description and binary files, the last access is the modification time of the binary
+func (f *FastCache) rescan() ([]Entry, error) {
+	es := make([]Entry, 0)
+	err := f.walkFiles(func(path string, d fs.DirEntry) {
+		if !strings.HasSuffix(d.Name(), DescriptionExt) {
+			return
+		}
+		id := strings.TrimSuffix(d.Name(), DescriptionExt)
+		e, err := f.loadEntry(id)
+		if err != nil {
+			logger.Debugf("fastcache: dropping entry %s: %v", id, err)
+			return
+		}
+		if info, err := os.Stat(strings.TrimSuffix(path, DescriptionExt) + BinaryExt); err == nil {
+			e.LastAccess = info.ModTime()
+		}
+		es = append(es, e)
+	})
+	return es, err
+}
+
+// loadEntry reading the description of the blob and checking the binary file
+func (f *FastCache) loadEntry(id string) (Entry, error) {
+	if len(id) < 2 {
+		return Entry{}, errors.New("invalid id")
+	}
+	js, err := os.ReadFile(f.filename(id, DescriptionExt))
+	if err != nil {
+		return Entry{}, err
+	}
+	var b model.BlobDescription
+	err = json.Unmarshal(js, &b)
+	if err != nil {
+		return Entry{}, err
+	}
+	if b.BlobID != id {
+		return Entry{}, fmt.Errorf("wrong id in description: %s", b.BlobID)
+	}
+	binFile := f.filename(id, BinaryExt)
+	info, err := os.Stat(binFile)
+	if err != nil {
+		return Entry{}, err
+	}
+	if b.ContentLength > 0 && b.ContentLength != info.Size() {
+		return Entry{}, fmt.Errorf("wrong size %d != %d", info.Size(), b.ContentLength)
+	}
+	e := Entry{
+		LastAccess:  info.ModTime(),
+		Description: b,
+		Size:        info.Size(),
+	}
+	if info.Size() < f.MaxFileSizeForRAM {
+		if dat, err := os.ReadFile(binFile); err == nil {
+			e.Data = dat
+		}
+	}
+	return e, nil
+}
+
+// removeOrphans removing all files in the blob folders, which don't belong to a valid entry
+func (f *FastCache) removeOrphans(valid map[string]bool) (int, error) {
+	count := 0
+	err := f.walkFiles(func(path string, d fs.DirEntry) {
+		id := strings.TrimSuffix(d.Name(), filepath.Ext(d.Name()))
+		if valid[id] {
+			return
+		}
+		if err := os.Remove(path); err != nil {
+			logger.Errorf("fastcache: can't remove %s: %v", path, err)
+			return
+		}
+		count++
+	})
+	return count, err
+}
+
+// walkFiles walking thru all files of the blob folders
+func (f *FastCache) walkFiles(callback func(path string, d fs.DirEntry)) error {
+	dirs, err := os.ReadDir(f.RootPath)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		fp := filepath.Join(f.RootPath, dir.Name())
+		files, err := os.ReadDir(fp)
+		if err != nil {
+			return err
+		}
+		for _, d := range files {
+			if !d.IsDir() {
+				callback(filepath.Join(fp, d.Name()), d)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package fastcache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const payload = "this is a blob content"
+
+func getPersistentSrv(t *testing.T) *FastCache {
+	srv := FastCache{
+		RootPath:   rootpath,
+		MaxCount:   20,
+		MaxRAMSize: 1 * 1024 * 1024 * 1024,
+		Persistent: true,
+	}
+	err := srv.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &srv
+}
+
+func fillCache(t *testing.T, srv *FastCache, count int) []string {
+	ids := make([]string, 0)
+	for i := 0; i < count; i++ {
+		b := getBlobDescription(strconv.Itoa(i))
+		id, err := srv.StoreBlob(b, strings.NewReader(payload))
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestPersistent(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	srv := getPersistentSrv(t)
+	ids := fillCache(t, srv, 10)
+	// the first blob is the most recently used
+	time.Sleep(10 * time.Millisecond)
+	_, err := srv.GetBlobDescription(ids[0])
+	ast.Nil(err)
+	ast.Nil(srv.Close())
+	ast.FileExists(filepath.Join(rootpath, CheckpointFile))
+
+	srv = getPersistentSrv(t)
+	ast.ElementsMatch(ids, getFiles(t, srv))
+	for _, id := range ids {
+		var buf bytes.Buffer
+		ast.Nil(srv.RetrieveBlob(id, &buf))
+		ast.Equal(payload, buf.String())
+	}
+	e, ok := srv.entries.Get(ids[0])
+	ast.True(ok)
+	ast.Equal(e.Description.Filename, "0")
+
+	ast.Nil(srv.Close())
+
+	// the LRU state is restored, so with a lower limit the least recently used blobs are evicted first
+	srv = &FastCache{
+		RootPath:   rootpath,
+		MaxCount:   5,
+		MaxRAMSize: 1 * 1024 * 1024 * 1024,
+		Persistent: true,
+	}
+	ast.Nil(srv.Init())
+	files := getFiles(t, srv)
+	ast.Len(files, 5)
+	ast.Contains(files, ids[0])
+	ast.NotContains(files, ids[1])
+	ast.NoFileExists(srv.filename(ids[1], BinaryExt))
+	ast.Nil(srv.Close())
+}
+
+func TestCheckpointValidation(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	srv := getPersistentSrv(t)
+	ids := fillCache(t, srv, 5)
+	ast.Nil(srv.Close())
+
+	// a missing binary, a corrupt binary, a corrupt description and an orphan file
+	ast.Nil(os.Remove(srv.filename(ids[0], BinaryExt)))
+	ast.Nil(os.WriteFile(srv.filename(ids[1], BinaryExt), []byte("corrupt"), os.ModePerm))
+	ast.Nil(os.WriteFile(srv.filename(ids[2], DescriptionExt), []byte("{corrupt"), os.ModePerm))
+	orphan := filepath.Join(rootpath, ids[3][:2], "0123456789"+BinaryExt)
+	ast.Nil(os.WriteFile(orphan, []byte(payload), os.ModePerm))
+
+	srv = getPersistentSrv(t)
+	ast.ElementsMatch(ids[3:], getFiles(t, srv))
+	ast.NoFileExists(orphan)
+	ast.NoFileExists(srv.filename(ids[1], BinaryExt))
+	ast.NoFileExists(srv.filename(ids[2], BinaryExt))
+	ast.Nil(srv.Close())
+}
+
+func TestRescan(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	srv := getPersistentSrv(t)
+	ids := fillCache(t, srv, 5)
+	ast.Nil(srv.Close())
+
+	// without a valid checkpoint all files are rescanned
+	ast.Nil(os.WriteFile(filepath.Join(rootpath, CheckpointFile), []byte("{corrupt"), os.ModePerm))
+	srv = getPersistentSrv(t)
+	ast.ElementsMatch(ids, getFiles(t, srv))
+	ast.Nil(srv.Close())
+
+	ast.Nil(os.Remove(filepath.Join(rootpath, CheckpointFile)))
+	srv = getPersistentSrv(t)
+	ast.ElementsMatch(ids, getFiles(t, srv))
+	ast.Nil(srv.DeleteBlob(ids[0]))
+	ast.NoFileExists(srv.filename(ids[0], DescriptionExt))
+	ast.Nil(srv.Close())
+
+	// a not persistent cache is cleared on startup
+	srv = getStoreageSrv(t)
+	ast.Empty(getFiles(t, srv))
+	ast.Nil(srv.Close())
+}
+
+func TestPin(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	srv := getPersistentSrv(t)
+	ids := fillCache(t, srv, 5)
+	ast.ErrorIs(srv.Pin("unknown", true), os.ErrNotExist)
+	ast.Nil(srv.Pin(ids[0], true))
+	ast.Nil(srv.Pin(ids[0], true))
+	for x := 0; x < 3; x++ {
+		_, err := srv.GetBlobDescription(ids[4])
+		ast.Nil(err)
+	}
+	ces := srv.CacheEntries("", OrderHot, 2)
+	ast.Len(ces, 2)
+	ast.Equal(ids[4], ces[0].BlobID)
+	ast.Equal(int64(3), ces[0].Hits)
+	ces = srv.CacheEntries("", OrderOld, 0)
+	ast.Len(ces, 5)
+	ast.Equal(ids[0], ces[0].BlobID)
+	ast.True(ces[0].Pinned)
+	ast.Len(srv.CacheEntries("unknown", "", 0), 0)
+	ast.Nil(srv.Close())
+
+	// the pin is kept over a restart and the pinned blob is never evicted
+	srv = getPersistentSrv(t)
+	st := srv.Stats()
+	ast.Equal(1, st.Pinned)
+	ast.Equal(int64(5*len(payload)), st.RAMSize)
+	srv.SetLimits(2, 0)
+	ast.ElementsMatch([]string{ids[0], ids[4]}, getFiles(t, srv))
+
+	// unpinning evicts the blob, if the cache is over its limits
+	ast.Nil(srv.Pin(ids[4], true))
+	srv.SetLimits(1, 0)
+	ast.ElementsMatch([]string{ids[0], ids[4]}, getFiles(t, srv))
+	ast.Nil(srv.Pin(ids[4], false))
+	ast.ElementsMatch([]string{ids[0]}, getFiles(t, srv))
+	ast.Equal(1, srv.Stats().Pinned)
+	ast.Nil(srv.Close())
+}
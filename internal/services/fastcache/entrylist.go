@@ -0,0 +1,325 @@
+package fastcache
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// Entry one entry of the cache
+type Entry struct {
+	LastAccess  time.Time             `json:"lastAccess"`
+	Description model.BlobDescription `json:"description"`
+	Data        []byte                `json:"data"`
+	Size        int64                 `json:"size"`
+	Hits        int64                 `json:"hits"`
+	Pinned      bool                  `json:"pinned"` // a pinned entry is never evicted
+}
+
+// item the stored entry with its position in the ram list
+type item struct {
+	Entry
+	ram *list.Element
+}
+
+// EntryList the full list of cache entries, the entry to evict is chosen by the policy
+type EntryList struct {
+	MaxCount   int
+	MaxSize    int64 // maximum size of all entries, 0 for no limit
+	MaxRAMSize int64
+	Policy     Policy
+	entries    map[string]*item
+	ram        *list.List // entries with data in memory, the least recently used at the back
+	dmu        sync.Mutex
+	ramsize    int64
+	size       int64
+	pinned     int
+}
+
+// Init initialise this entry list, without a policy a LRU policy is used
+func (l *EntryList) Init() {
+	l.entries = make(map[string]*item)
+	l.ram = list.New()
+	l.ramsize = 0
+	l.size = 0
+	l.pinned = 0
+	if l.Policy == nil {
+		l.Policy = NewLRU()
+	}
+}
+
+// Size getting the size of the list
+func (l *EntryList) Size() int {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	return len(l.entries)
+}
+
+// Add add a new entry to the list
+func (l *EntryList) Add(e Entry) bool {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	id := e.Description.BlobID
+	if _, ok := l.entries[id]; ok {
+		l.delete(id)
+	}
+	it := &item{Entry: e}
+	l.entries[id] = it
+	l.size += e.Size
+	if it.Data != nil {
+		it.ram = l.ram.PushFront(id)
+		l.ramsize += int64(len(it.Data))
+	}
+	if it.Pinned {
+		l.pinned++
+	} else {
+		l.Policy.Add(&it.Entry)
+	}
+	return true
+}
+
+// Pin pinning or unpinning an entry, a pinned entry is not known to the policy, so it is never evicted
+func (l *EntryList) Pin(id string, pin bool) bool {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	it, ok := l.entries[id]
+	if !ok {
+		return false
+	}
+	if it.Pinned == pin {
+		return true
+	}
+	it.Pinned = pin
+	if pin {
+		l.pinned++
+		l.Policy.Remove(id)
+	} else {
+		l.pinned--
+		l.Policy.Add(&it.Entry)
+	}
+	return true
+}
+
+// Update updating the description of an entry
+func (l *EntryList) Update(e Entry) {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	if it, ok := l.entries[e.Description.BlobID]; ok {
+		it.Description = e.Description
+		l.access(it)
+	}
+}
+
+// UpdateAccess updates the access time of an entry
+func (l *EntryList) UpdateAccess(id string) {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	if it, ok := l.entries[id]; ok {
+		l.access(it)
+	}
+}
+
+// SetLimits changing the maximum count and size, HandleContrains is evicting the entries over the limits
+func (l *EntryList) SetLimits(maxCount int, maxSize int64) {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	l.MaxCount = maxCount
+	l.MaxSize = maxSize
+}
+
+// Limits getting the maximum count and size
+func (l *EntryList) Limits() (int, int64) {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	return l.MaxCount, l.MaxSize
+}
+
+// Pinned getting the count of pinned entries
+func (l *EntryList) Pinned() int {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	return l.pinned
+}
+
+// RAMBytes getting the size of the data of all entries in memory
+func (l *EntryList) RAMBytes() int64 {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	return l.ramsize
+}
+
+// Bytes getting the size of all entries
+func (l *EntryList) Bytes() int64 {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	return l.size
+}
+
+// GetFullIDList getting a copy of the full id list of all entries, sorted by id
+func (l *EntryList) GetFullIDList() []string {
+	l.dmu.Lock()
+	ids := make([]string, 0, len(l.entries))
+	for id := range l.entries {
+		ids = append(ids, id)
+	}
+	l.dmu.Unlock()
+	sort.Strings(ids)
+	return ids
+}
+
+// Entries getting a copy of all entries without the data, sorted by id
+func (l *EntryList) Entries() []Entry {
+	l.dmu.Lock()
+	es := make([]Entry, 0, len(l.entries))
+	for _, it := range l.entries {
+		e := it.Entry
+		e.Data = nil
+		es = append(es, e)
+	}
+	l.dmu.Unlock()
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].Description.BlobID < es[j].Description.BlobID
+	})
+	return es
+}
+
+// CacheEntries getting the infos of all entries, sorted by id
+func (l *EntryList) CacheEntries() []model.CacheEntry {
+	l.dmu.Lock()
+	ces := make([]model.CacheEntry, 0, len(l.entries))
+	for _, it := range l.entries {
+		ces = append(ces, it.cacheEntry())
+	}
+	l.dmu.Unlock()
+	sort.Slice(ces, func(i, j int) bool {
+		return ces[i].BlobID < ces[j].BlobID
+	})
+	return ces
+}
+
+// CacheEntry getting the info of an entry, this doesn't count as an access
+func (l *EntryList) CacheEntry(id string) (model.CacheEntry, bool) {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	it, ok := l.entries[id]
+	if !ok {
+		return model.CacheEntry{}, false
+	}
+	return it.cacheEntry(), true
+}
+
+// HandleContrains doing the self reoganising, returning the id of the entry to evict or "" if the limits are kept
+func (l *EntryList) HandleContrains() string {
+	var id string
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	for len(l.entries) > l.MaxCount || (l.MaxSize > 0 && l.size > l.MaxSize) {
+		id = l.Policy.Victim()
+		if id == "" {
+			break
+		}
+		if _, ok := l.entries[id]; ok {
+			break
+		}
+		// the policy is out of sync, this entry is already gone
+		l.Policy.Remove(id)
+		id = ""
+	}
+	if l.MaxRAMSize > 0 {
+		for l.ramsize > l.MaxRAMSize {
+			oldest := l.ram.Back()
+			if oldest == nil {
+				l.ramsize = 0
+				break
+			}
+			l.dropData(l.entries[oldest.Value.(string)])
+		}
+	}
+	return id
+}
+
+// Has checking the existence of an entry
+func (l *EntryList) Has(id string) bool {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	_, ok := l.entries[id]
+	return ok
+}
+
+// Get getting an entry if present, this counts as an access to the entry
+func (l *EntryList) Get(id string) (Entry, bool) {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	it, ok := l.entries[id]
+	if !ok {
+		return Entry{}, false
+	}
+	l.access(it)
+	return it.Entry, true
+}
+
+// Peek getting an entry if present, without counting an access to the entry
+func (l *EntryList) Peek(id string) (Entry, bool) {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	it, ok := l.entries[id]
+	if !ok {
+		return Entry{}, false
+	}
+	return it.Entry, true
+}
+
+// Delete removing an entry from the list
+func (l *EntryList) Delete(id string) string {
+	l.dmu.Lock()
+	defer l.dmu.Unlock()
+	if _, ok := l.entries[id]; ok {
+		l.delete(id)
+		return id
+	}
+	return ""
+}
+
+func (l *EntryList) delete(id string) {
+	it := l.entries[id]
+	l.dropData(it)
+	l.size -= it.Size
+	if it.Pinned {
+		l.pinned--
+	}
+	delete(l.entries, id)
+	l.Policy.Remove(id)
+}
+
+func (l *EntryList) access(it *item) {
+	it.LastAccess = time.Now()
+	it.Hits++
+	if it.ram != nil {
+		l.ram.MoveToFront(it.ram)
+	}
+	l.Policy.Access(it.Description.BlobID)
+}
+
+func (it *item) cacheEntry() model.CacheEntry {
+	return model.CacheEntry{
+		BlobID:     it.Description.BlobID,
+		TenantID:   it.Description.TenantID,
+		Size:       it.Size,
+		Hits:       it.Hits,
+		LastAccess: it.LastAccess,
+		InRAM:      it.Data != nil,
+		Pinned:     it.Pinned,
+	}
+}
+
+func (l *EntryList) dropData(it *item) {
+	if it.ram != nil {
+		l.ram.Remove(it.ram)
+		it.ram = nil
+	}
+	l.ramsize -= int64(len(it.Data))
+	it.Data = nil
+}
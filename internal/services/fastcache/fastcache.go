@@ -2,11 +2,13 @@
 package fastcache
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,6 +27,12 @@ const (
 	Defaultmffrs = 100 * 1024
 	// DefaultTnt the default tenant. Because this storage isn't tenant specific
 	DefaultTnt = "n.n."
+	// DefaultName the name of a not partitioned cache
+	DefaultName = "default"
+	// OrderHot ordering the cache entries by hits, the most used first
+	OrderHot = "hot"
+	// OrderOld ordering the cache entries by last access, the least recently used first
+	OrderOld = "old"
 )
 
 var (
@@ -37,21 +45,32 @@ var (
 // FastCache a fast cache implementation using a mix of memory and fast ssd storage
 type FastCache struct {
 	RootPath          string // this is the root path for the file system storage
+	Name              string // the name of the cache partition, used for the metrics
 	MaxCount          int64
+	MaxSize           int64 // maximum size of all blobs in bytes, 0 for no limit
 	MaxRAMSize        int64
 	MaxFileSizeForRAM int64
-	size              int64
-	count             int64
-	entries           LRUList
+	Persistent        bool   // keeping the cache over restarts, otherwise the cache is cleared on startup
+	Policy            string // the eviction policy, one of lru, lfu, tinylfu or gdsf, default is lru
+	entries           EntryList
 	bf                bloomfilter.BloomFilter
 	bfDirty           bool
 	bfm               sync.Mutex
 	background        *time.Ticker
 	quit              chan bool
+	dirty             atomic.Bool // the state of the entries is changed since the last checkpoint
+	cpm               sync.Mutex
+	hits              atomic.Int64
+	misses            atomic.Int64
+	evictions         atomic.Int64
+	closed            atomic.Bool
 }
 
 // Checking interface compatibility
-var _ interfaces.BlobStorage = &FastCache{}
+var (
+	_ interfaces.BlobStorage   = &FastCache{}
+	_ interfaces.HintedStorage = &FastCache{}
+)
 
 // Init initialize this service
 func (f *FastCache) Init() error {
@@ -60,17 +79,26 @@ func (f *FastCache) Init() error {
 		return err
 	}
 
-	f.count = 0
-	f.size = 0
+	if f.Name == "" {
+		f.Name = DefaultName
+	}
 
-	err = f.removeContents(f.RootPath)
+	if !f.Persistent {
+		err = f.removeContents(f.RootPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	policy, err := NewPolicy(f.Policy, int(f.MaxCount))
 	if err != nil {
 		return err
 	}
-
-	f.entries = LRUList{
+	f.entries = EntryList{
 		MaxCount:   int(f.MaxCount),
+		MaxSize:    f.MaxSize,
 		MaxRAMSize: f.MaxRAMSize,
+		Policy:     policy,
 	}
 
 	f.entries.Init()
@@ -82,6 +110,12 @@ func (f *FastCache) Init() error {
 	// initialise the bloomfilter
 	f.bf = *bloomfilter.NewBloomFilter(uint64(f.MaxCount), 0.1)
 	f.bfDirty = false
+	if f.Persistent {
+		err = f.load()
+		if err != nil {
+			return err
+		}
+	}
 	f.background = time.NewTicker(60 * time.Second)
 	f.quit = make(chan bool)
 	go func() {
@@ -89,6 +123,11 @@ func (f *FastCache) Init() error {
 			select {
 			case <-f.background.C:
 				f.rebuildBloomFilter()
+				if f.Persistent && f.dirty.Load() {
+					if err := f.Checkpoint(); err != nil {
+						logger.Errorf("fastcache: error writing checkpoint: %v", err)
+					}
+				}
 			case <-f.quit:
 				f.background.Stop()
 				return
@@ -154,24 +193,43 @@ func (f *FastCache) StoreBlob(b *model.BlobDescription, r io.Reader) (string, er
 		logger.Errorf("cache: writing file: %v", err)
 		return "", err
 	}
-	atomic.AddInt64(&f.size, size)
-	f.entries.Add(LRUEntry{
+	if f.Persistent {
+		// the description is written after the binary, so a blob with description is complete
+		err = f.writeDescFile(b)
+		if err != nil {
+			logger.Errorf("cache: writing description: %v", err)
+			_ = f.deleteBlobFile(b.BlobID)
+			return "", err
+		}
+	}
+	f.entries.Add(Entry{
 		LastAccess:  time.Now(),
 		Description: *b,
 		Data:        dat,
+		Size:        size,
 	})
+	f.dirty.Store(true)
+	f.updateBloom(b.BlobID)
+	f.evict()
+	return b.BlobID, nil
+}
+
+// evict removing blobs, until the limits are kept
+func (f *FastCache) evict() {
 	for {
 		id := f.entries.HandleContrains()
 		if id == "" {
 			break
 		}
-		err = f.DeleteBlob(id)
+		err := f.DeleteBlob(id)
 		if err != nil {
 			logger.Errorf("cache: can't delete blob %s: %v", id, err)
+			// the entry must go anyway, otherwise it is chosen again
+			f.entries.Delete(id)
 		}
+		f.evictions.Add(1)
+		cacheEvictions.WithLabelValues(f.Name).Inc()
 	}
-	f.updateBloom(b.BlobID)
-	return b.BlobID, nil
 }
 
 // UpdateBlobDescription updating the blob description
@@ -190,11 +248,32 @@ func (f *FastCache) UpdateBlobDescription(id string, b *model.BlobDescription) e
 		if ok {
 			l.Description = *b
 			f.entries.Update(l)
+			f.dirty.Store(true)
+			if f.Persistent {
+				return f.writeDescFile(b)
+			}
 		}
 	}
 	return nil
 }
 
+func (f *FastCache) writeDescFile(b *model.BlobDescription) error {
+	js, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	descFile, err := f.buildFilename(b.BlobID, DescriptionExt)
+	if err != nil {
+		return err
+	}
+	tmpFile := descFile + ".tmp"
+	err = os.WriteFile(tmpFile, js, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, descFile)
+}
+
 func (f *FastCache) writeBinFile(id string, r io.Reader) (int64, []byte, error) {
 	binFile, err := f.buildFilename(id, BinaryExt)
 	if err != nil {
@@ -236,6 +315,11 @@ func (f *FastCache) buildFilename(id string, ext string) (string, error) {
 	return filepath.Join(fp, fmt.Sprintf("%s%s", id, ext)), nil
 }
 
+// filename the name of the file without creating the folder
+func (f *FastCache) filename(id string, ext string) string {
+	return filepath.Join(f.RootPath, id[:2], fmt.Sprintf("%s%s", id, ext))
+}
+
 // HasBlob checking, if a blob is present
 func (f *FastCache) HasBlob(id string) (bool, error) {
 	if id == "" {
@@ -257,6 +341,7 @@ func (f *FastCache) GetBlobDescription(id string) (*model.BlobDescription, error
 	if f.inBloom(id) {
 		l, ok := f.entries.Get(id)
 		if ok {
+			f.dirty.Store(true)
 			return &l.Description, nil
 		}
 	}
@@ -265,12 +350,30 @@ func (f *FastCache) GetBlobDescription(id string) (*model.BlobDescription, error
 
 // RetrieveBlob retrieving the binary data from the storage system
 func (f *FastCache) RetrieveBlob(id string, w io.Writer) error {
+	return f.RetrieveBlobWithHint(id, w, interfaces.CacheDefault)
+}
+
+// RetrieveBlobWithHint retrieving the binary data from the storage system, with CacheBypass the read is not counted,
+// neither as an access for the eviction policy nor as a hit or miss
+func (f *FastCache) RetrieveBlobWithHint(id string, w io.Writer, hint interfaces.CacheHint) error {
 	if id == "" {
 		return errEmptyIndex
 	}
+	bypass := hint == interfaces.CacheBypass
 	if f.inBloom(id) {
-		l, ok := f.entries.Get(id)
+		var l Entry
+		var ok bool
+		if bypass {
+			l, ok = f.entries.Peek(id)
+		} else {
+			l, ok = f.entries.Get(id)
+		}
 		if ok {
+			if !bypass {
+				f.dirty.Store(true)
+				f.hits.Add(1)
+				cacheHits.WithLabelValues(f.Name).Inc()
+			}
 			// checking memory cache
 			if l.Data != nil {
 				_, err := w.Write(l.Data)
@@ -286,6 +389,10 @@ func (f *FastCache) RetrieveBlob(id string, w io.Writer) error {
 			return nil
 		}
 	}
+	if !bypass {
+		f.misses.Add(1)
+		cacheMisses.WithLabelValues(f.Name).Inc()
+	}
 	return os.ErrNotExist
 }
 
@@ -324,6 +431,7 @@ func (f *FastCache) DeleteBlob(id string) error {
 					return err
 				}
 				f.bfDirty = true
+				f.dirty.Store(true)
 			}
 			return nil
 		}
@@ -331,6 +439,93 @@ func (f *FastCache) DeleteBlob(id string) error {
 	return os.ErrNotExist
 }
 
+// Stats getting the statistics of this cache
+func (f *FastCache) Stats() model.CacheStats {
+	maxCount, maxSize := f.entries.Limits()
+	return model.CacheStats{
+		Partition: f.Name,
+		Policy:    f.entries.Policy.Name(),
+		Count:     f.entries.Size(),
+		Size:      f.entries.Bytes(),
+		RAMSize:   f.entries.RAMBytes(),
+		Pinned:    f.entries.Pinned(),
+		MaxCount:  int64(maxCount),
+		MaxSize:   maxSize,
+		Hits:      f.hits.Load(),
+		Misses:    f.misses.Load(),
+		Evictions: f.evictions.Load(),
+	}
+}
+
+// CacheEntries getting the infos of the blobs of the tenant, with an empty tenant of all blobs.
+// The entries are ordered by OrderHot or OrderOld, otherwise by id. With a limit > 0 only the first entries are returned.
+func (f *FastCache) CacheEntries(tenant, order string, limit int) []model.CacheEntry {
+	ces := f.entries.CacheEntries()
+	if tenant != "" {
+		tces := make([]model.CacheEntry, 0)
+		for _, ce := range ces {
+			if ce.TenantID == tenant {
+				tces = append(tces, ce)
+			}
+		}
+		ces = tces
+	}
+	switch order {
+	case OrderHot:
+		sort.SliceStable(ces, func(i, j int) bool {
+			return ces[i].Hits > ces[j].Hits
+		})
+	case OrderOld:
+		sort.SliceStable(ces, func(i, j int) bool {
+			return ces[i].LastAccess.Before(ces[j].LastAccess)
+		})
+	}
+	if limit > 0 && len(ces) > limit {
+		ces = ces[:limit]
+	}
+	return ces
+}
+
+// CacheEntry getting the info of a blob in the cache, this doesn't count as an access of the blob
+func (f *FastCache) CacheEntry(id string) (model.CacheEntry, bool) {
+	return f.entries.CacheEntry(id)
+}
+
+// Pin pinning or unpinning a blob, a pinned blob is never evicted. If the blob is not in the cache os.ErrNotExist is returned.
+func (f *FastCache) Pin(id string, pin bool) error {
+	if !f.entries.Pin(id, pin) {
+		return os.ErrNotExist
+	}
+	f.dirty.Store(true)
+	if !pin {
+		f.evict()
+	}
+	return nil
+}
+
+// Clear removing all blobs of the tenant from the cache, with an empty tenant all blobs are removed
+func (f *FastCache) Clear(tenant string) (int, error) {
+	count := 0
+	for _, e := range f.entries.Entries() {
+		if tenant != "" && e.Description.TenantID != tenant {
+			continue
+		}
+		err := f.DeleteBlob(e.Description.BlobID)
+		if err != nil && !os.IsNotExist(err) {
+			return count, err
+		}
+		count++
+	}
+	f.rebuildBloomFilter()
+	return count, nil
+}
+
+// SetLimits changing the maximum count and size of the cache, blobs over the new limits are evicted
+func (f *FastCache) SetLimits(maxCount, maxSize int64) {
+	f.entries.SetLimits(int(maxCount), maxSize)
+	f.evict()
+}
+
 // CheckBlob checking a single blob from the storage system
 func (f *FastCache) CheckBlob(id string) (*model.CheckInfo, error) {
 	return utils.CheckBlob(id, f)
@@ -348,6 +543,12 @@ func (f *FastCache) deleteBlobFile(id string) error {
 	if err != nil {
 		return err
 	}
+	if f.Persistent {
+		err = os.Remove(f.filename(id, DescriptionExt))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -415,8 +616,14 @@ func (f *FastCache) GetLastError() error {
 	return nil
 }
 
-// Close closing the storage
+// Close closing the storage, a persistent cache writes a last checkpoint
 func (f *FastCache) Close() error {
+	if f.closed.Swap(true) {
+		return nil
+	}
 	f.quit <- true
+	if f.Persistent {
+		return f.Checkpoint()
+	}
 	return nil
 }
@@ -0,0 +1,22 @@
+package fastcache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// the metrics of the cache, labeled with the name of the partition
+var (
+	cacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goblobstore_cache_hits_total",
+		Help: "count of blobs delivered from the cache",
+	}, []string{"partition"})
+	cacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goblobstore_cache_misses_total",
+		Help: "count of blobs not found in the cache",
+	}, []string{"partition"})
+	cacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goblobstore_cache_evictions_total",
+		Help: "count of blobs evicted from the cache",
+	}, []string{"partition"})
+)
@@ -0,0 +1,245 @@
+package fastcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const (
+	// TenantsDir the folder of the tenant partitions in the root path
+	TenantsDir = "tenants"
+	// GroupsDir the folder of the group partitions in the root path
+	GroupsDir = "groups"
+	// GroupPrefix the prefix of the name of a group partition
+	GroupPrefix = "group:"
+)
+
+// Group a group of tenants sharing one cache partition
+type Group struct {
+	Name    string
+	Tenants []string
+	Quota   config.CacheQuota
+}
+
+// Partitions the cache partitioned by tenants. Every tenant gets its own fast cache in a sub folder of the root path,
+// so one tenant can't evict the blobs of the others. The tenants of a group are sharing one partition.
+type Partitions struct {
+	RootPath          string
+	MaxRAMSize        int64 // per partition
+	MaxFileSizeForRAM int64
+	Persistent        bool
+	Policy            string
+	Default           config.CacheQuota // limits of a tenant partition
+	Groups            []Group
+	groups            map[string]*Group // group by tenant
+	caches            map[string]*FastCache
+	pm                sync.Mutex
+}
+
+// Init initialize the partitions, the partitions are created on first usage
+func (p *Partitions) Init() error {
+	if p.Default.MaxCount <= 0 {
+		return fmt.Errorf("fastcache: no maxcount for the partitions")
+	}
+	p.caches = make(map[string]*FastCache)
+	p.groups = make(map[string]*Group)
+	for x := range p.Groups {
+		g := &p.Groups[x]
+		if g.Name == "" {
+			return fmt.Errorf("fastcache: group %d without name", x)
+		}
+		if g.Quota.MaxCount <= 0 {
+			g.Quota.MaxCount = p.Default.MaxCount
+		}
+		for _, t := range g.Tenants {
+			if o, ok := p.groups[t]; ok {
+				return fmt.Errorf("fastcache: tenant %s is in group %s and %s", t, o.Name, g.Name)
+			}
+			p.groups[t] = g
+		}
+	}
+	err := os.MkdirAll(p.RootPath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	// removing the files of a not partitioned cache
+	names, err := os.ReadDir(p.RootPath)
+	if err != nil {
+		return err
+	}
+	for _, n := range names {
+		if n.Name() == TenantsDir || n.Name() == GroupsDir {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(p.RootPath, n.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get getting the cache partition of the tenant. The quota of the tenant config overrides the default limits of a tenant partition,
+// for the tenants of a group the limits of the group are used.
+func (p *Partitions) Get(tenant string, quota *config.CacheQuota) (*FastCache, error) {
+	p.pm.Lock()
+	defer p.pm.Unlock()
+	return p.get(tenant, quota)
+}
+
+func (p *Partitions) get(tenant string, quota *config.CacheQuota) (*FastCache, error) {
+	name, path, q := p.partition(tenant, quota)
+	if c, ok := p.caches[name]; ok {
+		if mc, ms := c.entries.Limits(); int64(mc) != q.MaxCount || ms != q.MaxSize {
+			c.SetLimits(q.MaxCount, q.MaxSize)
+		}
+		return c, nil
+	}
+	c := &FastCache{
+		RootPath:          filepath.Join(p.RootPath, path),
+		Name:              name,
+		MaxCount:          q.MaxCount,
+		MaxSize:           q.MaxSize,
+		MaxRAMSize:        p.MaxRAMSize,
+		MaxFileSizeForRAM: p.MaxFileSizeForRAM,
+		Persistent:        p.Persistent,
+		Policy:            p.Policy,
+	}
+	err := c.Init()
+	if err != nil {
+		return nil, err
+	}
+	p.caches[name] = c
+	return c, nil
+}
+
+// partition getting the name, the folder and the limits of the partition of the tenant
+func (p *Partitions) partition(tenant string, quota *config.CacheQuota) (string, string, config.CacheQuota) {
+	if g, ok := p.groups[tenant]; ok {
+		return GroupPrefix + g.Name, filepath.Join(GroupsDir, g.Name), g.Quota
+	}
+	q := p.Default
+	if quota != nil {
+		if quota.MaxCount > 0 {
+			q.MaxCount = quota.MaxCount
+		}
+		if quota.MaxSize > 0 {
+			q.MaxSize = quota.MaxSize
+		}
+	}
+	return tenant, filepath.Join(TenantsDir, tenant), q
+}
+
+// Stats getting the statistics of all created partitions, sorted by name
+func (p *Partitions) Stats() []model.CacheStats {
+	p.pm.Lock()
+	defer p.pm.Unlock()
+	sts := make([]model.CacheStats, 0, len(p.caches))
+	for _, c := range p.caches {
+		st := c.Stats()
+		for _, g := range p.Groups {
+			if GroupPrefix+g.Name == st.Partition {
+				st.Tenants = g.Tenants
+			}
+		}
+		sts = append(sts, st)
+	}
+	sort.Slice(sts, func(i, j int) bool {
+		return sts[i].Partition < sts[j].Partition
+	})
+	return sts
+}
+
+// Clear removing all blobs of the tenant from its partition, the other tenants of a group are not affected
+func (p *Partitions) Clear(tenant string) (int, error) {
+	p.pm.Lock()
+	defer p.pm.Unlock()
+	name, _, _ := p.partition(tenant, nil)
+	c, ok := p.caches[name]
+	if !ok {
+		// a persistent partition is maybe not loaded yet
+		var err error
+		c, err = p.get(tenant, nil)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return c.Clear(tenant)
+}
+
+// Close closing all partitions
+func (p *Partitions) Close() error {
+	p.pm.Lock()
+	defer p.pm.Unlock()
+	var err error
+	for _, c := range p.caches {
+		if cerr := c.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// ParsePartitions getting the default limits and the groups of the partitions from the config.
+// A missing maxcount is taken from the cache config.
+func ParsePartitions(cfg map[string]any) (config.CacheQuota, []Group, error) {
+	q, err := parseQuota(cfg)
+	if err != nil {
+		return q, nil, err
+	}
+	groups := make([]Group, 0)
+	gc, ok := cfg["groups"]
+	if !ok {
+		return q, groups, nil
+	}
+	gl, ok := gc.([]any)
+	if !ok {
+		return q, nil, fmt.Errorf("fastcache: config value for groups is not a list")
+	}
+	for x, c := range gl {
+		props, ok := c.(map[string]any)
+		if !ok {
+			return q, nil, fmt.Errorf("fastcache: group %d is not a map", x)
+		}
+		g := Group{}
+		g.Name, err = config.GetConfigValueAsString(props, "name")
+		if err != nil {
+			return q, nil, err
+		}
+		g.Quota, err = parseQuota(props)
+		if err != nil {
+			return q, nil, err
+		}
+		if tl, ok := props["tenants"].([]any); ok {
+			for _, t := range tl {
+				g.Tenants = append(g.Tenants, fmt.Sprintf("%v", t))
+			}
+		}
+		groups = append(groups, g)
+	}
+	return q, groups, nil
+}
+
+func parseQuota(props map[string]any) (config.CacheQuota, error) {
+	var q config.CacheQuota
+	var err error
+	if _, ok := props["maxcount"]; ok {
+		q.MaxCount, err = config.GetConfigValueAsInt(props, "maxcount")
+		if err != nil {
+			return q, err
+		}
+	}
+	if _, ok := props["maxsize"]; ok {
+		q.MaxSize, err = config.GetConfigValueAsInt(props, "maxsize")
+		if err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,154 @@
+package fastcache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/config"
+)
+
+func getPartitions(t *testing.T) *Partitions {
+	p := &Partitions{
+		RootPath:   rootpath,
+		MaxRAMSize: 1024 * 1024,
+		Default:    config.CacheQuota{MaxCount: 5},
+		Groups: []Group{
+			{Name: "small", Tenants: []string{"a", "b"}, Quota: config.CacheQuota{MaxCount: 4}},
+		},
+	}
+	assert.Nil(t, p.Init())
+	return p
+}
+
+func storeTenantBlobs(t *testing.T, c *FastCache, tenant string, count int) []string {
+	ids := make([]string, 0)
+	for x := 0; x < count; x++ {
+		b := getBlobDescription(tenant)
+		b.TenantID = tenant
+		id, err := c.StoreBlob(b, strings.NewReader(payload))
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestPartitions(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	p := getPartitions(t)
+
+	c1, err := p.Get("t1", nil)
+	ast.Nil(err)
+	c2, err := p.Get("t2", &config.CacheQuota{MaxCount: 2})
+	ast.Nil(err)
+	ast.NotSame(c1, c2)
+	ids1 := storeTenantBlobs(t, c1, "t1", 5)
+	storeTenantBlobs(t, c2, "t2", 10)
+
+	// a noisy tenant doesn't evict the blobs of the others
+	ast.ElementsMatch(ids1, getFiles(t, c1))
+	ast.Len(getFiles(t, c2), 2)
+
+	var buf bytes.Buffer
+	ast.Nil(c1.RetrieveBlob(ids1[0], &buf))
+	ast.NotNil(c1.RetrieveBlob("0815", &buf))
+
+	sts := p.Stats()
+	ast.Len(sts, 2)
+	ast.Equal("t1", sts[0].Partition)
+	ast.Equal(5, sts[0].Count)
+	ast.Equal(int64(5*len(payload)), sts[0].Size)
+	ast.Equal(int64(1), sts[0].Hits)
+	ast.Equal(int64(1), sts[0].Misses)
+	ast.Equal(int64(0), sts[0].Evictions)
+	ast.Equal("t2", sts[1].Partition)
+	ast.Equal(int64(2), sts[1].MaxCount)
+	ast.Equal(int64(8), sts[1].Evictions)
+
+	// changing the quota of a tenant
+	c2, err = p.Get("t2", nil)
+	ast.Nil(err)
+	ast.Equal(int64(5), c2.Stats().MaxCount)
+	c1, err = p.Get("t1", &config.CacheQuota{MaxCount: 3})
+	ast.Nil(err)
+	ast.Len(getFiles(t, c1), 3)
+
+	n, err := p.Clear("t1")
+	ast.Nil(err)
+	ast.Equal(3, n)
+	ast.Empty(getFiles(t, c1))
+	ast.Len(getFiles(t, c2), 2)
+	ast.Nil(p.Close())
+}
+
+func TestPartitionGroups(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	p := getPartitions(t)
+
+	ca, err := p.Get("a", &config.CacheQuota{MaxCount: 100})
+	ast.Nil(err)
+	cb, err := p.Get("b", nil)
+	ast.Nil(err)
+	ast.Same(ca, cb)
+	ast.Equal(GroupPrefix+"small", ca.Name)
+	// the quota of the group is used
+	ast.Equal(int64(4), ca.Stats().MaxCount)
+
+	idsa := storeTenantBlobs(t, ca, "a", 2)
+	idsb := storeTenantBlobs(t, cb, "b", 2)
+
+	// clearing one tenant of a group doesn't affect the others
+	n, err := p.Clear("a")
+	ast.Nil(err)
+	ast.Equal(2, n)
+	ast.ElementsMatch(idsb, getFiles(t, cb))
+	for _, id := range idsa {
+		ok, err := ca.HasBlob(id)
+		ast.Nil(err)
+		ast.False(ok)
+	}
+
+	sts := p.Stats()
+	ast.Len(sts, 1)
+	ast.Equal([]string{"a", "b"}, sts[0].Tenants)
+	ast.Nil(p.Close())
+
+	p = &Partitions{
+		RootPath: rootpath,
+		Default:  config.CacheQuota{MaxCount: 5},
+		Groups: []Group{
+			{Name: "g1", Tenants: []string{"a"}},
+			{Name: "g2", Tenants: []string{"a"}},
+		},
+	}
+	ast.NotNil(p.Init())
+}
+
+func TestParsePartitions(t *testing.T) {
+	ast := assert.New(t)
+	q, groups, err := ParsePartitions(map[string]any{
+		"maxcount": 100,
+		"maxsize":  1024,
+		"groups": []any{
+			map[string]any{
+				"name":     "small",
+				"tenants":  []any{"a", "b"},
+				"maxcount": 10,
+			},
+		},
+	})
+	ast.Nil(err)
+	ast.Equal(config.CacheQuota{MaxCount: 100, MaxSize: 1024}, q)
+	ast.Len(groups, 1)
+	ast.Equal("small", groups[0].Name)
+	ast.Equal([]string{"a", "b"}, groups[0].Tenants)
+	ast.Equal(int64(10), groups[0].Quota.MaxCount)
+
+	_, _, err = ParsePartitions(map[string]any{"groups": "small"})
+	ast.NotNil(err)
+	_, _, err = ParsePartitions(map[string]any{"groups": []any{map[string]any{"maxcount": 10}}})
+	ast.NotNil(err)
+}
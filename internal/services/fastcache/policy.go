@@ -0,0 +1,243 @@
+package fastcache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+const (
+	// PolicyLRU evicting the least recently used entry
+	PolicyLRU = "lru"
+	// PolicyLFU evicting the least frequently used entry
+	PolicyLFU = "lfu"
+	// PolicyTinyLFU a small LRU window in front of a segmented LRU, admission to the main cache is decided by the access frequency
+	PolicyTinyLFU = "tinylfu"
+	// PolicyGDSF greedy dual size frequency, small and often used entries are kept longer
+	PolicyGDSF = "gdsf"
+)
+
+// Policy the eviction and admission policy of the cache. All methods are called with the lock of the entry list held,
+// so implementations don't have to be thread safe.
+type Policy interface {
+	// Name the name of the policy
+	Name() string
+	// Add a new entry is added to the cache
+	Add(e *Entry)
+	// Access an entry of the cache is accessed
+	Access(id string)
+	// Remove an entry is removed from the cache
+	Remove(id string)
+	// Victim getting the entry to evict next, "" if there is none. With an admission policy this can be the newly added entry.
+	Victim() string
+}
+
+// NewPolicy creating the policy with the given name for a cache with capacity entries, an empty name is a LRU policy
+func NewPolicy(name string, capacity int) (Policy, error) {
+	switch strings.ToLower(name) {
+	case "", PolicyLRU:
+		return NewLRU(), nil
+	case PolicyLFU:
+		return NewLFU(), nil
+	case PolicyTinyLFU:
+		return NewTinyLFU(capacity), nil
+	case PolicyGDSF:
+		return NewGDSF(), nil
+	}
+	return nil, fmt.Errorf("unknown cache policy: %s", name)
+}
+
+// LRU the least recently used policy, all operations are O(1)
+type LRU struct {
+	ll    *list.List
+	elems map[string]*list.Element
+}
+
+// NewLRU creating a new LRU policy
+func NewLRU() *LRU {
+	return &LRU{
+		ll:    list.New(),
+		elems: make(map[string]*list.Element),
+	}
+}
+
+// Name the name of the policy
+func (p *LRU) Name() string {
+	return PolicyLRU
+}
+
+// Add a new entry is added to the cache
+func (p *LRU) Add(e *Entry) {
+	id := e.Description.BlobID
+	if el, ok := p.elems[id]; ok {
+		p.ll.MoveToFront(el)
+		return
+	}
+	p.elems[id] = p.ll.PushFront(id)
+}
+
+// Access an entry of the cache is accessed
+func (p *LRU) Access(id string) {
+	if el, ok := p.elems[id]; ok {
+		p.ll.MoveToFront(el)
+	}
+}
+
+// Remove an entry is removed from the cache
+func (p *LRU) Remove(id string) {
+	if el, ok := p.elems[id]; ok {
+		p.ll.Remove(el)
+		delete(p.elems, id)
+	}
+}
+
+// Victim getting the least recently used entry
+func (p *LRU) Victim() string {
+	el := p.ll.Back()
+	if el == nil {
+		return ""
+	}
+	return el.Value.(string)
+}
+
+// HeapPolicy a policy evicting the entry with the lowest priority, all operations are O(log n).
+// Entries with the same priority are evicted in LRU order.
+type HeapPolicy struct {
+	name     string
+	items    map[string]*heapItem
+	h        itemHeap
+	seq      uint64
+	clock    float64 // the inflation value of GDSF, the priority of the last victim
+	priority func(clock float64, it *heapItem) float64
+}
+
+type heapItem struct {
+	id    string
+	freq  int64
+	size  int64
+	prio  float64
+	seq   uint64
+	index int
+}
+
+// NewLFU creating a new least frequently used policy
+func NewLFU() *HeapPolicy {
+	return newHeapPolicy(PolicyLFU, func(_ float64, it *heapItem) float64 {
+		return float64(it.freq)
+	})
+}
+
+// NewGDSF creating a new greedy dual size frequency policy, the priority of an entry is clock + frequency / size.
+// The clock is raised to the priority of every victim, so entries which are not used any more are aging.
+func NewGDSF() *HeapPolicy {
+	return newHeapPolicy(PolicyGDSF, func(clock float64, it *heapItem) float64 {
+		size := it.size
+		if size < 1 {
+			size = 1
+		}
+		return clock + float64(it.freq)/float64(size)
+	})
+}
+
+func newHeapPolicy(name string, priority func(clock float64, it *heapItem) float64) *HeapPolicy {
+	return &HeapPolicy{
+		name:     name,
+		items:    make(map[string]*heapItem),
+		h:        make(itemHeap, 0),
+		priority: priority,
+	}
+}
+
+// Name the name of the policy
+func (p *HeapPolicy) Name() string {
+	return p.name
+}
+
+// Add a new entry is added to the cache, the hits of a restored entry are taken as frequency
+func (p *HeapPolicy) Add(e *Entry) {
+	id := e.Description.BlobID
+	if _, ok := p.items[id]; ok {
+		p.Access(id)
+		return
+	}
+	p.seq++
+	it := &heapItem{
+		id:   id,
+		freq: e.Hits + 1,
+		size: e.Size,
+		seq:  p.seq,
+	}
+	it.prio = p.priority(p.clock, it)
+	p.items[id] = it
+	heap.Push(&p.h, it)
+}
+
+// Access an entry of the cache is accessed
+func (p *HeapPolicy) Access(id string) {
+	it, ok := p.items[id]
+	if !ok {
+		return
+	}
+	p.seq++
+	it.freq++
+	it.seq = p.seq
+	it.prio = p.priority(p.clock, it)
+	heap.Fix(&p.h, it.index)
+}
+
+// Remove an entry is removed from the cache
+func (p *HeapPolicy) Remove(id string) {
+	it, ok := p.items[id]
+	if !ok {
+		return
+	}
+	heap.Remove(&p.h, it.index)
+	delete(p.items, id)
+}
+
+// Victim getting the entry with the lowest priority
+func (p *HeapPolicy) Victim() string {
+	if len(p.h) == 0 {
+		return ""
+	}
+	it := p.h[0]
+	p.clock = it.prio
+	return it.id
+}
+
+// itemHeap a min heap of the items, implementing heap.Interface
+type itemHeap []*heapItem
+
+func (h itemHeap) Len() int {
+	return len(h)
+}
+
+func (h itemHeap) Less(i, j int) bool {
+	if h[i].prio == h[j].prio {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].prio < h[j].prio
+}
+
+func (h itemHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *itemHeap) Push(x any) {
+	it := x.(*heapItem)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *itemHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
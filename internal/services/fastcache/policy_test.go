@@ -0,0 +1,233 @@
+package fastcache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+func newEntry(id string, size int64) *Entry {
+	return &Entry{
+		Description: model.BlobDescription{BlobID: id},
+		Size:        size,
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	ast := assert.New(t)
+	for _, n := range []string{"", PolicyLRU, PolicyLFU, "TinyLFU", PolicyGDSF} {
+		p, err := NewPolicy(n, 10)
+		ast.Nil(err)
+		ast.NotNil(p)
+	}
+	p, _ := NewPolicy("", 10)
+	ast.Equal(PolicyLRU, p.Name())
+	_, err := NewPolicy("random", 10)
+	ast.NotNil(err)
+}
+
+func TestPolicyLRU(t *testing.T) {
+	ast := assert.New(t)
+	p := NewLRU()
+	ast.Equal("", p.Victim())
+	for _, id := range []string{"a", "b", "c"} {
+		p.Add(newEntry(id, 1))
+	}
+	ast.Equal("a", p.Victim())
+	p.Access("a")
+	ast.Equal("b", p.Victim())
+	p.Remove("b")
+	ast.Equal("c", p.Victim())
+}
+
+func TestPolicyLFU(t *testing.T) {
+	ast := assert.New(t)
+	p := NewLFU()
+	for _, id := range []string{"a", "b", "c"} {
+		p.Add(newEntry(id, 1))
+	}
+	// same frequency, the oldest one
+	ast.Equal("a", p.Victim())
+	p.Access("a")
+	p.Access("a")
+	p.Access("c")
+	ast.Equal("b", p.Victim())
+	p.Remove("b")
+	ast.Equal("c", p.Victim())
+
+	// the hits of a restored entry are the frequency
+	e := newEntry("d", 1)
+	e.Hits = 10
+	p.Add(e)
+	p.Remove("c")
+	ast.Equal("a", p.Victim())
+}
+
+func TestPolicyGDSF(t *testing.T) {
+	ast := assert.New(t)
+	p := NewGDSF()
+	p.Add(newEntry("big", 1024*1024))
+	p.Add(newEntry("small", 1024))
+	// the big one is evicted first, even if it is used more often
+	p.Access("big")
+	p.Access("big")
+	ast.Equal("big", p.Victim())
+	p.Remove("big")
+
+	// after the eviction the clock has been raised, so old entries are aging
+	p = NewGDSF()
+	p.Add(newEntry("a", 1000))
+	p.Add(newEntry("b", 1000))
+	p.Access("b")
+	ast.Equal("a", p.Victim())
+	p.Remove("a")
+	p.Add(newEntry("c", 1000))
+	ast.Equal("b", p.Victim())
+}
+
+func TestPolicyTinyLFU(t *testing.T) {
+	ast := assert.New(t)
+	l := EntryList{
+		MaxCount: 100,
+		Policy:   NewTinyLFU(100),
+	}
+	l.Init()
+	add := func(id string) {
+		l.Add(Entry{Description: model.BlobDescription{BlobID: id}})
+		for {
+			vid := l.HandleContrains()
+			if vid == "" {
+				break
+			}
+			l.Delete(vid)
+		}
+	}
+	hot := make([]string, 0)
+	for x := 0; x < 50; x++ {
+		id := fmt.Sprintf("hot%d", x)
+		hot = append(hot, id)
+		add(id)
+		for y := 0; y < 5; y++ {
+			l.Get(id)
+		}
+	}
+	// a scan over many blobs, which are read only once
+	for x := 0; x < 1000; x++ {
+		add(fmt.Sprintf("scan%d", x))
+	}
+	ast.Equal(100, l.Size())
+	for _, id := range hot {
+		ast.True(l.Has(id), "hot entry evicted: %s", id)
+	}
+}
+
+func TestEntryListRAM(t *testing.T) {
+	ast := assert.New(t)
+	l := EntryList{
+		MaxCount:   10,
+		MaxRAMSize: 10,
+	}
+	l.Init()
+	for _, id := range []string{"a", "b", "c"} {
+		l.Add(Entry{Description: model.BlobDescription{BlobID: id}, Data: []byte("12345")})
+	}
+	l.Get("a")
+	ast.Equal("", l.HandleContrains())
+	// the data of the least recently used entry is dropped
+	e, ok := l.Get("b")
+	ast.True(ok)
+	ast.Nil(e.Data)
+	e, _ = l.Get("a")
+	ast.NotNil(e.Data)
+	ast.Equal(int64(10), l.ramsize)
+	l.Delete("a")
+	ast.Equal(int64(5), l.ramsize)
+	ast.Equal([]string{"b", "c"}, l.GetFullIDList())
+}
+
+func TestSketch(t *testing.T) {
+	ast := assert.New(t)
+	s := newSketch(10)
+	ast.Equal(uint8(0), s.estimate("a"))
+	// the first access goes to the doorkeeper
+	s.increment("a")
+	ast.Equal(uint8(1), s.estimate("a"))
+	for x := 0; x < 20; x++ {
+		s.increment("b")
+	}
+	ast.Equal(uint8(sketchMax+1), s.estimate("b"))
+	ast.Equal(uint8(1), s.estimate("a"))
+
+	// after 10 times the capacity increments the counters are halved
+	for x := 0; x < 79; x++ {
+		s.increment(fmt.Sprintf("c%d", x))
+	}
+	ast.Equal(uint8(sketchMax/2), s.estimate("b"))
+	ast.Equal(uint8(0), s.estimate("a"))
+}
+
+func TestFastCachePolicy(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	srv := FastCache{
+		RootPath: rootpath,
+		MaxCount: 5,
+		Policy:   "random",
+	}
+	ast.NotNil(srv.Init())
+
+	srv.Policy = PolicyLFU
+	ast.Nil(srv.Init())
+	ids := fillCache(t, &srv, 5)
+	for x := 0; x < 3; x++ {
+		_, err := srv.GetBlobDescription(ids[0])
+		ast.Nil(err)
+	}
+	fillCache(t, &srv, 5)
+	ok, err := srv.HasBlob(ids[0])
+	ast.Nil(err)
+	ast.True(ok)
+	ast.Len(getFiles(t, &srv), 5)
+	ast.Nil(srv.Close())
+}
+
+func TestBypassPolicy(t *testing.T) {
+	ast := assert.New(t)
+	for _, p := range []string{PolicyLFU, "TinyLFU"} {
+		clear(t)
+		srv := FastCache{
+			RootPath: rootpath,
+			MaxCount: 5,
+			Policy:   p,
+		}
+		ast.Nil(srv.Init())
+		ids := fillCache(t, &srv, 5)
+		for x := 0; x < 3; x++ {
+			var buf bytes.Buffer
+			ast.Nil(srv.RetrieveBlobWithHint(ids[0], &buf, interfaces.CacheBypass))
+			ast.Equal(payload, buf.String())
+		}
+		ast.NotNil(srv.RetrieveBlobWithHint("unknown", &bytes.Buffer{}, interfaces.CacheBypass))
+		st := srv.Stats()
+		ast.Equal(int64(0), st.Hits, p)
+		ast.Equal(int64(0), st.Misses, p)
+		ce, ok := srv.CacheEntry(ids[0])
+		ast.True(ok)
+		ast.Equal(int64(0), ce.Hits, p)
+
+		// the bypassed reads are not counted by the policy
+		if tl, ok := srv.entries.Policy.(*TinyLFU); ok {
+			ast.Equal(tl.sketch.estimate(ids[1]), tl.sketch.estimate(ids[0]))
+		} else {
+			fillCache(t, &srv, 5)
+			ok, err := srv.HasBlob(ids[0])
+			ast.Nil(err)
+			ast.False(ok, p)
+		}
+		ast.Nil(srv.Close())
+	}
+}
@@ -0,0 +1,273 @@
+package fastcache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+const (
+	sketchDepth = 4
+	sketchMax   = 15
+)
+
+// the segments of the W-TinyLFU policy
+const (
+	segWindow = iota
+	segProbation
+	segProtected
+)
+
+// TinyLFU the W-TinyLFU policy. New entries are going into a small LRU window (1% of the capacity),
+// the main cache is a segmented LRU with a probation and a protected (80%) part.
+// An entry falling out of the window is only admitted to the main cache, if it's estimated frequency
+// is higher than the one of the probation victim, otherwise it is evicted itself.
+// So a single scan over all blobs can't flush the frequently used entries out of the cache.
+type TinyLFU struct {
+	sketch       *sketch
+	window       *list.List
+	probation    *list.List
+	protected    *list.List
+	elems        map[string]*list.Element
+	windowCap    int
+	mainCap      int
+	protectedCap int
+}
+
+type tinyItem struct {
+	id  string
+	seg int
+}
+
+// NewTinyLFU creating a new W-TinyLFU policy for a cache with capacity entries
+func NewTinyLFU(capacity int) *TinyLFU {
+	if capacity < 1 {
+		capacity = 1
+	}
+	wc := capacity / 100
+	if wc < 1 {
+		wc = 1
+	}
+	mc := capacity - wc
+	return &TinyLFU{
+		sketch:       newSketch(capacity),
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		elems:        make(map[string]*list.Element),
+		windowCap:    wc,
+		mainCap:      mc,
+		protectedCap: mc * 8 / 10,
+	}
+}
+
+// Name the name of the policy
+func (p *TinyLFU) Name() string {
+	return PolicyTinyLFU
+}
+
+// Add a new entry is added to the window, the hits of a restored entry are added to the frequency
+func (p *TinyLFU) Add(e *Entry) {
+	id := e.Description.BlobID
+	if _, ok := p.elems[id]; ok {
+		p.Access(id)
+		return
+	}
+	for x := int64(0); x <= e.Hits && x < sketchMax; x++ {
+		p.sketch.increment(id)
+	}
+	p.elems[id] = p.window.PushFront(&tinyItem{id: id, seg: segWindow})
+}
+
+// Access an entry of the cache is accessed, a probation entry is promoted to the protected segment
+func (p *TinyLFU) Access(id string) {
+	p.sketch.increment(id)
+	el, ok := p.elems[id]
+	if !ok {
+		return
+	}
+	it := el.Value.(*tinyItem)
+	switch it.seg {
+	case segWindow:
+		p.window.MoveToFront(el)
+	case segProtected:
+		p.protected.MoveToFront(el)
+	case segProbation:
+		p.probation.Remove(el)
+		it.seg = segProtected
+		p.elems[id] = p.protected.PushFront(it)
+		if p.protected.Len() > p.protectedCap {
+			p.move(p.protected.Back(), segProbation)
+		}
+	}
+}
+
+// Remove an entry is removed from the cache
+func (p *TinyLFU) Remove(id string) {
+	el, ok := p.elems[id]
+	if !ok {
+		return
+	}
+	p.segment(el.Value.(*tinyItem).seg).Remove(el)
+	delete(p.elems, id)
+}
+
+// Victim getting the entry to evict. A candidate of the full window has to compete with the probation victim.
+func (p *TinyLFU) Victim() string {
+	for p.window.Len() > p.windowCap {
+		cand := p.window.Back()
+		if p.probation.Len()+p.protected.Len() < p.mainCap {
+			p.move(cand, segProbation)
+			continue
+		}
+		victim := p.mainVictim()
+		if victim == nil {
+			break
+		}
+		cid := cand.Value.(*tinyItem).id
+		vid := victim.Value.(*tinyItem).id
+		if p.sketch.estimate(cid) > p.sketch.estimate(vid) {
+			p.move(cand, segProbation)
+			return vid
+		}
+		return cid
+	}
+	if victim := p.mainVictim(); victim != nil {
+		return victim.Value.(*tinyItem).id
+	}
+	if cand := p.window.Back(); cand != nil {
+		return cand.Value.(*tinyItem).id
+	}
+	return ""
+}
+
+func (p *TinyLFU) mainVictim() *list.Element {
+	if el := p.probation.Back(); el != nil {
+		return el
+	}
+	return p.protected.Back()
+}
+
+// move moving the element to the front of the segment
+func (p *TinyLFU) move(el *list.Element, seg int) {
+	it := el.Value.(*tinyItem)
+	p.segment(it.seg).Remove(el)
+	it.seg = seg
+	p.elems[it.id] = p.segment(seg).PushFront(it)
+}
+
+func (p *TinyLFU) segment(seg int) *list.List {
+	switch seg {
+	case segProbation:
+		return p.probation
+	case segProtected:
+		return p.protected
+	}
+	return p.window
+}
+
+// sketch a count min sketch for estimating the access frequency. Every row has 16 counters per cached entry,
+// the 4 bit counters are packed two in a byte.
+// The first access of an entry is only recorded in the doorkeeper, so entries used only once are not polluting the counters.
+// After 10 times the capacity increments all counters are halved and the doorkeeper is cleared, so old accesses are fading out.
+type sketch struct {
+	rows    [sketchDepth][]uint8 // two counters per byte, the even one in the low nibble
+	door    []uint64             // bloom filter sized for all increments until the reset
+	dmask   uint64
+	mask    uint64
+	seeds   [sketchDepth]maphash.Seed // independent hashes per row, so a collision in one row says nothing about the others
+	adds    int
+	resetAt int
+}
+
+func newSketch(capacity int) *sketch {
+	w := 64
+	for w < 16*capacity {
+		w <<= 1
+	}
+	s := &sketch{
+		mask:    uint64(w - 1),
+		resetAt: 10 * capacity,
+	}
+	for x := range s.rows {
+		s.rows[x] = make([]uint8, w/2)
+		s.seeds[x] = maphash.MakeSeed()
+	}
+	d := 64
+	for d < 80*capacity {
+		d <<= 1
+	}
+	s.door = make([]uint64, d/64)
+	s.dmask = uint64(d - 1)
+	return s
+}
+
+// indexes the hashes of the id, the counters are taken with the mask, the doorkeeper bits with the dmask
+func (s *sketch) indexes(id string) [sketchDepth]uint64 {
+	var idx [sketchDepth]uint64
+	for x := range idx {
+		idx[x] = maphash.String(s.seeds[x], id)
+	}
+	return idx
+}
+
+func (s *sketch) increment(id string) {
+	idx := s.indexes(id)
+	if !s.inDoor(idx) {
+		for _, i := range idx {
+			i &= s.dmask
+			s.door[i/64] |= 1 << (i % 64)
+		}
+	} else {
+		for x, i := range idx {
+			i &= s.mask
+			if s.counter(x, i) < sketchMax {
+				s.rows[x][i/2] += 1 << (4 * (i % 2))
+			}
+		}
+	}
+	s.adds++
+	if s.adds >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *sketch) estimate(id string) uint8 {
+	idx := s.indexes(id)
+	est := uint8(sketchMax)
+	for x, i := range idx {
+		if c := s.counter(x, i&s.mask); c < est {
+			est = c
+		}
+	}
+	if s.inDoor(idx) {
+		est++
+	}
+	return est
+}
+
+func (s *sketch) counter(x int, i uint64) uint8 {
+	return (s.rows[x][i/2] >> (4 * (i % 2))) & 0x0f
+}
+
+func (s *sketch) inDoor(idx [sketchDepth]uint64) bool {
+	for _, i := range idx {
+		i &= s.dmask
+		if s.door[i/64]&(1<<(i%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sketch) reset() {
+	for x := range s.rows {
+		for i := range s.rows[x] {
+			// halving both nibbles, the lowest bit of the high nibble mustn't move into the low one
+			s.rows[x][i] = (s.rows[x][i] >> 1) & 0x77
+		}
+	}
+	for x := range s.door {
+		s.door[x] = 0
+	}
+	s.adds /= 2
+}
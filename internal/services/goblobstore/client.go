@@ -0,0 +1,262 @@
+// Package goblobstore contains a storage and a tenant manager, which are using a remote GoBlobStore instance over the http api.
+// With this, instances can be chained, e.g. an edge node can write through to a central instance.
+package goblobstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/api"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/logging"
+)
+
+// GoBlobStoreName name of this storage class
+const GoBlobStoreName = "goblobstore"
+
+// defaults of the client
+const (
+	DefaultRetries    = 3
+	DefaultRetryDelay = 500 * time.Millisecond
+	DefaultTimeout    = 30 * time.Second
+)
+
+// base path of the remote api
+const basePath = "/api/v1"
+
+var logger = logging.New().WithName("goblobstore")
+
+// ErrRemote the remote instance answered with an error
+var ErrRemote = errors.New("remote error")
+
+// Client the http client for a remote GoBlobStore instance
+type Client struct {
+	URL           string            // base url of the remote instance, e.g. https://central.example.com:8443
+	APIKey        string            // api key of the remote instance
+	Token         string            // jwt, send as bearer token
+	SkipVerify    bool              // skipping the verification of the server certificate
+	CACert        string            // pem file with the ca certificates for the server certificate
+	Retries       int               // count of retries of a failed request, default 3
+	RetryDelay    time.Duration     // delay before the first retry, doubled on every retry, default 500ms
+	Timeout       time.Duration     // timeout for waiting on the response header, default 30s
+	HeaderMapping map[string]string // header mapping of the remote instance, missing keys are taken from the default mapping
+	http          *http.Client
+	lastError     error
+	em            sync.Mutex
+}
+
+// Init initialize the client
+func (c *Client) Init() error {
+	if c.URL == "" {
+		return errors.New("goblobstore: url should not be empty")
+	}
+	c.URL = strings.TrimSuffix(c.URL, "/")
+	if c.Retries < 0 {
+		c.Retries = 0
+	} else if c.Retries == 0 {
+		c.Retries = DefaultRetries
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = DefaultRetryDelay
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	hm := make(map[string]string)
+	for k, v := range config.DefaultConfig.HeaderMapping {
+		hm[k] = v
+	}
+	for k, v := range c.HeaderMapping {
+		if v != "" {
+			hm[k] = v
+		}
+	}
+	c.HeaderMapping = hm
+
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.SkipVerify, // #nosec G402 only if configured
+	}
+	if c.CACert != "" {
+		pem, err := os.ReadFile(c.CACert)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("goblobstore: no certificates found in %s", c.CACert)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsCfg
+	tr.ResponseHeaderTimeout = c.Timeout
+	// no overall timeout, up- and downloads are streamed and can take their time
+	c.http = &http.Client{Transport: tr}
+	return nil
+}
+
+// header getting the name of the mapped header
+func (c *Client) header(key string) string {
+	return c.HeaderMapping[key]
+}
+
+// request a single request, body is called for every try, so the body can be replayed
+type request struct {
+	method string
+	path   string
+	query  string
+	tenant string // send as tenant header, if the tenant is not part of the path
+	header http.Header
+	body   func() (io.Reader, int64, error)
+	retry  bool // retrying is only possible, if the body can be replayed
+}
+
+func (c *Client) newRequest(r request) (*http.Request, error) {
+	var body io.Reader
+	length := int64(-1)
+	if r.body != nil {
+		var err error
+		body, length, err = r.body()
+		if err != nil {
+			return nil, err
+		}
+	}
+	url := c.URL + basePath + r.path
+	if r.query != "" {
+		url += "?" + r.query
+	}
+	req, err := http.NewRequest(r.method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && length >= 0 {
+		req.ContentLength = length
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if c.APIKey != "" {
+		req.Header.Set(c.header(api.APIKeyHeaderKey), c.APIKey)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if r.tenant != "" {
+		req.Header.Set(c.header(api.TenantHeaderKey), r.tenant)
+	}
+	return req, nil
+}
+
+// do executing the request with retries on network errors and server errors,
+// a response with a status code >= 400 is returned as error, 404 as os.ErrNotExist.
+// The caller has to close the body of the response.
+func (c *Client) do(r request) (*http.Response, error) {
+	delay := c.RetryDelay
+	var err error
+	for try := 0; ; try++ {
+		var rsp *http.Response
+		rsp, err = c.try(r)
+		if err == nil {
+			c.setLastError(nil)
+			return rsp, nil
+		}
+		if !r.retry || !retryable(err) || try >= c.Retries {
+			break
+		}
+		logger.Debugf("goblobstore: retrying %s %s: %v", r.method, r.path, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		c.setLastError(err)
+	}
+	return nil, err
+}
+
+// remoteError an error response of the remote instance
+type remoteError struct {
+	code int
+	msg  string
+}
+
+func (e *remoteError) Error() string {
+	return fmt.Sprintf("%v: %d %s", ErrRemote, e.code, e.msg)
+}
+
+func (e *remoteError) Unwrap() error {
+	if e.code == http.StatusNotFound {
+		return os.ErrNotExist
+	}
+	return ErrRemote
+}
+
+func retryable(err error) bool {
+	var re *remoteError
+	if errors.As(err, &re) {
+		return re.code >= 500 || re.code == http.StatusTooManyRequests
+	}
+	// network errors
+	return true
+}
+
+func (c *Client) try(r request) (*http.Response, error) {
+	req, err := c.newRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode < 400 {
+		return rsp, nil
+	}
+	defer rsp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 4096))
+	return nil, &remoteError{code: rsp.StatusCode, msg: strings.TrimSpace(string(msg))}
+}
+
+// doJSON executing the request and decoding the json response into v
+func (c *Client) doJSON(r request, v any) error {
+	rsp, err := c.do(r)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if v == nil {
+		_, err = io.Copy(io.Discard, rsp.Body)
+		return err
+	}
+	return json.NewDecoder(rsp.Body).Decode(v)
+}
+
+func (c *Client) setLastError(err error) {
+	c.em.Lock()
+	defer c.em.Unlock()
+	c.lastError = err
+}
+
+// GetLastError returning the error of the last request
+func (c *Client) GetLastError() error {
+	c.em.Lock()
+	defer c.em.Unlock()
+	return c.lastError
+}
+
+// Close closing the idle connections
+func (c *Client) Close() error {
+	if c.http != nil {
+		c.http.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package goblobstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vfaronov/httpheader"
+	"github.com/willie68/GoBlobStore/internal/api"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// page size for listing blobs
+const pageSize = 1000
+
+// checking interface compatibility
+var (
+	_ interfaces.BlobStorage   = &BlobStorage{}
+	_ interfaces.TenantManager = &TenantManager{}
+)
+
+// BlobStorage service for storing blobs on a remote GoBlobStore instance. The retention of a blob is transferred
+// together with the blob, the remote instance is responsible for the retention of the blobs.
+type BlobStorage struct {
+	Client *Client // client of the remote instance, can be shared between storages
+	Tenant string  // this is the tenant, on which this service will work
+}
+
+// Init initialize this service
+func (s *BlobStorage) Init() error {
+	if s.Tenant == "" {
+		return errors.New("tenant should not be null or empty")
+	}
+	if s.Client == nil {
+		return errors.New("goblobstore: client should not be nil")
+	}
+	if s.Client.http == nil {
+		return s.Client.Init()
+	}
+	return nil
+}
+
+// GetTenant return the id of the tenant
+func (s *BlobStorage) GetTenant() string {
+	return s.Tenant
+}
+
+func (s *BlobStorage) blobPath(sub string) string {
+	return fmt.Sprintf("/stores/%s/blobs/%s", url.PathEscape(s.Tenant), sub)
+}
+
+// GetBlobs getting a list of blob from the remote instance, page by page
+func (s *BlobStorage) GetBlobs(callback func(id string) bool) error {
+	return s.pages(func(offset int) request {
+		return request{
+			method: http.MethodGet,
+			path:   s.blobPath(""),
+			query:  fmt.Sprintf("offset=%d&limit=%d", offset, pageSize),
+			retry:  true,
+		}
+	}, callback)
+}
+
+// pages walking thru all pages of a list of ids
+func (s *BlobStorage) pages(page func(offset int) request, callback func(id string) bool) error {
+	offset := 0
+	for {
+		ids := make([]string, 0)
+		err := s.Client.doJSON(page(offset), &ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if !callback(id) {
+				return nil
+			}
+		}
+		if len(ids) < pageSize {
+			return nil
+		}
+		offset += len(ids)
+	}
+}
+
+// StoreBlob streaming a blob to the remote instance. The request is only retried, if the reader is seekable.
+func (s *BlobStorage) StoreBlob(b *model.BlobDescription, r io.Reader) (string, error) {
+	h := make(http.Header)
+	ct := b.ContentType
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	h.Set("Content-Type", ct)
+	set := func(key, value string) {
+		if name := s.Client.header(key); name != "" && value != "" {
+			h.Set(name, value)
+		}
+	}
+	set(api.BlobIDHeaderKey, b.BlobID)
+	set(api.RetentionHeaderKey, strconv.FormatInt(b.Retention, 10))
+	set(api.RetentionModeHeaderKey, b.RetentionMode)
+	if b.Filename != "" {
+		set(api.FilenameKey, httpheader.EncodeExtValue(b.Filename, ""))
+	}
+	// only string properties with the header prefix can be transferred as headers,
+	// all others are transferred as json with an update of the description
+	prefix := strings.ToLower(s.Client.header(api.HeaderPrefixKey))
+	props := make(map[string]any)
+	for k, v := range b.Properties {
+		if str, ok := v.(string); ok && prefix != "" && strings.HasPrefix(strings.ToLower(k), prefix) {
+			h.Set(k, str)
+			continue
+		}
+		if v != nil {
+			props[k] = v
+		}
+	}
+
+	seeker, retry := r.(io.Seeker)
+	var start int64
+	if retry {
+		var err error
+		start, err = seeker.Seek(0, io.SeekCurrent)
+		retry = err == nil
+	}
+	first := true
+	req := request{
+		method: http.MethodPost,
+		path:   s.blobPath(""),
+		header: h,
+		retry:  retry,
+		body: func() (io.Reader, int64, error) {
+			if !first {
+				if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+					return nil, 0, err
+				}
+			}
+			first = false
+			length := int64(-1)
+			if b.ContentLength > 0 {
+				length = b.ContentLength
+			}
+			// hiding the other interfaces of the reader, so the http client will not close the reader
+			return io.NopCloser(r), length, nil
+		},
+	}
+	var res model.BlobDescription
+	err := s.Client.doJSON(req, &res)
+	if err != nil {
+		return "", err
+	}
+	b.BlobID = res.BlobID
+	b.Hash = res.Hash
+	b.ContentLength = res.ContentLength
+	b.CreationDate = res.CreationDate
+	if len(props) > 0 {
+		err = s.UpdateBlobDescription(b.BlobID, &model.BlobDescription{Properties: props})
+		if err != nil {
+			// no blob with missing properties
+			_ = s.DeleteBlob(b.BlobID)
+			return "", err
+		}
+	}
+	return b.BlobID, nil
+}
+
+// UpdateBlobDescription updating the blob description, the remote instance only updates the properties
+func (s *BlobStorage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
+	js, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	h := make(http.Header)
+	h.Set("Content-Type", "application/json")
+	return s.Client.doJSON(request{
+		method: http.MethodPut,
+		path:   s.blobPath(url.PathEscape(id) + "/info"),
+		header: h,
+		retry:  true,
+		body: func() (io.Reader, int64, error) {
+			return bytes.NewReader(js), int64(len(js)), nil
+		},
+	}, nil)
+}
+
+// HasBlob checking, if a blob is present
+func (s *BlobStorage) HasBlob(id string) (bool, error) {
+	if id == "" {
+		return false, nil
+	}
+	_, err := s.GetBlobDescription(id)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetBlobDescription getting the description of the file
+func (s *BlobStorage) GetBlobDescription(id string) (*model.BlobDescription, error) {
+	var b model.BlobDescription
+	err := s.Client.doJSON(request{
+		method: http.MethodGet,
+		path:   s.blobPath(url.PathEscape(id) + "/info"),
+		retry:  true,
+	}, &b)
+	if err != nil {
+		return nil, err
+	}
+	// the url is only valid on the remote instance
+	b.BlobURL = ""
+	return &b, nil
+}
+
+// RetrieveBlob streaming the binary data from the remote instance into the writer,
+// a broken download is only retried, if nothing is written yet
+func (s *BlobStorage) RetrieveBlob(id string, w io.Writer) error {
+	cw := &utils.CountWriter{W: w}
+	var err error
+	for try := 0; try <= s.Client.Retries; try++ {
+		var rsp *http.Response
+		rsp, err = s.Client.do(request{
+			method: http.MethodGet,
+			path:   s.blobPath(url.PathEscape(id)),
+			retry:  true,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(cw, rsp.Body)
+		rsp.Body.Close()
+		if err == nil || cw.N > 0 {
+			break
+		}
+	}
+	if err != nil {
+		s.Client.setLastError(err)
+	}
+	return err
+}
+
+// DeleteBlob removing a blob from the remote instance
+func (s *BlobStorage) DeleteBlob(id string) error {
+	return s.Client.doJSON(request{
+		method: http.MethodDelete,
+		path:   s.blobPath(url.PathEscape(id)),
+		retry:  true,
+	}, nil)
+}
+
+// CheckBlob starting a check of a single blob on the remote instance
+func (s *BlobStorage) CheckBlob(id string) (*model.CheckInfo, error) {
+	var ci model.CheckInfo
+	err := s.Client.doJSON(request{
+		method: http.MethodPost,
+		path:   s.blobPath(url.PathEscape(id) + "/check"),
+		retry:  true,
+	}, &ci)
+	if err != nil {
+		return nil, err
+	}
+	return &ci, nil
+}
+
+// SearchBlobs querying the index of the remote instance
+func (s *BlobStorage) SearchBlobs(q string, callback func(id string) bool) error {
+	return s.pages(func(offset int) request {
+		return request{
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/stores/%s/search", url.PathEscape(s.Tenant)),
+			query:  fmt.Sprintf("offset=%d&limit=%d", offset, pageSize),
+			retry:  true,
+			body: func() (io.Reader, int64, error) {
+				return strings.NewReader(q), int64(len(q)), nil
+			},
+		}
+	}, callback)
+}
+
+// GetAllRetentions the remote instance manages the retentions of its blobs itself, so there are no entries to process here
+func (s *BlobStorage) GetAllRetentions(_ func(r model.RetentionEntry) bool) error {
+	return nil
+}
+
+// AddRetention the retention is transferred together with the blob, so only the blob is checked
+func (s *BlobStorage) AddRetention(r *model.RetentionEntry) error {
+	ok, err := s.HasBlob(r.BlobID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+// GetRetention getting the retention entry out of the description of the blob
+func (s *BlobStorage) GetRetention(id string) (model.RetentionEntry, error) {
+	b, err := s.GetBlobDescription(id)
+	if err != nil {
+		return model.RetentionEntry{}, err
+	}
+	if b.Retention <= 0 {
+		return model.RetentionEntry{}, fmt.Errorf("no retention found for id %s", id)
+	}
+	return model.RetentionEntryFromBlobDescription(*b), nil
+}
+
+// DeleteRetention the remote instance manages the retentions, nothing to do here
+func (s *BlobStorage) DeleteRetention(_ string) error {
+	return nil
+}
+
+// ResetRetention resets the retention for a blob on the remote instance
+func (s *BlobStorage) ResetRetention(id string) error {
+	var found bool
+	err := s.Client.doJSON(request{
+		method: http.MethodGet,
+		path:   s.blobPath(url.PathEscape(id) + "/resetretention"),
+		retry:  true,
+	}, &found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+// GetLastError returning the error of the last request to the remote instance
+func (s *BlobStorage) GetLastError() error {
+	return s.Client.GetLastError()
+}
+
+// Close closing the storage
+func (s *BlobStorage) Close() error {
+	return s.Client.Close()
+}
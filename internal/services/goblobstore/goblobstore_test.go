@@ -0,0 +1,260 @@
+package goblobstore_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/apiv1"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services"
+	"github.com/willie68/GoBlobStore/internal/services/goblobstore"
+	"github.com/willie68/GoBlobStore/internal/services/memory"
+	"github.com/willie68/GoBlobStore/internal/services/noindex"
+	"github.com/willie68/GoBlobStore/internal/services/retentionmanager"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const (
+	tenant  = "edge"
+	payload = "this is a blob content"
+)
+
+var remote *httptest.Server
+
+// startRemote starting an in process GoBlobStore instance with a memory storage
+func startRemote(t *testing.T) *httptest.Server {
+	if remote != nil {
+		return remote
+	}
+	ast := assert.New(t)
+	ast.Nil(services.Init(config.Engine{
+		RetentionManager: retentionmanager.SingleRetentionManagerName,
+		Storage: config.Storage{
+			Storageclass: memory.MemoryStorageName,
+			Properties: map[string]any{
+				"namespace": "remote",
+			},
+		},
+		Index: config.Storage{
+			Storageclass: noindex.NoIndexName,
+		},
+	}))
+	cfg := config.Get()
+	cfg.Apikey = true
+	ast.Nil(services.InitHelperServices(cfg))
+	router, err := apiv1.APIRoutes(cfg, nil)
+	ast.Nil(err)
+	remote = httptest.NewServer(router)
+	tm := &goblobstore.TenantManager{
+		Client: &goblobstore.Client{
+			URL:    remote.URL,
+			APIKey: apiv1.APIKey,
+		},
+	}
+	ast.Nil(tm.Init())
+	ast.Nil(tm.AddTenant(tenant))
+	return remote
+}
+
+func getStorage(t *testing.T) *goblobstore.BlobStorage {
+	srv := startRemote(t)
+	stg := &goblobstore.BlobStorage{
+		Client: &goblobstore.Client{
+			URL:    srv.URL,
+			APIKey: apiv1.APIKey,
+		},
+		Tenant: tenant,
+	}
+	assert.Nil(t, stg.Init())
+	return stg
+}
+
+func newBlob() model.BlobDescription {
+	return model.BlobDescription{
+		ContentLength: int64(len(payload)),
+		ContentType:   "text/plain",
+		Filename:      "Töst.txt",
+		Retention:     60,
+		Properties: map[string]any{
+			"X-User": "willie",
+		},
+	}
+}
+
+func TestCRUD(t *testing.T) {
+	ast := assert.New(t)
+	stg := getStorage(t)
+	defer stg.Close()
+
+	b := newBlob()
+	b.Properties["X-Roles"] = []string{"a", "b"}
+	b.Properties["X-Count"] = 42
+	b.Properties["user"] = "no header"
+	id, err := stg.StoreBlob(&b, strings.NewReader(payload))
+	ast.Nil(err)
+	ast.NotEmpty(id)
+	ast.NotEmpty(b.Hash)
+
+	ok, err := stg.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+
+	bd, err := stg.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal("Töst.txt", bd.Filename)
+	ast.Equal(int64(60), bd.Retention)
+	ast.Equal("willie", bd.Properties["X-User"])
+	ast.Equal([]any{"a", "b"}, bd.Properties["X-Roles"])
+	ast.Equal(float64(42), bd.Properties["X-Count"])
+	ast.Equal("no header", bd.Properties["user"])
+
+	var buf bytes.Buffer
+	ast.Nil(stg.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.String())
+
+	bd.Properties["X-User"] = "changed"
+	bd.Properties["X-Count"] = nil
+	ast.Nil(stg.UpdateBlobDescription(id, bd))
+	bd, err = stg.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal("changed", bd.Properties["X-User"])
+	_, ok = bd.Properties["X-Count"]
+	ast.False(ok)
+
+	r, err := stg.GetRetention(id)
+	ast.Nil(err)
+	ast.Equal(int64(60), r.Retention)
+	ast.Nil(stg.ResetRetention(id))
+
+	ci, err := stg.CheckBlob(id)
+	ast.Nil(err)
+	ast.True(ci.Healthy)
+
+	count := 0
+	ast.Nil(stg.GetBlobs(func(bid string) bool {
+		ast.Equal(id, bid)
+		count++
+		return true
+	}))
+	ast.Equal(1, count)
+
+	ast.Nil(stg.DeleteBlob(id))
+	ok, err = stg.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+	ast.NotNil(stg.RetrieveBlob(id, &buf))
+	ast.Nil(stg.GetLastError())
+
+	// a given id is used on the remote instance
+	b = newBlob()
+	b.BlobID = "0815"
+	id, err = stg.StoreBlob(&b, strings.NewReader(payload))
+	ast.Nil(err)
+	ast.Equal("0815", id)
+	ast.Nil(stg.DeleteBlob(id))
+}
+
+func TestAuth(t *testing.T) {
+	ast := assert.New(t)
+	srv := startRemote(t)
+	stg := &goblobstore.BlobStorage{
+		Client: &goblobstore.Client{
+			URL:     srv.URL,
+			APIKey:  "wrong",
+			Retries: -1,
+		},
+		Tenant: tenant,
+	}
+	ast.Nil(stg.Init())
+	_, err := stg.HasBlob("0815")
+	ast.ErrorIs(err, goblobstore.ErrRemote)
+	ast.NotNil(stg.GetLastError())
+}
+
+func TestRetries(t *testing.T) {
+	ast := assert.New(t)
+	srv := startRemote(t)
+	// the first two requests fail with a server error
+	var calls atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+	stg := &goblobstore.BlobStorage{
+		Client: &goblobstore.Client{
+			URL:        proxy.URL,
+			APIKey:     apiv1.APIKey,
+			RetryDelay: time.Millisecond,
+		},
+		Tenant: tenant,
+	}
+	ast.Nil(stg.Init())
+
+	// a seekable reader can be send again
+	b := newBlob()
+	id, err := stg.StoreBlob(&b, bytes.NewReader([]byte(payload)))
+	ast.Nil(err)
+	ast.Equal(int32(3), calls.Load())
+	var buf bytes.Buffer
+	ast.Nil(stg.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.String())
+	ast.Nil(stg.DeleteBlob(id))
+
+	// a stream can't be send again
+	calls.Store(0)
+	b = newBlob()
+	_, err = stg.StoreBlob(&b, io.MultiReader(strings.NewReader(payload)))
+	ast.ErrorIs(err, goblobstore.ErrRemote)
+	ast.Equal(int32(1), calls.Load())
+}
+
+func TestTenantManager(t *testing.T) {
+	ast := assert.New(t)
+	srv := startRemote(t)
+	tm := &goblobstore.TenantManager{
+		Client: &goblobstore.Client{
+			URL:    srv.URL,
+			APIKey: apiv1.APIKey,
+		},
+		Tenants: []string{"tnt1", "tnt2"},
+	}
+	ast.Nil(tm.Init())
+
+	ast.False(tm.HasTenant("tnt1"))
+	ast.Nil(tm.AddTenant("tnt1"))
+	ast.True(tm.HasTenant("tnt1"))
+
+	tenants := make([]string, 0)
+	ast.Nil(tm.GetTenants(func(t string) bool {
+		tenants = append(tenants, t)
+		return true
+	}))
+	ast.Equal([]string{"tnt1"}, tenants)
+
+	cfg, err := tm.GetConfig("tnt1")
+	ast.Nil(err)
+	ast.Nil(cfg)
+
+	stg := &goblobstore.BlobStorage{Client: tm.Client, Tenant: "tnt1"}
+	ast.Nil(stg.Init())
+	b := newBlob()
+	_, err = stg.StoreBlob(&b, strings.NewReader(payload))
+	ast.Nil(err)
+	ast.Equal(int64(len(payload)), tm.GetSize("tnt1"))
+
+	_, err = tm.RemoveTenant("tnt1")
+	ast.Nil(err)
+	_, err = tm.RemoveTenant("tnt3")
+	ast.NotNil(err)
+}
@@ -0,0 +1,188 @@
+package goblobstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+)
+
+// path of the tenant administration on the remote instance, the tenant is given via header
+const storesPath = "/config/stores"
+
+// TenantManager the tenant manager of a remote GoBlobStore instance
+type TenantManager struct {
+	Client  *Client  // client of the remote instance, can be shared with the storages
+	Tenants []string // the remote api can't list tenants, so GetTenants walks thru these configured tenants
+}
+
+// Init intialise this tenant manager
+func (s *TenantManager) Init() error {
+	if s.Client == nil {
+		return errors.New("goblobstore: client should not be nil")
+	}
+	if s.Client.http == nil {
+		return s.Client.Init()
+	}
+	return nil
+}
+
+// GetTenants walk thru all configured tenants, which are present on the remote instance
+func (s *TenantManager) GetTenants(callback func(tenant string) bool) error {
+	for _, tenant := range s.Tenants {
+		if !s.HasTenant(tenant) {
+			continue
+		}
+		if !callback(tenant) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func jsonBody(v any) (func() (io.Reader, int64, error), error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.Reader, int64, error) {
+		return bytes.NewReader(js), int64(len(js)), nil
+	}, nil
+}
+
+func jsonHeader() http.Header {
+	h := make(http.Header)
+	h.Set("Content-Type", "application/json")
+	return h
+}
+
+// AddTenant add a new tenant on the remote instance
+func (s *TenantManager) AddTenant(tenant string) error {
+	body, err := jsonBody(config.Storage{})
+	if err != nil {
+		return err
+	}
+	return s.Client.doJSON(request{
+		method: http.MethodPost,
+		path:   storesPath,
+		tenant: tenant,
+		header: jsonHeader(),
+		body:   body,
+		retry:  true,
+	}, nil)
+}
+
+// RemoveTenant remove a tenant from the remote instance, returning the process id of the remote deletion
+func (s *TenantManager) RemoveTenant(tenant string) (string, error) {
+	var rsp struct {
+		ProcessID string `json:"processid"`
+	}
+	err := s.Client.doJSON(request{
+		method: http.MethodDelete,
+		path:   storesPath,
+		tenant: tenant,
+		retry:  true,
+	}, &rsp)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", errors.New("tenant not exists")
+	}
+	return rsp.ProcessID, err
+}
+
+// configResponse the config response of the remote instance
+type configResponse struct {
+	Created    bool           `json:"created"`
+	Backup     config.Storage `json:"backup"`
+	Properties map[string]any `json:"properties"`
+}
+
+func (s *TenantManager) getConfig(tenant string) (*configResponse, error) {
+	var rsp configResponse
+	err := s.Client.doJSON(request{
+		method: http.MethodGet,
+		path:   storesPath,
+		tenant: tenant,
+		retry:  true,
+	}, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// HasTenant checking is a tenant is created on the remote instance
+func (s *TenantManager) HasTenant(tenant string) bool {
+	rsp, err := s.getConfig(tenant)
+	if err != nil {
+		logger.Errorf("goblobstore: error getting the config of tenant %s: %v", tenant, err)
+		return false
+	}
+	return rsp.Created
+}
+
+// SetConfig setting the backup of the tenant on the remote instance, the remote instance must allow tenant backups.
+// The properties of the tenant config can't be set remotely.
+func (s *TenantManager) SetConfig(tenant string, cnfg interfaces.TenantConfig) error {
+	body, err := jsonBody(cnfg.Backup)
+	if err != nil {
+		return err
+	}
+	return s.Client.doJSON(request{
+		method: http.MethodPost,
+		path:   storesPath,
+		tenant: tenant,
+		header: jsonHeader(),
+		body:   body,
+		retry:  true,
+	}, nil)
+}
+
+// GetConfig getting the config of the tenant from the remote instance
+func (s *TenantManager) GetConfig(tenant string) (*interfaces.TenantConfig, error) {
+	rsp, err := s.getConfig(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Backup.Storageclass == "" && rsp.Properties == nil {
+		return nil, nil
+	}
+	return &interfaces.TenantConfig{
+		Backup:     rsp.Backup,
+		Properties: rsp.Properties,
+	}, nil
+}
+
+// GetSize getting the overall storage size for a tenant from the remote instance
+func (s *TenantManager) GetSize(tenant string) int64 {
+	var rsp struct {
+		Size int64 `json:"size"`
+	}
+	err := s.Client.doJSON(request{
+		method: http.MethodGet,
+		path:   storesPath + "/size",
+		tenant: tenant,
+		retry:  true,
+	}, &rsp)
+	if err != nil {
+		return -1
+	}
+	return rsp.Size
+}
+
+// AddSize nothing to do, the remote instance calculates the size
+func (s *TenantManager) AddSize(_ string, _ int64) {
+}
+
+// SubSize nothing to do, the remote instance calculates the size
+func (s *TenantManager) SubSize(_ string, _ int64) {
+}
+
+// Close closing this service
+func (s *TenantManager) Close() error {
+	return s.Client.Close()
+}
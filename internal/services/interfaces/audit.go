@@ -0,0 +1,16 @@
+package interfaces
+
+import "github.com/willie68/GoBlobStore/pkg/model"
+
+// AuditJournal interface for the append only deletion audit journal
+type AuditJournal interface {
+	Init() error                                                                       // initialize the journal
+	Append(tenant string, e model.AuditEntry) error                                    // appending a new entry to the journal of the tenant
+	Query(tenant string, from, to int64, callback func(e model.AuditEntry) bool) error // walking thru all entries of the tenant in the time range [from, to], to = 0 means open end
+	Close() error                                                                      // closing the journal
+}
+
+// AuditDeleter is implemented by storages, which can record the reason of a deletion into the audit journal
+type AuditDeleter interface {
+	DeleteBlobWithReason(id string, reason model.DeletionReason) error // removing a blob from the storage and recording the reason
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import "github.com/willie68/GoBlobStore/pkg/model"
+
+// BulkOperator is implemented by tenant storages, which can run an operation on all blobs matching a query
+type BulkOperator interface {
+	StartBulk(req model.BulkRequest) (model.BulkJob, error) // starting the bulk job in the background
+	GetBulk() (model.BulkJob, bool)                         // getting the status of the actual or last bulk job
+	GetBulkReport() ([]model.BulkResult, bool)              // getting the results of the single blobs of the actual or last bulk job
+	CancelBulk() (model.BulkJob, error)                     // cancelling the running bulk job
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import "io"
+
+// CacheHint a hint for the cache handling of a single request
+type CacheHint int
+
+const (
+	// CacheDefault the blob is admitted to the cache as usual
+	CacheDefault CacheHint = iota
+	// CacheBypass the blob is not admitted to the cache, for bulk operations like backup, check or export, which are reading every blob once
+	CacheBypass
+)
+
+// HintedStorage is implemented by storages with a cache, which are taking a cache hint per request
+type HintedStorage interface {
+	RetrieveBlobWithHint(id string, w io.Writer, hint CacheHint) error // retrieving the binary data, with CacheBypass the blob is not added to the cache
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import "github.com/willie68/GoBlobStore/pkg/model"
+
+// CacheManager is implemented by storage factories, which are managing the cache, maybe partitioned by tenants
+type CacheManager interface {
+	GetCacheStats() []model.CacheStats                                                       // getting the statistics of all cache partitions
+	ClearCache(tenant string) (int, error)                                                   // removing all blobs of the tenant from the cache, returning the count of removed blobs
+	GetCacheEntries(tenant, order string, limit int) ([]model.CacheEntry, error)             // getting the blobs of the tenant in the cache, ordered by hits or last access
+	EvictCacheBlob(tenant, id string) error                                                  // removing a single blob of the tenant from the cache
+	PinCacheBlob(tenant, id string, pin bool) error                                          // pinning a blob, so it is never evicted, a missing blob is loaded first
+	StartCacheWarming(tenant string, req model.CacheWarmRequest) (model.CacheWarming, error) // pre-loading blobs of the tenant into the cache
+	GetCacheWarming(tenant string) (model.CacheWarming, bool)                                // getting the status of the actual or last warming of the tenant
+	CancelCacheWarming(tenant string) (model.CacheWarming, error)                            // cancelling the running warming of the tenant
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import "github.com/willie68/GoBlobStore/pkg/model"
+
+// CacheWarmer is implemented by tenant storages, which can pre-load blobs into their cache
+type CacheWarmer interface {
+	WarmBlob(id string) (bool, error)                                    // loading a single blob into the cache, returning true if the blob was loaded
+	StartWarming(req model.CacheWarmRequest) (model.CacheWarming, error) // starting the warming of the cache in the background
+	GetWarming() (model.CacheWarming, bool)                              // getting the status of the actual or last warming
+	CancelWarming() (model.CacheWarming, error)                          // cancelling the running warming
+}
@@ -1,6 +1,9 @@
 package interfaces
 
-import "github.com/willie68/GoBlobStore/pkg/model"
+import (
+	"github.com/willie68/GoBlobStore/pkg/model"
+	"github.com/willie68/GoBlobStore/pkg/model/query"
+)
 
 // Index interface for indexer
 type Index interface {
@@ -10,6 +13,16 @@ type Index interface {
 	NewBatch() IndexBatch                                     // returning a index batch processor
 }
 
+// QueryExplainer interface for an index, which can show the native query it would run for a parsed query
+type QueryExplainer interface {
+	ExplainQuery(q query.Query) (string, any, error) // returning the name of the index engine and the native query
+}
+
+// MultiTenantIndex interface for an index, which can search the blobs of many tenants with one shared query
+type MultiTenantIndex interface {
+	SearchTenants(query string, tenants []string, callback func(tenant, id string) bool) error // searching all tenants, the hits are delivered ordered by the lower case tenant and blob id
+}
+
 // IndexBatch interface batch index
 type IndexBatch interface {
 	Add(id string, b model.BlobDescription) error // add a single blob description to this batch
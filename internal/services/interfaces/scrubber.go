@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/willie68/GoBlobStore/pkg/model"
+
+// BlobScrubber is implemented by tenant storages, which can verify a blob on all of their storages
+type BlobScrubber interface {
+	ScrubBlob(id string) (*model.BlobDescription, error) // checking the hashes of the blob, the result is written into the check of the blob description
+}
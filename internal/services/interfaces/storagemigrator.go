@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// StorageMigrator is implemented by storage factories, which can migrate the primary storage of a tenant to another storage without downtime
+type StorageMigrator interface {
+	StartMigration(tenant string, target config.Storage) (model.StorageMigration, error) // starting the migration of the tenant to the target storage
+	GetMigration(tenant string) (model.StorageMigration, bool)                           // getting the status of the actual or last migration of the tenant
+	CancelMigration(tenant string) (model.StorageMigration, error)                       // cancelling the migration, the tenant stays on the source storage
+}
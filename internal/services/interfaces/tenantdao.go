@@ -4,8 +4,11 @@ import "github.com/willie68/GoBlobStore/internal/config"
 
 // TenantConfig config for the tenant
 type TenantConfig struct {
-	Backup     config.Storage `yaml:"backup" json:"backup"`
-	Properties map[string]any `yaml:"properties" json:"properties"`
+	Backup     config.Storage     `yaml:"backup" json:"backup"`
+	Properties map[string]any     `yaml:"properties" json:"properties"`
+	Storage    *config.Storage    `yaml:"storage,omitempty" json:"storage,omitempty"` // primary storage of the tenant, if different from the engine storage
+	Target     *config.Storage    `yaml:"target,omitempty" json:"target,omitempty"`   // target storage of a running migration of the primary storage
+	Cache      *config.CacheQuota `yaml:"cache,omitempty" json:"cache,omitempty"`     // limits of the cache partition of the tenant, if the cache is partitioned
 }
 
 // TenantManager is the part of the service which will administrate the tenant part of a storage system
@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/willie68/GoBlobStore/pkg/model"
+
+// TenantSearcher is implemented by storage factories, which can search the blobs of all tenants
+type TenantSearcher interface {
+	SearchAllTenants(query string, offset, limit int) (model.TenantSearch, error) // searching the blobs of all tenants, the hits are ordered by tenant and blob id
+}
@@ -0,0 +1,8 @@
+package interfaces
+
+import "github.com/willie68/GoBlobStore/pkg/model"
+
+// TieredStorage is implemented by storages, which are moving blobs between different storage tiers
+type TieredStorage interface {
+	SetMoveListener(listener func(id string, b *model.BlobDescription)) // registering a listener, which will be called after a blob has been moved to another tier
+}
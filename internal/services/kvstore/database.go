@@ -0,0 +1,287 @@
+package kvstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// the buckets of the database
+var (
+	bktDescriptions = []byte("descriptions")
+	bktBlobs        = []byte("blobs")
+	bktChunks       = []byte("chunks")
+	bktGenerations  = []byte("generations") // the actual chunk generation of a chunked blob
+	bktRetentions   = []byte("retentions")
+)
+
+// max size of a single transaction while compacting
+const compactTxSize = 64 * 1024 * 1024
+
+// database a bbolt database, which is shared between all storages working on the same database file
+// (e.g. the same storage is used as cache and for a tenant)
+type database struct {
+	path    string
+	db      *bolt.DB
+	m       sync.RWMutex // the write lock is only used for switching the database file on compaction
+	refs    int
+	um      sync.Mutex
+	uploads map[string]int // blobs, which chunks are actually written
+}
+
+var (
+	dbs  = make(map[string]*database)
+	dbsm sync.Mutex
+)
+
+// openDatabase opening the database file or getting the already opened database
+func openDatabase(path string) (*database, error) {
+	dbsm.Lock()
+	defer dbsm.Unlock()
+	if d, ok := dbs[path]; ok {
+		d.refs++
+		return d, nil
+	}
+	db, err := openBolt(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &database{
+		path:    path,
+		db:      db,
+		refs:    1,
+		uploads: make(map[string]int),
+	}
+	dbs[path] = d
+	return d, nil
+}
+
+func openBolt(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bktDescriptions, bktBlobs, bktChunks, bktGenerations, bktRetentions} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// release releasing the database, the last release closes the database file
+func (d *database) release() error {
+	dbsm.Lock()
+	defer dbsm.Unlock()
+	d.refs--
+	if d.refs > 0 {
+		return nil
+	}
+	delete(dbs, d.path)
+	d.m.Lock()
+	defer d.m.Unlock()
+	return d.db.Close()
+}
+
+func (d *database) view(fn func(tx *bolt.Tx) error) error {
+	d.m.RLock()
+	defer d.m.RUnlock()
+	return d.db.View(fn)
+}
+
+func (d *database) update(fn func(tx *bolt.Tx) error) error {
+	d.m.RLock()
+	defer d.m.RUnlock()
+	return d.db.Update(fn)
+}
+
+// startUpload marking a blob as uploading, so the compaction will not remove the chunks
+func (d *database) startUpload(id string) {
+	d.um.Lock()
+	defer d.um.Unlock()
+	d.uploads[id]++
+}
+
+func (d *database) endUpload(id string) {
+	d.um.Lock()
+	defer d.um.Unlock()
+	d.uploads[id]--
+	if d.uploads[id] <= 0 {
+		delete(d.uploads, id)
+	}
+}
+
+func (d *database) uploading(id string) bool {
+	d.um.Lock()
+	defer d.um.Unlock()
+	return d.uploads[id] > 0
+}
+
+// compact removing orphaned chunks and writing the database into a new file, so the free pages are given back
+func (d *database) compact() error {
+	err := d.removeOrphans()
+	if err != nil {
+		return err
+	}
+	d.m.Lock()
+	defer d.m.Unlock()
+	tmp := d.path + ".compact"
+	_ = os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0600, nil)
+	if err != nil {
+		return err
+	}
+	err = bolt.Compact(dst, d.db, compactTxSize)
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	err = d.db.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, d.path)
+	if err != nil {
+		logger.Errorf("kvstore: can't replace %s with the compacted database: %v", d.path, err)
+	}
+	// the database must be opened again in any case
+	db, oerr := openBolt(d.path)
+	if oerr != nil {
+		return oerr
+	}
+	d.db = db
+	return err
+}
+
+// orphan chunks of a generation of a blob, which is not in use
+type orphan struct {
+	id  string
+	gen uint64
+}
+
+// removeOrphans removing chunks of blobs without a description or of an old generation, e.g. from interrupted uploads
+func (d *database) removeOrphans() error {
+	orphans := make([]orphan, 0)
+	err := d.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bktChunks).Cursor()
+		for k, _ := c.First(); k != nil; {
+			id, gen, ok := parseChunkKey(k)
+			if !ok {
+				k, _ = c.Next()
+				continue
+			}
+			if !currentGen(tx, id, gen) {
+				orphans = append(orphans, orphan{id: id, gen: gen})
+			}
+			k, _ = c.Seek(chunkKey(id, gen, math.MaxUint64))
+			if k != nil && bytes.HasPrefix(k, chunkPrefix(id, gen)) {
+				k, _ = c.Next()
+			}
+		}
+		return nil
+	})
+	if err != nil || len(orphans) == 0 {
+		return err
+	}
+	return d.update(func(tx *bolt.Tx) error {
+		for _, o := range orphans {
+			if currentGen(tx, o.id, o.gen) || d.uploading(o.id) {
+				continue
+			}
+			logger.Infof("kvstore: removing orphaned chunks of %s", o.id)
+			if err := deleteChunks(tx, chunkPrefix(o.id, o.gen)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// currentGen checking if the chunks of this generation are the content of the blob
+func currentGen(tx *bolt.Tx, id string, gen uint64) bool {
+	if tx.Bucket(bktDescriptions).Get([]byte(id)) == nil {
+		return false
+	}
+	g, ok := getGen(tx, id)
+	return ok && g == gen
+}
+
+// getGen getting the actual chunk generation of the blob, false for a blob without chunks
+func getGen(tx *bolt.Tx, id string) (uint64, bool) {
+	v := tx.Bucket(bktGenerations).Get([]byte(id))
+	if len(v) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(v), true
+}
+
+func putGen(tx *bolt.Tx, id string, gen uint64) error {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, gen)
+	return tx.Bucket(bktGenerations).Put([]byte(id), v)
+}
+
+// chunkKey the key of a chunk: length of the id, id, generation and index. The length prefix makes the key unique,
+// even if the id contains any separator, the numbers are big endian, so the chunks are sorted.
+func chunkKey(id string, gen, idx uint64) []byte {
+	p := chunkPrefix(id, gen)
+	k := make([]byte, len(p)+8)
+	copy(k, p)
+	binary.BigEndian.PutUint64(k[len(p):], idx)
+	return k
+}
+
+// chunkPrefix the prefix of all chunks of a generation of the blob
+func chunkPrefix(id string, gen uint64) []byte {
+	p := blobPrefix(id)
+	k := make([]byte, len(p)+8)
+	copy(k, p)
+	binary.BigEndian.PutUint64(k[len(p):], gen)
+	return k
+}
+
+// blobPrefix the prefix of all chunks of all generations of the blob
+func blobPrefix(id string) []byte {
+	k := make([]byte, 2+len(id))
+	binary.BigEndian.PutUint16(k, uint16(len(id)))
+	copy(k[2:], id)
+	return k
+}
+
+// parseChunkKey getting the id and the generation of the chunk key
+func parseChunkKey(k []byte) (string, uint64, bool) {
+	if len(k) < 2 {
+		return "", 0, false
+	}
+	l := int(binary.BigEndian.Uint16(k))
+	if len(k) != 2+l+16 {
+		return "", 0, false
+	}
+	return string(k[2 : 2+l]), binary.BigEndian.Uint64(k[2+l:]), true
+}
+
+// deleteChunks deleting all chunks with the prefix
+func deleteChunks(tx *bolt.Tx, prefix []byte) error {
+	c := tx.Bucket(bktChunks).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,564 @@
+// Package kvstore contains a storage, which is storing the blobs in an embedded key value store (bbolt), one database per tenant.
+// Small blobs are stored as a single value, larger blobs are split into chunks.
+package kvstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/logging"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+// KVStorageName name of this storage class
+const KVStorageName = "kvstore"
+
+// DBFilename name of the database file in the tenant folder
+const DBFilename = "blobstore.db"
+
+// DefaultChunkSize blobs greater than this are stored in chunks of this size
+const DefaultChunkSize = 64 * 1024
+
+// chunks written in one transaction
+const chunksPerTx = 16
+
+// keys read in one transaction while listing
+const listBatch = 1000
+
+var logger = logging.New().WithName("kvstore")
+
+// checking interface compatibility
+var _ interfaces.BlobStorage = &BlobStorage{}
+
+// BlobStorage service for storing blobs into an embedded key value store
+type BlobStorage struct {
+	RootPath         string        // root path of the storage, the database is stored in <rootpath>/<tenant>/blobstore.db
+	Tenant           string        // this is the tenant, on which this service will work
+	ChunkSize        int           // blobs greater than this are stored in chunks, default 64kB
+	CompactInterval  time.Duration // interval of the automatic compaction, 0 means no automatic compaction
+	SnapshotPath     string        // folder for the backup snapshots, empty means no automatic snapshots
+	SnapshotInterval time.Duration // interval of the automatic snapshots
+	db               *database
+	lastError        error
+	em               sync.Mutex
+	quit             chan bool
+}
+
+// Init initialize this service
+func (s *BlobStorage) Init() error {
+	if s.Tenant == "" {
+		return errors.New("tenant should not be null or empty")
+	}
+	if s.ChunkSize <= 0 {
+		s.ChunkSize = DefaultChunkSize
+	}
+	path, err := filepath.Abs(filepath.Join(s.RootPath, s.Tenant))
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	s.db, err = openDatabase(filepath.Join(path, DBFilename))
+	if err != nil {
+		return fmt.Errorf("kvstore: can't open database of tenant %s: %w", s.Tenant, err)
+	}
+	s.quit = make(chan bool)
+	s.startBackground()
+	return nil
+}
+
+func (s *BlobStorage) startBackground() {
+	if s.CompactInterval <= 0 && (s.SnapshotPath == "" || s.SnapshotInterval <= 0) {
+		return
+	}
+	// a nil channel is never selected, so the disabled task will never run
+	var compact, snapshot <-chan time.Time
+	tickers := make([]*time.Ticker, 0, 2)
+	if s.CompactInterval > 0 {
+		t := time.NewTicker(s.CompactInterval)
+		tickers = append(tickers, t)
+		compact = t.C
+	}
+	if s.SnapshotPath != "" && s.SnapshotInterval > 0 {
+		t := time.NewTicker(s.SnapshotInterval)
+		tickers = append(tickers, t)
+		snapshot = t.C
+	}
+	go func() {
+		defer func() {
+			for _, t := range tickers {
+				t.Stop()
+			}
+		}()
+		for {
+			select {
+			case <-compact:
+				if err := s.Compact(); err != nil {
+					s.setLastError(err)
+					logger.Errorf("kvstore: error compacting database of tenant %s: %v", s.Tenant, err)
+				}
+			case <-snapshot:
+				if _, err := s.SnapshotFile(); err != nil {
+					s.setLastError(err)
+					logger.Errorf("kvstore: error writing snapshot of tenant %s: %v", s.Tenant, err)
+				}
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+// GetTenant return the id of the tenant
+func (s *BlobStorage) GetTenant() string {
+	return s.Tenant
+}
+
+// GetBlobs getting a list of blob from the database
+func (s *BlobStorage) GetBlobs(callback func(id string) bool) error {
+	return s.walk(bktDescriptions, func(k, _ []byte) bool {
+		return callback(string(k))
+	})
+}
+
+// walk calling the callback for every entry of the bucket, the callback is called outside of the transaction,
+// so the callback can use the storage
+func (s *BlobStorage) walk(bkt []byte, callback func(k, v []byte) bool) error {
+	var last []byte
+	for {
+		keys := make([][]byte, 0, listBatch)
+		values := make([][]byte, 0, listBatch)
+		err := s.db.view(func(tx *bolt.Tx) error {
+			c := tx.Bucket(bkt).Cursor()
+			var k, v []byte
+			if last == nil {
+				k, v = c.First()
+			} else {
+				k, v = c.Seek(last)
+				if bytes.Equal(k, last) {
+					k, v = c.Next()
+				}
+			}
+			for ; k != nil && len(keys) < listBatch; k, v = c.Next() {
+				keys = append(keys, bytes.Clone(k))
+				values = append(values, bytes.Clone(v))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for x := range keys {
+			if !callback(keys[x], values[x]) {
+				return nil
+			}
+		}
+		if len(keys) < listBatch {
+			return nil
+		}
+		last = keys[len(keys)-1]
+	}
+}
+
+// StoreBlob storing a blob to the database
+func (s *BlobStorage) StoreBlob(b *model.BlobDescription, r io.Reader) (string, error) {
+	if b.BlobID == "" {
+		b.BlobID = utils.GenerateID()
+	}
+	id := b.BlobID
+	if len(id) > math.MaxUint16 {
+		return "", errors.New("kvstore: blob id too long")
+	}
+	s.db.startUpload(id)
+	defer s.db.endUpload(id)
+
+	h := sha256.New()
+	tr := io.TeeReader(r, h)
+	// reading one byte more than a chunk, to decide if the blob will be chunked
+	head := make([]byte, s.ChunkSize+1)
+	n, err := io.ReadFull(tr, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	size := int64(n)
+	chunked := n > s.ChunkSize
+	// the chunks are written under a new generation, an old version of the blob stays readable until the switch
+	var gen uint64
+	if chunked {
+		err = s.db.update(func(tx *bolt.Tx) error {
+			var err error
+			gen, err = tx.Bucket(bktGenerations).NextSequence()
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		size, err = s.writeChunks(id, gen, io.MultiReader(bytes.NewReader(head[:n]), tr))
+		if err != nil {
+			s.deleteChunksQuiet(chunkPrefix(id, gen))
+			return "", err
+		}
+	}
+	if (b.ContentLength > 0) && b.ContentLength != size {
+		if chunked {
+			s.deleteChunksQuiet(chunkPrefix(id, gen))
+		}
+		return "", fmt.Errorf("wrong content length %d=%d", b.ContentLength, size)
+	}
+	b.Hash = fmt.Sprintf("sha-256:%x", h.Sum(nil))
+	b.ContentLength = size
+	js, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	// switching to the new content and description in one transaction, the old version is removed
+	err = s.db.update(func(tx *bolt.Tx) error {
+		old, hasOld := getGen(tx, id)
+		if err := tx.Bucket(bktRetentions).Delete([]byte(id)); err != nil {
+			return err
+		}
+		if chunked {
+			if err := tx.Bucket(bktBlobs).Delete([]byte(id)); err != nil {
+				return err
+			}
+			if err := putGen(tx, id, gen); err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Bucket(bktBlobs).Put([]byte(id), head[:n]); err != nil {
+				return err
+			}
+			if err := tx.Bucket(bktGenerations).Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		if hasOld && (!chunked || old != gen) {
+			if err := deleteChunks(tx, chunkPrefix(id, old)); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bktDescriptions).Put([]byte(id), js)
+	})
+	if err != nil {
+		if chunked {
+			s.deleteChunksQuiet(chunkPrefix(id, gen))
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+// writeChunks writing the data in chunks of the generation, some chunks in one transaction
+func (s *BlobStorage) writeChunks(id string, gen uint64, r io.Reader) (int64, error) {
+	var size int64
+	var idx uint64
+	buf := make([]byte, s.ChunkSize)
+	eof := false
+	for !eof {
+		chunks := make([][]byte, 0, chunksPerTx)
+		for len(chunks) < chunksPerTx {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				chunks = append(chunks, bytes.Clone(buf[:n]))
+				size += int64(n)
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				eof = true
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		err := s.db.update(func(tx *bolt.Tx) error {
+			bkt := tx.Bucket(bktChunks)
+			for x, c := range chunks {
+				if err := bkt.Put(chunkKey(id, gen, idx+uint64(x)), c); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		idx += uint64(len(chunks))
+	}
+	return size, nil
+}
+
+// UpdateBlobDescription updating the blob description
+func (s *BlobStorage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
+	return s.db.update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(bktDescriptions)
+		if bkt.Get([]byte(id)) == nil {
+			return os.ErrNotExist
+		}
+		js, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(id), js)
+	})
+}
+
+// HasBlob checking, if a blob is present
+func (s *BlobStorage) HasBlob(id string) (bool, error) {
+	if id == "" {
+		return false, nil
+	}
+	found := false
+	err := s.db.view(func(tx *bolt.Tx) error {
+		found = tx.Bucket(bktDescriptions).Get([]byte(id)) != nil
+		return nil
+	})
+	return found, err
+}
+
+// GetBlobDescription getting the description of the file
+func (s *BlobStorage) GetBlobDescription(id string) (*model.BlobDescription, error) {
+	var b model.BlobDescription
+	err := s.getJSON(bktDescriptions, id, &b)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// RetrieveBlob retrieving the binary data from the database, chunked blobs are streamed chunk by chunk.
+// The blob is read in one transaction, so a concurrent overwrite can't mix two versions.
+func (s *BlobStorage) RetrieveBlob(id string, w io.Writer) error {
+	return s.db.view(func(tx *bolt.Tx) error {
+		js := tx.Bucket(bktDescriptions).Get([]byte(id))
+		if js == nil {
+			return os.ErrNotExist
+		}
+		var b model.BlobDescription
+		if err := json.Unmarshal(js, &b); err != nil {
+			return err
+		}
+		var size int64
+		if v := tx.Bucket(bktBlobs).Get([]byte(id)); v != nil {
+			n, err := w.Write(v)
+			if err != nil {
+				return err
+			}
+			size = int64(n)
+		} else if gen, ok := getGen(tx, id); ok {
+			prefix := chunkPrefix(id, gen)
+			c := tx.Bucket(bktChunks).Cursor()
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				n, err := w.Write(v)
+				if err != nil {
+					return err
+				}
+				size += int64(n)
+			}
+		}
+		if size != b.ContentLength {
+			return fmt.Errorf("kvstore: blob %s is incomplete, %d of %d bytes read", id, size, b.ContentLength)
+		}
+		return nil
+	})
+}
+
+// DeleteBlob removing a blob from the database
+func (s *BlobStorage) DeleteBlob(id string) error {
+	found := false
+	err := s.db.update(func(tx *bolt.Tx) error {
+		found = tx.Bucket(bktDescriptions).Get([]byte(id)) != nil
+		return deleteBlob(tx, id)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+// deleteChunksQuiet removing the chunks of an aborted upload
+func (s *BlobStorage) deleteChunksQuiet(prefix []byte) {
+	err := s.db.update(func(tx *bolt.Tx) error {
+		return deleteChunks(tx, prefix)
+	})
+	if err != nil {
+		logger.Errorf("kvstore: error removing chunks of tenant %s: %v", s.Tenant, err)
+	}
+}
+
+// deleteBlob deleting all entries of a blob
+func deleteBlob(tx *bolt.Tx, id string) error {
+	for _, bkt := range [][]byte{bktDescriptions, bktBlobs, bktGenerations, bktRetentions} {
+		if err := tx.Bucket(bkt).Delete([]byte(id)); err != nil {
+			return err
+		}
+	}
+	return deleteChunks(tx, blobPrefix(id))
+}
+
+// CheckBlob checking a single blob from the storage system
+func (s *BlobStorage) CheckBlob(id string) (*model.CheckInfo, error) {
+	return utils.CheckBlob(id, s)
+}
+
+// SearchBlobs querying a single blob, niy
+func (s *BlobStorage) SearchBlobs(_ string, _ func(id string) bool) error {
+	return errors.New("not implemented yet")
+}
+
+// GetAllRetentions for every retention entry for this tenant we call this this function, you can stop the listing by returnong a false
+func (s *BlobStorage) GetAllRetentions(callback func(r model.RetentionEntry) bool) error {
+	return s.walk(bktRetentions, func(k, v []byte) bool {
+		var r model.RetentionEntry
+		if err := json.Unmarshal(v, &r); err != nil {
+			logger.Errorf("GetAllRetention: error deserialising: %s\r\n%v", string(k), err)
+			return true
+		}
+		return callback(r)
+	})
+}
+
+// AddRetention adding a retention entry to the storage
+func (s *BlobStorage) AddRetention(r *model.RetentionEntry) error {
+	ok, err := s.HasBlob(r.BlobID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return os.ErrNotExist
+	}
+	return s.putJSON(bktRetentions, r.BlobID, r)
+}
+
+// GetRetention getting a single retention entry
+func (s *BlobStorage) GetRetention(id string) (model.RetentionEntry, error) {
+	var r model.RetentionEntry
+	err := s.getJSON(bktRetentions, id, &r)
+	if err != nil {
+		return model.RetentionEntry{}, err
+	}
+	return r, nil
+}
+
+// DeleteRetention deletes the retention entry from the storage
+func (s *BlobStorage) DeleteRetention(id string) error {
+	return s.db.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bktRetentions).Delete([]byte(id))
+	})
+}
+
+// ResetRetention resets the retention for a blob
+func (s *BlobStorage) ResetRetention(id string) error {
+	r, err := s.GetRetention(id)
+	if err != nil {
+		return err
+	}
+	r.RetentionBase = time.Now().UnixMilli()
+	return s.putJSON(bktRetentions, id, r)
+}
+
+func (s *BlobStorage) putJSON(bkt []byte, id string, v any) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.db.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bkt).Put([]byte(id), js)
+	})
+}
+
+func (s *BlobStorage) getJSON(bkt []byte, id string, v any) error {
+	var js []byte
+	err := s.db.view(func(tx *bolt.Tx) error {
+		if d := tx.Bucket(bkt).Get([]byte(id)); d != nil {
+			js = bytes.Clone(d)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if js == nil {
+		return os.ErrNotExist
+	}
+	return json.Unmarshal(js, v)
+}
+
+// Compact removing orphaned chunks and compacting the database file
+func (s *BlobStorage) Compact() error {
+	return s.db.compact()
+}
+
+// Snapshot writing a consistent backup of the whole database to the writer
+func (s *BlobStorage) Snapshot(w io.Writer) error {
+	return s.db.view(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+// SnapshotFile writing a backup snapshot to <snapshotpath>/<tenant>.db, returning the name of the file
+func (s *BlobStorage) SnapshotFile() (string, error) {
+	if s.SnapshotPath == "" {
+		return "", errors.New("kvstore: no snapshot path configured")
+	}
+	err := os.MkdirAll(s.SnapshotPath, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Join(s.SnapshotPath, s.Tenant+".db")
+	tmp := filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	err = s.Snapshot(f)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return filename, os.Rename(tmp, filename)
+}
+
+func (s *BlobStorage) setLastError(err error) {
+	s.em.Lock()
+	defer s.em.Unlock()
+	s.lastError = err
+}
+
+// GetLastError returning the last error of the background tasks
+func (s *BlobStorage) GetLastError() error {
+	s.em.Lock()
+	defer s.em.Unlock()
+	return s.lastError
+}
+
+// Close closing the storage, the database is closed with the last storage of the tenant
+func (s *BlobStorage) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	close(s.quit)
+	err := s.db.release()
+	s.db = nil
+	return err
+}
@@ -0,0 +1,291 @@
+package kvstore
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/pkg/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	rootpath = "../../../testdata/kvstore"
+	tenant   = "test"
+	payload  = "this is a blob content"
+)
+
+func initTest(t *testing.T) *BlobStorage {
+	ast := assert.New(t)
+	ast.Nil(os.RemoveAll(rootpath))
+	srv := &BlobStorage{
+		RootPath:     rootpath,
+		Tenant:       tenant,
+		ChunkSize:    1024,
+		SnapshotPath: filepath.Join(rootpath, "_snapshots"),
+	}
+	ast.Nil(srv.Init())
+	return srv
+}
+
+func store(ast *assert.Assertions, srv *BlobStorage, data []byte) string {
+	b := model.BlobDescription{
+		StoreID:       tenant,
+		TenantID:      tenant,
+		ContentLength: int64(len(data)),
+		ContentType:   "application/octet-stream",
+		CreationDate:  time.Now().UnixMilli(),
+		Filename:      "test.bin",
+		Retention:     180000,
+		Properties:    make(map[string]any),
+	}
+	id, err := srv.StoreBlob(&b, bytes.NewReader(data))
+	ast.Nil(err)
+	ast.NotEmpty(id)
+	return id
+}
+
+func chunkCount(ast *assert.Assertions, srv *BlobStorage) int {
+	count := 0
+	ast.Nil(srv.db.view(func(tx *bolt.Tx) error {
+		count = tx.Bucket(bktChunks).Stats().KeyN
+		return nil
+	}))
+	return count
+}
+
+func TestCRUD(t *testing.T) {
+	ast := assert.New(t)
+	srv := initTest(t)
+	defer srv.Close()
+
+	big := make([]byte, 10*1024+17)
+	rand.Read(big)
+	for _, data := range [][]byte{[]byte(payload), big, {}} {
+		id := store(ast, srv, data)
+		ok, err := srv.HasBlob(id)
+		ast.Nil(err)
+		ast.True(ok)
+
+		bd, err := srv.GetBlobDescription(id)
+		ast.Nil(err)
+		ast.Equal(int64(len(data)), bd.ContentLength)
+		ast.NotEmpty(bd.Hash)
+
+		var buf bytes.Buffer
+		ast.Nil(srv.RetrieveBlob(id, &buf))
+		ast.True(bytes.Equal(data, buf.Bytes()))
+
+		ci, err := srv.CheckBlob(id)
+		ast.Nil(err)
+		ast.True(ci.Healthy)
+
+		bd.Filename = "changed.bin"
+		ast.Nil(srv.UpdateBlobDescription(id, bd))
+		bd, err = srv.GetBlobDescription(id)
+		ast.Nil(err)
+		ast.Equal("changed.bin", bd.Filename)
+
+		ast.Nil(srv.DeleteBlob(id))
+		ok, err = srv.HasBlob(id)
+		ast.Nil(err)
+		ast.False(ok)
+		ast.ErrorIs(srv.RetrieveBlob(id, &buf), os.ErrNotExist)
+		ast.ErrorIs(srv.DeleteBlob(id), os.ErrNotExist)
+		_, err = srv.GetBlobDescription(id)
+		ast.ErrorIs(err, os.ErrNotExist)
+	}
+	ast.Equal(0, chunkCount(ast, srv))
+	ast.ErrorIs(srv.UpdateBlobDescription("unknown", &model.BlobDescription{}), os.ErrNotExist)
+}
+
+func TestContentLength(t *testing.T) {
+	ast := assert.New(t)
+	srv := initTest(t)
+	defer srv.Close()
+
+	big := make([]byte, 5000)
+	b := model.BlobDescription{ContentLength: 4000}
+	_, err := srv.StoreBlob(&b, bytes.NewReader(big))
+	ast.NotNil(err)
+	ok, err := srv.HasBlob(b.BlobID)
+	ast.Nil(err)
+	ast.False(ok)
+	ast.Equal(0, chunkCount(ast, srv))
+}
+
+func TestListAndRetention(t *testing.T) {
+	ast := assert.New(t)
+	srv := initTest(t)
+	defer srv.Close()
+
+	ids := make(map[string]bool)
+	for x := 0; x < listBatch+10; x++ {
+		id := store(ast, srv, []byte(payload))
+		ids[id] = true
+		if x%2 == 0 {
+			bd, err := srv.GetBlobDescription(id)
+			ast.Nil(err)
+			r := model.RetentionEntryFromBlobDescription(*bd)
+			ast.Nil(srv.AddRetention(&r))
+		}
+	}
+	count := 0
+	ast.Nil(srv.GetBlobs(func(id string) bool {
+		ast.True(ids[id])
+		count++
+		return true
+	}))
+	ast.Equal(len(ids), count)
+
+	count = 0
+	var rid string
+	ast.Nil(srv.GetAllRetentions(func(r model.RetentionEntry) bool {
+		ast.True(ids[r.BlobID])
+		rid = r.BlobID
+		count++
+		return true
+	}))
+	ast.Equal((listBatch+11)/2, count)
+
+	r, err := srv.GetRetention(rid)
+	ast.Nil(err)
+	base := r.RetentionBase
+	time.Sleep(2 * time.Millisecond)
+	ast.Nil(srv.ResetRetention(rid))
+	r, err = srv.GetRetention(rid)
+	ast.Nil(err)
+	ast.Greater(r.RetentionBase, base)
+	ast.Nil(srv.DeleteRetention(rid))
+	_, err = srv.GetRetention(rid)
+	ast.NotNil(err)
+
+	// stopping the listing
+	count = 0
+	ast.Nil(srv.GetBlobs(func(id string) bool {
+		count++
+		return count < 10
+	}))
+	ast.Equal(10, count)
+
+	ast.ErrorIs(srv.AddRetention(&model.RetentionEntry{BlobID: "unknown"}), os.ErrNotExist)
+}
+
+func TestCompactAndSnapshot(t *testing.T) {
+	ast := assert.New(t)
+	srv := initTest(t)
+	defer srv.Close()
+
+	big := make([]byte, 100*1024)
+	rand.Read(big)
+	keep := store(ast, srv, big)
+	for x := 0; x < 20; x++ {
+		ast.Nil(srv.DeleteBlob(store(ast, srv, big)))
+	}
+	// orphaned chunks of an interrupted upload and of an old generation of the kept blob
+	ast.Nil(srv.db.update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bktChunks).Put(chunkKey(keep, 0, 0), big[:1024]); err != nil {
+			return err
+		}
+		return tx.Bucket(bktChunks).Put(chunkKey("orphan", 1, 0), big[:1024])
+	}))
+	dbfile := filepath.Join(rootpath, tenant, DBFilename)
+	before, err := os.Stat(dbfile)
+	ast.Nil(err)
+
+	ast.Nil(srv.Compact())
+	after, err := os.Stat(dbfile)
+	ast.Nil(err)
+	ast.Less(after.Size(), before.Size())
+	ast.Equal(100, chunkCount(ast, srv))
+
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(keep, &buf))
+	ast.Equal(big, buf.Bytes())
+
+	// the snapshot is a complete database
+	filename, err := srv.SnapshotFile()
+	ast.Nil(err)
+	snap := &BlobStorage{RootPath: filepath.Join(rootpath, "restore"), Tenant: tenant}
+	ast.Nil(os.MkdirAll(filepath.Join(rootpath, "restore", tenant), os.ModePerm))
+	ast.Nil(os.Rename(filename, filepath.Join(rootpath, "restore", tenant, DBFilename)))
+	ast.Nil(snap.Init())
+	defer snap.Close()
+	buf.Reset()
+	ast.Nil(snap.RetrieveBlob(keep, &buf))
+	ast.Equal(big, buf.Bytes())
+}
+
+func TestOverwrite(t *testing.T) {
+	ast := assert.New(t)
+	srv := initTest(t)
+	defer srv.Close()
+
+	big := make([]byte, 10*1024+17)
+	rand.Read(big)
+	for _, data := range [][]byte{[]byte(payload), big} {
+		id := store(ast, srv, data)
+
+		// a failing overwrite must not destroy the old version
+		b := model.BlobDescription{BlobID: id, ContentLength: 4000}
+		_, err := srv.StoreBlob(&b, bytes.NewReader(make([]byte, 5000)))
+		ast.NotNil(err)
+		var buf bytes.Buffer
+		ast.Nil(srv.RetrieveBlob(id, &buf))
+		ast.Equal(data, buf.Bytes())
+
+		// a successful overwrite replaces the content and removes the old chunks
+		other := make([]byte, 3*1024)
+		rand.Read(other)
+		b = model.BlobDescription{BlobID: id, ContentLength: int64(len(other))}
+		_, err = srv.StoreBlob(&b, bytes.NewReader(other))
+		ast.Nil(err)
+		buf.Reset()
+		ast.Nil(srv.RetrieveBlob(id, &buf))
+		ast.Equal(other, buf.Bytes())
+		ast.Equal(3, chunkCount(ast, srv))
+
+		ast.Nil(srv.DeleteBlob(id))
+		ast.Equal(0, chunkCount(ast, srv))
+	}
+}
+
+func TestIDsWithSeparator(t *testing.T) {
+	ast := assert.New(t)
+	srv := initTest(t)
+	defer srv.Close()
+
+	big := make([]byte, 3*1024)
+	rand.Read(big)
+	for _, id := range []string{"a", "a/b"} {
+		b := model.BlobDescription{BlobID: id, ContentLength: int64(len(big))}
+		_, err := srv.StoreBlob(&b, bytes.NewReader(big))
+		ast.Nil(err)
+	}
+	ast.Nil(srv.DeleteBlob("a"))
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob("a/b", &buf))
+	ast.Equal(big, buf.Bytes())
+}
+
+func TestIncompleteBlob(t *testing.T) {
+	ast := assert.New(t)
+	srv := initTest(t)
+	defer srv.Close()
+
+	big := make([]byte, 3*1024)
+	rand.Read(big)
+	id := store(ast, srv, big)
+	ast.Nil(srv.db.update(func(tx *bolt.Tx) error {
+		gen, ok := getGen(tx, id)
+		ast.True(ok)
+		return tx.Bucket(bktChunks).Delete(chunkKey(id, gen, 2))
+	}))
+
+	var buf bytes.Buffer
+	ast.NotNil(srv.RetrieveBlob(id, &buf))
+}
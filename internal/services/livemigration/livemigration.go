@@ -0,0 +1,422 @@
+// Package livemigration contains a storage for moving a tenant from one storage to another without downtime
+package livemigration
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/willie68/GoBlobStore/internal/logging"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+var logger = logging.New().WithName("livemigration")
+
+// checking interface compatibility
+var _ interfaces.BlobStorage = &Storage{}
+
+// ErrNotInSync the catch-up before the switch couldn't copy all queued blobs
+var ErrNotInSync = errors.New("livemigration: target storage not in sync")
+
+// Storage this storage wraps the source and the target storage of a running migration.
+// All writes are done on the source and are copied to the target, reads are done on the target with a fallback to the source.
+// So the source stays complete until the tenant is switched to the target.
+// Blobs, which can't be written to the target, are queued and copied again by a catch-up before the switch.
+type Storage struct {
+	Source   interfaces.BlobStorage
+	Target   interfaces.BlobStorage
+	Tenant   string
+	locks    utils.IDLocks
+	sw       sync.RWMutex // writes are holding the read lock, the switch the write lock
+	switched bool         // after the switch all writes are done on the target only
+	pm       sync.Mutex
+	pending  map[string]bool // blobs, which are not in sync on the target
+}
+
+// Init initialize this service
+func (s *Storage) Init() error {
+	if s.Source == nil || s.Target == nil {
+		return errors.New("livemigration: source and target storage must be given")
+	}
+	return nil
+}
+
+// GetTenant return the id of the tenant
+func (s *Storage) GetTenant() string {
+	return s.Tenant
+}
+
+// GetBlobs walking thru all blobs of the source
+func (s *Storage) GetBlobs(callback func(id string) bool) error {
+	return s.Source.GetBlobs(callback)
+}
+
+// StoreBlob storing the blob to the source and copying it to the target. A blob, which can't be copied, is queued for the catch-up.
+func (s *Storage) StoreBlob(b *model.BlobDescription, r io.Reader) (string, error) {
+	unlock, switched := s.write()
+	defer unlock()
+	if switched {
+		return s.Target.StoreBlob(b, r)
+	}
+	id, err := s.Source.StoreBlob(b, r)
+	if err != nil {
+		return "", err
+	}
+	if _, _, err := s.CopyBlob(id); err != nil {
+		logger.Errorf("livemigration: tenant %s, can't copy blob %s to the target: %v", s.Tenant, id, err)
+		s.queue(id)
+	}
+	return id, nil
+}
+
+// HasBlob checking if the target or the source has the blob
+func (s *Storage) HasBlob(id string) (bool, error) {
+	ok, err := s.Target.HasBlob(id)
+	if err == nil && ok {
+		return true, nil
+	}
+	return s.Source.HasBlob(id)
+}
+
+// GetBlobDescription getting the description from the target with a fallback to the source
+func (s *Storage) GetBlobDescription(id string) (*model.BlobDescription, error) {
+	b, err := s.Target.GetBlobDescription(id)
+	if err == nil {
+		return b, nil
+	}
+	return s.Source.GetBlobDescription(id)
+}
+
+// UpdateBlobDescription updating the description on both storages
+func (s *Storage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
+	wunlock, switched := s.write()
+	defer wunlock()
+	if switched {
+		return s.Target.UpdateBlobDescription(id, b)
+	}
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	err := s.Source.UpdateBlobDescription(id, b)
+	if err != nil {
+		return err
+	}
+	s.onTarget(id, func() error {
+		return s.Target.UpdateBlobDescription(id, b)
+	})
+	return nil
+}
+
+// RetrieveBlob retrieving the blob from the target with a fallback to the source, as long as nothing is written
+func (s *Storage) RetrieveBlob(id string, w io.Writer) error {
+	ok, err := s.Target.HasBlob(id)
+	if err == nil && ok {
+		cw := &utils.CountWriter{W: w}
+		err = s.Target.RetrieveBlob(id, cw)
+		if err == nil || cw.N > 0 {
+			return err
+		}
+		logger.Errorf("livemigration: tenant %s, can't retrieve blob %s from the target: %v", s.Tenant, id, err)
+	}
+	return s.Source.RetrieveBlob(id, w)
+}
+
+// DeleteBlob removing the blob from both storages
+func (s *Storage) DeleteBlob(id string) error {
+	wunlock, switched := s.write()
+	defer wunlock()
+	if switched {
+		return s.Target.DeleteBlob(id)
+	}
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	err := s.Source.DeleteBlob(id)
+	if ok, terr := s.Target.HasBlob(id); terr == nil && ok {
+		if terr := s.Target.DeleteBlob(id); terr != nil {
+			logger.Errorf("livemigration: tenant %s, can't delete blob %s on the target: %v", s.Tenant, id, terr)
+			s.queue(id)
+		}
+	}
+	return err
+}
+
+// CheckBlob checking the blob on the target, if present, otherwise on the source
+func (s *Storage) CheckBlob(id string) (*model.CheckInfo, error) {
+	ok, err := s.Target.HasBlob(id)
+	if err == nil && ok {
+		return s.Target.CheckBlob(id)
+	}
+	return s.Source.CheckBlob(id)
+}
+
+// SearchBlobs searching on the source
+func (s *Storage) SearchBlobs(query string, callback func(id string) bool) error {
+	return s.Source.SearchBlobs(query, callback)
+}
+
+// GetAllRetentions for every retention entry of the source we call this this function, you can stop the listing by returning a false
+func (s *Storage) GetAllRetentions(callback func(r model.RetentionEntry) bool) error {
+	return s.Source.GetAllRetentions(callback)
+}
+
+// AddRetention adding the retention entry to both storages
+func (s *Storage) AddRetention(r *model.RetentionEntry) error {
+	wunlock, switched := s.write()
+	defer wunlock()
+	if switched {
+		return s.Target.AddRetention(r)
+	}
+	unlock := s.locks.Lock(r.BlobID)
+	defer unlock()
+	err := s.Source.AddRetention(r)
+	if err != nil {
+		return err
+	}
+	s.onTarget(r.BlobID, func() error {
+		return s.Target.AddRetention(r)
+	})
+	return nil
+}
+
+// GetRetention getting the retention entry from the target with a fallback to the source
+func (s *Storage) GetRetention(id string) (model.RetentionEntry, error) {
+	r, err := s.Target.GetRetention(id)
+	if err == nil {
+		return r, nil
+	}
+	return s.Source.GetRetention(id)
+}
+
+// DeleteRetention deletes the retention entry on both storages
+func (s *Storage) DeleteRetention(id string) error {
+	wunlock, switched := s.write()
+	defer wunlock()
+	if switched {
+		return s.Target.DeleteRetention(id)
+	}
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	err := s.Source.DeleteRetention(id)
+	if err != nil {
+		return err
+	}
+	s.onTarget(id, func() error {
+		if _, err := s.Target.GetRetention(id); err != nil {
+			return nil
+		}
+		return s.Target.DeleteRetention(id)
+	})
+	return nil
+}
+
+// ResetRetention resets the retention on both storages
+func (s *Storage) ResetRetention(id string) error {
+	wunlock, switched := s.write()
+	defer wunlock()
+	if switched {
+		return s.Target.ResetRetention(id)
+	}
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	err := s.Source.ResetRetention(id)
+	if err != nil {
+		return err
+	}
+	s.onTarget(id, func() error {
+		r, err := s.Source.GetRetention(id)
+		if err != nil {
+			return err
+		}
+		return s.Target.AddRetention(&r)
+	})
+	return nil
+}
+
+// GetLastError returning the last error of the source or the target
+func (s *Storage) GetLastError() error {
+	if err := s.Source.GetLastError(); err != nil {
+		return err
+	}
+	return s.Target.GetLastError()
+}
+
+// Close closing both storages
+func (s *Storage) Close() error {
+	err := s.Source.Close()
+	if terr := s.Target.Close(); terr != nil {
+		logger.Errorf("livemigration: error closing target storage: %v", terr)
+	}
+	return err
+}
+
+// CopyBlob copying the blob with description and retention from the source to the target and verifying the hash.
+// A blob, which is already present with the same hash in the target, is skipped. Returning the copied bytes and if the blob was skipped.
+func (s *Storage) CopyBlob(id string) (int64, bool, error) {
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	b, err := s.Source.GetBlobDescription(id)
+	if err != nil {
+		return 0, false, err
+	}
+	if tb, err := s.Target.GetBlobDescription(id); err == nil && sameBlob(b, tb) {
+		return 0, true, s.copyRetention(id)
+	}
+
+	hash := b.Hash
+	tb := *b
+	rd, wr := io.Pipe()
+	go func() {
+		// close the writer, so the reader knows there's no more data
+		var err error
+		defer func() {
+			wr.CloseWithError(err)
+		}()
+		err = s.Source.RetrieveBlob(id, wr)
+	}()
+	_, err = s.Target.StoreBlob(&tb, rd)
+	_ = rd.Close()
+	if err != nil {
+		s.removeFromTarget(id)
+		return 0, false, err
+	}
+	if hash != "" && !strings.EqualFold(hash, tb.Hash) {
+		s.removeFromTarget(id)
+		return 0, false, fmt.Errorf("hashes are not equal: %s != %s", hash, tb.Hash)
+	}
+	// the description of the source is the master, the target may have changed some values on storing
+	if err := s.Target.UpdateBlobDescription(id, b); err != nil {
+		s.removeFromTarget(id)
+		return 0, false, err
+	}
+	if err := s.copyRetention(id); err != nil {
+		s.removeFromTarget(id)
+		return 0, false, err
+	}
+	return tb.ContentLength, false, nil
+}
+
+// copyRetention copying the retention entry of the blob, if the target has none or a different one
+func (s *Storage) copyRetention(id string) error {
+	r, err := s.Source.GetRetention(id)
+	if err != nil {
+		// blob without retention
+		return nil
+	}
+	if tr, err := s.Target.GetRetention(id); err == nil && tr == r {
+		return nil
+	}
+	return s.Target.AddRetention(&r)
+}
+
+// onTarget doing the operation on the target, if the target has the blob. If the operation fails, the blob is removed from the target,
+// so the copier will copy it again.
+func (s *Storage) onTarget(id string, op func() error) {
+	ok, err := s.Target.HasBlob(id)
+	if err != nil || !ok {
+		return
+	}
+	if err := op(); err != nil {
+		logger.Errorf("livemigration: tenant %s, error on target for blob %s, removing it for a new copy: %v", s.Tenant, id, err)
+		s.removeFromTarget(id)
+		s.queue(id)
+	}
+}
+
+func (s *Storage) removeFromTarget(id string) {
+	if err := s.Target.DeleteBlob(id); err != nil {
+		logger.Debugf("livemigration: tenant %s, can't remove blob %s from the target: %v", s.Tenant, id, err)
+	}
+}
+
+// sameBlob checking if the target blob has the same content as the source blob
+func sameBlob(src, dst *model.BlobDescription) bool {
+	if src.ContentLength != dst.ContentLength {
+		return false
+	}
+	return src.Hash == "" || strings.EqualFold(src.Hash, dst.Hash)
+}
+
+// write taking the read lock of the switch for a write, returning the unlock function and if the tenant is already switched to the target
+func (s *Storage) write() (func(), bool) {
+	s.sw.RLock()
+	return s.sw.RUnlock, s.switched
+}
+
+// queue remembering the blob for the catch-up
+func (s *Storage) queue(id string) {
+	s.pm.Lock()
+	defer s.pm.Unlock()
+	if s.pending == nil {
+		s.pending = make(map[string]bool)
+	}
+	s.pending[id] = true
+}
+
+// Pending returning the count of blobs, which are waiting for the catch-up
+func (s *Storage) Pending() int {
+	s.pm.Lock()
+	defer s.pm.Unlock()
+	return len(s.pending)
+}
+
+// CatchUp copying the queued blobs again, blobs deleted on the source are removed from the target.
+// Blobs, which are still failing, are staying in the queue.
+func (s *Storage) CatchUp() error {
+	s.pm.Lock()
+	ids := make([]string, 0, len(s.pending))
+	for id := range s.pending {
+		ids = append(ids, id)
+	}
+	s.pm.Unlock()
+	var errs error
+	for _, id := range ids {
+		err := s.syncBlob(id)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("blob %s: %w", id, err))
+			continue
+		}
+		s.pm.Lock()
+		delete(s.pending, id)
+		s.pm.Unlock()
+	}
+	return errs
+}
+
+// syncBlob bringing the target in sync with the source for a single blob
+func (s *Storage) syncBlob(id string) error {
+	ok, err := s.Source.HasBlob(id)
+	if err != nil {
+		return err
+	}
+	if ok {
+		_, _, err = s.CopyBlob(id)
+		return err
+	}
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	ok, err = s.Target.HasBlob(id)
+	if err != nil || !ok {
+		return err
+	}
+	return s.Target.DeleteBlob(id)
+}
+
+// Switch blocking all writes, running the last catch-up and switching the tenant with the finish function.
+// After a successful switch, all writes are done on the target only.
+func (s *Storage) Switch(finish func() error) error {
+	s.sw.Lock()
+	defer s.sw.Unlock()
+	if err := s.CatchUp(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotInSync, err)
+	}
+	if finish != nil {
+		if err := finish(); err != nil {
+			return err
+		}
+	}
+	s.switched = true
+	return nil
+}
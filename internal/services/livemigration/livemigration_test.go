@@ -0,0 +1,258 @@
+package livemigration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/memory"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const (
+	rootpath = "../../../testdata/livemigration"
+	tenant   = "test"
+	payload  = "this is a blob content"
+)
+
+func initTest(t *testing.T) *Storage {
+	ast := assert.New(t)
+	ast.Nil(os.RemoveAll(rootpath))
+	memory.Clear(t.Name())
+	src := &simplefile.BlobStorage{
+		RootPath: rootpath,
+		Tenant:   tenant,
+	}
+	ast.Nil(src.Init())
+	tgt := &memory.BlobStorage{
+		Namespace: t.Name(),
+		Tenant:    tenant,
+	}
+	ast.Nil(tgt.Init())
+	return &Storage{
+		Source: src,
+		Target: tgt,
+		Tenant: tenant,
+	}
+}
+
+func newBlob() *model.BlobDescription {
+	return &model.BlobDescription{
+		ContentLength: int64(len(payload)),
+		ContentType:   "text/plain",
+		Filename:      "test.txt",
+		Retention:     180000,
+		Properties:    make(map[string]any),
+	}
+}
+
+func TestDualWrite(t *testing.T) {
+	ast := assert.New(t)
+	s := initTest(t)
+	ast.Nil(s.Init())
+
+	id, err := s.StoreBlob(newBlob(), strings.NewReader(payload))
+	ast.Nil(err)
+	for _, stg := range []interfaces.BlobStorage{s.Source, s.Target} {
+		ok, err := stg.HasBlob(id)
+		ast.Nil(err)
+		ast.True(ok)
+	}
+
+	ast.Nil(s.AddRetention(&model.RetentionEntry{BlobID: id, TenantID: tenant, Retention: 1000}))
+	r, err := s.Target.GetRetention(id)
+	ast.Nil(err)
+	ast.Equal(int64(1000), r.Retention)
+
+	b, err := s.GetBlobDescription(id)
+	ast.Nil(err)
+	b.Filename = "changed.txt"
+	ast.Nil(s.UpdateBlobDescription(id, b))
+	tb, err := s.Target.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal("changed.txt", tb.Filename)
+
+	ast.Nil(s.DeleteBlob(id))
+	ok, err := s.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+	ok, _ = s.Target.HasBlob(id)
+	ast.False(ok)
+}
+
+func TestReadFallback(t *testing.T) {
+	ast := assert.New(t)
+	s := initTest(t)
+
+	// a blob stored before the migration is only in the source
+	id, err := s.Source.StoreBlob(newBlob(), strings.NewReader(payload))
+	ast.Nil(err)
+	ok, err := s.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+	var buf bytes.Buffer
+	ast.Nil(s.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.String())
+	b, err := s.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(id, b.BlobID)
+	ci, err := s.CheckBlob(id)
+	ast.Nil(err)
+	ast.True(ci.Healthy)
+}
+
+func TestCopy(t *testing.T) {
+	ast := assert.New(t)
+	s := initTest(t)
+
+	ids := make([]string, 0)
+	for x := 0; x < 10; x++ {
+		id, err := s.Source.StoreBlob(newBlob(), strings.NewReader(payload))
+		ast.Nil(err)
+		ast.Nil(s.Source.AddRetention(&model.RetentionEntry{BlobID: id, TenantID: tenant, Retention: 1000}))
+		ids = append(ids, id)
+	}
+	// one blob is already present in the target
+	_, skipped, err := s.CopyBlob(ids[0])
+	ast.Nil(err)
+	ast.False(skipped)
+
+	finished := false
+	m := &Migration{
+		Storage: s,
+		Source:  "simplefile",
+		Target:  "memory",
+		OnFinish: func() error {
+			finished = true
+			return nil
+		},
+	}
+	st := m.Run(context.Background())
+	ast.True(finished)
+	ast.Equal(model.MigrationStateFinished, st.State)
+	ast.False(st.Running)
+	ast.Equal(2, st.Pass)
+	ast.Equal(9, st.Copied)
+	ast.Equal(int64(9*len(payload)), st.Bytes)
+	ast.Equal(10, st.Skipped)
+	ast.Equal(0, st.Failed)
+
+	for _, id := range ids {
+		var buf bytes.Buffer
+		ast.Nil(s.Target.RetrieveBlob(id, &buf))
+		ast.Equal(payload, buf.String())
+		r, err := s.Target.GetRetention(id)
+		ast.Nil(err)
+		ast.Equal(int64(1000), r.Retention)
+	}
+}
+
+func TestCopyWrongHash(t *testing.T) {
+	ast := assert.New(t)
+	s := initTest(t)
+
+	id, err := s.Source.StoreBlob(newBlob(), strings.NewReader(payload))
+	ast.Nil(err)
+	b, err := s.Source.GetBlobDescription(id)
+	ast.Nil(err)
+	b.Hash = "sha-256:0815"
+	ast.Nil(s.Source.UpdateBlobDescription(id, b))
+
+	_, _, err = s.CopyBlob(id)
+	ast.NotNil(err)
+	ok, _ := s.Target.HasBlob(id)
+	ast.False(ok)
+
+	m := &Migration{Storage: s, Passes: 2}
+	st := m.Run(context.Background())
+	ast.Equal(model.MigrationStateFailed, st.State)
+	ast.Equal(1, st.Failed)
+	ast.NotEmpty(st.LastError)
+}
+
+func TestCancel(t *testing.T) {
+	ast := assert.New(t)
+	s := initTest(t)
+	_, err := s.Source.StoreBlob(newBlob(), strings.NewReader(payload))
+	ast.Nil(err)
+
+	m := &Migration{Storage: s}
+	ast.False(m.Cancel())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	st := m.Run(ctx)
+	ast.Equal(model.MigrationStateCancelled, st.State)
+	ast.False(st.Running)
+}
+
+// failingStorage a target, which is failing writes, as long as fail is set
+type failingStorage struct {
+	interfaces.BlobStorage
+	fail bool
+}
+
+func (f *failingStorage) StoreBlob(b *model.BlobDescription, r io.Reader) (string, error) {
+	if f.fail {
+		return "", errors.New("store failed")
+	}
+	return f.BlobStorage.StoreBlob(b, r)
+}
+
+func (f *failingStorage) DeleteBlob(id string) error {
+	if f.fail {
+		return errors.New("delete failed")
+	}
+	return f.BlobStorage.DeleteBlob(id)
+}
+
+func TestCatchUp(t *testing.T) {
+	ast := assert.New(t)
+	s := initTest(t)
+	tgt := &failingStorage{BlobStorage: s.Target}
+	s.Target = tgt
+
+	deleted, err := s.StoreBlob(newBlob(), strings.NewReader(payload))
+	ast.Nil(err)
+
+	// failing writes on the target are queued
+	tgt.fail = true
+	id, err := s.StoreBlob(newBlob(), strings.NewReader(payload))
+	ast.Nil(err)
+	ast.Nil(s.DeleteBlob(deleted))
+	ast.Equal(2, s.Pending())
+	ast.NotNil(s.CatchUp())
+	ast.Equal(2, s.Pending())
+
+	// the switch is only done with a target in sync
+	finished := false
+	finish := func() error {
+		finished = true
+		return nil
+	}
+	ast.ErrorIs(s.Switch(finish), ErrNotInSync)
+	ast.False(finished)
+
+	tgt.fail = false
+	ast.Nil(s.Switch(finish))
+	ast.True(finished)
+	ast.Equal(0, s.Pending())
+	ok, _ := tgt.HasBlob(id)
+	ast.True(ok)
+	ok, _ = tgt.HasBlob(deleted)
+	ast.False(ok)
+
+	// after the switch the writes are done on the target only
+	nid, err := s.StoreBlob(newBlob(), strings.NewReader(payload))
+	ast.Nil(err)
+	ok, _ = tgt.HasBlob(nid)
+	ast.True(ok)
+	ok, _ = s.Source.HasBlob(nid)
+	ast.False(ok)
+}
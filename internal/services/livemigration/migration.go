@@ -0,0 +1,203 @@
+package livemigration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// DefaultPasses default count of passes over all blobs, before a migration fails
+const DefaultPasses = 3
+
+// Migration the background copier of a live migration. Every pass copies all blobs of the source, which are not already present in the target.
+// A pass without any copied or failed blob verifies, that the target is complete, and the tenant is switched with the finish function.
+type Migration struct {
+	Storage  *Storage
+	Source   string       // storage class of the source, only for the status
+	Target   string       // storage class of the target, only for the status
+	Passes   int          // maximum count of passes
+	OnFinish func() error // switching the tenant to the target storage
+	status   model.StorageMigration
+	cancel   context.CancelFunc
+	done     chan struct{}
+	m        sync.Mutex
+}
+
+// Start starting the migration in the background
+func (m *Migration) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.m.Lock()
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	m.m.Unlock()
+	m.begin()
+	go func() {
+		defer close(m.done)
+		defer cancel()
+		m.execute(ctx)
+	}()
+}
+
+// Cancel cancelling the running migration, the blob in work is finished
+func (m *Migration) Cancel() bool {
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.cancel == nil || !m.status.Running {
+		return false
+	}
+	m.cancel()
+	return true
+}
+
+// Wait waiting for the end of the background migration
+func (m *Migration) Wait() {
+	m.m.Lock()
+	done := m.done
+	m.m.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Status getting the status of the migration
+func (m *Migration) Status() model.StorageMigration {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.status
+}
+
+// Run running the migration synchronously, the context can be used for cancelling
+func (m *Migration) Run(ctx context.Context) model.StorageMigration {
+	m.begin()
+	return m.execute(ctx)
+}
+
+func (m *Migration) begin() {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.status = model.StorageMigration{
+		ID:      utils.GenerateID(),
+		Tenant:  m.Storage.Tenant,
+		Source:  m.Source,
+		Target:  m.Target,
+		State:   model.MigrationStateCopying,
+		Running: true,
+		Started: time.Now(),
+	}
+}
+
+func (m *Migration) execute(ctx context.Context) model.StorageMigration {
+	state, err := m.run(ctx)
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.status.State = state
+	m.status.Running = false
+	m.status.Finished = time.Now()
+	if err != nil {
+		m.status.LastError = err.Error()
+	}
+	logger.Infof("livemigration: tenant %s, migration %s %s", m.status.Tenant, m.status.ID, state)
+	return m.status
+}
+
+func (m *Migration) run(ctx context.Context) (string, error) {
+	passes := m.Passes
+	if passes <= 0 {
+		passes = DefaultPasses
+	}
+	for pass := 1; pass <= passes; pass++ {
+		clean, err := m.pass(ctx, pass)
+		if ctx.Err() != nil {
+			return model.MigrationStateCancelled, nil
+		}
+		if err != nil {
+			return model.MigrationStateFailed, err
+		}
+		if !clean {
+			continue
+		}
+		m.update(func(s *model.StorageMigration) {
+			s.State = model.MigrationStateSwitching
+		})
+		// writes in the meantime are copied by the catch-up of the switch
+		if err := m.Storage.Switch(m.OnFinish); err != nil {
+			if ctx.Err() != nil {
+				return model.MigrationStateCancelled, nil
+			}
+			if errors.Is(err, ErrNotInSync) && pass < passes {
+				logger.Errorf("livemigration: tenant %s, %v", m.Storage.Tenant, err)
+				m.update(func(s *model.StorageMigration) {
+					s.State = model.MigrationStateCopying
+				})
+				continue
+			}
+			return model.MigrationStateFailed, err
+		}
+		return model.MigrationStateFinished, nil
+	}
+	return model.MigrationStateFailed, errors.New("target storage not complete after the last pass")
+}
+
+// pass copying all blobs of the source, returning true if all blobs are already present in the target
+func (m *Migration) pass(ctx context.Context, pass int) (bool, error) {
+	// the ids are collected first, so the source isn't blocked while copying
+	ids := make([]string, 0)
+	err := m.Storage.Source.GetBlobs(func(id string) bool {
+		ids = append(ids, id)
+		return true
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	m.update(func(s *model.StorageMigration) {
+		s.Pass = pass
+		s.Total = len(ids)
+		s.Skipped = 0
+		s.Failed = 0
+	})
+	logger.Infof("livemigration: tenant %s, pass %d with %d blobs", m.Storage.Tenant, pass, len(ids))
+	clean := true
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return false, nil
+		}
+		size, skipped, err := m.Storage.CopyBlob(id)
+		if err != nil {
+			// a blob deleted in the meantime needs no copy
+			if ok, herr := m.Storage.Source.HasBlob(id); herr == nil && !ok {
+				continue
+			}
+			logger.Errorf("livemigration: tenant %s, can't copy blob %s: %v", m.Storage.Tenant, id, err)
+		}
+		clean = clean && skipped
+		m.update(func(s *model.StorageMigration) {
+			switch {
+			case err != nil:
+				s.Failed++
+				s.LastError = err.Error()
+			case skipped:
+				s.Skipped++
+			default:
+				s.Copied++
+				s.Bytes += size
+			}
+		})
+	}
+	// the queued blobs are copied here too, so the catch-up of the switch has less to do
+	if err := m.Storage.CatchUp(); err != nil {
+		logger.Errorf("livemigration: tenant %s, catch-up: %v", m.Storage.Tenant, err)
+		clean = false
+	}
+	return clean, nil
+}
+
+func (m *Migration) update(f func(s *model.StorageMigration)) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	f(&m.status)
+}
@@ -0,0 +1,147 @@
+// Package memory contains a storage and a tenant manager, which are holding all data in memory.
+// Nothing is written to disk, so this is usable for tests and ephemeral tenants.
+package memory
+
+import (
+	"container/list"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/willie68/GoBlobStore/internal/logging"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+)
+
+// MemoryStorageName name of this storage class
+const MemoryStorageName = "memory"
+
+// DefaultNamespace namespace used, if no namespace is given
+const DefaultNamespace = "default"
+
+// ErrStorageFull the size or count limit of the tenant is reached
+var ErrStorageFull = errors.New("storage is full")
+
+var logger = logging.New().WithName("memory")
+
+// entry a single blob
+type entry struct {
+	desc []byte // json of the description, so every reader gets its own copy
+	data []byte
+	elem *list.Element // position in the lru list
+}
+
+// tenantData all data of a single tenant
+type tenantData struct {
+	blobs      map[string]*entry
+	retentions map[string][]byte
+	config     []byte
+	size       int64
+	lru        *list.List // most recently used blobs at the front
+}
+
+// namespace all tenants of one namespace, storages and tenant managers with the same namespace share the data
+type namespace struct {
+	m       sync.Mutex
+	tenants map[string]*tenantData
+}
+
+var (
+	namespaces = make(map[string]*namespace)
+	nsm        sync.Mutex
+)
+
+// getNamespace getting the namespace with the name, a new namespace will be created
+func getNamespace(name string) *namespace {
+	if name == "" {
+		name = DefaultNamespace
+	}
+	nsm.Lock()
+	defer nsm.Unlock()
+	ns, ok := namespaces[name]
+	if !ok {
+		ns = &namespace{
+			tenants: make(map[string]*tenantData),
+		}
+		namespaces[name] = ns
+	}
+	return ns
+}
+
+// Clear removing all data of the namespace
+func Clear(name string) {
+	ns := getNamespace(name)
+	ns.m.Lock()
+	defer ns.m.Unlock()
+	ns.tenants = make(map[string]*tenantData)
+}
+
+// tenant getting the data of the tenant, the caller must hold the lock
+func (n *namespace) tenant(name string, create bool) *tenantData {
+	td, ok := n.tenants[name]
+	if !ok && create {
+		td = &tenantData{
+			blobs:      make(map[string]*entry),
+			retentions: make(map[string][]byte),
+			lru:        list.New(),
+		}
+		n.tenants[name] = td
+	}
+	return td
+}
+
+// names getting the sorted tenant names
+func (n *namespace) names() []string {
+	n.m.Lock()
+	defer n.m.Unlock()
+	names := make([]string, 0, len(n.tenants))
+	for name := range n.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *tenantData) touch(e *entry) {
+	t.lru.MoveToFront(e.elem)
+}
+
+func (t *tenantData) remove(id string) bool {
+	e, ok := t.blobs[id]
+	if !ok {
+		return false
+	}
+	t.lru.Remove(e.elem)
+	t.size -= int64(len(e.data))
+	delete(t.blobs, id)
+	delete(t.retentions, id)
+	return true
+}
+
+// evict removing the least recently used blob, blobs with a retention and the blob with the skip id are never evicted
+func (t *tenantData) evict(skip string) bool {
+	for el := t.lru.Back(); el != nil; el = el.Prev() {
+		id, _ := el.Value.(string)
+		if id == skip || t.retentions[id] != nil {
+			continue
+		}
+		logger.Debugf("evicting blob %s", id)
+		return t.remove(id)
+	}
+	return false
+}
+
+// sortedKeys getting the sorted keys of the map
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checking interface compatibility
+var (
+	_ interfaces.BlobStorage   = &BlobStorage{}
+	_ interfaces.TenantManager = &TenantManager{}
+)
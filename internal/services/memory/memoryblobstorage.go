@@ -0,0 +1,302 @@
+package memory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// BlobStorage service for storing blobs in memory
+type BlobStorage struct {
+	Namespace string // storages and tenant managers with the same namespace share the data, default is "default"
+	Tenant    string // this is the tenant, on which this service will work
+	MaxSize   int64  // max size of all binaries of the tenant in bytes, 0 means unlimited
+	MaxCount  int    // max count of blobs of the tenant, 0 means unlimited
+	LRU       bool   // evicting the least recently used blobs, if a limit is reached, otherwise storing fails with ErrStorageFull
+	ns        *namespace
+}
+
+// Init initialize this service
+func (s *BlobStorage) Init() error {
+	if s.Tenant == "" {
+		return errors.New("tenant should not be null or empty")
+	}
+	s.ns = getNamespace(s.Namespace)
+	return nil
+}
+
+// GetTenant return the id of the tenant
+func (s *BlobStorage) GetTenant() string {
+	return s.Tenant
+}
+
+// GetBlobs getting a list of blob from the memory
+func (s *BlobStorage) GetBlobs(callback func(id string) bool) error {
+	s.ns.m.Lock()
+	ids := make([]string, 0)
+	if td := s.ns.tenant(s.Tenant, false); td != nil {
+		ids = sortedKeys(td.blobs)
+	}
+	s.ns.m.Unlock()
+	for _, id := range ids {
+		if !callback(id) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// StoreBlob storing a blob to the memory
+func (s *BlobStorage) StoreBlob(b *model.BlobDescription, r io.Reader) (string, error) {
+	if b.BlobID == "" {
+		b.BlobID = utils.GenerateID()
+	}
+	h := sha256.New()
+	data, err := io.ReadAll(io.TeeReader(r, h))
+	if err != nil {
+		return "", err
+	}
+	size := int64(len(data))
+	if (b.ContentLength > 0) && b.ContentLength != size {
+		return "", fmt.Errorf("wrong content length %d=%d", b.ContentLength, size)
+	}
+	b.Hash = fmt.Sprintf("sha-256:%x", h.Sum(nil))
+	b.ContentLength = size
+	js, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	if s.MaxSize > 0 && size > s.MaxSize {
+		return "", fmt.Errorf("%w: blob size %d exceeds the max size %d", ErrStorageFull, size, s.MaxSize)
+	}
+
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	td := s.ns.tenant(s.Tenant, true)
+	// an old version of the blob is only removed, if the new one fits
+	for s.full(td, b.BlobID, size) {
+		if !s.LRU || !td.evict(b.BlobID) {
+			return "", fmt.Errorf("%w: tenant %s", ErrStorageFull, s.Tenant)
+		}
+	}
+	td.remove(b.BlobID)
+	e := &entry{
+		desc: js,
+		data: data,
+	}
+	e.elem = td.lru.PushFront(b.BlobID)
+	td.blobs[b.BlobID] = e
+	td.size += size
+	return b.BlobID, nil
+}
+
+// full checking, if a new blob with the size will exceed the limits, an old version of the blob is replaced and not counted
+func (s *BlobStorage) full(td *tenantData, id string, size int64) bool {
+	count := len(td.blobs)
+	used := td.size
+	if e, ok := td.blobs[id]; ok {
+		count--
+		used -= int64(len(e.data))
+	}
+	if s.MaxCount > 0 && count >= s.MaxCount {
+		return true
+	}
+	return s.MaxSize > 0 && used+size > s.MaxSize
+}
+
+// UpdateBlobDescription updating the blob description
+func (s *BlobStorage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
+	js, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	e, ok := s.entry(id)
+	if !ok {
+		return os.ErrNotExist
+	}
+	e.desc = js
+	return nil
+}
+
+// entry getting the entry of the blob, the caller must hold the lock
+func (s *BlobStorage) entry(id string) (*entry, bool) {
+	td := s.ns.tenant(s.Tenant, false)
+	if td == nil {
+		return nil, false
+	}
+	e, ok := td.blobs[id]
+	if ok {
+		td.touch(e)
+	}
+	return e, ok
+}
+
+// HasBlob checking, if a blob is present
+func (s *BlobStorage) HasBlob(id string) (bool, error) {
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	td := s.ns.tenant(s.Tenant, false)
+	if td == nil {
+		return false, nil
+	}
+	_, ok := td.blobs[id]
+	return ok, nil
+}
+
+// GetBlobDescription getting the description of the file
+func (s *BlobStorage) GetBlobDescription(id string) (*model.BlobDescription, error) {
+	s.ns.m.Lock()
+	e, ok := s.entry(id)
+	var js []byte
+	if ok {
+		js = e.desc
+	}
+	s.ns.m.Unlock()
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	var b model.BlobDescription
+	err := json.Unmarshal(js, &b)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// RetrieveBlob retrieving the binary data from the memory
+func (s *BlobStorage) RetrieveBlob(id string, w io.Writer) error {
+	s.ns.m.Lock()
+	e, ok := s.entry(id)
+	var data []byte
+	if ok {
+		// the data of an entry will never be changed, so it can be written without the lock
+		data = e.data
+	}
+	s.ns.m.Unlock()
+	if !ok {
+		return os.ErrNotExist
+	}
+	_, err := io.Copy(w, bytes.NewReader(data))
+	return err
+}
+
+// DeleteBlob removing a blob from the memory
+func (s *BlobStorage) DeleteBlob(id string) error {
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	td := s.ns.tenant(s.Tenant, false)
+	if td == nil || !td.remove(id) {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+// CheckBlob checking a single blob from the storage system
+func (s *BlobStorage) CheckBlob(id string) (*model.CheckInfo, error) {
+	return utils.CheckBlob(id, s)
+}
+
+// SearchBlobs querying a single blob, niy
+func (s *BlobStorage) SearchBlobs(_ string, _ func(id string) bool) error {
+	return errors.New("not implemented yet")
+}
+
+// GetAllRetentions for every retention entry for this tenant we call this this function, you can stop the listing by returnong a false
+func (s *BlobStorage) GetAllRetentions(callback func(r model.RetentionEntry) bool) error {
+	s.ns.m.Lock()
+	rets := make([][]byte, 0)
+	if td := s.ns.tenant(s.Tenant, false); td != nil {
+		for _, id := range sortedKeys(td.retentions) {
+			rets = append(rets, td.retentions[id])
+		}
+	}
+	s.ns.m.Unlock()
+	for _, js := range rets {
+		var r model.RetentionEntry
+		if err := json.Unmarshal(js, &r); err != nil {
+			logger.Errorf("GetAllRetention: error deserialising: %v", err)
+			continue
+		}
+		if !callback(r) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// GetRetention getting a single retention entry
+func (s *BlobStorage) GetRetention(id string) (model.RetentionEntry, error) {
+	s.ns.m.Lock()
+	var js []byte
+	if td := s.ns.tenant(s.Tenant, false); td != nil {
+		js = td.retentions[id]
+	}
+	s.ns.m.Unlock()
+	if js == nil {
+		return model.RetentionEntry{}, fmt.Errorf("no retention found for id %s: %w", id, os.ErrNotExist)
+	}
+	var r model.RetentionEntry
+	err := json.Unmarshal(js, &r)
+	if err != nil {
+		return model.RetentionEntry{}, err
+	}
+	return r, nil
+}
+
+// AddRetention adding a retention entry to the storage
+func (s *BlobStorage) AddRetention(r *model.RetentionEntry) error {
+	js, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	td := s.ns.tenant(s.Tenant, false)
+	if td == nil || td.blobs[r.BlobID] == nil {
+		return os.ErrNotExist
+	}
+	td.retentions[r.BlobID] = js
+	return nil
+}
+
+// DeleteRetention deletes the retention entry from the storage
+func (s *BlobStorage) DeleteRetention(id string) error {
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	td := s.ns.tenant(s.Tenant, false)
+	if td == nil || td.retentions[id] == nil {
+		return os.ErrNotExist
+	}
+	delete(td.retentions, id)
+	return nil
+}
+
+// ResetRetention resets the retention for a blob
+func (s *BlobStorage) ResetRetention(id string) error {
+	r, err := s.GetRetention(id)
+	if err != nil {
+		return err
+	}
+	r.RetentionBase = time.Now().UnixMilli()
+	return s.AddRetention(&r)
+}
+
+// GetLastError returning the last error (niy)
+func (s *BlobStorage) GetLastError() error {
+	return nil
+}
+
+// Close closing the storage, the data will stay in the namespace until the tenant is removed
+func (s *BlobStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/blobstoragetest"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+func getMemStorageSrv(t *testing.T) *BlobStorage {
+	srv := &BlobStorage{
+		Namespace: t.Name(),
+		Tenant:    tenant,
+	}
+	err := srv.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func getTestBlob() model.BlobDescription {
+	return blobstoragetest.TestBlob()
+}
+
+func TestTenanthandling(t *testing.T) {
+	srv := BlobStorage{}
+	err := srv.Init()
+	assert.NotNil(t, err)
+}
+
+func TestStorage(t *testing.T) {
+	blobstoragetest.Run(t, func(t *testing.T, withData bool) interfaces.BlobStorage {
+		if withData {
+			initTest(t)
+		}
+		return getMemStorageSrv(t)
+	})
+}
+
+func TestNotFound(t *testing.T) {
+	srv := getMemStorageSrv(t)
+	ast := assert.New(t)
+
+	ast.NotNil(srv.DeleteBlob("wrongid"))
+	ast.NotNil(srv.UpdateBlobDescription("wrongid", &model.BlobDescription{}))
+	ast.NotNil(srv.AddRetention(&model.RetentionEntry{BlobID: "wrongid"}))
+	ast.NotNil(srv.DeleteRetention("wrongid"))
+	ast.NotNil(srv.ResetRetention("wrongid"))
+}
+
+func TestCopies(t *testing.T) {
+	ast := assert.New(t)
+	srv := getMemStorageSrv(t)
+
+	b := getTestBlob()
+	id, err := srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+
+	// changing the read description doesn't change the stored one
+	info, err := srv.GetBlobDescription(id)
+	ast.Nil(err)
+	info.Properties["X-tenant"] = "MCS_3"
+	info, err = srv.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal("MCS", info.Properties["X-tenant"])
+
+	b = getTestBlob()
+	b.ContentLength = 10
+	_, err = srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.NotNil(err)
+
+	ast.Nil(srv.Close())
+}
+
+func TestDeleteRetention(t *testing.T) {
+	initTest(t)
+	ast := assert.New(t)
+	srv := getMemStorageSrv(t)
+
+	// deleting a blob removes the retention too
+	r, err := srv.GetRetention("0000fc02-050a-418a-a701-efd814aa6b36")
+	ast.Nil(err)
+	ast.Nil(srv.DeleteBlob(r.BlobID))
+	_, err = srv.GetRetention(r.BlobID)
+	ast.NotNil(err)
+}
+
+func TestLimits(t *testing.T) {
+	ast := assert.New(t)
+	srv := getMemStorageSrv(t)
+	srv.MaxCount = 2
+	srv.MaxSize = 50
+
+	store := func() (string, error) {
+		b := getTestBlob()
+		return srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	}
+	_, err := store()
+	ast.Nil(err)
+	_, err = store()
+	ast.Nil(err)
+	_, err = store()
+	ast.ErrorIs(err, ErrStorageFull)
+
+	// the least recently used blob will be evicted
+	srv.LRU = true
+	Clear(t.Name())
+	first, err := store()
+	ast.Nil(err)
+	second, err := store()
+	ast.Nil(err)
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(first, &buf))
+	third, err := store()
+	ast.Nil(err)
+	for id, present := range map[string]bool{first: true, second: false, third: true} {
+		ok, err := srv.HasBlob(id)
+		ast.Nil(err)
+		ast.Equal(present, ok, id)
+	}
+
+	// the size limit
+	srv.MaxCount = 0
+	_, err = store()
+	ast.Nil(err)
+	count := 0
+	ast.Nil(srv.GetBlobs(func(_ string) bool {
+		count++
+		return true
+	}))
+	ast.Equal(2, count)
+
+	b := model.BlobDescription{}
+	_, err = srv.StoreBlob(&b, bytes.NewReader(make([]byte, 51)))
+	ast.ErrorIs(err, ErrStorageFull)
+}
+
+func TestOverwrite(t *testing.T) {
+	ast := assert.New(t)
+	srv := getMemStorageSrv(t)
+	srv.MaxCount = 1
+
+	b := getTestBlob()
+	id, err := srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+
+	// the replaced blob is freeing its space
+	b = getTestBlob()
+	b.BlobID = id
+	b.ContentLength = 0
+	_, err = srv.StoreBlob(&b, strings.NewReader("another content"))
+	ast.Nil(err)
+
+	// a failing overwrite keeps the old blob
+	srv.MaxSize = 20
+	b = getTestBlob()
+	b.BlobID = id
+	_, err = srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.ErrorIs(err, ErrStorageFull)
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal("another content", buf.String())
+}
+
+func TestEvictRetention(t *testing.T) {
+	ast := assert.New(t)
+	srv := getMemStorageSrv(t)
+	srv.MaxCount = 2
+	srv.LRU = true
+
+	store := func() string {
+		b := getTestBlob()
+		id, err := srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+		ast.Nil(err)
+		return id
+	}
+	retain := func(id string) {
+		ast.Nil(srv.AddRetention(&model.RetentionEntry{BlobID: id, TenantID: tenant, Retention: 1}))
+	}
+	first := store()
+	retain(first)
+	second := store()
+
+	// the blob with the retention is skipped
+	third := store()
+	for id, present := range map[string]bool{first: true, second: false, third: true} {
+		ok, err := srv.HasBlob(id)
+		ast.Nil(err)
+		ast.Equal(present, ok, id)
+	}
+
+	// only retained blobs left
+	retain(third)
+	b := getTestBlob()
+	_, err := srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.ErrorIs(err, ErrStorageFull)
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+)
+
+// TenantManager the tenant manager for the in memory storage
+type TenantManager struct {
+	Namespace string // storages and tenant managers with the same namespace share the data, default is "default"
+	ns        *namespace
+}
+
+// Init intialise this tenant manager
+func (s *TenantManager) Init() error {
+	s.ns = getNamespace(s.Namespace)
+	return nil
+}
+
+// GetTenants walk thru all tenants
+func (s *TenantManager) GetTenants(callback func(tenant string) bool) error {
+	for _, name := range s.ns.names() {
+		if !callback(name) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// AddTenant add a new tenant to the manager
+func (s *TenantManager) AddTenant(tenant string) error {
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	s.ns.tenant(tenant, true)
+	return nil
+}
+
+// RemoveTenant remove a tenant from the service, delete all related data
+func (s *TenantManager) RemoveTenant(tenant string) (string, error) {
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	if s.ns.tenant(tenant, false) == nil {
+		return "", errors.New("tenant not exists")
+	}
+	delete(s.ns.tenants, tenant)
+	return "", nil
+}
+
+// HasTenant checking is a tenant is created
+func (s *TenantManager) HasTenant(tenant string) bool {
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	return s.ns.tenant(tenant, false) != nil
+}
+
+// SetConfig setting a new config object for the tenant
+func (s *TenantManager) SetConfig(tenant string, config interfaces.TenantConfig) error {
+	js, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	s.ns.tenant(tenant, true).config = js
+	return nil
+}
+
+// GetConfig getting the config object for the tenant
+func (s *TenantManager) GetConfig(tenant string) (*interfaces.TenantConfig, error) {
+	s.ns.m.Lock()
+	var js []byte
+	if td := s.ns.tenant(tenant, false); td != nil {
+		js = td.config
+	}
+	s.ns.m.Unlock()
+	if js == nil {
+		return nil, nil
+	}
+	var cfn interfaces.TenantConfig
+	err := json.Unmarshal(js, &cfn)
+	if err != nil {
+		return nil, err
+	}
+	return &cfn, nil
+}
+
+// GetSize getting the overall storage size for a tenant
+func (s *TenantManager) GetSize(tenant string) int64 {
+	s.ns.m.Lock()
+	defer s.ns.m.Unlock()
+	td := s.ns.tenant(tenant, false)
+	if td == nil {
+		return -1
+	}
+	return td.size
+}
+
+// AddSize nothing to do, the size of the tenant is always exact
+func (s *TenantManager) AddSize(_ string, _ int64) {
+}
+
+// SubSize nothing to do, the size of the tenant is always exact
+func (s *TenantManager) SubSize(_ string, _ int64) {
+}
+
+// Close closing this service
+func (s *TenantManager) Close() error {
+	return nil
+}
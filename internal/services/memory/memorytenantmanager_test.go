@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+)
+
+func TestMemoryTenantManager(t *testing.T) {
+	ast := assert.New(t)
+
+	srv := TenantManager{
+		Namespace: t.Name(),
+	}
+	err := srv.Init()
+	ast.Nil(err)
+
+	tenants := make([]string, 0)
+	err = srv.GetTenants(func(t string) bool {
+		tenants = append(tenants, t)
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(0, len(tenants))
+
+	ast.False(srv.HasTenant(tenant))
+	ast.Equal(int64(-1), srv.GetSize(tenant))
+
+	err = srv.AddTenant(tenant)
+	ast.Nil(err)
+	err = srv.GetTenants(func(t string) bool {
+		tenants = append(tenants, t)
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(1, len(tenants))
+	ast.True(srv.HasTenant(tenant))
+	ast.Equal(int64(0), srv.GetSize(tenant))
+
+	_, err = srv.RemoveTenant(tenant)
+	ast.Nil(err)
+	ast.False(srv.HasTenant(tenant))
+	_, err = srv.RemoveTenant(tenant)
+	ast.NotNil(err)
+}
+
+func TestMemoryTenantManagerConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	srv := TenantManager{
+		Namespace: t.Name(),
+	}
+	err := srv.Init()
+	ast.Nil(err)
+
+	err = srv.AddTenant("MCS")
+	ast.Nil(err)
+
+	cfn, err := srv.GetConfig("MCS")
+	ast.Nil(err)
+	ast.Nil(cfn)
+
+	stg := config.Storage{
+		Storageclass: "S3",
+		Properties:   make(map[string]any),
+	}
+	stg.Properties["accessKey"] = "accessKey"
+	stg.Properties["secretKey"] = "secretKey"
+	cfn = &interfaces.TenantConfig{
+		Backup: stg,
+	}
+	err = srv.SetConfig("MCS", *cfn)
+	ast.Nil(err)
+
+	cfn2, err := srv.GetConfig("MCS")
+	ast.Nil(err)
+	ast.NotNil(cfn2)
+	ast.Equal(cfn.Backup.Storageclass, cfn2.Backup.Storageclass)
+	ast.Equal(cfn.Backup.Properties["accessKey"], cfn2.Backup.Properties["accessKey"])
+	ast.Equal(cfn.Backup.Properties["secretKey"], cfn2.Backup.Properties["secretKey"])
+}
+
+func TestSize(t *testing.T) {
+	ast := assert.New(t)
+
+	tntsrv := TenantManager{
+		Namespace: t.Name(),
+	}
+	ast.Nil(tntsrv.Init())
+	stgsrv := getMemStorageSrv(t)
+
+	// storing a blob creates the tenant
+	ast.False(tntsrv.HasTenant(tenant))
+	b := getTestBlob()
+	id, err := stgsrv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+	ast.True(tntsrv.HasTenant(tenant))
+	ast.Equal(int64(22), tntsrv.GetSize(tenant))
+
+	ast.Nil(stgsrv.DeleteBlob(id))
+	ast.Equal(int64(0), tntsrv.GetSize(tenant))
+
+	// removing the tenant removes all blobs
+	_, err = stgsrv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+	_, err = tntsrv.RemoveTenant(tenant)
+	ast.Nil(err)
+	ok, err := stgsrv.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+)
+
+const (
+	zipfile  = "../../../testdata/mig.zip"
+	rootpath = "../../../testdata/memory"
+	tenant   = "MCS"
+)
+
+// initTest loading the simplefile test data into the memory of the namespace of the test,
+// descriptions are taken as they are, so corrupt blobs stay corrupt
+func initTest(t *testing.T) {
+	ast := assert.New(t)
+	Clear(t.Name())
+	ast.Nil(os.RemoveAll(rootpath))
+	archive, err := zip.OpenReader(zipfile)
+	ast.Nil(err)
+	defer archive.Close()
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		filePath := filepath.Join(rootpath, f.Name)
+		ast.Nil(os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		r, err := f.Open()
+		ast.Nil(err)
+		data, err := io.ReadAll(r)
+		ast.Nil(err)
+		_ = r.Close()
+		ast.Nil(os.WriteFile(filePath, data, os.ModePerm))
+	}
+
+	src := simplefile.BlobStorage{
+		RootPath: rootpath,
+		Tenant:   tenant,
+	}
+	ast.Nil(src.Init())
+	ns := getNamespace(t.Name())
+	ns.m.Lock()
+	defer ns.m.Unlock()
+	td := ns.tenant(tenant, true)
+	ast.Nil(src.GetBlobs(func(id string) bool {
+		b, err := src.GetBlobDescription(id)
+		ast.Nil(err)
+		js, err := json.Marshal(b)
+		ast.Nil(err)
+		var buf bytes.Buffer
+		ast.Nil(src.RetrieveBlob(id, &buf))
+		e := &entry{
+			desc: js,
+			data: buf.Bytes(),
+		}
+		e.elem = td.lru.PushFront(id)
+		td.blobs[id] = e
+		td.size += int64(buf.Len())
+		if r, err := src.GetRetention(id); err == nil {
+			js, err = json.Marshal(r)
+			ast.Nil(err)
+			td.retentions[id] = js
+		}
+		return true
+	}))
+	ast.Nil(os.RemoveAll(rootpath))
+}
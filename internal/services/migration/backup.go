@@ -8,6 +8,7 @@ import (
 
 	"github.com/willie68/GoBlobStore/internal/services/business"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
 )
 
 // BackupCheck the struct for doing a backup
@@ -82,7 +83,7 @@ func backup(id string, stg interfaces.BlobStorage, bck interfaces.BlobStorage) e
 			// close the writer, so the reader knows there's no more data
 			defer wr.Close()
 
-			err := stg.RetrieveBlob(id, wr)
+			err := utils.RetrieveBlob(stg, id, wr, interfaces.CacheBypass)
 			if err != nil {
 				logger.Errorf("error getting blob: %s,%v", id, err)
 			}
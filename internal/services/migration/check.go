@@ -1,13 +1,12 @@
 package migration
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
 	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
 // CheckContext struct for the running check
@@ -19,104 +18,99 @@ type CheckContext struct {
 	Cache    interfaces.BlobStorage
 	Primary  interfaces.BlobStorage
 	Backup   interfaces.BlobStorage
+	Reports  *ReportStore // the store for the report, without a store only the summary is built
 	Running  bool
-	Filename string
-	BlobID   string
 	cancel   bool
 	Message  string
+	report   model.CheckReport
+	writer   *reportWriter
 }
 
 // checking interface compatibility
 var _ interfaces.Running = &CheckContext{}
 
-// CheckResultLine on entry for the result of the check, usually converted into one report output line
-type CheckResultLine struct {
-	ID            string
-	Filename      string
-	InCache       bool
-	InBackup      bool
-	PrimaryHashOK bool
-	BackupHashOK  bool
-	HasError      bool
-	Messages      []string
-}
-
 // Admin functions
 
 // CheckStorage checks the storage to find inconsistencies.
-// It will write a audit file with a line for every blob in the storage, including name, hash, and state
-func (c *CheckContext) CheckStorage() (string, error) {
+// It will write a report with a line for every blob in the storage, including name, hash, and state, and returns the summary of the report
+func (c *CheckContext) CheckStorage() (model.CheckReport, error) {
 	c.Running = true
 	defer func() {
 		c.Running = false
 	}()
 	c.cancel = false
-	file, err := os.CreateTemp("", "check.*.json")
-	if err != nil {
-		return "", err
+	c.report = model.CheckReport{
+		ID:      c.CheckID,
+		Tenant:  c.TenantID,
+		State:   model.CheckStateRunning,
+		Started: time.Now(),
+	}
+	c.writer = nil
+	if c.Reports != nil {
+		w, err := c.Reports.create(c.report)
+		if err != nil {
+			return c.report, err
+		}
+		c.writer = w
 	}
-	defer file.Close()
-	c.Filename = file.Name()
-	logger.Debugf("start checking tenant \"%s\", results in file: %s", c.TenantID, file.Name())
-	_, _ = file.WriteString(fmt.Sprintf("{ \"Tenant\" : \"%s\"", c.TenantID))
+	logger.Debugf("start checking tenant \"%s\", report: %s", c.TenantID, c.CheckID)
 
 	// checking all blobs in cache
 	if c.Cache != nil {
-		c.checkCache(file)
+		c.checkCache()
 	}
 	// checking all blobs in main storage
-	count := 0
 	logger.Debug("checking primary")
-	_, _ = file.WriteString(",\r\n\"Primary\": [\r\n")
-	err = c.Primary.GetBlobs(func(id string) bool {
-		if count > 0 {
-			_, _ = file.WriteString(",\r\n")
-		}
-		c.checkBlob(id, file)
-		count++
+	err := c.Primary.GetBlobs(func(id string) bool {
+		c.checkBlob(id)
+		c.report.Primary++
 		return true
 	})
-	_, _ = file.WriteString("]")
-	_, _ = file.WriteString(fmt.Sprintf(",\r\n\"PrimaryCount\": %d", count))
 	if err != nil {
 		logger.Errorf("check: error checking primary. %v", err)
 	}
 	// checking all blobs in backup storage
 	if c.Backup != nil {
-		c.checkBackup(file)
+		c.checkBackup()
+	}
+
+	c.report.Finished = time.Now()
+	c.report.State = model.CheckStateFinished
+	if err != nil {
+		c.report.State = model.CheckStateFailed
+		c.report.Message = err.Error()
 	}
-	_, _ = file.WriteString("\r\n}")
-	return file.Name(), err
+	if c.writer != nil {
+		if werr := c.writer.close(c.report); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return c.report, err
 }
 
-func (c *CheckContext) checkBackup(file *os.File) {
+func (c *CheckContext) checkBackup() {
 	logger.Debug("checking backup")
-	count := 0
-	first := true
-	_, _ = file.WriteString(",\r\n\"Backup\": [\r\n")
 	err := c.Backup.GetBlobs(func(id string) bool {
 		// only check blobs that are not already checked in primary
 		if ok, _ := c.Primary.HasBlob(id); !ok {
-			if !first {
-				_, _ = file.WriteString(",\r\n")
-			}
-			_, _ = file.WriteString(fmt.Sprintf("{\"ID\": \"%s\", \"HasError\": true }", id))
-			first = false
+			c.writeLine(model.CheckResultLine{
+				Storage:  model.CheckStorageBackup,
+				ID:       id,
+				InBackup: true,
+				HasError: true,
+				Messages: []string{"missing blob in primary"},
+			})
 		}
-		count++
+		c.report.Backup++
 		return true
 	})
 	if err != nil {
 		logger.Errorf("check: error checking backup. %v", err)
 	}
-	_, _ = file.WriteString("]")
-	_, _ = file.WriteString(fmt.Sprintf(",\r\n\"BackupCount\": %d", count))
 }
 
-func (c *CheckContext) checkCache(file *os.File) {
-	count := 0
+func (c *CheckContext) checkCache() {
 	logger.Debug("checking cache")
-	_, _ = file.WriteString(",\r\n\"Cache\": [")
 	err := c.Cache.GetBlobs(func(id string) bool {
 		// checking if the blob belongs to the tenant
 		b, err := c.Cache.GetBlobDescription(id)
@@ -126,20 +120,21 @@ func (c *CheckContext) checkCache(file *os.File) {
 			if !ip {
 				msg = "cache inconsistent"
 			}
-			if count > 0 {
-				_, _ = file.WriteString(",\r\n")
-			}
-			_, _ = file.WriteString(fmt.Sprintf("{\"ID\": \"%s\", \"HasError\": %t, \"Messages\": [\"%s\"]}", id, !ip, msg))
-			count++
+			c.writeLine(model.CheckResultLine{
+				Storage:  model.CheckStorageCache,
+				ID:       id,
+				Filename: b.Filename,
+				InCache:  true,
+				HasError: !ip,
+				Messages: []string{msg},
+			})
+			c.report.Cache++
 		}
 		return true
 	})
-
-	_, _ = file.WriteString("]")
 	if err != nil {
 		logger.Errorf("check: error checking cache. %v", err)
 	}
-	_, _ = file.WriteString(fmt.Sprintf(",\r\n\"CacheCount\": %d", count))
 }
 
 // IsRunning checking if this task is running
@@ -147,13 +142,14 @@ func (c *CheckContext) IsRunning() bool {
 	return c.Running
 }
 
-func (c *CheckContext) checkBlob(id string, file *os.File) {
+func (c *CheckContext) checkBlob(id string) {
 	r := newResult()
 	r.ID = id
 	bd, err := c.Primary.GetBlobDescription(id)
 	if err != nil {
 		r.Messages = append(r.Messages, err.Error())
 		r.HasError = true
+		bd = &model.BlobDescription{}
 	}
 	// getting the filename
 	r.Filename = bd.Filename
@@ -195,13 +191,25 @@ func (c *CheckContext) checkBlob(id string, file *os.File) {
 	if len(r.Messages) == 0 {
 		r.Messages = append(r.Messages, "ok")
 	}
-	// writing a line of check results
-	js, _ := json.Marshal(r)
-	_, _ = file.WriteString(string(js))
+	c.writeLine(r)
+}
+
+// writeLine counting the errors and writing a line of check results into the report
+func (c *CheckContext) writeLine(r model.CheckResultLine) {
+	if r.HasError {
+		c.report.Errors++
+	}
+	if c.writer == nil {
+		return
+	}
+	if err := c.writer.write(r); err != nil {
+		logger.Errorf("check: error writing report line of %s: %v", r.ID, err)
+	}
 }
 
-func newResult() CheckResultLine {
-	return CheckResultLine{
+func newResult() model.CheckResultLine {
+	return model.CheckResultLine{
+		Storage:       model.CheckStoragePrimary,
 		HasError:      false,
 		InCache:       false,
 		InBackup:      false,
@@ -27,16 +27,14 @@ const (
 	blbPath        = rootFilePrefix + "blbstg"
 	cchPath        = rootFilePrefix + "blbcch"
 	bckPath        = rootFilePrefix + "bckstg"
+	rptPath        = rootFilePrefix + "reports"
 )
 
 type JSONResult struct {
-	Tenant       string
-	Cache        []CheckResultLine
-	CacheCount   int
-	Primary      []CheckResultLine
-	PrimaryCount int
-	Backup       []CheckResultLine
-	BackupCount  int
+	Report  model.CheckReport
+	Cache   []model.CheckResultLine
+	Primary []model.CheckResultLine
+	Backup  []model.CheckResultLine
 }
 
 var main *business.MainStorage
@@ -133,13 +131,13 @@ func checkBlob(ast *assert.Assertions, b model.BlobDescription) {
 	ast.Equal(b.BlobURL, buf.String(), fmt.Sprintf("payload doesn't match: %s", jsn))
 }
 
-func getResult(id string, res []CheckResultLine) (CheckResultLine, bool) {
+func getResult(id string, res []model.CheckResultLine) (model.CheckResultLine, bool) {
 	for _, r := range res {
 		if id == r.ID {
 			return r, true
 		}
 	}
-	return CheckResultLine{}, false
+	return model.CheckResultLine{}, false
 }
 
 func prepare(ast *assert.Assertions) []string {
@@ -170,25 +168,44 @@ func prepare(ast *assert.Assertions) []string {
 }
 
 func check(ast *assert.Assertions) JSONResult {
+	rs := &ReportStore{
+		RootPath: rptPath,
+	}
+	ast.Nil(rs.Init())
 	cctx := CheckContext{
 		TenantID: main.Tenant,
+		CheckID:  utils.GenerateID(),
 		Primary:  main.StgSrv,
 		Backup:   main.BckSrv,
 		Cache:    main.CchSrv,
+		Reports:  rs,
 	}
 
-	file, err := cctx.CheckStorage()
+	rp, err := cctx.CheckStorage()
 	ast.Nil(err)
-	ast.NotNil(file)
+	ast.Equal(model.CheckStateFinished, rp.State)
 
-	byteValue, err := os.ReadFile(file)
+	// the report is persisted
+	res := JSONResult{}
+	res.Report, err = rs.Get(main.Tenant, cctx.CheckID)
 	ast.Nil(err)
-	var res JSONResult
-	err = json.Unmarshal(byteValue, &res)
+	ast.Equal(rp.ID, res.Report.ID)
+	ast.Equal(model.CheckStateFinished, res.Report.State)
+	ast.True(rp.Started.Equal(res.Report.Started))
+	ast.Equal(rp.Primary, res.Report.Primary)
+	ast.Equal(rp.Errors, res.Report.Errors)
+	err = rs.Lines(main.Tenant, cctx.CheckID, false, func(l model.CheckResultLine) bool {
+		switch l.Storage {
+		case model.CheckStorageCache:
+			res.Cache = append(res.Cache, l)
+		case model.CheckStoragePrimary:
+			res.Primary = append(res.Primary, l)
+		case model.CheckStorageBackup:
+			res.Backup = append(res.Backup, l)
+		}
+		return true
+	})
 	ast.Nil(err)
-
-	// checking error attemps
-	// cache invalid
 	return res
 }
 
@@ -270,9 +287,22 @@ func TestCheck(t *testing.T) {
 	err = writeFiles(blobs)
 	ast.Nil(err)
 
-	ast.True(res.CacheCount >= 99, "cache count")
-	ast.True(res.PrimaryCount >= 99, "primary count")
-	ast.True(res.BackupCount >= 99, "backup count")
+	ast.True(res.Report.Cache >= 99, "cache count")
+	ast.True(res.Report.Primary >= 99, "primary count")
+	ast.True(res.Report.Backup >= 99, "backup count")
+
+	// only the lines with errors
+	errs := 0
+	rs := &ReportStore{RootPath: rptPath}
+	ast.Nil(rs.Init())
+	err = rs.Lines(tenant, res.Report.ID, true, func(l model.CheckResultLine) bool {
+		ast.True(l.HasError)
+		errs++
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal(res.Report.Errors, errs)
+	ast.True(errs >= 4)
 
 	// Test 1: cache inconsistent
 	r, ok := getResult(test1ID, res.Cache)
@@ -3,18 +3,17 @@ package migration
 import (
 	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/willie68/GoBlobStore/internal/services/business"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
 	"github.com/willie68/GoBlobStore/internal/utils"
-	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
 // Management this service takes control over several async migration parts, as backup, checks ...
 type Management struct {
 	StorageFactory interfaces.StorageFactory
+	Reports        *ReportStore // the store for the reports of the checks
 	cCtxs          map[string]any
 }
 
@@ -33,7 +32,10 @@ type Result struct {
 // Init creates a new migration service
 func (m *Management) Init() error {
 	m.cCtxs = make(map[string]any)
-	return nil
+	if m.Reports == nil {
+		m.Reports = &ReportStore{}
+	}
+	return m.Reports.Init()
 }
 
 // IsRunning checking if a migration task is running for a tenant
@@ -146,38 +148,15 @@ func (m *Management) doCheck(cCtx *CheckContext) {
 	defer func() {
 		cCtx.Finished = time.Now()
 	}()
-	file, err := cCtx.CheckStorage()
+	rp, err := cCtx.CheckStorage()
 	if err != nil {
 		cCtx.Message = fmt.Sprintf("error checking tenant %s: %v", cCtx.TenantID, err)
-		return
-	}
-	d, err := m.StorageFactory.GetStorage(cCtx.TenantID)
-	if err != nil {
-		cCtx.Message = fmt.Sprintf("error getting storage for tenant %s: %v", cCtx.TenantID, err)
-		return
-	}
-	s, err := os.Stat(file)
-	if err != nil {
-		cCtx.Message = fmt.Sprintf("error getting check file for tenant %s, %s: %v", cCtx.TenantID, file, err)
-		return
-	}
-	b := model.BlobDescription{
-		ContentType:   "application/json",
-		ContentLength: s.Size(),
-		Filename:      file,
+	} else {
+		logger.Infof("check %s of tenant %s finished, primary %d, backup %d, cache %d, errors %d", rp.ID, rp.Tenant, rp.Primary, rp.Backup, rp.Cache, rp.Errors)
 	}
-	r, err := os.Open(file)
-	if err != nil {
-		cCtx.Message = fmt.Sprintf("error getting check file for tenant %s, %s: %v", cCtx.TenantID, file, err)
-		return
-	}
-	defer r.Close()
-	id, err := d.StoreBlob(&b, r)
-	if err != nil {
-		cCtx.Message = fmt.Sprintf("couldn't store check file for tenant %s, %s: %v", cCtx.TenantID, file, err)
-		return
+	if err := m.Reports.Prune(cCtx.TenantID); err != nil {
+		logger.Errorf("error pruning check reports of tenant %s: %v", cCtx.TenantID, err)
 	}
-	cCtx.BlobID = id
 }
 
 func (m *Management) getCheckSrv(tenant string) (*CheckContext, error) {
@@ -196,6 +175,7 @@ func (m *Management) getCheckSrv(tenant string) (*CheckContext, error) {
 		Cache:    main.CchSrv,
 		Primary:  main.StgSrv,
 		Backup:   main.BckSrv,
+		Reports:  m.Reports,
 		Running:  false,
 	}
 	return &cCtx, nil
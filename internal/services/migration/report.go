@@ -0,0 +1,243 @@
+package migration
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// DefaultReportKeep default count of check reports kept per tenant
+const DefaultReportKeep = 10
+
+const (
+	reportExt  = ".json"
+	linesExt   = ".ndjson"
+	maxLineLen = 1024 * 1024
+)
+
+// ErrReportNotFound there is no check report with this id for the tenant
+var ErrReportNotFound = errors.New("check report not found")
+
+// ReportStore a file based store for the reports of the checks. Every tenant gets it's own folder,
+// every report consists of a summary file and a file with one json line per checked blob.
+type ReportStore struct {
+	RootPath string // root path of the reports, should not be part of the blob storage
+	Keep     int    // count of reports kept per tenant
+	MaxAge   int    // maximum age of the reports in days, 0 for no limit
+}
+
+// reportWriter writing the result lines of a running check into the report
+type reportWriter struct {
+	rs   *ReportStore
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+}
+
+// Init initialize the report store
+func (r *ReportStore) Init() error {
+	if r.RootPath == "" {
+		r.RootPath = filepath.Join(os.TempDir(), "goblobstore", "checks")
+		logger.Alertf("no root path for check reports given, using %s, the reports will not survive a new container", r.RootPath)
+	}
+	if r.Keep <= 0 {
+		r.Keep = DefaultReportKeep
+	}
+	return os.MkdirAll(r.RootPath, os.ModePerm)
+}
+
+// List getting the summaries of all reports of the tenant, the newest first
+func (r *ReportStore) List(tenant string) ([]model.CheckReport, error) {
+	res := make([]model.CheckReport, 0)
+	if !validName(tenant) {
+		return res, nil
+	}
+	files, err := os.ReadDir(filepath.Join(r.RootPath, tenant))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return nil, err
+	}
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), reportExt) {
+			continue
+		}
+		rp, err := r.Get(tenant, strings.TrimSuffix(fi.Name(), reportExt))
+		if err != nil {
+			logger.Errorf("check report: can't read %s: %v", fi.Name(), err)
+			continue
+		}
+		res = append(res, rp)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Started.After(res[j].Started)
+	})
+	return res, nil
+}
+
+// Get getting the summary of the report
+func (r *ReportStore) Get(tenant, id string) (model.CheckReport, error) {
+	var rp model.CheckReport
+	if !validName(tenant) || !validName(id) {
+		return rp, ErrReportNotFound
+	}
+	js, err := os.ReadFile(r.reportFile(tenant, id, reportExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return rp, ErrReportNotFound
+		}
+		return rp, err
+	}
+	err = json.Unmarshal(js, &rp)
+	return rp, err
+}
+
+// Lines walking thru the result lines of the report, with errorsOnly only the lines with errors.
+// You can stop the walk by returning false
+func (r *ReportStore) Lines(tenant, id string, errorsOnly bool, callback func(l model.CheckResultLine) bool) error {
+	if _, err := r.Get(tenant, id); err != nil {
+		return err
+	}
+	file, err := os.Open(r.reportFile(tenant, id, linesExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLen)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var l model.CheckResultLine
+		if err := json.Unmarshal(line, &l); err != nil {
+			logger.Errorf("check report: can't read line of %s: %v", id, err)
+			continue
+		}
+		if errorsOnly && !l.HasError {
+			continue
+		}
+		if !callback(l) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// Delete removing the report
+func (r *ReportStore) Delete(tenant, id string) error {
+	if _, err := r.Get(tenant, id); err != nil {
+		return err
+	}
+	err := os.Remove(r.reportFile(tenant, id, linesExt))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(r.reportFile(tenant, id, reportExt))
+}
+
+// Prune removing the reports of the tenant, which are older than the maximum age or exceeding the count of kept reports.
+// Running reports are never removed.
+func (r *ReportStore) Prune(tenant string) error {
+	rps, err := r.List(tenant)
+	if err != nil {
+		return err
+	}
+	var oldest time.Time
+	if r.MaxAge > 0 {
+		oldest = time.Now().AddDate(0, 0, -r.MaxAge)
+	}
+	kept := 0
+	for _, rp := range rps {
+		if rp.State == model.CheckStateRunning {
+			continue
+		}
+		if kept < r.Keep && (oldest.IsZero() || rp.Started.After(oldest)) {
+			kept++
+			continue
+		}
+		if err := r.Delete(tenant, rp.ID); err != nil {
+			logger.Errorf("check report: can't remove %s of tenant %s: %v", rp.ID, tenant, err)
+		}
+	}
+	return nil
+}
+
+// create creating a new report, the result lines are written with the returned writer
+func (r *ReportStore) create(rp model.CheckReport) (*reportWriter, error) {
+	if !validName(rp.Tenant) || !validName(rp.ID) {
+		return nil, errors.New("check report: wrong tenant or id")
+	}
+	err := os.MkdirAll(filepath.Join(r.RootPath, rp.Tenant), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	err = r.save(rp)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Create(r.reportFile(rp.Tenant, rp.ID, linesExt))
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	return &reportWriter{
+		rs:   r,
+		file: file,
+		buf:  buf,
+		enc:  json.NewEncoder(buf),
+	}, nil
+}
+
+// save writing the summary of the report, the file is replaced atomically
+func (r *ReportStore) save(rp model.CheckReport) error {
+	js, err := json.Marshal(rp)
+	if err != nil {
+		return err
+	}
+	fn := r.reportFile(rp.Tenant, rp.ID, reportExt)
+	tmp := fn + ".tmp"
+	err = os.WriteFile(tmp, js, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+func (r *ReportStore) reportFile(tenant, id, ext string) string {
+	return filepath.Join(r.RootPath, tenant, id+ext)
+}
+
+// write writing a result line into the report
+func (w *reportWriter) write(l model.CheckResultLine) error {
+	return w.enc.Encode(l)
+}
+
+// close closing the result lines and writing the final summary
+func (w *reportWriter) close(rp model.CheckReport) error {
+	err := w.buf.Flush()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return w.rs.save(rp)
+}
+
+// validName checking that the name can be used as a file name, without leaving the root path
+func validName(n string) bool {
+	return n != "" && n != "." && n != ".." && !strings.ContainsAny(n, `/\`)
+}
@@ -0,0 +1,110 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+func writeReport(ast *assert.Assertions, rs *ReportStore, started time.Time, state string) model.CheckReport {
+	rp := model.CheckReport{
+		ID:      utils.GenerateID(),
+		Tenant:  tenant,
+		State:   model.CheckStateRunning,
+		Started: started,
+	}
+	w, err := rs.create(rp)
+	ast.Nil(err)
+	ast.Nil(w.write(model.CheckResultLine{Storage: model.CheckStoragePrimary, ID: "ok", Messages: []string{"ok"}}))
+	ast.Nil(w.write(model.CheckResultLine{Storage: model.CheckStoragePrimary, ID: "err", HasError: true, Messages: []string{"primary hash not correct"}}))
+	rp.State = state
+	rp.Primary = 2
+	rp.Errors = 1
+	if state == model.CheckStateRunning {
+		ast.Nil(w.buf.Flush())
+		ast.Nil(w.file.Close())
+		return rp
+	}
+	rp.Finished = started.Add(time.Minute)
+	ast.Nil(w.close(rp))
+	return rp
+}
+
+func TestReportStore(t *testing.T) {
+	ast := assert.New(t)
+	ast.Nil(os.RemoveAll(rptPath))
+	rs := &ReportStore{
+		RootPath: rptPath,
+		Keep:     3,
+		MaxAge:   30,
+	}
+	ast.Nil(rs.Init())
+
+	now := time.Now()
+	old := writeReport(ast, rs, now.AddDate(0, 0, -40), model.CheckStateFinished)
+	rps := make([]model.CheckReport, 0)
+	for x := 4; x > 0; x-- {
+		rps = append(rps, writeReport(ast, rs, now.Add(-time.Duration(x)*time.Hour), model.CheckStateFinished))
+	}
+	running := writeReport(ast, rs, now.AddDate(0, 0, -50), model.CheckStateRunning)
+
+	list, err := rs.List(tenant)
+	ast.Nil(err)
+	ast.Equal(6, len(list))
+	ast.Equal(rps[3].ID, list[0].ID)
+
+	ids := make([]string, 0)
+	ast.Nil(rs.Lines(tenant, rps[0].ID, false, func(l model.CheckResultLine) bool {
+		ids = append(ids, l.ID)
+		return true
+	}))
+	ast.Equal([]string{"ok", "err"}, ids)
+	ids = ids[:0]
+	ast.Nil(rs.Lines(tenant, rps[0].ID, true, func(l model.CheckResultLine) bool {
+		ids = append(ids, l.ID)
+		return true
+	}))
+	ast.Equal([]string{"err"}, ids)
+
+	// the 3 newest are kept, too old and too many are removed, a running check stays
+	ast.Nil(rs.Prune(tenant))
+	list, err = rs.List(tenant)
+	ast.Nil(err)
+	ast.Equal(4, len(list))
+	for _, id := range []string{old.ID, rps[0].ID} {
+		_, err = rs.Get(tenant, id)
+		ast.ErrorIs(err, ErrReportNotFound)
+	}
+	rp, err := rs.Get(tenant, running.ID)
+	ast.Nil(err)
+	ast.Equal(model.CheckStateRunning, rp.State)
+
+	// ids and tenants are not leaving the root path
+	for _, id := range []string{"", "..", "../" + tenant + "/" + rps[3].ID, "a\\b"} {
+		_, err = rs.Get(tenant, id)
+		ast.ErrorIs(err, ErrReportNotFound, id)
+	}
+	err = rs.Lines("..", rps[3].ID, false, func(_ model.CheckResultLine) bool { return true })
+	ast.ErrorIs(err, ErrReportNotFound)
+	list, err = rs.List("unknown")
+	ast.Nil(err)
+	ast.Empty(list)
+
+	ast.Nil(rs.Delete(tenant, rps[3].ID))
+	_, err = rs.Get(tenant, rps[3].ID)
+	ast.ErrorIs(err, ErrReportNotFound)
+}
+
+func TestReportStoreDefaultPath(t *testing.T) {
+	ast := assert.New(t)
+	// without a root path the reports are kept in the temp folder
+	rs := &ReportStore{}
+	ast.Nil(rs.Init())
+	ast.Equal(filepath.Join(os.TempDir(), "goblobstore", "checks"), rs.RootPath)
+	ast.Equal(DefaultReportKeep, rs.Keep)
+}
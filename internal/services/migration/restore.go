@@ -8,6 +8,7 @@ import (
 
 	"github.com/willie68/GoBlobStore/internal/services/business"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
 )
 
 // RestoreContext struct for the running a full restore of the tenant
@@ -100,7 +101,7 @@ func restore(id string, src interfaces.BlobStorage, dst interfaces.BlobStorage)
 			// close the writer, so the reader knows there's no more data
 			defer wr.Close()
 
-			err := src.RetrieveBlob(id, wr)
+			err := utils.RetrieveBlob(src, id, wr, interfaces.CacheBypass)
 			if err != nil {
 				logger.Errorf("error getting blob: %s,%v", id, err)
 			}
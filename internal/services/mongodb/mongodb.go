@@ -7,8 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/willie68/GoBlobStore/internal/api"
@@ -29,16 +29,17 @@ const MongoIndex = "mongodb"
 
 // checking interface compatibility
 var (
-	_      interfaces.Index      = &Index{}
-	_      interfaces.IndexBatch = &IndexBatch{}
-	logger                       = logging.New().WithName("mongodb")
+	_      interfaces.Index            = &Index{}
+	_      interfaces.QueryExplainer   = &Index{}
+	_      interfaces.MultiTenantIndex = &Index{}
+	_      interfaces.IndexBatch       = &IndexBatch{}
+	logger                             = logging.New().WithName("mongodb")
 )
 
 // Index one index for a tenant
 type Index struct {
 	Tenant string
 	col    driver.Collection
-	qsync  sync.Mutex
 }
 
 // IndexBatch using batch functionality for indexing
@@ -116,7 +117,7 @@ func CloseMongoDB() {
 // Init initialisation of one tenant mongo indexer
 func (m *Index) Init() error {
 	m.Tenant = strings.ToLower(m.Tenant)
-	m.col = *database.Collection("c_" + m.Tenant)
+	m.col = *database.Collection(collectionName(m.Tenant))
 	// check for indexes
 	idx := m.col.Indexes()
 	opts := options.ListIndexes().SetMaxTime(2 * time.Second)
@@ -188,6 +189,65 @@ func (m *Index) Search(qry string, callback func(id string) bool) error {
 	return errors.New("no filter defined")
 }
 
+// SearchTenants searching the collections of all given tenants with one aggregation, the hits are delivered ordered by the lower case tenant and blob id
+func (m *Index) SearchTenants(qry string, tenants []string, callback func(tenant, id string) bool) error {
+	if len(tenants) == 0 {
+		return nil
+	}
+	bd, err := m.buildQuery(qry)
+	if err != nil {
+		return err
+	}
+	if bd == nil {
+		return errors.New("no filter defined")
+	}
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	cur, err := database.Collection(collectionName(tenants[0])).Aggregate(context.TODO(), tenantsPipeline(bd, tenants), opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.TODO())
+	for cur.Next(context.TODO()) {
+		elem := struct {
+			BlobID string `bson:"blobid"`
+			Tenant string `bson:"_tenant"`
+		}{}
+		err := cur.Decode(&elem)
+		if err != nil {
+			return err
+		}
+		if !callback(elem.Tenant, elem.BlobID) {
+			break
+		}
+	}
+	return cur.Err()
+}
+
+// tenantsPipeline building the aggregation, which is running on the collection of the first tenant and adding the hits of the collections of the other tenants
+func tenantsPipeline(bd bson.M, tenants []string) bson.A {
+	stages := func(tenant string) bson.A {
+		return bson.A{
+			bson.M{"$match": bd},
+			bson.M{"$project": bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "blobid", Value: 1},
+				{Key: "_tenant", Value: bson.M{"$literal": tenant}},
+				{Key: "_key", Value: bson.M{"$literal": strings.ToLower(tenant)}},
+			}},
+		}
+	}
+	pipeline := stages(tenants[0])
+	for _, t := range tenants[1:] {
+		pipeline = append(pipeline, bson.M{"$unionWith": bson.M{"coll": collectionName(t), "pipeline": stages(t)}})
+	}
+	return append(pipeline, bson.M{"$sort": bson.D{{Key: "_key", Value: 1}, {Key: "blobid", Value: 1}}})
+}
+
+// collectionName the name of the collection of the tenant
+func collectionName(tenant string) string {
+	return "c_" + strings.ToLower(tenant)
+}
+
 func (m *Index) buildQuery(qry string) (bson.M, error) {
 	var bd bson.M
 	if !strings.HasPrefix(qry, "#") {
@@ -209,20 +269,22 @@ func (m *Index) buildQuery(qry string) (bson.M, error) {
 }
 
 func (m *Index) buildAST(q string) (*query.Query, error) {
-	m.qsync.Lock()
-	defer m.qsync.Unlock()
-	query.N.Reset()
-	res, err := query.Parse("query", []byte(q))
+	qu, err := query.ParseQuery(q)
 	if err != nil {
 		return nil, err
 	}
-	qu, ok := res.(query.Query)
-	if !ok {
-		return nil, errors.New("unknown result")
-	}
 	return &qu, nil
 }
 
+// ExplainQuery getting the mongo query for a parsed query
+func (m *Index) ExplainQuery(q query.Query) (string, any, error) {
+	qry := strings.TrimPrefix(ToMongoQuery(q), "#")
+	if !json.Valid([]byte(qry)) {
+		return MongoIndex, qry, fmt.Errorf("invalid mongo query: %s", qry)
+	}
+	return MongoIndex, json.RawMessage(qry), nil
+}
+
 // Index indexing a single blob
 func (m *Index) Index(id string, b model.BlobDescription) error {
 	// checking if a blob with this id already exists
@@ -304,7 +366,7 @@ func (i *IndexBatch) Index() error {
 func ToMongoQuery(q query.Query) string {
 	var b strings.Builder
 	_, _ = b.WriteString("#")
-	c := q.Condition
+	c := q.Resolved(time.Now()).Condition
 	_, _ = b.WriteString(xToMdb(c))
 	return b.String()
 }
@@ -315,7 +377,11 @@ func cToMdb(c query.Condition) string {
 	f := c.Field
 	cv := oToMdb(c)
 	if c.Invert {
-		cv = fmt.Sprintf(`{"$not": %s}`, cv)
+		if c.Operator == query.EX {
+			cv = `{"$exists": false}`
+		} else {
+			cv = fmt.Sprintf(`{"$not": %s}`, cv)
+		}
 	}
 	_, _ = b.WriteString(fmt.Sprintf(`{"%s": %s}`, f, cv))
 	return b.String()
@@ -339,16 +405,50 @@ func oToMdb(c query.Condition) string {
 		return fmt.Sprintf(`{"$gte": %s}`, v)
 	case query.NE:
 		return fmt.Sprintf(`{"$ne": %s}`, v)
+	case query.IN:
+		vs, _ := c.Value.([]any)
+		l := make([]string, len(vs))
+		for i, x := range vs {
+			l[i] = query.ValueToString(x)
+		}
+		return fmt.Sprintf(`{"$in": [%s]}`, strings.Join(l, ", "))
+	case query.BT:
+		r, _ := c.Value.(query.Range)
+		return fmt.Sprintf(`{"$gte": %s, "$lte": %s}`, query.ValueToString(r.From), query.ValueToString(r.To))
+	case query.EX:
+		return `{"$exists": true}`
+	case query.CI:
+		return fmt.Sprintf(`{"$regex": %s, "$options": "i"}`, toRegex(v))
 	}
 	return ""
 }
 
+// toRegex converting a value with the wildcards * and ? into an anchored json coded regular expression
+func toRegex(v string) string {
+	v = strings.TrimPrefix(v, `"`)
+	v = strings.TrimSuffix(v, `"`)
+	re := regexp.QuoteMeta(v)
+	re = strings.ReplaceAll(re, `\*`, ".*")
+	re = strings.ReplaceAll(re, `\?`, ".")
+	js, _ := json.Marshal("^" + re + "$")
+	return string(js)
+}
+
 // nToMdb converting a node into a mongo query string
 func nToMdb(n query.Node) string {
 	var b strings.Builder
 	op := fmt.Sprintf("$%s", strings.ToLower(string(n.Operator)))
+	if n.Operator == query.NOOP {
+		op = "$and"
+	}
 	wh := xsToMdb(n.Conditions)
+	if n.Invert {
+		_, _ = b.WriteString(`{"$nor": [`)
+	}
 	_, _ = b.WriteString(fmt.Sprintf(`{"%s": [%s]}`, op, wh))
+	if n.Invert {
+		_, _ = b.WriteString(`]}`)
+	}
 	return b.String()
 }
 
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/willie68/GoBlobStore/pkg/model"
 	"github.com/willie68/GoBlobStore/pkg/model/query"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
@@ -137,3 +139,98 @@ func TestQueryConvertion(t *testing.T) {
 	fmt.Println(s)
 	ast.Equal(str, s)
 }
+
+func TestQueryExtensions(t *testing.T) {
+	ast := assert.New(t)
+
+	tests := []struct {
+		q string
+		r string
+	}{
+		{
+			q: `field1:IN(1, 2, "Willie")`,
+			r: `#{"field1": {"$in": [1.00000000, 2.00000000, "Willie"]}}`,
+		},
+		{
+			q: `field1:[50~200]`,
+			r: `#{"field1": {"$gte": 50.00000000, "$lte": 200.00000000}}`,
+		},
+		{
+			q: `field1:[2017-10-29T00:00:00Z~2017-10-30T00:00:00Z]`,
+			r: `#{"field1": {"$gte": "2017-10-29T00:00:00Z", "$lte": "2017-10-30T00:00:00Z"}}`,
+		},
+		{
+			q: `EXISTS(field1)`,
+			r: `#{"field1": {"$exists": true}}`,
+		},
+		{
+			q: `NOT EXISTS(field1)`,
+			r: `#{"field1": {"$exists": false}}`,
+		},
+		{
+			q: `field1:~"Wil*ie.x"`,
+			r: `#{"field1": {"$regex": "^Wil.*ie\\.x$", "$options": "i"}}`,
+		},
+		{
+			q: `NOT (field1:"Willie" OR field2:>100)`,
+			r: `#{"$nor": [{"$or": [{"field1": "Willie"}, {"field2": {"$gt": 100.00000000}}]}]}`,
+		},
+		{
+			q: `(field1:"Willie" AND field2:>100) OR field3:"muck"`,
+			r: `#{"$or": [{"$and": [{"field1": "Willie"}, {"field2": {"$gt": 100.00000000}}]}, {"field3": "muck"}]}`,
+		},
+		{
+			q: `!(field1:"Willie")`,
+			r: `#{"$nor": [{"$and": [{"field1": "Willie"}]}]}`,
+		},
+	}
+
+	idx := Index{}
+	for _, tc := range tests {
+		q, err := idx.buildAST(tc.q)
+		ast.Nil(err, tc.q)
+		ast.Equal(tc.r, ToMongoQuery(*q), tc.q)
+		_, err = idx.buildQuery(tc.q)
+		ast.Nil(err, tc.q)
+	}
+
+	// date math is resolved to unix milliseconds
+	q, err := idx.buildAST(`creationDate:>now-30d`)
+	ast.Nil(err)
+	qs := ToMongoQuery(*q)
+	ms := time.Now().AddDate(0, 0, -30).UnixMilli()
+	// only compare the leading digits, the query was resolved a moment ago
+	ast.Contains(qs, fmt.Sprintf(`{"creationDate": {"$gt": %d`, ms/100000))
+	bd, err := idx.buildQuery(`creationDate:[now-30d~now]`)
+	ast.Nil(err)
+	ast.NotNil(bd["creationDate"])
+}
+
+func TestExplainQuery(t *testing.T) {
+	ast := assert.New(t)
+	idx := Index{}
+
+	q, err := query.ParseQuery(`field1:IN(1, 2) AND NOT EXISTS(field2)`)
+	ast.Nil(err)
+	name, native, err := idx.ExplainQuery(q)
+	ast.Nil(err)
+	ast.Equal(MongoIndex, name)
+	js, err := json.Marshal(native)
+	ast.Nil(err)
+	ast.Equal(`{"$and":[{"field1":{"$in":[1.00000000,2.00000000]}},{"field2":{"$exists":false}}]}`, string(js))
+}
+
+func TestTenantsPipeline(t *testing.T) {
+	ast := assert.New(t)
+	idx := Index{}
+
+	bd, err := idx.buildQuery(`hash:"abc"`)
+	ast.Nil(err)
+	p := tenantsPipeline(bd, []string{"MCS", "other"})
+	ast.Equal(4, len(p))
+	js, err := bson.MarshalExtJSON(bson.M{"p": p}, false, false)
+	ast.Nil(err)
+	ast.Equal(`{"p":[{"$match":{"hash":"abc"}},{"$project":{"_id":0,"blobid":1,"_tenant":{"$literal":"MCS"},"_key":{"$literal":"mcs"}}},`+
+		`{"$unionWith":{"coll":"c_other","pipeline":[{"$match":{"hash":"abc"}},{"$project":{"_id":0,"blobid":1,"_tenant":{"$literal":"other"},"_key":{"$literal":"other"}}}]}},`+
+		`{"$sort":{"_key":1,"blobid":1}}]}`, string(js))
+}
@@ -61,16 +61,22 @@ func (s *SingleRetentionManager) Init(stgf interfaces.StorageFactory) error {
 func (s *SingleRetentionManager) processRetention() error {
 	actualTime := time.Now().Unix() * 1000
 	rmvList := make([]string, 0)
+	pushList := make([]model.RetentionEntry, 0)
 	for _, v := range s.retentionList {
 		if v.GetRetentionTimestampMS() < actualTime {
-			// TODO maybe the retention entry has been changed (from another node), so please refresh the entry and check again
 			rmvList = append(rmvList, v.BlobID)
 			stg, err := s.stgf.GetStorage(v.TenantID)
 			if err != nil {
 				logger.Errorf("RetMgr: error getting tenant store: %s", v.TenantID)
 				continue
 			}
-			err = stg.DeleteBlob(v.BlobID)
+			// the retention entry or the last access may have been changed in the meantime, so check again
+			r := s.refreshEntry(stg, v)
+			if r.GetRetentionTimestampMS() >= actualTime {
+				pushList = append(pushList, r)
+				continue
+			}
+			err = deleteBlob(stg, v.BlobID)
 			if err != nil {
 				logger.Errorf("RetMgr: error removing blob, t:%s, name: %s, id:%s", v.TenantID, v.Filename, v.BlobID)
 				continue
@@ -80,9 +86,35 @@ func (s *SingleRetentionManager) processRetention() error {
 	for _, v := range rmvList {
 		s.removeEntry(v)
 	}
+	for _, v := range pushList {
+		s.pushToList(v)
+	}
 	return nil
 }
 
+// refreshEntry reading the actual retention entry from the storage, for sliding retentions with the actual last access of the blob
+func (s *SingleRetentionManager) refreshEntry(stg interfaces.BlobStorage, v model.RetentionEntry) model.RetentionEntry {
+	r, err := stg.GetRetention(v.BlobID)
+	if err != nil {
+		r = v
+	}
+	if r.IsSliding() {
+		bd, err := stg.GetBlobDescription(v.BlobID)
+		if err == nil && bd.LastAccess > r.LastAccess {
+			r.LastAccess = bd.LastAccess
+		}
+	}
+	return r
+}
+
+// deleteBlob deletes the blob, if possible with the retention as reason for the audit journal
+func deleteBlob(stg interfaces.BlobStorage, id string) error {
+	if ad, ok := stg.(interfaces.AuditDeleter); ok {
+		return ad.DeleteBlobWithReason(id, model.ReasonRetention)
+	}
+	return stg.DeleteBlob(id)
+}
+
 func (s *SingleRetentionManager) removeEntry(id string) {
 	var i int
 	for x, v := range s.retentionList {
@@ -0,0 +1,34 @@
+package scrubber
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// the metrics of the scrubber, labeled with the tenant
+var (
+	scrubChecked = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goblobstore_scrubber_checked_total",
+		Help: "count of blobs checked by the scrubber",
+	}, []string{"tenant"})
+	scrubUnhealthy = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goblobstore_scrubber_unhealthy_total",
+		Help: "count of unhealthy blobs found by the scrubber",
+	}, []string{"tenant"})
+	scrubRepaired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goblobstore_scrubber_repaired_total",
+		Help: "count of blobs repaired by the scrubber",
+	}, []string{"tenant"})
+	scrubFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goblobstore_scrubber_failed_total",
+		Help: "count of blobs, the scrubber couldn't check",
+	}, []string{"tenant"})
+	scrubBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "goblobstore_scrubber_read_bytes_total",
+		Help: "count of bytes read by the scrubber",
+	})
+	scrubCycles = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "goblobstore_scrubber_cycles_total",
+		Help: "count of complete cycles of the scrubber",
+	})
+)
@@ -0,0 +1,374 @@
+// Package scrubber verifying the hashes of all blobs of all tenants on the primary and the backup storage continuously in the background
+package scrubber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/logging"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// default values of the configuration
+const (
+	DefaultCycleTime = 7 * 24 // one week in hours
+	DefaultRate      = 10     // blobs per second
+)
+
+// saveInterval count of checked blobs, after which the position is saved
+const saveInterval = 100
+
+var (
+	logger = logging.New().WithName("scrubber")
+	// windowCheck interval for checking, if the scrubber is in one of the time windows again
+	windowCheck = time.Minute
+	// retryDelay delay before a failed cycle is resumed
+	retryDelay = time.Minute
+)
+
+// Scrubber walking thru all blobs of all tenants, cycle by cycle, checking every blob with the BlobScrubber of the tenant storage
+type Scrubber struct {
+	StgFactory interfaces.StorageFactory
+	TntMgr     interfaces.TenantManager
+	Config     config.Scrubber
+	windows    []window
+	status     model.ScrubberStatus
+	sm         sync.Mutex
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// Init checking the configuration, loading the last position and starting the scrubber in the background
+func (s *Scrubber) Init() error {
+	if s.StgFactory == nil || s.TntMgr == nil {
+		return errors.New("scrubber needs a storage factory and a tenant manager")
+	}
+	s.windows = make([]window, 0)
+	for _, ws := range s.Config.Windows {
+		w, err := parseWindow(ws)
+		if err != nil {
+			return err
+		}
+		s.windows = append(s.windows, w)
+	}
+	if s.Config.CycleTime <= 0 {
+		s.Config.CycleTime = DefaultCycleTime
+	}
+	if err := utils.CheckRate(s.Config.Rate); err != nil {
+		return err
+	}
+	if s.Config.Rate == 0 {
+		s.Config.Rate = DefaultRate
+	}
+	err := s.load()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+	return nil
+}
+
+// Close stopping the scrubber and saving the position
+func (s *Scrubber) Close() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.done
+	s.sm.Lock()
+	s.status.Running = false
+	s.sm.Unlock()
+	return s.save()
+}
+
+// Status getting the status of the scrubber
+func (s *Scrubber) Status() model.ScrubberStatus {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+	return s.status
+}
+
+func (s *Scrubber) run(ctx context.Context) {
+	tick := time.NewTicker(utils.RateInterval(s.Config.Rate))
+	defer tick.Stop()
+	for {
+		st := s.Status()
+		inCycle := !st.CycleStarted.IsZero() && st.CycleFinished.Before(st.CycleStarted)
+		if !inCycle {
+			next := st.CycleStarted.Add(time.Duration(s.Config.CycleTime) * time.Hour)
+			s.sm.Lock()
+			s.status.NextCycle = next
+			s.sm.Unlock()
+			if !sleep(ctx, time.Until(next)) {
+				return
+			}
+			s.sm.Lock()
+			s.status = model.ScrubberStatus{
+				Cycle:         s.status.Cycle + 1,
+				CycleStarted:  time.Now(),
+				CycleFinished: s.status.CycleFinished,
+			}
+			st = s.status
+			s.sm.Unlock()
+			s.saveLogged()
+			logger.Infof("cycle %d started", st.Cycle)
+		}
+		err := s.scrubCycle(ctx, tick.C)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Errorf("error in cycle: %v", err)
+			if !sleep(ctx, retryDelay) {
+				return
+			}
+			continue
+		}
+		s.sm.Lock()
+		s.status.CycleFinished = time.Now()
+		s.status.Tenant = ""
+		s.status.BlobID = ""
+		s.status.Running = false
+		st = s.status
+		s.sm.Unlock()
+		s.saveLogged()
+		scrubCycles.Inc()
+		logger.Infof("cycle %d finished, checked %d, unhealthy %d, failed %d", st.Cycle, st.Checked, st.Unhealthy, st.Failed)
+	}
+}
+
+// scrubCycle checking all tenants, beginning with the tenant of the position
+func (s *Scrubber) scrubCycle(ctx context.Context, tick <-chan time.Time) error {
+	tenants, err := s.sortedTenants()
+	if err != nil {
+		return err
+	}
+	st := s.Status()
+	for _, t := range tenants {
+		after := ""
+		if st.Tenant != "" {
+			c := strings.Compare(strings.ToLower(t), strings.ToLower(st.Tenant))
+			if c < 0 {
+				continue
+			}
+			if c == 0 {
+				after = st.BlobID
+			}
+		}
+		s.sm.Lock()
+		s.status.Tenant = t
+		s.status.BlobID = after
+		s.sm.Unlock()
+		err := s.scrubTenant(ctx, tick, t, after)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			logger.Errorf("error checking tenant %s: %v", t, err)
+		}
+		s.saveLogged()
+	}
+	return nil
+}
+
+// scrubTenant checking all blobs of the tenant after the blob with the given id
+func (s *Scrubber) scrubTenant(ctx context.Context, tick <-chan time.Time, tenant, after string) error {
+	stg, err := s.StgFactory.GetStorage(tenant)
+	if err != nil {
+		return err
+	}
+	sc, ok := stg.(interfaces.BlobScrubber)
+	if !ok {
+		return fmt.Errorf("storage of tenant %s can't scrub blobs", tenant)
+	}
+	skip := after != ""
+	err = stg.GetBlobs(func(id string) bool {
+		if skip {
+			skip = id != after
+			return true
+		}
+		if !s.throttle(ctx, tick) {
+			return false
+		}
+		n := s.scrubBlob(sc, tenant, id)
+		if s.Config.ByteRate > 0 && n > 0 {
+			if !sleep(ctx, time.Duration(float64(n)/float64(s.Config.ByteRate)*float64(time.Second))) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+	if skip {
+		// the blob of the position is deleted, checking the tenant again from the start
+		return s.scrubTenant(ctx, tick, tenant, "")
+	}
+	return nil
+}
+
+// scrubBlob checking a single blob, returning the count of bytes read
+func (s *Scrubber) scrubBlob(sc interfaces.BlobScrubber, tenant, id string) int64 {
+	bd, err := sc.ScrubBlob(id)
+	var n int64
+	if err == nil && bd != nil {
+		n = bd.ContentLength
+		if bd.Check != nil && bd.Check.Backup != nil {
+			n *= 2
+		}
+	}
+	s.sm.Lock()
+	s.status.BlobID = id
+	s.status.Checked++
+	s.status.Bytes += n
+	switch {
+	case err != nil:
+		s.status.Failed++
+	case bd.Check == nil || !bd.Check.Healthy:
+		s.status.Unhealthy++
+	}
+	repaired := err == nil && bd.Check != nil && bd.Check.Repaired != ""
+	if repaired {
+		s.status.Repaired++
+	}
+	save := s.status.Checked%saveInterval == 0
+	s.sm.Unlock()
+
+	scrubChecked.WithLabelValues(tenant).Inc()
+	scrubBytes.Add(float64(n))
+	if repaired {
+		scrubRepaired.WithLabelValues(tenant).Inc()
+	}
+	switch {
+	case err != nil:
+		scrubFailed.WithLabelValues(tenant).Inc()
+		logger.Errorf("error checking blob %s of tenant %s: %v", id, tenant, err)
+	case bd.Check == nil || !bd.Check.Healthy:
+		scrubUnhealthy.WithLabelValues(tenant).Inc()
+		msg := ""
+		if bd.Check != nil {
+			msg = bd.Check.Message
+		}
+		logger.Alertf("unhealthy blob %s of tenant %s: %s", id, tenant, msg)
+	}
+	if save {
+		s.saveLogged()
+	}
+	return n
+}
+
+// throttle waiting for the next time window and the rate limit, returning false if the scrubber is stopped
+func (s *Scrubber) throttle(ctx context.Context, tick <-chan time.Time) bool {
+	for !inWindows(s.windows, time.Now()) {
+		s.setRunning(false)
+		if !sleep(ctx, windowCheck) {
+			return false
+		}
+	}
+	s.setRunning(true)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-tick:
+		return true
+	}
+}
+
+func (s *Scrubber) setRunning(r bool) {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+	s.status.Running = r
+}
+
+// sortedTenants getting all tenants ordered by the lower case name, so a cycle can be resumed
+func (s *Scrubber) sortedTenants() ([]string, error) {
+	tenants := make([]string, 0)
+	err := s.TntMgr.GetTenants(func(t string) bool {
+		tenants = append(tenants, t)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tenants, func(i, j int) bool {
+		return strings.ToLower(tenants[i]) < strings.ToLower(tenants[j])
+	})
+	return tenants, nil
+}
+
+// load loading the position of the last run
+func (s *Scrubber) load() error {
+	if s.Config.StateFile == "" {
+		return nil
+	}
+	js, err := os.ReadFile(s.Config.StateFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var st model.ScrubberStatus
+	err = json.Unmarshal(js, &st)
+	if err != nil {
+		return fmt.Errorf("wrong scrubber state file %s: %w", s.Config.StateFile, err)
+	}
+	st.Running = false
+	s.status = st
+	return nil
+}
+
+// save saving the position, the file is replaced atomically
+func (s *Scrubber) save() error {
+	if s.Config.StateFile == "" {
+		return nil
+	}
+	js, err := json.Marshal(s.Status())
+	if err != nil {
+		return err
+	}
+	tmp := s.Config.StateFile + ".tmp"
+	err = os.WriteFile(tmp, js, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Config.StateFile)
+}
+
+func (s *Scrubber) saveLogged() {
+	if err := s.save(); err != nil {
+		logger.Errorf("error saving state: %v", err)
+	}
+}
+
+// sleep waiting for the duration, returning false if the context is done before
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,334 @@
+package scrubber
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/services/factory"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/noindex"
+	"github.com/willie68/GoBlobStore/internal/services/retentionmanager"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const rootFilePrefix = "../../../testdata/scrub"
+
+var (
+	blbPath   = filepath.Join(rootFilePrefix, "blbstg")
+	bckPath   = filepath.Join(rootFilePrefix, "bckstg")
+	statePath = filepath.Join(rootFilePrefix, "scrubber.json")
+)
+
+func initScrubTest(ast *assert.Assertions, repair ...bool) (interfaces.StorageFactory, interfaces.TenantManager) {
+	ast.Nil(os.RemoveAll(rootFilePrefix))
+	tntMgr := &simplefile.TenantManager{
+		RootPath: blbPath,
+	}
+	ast.Nil(tntMgr.Init())
+	stgf := &factory.DefaultStorageFactory{
+		TenantMgr: tntMgr,
+	}
+	rtnMgr := &retentionmanager.NoRetention{}
+	ast.Nil(rtnMgr.Init(stgf))
+	ast.Nil(stgf.Init(config.Engine{
+		Tenantautoadd:  true,
+		BackupSyncmode: true,
+		AutoRepair:     len(repair) > 0 && repair[0],
+		Storage: config.Storage{
+			Storageclass: factory.STGClassSimpleFile,
+			Properties: map[string]any{
+				"rootpath": blbPath,
+			},
+		},
+		Backup: config.Storage{
+			Storageclass: factory.STGClassSimpleFile,
+			Properties: map[string]any{
+				"rootpath": bckPath,
+			},
+		},
+		Index: config.Storage{
+			Storageclass: noindex.NoIndexName,
+		},
+	}, rtnMgr))
+
+	for _, t := range []string{"tnt2", "tnt1"} {
+		ast.Nil(tntMgr.AddTenant(t))
+		stg, err := stgf.GetStorage(t)
+		ast.Nil(err)
+		for i := 0; i < 3; i++ {
+			payload := fmt.Sprintf("blob %d of %s", i, t)
+			b := model.BlobDescription{
+				BlobID:        fmt.Sprintf("%s-%d", t, i),
+				TenantID:      t,
+				StoreID:       t,
+				ContentType:   "text/plain",
+				ContentLength: int64(len(payload)),
+				Filename:      fmt.Sprintf("blob_%d.txt", i),
+			}
+			_, err := stg.StoreBlob(&b, strings.NewReader(payload))
+			ast.Nil(err)
+		}
+	}
+	return stgf, tntMgr
+}
+
+// corrupt overwriting the content of the blob in the storage
+func corrupt(ast *assert.Assertions, root, id string) {
+	found := false
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && d.Name() == id+".bin" {
+			found = true
+			return os.WriteFile(path, []byte("corrupted content"), 0o644)
+		}
+		return err
+	})
+	ast.Nil(err)
+	ast.True(found)
+}
+
+func waitCycle(ast *assert.Assertions, s *Scrubber, cycle int) model.ScrubberStatus {
+	for x := 0; x < 500; x++ {
+		st := s.Status()
+		if st.Cycle == cycle && st.CycleFinished.After(st.CycleStarted) {
+			return st
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ast.Fail("cycle not finished")
+	return s.Status()
+}
+
+func TestWindows(t *testing.T) {
+	ast := assert.New(t)
+	at := func(h, m int) time.Time {
+		return time.Date(2023, 9, 12, h, m, 0, 0, time.Local)
+	}
+	w, err := parseWindow("22:00-06:00")
+	ast.Nil(err)
+	ast.True(w.contains(at(23, 0)))
+	ast.True(w.contains(at(5, 59)))
+	ast.False(w.contains(at(6, 0)))
+	ast.False(w.contains(at(12, 0)))
+
+	w2, err := parseWindow(" 12:00 - 13:30 ")
+	ast.Nil(err)
+	ast.True(w2.contains(at(13, 29)))
+	ast.False(w2.contains(at(11, 59)))
+
+	ast.True(inWindows(nil, at(12, 0)))
+	ast.True(inWindows([]window{w, w2}, at(12, 0)))
+	ast.False(inWindows([]window{w, w2}, at(18, 0)))
+
+	for _, s := range []string{"", "22:00", "22:00-", "25:00-06:00", "aa-bb"} {
+		_, err = parseWindow(s)
+		ast.NotNil(err, s)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	ast := assert.New(t)
+	stgf, tntMgr := initScrubTest(ast)
+	corrupt(ast, bckPath, "tnt2-1")
+
+	s := &Scrubber{
+		StgFactory: stgf,
+		TntMgr:     tntMgr,
+		Config: config.Scrubber{
+			Enable:    true,
+			Rate:      1000,
+			StateFile: statePath,
+		},
+	}
+	ast.Nil(s.Init())
+	st := waitCycle(ast, s, 1)
+	ast.Nil(s.Close())
+
+	ast.Equal(6, st.Checked)
+	ast.Equal(1, st.Unhealthy)
+	ast.Equal(0, st.Failed)
+	ast.True(st.Bytes > 0)
+	ast.Empty(st.Tenant)
+	ast.Equal(DefaultCycleTime, s.Config.CycleTime)
+
+	stg, err := stgf.GetStorage("tnt2")
+	ast.Nil(err)
+	bd, err := stg.GetBlobDescription("tnt2-1")
+	ast.Nil(err)
+	ast.NotNil(bd.Check)
+	ast.False(bd.Check.Healthy)
+	ast.True(bd.Check.Store.Healthy)
+	ast.False(bd.Check.Backup.Healthy)
+	bd, err = stg.GetBlobDescription("tnt2-0")
+	ast.Nil(err)
+	ast.True(bd.Check.Healthy)
+	ast.NotNil(bd.Check.Store.LastCheck)
+
+	// the next cycle waits for the cycle time
+	js, err := os.ReadFile(statePath)
+	ast.Nil(err)
+	var saved model.ScrubberStatus
+	ast.Nil(json.Unmarshal(js, &saved))
+	ast.Equal(1, saved.Cycle)
+	ast.False(saved.Running)
+	s = &Scrubber{
+		StgFactory: stgf,
+		TntMgr:     tntMgr,
+		Config: config.Scrubber{
+			Rate:      1000,
+			StateFile: statePath,
+		},
+	}
+	ast.Nil(s.Init())
+	time.Sleep(50 * time.Millisecond)
+	st = s.Status()
+	ast.Equal(1, st.Cycle)
+	ast.True(st.NextCycle.After(time.Now()))
+	ast.Nil(s.Close())
+	ast.Nil(stgf.Close())
+}
+
+func TestScrubRepair(t *testing.T) {
+	ast := assert.New(t)
+	stgf, tntMgr := initScrubTest(ast, true)
+	corrupt(ast, bckPath, "tnt1-0")
+	corrupt(ast, blbPath, "tnt2-2")
+
+	s := &Scrubber{
+		StgFactory: stgf,
+		TntMgr:     tntMgr,
+		Config: config.Scrubber{
+			Rate: 1000,
+		},
+	}
+	ast.Nil(s.Init())
+	st := waitCycle(ast, s, 1)
+	ast.Nil(s.Close())
+
+	ast.Equal(6, st.Checked)
+	ast.Equal(2, st.Repaired)
+	ast.Equal(0, st.Unhealthy)
+	ast.Equal(0, st.Failed)
+
+	stg, err := stgf.GetStorage("tnt2")
+	ast.Nil(err)
+	bd, err := stg.GetBlobDescription("tnt2-2")
+	ast.Nil(err)
+	ast.True(bd.Check.Healthy)
+	ast.NotEmpty(bd.Check.Repaired)
+	var buf strings.Builder
+	ast.Nil(stg.RetrieveBlob("tnt2-2", &buf))
+	ast.Equal("blob 2 of tnt2", buf.String())
+	ast.Nil(stgf.Close())
+}
+
+func TestScrubResume(t *testing.T) {
+	ast := assert.New(t)
+	stgf, tntMgr := initScrubTest(ast)
+
+	// the cycle was interrupted after the first blob of the second tenant
+	stg, err := stgf.GetStorage("tnt2")
+	ast.Nil(err)
+	first := ""
+	// the simple file storage returns io.EOF, if the walk is stopped
+	_ = stg.GetBlobs(func(id string) bool {
+		first = id
+		return false
+	})
+	ast.NotEmpty(first)
+	st := model.ScrubberStatus{
+		Cycle:        3,
+		CycleStarted: time.Now().Add(-time.Hour),
+		Tenant:       "tnt2",
+		BlobID:       first,
+		Checked:      4,
+	}
+	js, err := json.Marshal(st)
+	ast.Nil(err)
+	ast.Nil(os.WriteFile(statePath, js, 0o644))
+
+	s := &Scrubber{
+		StgFactory: stgf,
+		TntMgr:     tntMgr,
+		Config: config.Scrubber{
+			Rate:      1000,
+			StateFile: statePath,
+		},
+	}
+	ast.Nil(s.Init())
+	st = waitCycle(ast, s, 3)
+	ast.Nil(s.Close())
+	ast.Equal(6, st.Checked)
+
+	// the blob of the position is deleted, so the tenant is checked again
+	ast.Nil(stg.DeleteBlob(first))
+	st.CycleFinished = time.Time{}
+	st.Tenant = "tnt2"
+	st.BlobID = first
+	st.Checked = 4
+	js, err = json.Marshal(st)
+	ast.Nil(err)
+	ast.Nil(os.WriteFile(statePath, js, 0o644))
+	s = &Scrubber{
+		StgFactory: stgf,
+		TntMgr:     tntMgr,
+		Config: config.Scrubber{
+			Rate:      1000,
+			StateFile: statePath,
+		},
+	}
+	ast.Nil(s.Init())
+	st = waitCycle(ast, s, 3)
+	ast.Nil(s.Close())
+	ast.Equal(6, st.Checked)
+	ast.Nil(stgf.Close())
+}
+
+func TestScrubWindow(t *testing.T) {
+	ast := assert.New(t)
+	stgf, tntMgr := initScrubTest(ast)
+	windowCheck = 10 * time.Millisecond
+	defer func() {
+		windowCheck = time.Minute
+	}()
+
+	// a window, which is not now
+	now := time.Now()
+	from := now.Add(2 * time.Hour)
+	to := now.Add(3 * time.Hour)
+	s := &Scrubber{
+		StgFactory: stgf,
+		TntMgr:     tntMgr,
+		Config: config.Scrubber{
+			Rate:    1000,
+			Windows: []string{fmt.Sprintf("%s-%s", from.Format("15:04"), to.Format("15:04"))},
+		},
+	}
+	ast.Nil(s.Init())
+	time.Sleep(100 * time.Millisecond)
+	st := s.Status()
+	ast.Equal(1, st.Cycle)
+	ast.False(st.Running)
+	ast.Equal(0, st.Checked)
+	ast.Nil(s.Close())
+
+	s.Config.Windows = []string{"25:00-26:00"}
+	ast.NotNil(s.Init())
+
+	// rates, which are negative or too large
+	s.Config.Windows = nil
+	s.Config.Rate = -1
+	ast.NotNil(s.Init())
+	s.Config.Rate = utils.MaxRate + 1
+	ast.NotNil(s.Init())
+	ast.Nil(stgf.Close())
+}
@@ -0,0 +1,61 @@
+package scrubber
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// window a time of day window, the end can be before the start for a window over midnight
+type window struct {
+	from int // minute of the day
+	to   int // minute of the day
+}
+
+// parseWindow parsing a window like "22:00-06:00"
+func parseWindow(s string) (window, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return window{}, fmt.Errorf("wrong time window: %s", s)
+	}
+	f, err := parseTimeOfDay(from)
+	if err != nil {
+		return window{}, fmt.Errorf("wrong time window: %s, %w", s, err)
+	}
+	t, err := parseTimeOfDay(to)
+	if err != nil {
+		return window{}, fmt.Errorf("wrong time window: %s, %w", s, err)
+	}
+	return window{from: f, to: t}, nil
+}
+
+// parseTimeOfDay parsing a time of day like "06:00" into the minute of the day
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains checking if the time of day is in the window
+func (w window) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.from <= w.to {
+		return m >= w.from && m < w.to
+	}
+	return m >= w.from || m < w.to
+}
+
+// inWindows checking if the time is in one of the windows, no windows is all day
+func inWindows(ws []window, t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
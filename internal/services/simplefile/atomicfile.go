@@ -0,0 +1,156 @@
+package simplefile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+)
+
+// TmpExt extension of temporary files, every file is written into a temporary file first and renamed, when it's complete
+const TmpExt = ".tmp"
+
+// startTime files older than this are leftovers of a former run, only these are touched by the recovery
+var startTime = time.Now()
+
+// tmpFile a file, which is written into a temporary file in the same directory and renamed to its name on commit
+type tmpFile struct {
+	f    *os.File
+	name string
+	sync bool
+}
+
+// createTmpFile creating the temporary file for the named file
+func createTmpFile(name string, sync bool) (*tmpFile, error) {
+	tmp := fmt.Sprintf("%s.%s%s", name, utils.GenerateID(), TmpExt)
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	return &tmpFile{
+		f:    f,
+		name: name,
+		sync: sync,
+	}, nil
+}
+
+func (t *tmpFile) Write(p []byte) (int, error) {
+	return t.f.Write(p)
+}
+
+// commit closing the temporary file and renaming it to its final name, with sync the file and the directory are synced to disk
+func (t *tmpFile) commit() error {
+	if t.sync {
+		if err := t.f.Sync(); err != nil {
+			t.abort()
+			return err
+		}
+	}
+	if err := t.f.Close(); err != nil {
+		_ = os.Remove(t.f.Name())
+		return err
+	}
+	if err := os.Rename(t.f.Name(), t.name); err != nil {
+		_ = os.Remove(t.f.Name())
+		return err
+	}
+	if t.sync {
+		return syncDir(filepath.Dir(t.name))
+	}
+	return nil
+}
+
+// abort closing and removing the temporary file, the file with the final name is untouched
+func (t *tmpFile) abort() {
+	_ = t.f.Close()
+	_ = os.Remove(t.f.Name())
+}
+
+// writeFileAtomic writing the data into the named file, readers will see the old or the new file, but never a partial one
+func writeFileAtomic(name string, data []byte, sync bool) error {
+	t, err := createTmpFile(name, sync)
+	if err != nil {
+		return err
+	}
+	if _, err := t.Write(data); err != nil {
+		t.abort()
+		return err
+	}
+	return t.commit()
+}
+
+// syncDir syncing the directory, so a rename survives a crash. Directories can't be synced on windows.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Recover cleaning up the leftovers of a crash in the tenant path: temporary files and binaries without a description,
+// which are half written blobs. Only files older than the start of the process are removed, so running writes are safe.
+// Returning the count of removed files.
+func (s *BlobStorage) Recover() (int, error) {
+	count := 0
+	remove := func(path string) {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Errorf("recovery: can't remove %s: %v", path, err)
+			return
+		}
+		if err == nil {
+			logger.Infof("recovery: removed %s", path)
+			count++
+		}
+	}
+	retPath := filepath.Join(s.filepath, RetentionPath)
+	err := filepath.WalkDir(s.filepath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if !strings.HasSuffix(name, TmpExt) && (!strings.HasSuffix(name, BinaryExt) || filepath.Dir(path) == retPath) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.ModTime().Before(startTime) {
+			return nil
+		}
+		if strings.HasSuffix(name, TmpExt) {
+			remove(path)
+			return nil
+		}
+		base := strings.TrimSuffix(path, BinaryExt)
+		if _, err := os.Stat(base + DescriptionExt); !errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		// the description is written last, without it the blob is half written
+		remove(path)
+		remove(base + ShardExt)
+		if rf, err := s.buildRetentionFilename(strings.TrimSuffix(name, BinaryExt)); err == nil {
+			remove(rf)
+		}
+		return nil
+	})
+	return count, err
+}
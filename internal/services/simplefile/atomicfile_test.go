@@ -0,0 +1,159 @@
+package simplefile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const atomicTenant = "atomic"
+
+func getAtomicSrv(t *testing.T) *BlobStorage {
+	ast := assert.New(t)
+	ast.Nil(os.RemoveAll(filepath.Join(rootpath, atomicTenant)))
+	srv := &BlobStorage{
+		RootPath: rootpath,
+		Tenant:   atomicTenant,
+		Sync:     true,
+		// the tests are calling the recovery directly
+		DisableRecovery: true,
+	}
+	ast.Nil(srv.Init())
+	return srv
+}
+
+// files getting all files of the tenant
+func files(t *testing.T, srv *BlobStorage) []string {
+	fs := make([]string, 0)
+	err := filepath.Walk(srv.filepath, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			fs = append(fs, filepath.Base(path))
+		}
+		return err
+	})
+	assert.Nil(t, err)
+	return fs
+}
+
+func TestAtomicWrite(t *testing.T) {
+	ast := assert.New(t)
+	srv := getAtomicSrv(t)
+
+	b := model.BlobDescription{
+		ContentLength: 22,
+		ContentType:   "text/plain",
+		Retention:     1000,
+		Properties:    make(map[string]any),
+	}
+	id, err := srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+	ast.Nil(srv.AddRetention(&model.RetentionEntry{BlobID: id, Retention: 1000}))
+	ast.ElementsMatch([]string{id + BinaryExt, id + DescriptionExt, id + RetentionExt}, files(t, srv))
+
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal("this is a blob content", buf.String())
+
+	// a wrong content length leaves nothing behind
+	b = model.BlobDescription{
+		ContentLength: 10,
+		Properties:    make(map[string]any),
+	}
+	_, err = srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.NotNil(err)
+	ok, err := srv.HasBlob(b.BlobID)
+	ast.Nil(err)
+	ast.False(ok)
+	ast.Len(files(t, srv), 3)
+
+	ast.Nil(srv.DeleteBlob(id))
+	ast.Len(files(t, srv), 0)
+}
+
+func TestAtomicOverwrite(t *testing.T) {
+	ast := assert.New(t)
+	srv := getAtomicSrv(t)
+
+	b := model.BlobDescription{
+		ContentType: "text/plain",
+		Properties:  make(map[string]any),
+	}
+	id, err := srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+	ast.Nil(srv.AddRetention(&model.RetentionEntry{BlobID: id, Retention: 1000}))
+
+	// a description, which can't be written, keeps the old blob
+	nb := model.BlobDescription{
+		BlobID:     id,
+		Properties: map[string]any{"X-fail": func() {}},
+	}
+	_, err = srv.StoreBlob(&nb, strings.NewReader("another content"))
+	ast.NotNil(err)
+	ast.ElementsMatch([]string{id + BinaryExt, id + DescriptionExt, id + RetentionExt}, files(t, srv))
+
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal("this is a blob content", buf.String())
+	_, err = srv.GetRetention(id)
+	ast.Nil(err)
+
+	ast.Nil(os.RemoveAll(srv.filepath))
+}
+
+func TestRecover(t *testing.T) {
+	ast := assert.New(t)
+	srv := getAtomicSrv(t)
+
+	b := model.BlobDescription{
+		ContentType: "text/plain",
+		Retention:   1000,
+		Properties:  make(map[string]any),
+	}
+	id, err := srv.StoreBlob(&b, strings.NewReader("this is a blob content"))
+	ast.Nil(err)
+
+	// simulating a crash: a temp file, a half written blob with shard and retention file and a running upload
+	orphan := "0123456789abcdef0123456789abcdef"
+	ast.Nil(srv.createFilePathV2(orphan))
+	old := []string{
+		srv.getBinV2(orphan),
+		srv.getBinV2(orphan) + ".4711" + TmpExt,
+		srv.getDescV2(id) + ".0815" + TmpExt,
+	}
+	sf, _ := srv.buildFilenameV2(orphan, ShardExt)
+	rf, _ := srv.buildRetentionFilename(orphan)
+	old = append(old, sf, rf)
+	running := srv.getBinV2(id) + ".running" + TmpExt
+	for _, f := range append(old, running) {
+		ast.Nil(os.WriteFile(f, []byte("crash"), os.ModePerm))
+	}
+	past := startTime.Add(-time.Hour)
+	for _, f := range old {
+		ast.Nil(os.Chtimes(f, past, past))
+	}
+	// blobs written before the start are untouched
+	ast.Nil(os.Chtimes(srv.getBinV2(id), past, past))
+
+	count, err := srv.Recover()
+	ast.Nil(err)
+	ast.Equal(len(old), count)
+	for _, f := range old {
+		ast.NoFileExists(f)
+	}
+	ast.FileExists(running)
+
+	ok, err := srv.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal("this is a blob content", buf.String())
+
+	ast.Nil(os.RemoveAll(srv.filepath))
+}
@@ -0,0 +1,565 @@
+package simplefile
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/internal/utils/erasure"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const (
+	// ShardExt extension of the shard info file of an erasure coded blob
+	ShardExt = ".shard"
+	// the maximal size of a shard block, every stripe of the blob has data shards blocks
+	shardBlockSize = 64 * 1024
+)
+
+// shardInfo information about a single shard of an erasure coded blob, stored beside the shard
+type shardInfo struct {
+	Index        int    `json:"index"`
+	DataShards   int    `json:"dataShards"`
+	ParityShards int    `json:"parityShards"`
+	BlockSize    int    `json:"blockSize"`
+	Size         int64  `json:"size"`
+	Hash         string `json:"hash"`
+}
+
+// stripes count of stripes of the blob
+func (si shardInfo) stripes() int64 {
+	sl := int64(si.DataShards * si.BlockSize)
+	return (si.Size + sl - 1) / sl
+}
+
+// shardWriter writing a shard file and calculating the hash of it, the file is committed with the shard info
+type shardWriter struct {
+	f *tmpFile
+	h hash.Hash
+}
+
+func newShardWriter(srv *BlobStorage, id string) (*shardWriter, error) {
+	err := srv.createFilePathV2(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := createTmpFile(srv.getBinV2(id), srv.Sync)
+	if err != nil {
+		return nil, err
+	}
+	return &shardWriter{
+		f: f,
+		h: sha256.New(),
+	}, nil
+}
+
+func (w *shardWriter) write(p []byte) error {
+	_, err := io.MultiWriter(w.f, w.h).Write(p)
+	return err
+}
+
+func (w *shardWriter) hash() string {
+	return fmt.Sprintf("sha-256:%x", w.h.Sum(nil))
+}
+
+// abortShardWriters removing the temporary files of the shard writers, committed shards are untouched
+func abortShardWriters(sws []*shardWriter) {
+	for _, sw := range sws {
+		if sw != nil {
+			sw.f.abort()
+		}
+	}
+}
+
+func closeShardReaders(rs []*os.File) {
+	for _, f := range rs {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+}
+
+// writeShard committing the binary file of the shard and writing the shard info and the description,
+// the description is written last, so the shard is only visible, if all files are complete
+func (s *BlobStorage) writeShard(b *model.BlobDescription, si shardInfo, sw *shardWriter) error {
+	err := sw.f.commit()
+	if err != nil {
+		return err
+	}
+	jsn, err := json.Marshal(si)
+	if err != nil {
+		return err
+	}
+	sf, _ := s.buildFilenameV2(b.BlobID, ShardExt)
+	err = writeFileAtomic(sf, jsn, s.Sync)
+	if err != nil {
+		return err
+	}
+	err = s.writeJSONFileV2(b)
+	if err != nil {
+		return err
+	}
+	s.cm.Lock()
+	defer s.cm.Unlock()
+	s.bdCch[b.BlobID] = *b
+	return nil
+}
+
+// getShardInfo reading the shard info of a shard
+func (s *BlobStorage) getShardInfo(id string) (*shardInfo, error) {
+	sf, _ := s.buildFilenameV2(id, ShardExt)
+	dat, err := os.ReadFile(sf)
+	if err != nil {
+		return nil, err
+	}
+	var si shardInfo
+	err = json.Unmarshal(dat, &si)
+	if err != nil {
+		return nil, err
+	}
+	return &si, nil
+}
+
+// hasShard checking if this storage holds a shard of the blob
+func (s *BlobStorage) hasShard(id string) bool {
+	sf, _ := s.buildFilenameV2(id, ShardExt)
+	_, err := os.Stat(sf)
+	return err == nil
+}
+
+// checkShard checking the hash of the shard file
+func (s *BlobStorage) checkShard(id string) (*shardInfo, error) {
+	si, err := s.getShardInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.getBinV2(id))
+	if err != nil {
+		return si, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return si, err
+	}
+	if fmt.Sprintf("sha-256:%x", h.Sum(nil)) != si.Hash {
+		return si, errors.New("hash not correct")
+	}
+	return si, nil
+}
+
+// deleteShard removing all files of the shard
+func (s *BlobStorage) deleteShard(id string) error {
+	sf, _ := s.buildFilenameV2(id, ShardExt)
+	err := os.Remove(sf)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	err = s.DeleteBlob(id)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// storeShards splitting the blob stripe by stripe into data shards and calculating the parity shards.
+// Every shard is stored on a different volume.
+func (s *MultiVolumeStorage) storeShards(b *model.BlobDescription, r io.Reader) (string, error) {
+	k, m := s.codec.DataShards(), s.codec.ParityShards()
+	hs, err := s.selectVolumes(k+m, nil)
+	if err != nil {
+		return "", err
+	}
+	if b.BlobID == "" {
+		b.BlobID = utils.GenerateID()
+	}
+	id := b.BlobID
+	bs := shardBlockSize
+	if b.ContentLength > 0 && b.ContentLength < int64(k*bs) {
+		bs = int((b.ContentLength + int64(k) - 1) / int64(k))
+	}
+
+	sws := make([]*shardWriter, k+m)
+	cleanup := func() {
+		abortShardWriters(sws)
+		for _, h := range hs {
+			_ = h.srv.deleteShard(id)
+		}
+	}
+	for i, h := range hs {
+		sws[i], err = newShardWriter(h.srv, id)
+		if err != nil {
+			cleanup()
+			return "", err
+		}
+	}
+	bh := sha256.New()
+	var size int64
+	buf := make([]byte, k*bs)
+	shards := make([][]byte, k+m)
+	for {
+		n, rerr := io.ReadFull(r, buf)
+		if n > 0 {
+			bh.Write(buf[:n])
+			size += int64(n)
+			for x := n; x < len(buf); x++ {
+				buf[x] = 0
+			}
+			for i := 0; i < k; i++ {
+				shards[i] = buf[i*bs : (i+1)*bs]
+			}
+			err = s.codec.Encode(shards)
+			if err != nil {
+				cleanup()
+				return "", err
+			}
+			for i, sw := range sws {
+				err = sw.write(shards[i])
+				if err != nil {
+					cleanup()
+					return "", err
+				}
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			cleanup()
+			return "", rerr
+		}
+	}
+	if (b.ContentLength > 0) && b.ContentLength != size {
+		cleanup()
+		return "", fmt.Errorf("wrong content length %d=%d", b.ContentLength, size)
+	}
+	b.ContentLength = size
+	b.Hash = fmt.Sprintf("sha-256:%x", bh.Sum(nil))
+	for i, h := range hs {
+		err = h.srv.writeShard(b, shardInfo{
+			Index:        i,
+			DataShards:   k,
+			ParityShards: m,
+			BlockSize:    bs,
+			Size:         size,
+			Hash:         sws[i].hash(),
+		}, sws[i])
+		if err != nil {
+			cleanup()
+			return "", err
+		}
+	}
+	s.locs.set(id, joinHolders(hs))
+	return id, nil
+}
+
+// openShards opening all available shards of a blob, the readers are sorted by the shard index
+func (s *MultiVolumeStorage) openShards(id string, hs []holder) ([]*os.File, *shardInfo, error) {
+	var si *shardInfo
+	var rs []*os.File
+	for _, h := range hs {
+		i, err := h.srv.getShardInfo(id)
+		if err != nil {
+			logger.Errorf("sfmv: can't read shard info of %s on volume %s: %v", id, h.name, err)
+			continue
+		}
+		if si == nil {
+			si = i
+			rs = make([]*os.File, i.DataShards+i.ParityShards)
+		}
+		if i.Index < 0 || i.Index >= len(rs) || rs[i.Index] != nil {
+			continue
+		}
+		f, err := os.Open(h.srv.getBinV2(id))
+		if err != nil {
+			logger.Errorf("sfmv: can't open shard of %s on volume %s: %v", id, h.name, err)
+			continue
+		}
+		rs[i.Index] = f
+	}
+	if si == nil {
+		return nil, nil, ErrSrvNotFound
+	}
+	count := 0
+	for _, f := range rs {
+		if f != nil {
+			count++
+		}
+	}
+	if count < si.DataShards {
+		closeShardReaders(rs)
+		return nil, nil, fmt.Errorf("blob %s, %d of %d shards: %w", id, count, si.DataShards, erasure.ErrTooFewShards)
+	}
+	return rs, si, nil
+}
+
+// codec4 getting the codec for the shard info, the configuration may have changed since the blob was stored
+func (s *MultiVolumeStorage) codec4(si *shardInfo) (*erasure.Codec, error) {
+	if s.codec != nil && s.codec.DataShards() == si.DataShards && s.codec.ParityShards() == si.ParityShards {
+		return s.codec, nil
+	}
+	return erasure.New(si.DataShards, si.ParityShards)
+}
+
+// readStripe reading the next stripe of all shards, missing shards will be reconstructed if needed
+func readStripe(codec *erasure.Codec, rs []*os.File, buf, shards [][]byte, all bool) (bool, error) {
+	k := codec.DataShards()
+	degraded := false
+	missing := false
+	for i, f := range rs {
+		shards[i] = nil
+		if f == nil {
+			degraded = true
+			missing = missing || i < k
+			continue
+		}
+		_, err := io.ReadFull(f, buf[i])
+		if err != nil {
+			logger.Errorf("sfmv: error reading shard %d: %v", i, err)
+			_ = f.Close()
+			rs[i] = nil
+			degraded = true
+			missing = missing || i < k
+			continue
+		}
+		shards[i] = buf[i]
+	}
+	if missing || (all && degraded) {
+		return degraded, codec.Reconstruct(shards)
+	}
+	return degraded, nil
+}
+
+// retrieveShards reading the blob from the data shards, missing data shards are reconstructed from the parity shards
+func (s *MultiVolumeStorage) retrieveShards(id string, hs []holder, w io.Writer) error {
+	rs, si, err := s.openShards(id, hs)
+	if err != nil {
+		return err
+	}
+	defer closeShardReaders(rs)
+	codec, err := s.codec4(si)
+	if err != nil {
+		return err
+	}
+	buf := make([][]byte, len(rs))
+	for i := range buf {
+		buf[i] = make([]byte, si.BlockSize)
+	}
+	shards := make([][]byte, len(rs))
+	degraded := false
+	remaining := si.Size
+	for remaining > 0 {
+		d, err := readStripe(codec, rs, buf, shards, false)
+		if err != nil {
+			return err
+		}
+		degraded = degraded || d
+		for i := 0; i < si.DataShards && remaining > 0; i++ {
+			n := int64(len(shards[i]))
+			if n > remaining {
+				n = remaining
+			}
+			if _, err := w.Write(shards[i][:n]); err != nil {
+				return err
+			}
+			remaining -= n
+		}
+	}
+	if degraded || len(hs) < len(rs) {
+		s.healAsync(id)
+	}
+	return nil
+}
+
+// checkShards checking the hashes of all shards and of the whole blob
+func (s *MultiVolumeStorage) checkShards(id string) (*model.CheckInfo, error) {
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return nil, os.ErrNotExist
+	}
+	now := time.Now()
+	res := model.CheckInfo{
+		LastCheck: &now,
+		Healthy:   true,
+	}
+	msgs := make([]string, 0)
+	var si *shardInfo
+	good := make(map[int]bool)
+	for _, h := range hs {
+		i, err := h.srv.checkShard(id)
+		if i != nil && si == nil {
+			si = i
+		}
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", h.name, err))
+			continue
+		}
+		good[i.Index] = true
+	}
+	if si == nil {
+		return nil, ErrSrvNotFound
+	}
+	total := si.DataShards + si.ParityShards
+	if len(good) < total {
+		res.Healthy = false
+		state := "degraded"
+		if len(good) < si.DataShards {
+			state = "lost"
+		}
+		msgs = append([]string{fmt.Sprintf("%s: %d of %d shards healthy", state, len(good), total)}, msgs...)
+		s.healAsync(id)
+	}
+	if len(good) >= si.DataShards {
+		bd, err := hs[0].srv.GetBlobDescription(id)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := utils.BuildHash(id, s)
+		if err != nil {
+			return nil, err
+		}
+		if hash != bd.Hash {
+			res.Healthy = false
+			msgs = append(msgs, "hash not correct")
+		}
+	}
+	res.Message = strings.Join(msgs, ", ")
+	return &res, nil
+}
+
+// healShards rebuilding missing or corrupt shards of the blob on other volumes
+func (s *MultiVolumeStorage) healShards(id string) (bool, error) {
+	hs := s.probe(id, s.volumeNames())
+	if len(hs) == 0 {
+		return false, nil
+	}
+	var si *shardInfo
+	good := make(map[int]holder)
+	bad := make([]holder, 0)
+	for _, h := range hs {
+		i, err := h.srv.checkShard(id)
+		if i != nil && si == nil {
+			si = i
+		}
+		if err != nil {
+			bad = append(bad, h)
+			continue
+		}
+		if _, ok := good[i.Index]; ok {
+			bad = append(bad, h)
+			continue
+		}
+		good[i.Index] = h
+	}
+	if si == nil {
+		return false, fmt.Errorf("no shard info for blob %s found", id)
+	}
+	total := si.DataShards + si.ParityShards
+	if len(good) < si.DataShards {
+		return false, fmt.Errorf("blob %s, %d of %d shards: %w", id, len(good), si.DataShards, erasure.ErrTooFewShards)
+	}
+	// the corrupt or duplicate shards are not needed anymore
+	for _, h := range bad {
+		logger.Errorf("sfmv: removing corrupt or duplicate shard of %s on volume %s", id, h.name)
+		_ = h.srv.deleteShard(id)
+	}
+	goods := make([]holder, 0, total)
+	for _, h := range good {
+		goods = append(goods, h)
+	}
+	if len(good) == total {
+		s.locs.set(id, joinHolders(goods))
+		return len(bad) > 0, nil
+	}
+
+	exclude := make([]string, 0, len(goods))
+	for _, h := range goods {
+		exclude = append(exclude, h.name)
+	}
+	targets, err := s.selectVolumes(total-len(good), exclude)
+	if err != nil {
+		s.locs.set(id, joinHolders(goods))
+		return false, err
+	}
+	rs, _, err := s.openShards(id, goods)
+	if err != nil {
+		return false, err
+	}
+	defer closeShardReaders(rs)
+	codec, err := s.codec4(si)
+	if err != nil {
+		return false, err
+	}
+	// writers for the missing shards
+	sws := make([]*shardWriter, total)
+	tgt := make(map[int]holder)
+	cleanup := func() {
+		abortShardWriters(sws)
+		for _, h := range tgt {
+			_ = h.srv.deleteShard(id)
+		}
+	}
+	x := 0
+	for i := 0; i < total; i++ {
+		if rs[i] != nil {
+			continue
+		}
+		tgt[i] = targets[x]
+		sws[i], err = newShardWriter(targets[x].srv, id)
+		if err != nil {
+			cleanup()
+			return false, err
+		}
+		x++
+	}
+	buf := make([][]byte, total)
+	for i := range buf {
+		buf[i] = make([]byte, si.BlockSize)
+	}
+	shards := make([][]byte, total)
+	for st := int64(0); st < si.stripes(); st++ {
+		_, err := readStripe(codec, rs, buf, shards, true)
+		if err != nil {
+			cleanup()
+			return false, err
+		}
+		for i, sw := range sws {
+			if sw == nil {
+				continue
+			}
+			if err := sw.write(shards[i]); err != nil {
+				cleanup()
+				return false, err
+			}
+		}
+	}
+	src := goods[0].srv
+	bd, err := src.GetBlobDescription(id)
+	if err != nil {
+		cleanup()
+		return false, err
+	}
+	for i, h := range tgt {
+		nsi := *si
+		nsi.Index = i
+		nsi.Hash = sws[i].hash()
+		err = h.srv.writeShard(bd, nsi, sws[i])
+		if err == nil {
+			err = copyRetention(src, h.srv, id)
+		}
+		if err != nil {
+			cleanup()
+			return false, err
+		}
+		goods = append(goods, h)
+	}
+	s.locs.set(id, joinHolders(goods))
+	return true, nil
+}
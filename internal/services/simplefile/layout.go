@@ -0,0 +1,134 @@
+package simplefile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// names of the known layouts
+const (
+	LayoutV1 = "v1" // legacy flat layout, all files direct in the tenant folder
+	LayoutV2 = "v2" // two level layout, the folders are build from the first 4 characters of the id
+)
+
+// Layout the arrangement of the blob files in a tenant folder, the retention files are the same for every layout
+type Layout interface {
+	// Name the name of the layout
+	Name() string
+	// Blobs walking thru the ids of all blobs with a description in this layout
+	Blobs(tntPath string, callback func(id string) bool) error
+	// Filename the name of the file of the blob with the extension
+	Filename(tntPath, id, ext string) string
+}
+
+var (
+	layouts = map[string]Layout{
+		LayoutV1: layoutV1{},
+		LayoutV2: layoutV2{},
+	}
+	lm sync.RWMutex
+)
+
+// RegisterLayout registering a new layout, so tenants can be migrated into or out of it
+func RegisterLayout(l Layout) {
+	lm.Lock()
+	defer lm.Unlock()
+	layouts[strings.ToLower(l.Name())] = l
+}
+
+// GetLayout getting the layout with the name
+func GetLayout(name string) (Layout, error) {
+	lm.RLock()
+	defer lm.RUnlock()
+	l, ok := layouts[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown layout: %s", name)
+	}
+	return l, nil
+}
+
+// Layouts getting the names of all registered layouts
+func Layouts() []string {
+	lm.RLock()
+	defer lm.RUnlock()
+	names := make([]string, 0, len(layouts))
+	for n := range layouts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type layoutV1 struct{}
+
+func (layoutV1) Name() string {
+	return LayoutV1
+}
+
+func (layoutV1) Blobs(tntPath string, callback func(id string) bool) error {
+	entries, err := os.ReadDir(tntPath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), DescriptionExt) {
+			continue
+		}
+		if !callback(strings.TrimSuffix(e.Name(), DescriptionExt)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (layoutV1) Filename(tntPath, id, ext string) string {
+	return filepath.Join(tntPath, id+ext)
+}
+
+type layoutV2 struct{}
+
+func (layoutV2) Name() string {
+	return LayoutV2
+}
+
+func (layoutV2) Blobs(tntPath string, callback func(id string) bool) error {
+	l1, err := os.ReadDir(tntPath)
+	if err != nil {
+		return err
+	}
+	for _, d1 := range l1 {
+		if !d1.IsDir() || len(d1.Name()) != 2 {
+			continue
+		}
+		l2, err := os.ReadDir(filepath.Join(tntPath, d1.Name()))
+		if err != nil {
+			return err
+		}
+		for _, d2 := range l2 {
+			if !d2.IsDir() || len(d2.Name()) != 2 {
+				continue
+			}
+			entries, err := os.ReadDir(filepath.Join(tntPath, d1.Name(), d2.Name()))
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				if e.IsDir() || !strings.HasSuffix(e.Name(), DescriptionExt) {
+					continue
+				}
+				if !callback(strings.TrimSuffix(e.Name(), DescriptionExt)) {
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (layoutV2) Filename(tntPath, id, ext string) string {
+	return filepath.Join(tntPath, id[:2], id[2:4], id+ext)
+}
@@ -0,0 +1,383 @@
+package simplefile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// maximum count of errors in the report of a tenant
+const maxLayoutErrors = 1000
+
+// ErrLayoutJobRunning there is already a layout migration running
+var ErrLayoutJobRunning = errors.New("a layout migration is already running")
+
+// LayoutOptions the options of a layout migration
+type LayoutOptions struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Tenants []string `json:"tenants,omitempty"` // the tenants to migrate, empty for all tenants
+	DryRun  bool     `json:"dryRun"`            // only checking, what would be migrated
+	Verify  bool     `json:"verify"`            // verifying the hash of every binary against the description
+}
+
+// LayoutReport the report of a layout migration
+type LayoutReport struct {
+	ID        string               `json:"id"`
+	Options   LayoutOptions        `json:"options"`
+	Running   bool                 `json:"running"`
+	Cancelled bool                 `json:"cancelled"`
+	Started   time.Time            `json:"started"`
+	Finished  time.Time            `json:"finished"`
+	Tenants   []TenantLayoutReport `json:"tenants"`
+	LastError string               `json:"lastError,omitempty"`
+}
+
+// TenantLayoutReport the report of the migration of a single tenant
+type TenantLayoutReport struct {
+	Tenant    string        `json:"tenant"`
+	Total     int           `json:"total"`
+	Converted int           `json:"converted"` // blobs converted, on a dry run the blobs which would be converted
+	Skipped   int           `json:"skipped"`   // blobs, which are already present in the target layout, e.g. from an interrupted run
+	Failed    int           `json:"failed"`
+	Bytes     int64         `json:"bytes"`
+	Errors    []LayoutError `json:"errors,omitempty"`
+}
+
+// LayoutError the error of a single blob
+type LayoutError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// LayoutMigrator converting the tenants of a simple file storage from one layout into another.
+// Every blob is copied, verified and then removed from the source layout, so an interrupted run can simply be started again.
+type LayoutMigrator struct {
+	RootPath string // root path of the simple file storage
+	Sync     bool   // syncing files and directories to disk after writing
+	job      *layoutJob
+	jm       sync.Mutex
+}
+
+// layoutJob a running or finished layout migration
+type layoutJob struct {
+	LayoutReport
+	cancel context.CancelFunc
+	m      sync.Mutex
+}
+
+func (j *layoutJob) report() LayoutReport {
+	j.m.Lock()
+	defer j.m.Unlock()
+	r := j.LayoutReport
+	r.Tenants = make([]TenantLayoutReport, len(j.Tenants))
+	for i, t := range j.Tenants {
+		r.Tenants[i] = t
+		r.Tenants[i].Errors = append([]LayoutError(nil), t.Errors...)
+	}
+	return r
+}
+
+// update changing the report of the actual tenant
+func (j *layoutJob) update(f func(t *TenantLayoutReport)) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	f(&j.Tenants[len(j.Tenants)-1])
+}
+
+// Start starting the migration as background job
+func (l *LayoutMigrator) Start(opts LayoutOptions) (LayoutReport, error) {
+	from, to, err := l.layouts(opts)
+	if err != nil {
+		return LayoutReport{}, err
+	}
+	l.jm.Lock()
+	defer l.jm.Unlock()
+	if l.job != nil && l.job.report().Running {
+		return LayoutReport{}, ErrLayoutJobRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := newLayoutJob(opts, cancel)
+	l.job = j
+	go func() {
+		defer cancel()
+		l.run(ctx, j, from, to)
+		logger.Infof("layout migration %s finished", j.ID)
+	}()
+	return j.report(), nil
+}
+
+// Job getting the report of the actual or last migration
+func (l *LayoutMigrator) Job() (LayoutReport, bool) {
+	l.jm.Lock()
+	defer l.jm.Unlock()
+	if l.job == nil {
+		return LayoutReport{}, false
+	}
+	return l.job.report(), true
+}
+
+// Cancel cancelling the running migration, the blob in work is finished
+func (l *LayoutMigrator) Cancel() bool {
+	l.jm.Lock()
+	defer l.jm.Unlock()
+	if l.job == nil || !l.job.report().Running {
+		return false
+	}
+	l.job.cancel()
+	return true
+}
+
+// Run running the migration synchronously, the context can be used for cancelling
+func (l *LayoutMigrator) Run(ctx context.Context, opts LayoutOptions) (LayoutReport, error) {
+	from, to, err := l.layouts(opts)
+	if err != nil {
+		return LayoutReport{}, err
+	}
+	j := newLayoutJob(opts, nil)
+	l.run(ctx, j, from, to)
+	return j.report(), nil
+}
+
+func newLayoutJob(opts LayoutOptions, cancel context.CancelFunc) *layoutJob {
+	return &layoutJob{
+		LayoutReport: LayoutReport{
+			ID:      utils.GenerateID(),
+			Options: opts,
+			Running: true,
+			Started: time.Now(),
+			Tenants: make([]TenantLayoutReport, 0),
+		},
+		cancel: cancel,
+	}
+}
+
+func (l *LayoutMigrator) layouts(opts LayoutOptions) (Layout, Layout, error) {
+	from, err := GetLayout(opts.From)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := GetLayout(opts.To)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from.Name() == to.Name() {
+		return nil, nil, errors.New("source and target layout are the same")
+	}
+	return from, to, nil
+}
+
+// tenants getting the tenants to migrate
+func (l *LayoutMigrator) tenants(opts LayoutOptions) ([]string, error) {
+	if len(opts.Tenants) > 0 {
+		return opts.Tenants, nil
+	}
+	entries, err := os.ReadDir(l.RootPath)
+	if err != nil {
+		return nil, err
+	}
+	tenants := make([]string, 0)
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), "_") {
+			tenants = append(tenants, e.Name())
+		}
+	}
+	return tenants, nil
+}
+
+func (l *LayoutMigrator) run(ctx context.Context, j *layoutJob, from, to Layout) {
+	defer func() {
+		j.m.Lock()
+		j.Running = false
+		j.Cancelled = ctx.Err() != nil
+		j.Finished = time.Now()
+		j.m.Unlock()
+	}()
+	tenants, err := l.tenants(j.Options)
+	if err != nil {
+		j.m.Lock()
+		j.LastError = err.Error()
+		j.m.Unlock()
+		return
+	}
+	for _, tenant := range tenants {
+		if ctx.Err() != nil {
+			return
+		}
+		j.m.Lock()
+		j.Tenants = append(j.Tenants, TenantLayoutReport{Tenant: tenant})
+		j.m.Unlock()
+		l.migrateTenant(ctx, j, tenant, from, to)
+	}
+}
+
+func (l *LayoutMigrator) migrateTenant(ctx context.Context, j *layoutJob, tenant string, from, to Layout) {
+	tntPath := filepath.Join(l.RootPath, tenant)
+	// the ids are collected first, because the migration changes the folders
+	ids := make([]string, 0)
+	err := from.Blobs(tntPath, func(id string) bool {
+		ids = append(ids, id)
+		return true
+	})
+	if err != nil {
+		j.update(func(t *TenantLayoutReport) {
+			t.Errors = append(t.Errors, LayoutError{Error: err.Error()})
+		})
+		return
+	}
+	j.update(func(t *TenantLayoutReport) {
+		t.Total = len(ids)
+	})
+	logger.Infof("layout migration: tenant %s, %d blobs from %s to %s", tenant, len(ids), from.Name(), to.Name())
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		size, skipped, err := l.migrateBlob(tntPath, id, from, to, j.Options)
+		j.update(func(t *TenantLayoutReport) {
+			switch {
+			case err != nil:
+				t.Failed++
+				if len(t.Errors) < maxLayoutErrors {
+					t.Errors = append(t.Errors, LayoutError{ID: id, Error: err.Error()})
+				}
+			case skipped:
+				t.Skipped++
+			default:
+				t.Converted++
+				t.Bytes += size
+			}
+		})
+		if err != nil {
+			logger.Errorf("layout migration: tenant %s, blob %s: %v", tenant, id, err)
+		}
+	}
+}
+
+// migrateBlob copying the binary and the description into the target layout and removing the source files afterwards.
+// If the blob is already complete in the target layout, only the source files are removed.
+func (l *LayoutMigrator) migrateBlob(tntPath, id string, from, to Layout, opts LayoutOptions) (int64, bool, error) {
+	if len(id) < 4 {
+		return 0, false, fmt.Errorf("invalid id: %s", id)
+	}
+	srcBin, srcDesc := from.Filename(tntPath, id, BinaryExt), from.Filename(tntPath, id, DescriptionExt)
+	dstBin, dstDesc := to.Filename(tntPath, id, BinaryExt), to.Filename(tntPath, id, DescriptionExt)
+	js, err := os.ReadFile(srcDesc)
+	if err != nil {
+		return 0, false, err
+	}
+	var bd model.BlobDescription
+	err = json.Unmarshal(js, &bd)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid description: %v", err)
+	}
+
+	if _, err := os.Stat(dstDesc); err == nil {
+		// resuming an interrupted migration
+		hash, _, err := hashFile(dstBin)
+		if err != nil {
+			return 0, false, fmt.Errorf("blob already present in layout %s: %v", to.Name(), err)
+		}
+		if !equalHash(bd.Hash, hash) {
+			return 0, false, fmt.Errorf("blob already present in layout %s with a different hash", to.Name())
+		}
+		if !opts.DryRun {
+			err = removeFiles(srcBin, srcDesc)
+		}
+		return 0, true, err
+	}
+
+	if opts.DryRun {
+		hash, size, err := hashFile(srcBin)
+		if err != nil {
+			return 0, false, err
+		}
+		if opts.Verify && !equalHash(bd.Hash, hash) {
+			return 0, false, fmt.Errorf("hash not correct: %s != %s", bd.Hash, hash)
+		}
+		return size, false, nil
+	}
+
+	size, err := l.copyBin(srcBin, dstBin, bd.Hash, opts.Verify)
+	if err != nil {
+		return 0, false, err
+	}
+	// the description is written last, so the blob is complete in the target layout, before the source is removed
+	err = writeFileAtomic(dstDesc, js, l.Sync)
+	if err != nil {
+		_ = os.Remove(dstBin)
+		return 0, false, err
+	}
+	return size, false, removeFiles(srcBin, srcDesc)
+}
+
+func (l *LayoutMigrator) copyBin(src, dst, hash string, verify bool) (int64, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+	tmp, err := createTmpFile(dst, l.Sync)
+	if err != nil {
+		return 0, err
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), f)
+	if err != nil {
+		tmp.abort()
+		return 0, err
+	}
+	act := fmt.Sprintf("sha-256:%x", h.Sum(nil))
+	if verify && !equalHash(hash, act) {
+		tmp.abort()
+		return 0, fmt.Errorf("hash not correct: %s != %s", hash, act)
+	}
+	return size, tmp.commit()
+}
+
+// equalHash comparing the hash of the description with the calculated one, a description without a sha-256 hash can't be verified
+func equalHash(hash, act string) bool {
+	if !strings.HasPrefix(hash, "sha-256:") {
+		return true
+	}
+	return strings.EqualFold(hash, act)
+}
+
+func hashFile(name string) (string, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("sha-256:%x", h.Sum(nil)), size, nil
+}
+
+func removeFiles(names ...string) error {
+	for _, n := range names {
+		err := os.Remove(n)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
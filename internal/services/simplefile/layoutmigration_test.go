@@ -0,0 +1,179 @@
+package simplefile
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const v1id = "004b4987-42fb-43e4-8e13-d6994ce0e6f1"
+
+func v1Files() []string {
+	tntPath := filepath.Join(rootpath, tenant)
+	return []string{
+		filepath.Join(tntPath, v1id+BinaryExt),
+		filepath.Join(tntPath, v1id+DescriptionExt),
+	}
+}
+
+func v2Files() []string {
+	tntPath := filepath.Join(rootpath, tenant)
+	return []string{
+		filepath.Join(tntPath, "00", "4b", v1id+BinaryExt),
+		filepath.Join(tntPath, "00", "4b", v1id+DescriptionExt),
+	}
+}
+
+func v1v2() LayoutOptions {
+	return LayoutOptions{
+		From:    LayoutV1,
+		To:      LayoutV2,
+		Tenants: []string{tenant},
+		Verify:  true,
+	}
+}
+
+func TestLayouts(t *testing.T) {
+	ast := assert.New(t)
+	ast.Equal([]string{LayoutV1, LayoutV2}, Layouts())
+	_, err := GetLayout("v3")
+	ast.NotNil(err)
+	l, err := GetLayout("V2")
+	ast.Nil(err)
+	ast.Equal(filepath.Join("tnt", "01", "23", "0123.bin"), l.Filename("tnt", "0123", BinaryExt))
+
+	mig := LayoutMigrator{RootPath: rootpath}
+	_, err = mig.Run(context.Background(), LayoutOptions{From: LayoutV1, To: LayoutV1})
+	ast.NotNil(err)
+}
+
+func TestLayoutDryRun(t *testing.T) {
+	ast := assert.New(t)
+	initTest(t)
+	mig := LayoutMigrator{RootPath: rootpath}
+
+	opts := v1v2()
+	opts.DryRun = true
+	r, err := mig.Run(context.Background(), opts)
+	ast.Nil(err)
+	ast.False(r.Running)
+	ast.Len(r.Tenants, 1)
+	ast.Equal(1, r.Tenants[0].Total)
+	ast.Equal(1, r.Tenants[0].Converted)
+	ast.Equal(int64(32768), r.Tenants[0].Bytes)
+	for _, f := range v1Files() {
+		ast.FileExists(f)
+	}
+	for _, f := range v2Files() {
+		ast.NoFileExists(f)
+	}
+}
+
+func TestLayoutMigration(t *testing.T) {
+	ast := assert.New(t)
+	initTest(t)
+	mig := LayoutMigrator{RootPath: rootpath, Sync: true}
+
+	r, err := mig.Run(context.Background(), v1v2())
+	ast.Nil(err)
+	ast.Equal(1, r.Tenants[0].Converted)
+	ast.Equal(0, r.Tenants[0].Failed)
+	for _, f := range v1Files() {
+		ast.NoFileExists(f)
+	}
+	for _, f := range v2Files() {
+		ast.FileExists(f)
+	}
+
+	// the blob is readable without the v1 layout
+	srv := BlobStorage{RootPath: rootpath, Tenant: tenant, DisableV1: true}
+	ast.Nil(srv.Init())
+	ci, err := srv.CheckBlob(v1id)
+	ast.Nil(err)
+	ast.True(ci.Healthy)
+
+	// nothing more to do
+	r, err = mig.Run(context.Background(), v1v2())
+	ast.Nil(err)
+	ast.Equal(0, r.Tenants[0].Total)
+}
+
+func TestLayoutResume(t *testing.T) {
+	ast := assert.New(t)
+	initTest(t)
+	mig := LayoutMigrator{RootPath: rootpath}
+	// interrupted after writing the target, before removing the source
+	src := v1Files()
+	dst := v2Files()
+	for i := range src {
+		data, err := os.ReadFile(src[i])
+		ast.Nil(err)
+		ast.Nil(os.MkdirAll(filepath.Dir(dst[i]), os.ModePerm))
+		ast.Nil(os.WriteFile(dst[i], data, os.ModePerm))
+	}
+
+	r, err := mig.Run(context.Background(), v1v2())
+	ast.Nil(err)
+	ast.Equal(1, r.Tenants[0].Skipped)
+	for _, f := range src {
+		ast.NoFileExists(f)
+	}
+	for _, f := range dst {
+		ast.FileExists(f)
+	}
+}
+
+func TestLayoutWrongHash(t *testing.T) {
+	ast := assert.New(t)
+	initTest(t)
+	mig := LayoutMigrator{RootPath: rootpath}
+	ast.Nil(os.WriteFile(v1Files()[0], []byte("corrupt"), os.ModePerm))
+
+	r, err := mig.Run(context.Background(), v1v2())
+	ast.Nil(err)
+	ast.Equal(1, r.Tenants[0].Failed)
+	ast.Len(r.Tenants[0].Errors, 1)
+	ast.Equal(v1id, r.Tenants[0].Errors[0].ID)
+	for _, f := range v1Files() {
+		ast.FileExists(f)
+	}
+	for _, f := range v2Files() {
+		ast.NoFileExists(f)
+	}
+
+	// without verification the blob is migrated as it is
+	opts := v1v2()
+	opts.Verify = false
+	r, err = mig.Run(context.Background(), opts)
+	ast.Nil(err)
+	ast.Equal(1, r.Tenants[0].Converted)
+	data, err := os.ReadFile(v2Files()[0])
+	ast.Nil(err)
+	ast.True(bytes.Equal([]byte("corrupt"), data))
+}
+
+func TestLayoutJob(t *testing.T) {
+	ast := assert.New(t)
+	initTest(t)
+	mig := LayoutMigrator{RootPath: rootpath}
+	_, ok := mig.Job()
+	ast.False(ok)
+	ast.False(mig.Cancel())
+
+	j, err := mig.Start(v1v2())
+	ast.Nil(err)
+	ast.NotEmpty(j.ID)
+	for x := 0; x < 500 && j.Running; x++ {
+		time.Sleep(10 * time.Millisecond)
+		j, ok = mig.Job()
+		ast.True(ok)
+	}
+	ast.False(j.Running)
+	ast.False(j.Cancelled)
+	ast.Equal(1, j.Tenants[0].Converted)
+}
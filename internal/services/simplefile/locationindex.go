@@ -0,0 +1,247 @@
+package simplefile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/akgarhwal/bloomfilter/bloomfilter"
+)
+
+// markers of the location index file
+const (
+	locVolumesMarker = "#volumes "
+	locClosedMarker  = "#closed"
+	locMinBloomSize  = 100000
+)
+
+// locationIndex a persistent map of blob ids to the volumes holding the blob, multiple volumes are separated by comma.
+// The index is stored as a journal file, every store appends a "+id volume" line, every delete a "-id" line.
+// On load the journal is replayed and compacted. A bloom filter of all known ids is used for fast negative lookups.
+type locationIndex struct {
+	filename string
+	locs     map[string]string
+	bf       *bloomfilter.BloomFilter
+	bfSize   int
+	file     *os.File
+	ready    bool
+	closed   bool
+	m        sync.Mutex
+}
+
+// newLocationIndex loading the index from the file. If the file is missing or stale (other volumes,
+// unclean shutdown), false is returned and the index should be rebuilt by scanning the volumes.
+// Without a filename the index is only held in memory.
+func newLocationIndex(filename string, volumes []string) (*locationIndex, bool) {
+	l := &locationIndex{
+		filename: filename,
+		locs:     make(map[string]string),
+	}
+	if filename == "" {
+		l.resetBloom()
+		return l, false
+	}
+	ok, err := l.load(volumes)
+	if err != nil {
+		logger.Infof("location index %s will be rebuild: %v", filename, err)
+		l.locs = make(map[string]string)
+	}
+	l.ready = ok && err == nil
+	l.resetBloom()
+	return l, l.ready
+}
+
+func (l *locationIndex) load(volumes []string) (bool, error) {
+	f, err := os.Open(l.filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return false, errors.New("empty location index")
+	}
+	if scanner.Text() != locVolumesMarker+strings.Join(volumes, ",") {
+		return false, errors.New("volumes have changed")
+	}
+	closed := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		closed = line == locClosedMarker
+		switch {
+		case strings.HasPrefix(line, "+"):
+			id, vol, ok := strings.Cut(line[1:], " ")
+			if ok {
+				l.locs[id] = vol
+			}
+		case strings.HasPrefix(line, "-"):
+			delete(l.locs, line[1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+	if !closed {
+		return false, errors.New("location index was not closed")
+	}
+	return true, nil
+}
+
+// rebuild merging the scanned locations into the index, entries added while scanning will be preserved
+func (l *locationIndex) rebuild(locs map[string]string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	for id, vol := range locs {
+		if _, ok := l.locs[id]; !ok {
+			l.locs[id] = vol
+		}
+	}
+	l.ready = true
+	l.resetBloom()
+}
+
+// isReady checking if the index is loaded or rebuild
+func (l *locationIndex) isReady() bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.ready
+}
+
+// open writing a compacted version of the index and opening it for appending new entries
+func (l *locationIndex) open(volumes []string) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed || l.filename == "" {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(l.filename), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(l.locs))
+	for id := range l.locs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	tmp := l.filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "%s%s\n", locVolumesMarker, strings.Join(volumes, ","))
+	for _, id := range ids {
+		fmt.Fprintf(w, "+%s %s\n", id, l.locs[id])
+	}
+	err = w.Flush()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, l.filename)
+	if err != nil {
+		return err
+	}
+	l.file, err = os.OpenFile(l.filename, os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
+
+// get getting the volume of the blob, maybe is false, if the bloom filter says the blob is unknown.
+// While the index is rebuilding, every blob is maybe present.
+func (l *locationIndex) get(id string) (vol string, maybe bool) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	vol, ok := l.locs[id]
+	if ok {
+		return vol, true
+	}
+	return "", !l.ready || l.bf.Lookup([]byte(id))
+}
+
+// set adding or changing the location of a blob
+func (l *locationIndex) set(id, vol string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if v, ok := l.locs[id]; ok && v == vol {
+		return
+	}
+	l.locs[id] = vol
+	if len(l.locs) > l.bfSize {
+		l.resetBloom()
+	} else {
+		l.bf.Insert([]byte(id))
+	}
+	l.write(fmt.Sprintf("+%s %s\n", id, vol))
+}
+
+// remove removing the location of a blob
+func (l *locationIndex) remove(id string) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if _, ok := l.locs[id]; !ok {
+		return
+	}
+	delete(l.locs, id)
+	l.write(fmt.Sprintf("-%s\n", id))
+}
+
+// find getting all blobs with a location on the volume
+func (l *locationIndex) find(vol string) []string {
+	l.m.Lock()
+	defer l.m.Unlock()
+	ids := make([]string, 0)
+	for id, vols := range l.locs {
+		for _, v := range strings.Split(vols, ",") {
+			if v == vol {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// close marking the index as cleanly closed
+func (l *locationIndex) close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	l.write(locClosedMarker + "\n")
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *locationIndex) write(line string) {
+	if l.file == nil {
+		return
+	}
+	_, err := l.file.WriteString(line)
+	if err != nil {
+		logger.Errorf("location index: error writing %s: %v", l.filename, err)
+	}
+}
+
+// resetBloom creating a new bloom filter with all known ids, with room for growing
+func (l *locationIndex) resetBloom() {
+	l.bfSize = 2 * len(l.locs)
+	if l.bfSize < locMinBloomSize {
+		l.bfSize = locMinBloomSize
+	}
+	l.bf = bloomfilter.NewBloomFilter(uint64(l.bfSize), 0.01)
+	for id := range l.locs {
+		l.bf.Insert([]byte(id))
+	}
+}
@@ -0,0 +1,281 @@
+package simplefile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/internal/utils/erasure"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// defining the redundancy modes of the multi volume storage
+const (
+	RedundancyNone     = "none"     // every blob is stored on exactly one volume
+	RedundancyReplicas = "replicas" // every blob is stored on Replicas different volumes
+	RedundancyErasure  = "erasure"  // every blob is split into DataShards + ParityShards reed solomon shards on different volumes
+)
+
+// ErrNotEnoughVolumes there are not enough writable volumes for the redundancy mode
+var ErrNotEnoughVolumes = errors.New("not enough writable volumes")
+
+// holder a volume holding a blob, a copy or a shard of a blob
+type holder struct {
+	name string
+	srv  *BlobStorage
+}
+
+func joinHolders(hs []holder) string {
+	names := make([]string, len(hs))
+	for i, h := range hs {
+		names[i] = h.name
+	}
+	return strings.Join(names, ",")
+}
+
+func (s *MultiVolumeStorage) initRedundancy() error {
+	switch strings.ToLower(s.Redundancy) {
+	case "", RedundancyNone:
+		s.Redundancy = RedundancyNone
+	case RedundancyReplicas:
+		s.Redundancy = RedundancyReplicas
+		if s.Replicas < 2 {
+			return fmt.Errorf("replicas should be at least 2, not %d", s.Replicas)
+		}
+	case RedundancyErasure:
+		s.Redundancy = RedundancyErasure
+		codec, err := erasure.New(s.DataShards, s.ParityShards)
+		if err != nil {
+			return err
+		}
+		s.codec = codec
+	default:
+		return fmt.Errorf("unknown redundancy mode: %s", s.Redundancy)
+	}
+	s.healing = make(map[string]bool)
+	return nil
+}
+
+func (s *MultiVolumeStorage) redundant() bool {
+	return s.Redundancy != RedundancyNone
+}
+
+// selectVolumes selecting count different writable volumes, with respect to the utilisation of the volumes
+func (s *MultiVolumeStorage) selectVolumes(count int, exclude []string) ([]holder, error) {
+	used := make(map[string]bool)
+	for _, name := range exclude {
+		used[name] = true
+	}
+	hs := make([]holder, 0, count)
+	add := func(name string) {
+		if name == "" || used[name] {
+			return
+		}
+		if srv := s.srv(name); srv != nil {
+			used[name] = true
+			hs = append(hs, holder{name: name, srv: srv})
+		}
+	}
+	for try := 0; len(hs) < count && try < 10*count; try++ {
+		add(s.volMan.SelectFree(s.volMan.Rnd()))
+	}
+	// the random selection has not found enough volumes, taking the remaining writable volumes
+	for _, vi := range s.volMan.Volumes() {
+		if len(hs) >= count {
+			break
+		}
+		if vi.Writable() {
+			add(vi.Name)
+		}
+	}
+	if len(hs) < count {
+		return nil, fmt.Errorf("%w: %d of %d", ErrNotEnoughVolumes, len(hs), count)
+	}
+	return hs, nil
+}
+
+// storeReplicas storing the blob on the first volume and copying it to the other volumes
+func (s *MultiVolumeStorage) storeReplicas(b *model.BlobDescription, f io.Reader) (string, error) {
+	hs, err := s.selectVolumes(s.Replicas, nil)
+	if err != nil {
+		return "", err
+	}
+	id, err := hs[0].srv.StoreBlob(b, f)
+	if err != nil {
+		return "", err
+	}
+	for _, h := range hs[1:] {
+		err = copyBlob(hs[0].srv, h.srv, id)
+		if err != nil {
+			for _, h := range hs {
+				_ = h.srv.DeleteBlob(id)
+			}
+			return "", err
+		}
+	}
+	s.locs.set(id, joinHolders(hs))
+	return id, nil
+}
+
+// checkReplicas checking the hashes of all copies of the blob
+func (s *MultiVolumeStorage) checkReplicas(id string) (*model.CheckInfo, error) {
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return nil, os.ErrNotExist
+	}
+	now := time.Now()
+	res := model.CheckInfo{
+		LastCheck: &now,
+		Healthy:   true,
+	}
+	msgs := make([]string, 0)
+	healthy := 0
+	for _, h := range hs {
+		ci, err := utils.CheckBlob(id, h.srv)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", h.name, err))
+			continue
+		}
+		if !ci.Healthy {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", h.name, ci.Message))
+			continue
+		}
+		healthy++
+	}
+	if healthy < s.Replicas {
+		res.Healthy = false
+		state := "degraded"
+		if healthy == 0 {
+			state = "lost"
+		}
+		msgs = append([]string{fmt.Sprintf("%s: %d of %d copies healthy", state, healthy, s.Replicas)}, msgs...)
+		s.healAsync(id)
+	}
+	res.Message = strings.Join(msgs, ", ")
+	return &res, nil
+}
+
+// healReplicas removing corrupt copies and creating new copies on other volumes
+func (s *MultiVolumeStorage) healReplicas(id string) (bool, error) {
+	hs := s.probe(id, s.volumeNames())
+	if len(hs) == 0 {
+		return false, nil
+	}
+	good := make([]holder, 0, len(hs))
+	bad := make([]holder, 0)
+	for _, h := range hs {
+		ci, err := utils.CheckBlob(id, h.srv)
+		if err != nil || !ci.Healthy {
+			bad = append(bad, h)
+			continue
+		}
+		good = append(good, h)
+	}
+	if len(good) == 0 {
+		return false, fmt.Errorf("no healthy copy of blob %s found", id)
+	}
+	for _, h := range bad {
+		logger.Errorf("sfmv: removing corrupt copy of %s on volume %s", id, h.name)
+		_ = h.srv.DeleteBlob(id)
+	}
+	if len(good) >= s.Replicas {
+		s.locs.set(id, joinHolders(good))
+		return len(bad) > 0, nil
+	}
+	exclude := make([]string, 0, len(good))
+	for _, h := range good {
+		exclude = append(exclude, h.name)
+	}
+	targets, err := s.selectVolumes(s.Replicas-len(good), exclude)
+	if err != nil {
+		s.locs.set(id, joinHolders(good))
+		return false, err
+	}
+	for _, h := range targets {
+		err = copyBlob(good[0].srv, h.srv, id)
+		if err != nil {
+			s.locs.set(id, joinHolders(good))
+			return false, err
+		}
+		good = append(good, h)
+	}
+	s.locs.set(id, joinHolders(good))
+	return true, nil
+}
+
+// heal rebuilding the missing or corrupt copies or shards of a blob
+func (s *MultiVolumeStorage) heal(id string) (bool, error) {
+	switch s.Redundancy {
+	case RedundancyReplicas:
+		return s.healReplicas(id)
+	case RedundancyErasure:
+		return s.healShards(id)
+	}
+	return false, nil
+}
+
+// healAsync healing the blob in the background, only one healing per blob at a time
+func (s *MultiVolumeStorage) healAsync(id string) {
+	if !s.redundant() {
+		return
+	}
+	s.cm.Lock()
+	if s.healing[id] {
+		s.cm.Unlock()
+		return
+	}
+	s.healing[id] = true
+	s.cm.Unlock()
+	go func() {
+		defer func() {
+			s.cm.Lock()
+			delete(s.healing, id)
+			s.cm.Unlock()
+		}()
+		s.logHeal(id)
+	}()
+}
+
+func (s *MultiVolumeStorage) logHeal(id string) bool {
+	healed, err := s.heal(id)
+	if err != nil {
+		logger.Errorf("sfmv: blob %s of tenant %s can't be healed: %v", id, s.Tenant, err)
+		return false
+	}
+	if healed {
+		logger.Infof("sfmv: blob %s of tenant %s healed", id, s.Tenant)
+	}
+	return healed
+}
+
+// Heal checking all blobs of this tenant and rebuilding missing or corrupt copies or shards, returns the count of healed blobs
+func (s *MultiVolumeStorage) Heal() (int, error) {
+	if !s.redundant() {
+		return 0, nil
+	}
+	count := 0
+	err := s.GetBlobs(func(id string) bool {
+		if s.logHeal(id) {
+			count++
+		}
+		return true
+	})
+	return count, err
+}
+
+// healVolume rebuilding the copies or shards of all blobs, which were stored on the removed volume
+func (s *MultiVolumeStorage) healVolume(name string) {
+	ids := s.locs.find(name)
+	logger.Infof("sfmv: volume %s removed, healing %d blobs of tenant %s", name, len(ids), s.Tenant)
+	count := 0
+	for _, id := range ids {
+		if s.logHeal(id) {
+			count++
+		}
+	}
+	logger.Infof("sfmv: volume %s removed, %d blobs of tenant %s healed", name, count, s.Tenant)
+}
@@ -0,0 +1,223 @@
+package simplefile
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+func getRedundantSrv(t *testing.T, srv *MultiVolumeStorage) *MultiVolumeStorage {
+	initSFMVTest(t)
+	srv.RootPath = sfmvRootPath
+	srv.Tenant = tenant
+	err := srv.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	if !waitLocations(srv) {
+		t.Fatal("location index not ready")
+	}
+	return srv
+}
+
+func storeRedundant(ast *assert.Assertions, srv *MultiVolumeStorage, payload []byte) string {
+	b := model.BlobDescription{
+		StoreID:       tenant,
+		TenantID:      tenant,
+		ContentLength: int64(len(payload)),
+		ContentType:   "application/octet-stream",
+		CreationDate:  time.Now().UnixMilli(),
+		Filename:      "test.bin",
+		Retention:     180000,
+		Properties:    make(map[string]any),
+	}
+	id, err := srv.StoreBlob(&b, bytes.NewReader(payload))
+	ast.Nil(err)
+	r := model.RetentionEntryFromBlobDescription(b)
+	ast.Nil(srv.AddRetention(&r))
+	return id
+}
+
+func waitHealthy(srv *MultiVolumeStorage, id string) *model.CheckInfo {
+	var ci *model.CheckInfo
+	for x := 0; x < 200; x++ {
+		ci, _ = srv.CheckBlob(id)
+		if ci != nil && ci.Healthy {
+			return ci
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return ci
+}
+
+func TestRedundancyConfig(t *testing.T) {
+	ast := assert.New(t)
+	initSFMVTest(t)
+	srv := MultiVolumeStorage{RootPath: sfmvRootPath, Tenant: tenant, Redundancy: "unknown"}
+	ast.NotNil(srv.Init())
+	srv = MultiVolumeStorage{RootPath: sfmvRootPath, Tenant: tenant, Redundancy: RedundancyReplicas, Replicas: 1}
+	ast.NotNil(srv.Init())
+	srv = MultiVolumeStorage{RootPath: sfmvRootPath, Tenant: tenant, Redundancy: RedundancyErasure, DataShards: 2}
+	ast.NotNil(srv.Init())
+
+	// more copies than volumes
+	srv2 := getRedundantSrv(t, &MultiVolumeStorage{Redundancy: RedundancyReplicas, Replicas: 4})
+	defer srv2.Close()
+	_, err := srv2.StoreBlob(&model.BlobDescription{}, bytes.NewReader([]byte(sfmvSimpleContent)))
+	ast.ErrorIs(err, ErrNotEnoughVolumes)
+}
+
+func TestReplicas(t *testing.T) {
+	ast := assert.New(t)
+	srv := getRedundantSrv(t, &MultiVolumeStorage{Redundancy: RedundancyReplicas, Replicas: 2})
+	defer srv.Close()
+
+	id := storeRedundant(ast, srv, []byte(sfmvSimpleContent))
+	hs := srv.probe(id, srv.volumeNames())
+	ast.Equal(2, len(hs))
+	for _, h := range hs {
+		_, err := h.srv.GetRetention(id)
+		ast.Nil(err)
+	}
+
+	count := 0
+	ast.Nil(srv.GetBlobs(func(bid string) bool {
+		ast.Equal(id, bid)
+		count++
+		return true
+	}))
+	ast.Equal(1, count)
+	count = 0
+	ast.Nil(srv.GetAllRetentions(func(r model.RetentionEntry) bool {
+		count++
+		return true
+	}))
+	ast.Equal(1, count)
+
+	ci, err := srv.CheckBlob(id)
+	ast.Nil(err)
+	ast.True(ci.Healthy)
+
+	bd, err := srv.GetBlobDescription(id)
+	ast.Nil(err)
+	bd.Filename = "changed.bin"
+	ast.Nil(srv.UpdateBlobDescription(id, bd))
+	for _, h := range hs {
+		bd, err := h.srv.GetBlobDescription(id)
+		ast.Nil(err)
+		ast.Equal("changed.bin", bd.Filename)
+	}
+
+	// a corrupt copy will be reported and healed
+	ast.Nil(os.WriteFile(hs[0].srv.getBinV2(id), []byte("this is a corrupt content"), os.ModePerm))
+	ci, err = srv.CheckBlob(id)
+	ast.Nil(err)
+	ast.False(ci.Healthy)
+	ast.Contains(ci.Message, "degraded: 1 of 2 copies healthy")
+	ci = waitHealthy(srv, id)
+	ast.True(ci.Healthy)
+
+	// a lost volume will be healed
+	hs = srv.probe(id, srv.volumeNames())
+	ast.Equal(2, len(hs))
+	lost := hs[0].name
+	ast.Nil(os.RemoveAll(filepath.Join(sfmvRootPath, lost)))
+	ast.Nil(srv.volMan.Rescan())
+	ast.True(waitHealthy(srv, id).Healthy)
+
+	hs = srv.probe(id, srv.volumeNames())
+	ast.Equal(2, len(hs))
+	for _, h := range hs {
+		ast.NotEqual(lost, h.name)
+	}
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal(sfmvSimpleContent, buf.String())
+
+	ast.Nil(srv.DeleteBlob(id))
+	ast.Equal(0, len(srv.probe(id, srv.volumeNames())))
+}
+
+func TestErasure(t *testing.T) {
+	ast := assert.New(t)
+	srv := getRedundantSrv(t, &MultiVolumeStorage{Redundancy: RedundancyErasure, DataShards: 2, ParityShards: 1})
+	defer srv.Close()
+
+	payload := make([]byte, 300000)
+	rand.Read(payload)
+	id := storeRedundant(ast, srv, payload)
+	small := storeRedundant(ast, srv, []byte(sfmvSimpleContent))
+
+	hs := srv.probe(id, srv.volumeNames())
+	ast.Equal(3, len(hs))
+	for _, h := range hs {
+		si, err := h.srv.checkShard(id)
+		ast.Nil(err)
+		ast.Equal(int64(len(payload)), si.Size)
+		bd, err := h.srv.GetBlobDescription(id)
+		ast.Nil(err)
+		ast.Equal(int64(len(payload)), bd.ContentLength)
+	}
+
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.Bytes())
+	buf.Reset()
+	ast.Nil(srv.RetrieveBlob(small, &buf))
+	ast.Equal(sfmvSimpleContent, buf.String())
+
+	ci, err := srv.CheckBlob(id)
+	ast.Nil(err)
+	ast.True(ci.Healthy)
+
+	// degraded read with a missing data shard
+	for _, h := range hs {
+		si, _ := h.srv.getShardInfo(id)
+		if si.Index == 0 {
+			ast.Nil(os.Remove(h.srv.getBinV2(id)))
+		}
+	}
+	buf.Reset()
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.Bytes())
+	ci = waitHealthy(srv, id)
+	ast.True(ci.Healthy)
+
+	// a lost volume can only be healed, if there is a free volume
+	lost := hs[1].name
+	ast.Nil(os.RemoveAll(filepath.Join(sfmvRootPath, lost)))
+	ast.Nil(srv.volMan.Rescan())
+	ci, err = srv.CheckBlob(id)
+	ast.Nil(err)
+	ast.False(ci.Healthy)
+	ast.Contains(ci.Message, "degraded: 2 of 3 shards healthy")
+	buf.Reset()
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.Bytes())
+
+	ast.Nil(os.MkdirAll(filepath.Join(sfmvRootPath, "mvn04"), os.ModePerm))
+	ast.Nil(srv.volMan.Rescan())
+	_, err = srv.Heal()
+	ast.Nil(err)
+	ci = waitHealthy(srv, id)
+	ast.True(ci.Healthy)
+	ci = waitHealthy(srv, small)
+	ast.True(ci.Healthy)
+	buf.Reset()
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.Bytes())
+
+	ast.Nil(srv.DeleteBlob(id))
+	for _, name := range srv.volumeNames() {
+		ast.False(srv.srv(name).hasShard(id))
+	}
+}
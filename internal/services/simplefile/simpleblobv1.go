@@ -124,13 +124,7 @@ func (s *BlobStorage) writeJSONFileV1(b *model.BlobDescription) error {
 		return err
 	}
 
-	err = os.WriteFile(jsonFile, js, os.ModePerm)
-	if err != nil {
-		_ = os.Remove(jsonFile)
-		return err
-	}
-
-	return nil
+	return writeFileAtomic(jsonFile, js, s.Sync)
 }
 
 func (s *BlobStorage) buildFilenameV1(id string, ext string) (string, error) {
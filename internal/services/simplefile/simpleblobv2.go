@@ -16,6 +16,9 @@ import (
 
 func (s *BlobStorage) getBlobsV2(callback func(id string) bool) error {
 	err := filepath.Walk(s.filepath, func(path string, info os.FileInfo, err error) error {
+		if s.DisableV1 && filepath.Dir(path) == s.filepath {
+			return nil
+		}
 		if !strings.Contains(path, RetentionPath) && strings.HasSuffix(path, DescriptionExt) {
 			id := info.Name()[:len(info.Name())-5]
 			ok := callback(id)
@@ -115,19 +118,30 @@ func (s *BlobStorage) storeBlobV2(b *model.BlobDescription, f io.Reader) (string
 		uuid := utils.GenerateID()
 		b.BlobID = uuid
 	}
-	size, hash, err := s.writeBinFileV2(b.BlobID, f)
+	tmp, size, hash, err := s.writeBinFileV2(b.BlobID, f)
 	if err != nil {
 		return "", err
 	}
 	if (b.ContentLength > 0) && b.ContentLength != size {
-		_ = s.deleteFilesV2(b.BlobID)
+		tmp.abort()
 		return "", fmt.Errorf("wrong content length %d=%d", b.ContentLength, size)
 	}
 	b.Hash = hash
 	b.ContentLength = size
-	err = s.writeJSONFileV2(b)
+	// both files are written before renaming them, so a failing write keeps an existing blob
+	desc, err := s.createJSONFileV2(b)
+	if err != nil {
+		tmp.abort()
+		return "", err
+	}
+	err = tmp.commit()
+	if err != nil {
+		desc.abort()
+		return "", err
+	}
+	// the description is renamed last, so the blob is only visible, if both files are complete
+	err = desc.commit()
 	if err != nil {
-		_ = s.deleteFilesV2(b.BlobID)
 		return "", err
 	}
 	s.cm.Lock()
@@ -136,20 +150,21 @@ func (s *BlobStorage) storeBlobV2(b *model.BlobDescription, f io.Reader) (string
 	return b.BlobID, nil
 }
 
-func (s *BlobStorage) writeBinFileV2(id string, r io.Reader) (int64, string, error) {
+// writeBinFileV2 writing the binary into a temporary file, which must be committed or aborted by the caller
+func (s *BlobStorage) writeBinFileV2(id string, r io.Reader) (*tmpFile, int64, string, error) {
 	binFile, err := s.buildFilenameV2(id, BinaryExt)
 	if err != nil {
-		return 0, "", err
+		return nil, 0, "", err
 	}
 
 	err = s.createFilePathV2(id)
 	if err != nil {
-		return 0, "", err
+		return nil, 0, "", err
 	}
 
-	f, err := os.Create(binFile)
+	f, err := createTmpFile(binFile, s.Sync)
 	if err != nil {
-		return 0, "", err
+		return nil, 0, "", err
 	}
 	h := sha256.New()
 	w := io.MultiWriter(f, h)
@@ -157,13 +172,11 @@ func (s *BlobStorage) writeBinFileV2(id string, r io.Reader) (int64, string, err
 	size, err := io.Copy(w, r)
 
 	if err != nil {
-		_ = f.Close()
-		_ = os.Remove(binFile)
-		return 0, "", err
+		f.abort()
+		return nil, 0, "", err
 	}
-	err = f.Close()
 	hash := fmt.Sprintf("sha-256:%x", h.Sum(nil))
-	return size, hash, err
+	return f, size, hash, nil
 }
 
 func (s *BlobStorage) deleteFilesV2(id string) error {
@@ -186,23 +199,34 @@ func (s *BlobStorage) deleteFilesV2(id string) error {
 }
 
 func (s *BlobStorage) writeJSONFileV2(b *model.BlobDescription) error {
-	jsonFile, err := s.buildFilenameV2(b.BlobID, DescriptionExt)
+	t, err := s.createJSONFileV2(b)
 	if err != nil {
 		return err
 	}
+	return t.commit()
+}
 
-	jsn, err := json.Marshal(b)
+// createJSONFileV2 writing the description into a temporary file, which must be committed or aborted by the caller
+func (s *BlobStorage) createJSONFileV2(b *model.BlobDescription) (*tmpFile, error) {
+	jsonFile, err := s.buildFilenameV2(b.BlobID, DescriptionExt)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = os.WriteFile(jsonFile, jsn, os.ModePerm)
+	jsn, err := json.Marshal(b)
 	if err != nil {
-		_ = os.Remove(jsonFile)
-		return err
+		return nil, err
 	}
 
-	return nil
+	t, err := createTmpFile(jsonFile, s.Sync)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.Write(jsn); err != nil {
+		t.abort()
+		return nil, err
+	}
+	return t, nil
 }
 
 func (s *BlobStorage) writeRetentionFile(b *model.BlobDescription) error {
@@ -216,13 +240,7 @@ func (s *BlobStorage) writeRetentionFile(b *model.BlobDescription) error {
 		return err
 	}
 
-	err = os.WriteFile(jsonFile, jsn, os.ModePerm)
-	if err != nil {
-		_ = os.Remove(jsonFile)
-		return err
-	}
-
-	return nil
+	return writeFileAtomic(jsonFile, jsn, s.Sync)
 }
 
 func (s *BlobStorage) getRetention(id string) (*model.RetentionEntry, error) {
@@ -17,17 +17,23 @@ import (
 
 // BlobStorage service for storing blob files into a file system
 type BlobStorage struct {
-	RootPath string                           // this is the root path for the file system storage
-	Tenant   string                           // this is the tenant, on which this service will work
-	filepath string                           // direct path to the tenant specific sub path
-	bdCch    map[string]model.BlobDescription // short time cache of blob descriptions
-	cm       sync.RWMutex
+	RootPath        string                           // this is the root path for the file system storage
+	Tenant          string                           // this is the tenant, on which this service will work
+	Sync            bool                             // syncing files and directories to disk after writing
+	DisableRecovery bool                             // the recovery scan is running on init, once per tenant path and process, if not disabled
+	DisableV1       bool                             // the legacy v1 layout is not read anymore, after all tenants are migrated to v2
+	filepath        string                           // direct path to the tenant specific sub path
+	bdCch           map[string]model.BlobDescription // short time cache of blob descriptions
+	cm              sync.RWMutex
 }
 
 var _ interfaces.BlobStorage = &BlobStorage{}
 
 const retentionBaseKey = "retentionBase"
 
+// recovered tenant paths, which are already scanned by the recovery
+var recovered sync.Map
+
 // ---- SimpleFileBlobStorage
 
 // Init initialize this service
@@ -45,9 +51,24 @@ func (s *BlobStorage) Init() error {
 		logger.Debugf("tenant not exists: %s", s.Tenant)
 	}
 	s.bdCch = make(map[string]model.BlobDescription)
+	if !s.DisableRecovery {
+		if _, ok := recovered.LoadOrStore(s.filepath, true); !ok {
+			go s.recover()
+		}
+	}
 	return nil
 }
 
+func (s *BlobStorage) recover() {
+	count, err := s.Recover()
+	if err != nil {
+		logger.Errorf("recovery: error scanning tenant %s: %v", s.Tenant, err)
+	}
+	if count > 0 {
+		logger.Infof("recovery: %d files of tenant %s removed", count, s.Tenant)
+	}
+}
+
 // GetTenant return the id of the tenant
 func (s *BlobStorage) GetTenant() string {
 	return s.Tenant
@@ -66,7 +87,7 @@ func (s *BlobStorage) StoreBlob(b *model.BlobDescription, f io.Reader) (string,
 // UpdateBlobDescription updating the blob description
 func (s *BlobStorage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
 	err := s.updateBlobDescriptionV2(id, b)
-	if err == os.ErrNotExist {
+	if err == os.ErrNotExist && !s.DisableV1 {
 		err = s.updateBlobDescriptionV1(id, b)
 	}
 	if err != nil {
@@ -80,18 +101,16 @@ func (s *BlobStorage) HasBlob(id string) (bool, error) {
 	if id == "" {
 		return false, nil
 	}
-	found := s.hasBlobV1(id)
-	if found {
+	if !s.DisableV1 && s.hasBlobV1(id) {
 		return true, nil
 	}
-	found = s.hasBlobV2(id)
-	return found, nil
+	return s.hasBlobV2(id), nil
 }
 
 // GetBlobDescription getting the description of the file
 func (s *BlobStorage) GetBlobDescription(id string) (*model.BlobDescription, error) {
 	info, err := s.getBlobDescriptionV2(id)
-	if err == os.ErrNotExist {
+	if err == os.ErrNotExist && !s.DisableV1 {
 		info, err = s.getBlobDescriptionV1(id)
 	}
 	if err != nil {
@@ -103,7 +122,7 @@ func (s *BlobStorage) GetBlobDescription(id string) (*model.BlobDescription, err
 // RetrieveBlob retrieving the binary data from the storage system
 func (s *BlobStorage) RetrieveBlob(id string, writer io.Writer) error {
 	err := s.getBlobV2(id, writer)
-	if err == os.ErrNotExist {
+	if err == os.ErrNotExist && !s.DisableV1 {
 		err = s.getBlobV1(id, writer)
 	}
 	if err != nil {
@@ -117,6 +136,9 @@ func (s *BlobStorage) DeleteBlob(id string) error {
 	s.cm.Lock()
 	delete(s.bdCch, id)
 	s.cm.Unlock()
+	if s.DisableV1 {
+		return s.deleteFilesV2(id)
+	}
 	err := s.deleteFilesV1(id)
 	if errors.Is(err, os.ErrNotExist) {
 		err = s.deleteFilesV2(id)
@@ -3,22 +3,39 @@ package simplefile
 import (
 	"errors"
 	"io"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
 	"github.com/willie68/GoBlobStore/internal/services/volume"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/internal/utils/erasure"
 	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
-// MultiVolumeStorage this service takes multi volumes and treats them as a single file storage
+// MultiVolumeStorage this service takes multi volumes and treats them as a single file storage.
+// With a redundancy mode every blob is stored as copies or as erasure coded shards on different volumes.
 type MultiVolumeStorage struct {
-	RootPath string // this is the root path for the file system storage
-	Tenant   string // this is the tenant, on which this service will work
-	volMan   volume.Manager
-	srvs     []BlobStorage
-	idxsrv   map[string]*BlobStorage
-	cm       sync.Mutex
+	RootPath        string // this is the root path for the file system storage
+	Tenant          string // this is the tenant, on which this service will work
+	TenantPath      string // this is the path of the tenant manager, the location index of the blobs is stored here
+	Redundancy      string // the redundancy mode: none, replicas or erasure
+	Replicas        int    // count of copies in the replicas mode
+	DataShards      int    // count of data shards in the erasure mode
+	ParityShards    int    // count of parity shards in the erasure mode
+	Sync            bool   // syncing files and directories to disk after writing
+	DisableRecovery bool   // the recovery scan is running on every volume on init, if not disabled
+	volMan          *volume.Manager
+	unsubscribe     func()
+	idxsrv          map[string]*BlobStorage
+	locs            *locationIndex
+	codec           *erasure.Codec
+	healing         map[string]bool
+	cm              sync.Mutex
+	locks           utils.IDLocks // a delete must wait for a running move of the blob to another volume
 }
 
 // checking interface compatibility
@@ -38,19 +55,29 @@ func (s *MultiVolumeStorage) Init() error {
 		return errors.New("tenant should not be null or empty")
 	}
 	s.cm = sync.Mutex{}
-	volMan, err := volume.NewVolumeManager(s.RootPath)
+	err := s.initRedundancy()
+	if err != nil {
+		return err
+	}
+	// the volume manager is shared with all other tenants and the administration
+	volMan, err := volume.GetManager(s.RootPath)
 	if err != nil {
 		return err
 	}
 	s.cm.Lock()
-	s.srvs = make([]BlobStorage, 0)
 	s.idxsrv = make(map[string]*BlobStorage)
 	s.cm.Unlock()
 	s.volMan = volMan
-	s.volMan.AddCallback(func(name string) bool {
+	s.unsubscribe = s.volMan.Subscribe(func(name string) bool {
 		return s.addVolume(name)
+	}, func(name string) bool {
+		return s.removeVolume(name)
 	})
-	s.volMan.Init()
+	for _, vi := range s.volMan.Volumes() {
+		s.addVolume(vi.Name)
+	}
+	s.initLocations()
+	registerLive(s)
 	return nil
 }
 
@@ -59,75 +86,159 @@ func (s *MultiVolumeStorage) GetTenant() string {
 	return s.Tenant
 }
 
-// GetBlobs walking thru all blobs of this tenant
+// GetBlobs walking thru all blobs of this tenant, blobs with copies or shards on multiple volumes are reported only once
 func (s *MultiVolumeStorage) GetBlobs(callback func(id string) bool) error {
-	for _, srv := range s.srvs {
-		err := srv.GetBlobs(callback)
-		if err != io.EOF {
+	seen := make(map[string]bool)
+	for _, h := range s.allHolders() {
+		stopped := false
+		err := h.srv.GetBlobs(func(id string) bool {
+			if s.redundant() {
+				if seen[id] {
+					return true
+				}
+				seen[id] = true
+			}
+			stopped = !callback(id)
+			return !stopped
+		})
+		if stopped {
+			return nil
+		}
+		if err != nil && err != io.EOF {
 			return err
 		}
 	}
-	return io.EOF
+	return nil
 }
 
 // StoreBlob storing a blob to the storage system
 func (s *MultiVolumeStorage) StoreBlob(b *model.BlobDescription, f io.Reader) (string, error) {
-	srv, err := s.selectSrv()
+	switch s.Redundancy {
+	case RedundancyReplicas:
+		return s.storeReplicas(b, f)
+	case RedundancyErasure:
+		return s.storeShards(b, f)
+	}
+	name, srv, err := s.selectSrv()
 	if err != nil {
 		return "", err
 	}
-	return srv.StoreBlob(b, f)
+	id, err := srv.StoreBlob(b, f)
+	if err != nil {
+		return "", err
+	}
+	s.locs.set(id, name)
+	return id, nil
 }
 
-// UpdateBlobDescription updating the blob description
-// TODO implement this
-func (s *MultiVolumeStorage) UpdateBlobDescription(_ string, _ *model.BlobDescription) error {
-	return ErrNotImplemented
+// UpdateBlobDescription updating the blob description on every volume holding the blob
+func (s *MultiVolumeStorage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return ErrSrvNotFound
+	}
+	for _, h := range hs {
+		err := h.srv.UpdateBlobDescription(id, b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // HasBlob checking if one service has this blob
 func (s *MultiVolumeStorage) HasBlob(id string) (bool, error) {
-	_, err := s.srv4id(id)
-	if err != nil {
-		return false, err
+	if len(s.holders(id)) == 0 {
+		return false, ErrSrvNotFound
 	}
 	return true, nil
 }
 
 // GetBlobDescription getting the lob description from the service holding the blob
 func (s *MultiVolumeStorage) GetBlobDescription(id string) (*model.BlobDescription, error) {
-	srv, err := s.srv4id(id)
-	if err != nil {
-		return nil, err
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return nil, ErrSrvNotFound
+	}
+	var err error
+	for _, h := range hs {
+		var bd *model.BlobDescription
+		bd, err = h.srv.GetBlobDescription(id)
+		if err == nil {
+			return bd, nil
+		}
 	}
-	return srv.GetBlobDescription(id)
+	return nil, err
 }
 
-// RetrieveBlob retrieving the blob from the first service holding the blob file
+// RetrieveBlob retrieving the blob from the first service holding the blob file.
+// Copies or shards, which are not available, are healed in the background.
 func (s *MultiVolumeStorage) RetrieveBlob(id string, writer io.Writer) error {
-	srv, err := s.srv4id(id)
-	if err != nil {
-		return err
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return ErrSrvNotFound
+	}
+	if s.Redundancy == RedundancyErasure {
+		return s.retrieveShards(id, hs, writer)
 	}
-	return srv.RetrieveBlob(id, writer)
+	if s.Redundancy == RedundancyReplicas && len(hs) < s.Replicas {
+		s.healAsync(id)
+	}
+	var err error
+	for _, h := range hs {
+		cw := &utils.CountWriter{W: writer}
+		err = h.srv.RetrieveBlob(id, cw)
+		// only if nothing is written, another copy can be used
+		if err == nil || cw.N > 0 {
+			break
+		}
+		logger.Errorf("sfmv: error reading %s from volume %s: %v", id, h.name, err)
+		s.healAsync(id)
+	}
+	return err
 }
 
 // DeleteBlob removing a blob from the storage system
 func (s *MultiVolumeStorage) DeleteBlob(id string) error {
-	srv, err := s.srv4id(id)
-	if err != nil {
-		return err
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return ErrSrvNotFound
 	}
-	return srv.DeleteBlob(id)
+	var lerr error
+	for _, h := range hs {
+		var err error
+		if s.Redundancy == RedundancyErasure {
+			err = h.srv.deleteShard(id)
+		} else {
+			err = h.srv.DeleteBlob(id)
+		}
+		if err != nil {
+			logger.Errorf("sfmv: error deleting %s from volume %s: %v", id, h.name, err)
+			lerr = err
+		}
+	}
+	if lerr != nil {
+		return lerr
+	}
+	s.locs.remove(id)
+	return nil
 }
 
-// CheckBlob checking a single blob from the storage system
+// CheckBlob checking a single blob from the storage system, for redundant blobs every copy or shard is checked
 func (s *MultiVolumeStorage) CheckBlob(id string) (*model.CheckInfo, error) {
-	srv, err := s.srv4id(id)
-	if err != nil {
-		return nil, err
+	switch s.Redundancy {
+	case RedundancyReplicas:
+		return s.checkReplicas(id)
+	case RedundancyErasure:
+		return s.checkShards(id)
 	}
-	return srv.CheckBlob(id)
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return nil, ErrSrvNotFound
+	}
+	return hs[0].srv.CheckBlob(id)
 }
 
 // SearchBlobs is not implemented for this storage
@@ -137,8 +248,17 @@ func (s *MultiVolumeStorage) SearchBlobs(_ string, _ func(id string) bool) error
 
 // GetAllRetentions for every retention entry for this tenant we call this this function, you can stop the listing by returning a false
 func (s *MultiVolumeStorage) GetAllRetentions(callback func(r model.RetentionEntry) bool) error {
-	for _, srv := range s.srvs {
-		err := srv.GetAllRetentions(callback)
+	seen := make(map[string]bool)
+	for _, h := range s.allHolders() {
+		err := h.srv.GetAllRetentions(func(r model.RetentionEntry) bool {
+			if s.redundant() {
+				if seen[r.BlobID] {
+					return true
+				}
+				seen[r.BlobID] = true
+			}
+			return callback(r)
+		})
 		if err != nil {
 			return err
 		}
@@ -148,29 +268,50 @@ func (s *MultiVolumeStorage) GetAllRetentions(callback func(r model.RetentionEnt
 
 // GetRetention getting a single retention entry
 func (s *MultiVolumeStorage) GetRetention(id string) (model.RetentionEntry, error) {
-	srv, err := s.srv4id(id)
-	if err != nil {
-		return model.RetentionEntry{}, err
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return model.RetentionEntry{}, ErrSrvNotFound
+	}
+	var err error
+	for _, h := range hs {
+		var r model.RetentionEntry
+		r, err = h.srv.GetRetention(id)
+		if err == nil {
+			return r, nil
+		}
 	}
-	return srv.GetRetention(id)
+	return model.RetentionEntry{}, err
 }
 
-// AddRetention adding a retention entry to the storage
+// AddRetention adding a retention entry to every volume holding the blob
 func (s *MultiVolumeStorage) AddRetention(r *model.RetentionEntry) error {
-	srv, err := s.srv4id(r.BlobID)
-	if err != nil {
-		return err
+	hs := s.holders(r.BlobID)
+	if len(hs) == 0 {
+		return ErrSrvNotFound
 	}
-	return srv.AddRetention(r)
+	for _, h := range hs {
+		err := h.srv.AddRetention(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// DeleteRetention deletes the retention entry from the storage
+// DeleteRetention deletes the retention entry from every volume holding the blob
 func (s *MultiVolumeStorage) DeleteRetention(id string) error {
-	srv, err := s.srv4id(id)
-	if err != nil {
-		return err
+	hs := s.holders(id)
+	if len(hs) == 0 {
+		return ErrSrvNotFound
+	}
+	var lerr error
+	for _, h := range hs {
+		err := h.srv.DeleteRetention(id)
+		if err != nil {
+			lerr = err
+		}
 	}
-	return srv.DeleteRetention(id)
+	return lerr
 }
 
 // ResetRetention resets the retention for a blob
@@ -190,36 +331,144 @@ func (s *MultiVolumeStorage) GetLastError() error {
 
 // Close closing the storage
 func (s *MultiVolumeStorage) Close() error {
-	for _, srv := range s.srvs {
-		err := srv.Close()
+	unregisterLive(s)
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
+	for _, h := range s.allHolders() {
+		err := h.srv.Close()
 		if err != nil {
 			logger.Errorf("error closing volume service: %v", err)
 		}
 	}
-	s.srvs = make([]BlobStorage, 0)
-	return nil
+	return s.locs.close()
 }
 
-func (s *MultiVolumeStorage) selectSrv() (*BlobStorage, error) {
+func (s *MultiVolumeStorage) selectSrv() (string, *BlobStorage, error) {
 	rnd := s.volMan.Rnd()
 	name := s.volMan.SelectFree(rnd)
 	s.cm.Lock()
 	defer s.cm.Unlock()
 	srv := s.idxsrv[name]
 	if srv == nil {
-		return nil, ErrSrvNotFound
+		return "", nil, ErrSrvNotFound
 	}
-	return srv, nil
+	return name, srv, nil
 }
 
-func (s *MultiVolumeStorage) srv4id(id string) (*BlobStorage, error) {
-	for _, srv := range s.srvs {
-		ok, _ := srv.HasBlob(id)
-		if ok {
-			return &srv, nil
+// holders getting the volumes holding the blob, a copy or a shard of it. First the volumes of the location index are probed,
+// only if the index doesn't know the blob or none of the volumes holds the blob, all volumes are scanned and the index is corrected.
+// So a blob written by an older version or copied directly onto a volume will be found, too.
+func (s *MultiVolumeStorage) holders(id string) []holder {
+	vols, _ := s.locs.get(id)
+	if vols != "" {
+		hs := s.probe(id, strings.Split(vols, ","))
+		if len(hs) > 0 {
+			return hs
+		}
+	}
+	hs := s.probe(id, s.volumeNames())
+	if len(hs) == 0 {
+		s.locs.remove(id)
+		return nil
+	}
+	s.locs.set(id, joinHolders(hs))
+	return hs
+}
+
+// probe checking the volumes for the blob
+func (s *MultiVolumeStorage) probe(id string, names []string) []holder {
+	hs := make([]holder, 0, len(names))
+	for _, name := range names {
+		srv := s.srv(name)
+		if srv == nil {
+			continue
+		}
+		if ok, _ := srv.HasBlob(id); ok {
+			hs = append(hs, holder{name: name, srv: srv})
 		}
 	}
-	return nil, ErrSrvNotFound
+	return hs
+}
+
+// srv getting the service of a volume
+func (s *MultiVolumeStorage) srv(name string) *BlobStorage {
+	s.cm.Lock()
+	defer s.cm.Unlock()
+	return s.idxsrv[name]
+}
+
+// allHolders getting the services of all volumes sorted by name
+func (s *MultiVolumeStorage) allHolders() []holder {
+	names := s.volumeNames()
+	hs := make([]holder, 0, len(names))
+	for _, name := range names {
+		if srv := s.srv(name); srv != nil {
+			hs = append(hs, holder{name: name, srv: srv})
+		}
+	}
+	return hs
+}
+
+// initLocations loading the location index of the blobs, if missing or stale the index is rebuild in the background
+func (s *MultiVolumeStorage) initLocations() {
+	// the index is stored in the folder of the tenant, without a tenant path the index is only held in memory
+	filename := ""
+	if s.TenantPath != "" {
+		filename = filepath.Join(s.TenantPath, s.Tenant, "_locations", "locations.idx")
+	}
+	volumes := s.volumeNames()
+	locs, ok := newLocationIndex(filename, volumes)
+	s.locs = locs
+	if ok {
+		err := locs.open(volumes)
+		if err != nil {
+			logger.Errorf("sfmv: error writing location index: %v", err)
+		}
+		return
+	}
+	go s.rebuildLocations(volumes)
+}
+
+// rebuildLocations scanning all volumes for the blobs of this tenant
+func (s *MultiVolumeStorage) rebuildLocations(volumes []string) {
+	logger.Infof("sfmv: rebuilding location index of tenant %s", s.Tenant)
+	locs := make(map[string]string)
+	for _, name := range volumes {
+		srv := s.srv(name)
+		if srv == nil {
+			continue
+		}
+		err := srv.GetBlobs(func(id string) bool {
+			if v, ok := locs[id]; ok {
+				locs[id] = v + "," + name
+			} else {
+				locs[id] = name
+			}
+			return true
+		})
+		if err != nil && err != io.EOF {
+			logger.Errorf("sfmv: error scanning volume %s: %v", name, err)
+		}
+	}
+	s.locs.rebuild(locs)
+	err := s.locs.open(volumes)
+	if err != nil {
+		logger.Errorf("sfmv: error writing location index: %v", err)
+	}
+	logger.Infof("sfmv: location index of tenant %s rebuild with %d blobs", s.Tenant, len(locs))
+}
+
+// volumeNames getting the sorted names of all volumes of this storage
+func (s *MultiVolumeStorage) volumeNames() []string {
+	s.cm.Lock()
+	defer s.cm.Unlock()
+	names := make([]string, 0, len(s.idxsrv))
+	for name := range s.idxsrv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func (s *MultiVolumeStorage) addVolume(name string) bool {
@@ -230,17 +479,44 @@ func (s *MultiVolumeStorage) addVolume(name string) bool {
 	if vi == nil {
 		return false
 	}
+	if s.srv(name) != nil {
+		return true
+	}
 	sfbd := &BlobStorage{
-		RootPath: vi.Path,
-		Tenant:   s.Tenant,
+		RootPath:        vi.Path,
+		Tenant:          s.Tenant,
+		Sync:            s.Sync,
+		DisableRecovery: s.DisableRecovery,
 	}
 	err := sfbd.Init()
 	if err != nil {
 		return false
 	}
-	s.srvs = append(s.srvs, *sfbd)
 	s.cm.Lock()
 	defer s.cm.Unlock()
+	if _, ok := s.idxsrv[name]; ok {
+		// added in the meantime by the volume manager
+		_ = sfbd.Close()
+		return true
+	}
 	s.idxsrv[name] = sfbd
 	return true
 }
+
+// removeVolume removing a volume, which has disappeared. The copies or shards on this volume will be rebuild on the other volumes.
+func (s *MultiVolumeStorage) removeVolume(name string) bool {
+	s.cm.Lock()
+	srv, ok := s.idxsrv[name]
+	delete(s.idxsrv, name)
+	s.cm.Unlock()
+	if !ok {
+		return false
+	}
+	_ = srv.Close()
+	if !s.redundant() {
+		logger.Errorf("sfmv: volume %s removed, the blobs of tenant %s on this volume are not available", name, s.Tenant)
+		return true
+	}
+	go s.healVolume(name)
+	return true
+}
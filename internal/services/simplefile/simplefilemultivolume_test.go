@@ -11,11 +11,13 @@ import (
 
 	"github.com/nsf/jsondiff"
 	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/volume"
 	"github.com/willie68/GoBlobStore/pkg/model"
 )
 
 const (
 	sfmvRootPath      = "../../../testdata/mv/"
+	sfmvTenantPath    = "../../../testdata/mvtnt/"
 	sfmvSimpleContent = "this is a blob content"
 )
 
@@ -39,17 +41,27 @@ func clear(t *testing.T) {
 	}
 	err := os.MkdirAll(sfmvRootPath, os.ModePerm)
 	assert.Nil(t, err)
+	assert.Nil(t, os.RemoveAll(sfmvTenantPath))
 }
 
-func getSFMVStoreageSrv(t *testing.T) MultiVolumeStorage {
-	srv := MultiVolumeStorage{
-		RootPath: sfmvRootPath,
-		Tenant:   tenant,
+func getSFMVStoreageSrv(t *testing.T) *MultiVolumeStorage {
+	srv := &MultiVolumeStorage{
+		RootPath:   sfmvRootPath,
+		Tenant:     tenant,
+		TenantPath: sfmvTenantPath,
 	}
 	err := srv.Init()
 	if err != nil {
 		t.Fatal(err)
 	}
+	// the volume manager is shared, so the storage must not get the volume changes of the next test
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	// the location index is rebuild in the background
+	if !waitLocations(srv) {
+		t.Fatal("location index not ready")
+	}
 	return srv
 }
 
@@ -329,3 +341,228 @@ func TestSFMVSrvStoreMultiBlobs(t *testing.T) {
 		ast.Nil(err, "DeleteBlob throws error")
 	}
 }
+
+func TestVolumeMover(t *testing.T) {
+	ast := assert.New(t)
+	initSFMVTest(t)
+	srv := getSFMVStoreageSrv(t)
+
+	b := model.BlobDescription{
+		StoreID:       tenant,
+		TenantID:      tenant,
+		ContentLength: int64(len(sfmvSimpleContent)),
+		ContentType:   "text/plain",
+		CreationDate:  time.Now().UnixMilli(),
+		Filename:      "test.txt",
+		Retention:     180000,
+		Properties:    make(map[string]any),
+	}
+	id, err := srv.StoreBlob(&b, strings.NewReader(sfmvSimpleContent))
+	ast.Nil(err)
+	r := model.RetentionEntryFromBlobDescription(b)
+	ast.Nil(srv.AddRetention(&r))
+
+	mover := &VolumeMover{RootPath: sfmvRootPath}
+	from := ""
+	for _, v := range vols {
+		err := mover.Blobs(v, func(b volume.BlobRef) bool {
+			ast.Equal(id, b.ID)
+			ast.Equal(tenant, b.Tenant)
+			ast.Equal(int64(len(sfmvSimpleContent)), b.Size)
+			ast.Equal([]string{v}, b.Volumes)
+			from = v
+			return true
+		})
+		ast.Nil(err)
+	}
+	ast.NotEmpty(from)
+	to := vols[0]
+	if from == to {
+		to = vols[1]
+	}
+
+	err = mover.Move(volume.BlobRef{Tenant: tenant, ID: id, Size: b.ContentLength}, from, to)
+	ast.Nil(err)
+	// the move is done thru the live storage, so the location index knows the new volume
+	vol, ok := srv.locs.get(id)
+	ast.True(ok)
+	ast.Equal(to, vol)
+
+	_, err = os.Stat(filepath.Join(sfmvRootPath, from, tenant, RetentionPath, id+RetentionExt))
+	ast.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(sfmvRootPath, to, tenant, RetentionPath, id+RetentionExt))
+	ast.Nil(err)
+
+	ok, err = srv.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+	var buf bytes.Buffer
+	ast.Nil(srv.RetrieveBlob(id, &buf))
+	ast.Equal(sfmvSimpleContent, buf.String())
+	rd, err := srv.GetRetention(id)
+	ast.Nil(err)
+	ast.Equal(r.Retention, rd.Retention)
+
+	// moving again is not possible
+	err = mover.Move(volume.BlobRef{Tenant: tenant, ID: id}, from, to)
+	ast.NotNil(err)
+	ast.Nil(srv.Close())
+
+	// without a live storage of the tenant, there is nothing to move
+	err = mover.Move(volume.BlobRef{Tenant: tenant, ID: id}, to, from)
+	ast.NotNil(err)
+}
+
+func TestSharedVolumeState(t *testing.T) {
+	ast := assert.New(t)
+	initSFMVTest(t)
+	srv := getSFMVStoreageSrv(t)
+
+	volMan, err := volume.GetManager(sfmvRootPath)
+	ast.Nil(err)
+	ast.Same(volMan, srv.volMan)
+
+	// a readonly volume is not used for new blobs of the tenant anymore
+	ast.Nil(volMan.SetState(vols[0], volume.StateReadOnly))
+	defer func() {
+		ast.Nil(volMan.SetState(vols[0], volume.StateActive))
+	}()
+	for i := 0; i < 20; i++ {
+		name, _, err := srv.selectSrv()
+		ast.Nil(err)
+		ast.NotEqual(vols[0], name)
+	}
+}
+
+func waitLocations(srv *MultiVolumeStorage) bool {
+	for x := 0; x < 500; x++ {
+		if srv.locs.isReady() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestSFMVLocationIndex(t *testing.T) {
+	ast := assert.New(t)
+	initSFMVTest(t)
+	srv := getSFMVStoreageSrv(t)
+
+	ids := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		b := model.BlobDescription{
+			StoreID:       tenant,
+			TenantID:      tenant,
+			ContentLength: int64(len(sfmvSimpleContent)),
+			ContentType:   "text/plain",
+			CreationDate:  time.Now().UnixMilli(),
+			Filename:      "test.txt",
+			Properties:    make(map[string]any),
+		}
+		id, err := srv.StoreBlob(&b, strings.NewReader(sfmvSimpleContent))
+		ast.Nil(err)
+		ids = append(ids, id)
+
+		vol, ok := srv.locs.get(id)
+		ast.True(ok)
+		ok, err = srv.idxsrv[vol].HasBlob(id)
+		ast.Nil(err)
+		ast.True(ok)
+	}
+
+	_, maybe := srv.locs.get("unknown")
+	ast.False(maybe)
+	ok, err := srv.HasBlob("unknown")
+	ast.NotNil(err)
+	ast.False(ok)
+
+	ast.Nil(srv.DeleteBlob(ids[0]))
+	ok, _ = srv.HasBlob(ids[0])
+	ast.False(ok)
+	ids = ids[1:]
+	ast.Nil(srv.Close())
+
+	idx := filepath.Join(sfmvTenantPath, tenant, "_locations", "locations.idx")
+	data, err := os.ReadFile(idx)
+	ast.Nil(err)
+	ast.True(strings.HasSuffix(string(data), locClosedMarker+"\n"))
+
+	// a cleanly closed index will be loaded without scanning
+	_, ok = newLocationIndex(idx, vols)
+	ast.True(ok)
+	srv = getSFMVStoreageSrv(t)
+	for _, id := range ids {
+		var buf bytes.Buffer
+		ast.Nil(srv.RetrieveBlob(id, &buf))
+		ast.Equal(sfmvSimpleContent, buf.String())
+	}
+
+	// without closing, the index is stale and will be rebuild
+	_, ok = newLocationIndex(idx, vols)
+	ast.False(ok)
+	srv = getSFMVStoreageSrv(t)
+	for _, id := range ids {
+		_, ok := srv.locs.get(id)
+		ast.True(ok)
+		ok, err = srv.HasBlob(id)
+		ast.Nil(err)
+		ast.True(ok)
+	}
+
+	// a missing index will be rebuild
+	ast.Nil(srv.Close())
+	ast.Nil(os.Remove(idx))
+	srv = getSFMVStoreageSrv(t)
+	vol, ok := srv.locs.get(ids[0])
+	ast.True(ok)
+	ast.NotEmpty(vol)
+	ast.Nil(srv.Close())
+}
+
+func TestSFMVLocationFallback(t *testing.T) {
+	ast := assert.New(t)
+	initSFMVTest(t)
+	srv := getSFMVStoreageSrv(t)
+
+	// a blob written directly onto a volume is unknown to the index
+	b := model.BlobDescription{
+		StoreID:       tenant,
+		TenantID:      tenant,
+		ContentLength: int64(len(sfmvSimpleContent)),
+		ContentType:   "text/plain",
+		CreationDate:  time.Now().UnixMilli(),
+		Filename:      "test.txt",
+		Properties:    make(map[string]any),
+	}
+	id, err := srv.srv(vols[1]).StoreBlob(&b, strings.NewReader(sfmvSimpleContent))
+	ast.Nil(err)
+	_, maybe := srv.locs.get(id)
+	ast.False(maybe)
+
+	ok, err := srv.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+	vol, ok := srv.locs.get(id)
+	ast.True(ok)
+	ast.Equal(vols[1], vol)
+}
+
+func TestSFMVLocationInMemory(t *testing.T) {
+	ast := assert.New(t)
+	initSFMVTest(t)
+	srv := &MultiVolumeStorage{
+		RootPath: sfmvRootPath,
+		Tenant:   tenant,
+	}
+	ast.Nil(srv.Init())
+	defer srv.Close()
+	ast.True(waitLocations(srv))
+
+	// without a tenant path, nothing is written into the root path of the volumes
+	entries, err := os.ReadDir(sfmvRootPath)
+	ast.Nil(err)
+	for _, e := range entries {
+		ast.True(e.IsDir(), e.Name())
+	}
+}
@@ -122,8 +122,7 @@ func (s *TenantManager) SetConfig(tenant string, config interfaces.TenantConfig)
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(cfnName, str, os.ModePerm)
-	return err
+	return writeFileAtomic(cfnName, str, false)
 }
 
 // GetConfig reading the config object for the tenant
@@ -0,0 +1,247 @@
+package simplefile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/willie68/GoBlobStore/internal/services/volume"
+)
+
+// checking interface compatibility
+var _ volume.Mover = &VolumeMover{}
+
+// VolumeMover moving the blobs of all tenants between the volumes of a multi volume storage. The blobs are moved thru the
+// live storage of the tenant, so the location index of the tenant is always up to date.
+type VolumeMover struct {
+	RootPath string                    // this is the root path of the volumes
+	Load     func(tenant string) error // loading the storage of a tenant, which is not in use yet
+}
+
+// all live multi volume storages by root path and tenant
+var (
+	lives   = make(map[string]*MultiVolumeStorage)
+	livesMu sync.Mutex
+)
+
+func liveKey(rootpath, tenant string) string {
+	return filepath.Clean(rootpath) + "|" + tenant
+}
+
+// registerLive registering the storage, so the volume mover can work thru it
+func registerLive(s *MultiVolumeStorage) {
+	livesMu.Lock()
+	defer livesMu.Unlock()
+	lives[liveKey(s.RootPath, s.Tenant)] = s
+}
+
+// unregisterLive removing the storage, if it's still registered
+func unregisterLive(s *MultiVolumeStorage) {
+	livesMu.Lock()
+	defer livesMu.Unlock()
+	key := liveKey(s.RootPath, s.Tenant)
+	if lives[key] == s {
+		delete(lives, key)
+	}
+}
+
+func getLive(rootpath, tenant string) *MultiVolumeStorage {
+	livesMu.Lock()
+	defer livesMu.Unlock()
+	return lives[liveKey(rootpath, tenant)]
+}
+
+// Blobs walking thru all blobs of all tenants of a volume
+func (m *VolumeMover) Blobs(vol string, callback func(b volume.BlobRef) bool) error {
+	entries, err := os.ReadDir(filepath.Join(m.RootPath, vol))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		s, err := m.storage(e.Name())
+		if err != nil {
+			return err
+		}
+		srv := s.srv(vol)
+		if srv == nil {
+			return ErrSrvNotFound
+		}
+		stopped := false
+		err = srv.GetBlobs(func(id string) bool {
+			bd, err := srv.GetBlobDescription(id)
+			if err != nil {
+				logger.Errorf("volume mover: can't get description of %s: %v", id, err)
+				return true
+			}
+			hs := s.holders(id)
+			names := make([]string, len(hs))
+			for i, h := range hs {
+				names[i] = h.name
+			}
+			stopped = !callback(volume.BlobRef{
+				Tenant:  s.Tenant,
+				ID:      id,
+				Size:    bd.ContentLength,
+				Volumes: names,
+			})
+			return !stopped
+		})
+		if stopped {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move copying the blob with its retention to the destination volume, verifying the hash and removing it from the source volume
+func (m *VolumeMover) Move(b volume.BlobRef, from, to string) error {
+	s, err := m.storage(b.Tenant)
+	if err != nil {
+		return err
+	}
+	return s.moveBlob(b.ID, from, to)
+}
+
+// storage getting the live storage of the tenant, if needed the storage will be loaded
+func (m *VolumeMover) storage(tenant string) (*MultiVolumeStorage, error) {
+	if s := getLive(m.RootPath, tenant); s != nil {
+		return s, nil
+	}
+	if m.Load != nil {
+		err := m.Load(tenant)
+		if err != nil {
+			return nil, err
+		}
+		if s := getLive(m.RootPath, tenant); s != nil {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("volume mover: no storage for tenant %s present", tenant)
+}
+
+// moveBlob moving the blob, a copy or a shard of it from one volume to another and updating the location index
+func (s *MultiVolumeStorage) moveBlob(id, from, to string) error {
+	unlock := s.locks.Lock(id)
+	defer unlock()
+	src := s.srv(from)
+	dst := s.srv(to)
+	if src == nil || dst == nil {
+		return ErrSrvNotFound
+	}
+	ok, _ := dst.HasBlob(id)
+	if ok {
+		return fmt.Errorf("blob %s already exists on volume %s", id, to)
+	}
+	// shards of erasure coded blobs are moved as they are
+	var err error
+	if src.hasShard(id) {
+		err = copyShard(src, dst, id)
+		if err == nil {
+			err = src.deleteShard(id)
+		}
+	} else {
+		err = copyBlob(src, dst, id)
+		if err == nil {
+			err = src.DeleteBlob(id)
+		}
+	}
+	// the new location is written even if the source can't be deleted, the blob is now on both volumes
+	if hs := s.probe(id, s.volumeNames()); len(hs) > 0 {
+		s.locs.set(id, joinHolders(hs))
+	}
+	return err
+}
+
+// copyBlob copying the blob with its retention from one volume to another, verifying the hash
+func copyBlob(src, dst *BlobStorage, id string) error {
+	bd, err := src.GetBlobDescription(id)
+	if err != nil {
+		return err
+	}
+	hash := bd.Hash
+	rd, wr := io.Pipe()
+	go func() {
+		// close the writer, so the reader knows there's no more data
+		err := src.RetrieveBlob(id, wr)
+		wr.CloseWithError(err)
+	}()
+	_, err = dst.StoreBlob(bd, rd)
+	_ = rd.Close()
+	if err != nil {
+		_ = dst.DeleteBlob(id)
+		return err
+	}
+	if hash != "" && hash != bd.Hash {
+		_ = dst.DeleteBlob(id)
+		return fmt.Errorf("hashes are not equal: %s != %s", hash, bd.Hash)
+	}
+	err = copyRetention(src, dst, id)
+	if err != nil {
+		_ = dst.DeleteBlob(id)
+		return err
+	}
+	return nil
+}
+
+// copyShard copying the shard of an erasure coded blob with its retention from one volume to another, verifying the hash
+func copyShard(src, dst *BlobStorage, id string) error {
+	si, err := src.checkShard(id)
+	if err != nil {
+		return err
+	}
+	bd, err := src.GetBlobDescription(id)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(src.getBinV2(id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sw, err := newShardWriter(dst, id)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(sw.f, io.TeeReader(f, sw.h))
+	if err == nil && sw.hash() != si.Hash {
+		err = fmt.Errorf("hashes are not equal: %s != %s", si.Hash, sw.hash())
+	}
+	if err == nil {
+		err = dst.writeShard(bd, *si, sw)
+	}
+	if err == nil {
+		err = copyRetention(src, dst, id)
+	}
+	if err != nil {
+		sw.f.abort()
+		_ = dst.deleteShard(id)
+		return err
+	}
+	return nil
+}
+
+// copyRetention copying the retention file of a blob, if present
+func copyRetention(src, dst *BlobStorage, id string) error {
+	rf, err := src.buildRetentionFilename(id)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(rf); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	r, err := src.getRetention(id)
+	if err != nil {
+		return err
+	}
+	return dst.AddRetention(r)
+}
@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/pkg/model/query"
+)
+
+// ToSQL converting a blobstorage query into a sql condition on the blobs table b, the values are returned as arguments
+func ToSQL(q query.Query) (string, []any, error) {
+	return xToSQL(q.Resolved(time.Now()).Condition)
+}
+
+// xToSQL converting a node/condition to a sql condition
+func xToSQL(x any) (string, []any, error) {
+	switch v := x.(type) {
+	case query.Condition:
+		return cToSQL(v)
+	case *query.Condition:
+		return cToSQL(*v)
+	case query.Node:
+		return nToSQL(v)
+	case *query.Node:
+		return nToSQL(*v)
+	}
+	return "", nil, fmt.Errorf("can't convert %v to sql query", x)
+}
+
+// nToSQL converting a node into a sql condition
+func nToSQL(n query.Node) (string, []any, error) {
+	op := " AND "
+	if n.Operator == query.OROP {
+		op = " OR "
+	}
+	parts := make([]string, 0, len(n.Conditions))
+	args := make([]any, 0)
+	for _, c := range n.Conditions {
+		s, a, err := xToSQL(c)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, s)
+		args = append(args, a...)
+	}
+	if len(parts) == 0 {
+		return "", nil, fmt.Errorf("empty node in query")
+	}
+	s := "(" + strings.Join(parts, op) + ")"
+	if n.Invert {
+		s = "NOT " + s
+	}
+	return s, args, nil
+}
+
+// cToSQL converting a condition into a sql condition. A condition is true, if any value of the field matches,
+// != is true, if no value matches.
+func cToSQL(c query.Condition) (string, []any, error) {
+	if c.Field == "" {
+		return "", nil, fmt.Errorf("condition without field")
+	}
+	col, v := cToValue(c)
+	vc := ""
+	args := []any{c.Field}
+	exists := "EXISTS"
+	switch c.Operator {
+	case query.NO:
+		if s, ok := v.(string); ok && c.HasWildcard() {
+			vc = `p.str LIKE ? ESCAPE '\'`
+			v = toLike(s)
+		} else {
+			vc = fmt.Sprintf("p.%s = ?", col)
+		}
+		args = append(args, v)
+	case query.EQ:
+		vc = fmt.Sprintf("p.%s = ?", col)
+		args = append(args, v)
+	case query.NE:
+		vc = fmt.Sprintf("p.%s = ?", col)
+		args = append(args, v)
+		exists = "NOT EXISTS"
+	case query.LT, query.LE, query.GT, query.GE:
+		vc = fmt.Sprintf("p.%s %s ?", col, c.Operator)
+		args = append(args, v)
+	case query.CI:
+		// like is case insensitive for ascii characters
+		vc = `p.str LIKE ? ESCAPE '\'`
+		args = append(args, toLike(cToStr(c)))
+	case query.IN:
+		vs, ok := c.Value.([]any)
+		if !ok || len(vs) == 0 {
+			return "", nil, fmt.Errorf("missing list of values for field %s", c.Field)
+		}
+		ors := make([]string, len(vs))
+		for i, x := range vs {
+			col, v := cToValue(query.Condition{Value: x})
+			ors[i] = fmt.Sprintf("p.%s = ?", col)
+			args = append(args, v)
+		}
+		vc = "(" + strings.Join(ors, " OR ") + ")"
+	case query.BT:
+		r, ok := c.Value.(query.Range)
+		if !ok {
+			return "", nil, fmt.Errorf("missing range for field %s", c.Field)
+		}
+		col, f := cToValue(query.Condition{Value: r.From})
+		_, t := cToValue(query.Condition{Value: r.To})
+		vc = fmt.Sprintf("p.%s >= ? AND p.%s <= ?", col, col)
+		args = append(args, f, t)
+	case query.EX:
+	default:
+		return "", nil, fmt.Errorf("unknown operator %s", c.Operator)
+	}
+	if vc != "" {
+		vc = " AND " + vc
+	}
+	s := fmt.Sprintf("%s (SELECT 1 FROM props p WHERE p.tenant = b.tenant AND p.blobid = b.blobid AND p.name = ?%s)", exists, vc)
+	if c.Invert {
+		s = "NOT " + s
+	}
+	return s, args, nil
+}
+
+// cToValue getting the column and the value of the condition, numbers are compared numerically
+func cToValue(c query.Condition) (string, any) {
+	switch v := c.Value.(type) {
+	case float64:
+		return "num", v
+	case int:
+		return "num", float64(v)
+	case int64:
+		return "num", float64(v)
+	}
+	vs := cToStr(c)
+	if !c.HasWildcard() {
+		if f, err := strconv.ParseFloat(vs, 64); err == nil {
+			return "num", f
+		}
+	}
+	return "str", vs
+}
+
+func cToStr(c query.Condition) string {
+	vs := c.VtoS()
+	vs = strings.TrimPrefix(vs, `"`)
+	vs = strings.TrimSuffix(vs, `"`)
+	return vs
+}
+
+// toLike converting the wildcards * and ? into a like pattern
+func toLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`, `?`, `_`)
+	return r.Replace(s)
+}
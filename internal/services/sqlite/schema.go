@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations the steps to the actual schema, the version of the schema is the count of applied steps.
+// A new version is added as a new step at the end, existing steps are never changed.
+var migrations = []string{
+	// 1: blobs with the description as json, the typed values of the properties
+	`CREATE TABLE blobs (
+		tenant TEXT NOT NULL,
+		blobid TEXT NOT NULL,
+		doc    TEXT NOT NULL,
+		PRIMARY KEY (tenant, blobid)
+	) WITHOUT ROWID;
+	CREATE TABLE props (
+		tenant TEXT NOT NULL,
+		blobid TEXT NOT NULL,
+		name   TEXT NOT NULL,
+		str    TEXT,
+		num    REAL
+	);
+	CREATE INDEX props_blob ON props (tenant, blobid);
+	CREATE INDEX props_str ON props (tenant, name, str);
+	CREATE INDEX props_num ON props (tenant, name, num);`,
+}
+
+// SchemaVersion the version of the actual schema
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// migrate migrating the schema of the database to the actual version, every step in its own transaction
+func migrate(sdb *sql.DB) error {
+	version, err := schemaVersion(sdb)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d of the sqlite index is newer than the supported version %d", version, len(migrations))
+	}
+	for x := version; x < len(migrations); x++ {
+		tx, err := sdb.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[x]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migrating sqlite index to version %d: %w", x+1, err)
+		}
+		// pragmas can't be parameterised
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", x+1)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		logger.Infof("sqlite index migrated to schema version %d", x+1)
+	}
+	return nil
+}
+
+func schemaVersion(sdb *sql.DB) (int, error) {
+	var version int
+	err := sdb.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
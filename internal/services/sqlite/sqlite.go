@@ -0,0 +1,343 @@
+// Package sqlite using an embedded SQLite database as index engine for the search. The database is pure go, no cgo is needed.
+// All tenants are sharing one database file, every property value of a blob is a typed row in the props table.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/api"
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/internal/logging"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/pkg/model"
+	"github.com/willie68/GoBlobStore/pkg/model/query"
+
+	// the pure go sqlite driver
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteIndex name of the index engine
+const SQLiteIndex = "sqlite"
+
+// DBFile the name of the database file in the root path
+const DBFile = "index.db"
+
+var (
+	_      interfaces.Index            = &Index{}
+	_      interfaces.QueryExplainer   = &Index{}
+	_      interfaces.MultiTenantIndex = &Index{}
+	_      interfaces.IndexBatch       = &IndexBatch{}
+	logger                             = logging.New().WithName("sqlite")
+)
+
+// Index one index for a tenant
+type Index struct {
+	Tenant string
+}
+
+// IndexBatch for bulk indexing, all descriptions are indexed in one transaction
+type IndexBatch struct {
+	docs  []model.BlobDescription
+	index *Index
+}
+
+// Config the config for the indexer
+type Config struct {
+	Rootpath string `yaml:"rootpath"`
+}
+
+var (
+	scnfg Config
+	db    *sql.DB
+)
+
+// InitSQLite opening the database in the root path and migrating the schema to the actual version
+func InitSQLite(p map[string]any) error {
+	jsonStr, err := json.Marshal(p)
+	if err != nil {
+		logger.Errorf("%v", err)
+		return err
+	}
+	err = json.Unmarshal(jsonStr, &scnfg)
+	if err != nil {
+		logger.Errorf("%v", err)
+		return err
+	}
+	if scnfg.Rootpath == "" {
+		return errors.New("no rootpath for the sqlite index given. check config")
+	}
+	err = os.MkdirAll(scnfg.Rootpath, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", filepath.Join(scnfg.Rootpath, DBFile))
+	sdb, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+	err = migrate(sdb)
+	if err != nil {
+		_ = sdb.Close()
+		return err
+	}
+	db = sdb
+	return nil
+}
+
+// CloseSQLite closing the database
+func CloseSQLite() {
+	if db != nil {
+		if err := db.Close(); err != nil {
+			logger.Errorf("error closing database: %v", err)
+		}
+		db = nil
+	}
+}
+
+// Init initialisation of one tenant indexer
+func (m *Index) Init() error {
+	m.Tenant = strings.ToLower(m.Tenant)
+	if db == nil {
+		return errors.New("sqlite index not initialised")
+	}
+	return nil
+}
+
+// Search doing a search for the tenant, the ids are delivered ordered
+func (m *Index) Search(qry string, callback func(id string) bool) error {
+	if strings.HasPrefix(qry, "#") {
+		return errors.New("native queries are not supported by the sqlite index")
+	}
+	q, err := buildAST(qry)
+	if err != nil {
+		return err
+	}
+	stmt, args, err := m.statement(*q)
+	if err != nil {
+		return err
+	}
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		if !callback(id) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+// SearchTenants searching the blobs of all given tenants with one statement, the hits are delivered ordered by the lower case tenant and blob id
+func (m *Index) SearchTenants(qry string, tenants []string, callback func(tenant, id string) bool) error {
+	if strings.HasPrefix(qry, "#") {
+		return errors.New("native queries are not supported by the sqlite index")
+	}
+	if len(tenants) == 0 {
+		return nil
+	}
+	q, err := buildAST(qry)
+	if err != nil {
+		return err
+	}
+	where, wargs, err := ToSQL(*q)
+	if err != nil {
+		return err
+	}
+	// the tenants are stored in lower case, the hits are delivered with the given tenant names
+	names := make(map[string]string)
+	args := make([]any, 0, len(tenants)+len(wargs))
+	for _, t := range tenants {
+		names[strings.ToLower(t)] = t
+		args = append(args, strings.ToLower(t))
+	}
+	args = append(args, wargs...)
+	ph := strings.TrimSuffix(strings.Repeat("?, ", len(tenants)), ", ")
+	stmt := fmt.Sprintf("SELECT b.tenant, b.blobid FROM blobs b WHERE b.tenant IN (%s) AND %s ORDER BY b.tenant, b.blobid", ph, where)
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tenant, id string
+		if err := rows.Scan(&tenant, &id); err != nil {
+			return err
+		}
+		if !callback(names[tenant], id) {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+// ExplainQuery getting the sql statement with the arguments for a parsed query
+func (m *Index) ExplainQuery(q query.Query) (string, any, error) {
+	stmt, args, err := m.statement(q)
+	if err != nil {
+		return SQLiteIndex, nil, err
+	}
+	return SQLiteIndex, map[string]any{"sql": stmt, "args": args}, nil
+}
+
+// statement building the select statement for the blob ids of the tenant
+func (m *Index) statement(q query.Query) (string, []any, error) {
+	where, args, err := ToSQL(q)
+	if err != nil {
+		return "", nil, err
+	}
+	stmt := fmt.Sprintf("SELECT b.blobid FROM blobs b WHERE b.tenant = ? AND %s ORDER BY b.blobid", where)
+	return stmt, append([]any{m.Tenant}, args...), nil
+}
+
+func buildAST(q string) (*query.Query, error) {
+	qu, err := query.ParseQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	return &qu, nil
+}
+
+// Index indexing a single blob, an already indexed blob is replaced
+func (m *Index) Index(id string, b model.BlobDescription) error {
+	if id != b.BlobID {
+		return fmt.Errorf(`ID "%s" is not equal to BlobID "%s" `, id, b.BlobID)
+	}
+	return m.index([]model.BlobDescription{b})
+}
+
+func (m *Index) index(bds []model.BlobDescription) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	del, err := tx.Prepare("DELETE FROM props WHERE tenant = ? AND blobid = ?")
+	if err != nil {
+		return err
+	}
+	defer del.Close()
+	doc, err := tx.Prepare("INSERT OR REPLACE INTO blobs (tenant, blobid, doc) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer doc.Close()
+	prop, err := tx.Prepare("INSERT INTO props (tenant, blobid, name, str, num) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer prop.Close()
+
+	for _, b := range bds {
+		js, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		if _, err := del.Exec(m.Tenant, b.BlobID); err != nil {
+			return err
+		}
+		if _, err := doc.Exec(m.Tenant, b.BlobID, string(js)); err != nil {
+			return err
+		}
+		for k, i := range b.Map() {
+			key := strings.TrimPrefix(k, config.Get().HeaderMapping[api.HeaderPrefixKey])
+			for _, v := range values(i) {
+				str, num := typed(v)
+				if _, err := prop.Exec(m.Tenant, b.BlobID, key, str, num); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// values getting the single values of a property, a list is giving a value for every element
+func values(i any) []any {
+	if i == nil {
+		return nil
+	}
+	switch v := i.(type) {
+	case string, []byte:
+		return []any{v}
+	}
+	rv := reflect.ValueOf(i)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		vs := make([]any, 0, rv.Len())
+		for x := 0; x < rv.Len(); x++ {
+			vs = append(vs, values(rv.Index(x).Interface())...)
+		}
+		return vs
+	}
+	return []any{i}
+}
+
+// typed getting the string and the numeric value of a single value, the numeric value is nil if the value is not a number.
+// Structures are not indexed.
+func typed(v any) (any, any) {
+	switch t := v.(type) {
+	case string:
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return t, f
+		}
+		return t, nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case time.Time:
+		return t.UTC().Format(time.RFC3339), t.UnixMilli()
+	case int, int8, int16, int32, int64:
+		n := reflect.ValueOf(t).Int()
+		return strconv.FormatInt(n, 10), float64(n)
+	case uint, uint8, uint16, uint32, uint64:
+		n := reflect.ValueOf(t).Uint()
+		return strconv.FormatUint(n, 10), float64(n)
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32), float64(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), t
+	}
+	return nil, nil
+}
+
+// NewBatch creating a new batch for bulk index
+func (m *Index) NewBatch() interfaces.IndexBatch {
+	return &IndexBatch{index: m}
+}
+
+// Add adding a single blob description to the batch
+func (i *IndexBatch) Add(id string, b model.BlobDescription) error {
+	if id != b.BlobID {
+		return fmt.Errorf(`ID "%s" is not equal to BlobID "%s" `, id, b.BlobID)
+	}
+	i.docs = append(i.docs, b)
+	return nil
+}
+
+// Index indexing all blobs of the batch in one transaction
+func (i *IndexBatch) Index() error {
+	if len(i.docs) == 0 {
+		return nil
+	}
+	err := i.index.index(i.docs)
+	if err != nil {
+		return err
+	}
+	i.docs = make([]model.BlobDescription, 0)
+	return nil
+}
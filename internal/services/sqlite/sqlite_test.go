@@ -0,0 +1,292 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/pkg/model"
+	"github.com/willie68/GoBlobStore/pkg/model/query"
+)
+
+const sqlitefolder = "sqlite"
+
+var (
+	testroot = os.Getenv("gotestroot")
+	rootpath string
+)
+
+func InitT(t *testing.T) {
+	rootpath = filepath.Join("../../../testdata/", sqlitefolder)
+	if testroot != "" {
+		rootpath = filepath.Join(testroot, sqlitefolder)
+		t.Logf("using base path %s", rootpath)
+	}
+	CloseSQLite()
+	_ = os.RemoveAll(rootpath)
+	err := InitSQLite(map[string]any{"rootpath": rootpath})
+	assert.Nil(t, err)
+	assert.NotNil(t, db)
+}
+
+func getBlobDescription(id string, num int) model.BlobDescription {
+	b := model.BlobDescription{
+		BlobID:        id,
+		StoreID:       "MCS",
+		TenantID:      "MCS",
+		ContentLength: 22,
+		ContentType:   "text/plain",
+		CreationDate:  time.Now().UnixMilli(),
+		Filename:      fmt.Sprintf("test_%d.txt", num),
+		LastAccess:    time.Now().UnixMilli(),
+		Retention:     180000,
+		Properties:    make(map[string]any),
+	}
+	b.Properties["X-user"] = []string{"Hallo", "Hallo2"}
+	b.Properties["X-retention"] = []int{num}
+	b.Properties["X-tenant"] = "MCS"
+	b.Properties["X-intfield"] = num
+	return b
+}
+
+func search(ast *assert.Assertions, idx *Index, q string) []string {
+	rets := make([]string, 0)
+	err := idx.Search(q, func(id string) bool {
+		rets = append(rets, id)
+		return true
+	})
+	ast.Nil(err, q)
+	return rets
+}
+
+func TestMigration(t *testing.T) {
+	ast := assert.New(t)
+	InitT(t)
+	v, err := schemaVersion(db)
+	ast.Nil(err)
+	ast.Equal(SchemaVersion(), v)
+
+	// opening a migrated database again is not changing anything
+	CloseSQLite()
+	ast.Nil(InitSQLite(map[string]any{"rootpath": rootpath}))
+	v, err = schemaVersion(db)
+	ast.Nil(err)
+	ast.Equal(SchemaVersion(), v)
+	CloseSQLite()
+
+	// a database of a newer version can't be used
+	sdb, err := sql.Open("sqlite", filepath.Join(rootpath, DBFile))
+	ast.Nil(err)
+	_, err = sdb.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion()+1))
+	ast.Nil(err)
+	ast.Nil(sdb.Close())
+	ast.NotNil(InitSQLite(map[string]any{"rootpath": rootpath}))
+	ast.NotNil(InitSQLite(map[string]any{}))
+}
+
+var tests = []struct {
+	q  string
+	id string
+	n  int
+}{
+	{q: `tenant:MCS`, n: 100},
+	{q: `tenant:"MCS" AND user:"Hallo"`, n: 100},
+	{q: `user:Hallo2`, n: 100},
+	{q: `intfield:=1234`, id: "123434", n: 1},
+	{q: `intfield:1234`, id: "123434", n: 1},
+	{q: `intfield:"1234"`, id: "123434", n: 1},
+	{q: `intfield:>1234`, n: 65},
+	{q: `intfield:<1234`, n: 34},
+	{q: `intfield:>=1234`, n: 66},
+	{q: `intfield:<=1234`, n: 35},
+	{q: `intfield:!=1234`, n: 99},
+	{q: `NOT intfield:>1200`, id: "12340", n: 1},
+	{q: `intfield:<1202 OR intfield:>1297`, n: 4},
+	{q: `(intfield:<1202 OR intfield:>1297)`, n: 4},
+	{q: `intfield:>1200 AND NOT user:Hurz AND tenant:MCS`, n: 99},
+	{q: `retention:1250`, id: "123450", n: 1},
+	{q: `user:H*`, n: 100},
+	{q: `user:hallo?`, n: 100},
+	{q: `filename:"test_121?.txt"`, n: 10},
+	{q: `filename:"test_1212.txt"`, id: "123412", n: 1},
+	{q: `filename:"test_12%.txt"`, n: 0},
+	{q: `contentType:"text/plain"`, n: 100},
+	{q: `user:Hurz`, n: 0},
+	{q: `unknown:1234`, n: 0},
+	{q: `intfield:IN(1201, 1234, 1299, 1300)`, n: 3},
+	{q: `user:IN(Hurz, "Hallo2")`, n: 100},
+	{q: `intfield:[1210~1220]`, n: 11},
+	{q: `!intfield:[1210~1220]`, n: 89},
+	{q: `intfield:[1220~1220]`, id: "123420", n: 1},
+	{q: `EXISTS(intfield)`, n: 100},
+	{q: `EXISTS(unknown)`, n: 0},
+	{q: `NOT EXISTS(unknown) AND intfield:=1234`, id: "123434", n: 1},
+	{q: `creationDate:>now-1h`, n: 100},
+	{q: `creationDate:[now-1h~now+1h]`, n: 100},
+	{q: `creationDate:<now-1d`, n: 0},
+	{q: `user:~HALLO`, n: 100},
+	{q: `filename:~"TEST_121?.TXT"`, n: 10},
+	{q: `NOT (intfield:<1210 OR intfield:>=1220)`, n: 10},
+	{q: `(intfield:<1210 OR intfield:>=1290) AND tenant:MCS`, n: 20},
+	{q: `tenant:MCS AND NOT (intfield:>1200 AND intfield:<1299)`, n: 2},
+}
+
+func TestQueryConvertion(t *testing.T) {
+	ast := assert.New(t)
+	InitT(t)
+
+	idx := Index{
+		Tenant: "MCS",
+	}
+	ast.Nil(idx.Init())
+
+	bt := idx.NewBatch()
+	for x := 0; x < 100; x++ {
+		id := fmt.Sprintf("1234%d", x)
+		b := getBlobDescription(id, 1200+x)
+		err := bt.Add(b.BlobID, b)
+		ast.Nil(err, "adding to batch")
+	}
+	ast.NotNil(bt.Add("wrong", getBlobDescription("1234", 1)))
+	ast.Nil(bt.Index(), "indexing")
+
+	for _, tc := range tests {
+		rets := search(ast, &idx, tc.q)
+		ast.Equal(tc.n, len(rets), tc.q)
+		if tc.n == 1 && len(rets) > 0 {
+			ast.Equal(tc.id, rets[0], tc.q)
+		}
+	}
+
+	// the callback can stop the search
+	count := 0
+	ast.Nil(idx.Search(`tenant:MCS`, func(_ string) bool {
+		count++
+		return count < 10
+	}))
+	ast.Equal(10, count)
+
+	ast.NotNil(idx.Search(`#SELECT 1`, func(_ string) bool { return true }))
+	ast.NotNil(idx.Search(`intfield:`, func(_ string) bool { return true }))
+}
+
+func TestIndexTenants(t *testing.T) {
+	ast := assert.New(t)
+	InitT(t)
+
+	idx1 := Index{Tenant: "MCS"}
+	ast.Nil(idx1.Init())
+	idx2 := Index{Tenant: "other"}
+	ast.Nil(idx2.Init())
+
+	b := getBlobDescription("123456789", 1234)
+	ast.Nil(idx1.Index(b.BlobID, b))
+	ast.NotNil(idx1.Index("wrong", b))
+	ast.Equal([]string{b.BlobID}, search(ast, &idx1, `intfield:1234`))
+	ast.Empty(search(ast, &idx2, `intfield:1234`))
+
+	// indexing again replaces all values
+	b.Properties["X-intfield"] = 4321
+	ast.Nil(idx1.Index(b.BlobID, b))
+	ast.Empty(search(ast, &idx1, `intfield:1234`))
+	ast.Equal([]string{b.BlobID}, search(ast, &idx1, `intfield:4321`))
+	var count int
+	ast.Nil(db.QueryRow("SELECT count(*) FROM blobs").Scan(&count))
+	ast.Equal(1, count)
+	CloseSQLite()
+	ast.NotNil(idx1.Init())
+}
+
+func TestSearchTenants(t *testing.T) {
+	ast := assert.New(t)
+	InitT(t)
+
+	idx1 := Index{Tenant: "MCS"}
+	ast.Nil(idx1.Init())
+	idx2 := Index{Tenant: "other"}
+	ast.Nil(idx2.Init())
+	idx3 := Index{Tenant: "third"}
+	ast.Nil(idx3.Init())
+
+	b := getBlobDescription("b1", 1234)
+	ast.Nil(idx2.Index(b.BlobID, b))
+	b = getBlobDescription("a1", 1234)
+	ast.Nil(idx2.Index(b.BlobID, b))
+	b = getBlobDescription("c1", 1234)
+	ast.Nil(idx1.Index(b.BlobID, b))
+	b = getBlobDescription("d1", 1234)
+	ast.Nil(idx3.Index(b.BlobID, b))
+	b = getBlobDescription("e1", 4321)
+	ast.Nil(idx1.Index(b.BlobID, b))
+
+	hits := make([]string, 0)
+	var idx Index
+	err := idx.SearchTenants(`intfield:1234`, []string{"MCS", "other"}, func(tenant, id string) bool {
+		hits = append(hits, tenant+"/"+id)
+		return true
+	})
+	ast.Nil(err)
+	ast.Equal([]string{"MCS/c1", "other/a1", "other/b1"}, hits)
+
+	hits = make([]string, 0)
+	err = idx.SearchTenants(`intfield:1234`, []string{"MCS", "other", "third"}, func(tenant, id string) bool {
+		hits = append(hits, tenant+"/"+id)
+		return len(hits) < 2
+	})
+	ast.Nil(err)
+	ast.Equal([]string{"MCS/c1", "other/a1"}, hits)
+
+	ast.Nil(idx.SearchTenants(`intfield:1234`, []string{}, func(_, _ string) bool {
+		ast.Fail("no tenants, no hits")
+		return true
+	}))
+	ast.NotNil(idx.SearchTenants(`#{"intfield": 1234}`, []string{"MCS"}, func(_, _ string) bool { return true }))
+	ast.NotNil(idx.SearchTenants(`intfield:(`, []string{"MCS"}, func(_, _ string) bool { return true }))
+}
+
+func TestToSQL(t *testing.T) {
+	ast := assert.New(t)
+	q := query.Query{
+		Condition: query.Node{
+			Operator: query.OROP,
+			Conditions: []any{
+				query.Condition{Field: "field1", Operator: query.NO, Value: `"Wil_*"`},
+				query.Condition{Field: "field2", Operator: query.GT, Value: 100.0, Invert: true},
+				query.Condition{Field: "field3", Operator: query.NE, Value: "Max"},
+			},
+		},
+	}
+	s, args, err := ToSQL(q)
+	ast.Nil(err)
+	ast.Equal(`(EXISTS (SELECT 1 FROM props p WHERE p.tenant = b.tenant AND p.blobid = b.blobid AND p.name = ? AND p.str LIKE ? ESCAPE '\') OR `+
+		`NOT EXISTS (SELECT 1 FROM props p WHERE p.tenant = b.tenant AND p.blobid = b.blobid AND p.name = ? AND p.num > ?) OR `+
+		`NOT EXISTS (SELECT 1 FROM props p WHERE p.tenant = b.tenant AND p.blobid = b.blobid AND p.name = ? AND p.str = ?))`, s)
+	ast.Equal([]any{"field1", `Wil\_%`, "field2", 100.0, "field3", "Max"}, args)
+
+	_, _, err = ToSQL(query.Query{Condition: query.Node{Operator: query.ANDOP}})
+	ast.NotNil(err)
+	_, _, err = ToSQL(query.Query{Condition: "field"})
+	ast.NotNil(err)
+}
+
+func TestExplainQuery(t *testing.T) {
+	ast := assert.New(t)
+	idx := Index{Tenant: "mcs"}
+
+	q, err := query.ParseQuery(`intfield:>1234 AND EXISTS(user)`)
+	ast.Nil(err)
+	name, native, err := idx.ExplainQuery(q)
+	ast.Nil(err)
+	ast.Equal(SQLiteIndex, name)
+	m, ok := native.(map[string]any)
+	ast.True(ok)
+	ast.Contains(m["sql"], "SELECT b.blobid FROM blobs b WHERE b.tenant = ? AND (EXISTS")
+	ast.Equal([]any{"mcs", "intfield", 1234.0, "user"}, m["args"])
+
+	_, _, err = idx.ExplainQuery(query.Query{Condition: query.Node{Operator: query.ANDOP}})
+	ast.NotNil(err)
+}
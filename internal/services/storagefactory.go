@@ -2,6 +2,8 @@ package services
 
 import (
 	"errors"
+	"path/filepath"
+	"strings"
 
 	"github.com/samber/do"
 	"github.com/willie68/GoBlobStore/internal/services/business"
@@ -10,6 +12,9 @@ import (
 	"github.com/willie68/GoBlobStore/internal/config"
 	"github.com/willie68/GoBlobStore/internal/services/factory"
 	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/scrubber"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/internal/services/volume"
 )
 
 // all constant definitions for the different services
@@ -18,6 +23,7 @@ const (
 	DoRtnMgr = "rtnmgr"
 	DoStgf   = "stgf"
 	DoMigMgr = "migmgr"
+	DoAudit  = "audit"
 )
 
 var tntsrv interfaces.TenantManager
@@ -25,6 +31,11 @@ var rtnMgr interfaces.RetentionManager
 var cnfg config.Engine
 var stgf interfaces.StorageFactory
 var migMan *migration.Management
+var audit interfaces.AuditJournal
+var volMan *volume.Manager
+var volMover volume.Mover
+var layoutMig *simplefile.LayoutMigrator
+var scrub *scrubber.Scrubber
 
 // Init initialize the storage factory
 func Init(storage config.Engine) error {
@@ -46,10 +57,20 @@ func Init(storage config.Engine) error {
 		}
 	}
 
-	tntsrv = &business.MainTenant{
+	if cnfg.Audit.Storageclass != "" {
+		audit, err = factory.CreateAuditJournal(cnfg.Audit)
+		if err != nil {
+			return err
+		}
+		do.ProvideNamedValue[interfaces.AuditJournal](nil, DoAudit, audit)
+	}
+
+	mainTnt := &business.MainTenant{
 		TntSrv: tntMgr,
 		BckSrv: bktsrv,
+		Audit:  audit,
 	}
+	tntsrv = mainTnt
 
 	do.ProvideNamedValue[interfaces.TenantManager](nil, DoTntSrv, tntsrv)
 
@@ -60,7 +81,9 @@ func Init(storage config.Engine) error {
 	// this order of creation of factories is crucial, because the RetentionManager needs the StorageFactory and other way round
 	stgf = &factory.DefaultStorageFactory{
 		TenantMgr: tntsrv,
+		Audit:     audit,
 	}
+	mainTnt.StgFactory = stgf
 
 	rtnMgr, err = factory.CreateRetentionManager(cnfg.RetentionManager, tntsrv)
 	if err != nil {
@@ -81,8 +104,22 @@ func Init(storage config.Engine) error {
 		return err
 	}
 
+	rptPath := cnfg.CheckReports.RootPath
+	if rptPath == "" {
+		// defaults to a folder in the root of the storage, a folder with a leading _ is no tenant.
+		// A storage without a root path gets the default of the report store.
+		rootpath, _ := config.GetConfigValueAsString(cnfg.Storage.Properties, "rootpath")
+		if rootpath != "" {
+			rptPath = filepath.Join(rootpath, "_checks")
+		}
+	}
 	migMan = &migration.Management{
 		StorageFactory: stgf,
+		Reports: &migration.ReportStore{
+			RootPath: rptPath,
+			Keep:     cnfg.CheckReports.Keep,
+			MaxAge:   cnfg.CheckReports.MaxAge,
+		},
 	}
 	err = migMan.Init()
 	if err != nil {
@@ -97,6 +134,29 @@ func Init(storage config.Engine) error {
 
 	do.ProvideNamedValue[*migration.Management](nil, DoMigMgr, migMan)
 
+	if strings.ToLower(cnfg.Storage.Storageclass) == factory.STGClassSFMV {
+		volMan, volMover, err = factory.CreateVolumeManager(cnfg.Storage, stgf)
+		if err != nil {
+			return err
+		}
+	}
+	if strings.ToLower(cnfg.Storage.Storageclass) == factory.STGClassSimpleFile {
+		layoutMig, err = factory.CreateLayoutMigrator(cnfg.Storage)
+		if err != nil {
+			return err
+		}
+	}
+	if cnfg.Scrubber.Enable {
+		scrub = &scrubber.Scrubber{
+			StgFactory: stgf,
+			TntMgr:     tntsrv,
+			Config:     cnfg.Scrubber,
+		}
+		err = scrub.Init()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -116,6 +176,65 @@ func GetStorageFactory() (interfaces.StorageFactory, error) {
 	return stgf, nil
 }
 
+// GetAuditJournal returning the audit journal for deletions
+func GetAuditJournal() (interfaces.AuditJournal, error) {
+	if audit == nil {
+		return nil, errors.New("no audit journal configured")
+	}
+	return audit, nil
+}
+
+// GetVolumeManager returning the volume manager and the mover of the multi volume storage
+func GetVolumeManager() (*volume.Manager, volume.Mover, error) {
+	if volMan == nil {
+		return nil, nil, errors.New("no volume manager present")
+	}
+	return volMan, volMover, nil
+}
+
+// GetLayoutMigrator returning the migrator for the layouts of the simple file storage
+func GetLayoutMigrator() (*simplefile.LayoutMigrator, error) {
+	if layoutMig == nil {
+		return nil, errors.New("no layout migrator present")
+	}
+	return layoutMig, nil
+}
+
+// GetStorageMigrator returning the migrator for the primary storages of the tenants
+func GetStorageMigrator() (interfaces.StorageMigrator, error) {
+	mig, ok := stgf.(interfaces.StorageMigrator)
+	if !ok {
+		return nil, errors.New("storage factory doesn't support storage migrations")
+	}
+	return mig, nil
+}
+
+// GetCacheManager returning the manager of the cache
+func GetCacheManager() (interfaces.CacheManager, error) {
+	cm, ok := stgf.(interfaces.CacheManager)
+	if !ok {
+		return nil, errors.New("storage factory doesn't support cache management")
+	}
+	return cm, nil
+}
+
+// GetTenantSearcher returning the searcher over the blobs of all tenants
+func GetTenantSearcher() (interfaces.TenantSearcher, error) {
+	ts, ok := stgf.(interfaces.TenantSearcher)
+	if !ok {
+		return nil, errors.New("storage factory doesn't support searching all tenants")
+	}
+	return ts, nil
+}
+
+// GetScrubber returning the background scrubber
+func GetScrubber() (*scrubber.Scrubber, error) {
+	if scrub == nil {
+		return nil, errors.New("no scrubber configured")
+	}
+	return scrub, nil
+}
+
 // GetMigrationManagement returning the tenant for administration tenants
 func GetMigrationManagement() (*migration.Management, error) {
 	if migMan == nil {
@@ -126,6 +245,12 @@ func GetMigrationManagement() (*migration.Management, error) {
 
 // Close closing ths storage factory
 func Close() {
+	if scrub != nil {
+		if err := scrub.Close(); err != nil {
+			logger.Errorf("error closing scrubber:\r\n%v,", err)
+		}
+	}
+
 	err := stgf.Close()
 	if err != nil {
 		logger.Errorf("error closing storage factory:\r\n%v,", err)
@@ -145,4 +270,11 @@ func Close() {
 	if err != nil {
 		logger.Errorf("error closing check management:\r\n%v,", err)
 	}
+
+	if audit != nil {
+		err = audit.Close()
+		if err != nil {
+			logger.Errorf("error closing audit journal:\r\n%v,", err)
+		}
+	}
 }
@@ -0,0 +1,95 @@
+package tiering
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/config"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// Rule a rule for demoting a blob from the hot to the cold tier. All given conditions of a rule must match.
+type Rule struct {
+	Age         time.Duration // the blob is older than this
+	LastAccess  time.Duration // the last access of the blob is older than this
+	MinSize     int64         // the blob is at least this size in bytes
+	ContentType string        // the content type of the blob starts with this, e.g. video/
+}
+
+// Match checking if the blob description matches this rule at the time now
+func (r Rule) Match(b *model.BlobDescription, now time.Time) bool {
+	if r.Age == 0 && r.LastAccess == 0 && r.MinSize == 0 && r.ContentType == "" {
+		return false
+	}
+	if r.Age > 0 && time.UnixMilli(b.CreationDate).Add(r.Age).After(now) {
+		return false
+	}
+	if r.LastAccess > 0 {
+		la := b.LastAccess
+		if la == 0 {
+			la = b.CreationDate
+		}
+		if time.UnixMilli(la).Add(r.LastAccess).After(now) {
+			return false
+		}
+	}
+	if r.MinSize > 0 && b.ContentLength < r.MinSize {
+		return false
+	}
+	if r.ContentType != "" && !strings.HasPrefix(strings.ToLower(b.ContentType), strings.ToLower(r.ContentType)) {
+		return false
+	}
+	return true
+}
+
+// matchRules checking if one of the rules match
+func matchRules(rules []Rule, b *model.BlobDescription, now time.Time) bool {
+	for _, r := range rules {
+		if r.Match(b, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRules getting the rules from the config, age and lastaccess are given in minutes
+func ParseRules(cfg []any) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	for x, c := range cfg {
+		props, ok := c.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("tiering: rule %d is not a map", x)
+		}
+		r := Rule{}
+		age, err := optInt(props, "age")
+		if err != nil {
+			return nil, err
+		}
+		r.Age = time.Duration(age) * time.Minute
+		la, err := optInt(props, "lastaccess")
+		if err != nil {
+			return nil, err
+		}
+		r.LastAccess = time.Duration(la) * time.Minute
+		r.MinSize, err = optInt(props, "minsize")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := props["contenttype"]; ok {
+			r.ContentType, err = config.GetConfigValueAsString(props, "contenttype")
+			if err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func optInt(props map[string]any, key string) (int64, error) {
+	if _, ok := props[key]; !ok {
+		return 0, nil
+	}
+	return config.GetConfigValueAsInt(props, key)
+}
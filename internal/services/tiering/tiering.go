@@ -0,0 +1,407 @@
+// Package tiering contains a storage, which is moving the blobs between a hot and a cold storage
+package tiering
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/logging"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/utils"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+// TieredStorageName name of this storage class
+const TieredStorageName = "tiered"
+
+// DefaultInterval default interval of the background mover
+const DefaultInterval = time.Hour
+
+var logger = logging.New().WithName("tiering")
+
+// checking interface compatibility
+var (
+	_ interfaces.BlobStorage   = &TieredStorage{}
+	_ interfaces.TieredStorage = &TieredStorage{}
+)
+
+// ErrNotFound blob not found on both tiers
+var ErrNotFound = errors.New("blob not found")
+
+// TieredStorage this storage wraps a hot and a cold storage. New blobs are always stored on the hot storage,
+// a background mover demotes blobs by the configured rules to the cold storage.
+type TieredStorage struct {
+	Hot      interfaces.BlobStorage
+	Cold     interfaces.BlobStorage
+	Tenant   string
+	Rules    []Rule
+	Promote  bool          // reading a blob from the cold storage will promote the blob back to the hot storage
+	Interval time.Duration // interval of the background mover
+	listener func(id string, b *model.BlobDescription)
+	mm       sync.Mutex
+	lm       sync.Mutex
+	moving   map[string]bool
+	locks    utils.IDLocks // a delete must wait for a running move of the blob
+	ticker   *time.Ticker
+	quit     chan bool
+}
+
+// Init initialize this service
+func (t *TieredStorage) Init() error {
+	if t.Hot == nil || t.Cold == nil {
+		return errors.New("tiering: hot and cold storage must be given")
+	}
+	if t.Interval <= 0 {
+		t.Interval = DefaultInterval
+	}
+	t.moving = make(map[string]bool)
+	t.quit = make(chan bool)
+	t.ticker = time.NewTicker(t.Interval)
+	go func() {
+		for {
+			select {
+			case <-t.ticker.C:
+				if _, err := t.Demote(); err != nil {
+					logger.Errorf("tiering: error on moving blobs of tenant %s: %v", t.Tenant, err)
+				}
+			case <-t.quit:
+				t.ticker.Stop()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// SetMoveListener registering a listener, which will be called after a blob has been moved to another tier
+func (t *TieredStorage) SetMoveListener(listener func(id string, b *model.BlobDescription)) {
+	t.lm.Lock()
+	defer t.lm.Unlock()
+	t.listener = listener
+}
+
+// GetTenant return the id of the tenant
+func (t *TieredStorage) GetTenant() string {
+	return t.Tenant
+}
+
+// GetBlobs walking thru all blobs of both tiers
+func (t *TieredStorage) GetBlobs(callback func(id string) bool) error {
+	stopped := false
+	cb := func(id string) bool {
+		ok := callback(id)
+		stopped = !ok
+		return ok
+	}
+	err := t.Hot.GetBlobs(cb)
+	if stopped || (err != nil && !errors.Is(err, io.EOF)) {
+		return err
+	}
+	return t.Cold.GetBlobs(cb)
+}
+
+// StoreBlob storing a blob to the hot storage
+func (t *TieredStorage) StoreBlob(b *model.BlobDescription, f io.Reader) (string, error) {
+	b.Tier = model.TierHot
+	return t.Hot.StoreBlob(b, f)
+}
+
+// UpdateBlobDescription updating the blob description on the tier holding the blob
+func (t *TieredStorage) UpdateBlobDescription(id string, b *model.BlobDescription) error {
+	srv, tier, err := t.srv4id(id)
+	if err != nil {
+		return err
+	}
+	b.Tier = tier
+	return srv.UpdateBlobDescription(id, b)
+}
+
+// HasBlob checking if one of the tiers has this blob
+func (t *TieredStorage) HasBlob(id string) (bool, error) {
+	ok, err := t.Hot.HasBlob(id)
+	if err == nil && ok {
+		return true, nil
+	}
+	return t.Cold.HasBlob(id)
+}
+
+// GetBlobDescription getting the blob description with the actual tier of the blob
+func (t *TieredStorage) GetBlobDescription(id string) (*model.BlobDescription, error) {
+	b, err := t.Hot.GetBlobDescription(id)
+	if err == nil {
+		b.Tier = model.TierHot
+		return b, nil
+	}
+	b, cerr := t.Cold.GetBlobDescription(id)
+	if cerr != nil {
+		return nil, err
+	}
+	b.Tier = model.TierCold
+	return b, nil
+}
+
+// RetrieveBlob retrieving the blob from the tier holding the blob, optionally promoting the blob to the hot tier
+func (t *TieredStorage) RetrieveBlob(id string, w io.Writer) error {
+	srv, tier, err := t.srv4id(id)
+	if err != nil {
+		return err
+	}
+	err = srv.RetrieveBlob(id, w)
+	if err != nil {
+		return err
+	}
+	if tier == model.TierCold && t.Promote {
+		go func() {
+			if err := t.promote(id); err != nil {
+				logger.Errorf("tiering: error promoting blob %s: %v", id, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// DeleteBlob removing a blob from the tier holding the blob. A running move of the blob is finished first,
+// so the moved copy can't bring the blob back.
+func (t *TieredStorage) DeleteBlob(id string) error {
+	unlock := t.locks.Lock(id)
+	defer unlock()
+	srv, _, err := t.srv4id(id)
+	if err != nil {
+		return err
+	}
+	return srv.DeleteBlob(id)
+}
+
+// CheckBlob checking a single blob on the tier holding the blob
+func (t *TieredStorage) CheckBlob(id string) (*model.CheckInfo, error) {
+	srv, _, err := t.srv4id(id)
+	if err != nil {
+		return nil, err
+	}
+	return srv.CheckBlob(id)
+}
+
+// SearchBlobs searching the blobs on both tiers, you can stop the listing by returning a false
+func (t *TieredStorage) SearchBlobs(q string, callback func(id string) bool) error {
+	stopped := false
+	cb := func(id string) bool {
+		ok := callback(id)
+		stopped = !ok
+		return ok
+	}
+	err := t.Hot.SearchBlobs(q, cb)
+	if stopped || err != nil {
+		return err
+	}
+	return t.Cold.SearchBlobs(q, cb)
+}
+
+// GetAllRetentions for every retention entry of both tiers we call this this function, you can stop the listing by returning a false
+func (t *TieredStorage) GetAllRetentions(callback func(r model.RetentionEntry) bool) error {
+	stopped := false
+	cb := func(r model.RetentionEntry) bool {
+		ok := callback(r)
+		stopped = !ok
+		return ok
+	}
+	err := t.Hot.GetAllRetentions(cb)
+	if stopped || err != nil {
+		return err
+	}
+	return t.Cold.GetAllRetentions(cb)
+}
+
+// AddRetention adding a retention entry to the tier holding the blob
+func (t *TieredStorage) AddRetention(r *model.RetentionEntry) error {
+	srv, _, err := t.srv4id(r.BlobID)
+	if err != nil {
+		return err
+	}
+	return srv.AddRetention(r)
+}
+
+// GetRetention getting a single retention entry
+func (t *TieredStorage) GetRetention(id string) (model.RetentionEntry, error) {
+	srv, _, err := t.srv4id(id)
+	if err != nil {
+		return model.RetentionEntry{}, err
+	}
+	return srv.GetRetention(id)
+}
+
+// DeleteRetention deletes the retention entry from the tier holding the blob
+func (t *TieredStorage) DeleteRetention(id string) error {
+	srv, _, err := t.srv4id(id)
+	if err != nil {
+		return err
+	}
+	return srv.DeleteRetention(id)
+}
+
+// ResetRetention resets the retention for a blob
+func (t *TieredStorage) ResetRetention(id string) error {
+	srv, _, err := t.srv4id(id)
+	if err != nil {
+		return err
+	}
+	return srv.ResetRetention(id)
+}
+
+// GetLastError returning the last error of the hot storage
+func (t *TieredStorage) GetLastError() error {
+	return t.Hot.GetLastError()
+}
+
+// Close closing the storage
+func (t *TieredStorage) Close() error {
+	if t.quit != nil {
+		t.quit <- true
+		t.quit = nil
+	}
+	err := t.Hot.Close()
+	if cerr := t.Cold.Close(); cerr != nil {
+		logger.Errorf("tiering: error closing cold storage: %v", cerr)
+	}
+	return err
+}
+
+// Demote walking thru all blobs of the hot tier and moving all blobs matching one of the rules to the cold tier
+func (t *TieredStorage) Demote() (int, error) {
+	if len(t.Rules) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	ids := make([]string, 0)
+	err := t.Hot.GetBlobs(func(id string) bool {
+		b, err := t.Hot.GetBlobDescription(id)
+		if err != nil {
+			logger.Errorf("tiering: error getting description %s: %v", id, err)
+			return true
+		}
+		if matchRules(t.Rules, b, now) {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	count := 0
+	for _, id := range ids {
+		err := t.move(id, t.Hot, t.Cold, model.TierCold)
+		if err != nil {
+			logger.Errorf("tiering: error demoting blob %s: %v", id, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// promote moving the blob back to the hot tier, if it doesn't match the rules anymore
+func (t *TieredStorage) promote(id string) error {
+	b, err := t.Cold.GetBlobDescription(id)
+	if err != nil {
+		return err
+	}
+	// a blob, which would be demoted on the next run, should stay on the cold tier
+	pb := *b
+	pb.LastAccess = time.Now().UnixMilli()
+	if matchRules(t.Rules, &pb, time.Now()) {
+		return nil
+	}
+	if err := t.Cold.UpdateBlobDescription(id, &pb); err != nil {
+		return err
+	}
+	return t.move(id, t.Cold, t.Hot, model.TierHot)
+}
+
+// move copying the blob with description and retention from src to dst and removing it from src afterwards
+func (t *TieredStorage) move(id string, src, dst interfaces.BlobStorage, tier string) error {
+	t.mm.Lock()
+	if t.moving[id] {
+		t.mm.Unlock()
+		return nil
+	}
+	t.moving[id] = true
+	t.mm.Unlock()
+	defer func() {
+		t.mm.Lock()
+		delete(t.moving, id)
+		t.mm.Unlock()
+	}()
+	unlock := t.locks.Lock(id)
+	defer unlock()
+
+	b, err := src.GetBlobDescription(id)
+	if err != nil {
+		return err
+	}
+	hash := b.Hash
+	b.Tier = tier
+	rd, wr := io.Pipe()
+	go func() {
+		// close the writer, so the reader knows there's no more data
+		var err error
+		defer func() {
+			wr.CloseWithError(err)
+		}()
+		err = src.RetrieveBlob(id, wr)
+	}()
+	_, err = dst.StoreBlob(b, rd)
+	_ = rd.Close()
+	if err != nil {
+		_ = dst.DeleteBlob(id)
+		return err
+	}
+	if hash != "" && b.Hash != hash {
+		_ = dst.DeleteBlob(id)
+		return fmt.Errorf("hashes are not equal: %s != %s", hash, b.Hash)
+	}
+	// the description may be changed while copying the content
+	if sb, err := src.GetBlobDescription(id); err == nil {
+		sb.Tier = tier
+		sb.Hash = b.Hash
+		b = sb
+		if err := dst.UpdateBlobDescription(id, b); err != nil {
+			logger.Errorf("tiering: error updating description %s: %v", id, err)
+		}
+	}
+	if r, err := src.GetRetention(id); err == nil {
+		if err := dst.AddRetention(&r); err != nil {
+			_ = dst.DeleteBlob(id)
+			return err
+		}
+	}
+	err = src.DeleteBlob(id)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("tiering: moved blob %s to tier %s", id, tier)
+	t.lm.Lock()
+	listener := t.listener
+	t.lm.Unlock()
+	if listener != nil {
+		listener(id, b)
+	}
+	return nil
+}
+
+// srv4id getting the storage and the tier for the blob
+func (t *TieredStorage) srv4id(id string) (interfaces.BlobStorage, string, error) {
+	ok, err := t.Hot.HasBlob(id)
+	if err == nil && ok {
+		return t.Hot, model.TierHot, nil
+	}
+	ok, err = t.Cold.HasBlob(id)
+	if err == nil && ok {
+		return t.Cold, model.TierCold, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return nil, "", ErrNotFound
+}
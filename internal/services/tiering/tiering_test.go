@@ -0,0 +1,277 @@
+package tiering
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/willie68/GoBlobStore/internal/services/interfaces"
+	"github.com/willie68/GoBlobStore/internal/services/simplefile"
+	"github.com/willie68/GoBlobStore/pkg/model"
+)
+
+const (
+	rootpath = "../../../testdata/tiering"
+	tenant   = "test"
+	payload  = "this is a blob content"
+)
+
+func initTest(t *testing.T) *TieredStorage {
+	ast := assert.New(t)
+	ast.Nil(os.RemoveAll(rootpath))
+	hot := &simplefile.BlobStorage{
+		RootPath: filepath.Join(rootpath, "hot"),
+		Tenant:   tenant,
+	}
+	ast.Nil(hot.Init())
+	cold := &simplefile.BlobStorage{
+		RootPath: filepath.Join(rootpath, "cold"),
+		Tenant:   tenant,
+	}
+	ast.Nil(cold.Init())
+	ts := &TieredStorage{
+		Hot:    hot,
+		Cold:   cold,
+		Tenant: tenant,
+		Rules: []Rule{
+			{LastAccess: time.Hour},
+		},
+	}
+	ast.Nil(ts.Init())
+	return ts
+}
+
+func storeOldBlob(ast *assert.Assertions, ts *TieredStorage) string {
+	b := model.BlobDescription{
+		StoreID:       tenant,
+		TenantID:      tenant,
+		ContentLength: int64(len(payload)),
+		ContentType:   "text/plain",
+		CreationDate:  time.Now().UnixMilli(),
+		Filename:      "test.txt",
+		Retention:     180000,
+		Properties:    make(map[string]any),
+	}
+	id, err := ts.StoreBlob(&b, strings.NewReader(payload))
+	ast.Nil(err)
+	ast.Equal(model.TierHot, b.Tier)
+
+	// simulate a blob, which was not used for a long time
+	b.LastAccess = time.Now().Add(-2 * time.Hour).UnixMilli()
+	ast.Nil(ts.UpdateBlobDescription(id, &b))
+	r := model.RetentionEntryFromBlobDescription(b)
+	ast.Nil(ts.AddRetention(&r))
+	return id
+}
+
+func TestRules(t *testing.T) {
+	ast := assert.New(t)
+	now := time.Now()
+	b := model.BlobDescription{
+		ContentLength: 1000,
+		ContentType:   "video/mp4",
+		CreationDate:  now.Add(-48 * time.Hour).UnixMilli(),
+		LastAccess:    now.Add(-2 * time.Hour).UnixMilli(),
+	}
+
+	ast.False(Rule{}.Match(&b, now))
+	ast.True(Rule{Age: 24 * time.Hour}.Match(&b, now))
+	ast.False(Rule{Age: 72 * time.Hour}.Match(&b, now))
+	ast.True(Rule{LastAccess: time.Hour}.Match(&b, now))
+	ast.False(Rule{LastAccess: 3 * time.Hour}.Match(&b, now))
+	ast.True(Rule{MinSize: 1000, ContentType: "Video/"}.Match(&b, now))
+	ast.False(Rule{MinSize: 1001, ContentType: "video/"}.Match(&b, now))
+	ast.False(Rule{Age: 24 * time.Hour, ContentType: "image/"}.Match(&b, now))
+
+	rules, err := ParseRules([]any{
+		map[string]any{"age": 60, "minsize": 1024},
+		map[string]any{"lastaccess": 120, "contenttype": "video/"},
+	})
+	ast.Nil(err)
+	ast.Equal(2, len(rules))
+	ast.Equal(time.Hour, rules[0].Age)
+	ast.Equal(int64(1024), rules[0].MinSize)
+	ast.Equal(2*time.Hour, rules[1].LastAccess)
+	ast.Equal("video/", rules[1].ContentType)
+
+	_, err = ParseRules([]any{"age"})
+	ast.NotNil(err)
+}
+
+func TestDemote(t *testing.T) {
+	ast := assert.New(t)
+	ts := initTest(t)
+	defer ts.Close()
+
+	moved := make([]string, 0)
+	ts.SetMoveListener(func(id string, b *model.BlobDescription) {
+		ast.Equal(model.TierCold, b.Tier)
+		moved = append(moved, id)
+	})
+
+	id := storeOldBlob(ast, ts)
+
+	count, err := ts.Demote()
+	ast.Nil(err)
+	ast.Equal(1, count)
+	ast.Equal([]string{id}, moved)
+
+	ok, err := ts.Hot.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = ts.Cold.HasBlob(id)
+	ast.Nil(err)
+	ast.True(ok)
+
+	b, err := ts.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(model.TierCold, b.Tier)
+
+	r, err := ts.GetRetention(id)
+	ast.Nil(err)
+	ast.Equal(id, r.BlobID)
+
+	var buf bytes.Buffer
+	ast.Nil(ts.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.String())
+
+	ids := make([]string, 0)
+	ast.Nil(ts.GetBlobs(func(id string) bool {
+		ids = append(ids, id)
+		return true
+	}))
+	ast.Equal([]string{id}, ids)
+
+	// nothing more to do
+	count, err = ts.Demote()
+	ast.Nil(err)
+	ast.Equal(0, count)
+
+	ast.Nil(ts.DeleteBlob(id))
+	ok, err = ts.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+}
+
+func TestPromote(t *testing.T) {
+	ast := assert.New(t)
+	ts := initTest(t)
+	defer ts.Close()
+	ts.Promote = true
+
+	id := storeOldBlob(ast, ts)
+	count, err := ts.Demote()
+	ast.Nil(err)
+	ast.Equal(1, count)
+
+	var buf bytes.Buffer
+	ast.Nil(ts.RetrieveBlob(id, &buf))
+	ast.Equal(payload, buf.String())
+
+	// the promotion is running in the background
+	ok := false
+	for x := 0; x < 100 && !ok; x++ {
+		time.Sleep(10 * time.Millisecond)
+		ok, _ = ts.Hot.HasBlob(id)
+	}
+	ast.True(ok)
+	b, err := ts.GetBlobDescription(id)
+	ast.Nil(err)
+	ast.Equal(model.TierHot, b.Tier)
+}
+
+// slowStorage a storage, which blocks reading the content until released
+type slowStorage struct {
+	interfaces.BlobStorage
+	started chan bool
+	release chan bool
+}
+
+func (s *slowStorage) RetrieveBlob(id string, w io.Writer) error {
+	s.started <- true
+	<-s.release
+	return s.BlobStorage.RetrieveBlob(id, w)
+}
+
+func TestDeleteWhileDemote(t *testing.T) {
+	ast := assert.New(t)
+	ts := initTest(t)
+	defer ts.Close()
+
+	id := storeOldBlob(ast, ts)
+	slow := &slowStorage{
+		BlobStorage: ts.Hot,
+		started:     make(chan bool),
+		release:     make(chan bool),
+	}
+	ts.Hot = slow
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = ts.Demote()
+	}()
+	<-slow.started
+
+	deleted := make(chan error, 1)
+	go func() {
+		deleted <- ts.DeleteBlob(id)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	ast.Empty(deleted, "delete should wait for the running move")
+	close(slow.release)
+	wg.Wait()
+	ast.Nil(<-deleted)
+
+	// the blob must not come back on one of the tiers
+	ok, err := ts.Hot.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = ts.Cold.HasBlob(id)
+	ast.Nil(err)
+	ast.False(ok)
+}
+
+// searchStorage a storage, which only knows how to search
+type searchStorage struct {
+	interfaces.BlobStorage
+	ids []string
+}
+
+func (s *searchStorage) SearchBlobs(_ string, callback func(id string) bool) error {
+	for _, id := range s.ids {
+		if !callback(id) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func TestSearch(t *testing.T) {
+	ast := assert.New(t)
+	ts := &TieredStorage{
+		Hot:  &searchStorage{ids: []string{"h1", "h2"}},
+		Cold: &searchStorage{ids: []string{"c1"}},
+	}
+
+	ids := make([]string, 0)
+	ast.Nil(ts.SearchBlobs("*", func(id string) bool {
+		ids = append(ids, id)
+		return true
+	}))
+	ast.Equal([]string{"h1", "h2", "c1"}, ids)
+
+	ids = make([]string, 0)
+	ast.Nil(ts.SearchBlobs("*", func(id string) bool {
+		ids = append(ids, id)
+		return len(ids) < 2
+	}))
+	ast.Equal([]string{"h1", "h2"}, ids)
+}
@@ -0,0 +1,301 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/willie68/GoBlobStore/internal/utils"
+)
+
+// defining the commands of the volume jobs
+const (
+	CmdDrain     = "drain"
+	CmdRebalance = "rebalance"
+)
+
+// maximum count of passes for draining a volume, blobs can be written to the volume while the first pass is running
+const maxDrainPasses = 3
+
+// ErrJobRunning there is already a job running on this manager
+var ErrJobRunning = errors.New("a volume job is already running")
+
+// BlobRef reference to a blob on a volume
+type BlobRef struct {
+	Tenant  string
+	ID      string
+	Size    int64
+	Volumes []string // all volumes holding a copy or a shard of the blob, these are no targets for a move
+}
+
+// holds checking if the volume is holding a copy or a shard of the blob
+func (b BlobRef) holds(name string) bool {
+	for _, n := range b.Volumes {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Mover is implemented by the storage, which knows the blobs on the volumes
+type Mover interface {
+	Blobs(volume string, callback func(b BlobRef) bool) error // walking thru all blobs of a volume
+	Move(b BlobRef, from, to string) error                    // moving a blob with its retention from one volume to another, the checksum must be verified
+}
+
+// JobStatus the status of a drain or rebalance job on the volumes
+type JobStatus struct {
+	ID         string    `json:"id"`
+	Command    string    `json:"command"`
+	Volume     string    `json:"volume,omitempty"`
+	Running    bool      `json:"running"`
+	Cancelled  bool      `json:"cancelled"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Total      int       `json:"total"`
+	Moved      int       `json:"moved"`
+	Failed     int       `json:"failed"`
+	MovedBytes int64     `json:"movedBytes"`
+	LastError  string    `json:"lastError,omitempty"`
+}
+
+// job a running drain or rebalance job
+type job struct {
+	JobStatus
+	cancel context.CancelFunc
+	m      sync.Mutex
+}
+
+// status getting a copy of the actual job status
+func (j *job) status() JobStatus {
+	j.m.Lock()
+	defer j.m.Unlock()
+	return j.JobStatus
+}
+
+func (j *job) addTotal(c int) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	j.Total += c
+}
+
+func (j *job) done(b BlobRef, err error) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	if err != nil {
+		j.Failed++
+		j.LastError = err.Error()
+		return
+	}
+	j.Moved++
+	j.MovedBytes += b.Size
+}
+
+// Job getting the status of the actual or last job
+func (v *Manager) Job() (JobStatus, bool) {
+	v.jm.Lock()
+	defer v.jm.Unlock()
+	if v.job == nil {
+		return JobStatus{}, false
+	}
+	return v.job.status(), true
+}
+
+// CancelJob cancelling the running job
+func (v *Manager) CancelJob() bool {
+	v.jm.Lock()
+	defer v.jm.Unlock()
+	if v.job == nil || !v.job.status().Running {
+		return false
+	}
+	v.job.cancel()
+	return true
+}
+
+// Drain setting the volume to draining and moving all blobs of this volume to the other writable volumes
+func (v *Manager) Drain(mover Mover, name string) (JobStatus, error) {
+	if !v.HasVolume(name) {
+		return JobStatus{}, ErrUnknownVolume
+	}
+	err := v.SetState(name, StateDraining)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	return v.startJob(CmdDrain, name, func(ctx context.Context, j *job) {
+		v.drain(ctx, j, mover, name)
+	})
+}
+
+// Rebalance moving blobs between the writable volumes, until all volumes have the same utilisation.
+// The utilisation is calculated from the size of the stored blobs in relation to the capacity of the volume.
+func (v *Manager) Rebalance(mover Mover) (JobStatus, error) {
+	return v.startJob(CmdRebalance, "", func(ctx context.Context, j *job) {
+		v.rebalance(ctx, j, mover)
+	})
+}
+
+func (v *Manager) startJob(cmd, name string, f func(ctx context.Context, j *job)) (JobStatus, error) {
+	v.jm.Lock()
+	defer v.jm.Unlock()
+	if v.job != nil && v.job.status().Running {
+		return JobStatus{}, ErrJobRunning
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		JobStatus: JobStatus{
+			ID:      utils.GenerateID(),
+			Command: cmd,
+			Volume:  name,
+			Running: true,
+			Started: time.Now(),
+		},
+		cancel: cancel,
+	}
+	v.job = j
+	go func() {
+		defer cancel()
+		f(ctx, j)
+		j.m.Lock()
+		j.Running = false
+		j.Cancelled = ctx.Err() != nil
+		j.Finished = time.Now()
+		j.m.Unlock()
+		logger.Infof("volume manager: job %s %s finished", j.Command, j.ID)
+	}()
+	return j.status(), nil
+}
+
+func (v *Manager) drain(ctx context.Context, j *job, mover Mover, name string) {
+	for pass := 0; pass < maxDrainPasses; pass++ {
+		blobs, err := v.blobList(mover, name)
+		if err != nil {
+			j.done(BlobRef{}, err)
+			return
+		}
+		if len(blobs) == 0 {
+			return
+		}
+		j.addTotal(len(blobs))
+		moved := 0
+		for _, b := range blobs {
+			if ctx.Err() != nil {
+				return
+			}
+			to := v.target(name, b)
+			if to == "" {
+				j.done(b, errors.New("no writable volume with enough free space found"))
+				return
+			}
+			err := mover.Move(b, name, to)
+			j.done(b, err)
+			if err == nil {
+				moved++
+			}
+		}
+		if moved == 0 {
+			return
+		}
+	}
+}
+
+func (v *Manager) rebalance(ctx context.Context, j *job, mover Mover) {
+	// collecting the stored bytes and the blobs of all writable volumes
+	used := make(map[string]int64)
+	blobs := make(map[string][]BlobRef)
+	var sumUsed, sumTotal int64
+	for _, vi := range v.Volumes() {
+		if !vi.Writable() || vi.Total == 0 {
+			continue
+		}
+		bl, err := v.blobList(mover, vi.Name)
+		if err != nil {
+			j.done(BlobRef{}, err)
+			return
+		}
+		blobs[vi.Name] = bl
+		for _, b := range bl {
+			used[vi.Name] += b.Size
+		}
+		sumUsed += used[vi.Name]
+		sumTotal += int64(vi.Total)
+	}
+	if sumTotal == 0 {
+		return
+	}
+	target := make(map[string]int64)
+	for name := range blobs {
+		target[name] = int64(float64(sumUsed) * float64(v.Info(name).Total) / float64(sumTotal))
+	}
+	names := make([]string, 0, len(blobs))
+	for name := range blobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, from := range names {
+		for _, b := range blobs[from] {
+			if used[from] <= target[from] {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			// the volume with the highest deficit will get the blob
+			to := ""
+			var deficit int64
+			for _, n := range names {
+				if d := target[n] - used[n]; n != from && !b.holds(n) && d >= b.Size && d > deficit {
+					to = n
+					deficit = d
+				}
+			}
+			if to == "" {
+				continue
+			}
+			j.addTotal(1)
+			err := mover.Move(b, from, to)
+			j.done(b, err)
+			if err == nil {
+				used[from] -= b.Size
+				used[to] += b.Size
+			}
+		}
+	}
+}
+
+// blobList getting all blobs of a volume, biggest first
+func (v *Manager) blobList(mover Mover, name string) ([]BlobRef, error) {
+	blobs := make([]BlobRef, 0)
+	err := mover.Blobs(name, func(b BlobRef) bool {
+		blobs = append(blobs, b)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].Size > blobs[j].Size
+	})
+	return blobs, nil
+}
+
+// target getting the writable volume with the most free space, excluding the source volume and all volumes already
+// holding a copy or a shard of the blob
+func (v *Manager) target(from string, b BlobRef) string {
+	v.cm.Lock()
+	defer v.cm.Unlock()
+	to := ""
+	var free uint64
+	for name, vi := range v.volumes {
+		if name == from || b.holds(name) || !vi.Writable() || vi.Free < uint64(b.Size) {
+			continue
+		}
+		if to == "" || vi.Free > free {
+			to = name
+			free = vi.Free
+		}
+	}
+	return to
+}
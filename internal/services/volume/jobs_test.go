@@ -0,0 +1,173 @@
+package volume
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memMover struct {
+	blobs map[string][]BlobRef
+	delay time.Duration
+	m     sync.Mutex
+}
+
+func (m *memMover) Blobs(volume string, callback func(b BlobRef) bool) error {
+	m.m.Lock()
+	bl := append([]BlobRef{}, m.blobs[volume]...)
+	m.m.Unlock()
+	for _, b := range bl {
+		if !callback(b) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memMover) Move(b BlobRef, from, to string) error {
+	time.Sleep(m.delay)
+	m.m.Lock()
+	defer m.m.Unlock()
+	bl := make([]BlobRef, 0)
+	for _, x := range m.blobs[from] {
+		if x.ID != b.ID {
+			bl = append(bl, x)
+		}
+	}
+	m.blobs[from] = bl
+	m.blobs[to] = append(m.blobs[to], b)
+	return nil
+}
+
+func (m *memMover) count(volume string) int {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return len(m.blobs[volume])
+}
+
+func newMemMover() *memMover {
+	return &memMover{
+		blobs: map[string][]BlobRef{
+			"mvn01": {
+				{Tenant: "test", ID: "1", Size: 100},
+				{Tenant: "test", ID: "2", Size: 100},
+				{Tenant: "test", ID: "3", Size: 100},
+				{Tenant: "test", ID: "4", Size: 100},
+			},
+		},
+	}
+}
+
+func waitJob(v *Manager) JobStatus {
+	for x := 0; x < 500; x++ {
+		j, _ := v.Job()
+		if !j.Running {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	j, _ := v.Job()
+	return j
+}
+
+func TestState(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	initTest(t)
+	ast.Nil(volumes.Init())
+
+	ast.Nil(volumes.SetState("mvn01", StateReadOnly))
+	ast.NotNil(volumes.SetState("mvn01", "unknown"))
+	ast.ErrorIs(volumes.SetState("mvn99", StateReadOnly), ErrUnknownVolume)
+	ast.Equal(StateReadOnly, volumes.Info("mvn01").State)
+	for x := 0; x < 1000; x++ {
+		ast.Equal("mvn02", volumes.SelectFree(x))
+	}
+
+	// state must survive a restart
+	initTest(t)
+	ast.Nil(volumes.Init())
+	ast.Equal(StateReadOnly, volumes.Info("mvn01").State)
+	ast.False(volumes.Info("mvn01").Writable())
+	ast.True(volumes.Info("mvn02").Writable())
+	ast.Equal(2, len(volumes.Volumes()))
+
+	ast.Nil(volumes.SetState("mvn01", StateActive))
+}
+
+func TestDrain(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	initTest(t)
+	ast.Nil(volumes.Init())
+
+	mover := newMemMover()
+	_, err := volumes.Drain(mover, "mvn99")
+	ast.ErrorIs(err, ErrUnknownVolume)
+
+	j, err := volumes.Drain(mover, "mvn01")
+	ast.Nil(err)
+	ast.True(j.Running)
+	ast.Equal(CmdDrain, j.Command)
+
+	j = waitJob(&volumes)
+	ast.False(j.Running)
+	ast.False(j.Cancelled)
+	ast.Equal(4, j.Total)
+	ast.Equal(4, j.Moved)
+	ast.Equal(int64(400), j.MovedBytes)
+	ast.Equal(0, mover.count("mvn01"))
+	ast.Equal(4, mover.count("mvn02"))
+	ast.Equal(StateDraining, volumes.Info("mvn01").State)
+}
+
+func TestRebalance(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	initTest(t)
+	ast.Nil(volumes.Init())
+
+	mover := newMemMover()
+	_, err := volumes.Rebalance(mover)
+	ast.Nil(err)
+
+	j := waitJob(&volumes)
+	ast.Equal(CmdRebalance, j.Command)
+	ast.Equal(2, j.Moved)
+	ast.Equal(2, mover.count("mvn01"))
+	ast.Equal(2, mover.count("mvn02"))
+}
+
+func TestCancelJob(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	initTest(t)
+	ast.Nil(volumes.Init())
+
+	ast.False(volumes.CancelJob())
+
+	mover := newMemMover()
+	mover.delay = 100 * time.Millisecond
+	_, err := volumes.Drain(mover, "mvn01")
+	ast.Nil(err)
+	_, err = volumes.Rebalance(mover)
+	ast.ErrorIs(err, ErrJobRunning)
+
+	ast.True(volumes.CancelJob())
+	j := waitJob(&volumes)
+	ast.True(j.Cancelled)
+	ast.True(j.Moved < 4)
+}
+
+func TestTargetWithCopies(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	initTest(t)
+	ast.Nil(volumes.Init())
+
+	ast.Equal("mvn02", volumes.target("mvn01", BlobRef{ID: "1", Size: 100}))
+	// a volume holding already a copy of the blob is no target
+	ast.Equal("", volumes.target("mvn01", BlobRef{ID: "1", Size: 100, Volumes: []string{"mvn01", "mvn02"}}))
+}
@@ -1,6 +1,8 @@
 package volume
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -18,30 +20,64 @@ import (
 // every sub folder should be another mount point. The manager provide information about presence, capacity, free space and utilization.
 // It also provide a utilization in m% of all volumes.
 // The call back will be fired on every new mount of a volume in the monitored root folder.
+// The remove call backs will be fired, if a volume disappears from the root folder.
 type Manager struct {
-	root       string
-	tickertime time.Duration
-	cm         sync.Mutex
-	volumes    map[string]Info
-	callbacks  []Callback
-	ticker     *time.Ticker
-	rnd        *rand.Rand
+	root        string
+	tickertime  time.Duration
+	cm          sync.Mutex
+	volumes     map[string]Info
+	callbacks   []Callback
+	rmcallbacks []Callback
+	ticker      *time.Ticker
+	rnd         *rand.Rand
+	job         *job
+	jm          sync.Mutex
+	subs        map[int]subscription
+	subID       int
 }
 
+// subscription the callbacks of a storage using the volumes
+type subscription struct {
+	add    Callback
+	remove Callback
+}
+
+// all managers shared by the root path
+var (
+	managers = make(map[string]*Manager)
+	mm       sync.Mutex
+)
+
 // Callback a simple callback function
 type Callback func(name string) bool
 
+// defining the states of a volume
+const (
+	StateActive   = "active"   // the volume can be used for reading and writing
+	StateReadOnly = "readonly" // no new blobs will be written to this volume
+	StateDraining = "draining" // all blobs will be moved to other volumes, no new blobs will be written to this volume
+)
+
+// ErrUnknownVolume the volume is not known to the manager
+var ErrUnknownVolume = errors.New("unknown volume")
+
 // Info information about a volume
 type Info struct {
 	Name     string `yaml:"name",json:"name"`
 	Free     uint64 `yaml:"free",json:"free"`
 	Used     uint64 `yaml:"used",json:"used"`
 	Total    uint64 `yaml:"total",json:"total"`
+	State    string `yaml:"state,omitempty" json:"state,omitempty"`
 	Path     string `yaml:"-",json:"-"`
 	Selector int    `yaml:"-",json:"-"`
 	freepm   int
 }
 
+// Writable checking if new blobs can be written to this volume
+func (i Info) Writable() bool {
+	return i.State == "" || i.State == StateActive
+}
+
 // NewVolumeManager creating a new NewVolumeManager with a root path
 func NewVolumeManager(rootpath string) (Manager, error) {
 	vs := Manager{
@@ -51,6 +87,29 @@ func NewVolumeManager(rootpath string) (Manager, error) {
 	return vs, nil
 }
 
+// GetManager getting the shared volume manager of the root path. The storages of all tenants and the administration are
+// using the same manager, so a change of the volume state takes effect immediately. A new manager will be initialised,
+// a known manager will be rescanned.
+func GetManager(rootpath string) (*Manager, error) {
+	key := filepath.Clean(rootpath)
+	mm.Lock()
+	defer mm.Unlock()
+	if v, ok := managers[key]; ok {
+		return v, v.Rescan()
+	}
+	vs, err := NewVolumeManager(rootpath)
+	if err != nil {
+		return nil, err
+	}
+	v := &vs
+	err = v.Init()
+	if err != nil {
+		return nil, err
+	}
+	managers[key] = v
+	return v, nil
+}
+
 // Init initialize the volume manager
 func (v *Manager) Init() error {
 	s1 := rand.NewSource(time.Now().UnixNano())
@@ -62,9 +121,10 @@ func (v *Manager) Init() error {
 	}
 	v.volumes = make(map[string]Info)
 	err := v.Rescan()
-	v.ticker = time.NewTicker(v.tickertime)
+	ticker := time.NewTicker(v.tickertime)
+	v.ticker = ticker
 	go func() {
-		for range v.ticker.C {
+		for range ticker.C {
 			err := v.Rescan()
 			if err != nil {
 				logger.Errorf("volume manager: error rescan volumes: %v", err)
@@ -84,35 +144,106 @@ func (v *Manager) HasVolume(name string) bool {
 
 // AddCallback adding a callback for volume list changes
 func (v *Manager) AddCallback(cb Callback) bool {
+	v.cm.Lock()
+	defer v.cm.Unlock()
 	v.callbacks = append(v.callbacks, cb)
 	return true
 }
 
+// AddRemoveCallback adding a callback for volumes, which have disappeared
+func (v *Manager) AddRemoveCallback(cb Callback) bool {
+	v.cm.Lock()
+	defer v.cm.Unlock()
+	v.rmcallbacks = append(v.rmcallbacks, cb)
+	return true
+}
+
+// Subscribe adding the callbacks for new and for disappeared volumes, the returned function removes the callbacks again
+func (v *Manager) Subscribe(add, remove Callback) func() {
+	v.cm.Lock()
+	defer v.cm.Unlock()
+	if v.subs == nil {
+		v.subs = make(map[int]subscription)
+	}
+	v.subID++
+	id := v.subID
+	v.subs[id] = subscription{add: add, remove: remove}
+	return func() {
+		v.cm.Lock()
+		defer v.cm.Unlock()
+		delete(v.subs, id)
+	}
+}
+
+// getCallbacks getting all callbacks for new volumes, or with rm for disappeared volumes
+func (v *Manager) getCallbacks(rm bool) []Callback {
+	v.cm.Lock()
+	defer v.cm.Unlock()
+	cbs := v.callbacks
+	if rm {
+		cbs = v.rmcallbacks
+	}
+	cbs = append([]Callback{}, cbs...)
+	for _, s := range v.subs {
+		cb := s.add
+		if rm {
+			cb = s.remove
+		}
+		if cb != nil {
+			cbs = append(cbs, cb)
+		}
+	}
+	return cbs
+}
+
 // Rescan scan the mount points for new volumes
 func (v *Manager) Rescan() error {
 	entries, err := os.ReadDir(v.root)
 	if err != nil {
 		return err
 	}
+	present := make(map[string]bool)
 	for _, e := range entries {
 		if e.IsDir() {
 			name := e.Name()
+			present[name] = true
 			already := v.HasVolume(name)
 			vim, err := v.volInfo(name)
 			if err != nil {
 				return err
 			}
 			if !already {
-				for _, cb := range v.callbacks {
+				for _, cb := range v.getCallbacks(false) {
 					cb(vim.Name)
 				}
 			}
 		}
 	}
+	for _, name := range v.removed(present) {
+		logger.Errorf("volume manager: volume %s has disappeared", name)
+		for _, cb := range v.getCallbacks(true) {
+			cb(name)
+		}
+	}
 	err = v.CalculatePerMill()
 	return err
 }
 
+// removed removing all volumes, which are not present anymore
+func (v *Manager) removed(present map[string]bool) []string {
+	v.cm.Lock()
+	defer v.cm.Unlock()
+	names := make([]string, 0)
+	for name := range v.volumes {
+		if !present[name] {
+			names = append(names, name)
+			delete(v.volumes, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (v *Manager) volInfo(name string) (*Info, error) {
 	var vi Info
 	volRoot := filepath.Join(v.root, name)
@@ -145,12 +276,53 @@ func (v *Manager) volInfo(name string) (*Info, error) {
 	v.cm.Lock()
 	v.volumes[name] = vi
 	v.cm.Unlock()
+	err = writeInfo(vi)
+	return &vi, err
+}
+
+func writeInfo(vi Info) error {
 	data, err := yaml.Marshal(vi)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	err = os.WriteFile(volInfoFile, data, 0644)
-	return &vi, err
+	return os.WriteFile(filepath.Join(vi.Path, ".volumeinfo"), data, 0644)
+}
+
+// SetState setting the state of a volume, the state will be persisted in the volume info file
+func (v *Manager) SetState(name, state string) error {
+	switch state {
+	case StateActive, StateReadOnly, StateDraining:
+	default:
+		return fmt.Errorf("unknown volume state: %s", state)
+	}
+	v.cm.Lock()
+	vi, ok := v.volumes[name]
+	if !ok {
+		v.cm.Unlock()
+		return ErrUnknownVolume
+	}
+	vi.State = state
+	v.volumes[name] = vi
+	v.cm.Unlock()
+	err := writeInfo(vi)
+	if err != nil {
+		return err
+	}
+	return v.CalculatePerMill()
+}
+
+// Volumes getting the infos of all volumes, sorted by name
+func (v *Manager) Volumes() []Info {
+	v.cm.Lock()
+	defer v.cm.Unlock()
+	vis := make([]Info, 0, len(v.volumes))
+	for _, vi := range v.volumes {
+		vis = append(vis, vi)
+	}
+	sort.Slice(vis, func(i, j int) bool {
+		return vis[i].Name < vis[j].Name
+	})
+	return vis
 }
 
 // Info getting the volume info of a single volume
@@ -174,7 +346,10 @@ func (v *Manager) CalculatePerMill() error {
 		// Gesamtspeicher
 		g += vi.Total
 		// Auslastung in ProMille pro Volume
-		vi.freepm = int((vi.Free * 1000) / vi.Total)
+		vi.freepm = 0
+		if vi.Writable() && vi.Total > 0 {
+			vi.freepm = int((vi.Free * 1000) / vi.Total)
+		}
 		gfreepm += vi.freepm
 		v.volumes[k] = vi
 	}
@@ -198,6 +373,9 @@ func (v *Manager) SelectFree(i int) string {
 	sort.Strings(keys)
 	var sel = 0
 	for _, k := range keys {
+		if !v.volumes[k].Writable() {
+			continue
+		}
 		sel += v.volumes[k].Selector
 		if i <= sel {
 			return v.volumes[k].Name
@@ -164,6 +164,28 @@ func TestAddVol(t *testing.T) {
 	ast.Nil(err)
 }
 
+func TestRemoveVol(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	initTest(t)
+	removed := make([]string, 0)
+	volumes.AddRemoveCallback(func(name string) bool {
+		removed = append(removed, name)
+		return true
+	})
+	ast.Nil(volumes.Init())
+
+	ast.Nil(os.RemoveAll(filepath.Join(rootFilePrefix, "mvn02")))
+	ast.Nil(volumes.Rescan())
+	ast.Equal([]string{"mvn02"}, removed)
+	ast.False(volumes.HasVolume("mvn02"))
+	ast.True(volumes.HasVolume("mvn01"))
+
+	// nothing more to report
+	ast.Nil(volumes.Rescan())
+	ast.Equal(1, len(removed))
+}
+
 func TestCalculate(t *testing.T) {
 	ast := assert.New(t)
 	v, err := NewVolumeManager(rootFilePrefix)
@@ -218,3 +240,43 @@ func TestCalculate(t *testing.T) {
 	n = v.SelectFree(1001)
 	ast.Equal("", n)
 }
+
+func TestSharedManager(t *testing.T) {
+	ast := assert.New(t)
+	clear(t)
+	for _, v := range vols {
+		ast.Nil(os.MkdirAll(filepath.Join(rootFilePrefix, v), fs.ModePerm))
+	}
+
+	v1, err := GetManager(rootFilePrefix)
+	ast.Nil(err)
+	v2, err := GetManager(rootFilePrefix + "/")
+	ast.Nil(err)
+	ast.Same(v1, v2)
+	ast.Equal(len(vols), len(v2.Volumes()))
+
+	added := make([]string, 0)
+	removed := make([]string, 0)
+	unsubscribe := v1.Subscribe(func(name string) bool {
+		added = append(added, name)
+		return true
+	}, func(name string) bool {
+		removed = append(removed, name)
+		return true
+	})
+
+	// a state change is seen by every user of the manager
+	ast.Nil(v1.SetState("mvn01", StateReadOnly))
+	ast.False(v2.Info("mvn01").Writable())
+
+	ast.Nil(os.MkdirAll(filepath.Join(rootFilePrefix, "mvn03"), fs.ModePerm))
+	_, err = GetManager(rootFilePrefix)
+	ast.Nil(err)
+	ast.Equal([]string{"mvn03"}, added)
+
+	unsubscribe()
+	ast.Nil(os.RemoveAll(filepath.Join(rootFilePrefix, "mvn03")))
+	ast.Nil(v1.Rescan())
+	ast.Empty(removed)
+	ast.False(v1.HasVolume("mvn03"))
+}
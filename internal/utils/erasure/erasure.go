@@ -0,0 +1,227 @@
+// Package erasure provides a systematic Reed-Solomon erasure code over GF(2^8).
+// The first data shards are the unchanged data, the parity shards are calculated with a cauchy matrix,
+// so every combination of data shards out of all shards can be used for reconstruction.
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// defining some errors
+var (
+	ErrTooFewShards   = errors.New("too few shards for reconstruction")
+	ErrShardCount     = errors.New("wrong count of shards")
+	ErrShardSize      = errors.New("shards have different sizes")
+	ErrSingularMatrix = errors.New("matrix is singular")
+)
+
+// the tables for multiplication in GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1
+var (
+	expTbl [510]byte
+	logTbl [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		expTbl[i] = byte(x)
+		expTbl[i+255] = byte(x)
+		logTbl[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTbl[int(logTbl[a])+int(logTbl[b])]
+}
+
+func inv(a byte) byte {
+	return expTbl[255-int(logTbl[a])]
+}
+
+// Codec the erasure codec for a fixed count of data and parity shards
+type Codec struct {
+	data   int
+	parity int
+	// the rows of the encoding matrix, the first data rows are the identity
+	matrix [][]byte
+}
+
+// New creating a new codec with data and parity shards
+func New(data, parity int) (*Codec, error) {
+	if data < 1 || parity < 1 {
+		return nil, fmt.Errorf("data (%d) and parity (%d) shards must be greater than 0", data, parity)
+	}
+	if data+parity > 256 {
+		return nil, fmt.Errorf("too many shards: %d", data+parity)
+	}
+	c := &Codec{
+		data:   data,
+		parity: parity,
+		matrix: make([][]byte, data+parity),
+	}
+	for r := 0; r < data; r++ {
+		c.matrix[r] = make([]byte, data)
+		c.matrix[r][r] = 1
+	}
+	// cauchy matrix, x = data + r, y = col, every square sub matrix is invertible
+	for r := 0; r < parity; r++ {
+		row := make([]byte, data)
+		for col := 0; col < data; col++ {
+			row[col] = inv(byte(data+r) ^ byte(col))
+		}
+		c.matrix[data+r] = row
+	}
+	return c, nil
+}
+
+// DataShards count of data shards
+func (c *Codec) DataShards() int {
+	return c.data
+}
+
+// ParityShards count of parity shards
+func (c *Codec) ParityShards() int {
+	return c.parity
+}
+
+// Encode calculating the parity shards from the data shards. All shards must have the same size,
+// the parity shards will be allocated if needed.
+func (c *Codec) Encode(shards [][]byte) error {
+	if len(shards) != c.data+c.parity {
+		return ErrShardCount
+	}
+	size := len(shards[0])
+	for i := 0; i < c.data; i++ {
+		if len(shards[i]) != size {
+			return ErrShardSize
+		}
+	}
+	for r := c.data; r < c.data+c.parity; r++ {
+		if len(shards[r]) != size {
+			shards[r] = make([]byte, size)
+		}
+		c.calc(c.matrix[r], shards[:c.data], shards[r])
+	}
+	return nil
+}
+
+// Reconstruct rebuilding all missing shards. Missing shards are nil or empty,
+// at least data shards must be present.
+func (c *Codec) Reconstruct(shards [][]byte) error {
+	if len(shards) != c.data+c.parity {
+		return ErrShardCount
+	}
+	size := 0
+	present := make([]int, 0, c.data)
+	for i, s := range shards {
+		if len(s) == 0 {
+			continue
+		}
+		if size == 0 {
+			size = len(s)
+		}
+		if len(s) != size {
+			return ErrShardSize
+		}
+		if len(present) < c.data {
+			present = append(present, i)
+		}
+	}
+	if len(present) < c.data {
+		return ErrTooFewShards
+	}
+	// building the decoding matrix from the rows of the present shards
+	sub := make([][]byte, c.data)
+	in := make([][]byte, c.data)
+	for i, p := range present {
+		sub[i] = append([]byte{}, c.matrix[p]...)
+		in[i] = shards[p]
+	}
+	dec, err := invert(sub)
+	if err != nil {
+		return err
+	}
+	for r := 0; r < c.data; r++ {
+		if len(shards[r]) == 0 {
+			shards[r] = make([]byte, size)
+			c.calc(dec[r], in, shards[r])
+		}
+	}
+	for r := c.data; r < c.data+c.parity; r++ {
+		if len(shards[r]) == 0 {
+			shards[r] = make([]byte, size)
+			c.calc(c.matrix[r], shards[:c.data], shards[r])
+		}
+	}
+	return nil
+}
+
+// calc out = sum(row[i] * in[i])
+func (c *Codec) calc(row []byte, in [][]byte, out []byte) {
+	for x := range out {
+		out[x] = 0
+	}
+	for i, f := range row {
+		if f == 0 {
+			continue
+		}
+		if f == 1 {
+			for x, b := range in[i] {
+				out[x] ^= b
+			}
+			continue
+		}
+		lf := int(logTbl[f])
+		for x, b := range in[i] {
+			if b != 0 {
+				out[x] ^= expTbl[lf+int(logTbl[b])]
+			}
+		}
+	}
+}
+
+// invert inverting a square matrix with gauss jordan elimination, the matrix will be changed
+func invert(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	res := make([][]byte, n)
+	for r := range res {
+		res[r] = make([]byte, n)
+		res[r][r] = 1
+	}
+	for col := 0; col < n; col++ {
+		// searching the pivot
+		p := col
+		for p < n && m[p][col] == 0 {
+			p++
+		}
+		if p == n {
+			return nil, ErrSingularMatrix
+		}
+		m[col], m[p] = m[p], m[col]
+		res[col], res[p] = res[p], res[col]
+		f := inv(m[col][col])
+		for x := 0; x < n; x++ {
+			m[col][x] = mul(m[col][x], f)
+			res[col][x] = mul(res[col][x], f)
+		}
+		for r := 0; r < n; r++ {
+			if r == col || m[r][col] == 0 {
+				continue
+			}
+			f := m[r][col]
+			for x := 0; x < n; x++ {
+				m[r][x] ^= mul(f, m[col][x])
+				res[r][x] ^= mul(f, res[col][x])
+			}
+		}
+	}
+	return res, nil
+}
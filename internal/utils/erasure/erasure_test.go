@@ -0,0 +1,69 @@
+package erasure
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGF(t *testing.T) {
+	ast := assert.New(t)
+	for a := 1; a < 256; a++ {
+		ast.Equal(byte(1), mul(byte(a), inv(byte(a))))
+	}
+	ast.Equal(byte(0), mul(0, 17))
+}
+
+func TestNew(t *testing.T) {
+	ast := assert.New(t)
+	_, err := New(0, 2)
+	ast.NotNil(err)
+	_, err = New(200, 100)
+	ast.NotNil(err)
+	c, err := New(4, 2)
+	ast.Nil(err)
+	ast.Equal(4, c.DataShards())
+	ast.Equal(2, c.ParityShards())
+}
+
+func TestReconstruct(t *testing.T) {
+	ast := assert.New(t)
+	c, err := New(4, 3)
+	ast.Nil(err)
+
+	shards := make([][]byte, 7)
+	for i := 0; i < 4; i++ {
+		shards[i] = make([]byte, 1000)
+		rand.Read(shards[i])
+	}
+	ast.Nil(c.Encode(shards))
+	orig := make([][]byte, 7)
+	for i := range shards {
+		orig[i] = append([]byte{}, shards[i]...)
+	}
+
+	// every combination of up to 3 missing shards
+	for a := 0; a < 7; a++ {
+		for b := a; b < 7; b++ {
+			for x := b; x < 7; x++ {
+				test := make([][]byte, 7)
+				copy(test, orig)
+				test[a], test[b], test[x] = nil, nil, nil
+				ast.Nil(c.Reconstruct(test))
+				for i := range test {
+					ast.Equal(orig[i], test[i])
+				}
+			}
+		}
+	}
+
+	test := make([][]byte, 7)
+	copy(test, orig)
+	test[0], test[2], test[4], test[6] = nil, nil, nil, nil
+	ast.ErrorIs(c.Reconstruct(test), ErrTooFewShards)
+
+	ast.ErrorIs(c.Encode(shards[:5]), ErrShardCount)
+	shards[1] = shards[1][:10]
+	ast.ErrorIs(c.Encode(shards), ErrShardSize)
+}
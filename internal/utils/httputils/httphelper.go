@@ -4,6 +4,7 @@ package httputils
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
@@ -74,6 +75,20 @@ func Param(r *http.Request, name string) (string, error) {
 	return cid, nil
 }
 
+// QueryInt gets the integer query param of the given request, def if the param is not present
+func QueryInt(r *http.Request, name string, def int64) (int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		msg := fmt.Sprintf("query param %s is not a number: %s", name, v)
+		return 0, serror.BadRequest(err, "wrong-param", msg)
+	}
+	return i, nil
+}
+
 // Created object created
 func Created(w http.ResponseWriter, r *http.Request, id string, v any) {
 	// TODO add relative path to location
@@ -0,0 +1,40 @@
+package utils
+
+import "sync"
+
+// IDLocks locking single ids, e.g. of blobs, so two writes of the same id are not running in parallel. The zero value is ready to use.
+type IDLocks struct {
+	m     sync.Mutex
+	locks map[string]*idLock
+}
+
+type idLock struct {
+	sync.Mutex
+	count int
+}
+
+// Lock locking the id, returning the function for unlocking
+func (l *IDLocks) Lock(id string) func() {
+	l.m.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*idLock)
+	}
+	il, ok := l.locks[id]
+	if !ok {
+		il = &idLock{}
+		l.locks[id] = il
+	}
+	il.count++
+	l.m.Unlock()
+
+	il.Lock()
+	return func() {
+		il.Unlock()
+		l.m.Lock()
+		il.count--
+		if il.count == 0 {
+			delete(l.locks, id)
+		}
+		l.m.Unlock()
+	}
+}
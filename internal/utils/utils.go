@@ -3,6 +3,7 @@ package utils
 import (
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -22,13 +23,21 @@ func GenerateID() string {
 // BuildHash building a hash string of the binaries of a blob, using sha256
 func BuildHash(id string, stg interfaces.BlobStorage) (string, error) {
 	h := sha256.New()
-	err := stg.RetrieveBlob(id, h)
+	err := RetrieveBlob(stg, id, h, interfaces.CacheBypass)
 	if err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("sha-256:%x", h.Sum(nil)), nil
 }
 
+// RetrieveBlob retrieving the binary data of a blob with the cache hint, if the storage is taking hints
+func RetrieveBlob(stg interfaces.BlobStorage, id string, w io.Writer, hint interfaces.CacheHint) error {
+	if hs, ok := stg.(interfaces.HintedStorage); ok {
+		return hs.RetrieveBlobWithHint(id, w, hint)
+	}
+	return stg.RetrieveBlob(id, w)
+}
+
 // CheckBlob checking the blob with the hash function
 func CheckBlob(id string, s interfaces.BlobStorage) (*model.CheckInfo, error) {
 	ok, err := s.HasBlob(id)
@@ -58,3 +67,38 @@ func CheckBlob(id string, s interfaces.BlobStorage) (*model.CheckInfo, error) {
 	}
 	return &res, nil
 }
+
+// CountWriter counting the bytes written to the underlying writer
+type CountWriter struct {
+	W io.Writer // the underlying writer
+	N int64     // the count of written bytes
+}
+
+func (c *CountWriter) Write(p []byte) (int, error) {
+	n, err := c.W.Write(p)
+	c.N += int64(n)
+	return n, err
+}
+
+// MaxRate the highest rate of blobs per second of a throttled background job
+const MaxRate = 1000
+
+// CheckRate checking the rate of blobs per second of a throttled background job, 0 is for the default rate of the job
+func CheckRate(rate int) error {
+	if rate < 0 || rate > MaxRate {
+		return fmt.Errorf("the rate must be between 1 and %d blobs per second or 0 for the default", MaxRate)
+	}
+	return nil
+}
+
+// RateInterval getting the interval between two blobs of a throttled background job, the interval is at least a millisecond
+func RateInterval(rate int) time.Duration {
+	if rate <= 0 {
+		return time.Second
+	}
+	d := time.Second / time.Duration(rate)
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return d
+}
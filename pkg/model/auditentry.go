@@ -0,0 +1,40 @@
+package model
+
+// DeletionReason the reason why a blob has been deleted
+type DeletionReason string
+
+// defining the different deletion reasons
+const (
+	ReasonRetention     DeletionReason = "retention"
+	ReasonAPI           DeletionReason = "api"
+	ReasonTenantRemoval DeletionReason = "tenantremoval"
+	ReasonBulk          DeletionReason = "bulk"
+)
+
+// AuditEntry one entry of the deletion audit journal
+type AuditEntry struct {
+	TenantID      string         `yaml:"tenantID" json:"tenantID"`
+	BlobID        string         `yaml:"blobID" json:"blobID"`
+	Filename      string         `yaml:"filename" json:"filename"`
+	Hash          string         `yaml:"hash" json:"hash"`
+	ContentLength int64          `yaml:"contentLength" json:"contentLength"`
+	CreationDate  int64          `yaml:"creationDate" json:"creationDate"`
+	Retention     int64          `yaml:"retention" json:"retention"`
+	Reason        DeletionReason `yaml:"reason" json:"reason"`
+	Node          string         `yaml:"node" json:"node"`
+	Timestamp     int64          `yaml:"timestamp" json:"timestamp"`
+}
+
+// AuditEntryFromBlobDescription building an audit entry from a blob description
+func AuditEntryFromBlobDescription(b BlobDescription, reason DeletionReason) AuditEntry {
+	return AuditEntry{
+		TenantID:      b.TenantID,
+		BlobID:        b.BlobID,
+		Filename:      b.Filename,
+		Hash:          b.Hash,
+		ContentLength: b.ContentLength,
+		CreationDate:  b.CreationDate,
+		Retention:     b.Retention,
+		Reason:        reason,
+	}
+}
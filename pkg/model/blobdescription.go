@@ -16,18 +16,28 @@ type BlobDescription struct {
 	BlobID        string `yaml:"blobID" json:"blobID"`
 	LastAccess    int64  `yaml:"lastAccess" json:"lastAccess"`
 	Retention     int64  `yaml:"retention" json:"retention"`
+	RetentionMode string `yaml:"retentionMode,omitempty" json:"retentionMode,omitempty"`
 	BlobURL       string `yaml:"blobUrl" json:"blobUrl"`
 	Hash          string `yaml:"hash" json:"hash"`
+	Tier          string `yaml:"tier,omitempty" json:"tier,omitempty"`
 	Check         *Check `yaml:"check,omitempty" json:"check,omitempty"`
 	Properties    map[string]any
 }
 
+// defining the storage tiers of a blob
+const (
+	TierHot  = "hot"
+	TierCold = "cold"
+)
+
 // Check model for the info objects for  check, backup ...
 type Check struct {
-	Store   *CheckInfo `yaml:"store,omitempty" json:"store,omitempty"`
-	Backup  *CheckInfo `yaml:"backup,omitempty" json:"backup,omitempty"`
-	Healthy bool       `yaml:"healthy,omitempty" json:"healthy,omitempty"`
-	Message string     `yaml:"message,omitempty" json:"message,omitempty"`
+	Store    *CheckInfo `yaml:"store,omitempty" json:"store,omitempty"`
+	Backup   *CheckInfo `yaml:"backup,omitempty" json:"backup,omitempty"`
+	Healthy  bool       `yaml:"healthy,omitempty" json:"healthy,omitempty"`
+	Message  string     `yaml:"message,omitempty" json:"message,omitempty"`
+	Repaired string     `yaml:"repaired,omitempty" json:"repaired,omitempty"` // the repairs done by the check
+	Action   string     `yaml:"action,omitempty" json:"action,omitempty"`     // the problem, which couldn't be repaired and needs a manual action
 }
 
 // CheckInfo model
@@ -50,7 +60,13 @@ func (b BlobDescription) Map() map[string]any {
 	mymap["blobUrl"] = b.BlobURL
 	mymap["lastAccess"] = b.LastAccess
 	mymap["retention"] = b.Retention
+	if b.RetentionMode != "" {
+		mymap["retentionMode"] = b.RetentionMode
+	}
 	mymap["hash"] = b.Hash
+	if b.Tier != "" {
+		mymap["tier"] = b.Tier
+	}
 	if b.Check != nil {
 		mymap["check"] = b.Check
 	}
@@ -78,7 +94,9 @@ func (b *BlobDescription) UnmarshalJSON(data []byte) error {
 		BlobURL       string `yaml:"blobUrl" json:"blobUrl"`
 		LastAccess    int64  `yaml:"lastAccess" json:"lastAccess"`
 		Retention     int64  `yaml:"retention" json:"retention"`
+		RetentionMode string `yaml:"retentionMode,omitempty" json:"retentionMode,omitempty"`
 		Hash          string `yaml:"hash" json:"hash"`
+		Tier          string `yaml:"tier,omitempty" json:"tier,omitempty"`
 		Check         *Check `yaml:"check,omitempty" json:"check,omitempty"`
 	}{}
 	err := json.Unmarshal(data, &blob)
@@ -101,7 +119,9 @@ func (b *BlobDescription) UnmarshalJSON(data []byte) error {
 	delete(mymap, "blobUrl")
 	delete(mymap, "lastAccess")
 	delete(mymap, "retention")
+	delete(mymap, "retentionMode")
 	delete(mymap, "hash")
+	delete(mymap, "tier")
 	delete(mymap, "check")
 
 	b.BlobID = blob.BlobID
@@ -112,9 +132,11 @@ func (b *BlobDescription) UnmarshalJSON(data []byte) error {
 	b.Filename = blob.Filename
 	b.LastAccess = blob.LastAccess
 	b.Retention = blob.Retention
+	b.RetentionMode = blob.RetentionMode
 	b.StoreID = blob.StoreID
 	b.TenantID = blob.TenantID
 	b.Hash = blob.Hash
+	b.Tier = blob.Tier
 	if blob.Check != nil {
 		b.Check = blob.Check
 	}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// defining the operations of a bulk job
+const (
+	BulkOpDelete     = "delete"     // deleting the blobs
+	BulkOpRetention  = "retention"  // changing the retention of the blobs
+	BulkOpProperties = "properties" // merging properties into the blobs
+)
+
+// states of a bulk job
+const (
+	BulkStateRunning   = "running"
+	BulkStateFinished  = "finished"
+	BulkStateFailed    = "failed"
+	BulkStateCancelled = "cancelled"
+)
+
+// results of a single blob of a bulk job
+const (
+	BulkResultDone    = "done"    // the operation was executed
+	BulkResultMatched = "matched" // dry run, the operation would be executed
+	BulkResultFailed  = "failed"  // the operation failed
+)
+
+// BulkRequest the request for a bulk operation on all blobs of a tenant matching the query
+type BulkRequest struct {
+	Query         string         `json:"query"`
+	Operation     string         `json:"operation"`
+	Retention     int64          `json:"retention,omitempty"`     // the new retention in minutes, for the retention operation
+	RetentionMode string         `json:"retentionMode,omitempty"` // the new retention mode, for the retention operation, empty keeps the mode
+	Properties    map[string]any `json:"properties,omitempty"`    // the properties to merge, a null value removes the property, the keys get the header prefix
+	DryRun        bool           `json:"dryRun"`                  // only counting and reporting the matching blobs
+	Rate          int            `json:"rate,omitempty"`          // maximum count of blobs processed per second, 0 for the default
+}
+
+// BulkResult the result of a bulk operation on a single blob
+type BulkResult struct {
+	BlobID string `json:"blobID"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkJob the status of a bulk job of a tenant
+type BulkJob struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant"`
+	Operation string    `json:"operation"`
+	Query     string    `json:"query"`
+	DryRun    bool      `json:"dryRun"`
+	State     string    `json:"state"`
+	Running   bool      `json:"running"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Total     int       `json:"total"`     // count of matching blobs
+	Processed int       `json:"processed"` // blobs processed so far
+	Succeeded int       `json:"succeeded"` // blobs done, or matched in a dry run
+	Failed    int       `json:"failed"`    // blobs with errors
+	LastError string    `json:"lastError,omitempty"`
+}
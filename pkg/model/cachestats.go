@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// CacheStats the statistics of a cache partition
+type CacheStats struct {
+	Partition string   `json:"partition"`
+	Tenants   []string `json:"tenants,omitempty"` // the tenants of a group partition
+	Policy    string   `json:"policy"`
+	Count     int      `json:"count"`
+	Size      int64    `json:"size"`    // size of all blobs in bytes
+	RAMSize   int64    `json:"ramSize"` // size of the blobs held in memory in bytes
+	Pinned    int      `json:"pinned"`
+	MaxCount  int64    `json:"maxCount"`
+	MaxSize   int64    `json:"maxSize,omitempty"`
+	Hits      int64    `json:"hits"`
+	Misses    int64    `json:"misses"`
+	Evictions int64    `json:"evictions"`
+}
+
+// CacheEntry the info of a single blob in the cache
+type CacheEntry struct {
+	BlobID     string    `json:"blobid"`
+	TenantID   string    `json:"tenantid"`
+	Size       int64     `json:"size"`
+	Hits       int64     `json:"hits"`
+	LastAccess time.Time `json:"lastAccess"`
+	InRAM      bool      `json:"inRAM"`
+	Pinned     bool      `json:"pinned"`
+}
+
+// states of a cache warming
+const (
+	WarmingStateRunning   = "running"
+	WarmingStateFinished  = "finished"
+	WarmingStateFailed    = "failed"
+	WarmingStateCancelled = "cancelled"
+)
+
+// CacheWarmRequest the request for warming the cache of a tenant, the blobs are given by ids or by a search query
+type CacheWarmRequest struct {
+	IDs   []string `json:"ids,omitempty"`
+	Query string   `json:"query,omitempty"`
+	Rate  int      `json:"rate,omitempty"` // maximum count of blobs loaded per second, 0 for the default
+}
+
+// CacheWarming the status of the warming of the cache of a tenant
+type CacheWarming struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant"`
+	State     string    `json:"state"`
+	Running   bool      `json:"running"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Total     int       `json:"total"`   // count of blobs to load
+	Loaded    int       `json:"loaded"`  // blobs loaded into the cache
+	Skipped   int       `json:"skipped"` // blobs already in the cache
+	Failed    int       `json:"failed"`  // blobs which couldn't be loaded
+	LastError string    `json:"lastError,omitempty"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// states of a check run
+const (
+	CheckStateRunning  = "running"
+	CheckStateFinished = "finished"
+	CheckStateFailed   = "failed"
+)
+
+// storages of a check result line
+const (
+	CheckStorageCache   = "cache"
+	CheckStoragePrimary = "primary"
+	CheckStorageBackup  = "backup"
+)
+
+// CheckReport the summary of a check run of a tenant
+type CheckReport struct {
+	ID       string    `json:"id"`
+	Tenant   string    `json:"tenant"`
+	State    string    `json:"state"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Cache    int       `json:"cache"`   // count of blobs of the tenant in the cache
+	Primary  int       `json:"primary"` // count of blobs on the primary storage
+	Backup   int       `json:"backup"`  // count of blobs on the backup storage
+	Errors   int       `json:"errors"`  // count of result lines with errors
+	Message  string    `json:"message,omitempty"`
+}
+
+// CheckResultLine the result of a single blob of a check run, one line of the report
+type CheckResultLine struct {
+	Storage       string   `json:"storage"` // the storage, on which the blob was found
+	ID            string   `json:"id"`
+	Filename      string   `json:"filename,omitempty"`
+	InCache       bool     `json:"inCache"`
+	InBackup      bool     `json:"inBackup"`
+	PrimaryHashOK bool     `json:"primaryHashOK"`
+	BackupHashOK  bool     `json:"backupHashOK"`
+	HasError      bool     `json:"hasError"`
+	Messages      []string `json:"messages"`
+}
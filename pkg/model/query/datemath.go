@@ -0,0 +1,68 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DateMath a point in time relative to now, like now-30d
+type DateMath struct {
+	Amount int    // the offset, negative values are in the past
+	Unit   string // unit of the offset, s, m, h, d, w, M or y
+}
+
+var dateMathRegex = regexp.MustCompile(`^now(([+-]\d+)([smhdwMy]))?$`)
+
+// ParseDateMath parsing a date math expression like now, now-30d or now+1h
+func ParseDateMath(s string) (DateMath, error) {
+	m := dateMathRegex.FindStringSubmatch(s)
+	if m == nil {
+		return DateMath{}, fmt.Errorf("invalid date math: %s", s)
+	}
+	if m[1] == "" {
+		return DateMath{}, nil
+	}
+	a, err := strconv.Atoi(m[2])
+	if err != nil {
+		return DateMath{}, err
+	}
+	return DateMath{
+		Amount: a,
+		Unit:   m[3],
+	}, nil
+}
+
+// Time getting the point in time relative to now
+func (d DateMath) Time(now time.Time) time.Time {
+	switch d.Unit {
+	case "s":
+		return now.Add(time.Duration(d.Amount) * time.Second)
+	case "m":
+		return now.Add(time.Duration(d.Amount) * time.Minute)
+	case "h":
+		return now.Add(time.Duration(d.Amount) * time.Hour)
+	case "d":
+		return now.AddDate(0, 0, d.Amount)
+	case "w":
+		return now.AddDate(0, 0, 7*d.Amount)
+	case "M":
+		return now.AddDate(0, d.Amount, 0)
+	case "y":
+		return now.AddDate(d.Amount, 0, 0)
+	}
+	return now
+}
+
+// Millis getting the point in time relative to now as unix milliseconds, like the creationDate of a blob
+func (d DateMath) Millis(now time.Time) int64 {
+	return d.Time(now).UnixMilli()
+}
+
+func (d DateMath) String() string {
+	if d.Amount == 0 || d.Unit == "" {
+		return "now"
+	}
+	return fmt.Sprintf("now%+d%s", d.Amount, d.Unit)
+}
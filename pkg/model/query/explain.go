@@ -0,0 +1,122 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ParseError a single error of parsing a query with the position in the query
+type ParseError struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Offset   int      `json:"offset"`
+	Message  string   `json:"message"`
+	Expected []string `json:"expected,omitempty"`
+}
+
+// the parser is working on the global node stack
+var psync sync.Mutex
+
+// ParseQuery parsing a query string into a query, can be used concurrently
+func ParseQuery(s string) (Query, error) {
+	psync.Lock()
+	defer psync.Unlock()
+	N.Reset()
+	res, err := Parse("query", []byte(s))
+	if err != nil {
+		return Query{}, err
+	}
+	q, ok := res.(Query)
+	if !ok {
+		return Query{}, errors.New("unknown result")
+	}
+	return q, nil
+}
+
+// ParseErrors getting the single errors with their positions from an error of the parser
+func ParseErrors(err error) []ParseError {
+	if err == nil {
+		return nil
+	}
+	var el errList
+	if !errors.As(err, &el) {
+		return []ParseError{{Message: err.Error()}}
+	}
+	pes := make([]ParseError, 0, len(el))
+	for _, e := range el {
+		var pe *parserError
+		if !errors.As(e, &pe) {
+			pes = append(pes, ParseError{Message: e.Error()})
+			continue
+		}
+		pes = append(pes, ParseError{
+			Line:     pe.pos.line,
+			Column:   pe.pos.col,
+			Offset:   pe.pos.offset,
+			Message:  pe.Inner.Error(),
+			Expected: pe.expected,
+		})
+	}
+	return pes
+}
+
+var lowerOps = regexp.MustCompile(`(^|[\s(])(and|or|not|And|Or|Not)[\s(]`)
+
+// Suggestions getting some hints for fixing the errors of a query
+func Suggestions(s string, errs []ParseError) []string {
+	sgs := make([]string, 0)
+	if strings.TrimSpace(s) == "" {
+		return append(sgs, `the query is empty, a simple query looks like field:value`)
+	}
+	if strings.Count(s, `"`)%2 != 0 {
+		sgs = append(sgs, `a phrase is missing the closing double quote`)
+	}
+	// parentheses in phrases are not counted
+	var b strings.Builder
+	for i, p := range strings.Split(s, `"`) {
+		if i%2 == 0 {
+			b.WriteString(p)
+		}
+	}
+	us := b.String()
+	op, cp := strings.Count(us, "("), strings.Count(us, ")")
+	if op > cp {
+		sgs = append(sgs, fmt.Sprintf("%d closing parenthesis missing", op-cp))
+	}
+	if cp > op {
+		sgs = append(sgs, fmt.Sprintf("%d opening parenthesis missing", cp-op))
+	}
+	lo := lowerOps.FindStringSubmatch(us)
+	if lo != nil {
+		sgs = append(sgs, fmt.Sprintf("operators must be upper case, use %s instead of %s", strings.ToUpper(lo[2]), lo[2]))
+	}
+	for _, e := range errs {
+		if strings.Contains(e.Message, "mix AND and OR") {
+			sgs = append(sgs, `AND and OR can't be mixed in one group, use parentheses like (a:1 AND b:2) OR c:3`)
+		}
+		if contains(e.Expected, `"AND"`) && lo == nil && e.Offset <= len(s) {
+			f := strings.Fields(s[:e.Offset])
+			if len(f) > 0 && !strings.ContainsAny(f[len(f)-1], ":()") {
+				sgs = append(sgs, `a field must be followed by a colon and the value, like field:value`)
+			} else {
+				sgs = append(sgs, `terms must be combined with one of the operators AND, OR, & or |`)
+			}
+		}
+		if contains(e.Expected, "[smhdwMy]") {
+			sgs = append(sgs, `date math must look like now, now-30d or now+1h, units are s, m, h, d, w, M and y`)
+		}
+	}
+	return sgs
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
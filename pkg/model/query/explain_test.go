@@ -0,0 +1,73 @@
+package query
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	ast := assert.New(t)
+
+	var wg sync.WaitGroup
+	for x := 0; x < 10; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q, err := ParseQuery(`field1:1 AND NOT (field2:2 OR field3:3)`)
+			ast.Nil(err)
+			ast.Equal(`query: (field1:1.00000000 AND !(field2:2.00000000 OR field3:3.00000000)), sort `, q.String())
+		}()
+	}
+	wg.Wait()
+
+	_, err := ParseQuery(`field1:`)
+	ast.NotNil(err)
+}
+
+func TestParseErrors(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Nil(ParseErrors(nil))
+
+	_, err := ParseQuery("field1:1 AND\n  field2:2 OR field3:3")
+	pes := ParseErrors(err)
+	ast.Equal(1, len(pes))
+	ast.Equal(2, pes[0].Line)
+	ast.Equal(12, pes[0].Column)
+	ast.Equal(24, pes[0].Offset)
+	ast.Contains(pes[0].Message, "mix AND and OR")
+
+	_, err = ParseQuery(`field1:"Willie`)
+	pes = ParseErrors(err)
+	ast.Equal(1, len(pes))
+	ast.Equal(1, pes[0].Line)
+	ast.Equal(15, pes[0].Column)
+	ast.Contains(pes[0].Expected, `"\""`)
+}
+
+func TestSuggestions(t *testing.T) {
+	ast := assert.New(t)
+	tests := []struct {
+		q string
+		s string
+	}{
+		{q: ``, s: "the query is empty"},
+		{q: `field1:1 AND field2:2 OR field3:3`, s: "AND and OR can't be mixed"},
+		{q: `field1:"Willie`, s: "closing double quote"},
+		{q: `(field1:1 AND field2:2`, s: "1 closing parenthesis missing"},
+		{q: `field1:1)`, s: "1 opening parenthesis missing"},
+		{q: `field1:1 and field2:2`, s: "use AND instead of and"},
+		{q: `field1 Willie`, s: "a field must be followed by a colon"},
+		{q: `field1:1 field2:2`, s: "terms must be combined"},
+		{q: `creationDate:>now-30x`, s: "date math must look like"},
+	}
+	for _, tc := range tests {
+		_, err := ParseQuery(tc.q)
+		ast.NotNil(err, tc.q)
+		sgs := Suggestions(tc.q, ParseErrors(err))
+		ast.Contains(strings.Join(sgs, "\n"), tc.s, tc.q)
+	}
+}
@@ -9,6 +9,8 @@ type NodeStack struct {
 	InvertGroup      bool
 	currentNode      *Node
 	currentCondition *Condition
+	currentGroup     *Node   // the last closed group, not yet added to the current node
+	groups           []*Node // the enclosing nodes of the open groups
 }
 
 // N default nodestack
@@ -27,17 +29,19 @@ func (ns *NodeStack) Init() {
 func (ns *NodeStack) Reset() {
 	ns.currentNode = nil
 	ns.currentCondition = nil
+	ns.currentGroup = nil
+	ns.groups = nil
 }
 
 // Query generating a query from this nodestack
 func (ns *NodeStack) Query() Query {
 	logger.Info("get query")
 	var c any
-	c = ns.currentNode
 	if ns.currentNode == nil {
-		c = ns.currentCondition
+		c = ns.pending()
 	} else {
-		ns.currentNode.Conditions = append(ns.currentNode.Conditions, ns.currentCondition)
+		ns.flush()
+		c = ns.currentNode
 	}
 	q := Query{
 		Sorting:   []string{""},
@@ -54,14 +58,12 @@ func (ns *NodeStack) NewNode() *Node {
 		Conditions: make([]any, 0),
 	}
 	if ns.currentNode != nil {
-		ns.currentNode.Conditions = append(ns.currentNode.Conditions, ns.currentCondition)
+		ns.flush()
 		n.Conditions = append(n.Conditions, ns.currentNode)
+	} else if p := ns.pending(); p != nil {
+		n.Conditions = append(n.Conditions, p)
 		ns.currentCondition = nil
-	} else {
-		if ns.currentCondition != nil {
-			n.Conditions = append(n.Conditions, ns.currentCondition)
-			ns.currentCondition = nil
-		}
+		ns.currentGroup = nil
 	}
 	ns.currentNode = &n
 	return &n
@@ -78,14 +80,13 @@ func (ns *NodeStack) CurrentNode() *Node {
 // NewCondition create a new condition and add it to the actual nodestack
 func (ns *NodeStack) NewCondition() *Condition {
 	logger.Info("new condition")
-	if ns.currentCondition != nil {
+	if ns.pending() != nil {
 		if ns.currentNode != nil {
-			ns.currentNode.Conditions = append(ns.currentNode.Conditions, ns.currentCondition)
+			ns.flush()
 		} else {
 			ns.NewNode()
 		}
 	}
-	ns.currentCondition = nil
 	c := Condition{
 		Operator: NO,
 		Field:    "",
@@ -102,3 +103,52 @@ func (ns *NodeStack) CurrentCondition() *Condition {
 	}
 	return ns.currentCondition
 }
+
+// StartGroup opening a new group, the enclosing node is restored on closing the group
+func (ns *NodeStack) StartGroup(invert bool) *Node {
+	logger.Info("start group")
+	ns.groups = append(ns.groups, ns.currentNode)
+	n := Node{
+		Operator:   NOOP,
+		Conditions: make([]any, 0),
+		Invert:     invert,
+	}
+	ns.currentNode = &n
+	ns.currentCondition = nil
+	ns.currentGroup = nil
+	return &n
+}
+
+// EndGroup closing the actual group, the group will be added to the enclosing node with the next operator
+func (ns *NodeStack) EndGroup() *Node {
+	logger.Info("end group")
+	g := ns.CurrentNode()
+	ns.flush()
+	ns.currentNode = nil
+	if l := len(ns.groups); l > 0 {
+		ns.currentNode = ns.groups[l-1]
+		ns.groups = ns.groups[:l-1]
+	}
+	ns.currentGroup = g
+	return g
+}
+
+// pending getting the last group or condition, which is not added to a node
+func (ns *NodeStack) pending() any {
+	if ns.currentGroup != nil {
+		return ns.currentGroup
+	}
+	if ns.currentCondition != nil {
+		return ns.currentCondition
+	}
+	return nil
+}
+
+// flush adding the pending group or condition to the current node
+func (ns *NodeStack) flush() {
+	if p := ns.pending(); p != nil {
+		ns.currentNode.Conditions = append(ns.currentNode.Conditions, p)
+	}
+	ns.currentCondition = nil
+	ns.currentGroup = nil
+}
@@ -49,3 +49,34 @@ func TestNodeStackCondition(t *testing.T) {
 	ast.Equal(LT, ns.CurrentCondition().Operator)
 	ast.Equal("Willie", c.Field)
 }
+
+func TestNodeStackGroup(t *testing.T) {
+	ast := assert.New(t)
+
+	ns := NodeStack{}
+	ns.Init()
+
+	c := ns.CurrentCondition()
+	c.Field = "field1"
+	ns.NewCondition()
+	ns.CurrentNode().Operator = ANDOP
+
+	g := ns.StartGroup(true)
+	ast.True(g.Invert)
+	ast.Equal(g, ns.CurrentNode())
+	ns.CurrentCondition().Field = "field2"
+	ns.NewCondition()
+	ns.CurrentNode().Operator = OROP
+	ns.CurrentCondition().Field = "field3"
+	ast.Equal(g, ns.EndGroup())
+
+	q := ns.Query()
+	n, ok := q.Condition.(*Node)
+	ast.True(ok)
+	ast.Equal(ANDOP, n.Operator)
+	ast.Equal(2, len(n.Conditions))
+	ast.Equal("field1", n.Conditions[0].(*Condition).Field)
+	ast.Equal(g, n.Conditions[1])
+	ast.Equal(2, len(g.Conditions))
+	ast.Equal("field3", g.Conditions[1].(*Condition).Field)
+}
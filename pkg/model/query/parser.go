@@ -46,6 +46,14 @@ func toString(label interface{}) string {
 	return sb.String()
 }
 
+func toList(first interface{}, rest interface{}) []interface{} {
+	l := []interface{}{first}
+	for _, r := range rest.([]interface{}) {
+		l = append(l, r.([]interface{})[3])
+	}
+	return l
+}
+
 func toPrimitive(label interface{}) interface{} {
 	s := toString(label)
 	f, err := strconv.ParseFloat(s, 64)
@@ -67,35 +75,35 @@ var g = &grammar{
 	rules: []*rule{
 		{
 			name: "Input",
-			pos:  position{line: 57, col: 1, offset: 1181},
+			pos:  position{line: 65, col: 1, offset: 1395},
 			expr: &actionExpr{
-				pos: position{line: 57, col: 15, offset: 1195},
+				pos: position{line: 65, col: 15, offset: 1409},
 				run: (*parser).callonInput1,
 				expr: &seqExpr{
-					pos: position{line: 57, col: 15, offset: 1195},
-					exprs: []interface{}{
+					pos: position{line: 65, col: 15, offset: 1409},
+					exprs: []any{
 						&zeroOrOneExpr{
-							pos: position{line: 57, col: 15, offset: 1195},
+							pos: position{line: 65, col: 15, offset: 1409},
 							expr: &ruleRefExpr{
-								pos:  position{line: 57, col: 15, offset: 1195},
+								pos:  position{line: 65, col: 15, offset: 1409},
 								name: "_",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 57, col: 18, offset: 1198},
+							pos:  position{line: 65, col: 18, offset: 1412},
 							name: "Query",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 57, col: 24, offset: 1204},
+							pos: position{line: 65, col: 24, offset: 1418},
 							expr: &ruleRefExpr{
-								pos:  position{line: 57, col: 24, offset: 1204},
+								pos:  position{line: 65, col: 24, offset: 1418},
 								name: "_",
 							},
 						},
 						&notExpr{
-							pos: position{line: 57, col: 27, offset: 1207},
+							pos: position{line: 65, col: 27, offset: 1421},
 							expr: &anyMatcher{
-								line: 57, col: 28, offset: 1208,
+								line: 65, col: 28, offset: 1422,
 							},
 						},
 					},
@@ -104,41 +112,41 @@ var g = &grammar{
 		},
 		{
 			name: "Query",
-			pos:  position{line: 61, col: 1, offset: 1245},
+			pos:  position{line: 69, col: 1, offset: 1459},
 			expr: &ruleRefExpr{
-				pos:  position{line: 61, col: 15, offset: 1259},
+				pos:  position{line: 69, col: 15, offset: 1473},
 				name: "Exprs",
 			},
 		},
 		{
 			name: "Exprs",
-			pos:  position{line: 62, col: 1, offset: 1266},
+			pos:  position{line: 70, col: 1, offset: 1480},
 			expr: &seqExpr{
-				pos: position{line: 62, col: 15, offset: 1280},
-				exprs: []interface{}{
+				pos: position{line: 70, col: 15, offset: 1494},
+				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 62, col: 15, offset: 1280},
+						pos:  position{line: 70, col: 15, offset: 1494},
 						name: "Expr",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 62, col: 20, offset: 1285},
+						pos: position{line: 70, col: 20, offset: 1499},
 						expr: &seqExpr{
-							pos: position{line: 62, col: 21, offset: 1286},
-							exprs: []interface{}{
+							pos: position{line: 70, col: 21, offset: 1500},
+							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 62, col: 21, offset: 1286},
+									pos:  position{line: 70, col: 21, offset: 1500},
 									name: "_",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 62, col: 23, offset: 1288},
+									pos:  position{line: 70, col: 23, offset: 1502},
 									name: "Operator",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 62, col: 32, offset: 1297},
+									pos:  position{line: 70, col: 32, offset: 1511},
 									name: "_",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 62, col: 34, offset: 1299},
+									pos:  position{line: 70, col: 34, offset: 1513},
 									name: "Expr",
 								},
 							},
@@ -149,23 +157,23 @@ var g = &grammar{
 		},
 		{
 			name: "Operator",
-			pos:  position{line: 63, col: 1, offset: 1307},
+			pos:  position{line: 71, col: 1, offset: 1521},
 			expr: &choiceExpr{
-				pos: position{line: 63, col: 15, offset: 1321},
-				alternatives: []interface{}{
+				pos: position{line: 71, col: 15, offset: 1535},
+				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 63, col: 15, offset: 1321},
+						pos: position{line: 71, col: 15, offset: 1535},
 						run: (*parser).callonOperator2,
 						expr: &ruleRefExpr{
-							pos:  position{line: 63, col: 15, offset: 1321},
+							pos:  position{line: 71, col: 15, offset: 1535},
 							name: "OR",
 						},
 					},
 					&actionExpr{
-						pos: position{line: 63, col: 205, offset: 1511},
+						pos: position{line: 71, col: 205, offset: 1725},
 						run: (*parser).callonOperator4,
 						expr: &ruleRefExpr{
-							pos:  position{line: 63, col: 205, offset: 1511},
+							pos:  position{line: 71, col: 205, offset: 1725},
 							name: "AND",
 						},
 					},
@@ -174,16 +182,16 @@ var g = &grammar{
 		},
 		{
 			name: "Expr",
-			pos:  position{line: 64, col: 1, offset: 1700},
+			pos:  position{line: 72, col: 1, offset: 1914},
 			expr: &choiceExpr{
-				pos: position{line: 64, col: 15, offset: 1714},
-				alternatives: []interface{}{
+				pos: position{line: 72, col: 15, offset: 1928},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 64, col: 15, offset: 1714},
+						pos:  position{line: 72, col: 15, offset: 1928},
 						name: "GroupOrNot",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 64, col: 28, offset: 1727},
+						pos:  position{line: 72, col: 28, offset: 1941},
 						name: "Term",
 					},
 				},
@@ -191,26 +199,30 @@ var g = &grammar{
 		},
 		{
 			name: "Term",
-			pos:  position{line: 66, col: 1, offset: 1735},
+			pos:  position{line: 74, col: 1, offset: 1949},
 			expr: &seqExpr{
-				pos: position{line: 66, col: 15, offset: 1749},
-				exprs: []interface{}{
+				pos: position{line: 74, col: 15, offset: 1963},
+				exprs: []any{
 					&zeroOrOneExpr{
-						pos: position{line: 66, col: 15, offset: 1749},
+						pos: position{line: 74, col: 15, offset: 1963},
 						expr: &ruleRefExpr{
-							pos:  position{line: 66, col: 15, offset: 1749},
+							pos:  position{line: 74, col: 15, offset: 1963},
 							name: "NotCheck",
 						},
 					},
 					&choiceExpr{
-						pos: position{line: 66, col: 26, offset: 1760},
-						alternatives: []interface{}{
+						pos: position{line: 74, col: 26, offset: 1974},
+						alternatives: []any{
 							&ruleRefExpr{
-								pos:  position{line: 66, col: 26, offset: 1760},
+								pos:  position{line: 74, col: 26, offset: 1974},
+								name: "Exists",
+							},
+							&ruleRefExpr{
+								pos:  position{line: 74, col: 35, offset: 1983},
 								name: "KeyValue",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 66, col: 37, offset: 1771},
+								pos:  position{line: 74, col: 46, offset: 1994},
 								name: "SingleValue",
 							},
 						},
@@ -220,21 +232,21 @@ var g = &grammar{
 		},
 		{
 			name: "NotCheck",
-			pos:  position{line: 67, col: 1, offset: 1785},
+			pos:  position{line: 75, col: 1, offset: 2008},
 			expr: &actionExpr{
-				pos: position{line: 67, col: 15, offset: 1799},
+				pos: position{line: 75, col: 15, offset: 2022},
 				run: (*parser).callonNotCheck1,
 				expr: &seqExpr{
-					pos: position{line: 67, col: 15, offset: 1799},
-					exprs: []interface{}{
+					pos: position{line: 75, col: 15, offset: 2022},
+					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 67, col: 15, offset: 1799},
+							pos:  position{line: 75, col: 15, offset: 2022},
 							name: "NOT",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 67, col: 19, offset: 1803},
+							pos: position{line: 75, col: 19, offset: 2026},
 							expr: &ruleRefExpr{
-								pos:  position{line: 67, col: 19, offset: 1803},
+								pos:  position{line: 75, col: 19, offset: 2026},
 								name: "_",
 							},
 						},
@@ -244,16 +256,16 @@ var g = &grammar{
 		},
 		{
 			name: "GroupOrNot",
-			pos:  position{line: 72, col: 1, offset: 1876},
+			pos:  position{line: 80, col: 1, offset: 2099},
 			expr: &seqExpr{
-				pos: position{line: 72, col: 18, offset: 1893},
-				exprs: []interface{}{
+				pos: position{line: 80, col: 18, offset: 2116},
+				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 72, col: 18, offset: 1893},
+						pos:  position{line: 80, col: 18, offset: 2116},
 						name: "GroupPrefix",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 72, col: 30, offset: 1905},
+						pos:  position{line: 80, col: 30, offset: 2128},
 						name: "GroupSuffix",
 					},
 				},
@@ -261,16 +273,16 @@ var g = &grammar{
 		},
 		{
 			name: "GroupPrefix",
-			pos:  position{line: 73, col: 1, offset: 1918},
+			pos:  position{line: 81, col: 1, offset: 2141},
 			expr: &choiceExpr{
-				pos: position{line: 73, col: 18, offset: 1935},
-				alternatives: []interface{}{
+				pos: position{line: 81, col: 18, offset: 2158},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 73, col: 18, offset: 1935},
+						pos:  position{line: 81, col: 18, offset: 2158},
 						name: "NotGroupStart",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 73, col: 34, offset: 1951},
+						pos:  position{line: 81, col: 34, offset: 2174},
 						name: "GroupStart",
 					},
 				},
@@ -278,22 +290,22 @@ var g = &grammar{
 		},
 		{
 			name: "GroupStart",
-			pos:  position{line: 74, col: 1, offset: 1963},
+			pos:  position{line: 82, col: 1, offset: 2186},
 			expr: &actionExpr{
-				pos: position{line: 74, col: 18, offset: 1980},
+				pos: position{line: 82, col: 18, offset: 2203},
 				run: (*parser).callonGroupStart1,
 				expr: &seqExpr{
-					pos: position{line: 74, col: 18, offset: 1980},
-					exprs: []interface{}{
+					pos: position{line: 82, col: 18, offset: 2203},
+					exprs: []any{
 						&notExpr{
-							pos: position{line: 74, col: 18, offset: 1980},
+							pos: position{line: 82, col: 18, offset: 2203},
 							expr: &ruleRefExpr{
-								pos:  position{line: 74, col: 19, offset: 1981},
+								pos:  position{line: 82, col: 19, offset: 2204},
 								name: "Not",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 74, col: 23, offset: 1985},
+							pos:  position{line: 82, col: 23, offset: 2208},
 							name: "OPENPAREN",
 						},
 					},
@@ -302,19 +314,19 @@ var g = &grammar{
 		},
 		{
 			name: "NotGroupStart",
-			pos:  position{line: 75, col: 1, offset: 2033},
+			pos:  position{line: 83, col: 1, offset: 2256},
 			expr: &actionExpr{
-				pos: position{line: 75, col: 18, offset: 2050},
+				pos: position{line: 83, col: 18, offset: 2273},
 				run: (*parser).callonNotGroupStart1,
 				expr: &seqExpr{
-					pos: position{line: 75, col: 18, offset: 2050},
-					exprs: []interface{}{
+					pos: position{line: 83, col: 18, offset: 2273},
+					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 75, col: 18, offset: 2050},
+							pos:  position{line: 83, col: 18, offset: 2273},
 							name: "Not",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 75, col: 22, offset: 2054},
+							pos:  position{line: 83, col: 22, offset: 2277},
 							name: "OPENPAREN",
 						},
 					},
@@ -323,49 +335,53 @@ var g = &grammar{
 		},
 		{
 			name: "GroupSuffix",
-			pos:  position{line: 76, col: 1, offset: 2099},
-			expr: &seqExpr{
-				pos: position{line: 76, col: 18, offset: 2116},
-				exprs: []interface{}{
-					&zeroOrOneExpr{
-						pos: position{line: 76, col: 18, offset: 2116},
-						expr: &ruleRefExpr{
-							pos:  position{line: 76, col: 18, offset: 2116},
-							name: "_",
+			pos:  position{line: 84, col: 1, offset: 2325},
+			expr: &actionExpr{
+				pos: position{line: 84, col: 18, offset: 2342},
+				run: (*parser).callonGroupSuffix1,
+				expr: &seqExpr{
+					pos: position{line: 84, col: 18, offset: 2342},
+					exprs: []any{
+						&zeroOrOneExpr{
+							pos: position{line: 84, col: 18, offset: 2342},
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 18, offset: 2342},
+								name: "_",
+							},
 						},
-					},
-					&ruleRefExpr{
-						pos:  position{line: 76, col: 21, offset: 2119},
-						name: "Query",
-					},
-					&zeroOrOneExpr{
-						pos: position{line: 76, col: 27, offset: 2125},
-						expr: &ruleRefExpr{
-							pos:  position{line: 76, col: 27, offset: 2125},
-							name: "_",
+						&ruleRefExpr{
+							pos:  position{line: 84, col: 21, offset: 2345},
+							name: "Query",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 84, col: 27, offset: 2351},
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 27, offset: 2351},
+								name: "_",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 84, col: 30, offset: 2354},
+							name: "CLOSEPAREN",
 						},
-					},
-					&ruleRefExpr{
-						pos:  position{line: 76, col: 30, offset: 2128},
-						name: "CLOSEPAREN",
 					},
 				},
 			},
 		},
 		{
 			name: "Not",
-			pos:  position{line: 77, col: 1, offset: 2140},
+			pos:  position{line: 85, col: 1, offset: 2395},
 			expr: &seqExpr{
-				pos: position{line: 77, col: 18, offset: 2157},
-				exprs: []interface{}{
+				pos: position{line: 85, col: 18, offset: 2412},
+				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 77, col: 18, offset: 2157},
+						pos:  position{line: 85, col: 18, offset: 2412},
 						name: "NOT",
 					},
 					&zeroOrOneExpr{
-						pos: position{line: 77, col: 22, offset: 2161},
+						pos: position{line: 85, col: 22, offset: 2416},
 						expr: &ruleRefExpr{
-							pos:  position{line: 77, col: 22, offset: 2161},
+							pos:  position{line: 85, col: 22, offset: 2416},
 							name: "_",
 						},
 					},
@@ -374,37 +390,37 @@ var g = &grammar{
 		},
 		{
 			name: "KeyValue",
-			pos:  position{line: 79, col: 1, offset: 2167},
+			pos:  position{line: 87, col: 1, offset: 2422},
 			expr: &actionExpr{
-				pos: position{line: 79, col: 18, offset: 2184},
+				pos: position{line: 87, col: 18, offset: 2439},
 				run: (*parser).callonKeyValue1,
 				expr: &seqExpr{
-					pos: position{line: 79, col: 18, offset: 2184},
-					exprs: []interface{}{
+					pos: position{line: 87, col: 18, offset: 2439},
+					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 79, col: 18, offset: 2184},
+							pos:   position{line: 87, col: 18, offset: 2439},
 							label: "k",
 							expr: &ruleRefExpr{
-								pos:  position{line: 79, col: 20, offset: 2186},
+								pos:  position{line: 87, col: 20, offset: 2441},
 								name: "Key",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 79, col: 24, offset: 2190},
+							pos:  position{line: 87, col: 24, offset: 2445},
 							name: "COLON",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 79, col: 30, offset: 2196},
+							pos: position{line: 87, col: 30, offset: 2451},
 							expr: &ruleRefExpr{
-								pos:  position{line: 79, col: 30, offset: 2196},
+								pos:  position{line: 87, col: 30, offset: 2451},
 								name: "_",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 79, col: 33, offset: 2199},
+							pos:   position{line: 87, col: 33, offset: 2454},
 							label: "v",
 							expr: &ruleRefExpr{
-								pos:  position{line: 79, col: 35, offset: 2201},
+								pos:  position{line: 87, col: 35, offset: 2456},
 								name: "Value",
 							},
 						},
@@ -412,26 +428,82 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "Exists",
+			pos:  position{line: 94, col: 1, offset: 2619},
+			expr: &actionExpr{
+				pos: position{line: 94, col: 18, offset: 2636},
+				run: (*parser).callonExists1,
+				expr: &seqExpr{
+					pos: position{line: 94, col: 18, offset: 2636},
+					exprs: []any{
+						&litMatcher{
+							pos:        position{line: 94, col: 18, offset: 2636},
+							val:        "EXISTS",
+							ignoreCase: false,
+							want:       "\"EXISTS\"",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 94, col: 27, offset: 2645},
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 27, offset: 2645},
+								name: "_",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 94, col: 30, offset: 2648},
+							name: "OPENPAREN",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 94, col: 40, offset: 2658},
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 40, offset: 2658},
+								name: "_",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 94, col: 43, offset: 2661},
+							label: "k",
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 45, offset: 2663},
+								name: "Key",
+							},
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 94, col: 49, offset: 2667},
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 49, offset: 2667},
+								name: "_",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 94, col: 52, offset: 2670},
+							name: "CLOSEPAREN",
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "SingleValue",
-			pos:  position{line: 85, col: 1, offset: 2310},
+			pos:  position{line: 100, col: 1, offset: 2788},
 			expr: &choiceExpr{
-				pos: position{line: 85, col: 18, offset: 2327},
-				alternatives: []interface{}{
+				pos: position{line: 100, col: 18, offset: 2805},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 18, offset: 2327},
+						pos:  position{line: 100, col: 18, offset: 2805},
 						name: "Phrase",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 27, offset: 2336},
+						pos:  position{line: 100, col: 27, offset: 2814},
 						name: "DateTime",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 38, offset: 2347},
+						pos:  position{line: 100, col: 38, offset: 2825},
 						name: "Number",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 85, col: 47, offset: 2356},
+						pos:  position{line: 100, col: 47, offset: 2834},
 						name: "Word",
 					},
 				},
@@ -439,14 +511,14 @@ var g = &grammar{
 		},
 		{
 			name: "Key",
-			pos:  position{line: 86, col: 1, offset: 2362},
+			pos:  position{line: 101, col: 1, offset: 2840},
 			expr: &actionExpr{
-				pos: position{line: 86, col: 18, offset: 2379},
+				pos: position{line: 101, col: 18, offset: 2857},
 				run: (*parser).callonKey1,
 				expr: &oneOrMoreExpr{
-					pos: position{line: 86, col: 18, offset: 2379},
+					pos: position{line: 101, col: 18, offset: 2857},
 					expr: &charClassMatcher{
-						pos:        position{line: 86, col: 18, offset: 2379},
+						pos:        position{line: 101, col: 18, offset: 2857},
 						val:        "[A-Za-z0-9_-]",
 						chars:      []rune{'_', '-'},
 						ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9'},
@@ -458,38 +530,50 @@ var g = &grammar{
 		},
 		{
 			name: "Value",
-			pos:  position{line: 87, col: 1, offset: 2426},
+			pos:  position{line: 102, col: 1, offset: 2904},
 			expr: &choiceExpr{
-				pos: position{line: 87, col: 18, offset: 2443},
-				alternatives: []interface{}{
+				pos: position{line: 102, col: 18, offset: 2921},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 87, col: 18, offset: 2443},
+						pos:  position{line: 102, col: 18, offset: 2921},
 						name: "Window",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 87, col: 27, offset: 2452},
+						pos:  position{line: 102, col: 27, offset: 2930},
+						name: "InList",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 102, col: 36, offset: 2939},
+						name: "CIValue",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 102, col: 46, offset: 2949},
 						name: "OpValue",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 87, col: 37, offset: 2462},
+						pos:  position{line: 102, col: 56, offset: 2959},
+						name: "DateMath",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 102, col: 67, offset: 2970},
 						name: "Phrase",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 87, col: 46, offset: 2471},
+						pos:  position{line: 102, col: 76, offset: 2979},
 						name: "DateTime",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 87, col: 57, offset: 2482},
+						pos:  position{line: 102, col: 87, offset: 2990},
 						name: "Number",
 					},
 					&actionExpr{
-						pos: position{line: 87, col: 66, offset: 2491},
-						run: (*parser).callonValue7,
+						pos: position{line: 102, col: 96, offset: 2999},
+						run: (*parser).callonValue10,
 						expr: &labeledExpr{
-							pos:   position{line: 87, col: 66, offset: 2491},
+							pos:   position{line: 102, col: 96, offset: 2999},
 							label: "w",
 							expr: &ruleRefExpr{
-								pos:  position{line: 87, col: 68, offset: 2493},
+								pos:  position{line: 102, col: 98, offset: 3001},
 								name: "Word",
 							},
 						},
@@ -499,25 +583,46 @@ var g = &grammar{
 		},
 		{
 			name: "OpValue",
-			pos:  position{line: 90, col: 1, offset: 2565},
+			pos:  position{line: 105, col: 1, offset: 3073},
 			expr: &choiceExpr{
-				pos: position{line: 90, col: 17, offset: 2581},
-				alternatives: []interface{}{
+				pos: position{line: 105, col: 17, offset: 3089},
+				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 90, col: 17, offset: 2581},
+						pos: position{line: 105, col: 17, offset: 3089},
 						run: (*parser).callonOpValue2,
 						expr: &seqExpr{
-							pos: position{line: 90, col: 17, offset: 2581},
-							exprs: []interface{}{
+							pos: position{line: 105, col: 17, offset: 3089},
+							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 90, col: 17, offset: 2581},
+									pos:  position{line: 105, col: 17, offset: 3089},
 									name: "FIELDOP",
 								},
 								&labeledExpr{
-									pos:   position{line: 90, col: 25, offset: 2589},
+									pos:   position{line: 105, col: 25, offset: 3097},
+									label: "m",
+									expr: &ruleRefExpr{
+										pos:  position{line: 105, col: 27, offset: 3099},
+										name: "DateMath",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 105, col: 54, offset: 3126},
+						run: (*parser).callonOpValue7,
+						expr: &seqExpr{
+							pos: position{line: 105, col: 54, offset: 3126},
+							exprs: []any{
+								&ruleRefExpr{
+									pos:  position{line: 105, col: 54, offset: 3126},
+									name: "FIELDOP",
+								},
+								&labeledExpr{
+									pos:   position{line: 105, col: 62, offset: 3134},
 									label: "d",
 									expr: &ruleRefExpr{
-										pos:  position{line: 90, col: 27, offset: 2591},
+										pos:  position{line: 105, col: 64, offset: 3136},
 										name: "DateTime",
 									},
 								},
@@ -525,20 +630,20 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 90, col: 54, offset: 2618},
-						run: (*parser).callonOpValue7,
+						pos: position{line: 105, col: 91, offset: 3163},
+						run: (*parser).callonOpValue12,
 						expr: &seqExpr{
-							pos: position{line: 90, col: 54, offset: 2618},
-							exprs: []interface{}{
+							pos: position{line: 105, col: 91, offset: 3163},
+							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 90, col: 54, offset: 2618},
+									pos:  position{line: 105, col: 91, offset: 3163},
 									name: "FIELDOP",
 								},
 								&labeledExpr{
-									pos:   position{line: 90, col: 62, offset: 2626},
+									pos:   position{line: 105, col: 99, offset: 3171},
 									label: "n",
 									expr: &ruleRefExpr{
-										pos:  position{line: 90, col: 64, offset: 2628},
+										pos:  position{line: 105, col: 101, offset: 3173},
 										name: "Number",
 									},
 								},
@@ -546,20 +651,20 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 90, col: 89, offset: 2653},
-						run: (*parser).callonOpValue12,
+						pos: position{line: 105, col: 126, offset: 3198},
+						run: (*parser).callonOpValue17,
 						expr: &seqExpr{
-							pos: position{line: 90, col: 89, offset: 2653},
-							exprs: []interface{}{
+							pos: position{line: 105, col: 126, offset: 3198},
+							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 90, col: 89, offset: 2653},
+									pos:  position{line: 105, col: 126, offset: 3198},
 									name: "FIELDOP",
 								},
 								&labeledExpr{
-									pos:   position{line: 90, col: 97, offset: 2661},
+									pos:   position{line: 105, col: 134, offset: 3206},
 									label: "p",
 									expr: &ruleRefExpr{
-										pos:  position{line: 90, col: 99, offset: 2663},
+										pos:  position{line: 105, col: 136, offset: 3208},
 										name: "Phrase",
 									},
 								},
@@ -567,20 +672,20 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 90, col: 125, offset: 2689},
-						run: (*parser).callonOpValue17,
+						pos: position{line: 105, col: 162, offset: 3234},
+						run: (*parser).callonOpValue22,
 						expr: &seqExpr{
-							pos: position{line: 90, col: 125, offset: 2689},
-							exprs: []interface{}{
+							pos: position{line: 105, col: 162, offset: 3234},
+							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 90, col: 125, offset: 2689},
+									pos:  position{line: 105, col: 162, offset: 3234},
 									name: "FIELDOP",
 								},
 								&labeledExpr{
-									pos:   position{line: 90, col: 133, offset: 2697},
+									pos:   position{line: 105, col: 170, offset: 3242},
 									label: "w",
 									expr: &ruleRefExpr{
-										pos:  position{line: 90, col: 135, offset: 2699},
+										pos:  position{line: 105, col: 172, offset: 3244},
 										name: "Word",
 									},
 								},
@@ -592,27 +697,27 @@ var g = &grammar{
 		},
 		{
 			name: "DateTime",
-			pos:  position{line: 91, col: 1, offset: 2722},
+			pos:  position{line: 106, col: 1, offset: 3267},
 			expr: &actionExpr{
-				pos: position{line: 91, col: 17, offset: 2738},
+				pos: position{line: 106, col: 17, offset: 3283},
 				run: (*parser).callonDateTime1,
 				expr: &seqExpr{
-					pos: position{line: 91, col: 17, offset: 2738},
-					exprs: []interface{}{
+					pos: position{line: 106, col: 17, offset: 3283},
+					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 91, col: 17, offset: 2738},
+							pos:  position{line: 106, col: 17, offset: 3283},
 							name: "Date",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 91, col: 22, offset: 2743},
+							pos:  position{line: 106, col: 22, offset: 3288},
 							name: "TEE",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 91, col: 26, offset: 2747},
+							pos:  position{line: 106, col: 26, offset: 3292},
 							name: "Time",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 91, col: 31, offset: 2752},
+							pos:  position{line: 106, col: 31, offset: 3297},
 							name: "ZEE",
 						},
 					},
@@ -620,209 +725,424 @@ var g = &grammar{
 			},
 		},
 		{
-			name: "Phrase",
-			pos:  position{line: 94, col: 1, offset: 2851},
+			name: "DateMath",
+			pos:  position{line: 109, col: 1, offset: 3410},
 			expr: &actionExpr{
-				pos: position{line: 94, col: 17, offset: 2867},
-				run: (*parser).callonPhrase1,
+				pos: position{line: 109, col: 17, offset: 3426},
+				run: (*parser).callonDateMath1,
 				expr: &seqExpr{
-					pos: position{line: 94, col: 17, offset: 2867},
-					exprs: []interface{}{
-						&ruleRefExpr{
-							pos:  position{line: 94, col: 17, offset: 2867},
-							name: "DQ",
+					pos: position{line: 109, col: 17, offset: 3426},
+					exprs: []any{
+						&litMatcher{
+							pos:        position{line: 109, col: 17, offset: 3426},
+							val:        "now",
+							ignoreCase: false,
+							want:       "\"now\"",
 						},
-						&oneOrMoreExpr{
-							pos: position{line: 94, col: 20, offset: 2870},
+						&zeroOrOneExpr{
+							pos: position{line: 109, col: 23, offset: 3432},
+							expr: &seqExpr{
+								pos: position{line: 109, col: 24, offset: 3433},
+								exprs: []any{
+									&choiceExpr{
+										pos: position{line: 109, col: 25, offset: 3434},
+										alternatives: []any{
+											&litMatcher{
+												pos:        position{line: 109, col: 25, offset: 3434},
+												val:        "+",
+												ignoreCase: false,
+												want:       "\"+\"",
+											},
+											&litMatcher{
+												pos:        position{line: 109, col: 31, offset: 3440},
+												val:        "-",
+												ignoreCase: false,
+												want:       "\"-\"",
+											},
+										},
+									},
+									&oneOrMoreExpr{
+										pos: position{line: 109, col: 36, offset: 3445},
+										expr: &ruleRefExpr{
+											pos:  position{line: 109, col: 36, offset: 3445},
+											name: "DIGIT",
+										},
+									},
+									&charClassMatcher{
+										pos:        position{line: 109, col: 43, offset: 3452},
+										val:        "[smhdwMy]",
+										chars:      []rune{'s', 'm', 'h', 'd', 'w', 'M', 'y'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+							},
+						},
+						&notExpr{
+							pos: position{line: 109, col: 55, offset: 3464},
 							expr: &charClassMatcher{
-								pos:        position{line: 94, col: 20, offset: 2870},
-								val:        "[^\"]",
-								chars:      []rune{'"'},
+								pos:        position{line: 109, col: 56, offset: 3465},
+								val:        "[a-zA-Z0-9_?\\\\*]",
+								chars:      []rune{'_', '?', '\\', '*'},
+								ranges:     []rune{'a', 'z', 'A', 'Z', '0', '9'},
 								ignoreCase: false,
-								inverted:   true,
+								inverted:   false,
 							},
 						},
+					},
+				},
+			},
+		},
+		{
+			name: "CIValue",
+			pos:  position{line: 112, col: 1, offset: 3574},
+			expr: &actionExpr{
+				pos: position{line: 112, col: 17, offset: 3590},
+				run: (*parser).callonCIValue1,
+				expr: &seqExpr{
+					pos: position{line: 112, col: 17, offset: 3590},
+					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 94, col: 26, offset: 2876},
-							name: "DQ",
+							pos:  position{line: 112, col: 17, offset: 3590},
+							name: "TILDA",
+						},
+						&labeledExpr{
+							pos:   position{line: 112, col: 23, offset: 3596},
+							label: "v",
+							expr: &choiceExpr{
+								pos: position{line: 112, col: 26, offset: 3599},
+								alternatives: []any{
+									&ruleRefExpr{
+										pos:  position{line: 112, col: 26, offset: 3599},
+										name: "Phrase",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 112, col: 35, offset: 3608},
+										name: "Word",
+									},
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 		{
-			name: "Window",
-			pos:  position{line: 97, col: 1, offset: 2989},
+			name: "InList",
+			pos:  position{line: 115, col: 1, offset: 3712},
 			expr: &actionExpr{
-				pos: position{line: 97, col: 17, offset: 3005},
-				run: (*parser).callonWindow1,
+				pos: position{line: 115, col: 17, offset: 3728},
+				run: (*parser).callonInList1,
 				expr: &seqExpr{
-					pos: position{line: 97, col: 17, offset: 3005},
-					exprs: []interface{}{
-						&ruleRefExpr{
-							pos:  position{line: 97, col: 17, offset: 3005},
-							name: "OPENBRACKET",
+					pos: position{line: 115, col: 17, offset: 3728},
+					exprs: []any{
+						&litMatcher{
+							pos:        position{line: 115, col: 17, offset: 3728},
+							val:        "IN",
+							ignoreCase: false,
+							want:       "\"IN\"",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 97, col: 29, offset: 3017},
+							pos: position{line: 115, col: 22, offset: 3733},
 							expr: &ruleRefExpr{
-								pos:  position{line: 97, col: 29, offset: 3017},
+								pos:  position{line: 115, col: 22, offset: 3733},
 								name: "_",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 97, col: 32, offset: 3020},
-							name: "WindowRange",
+							pos:  position{line: 115, col: 25, offset: 3736},
+							name: "OPENPAREN",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 115, col: 35, offset: 3746},
+							expr: &ruleRefExpr{
+								pos:  position{line: 115, col: 35, offset: 3746},
+								name: "_",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 115, col: 38, offset: 3749},
+							label: "f",
+							expr: &ruleRefExpr{
+								pos:  position{line: 115, col: 40, offset: 3751},
+								name: "ListValue",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 115, col: 50, offset: 3761},
+							label: "r",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 115, col: 52, offset: 3763},
+								expr: &seqExpr{
+									pos: position{line: 115, col: 53, offset: 3764},
+									exprs: []any{
+										&zeroOrOneExpr{
+											pos: position{line: 115, col: 53, offset: 3764},
+											expr: &ruleRefExpr{
+												pos:  position{line: 115, col: 53, offset: 3764},
+												name: "_",
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 115, col: 56, offset: 3767},
+											name: "COMMA",
+										},
+										&zeroOrOneExpr{
+											pos: position{line: 115, col: 62, offset: 3773},
+											expr: &ruleRefExpr{
+												pos:  position{line: 115, col: 62, offset: 3773},
+												name: "_",
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 115, col: 65, offset: 3776},
+											name: "ListValue",
+										},
+									},
+								},
+							},
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 97, col: 44, offset: 3032},
+							pos: position{line: 115, col: 77, offset: 3788},
 							expr: &ruleRefExpr{
-								pos:  position{line: 97, col: 44, offset: 3032},
+								pos:  position{line: 115, col: 77, offset: 3788},
 								name: "_",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 97, col: 47, offset: 3035},
-							name: "CLOSEBRACKET",
+							pos:  position{line: 115, col: 80, offset: 3791},
+							name: "CLOSEPAREN",
 						},
 					},
 				},
 			},
 		},
 		{
-			name: "WindowRange",
-			pos:  position{line: 98, col: 1, offset: 3081},
+			name: "ListValue",
+			pos:  position{line: 119, col: 1, offset: 3878},
 			expr: &choiceExpr{
-				pos: position{line: 98, col: 17, offset: 3097},
-				alternatives: []interface{}{
+				pos: position{line: 119, col: 17, offset: 3894},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 98, col: 17, offset: 3097},
-						name: "DateWindow",
+						pos:  position{line: 119, col: 17, offset: 3894},
+						name: "DateMath",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 98, col: 30, offset: 3110},
-						name: "NumberWindow",
+						pos:  position{line: 119, col: 28, offset: 3905},
+						name: "Phrase",
 					},
-				},
-			},
-		},
-		{
-			name: "DateWindow",
-			pos:  position{line: 99, col: 1, offset: 3124},
-			expr: &seqExpr{
-				pos: position{line: 99, col: 17, offset: 3140},
-				exprs: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 99, col: 17, offset: 3140},
-						name: "WinDateTime",
+						pos:  position{line: 119, col: 37, offset: 3914},
+						name: "DateTime",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 99, col: 29, offset: 3152},
-						name: "TILDA",
+						pos:  position{line: 119, col: 48, offset: 3925},
+						name: "Number",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 99, col: 35, offset: 3158},
-						name: "WinDateTime",
+						pos:  position{line: 119, col: 57, offset: 3934},
+						name: "Word",
 					},
 				},
 			},
 		},
 		{
-			name: "WinDateTime",
-			pos:  position{line: 100, col: 1, offset: 3171},
-			expr: &seqExpr{
-				pos: position{line: 100, col: 17, offset: 3187},
-				exprs: []interface{}{
-					&ruleRefExpr{
-						pos:  position{line: 100, col: 17, offset: 3187},
-						name: "Date",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 100, col: 22, offset: 3192},
-						name: "TEE",
-					},
-					&ruleRefExpr{
-						pos:  position{line: 100, col: 26, offset: 3196},
-						name: "Time",
+			name: "Phrase",
+			pos:  position{line: 122, col: 1, offset: 4002},
+			expr: &actionExpr{
+				pos: position{line: 122, col: 17, offset: 4018},
+				run: (*parser).callonPhrase1,
+				expr: &seqExpr{
+					pos: position{line: 122, col: 17, offset: 4018},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 122, col: 17, offset: 4018},
+							name: "DQ",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 122, col: 20, offset: 4021},
+							expr: &charClassMatcher{
+								pos:        position{line: 122, col: 20, offset: 4021},
+								val:        "[^\"]",
+								chars:      []rune{'"'},
+								ignoreCase: false,
+								inverted:   true,
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 122, col: 26, offset: 4027},
+							name: "DQ",
+						},
 					},
-					&ruleRefExpr{
-						pos:  position{line: 100, col: 31, offset: 3201},
-						name: "ZEE",
+				},
+			},
+		},
+		{
+			name: "Window",
+			pos:  position{line: 125, col: 1, offset: 4139},
+			expr: &actionExpr{
+				pos: position{line: 125, col: 17, offset: 4155},
+				run: (*parser).callonWindow1,
+				expr: &seqExpr{
+					pos: position{line: 125, col: 17, offset: 4155},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 125, col: 17, offset: 4155},
+							name: "OPENBRACKET",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 125, col: 29, offset: 4167},
+							expr: &ruleRefExpr{
+								pos:  position{line: 125, col: 29, offset: 4167},
+								name: "_",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 125, col: 32, offset: 4170},
+							label: "f",
+							expr: &ruleRefExpr{
+								pos:  position{line: 125, col: 34, offset: 4172},
+								name: "WinValue",
+							},
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 125, col: 43, offset: 4181},
+							expr: &ruleRefExpr{
+								pos:  position{line: 125, col: 43, offset: 4181},
+								name: "_",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 125, col: 46, offset: 4184},
+							name: "TILDA",
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 125, col: 52, offset: 4190},
+							expr: &ruleRefExpr{
+								pos:  position{line: 125, col: 52, offset: 4190},
+								name: "_",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 125, col: 55, offset: 4193},
+							label: "t",
+							expr: &ruleRefExpr{
+								pos:  position{line: 125, col: 57, offset: 4195},
+								name: "WinValue",
+							},
+						},
+						&zeroOrOneExpr{
+							pos: position{line: 125, col: 66, offset: 4204},
+							expr: &ruleRefExpr{
+								pos:  position{line: 125, col: 66, offset: 4204},
+								name: "_",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 125, col: 69, offset: 4207},
+							name: "CLOSEBRACKET",
+						},
 					},
 				},
 			},
 		},
 		{
-			name: "NumberWindow",
-			pos:  position{line: 101, col: 1, offset: 3206},
-			expr: &seqExpr{
-				pos: position{line: 101, col: 17, offset: 3222},
-				exprs: []interface{}{
+			name: "WinValue",
+			pos:  position{line: 129, col: 1, offset: 4305},
+			expr: &choiceExpr{
+				pos: position{line: 129, col: 17, offset: 4321},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 101, col: 17, offset: 3222},
-						name: "WinNumber",
+						pos:  position{line: 129, col: 17, offset: 4321},
+						name: "DateMath",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 101, col: 27, offset: 3232},
-						name: "TILDA",
-					},
-					&zeroOrOneExpr{
-						pos: position{line: 101, col: 33, offset: 3238},
-						expr: &ruleRefExpr{
-							pos:  position{line: 101, col: 33, offset: 3238},
-							name: "DASH",
-						},
+						pos:  position{line: 129, col: 28, offset: 4332},
+						name: "WinDateTime",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 101, col: 39, offset: 3244},
+						pos:  position{line: 129, col: 42, offset: 4346},
 						name: "WinNumber",
 					},
 				},
 			},
 		},
 		{
-			name: "WinNumber",
-			pos:  position{line: 102, col: 1, offset: 3255},
-			expr: &seqExpr{
-				pos: position{line: 102, col: 17, offset: 3271},
-				exprs: []interface{}{
-					&choiceExpr{
-						pos: position{line: 102, col: 18, offset: 3272},
-						alternatives: []interface{}{
-							&ruleRefExpr{
-								pos:  position{line: 102, col: 18, offset: 3272},
-								name: "DIGIT",
-							},
-							&ruleRefExpr{
-								pos:  position{line: 102, col: 26, offset: 3280},
-								name: "DOT",
-							},
-							&ruleRefExpr{
-								pos:  position{line: 102, col: 31, offset: 3285},
-								name: "DASH",
-							},
+			name: "WinDateTime",
+			pos:  position{line: 130, col: 1, offset: 4357},
+			expr: &actionExpr{
+				pos: position{line: 130, col: 17, offset: 4373},
+				run: (*parser).callonWinDateTime1,
+				expr: &seqExpr{
+					pos: position{line: 130, col: 17, offset: 4373},
+					exprs: []any{
+						&ruleRefExpr{
+							pos:  position{line: 130, col: 17, offset: 4373},
+							name: "Date",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 130, col: 22, offset: 4378},
+							name: "TEE",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 130, col: 26, offset: 4382},
+							name: "Time",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 130, col: 31, offset: 4387},
+							name: "ZEE",
 						},
 					},
-					&zeroOrMoreExpr{
-						pos: position{line: 102, col: 37, offset: 3291},
-						expr: &choiceExpr{
-							pos: position{line: 102, col: 38, offset: 3292},
-							alternatives: []interface{}{
+				},
+			},
+		},
+		{
+			name: "WinNumber",
+			pos:  position{line: 131, col: 1, offset: 4423},
+			expr: &actionExpr{
+				pos: position{line: 131, col: 17, offset: 4439},
+				run: (*parser).callonWinNumber1,
+				expr: &seqExpr{
+					pos: position{line: 131, col: 17, offset: 4439},
+					exprs: []any{
+						&choiceExpr{
+							pos: position{line: 131, col: 18, offset: 4440},
+							alternatives: []any{
 								&ruleRefExpr{
-									pos:  position{line: 102, col: 38, offset: 3292},
+									pos:  position{line: 131, col: 18, offset: 4440},
 									name: "DIGIT",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 102, col: 46, offset: 3300},
-									name: "DASH",
+									pos:  position{line: 131, col: 26, offset: 4448},
+									name: "DOT",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 102, col: 53, offset: 3307},
-									name: "EEE",
+									pos:  position{line: 131, col: 31, offset: 4453},
+									name: "DASH",
 								},
-								&ruleRefExpr{
-									pos:  position{line: 102, col: 59, offset: 3313},
-									name: "DOT",
+							},
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 131, col: 37, offset: 4459},
+							expr: &choiceExpr{
+								pos: position{line: 131, col: 38, offset: 4460},
+								alternatives: []any{
+									&ruleRefExpr{
+										pos:  position{line: 131, col: 38, offset: 4460},
+										name: "DIGIT",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 131, col: 46, offset: 4468},
+										name: "DASH",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 131, col: 53, offset: 4475},
+										name: "EEE",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 131, col: 59, offset: 4481},
+										name: "DOT",
+									},
 								},
 							},
 						},
@@ -832,28 +1152,28 @@ var g = &grammar{
 		},
 		{
 			name: "Date",
-			pos:  position{line: 106, col: 1, offset: 3343},
+			pos:  position{line: 135, col: 1, offset: 4561},
 			expr: &seqExpr{
-				pos: position{line: 106, col: 12, offset: 3354},
-				exprs: []interface{}{
+				pos: position{line: 135, col: 12, offset: 4572},
+				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 106, col: 12, offset: 3354},
+						pos:  position{line: 135, col: 12, offset: 4572},
 						name: "Digits4",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 106, col: 20, offset: 3362},
+						pos:  position{line: 135, col: 20, offset: 4580},
 						name: "DASH",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 106, col: 25, offset: 3367},
+						pos:  position{line: 135, col: 25, offset: 4585},
 						name: "Digits2",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 106, col: 33, offset: 3375},
+						pos:  position{line: 135, col: 33, offset: 4593},
 						name: "DASH",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 106, col: 38, offset: 3380},
+						pos:  position{line: 135, col: 38, offset: 4598},
 						name: "Digits2",
 					},
 				},
@@ -861,28 +1181,28 @@ var g = &grammar{
 		},
 		{
 			name: "Time",
-			pos:  position{line: 107, col: 1, offset: 3389},
+			pos:  position{line: 136, col: 1, offset: 4607},
 			expr: &seqExpr{
-				pos: position{line: 107, col: 12, offset: 3400},
-				exprs: []interface{}{
+				pos: position{line: 136, col: 12, offset: 4618},
+				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 107, col: 12, offset: 3400},
+						pos:  position{line: 136, col: 12, offset: 4618},
 						name: "Digits2",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 107, col: 20, offset: 3408},
+						pos:  position{line: 136, col: 20, offset: 4626},
 						name: "COLON",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 107, col: 26, offset: 3414},
+						pos:  position{line: 136, col: 26, offset: 4632},
 						name: "Digits2",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 107, col: 34, offset: 3422},
+						pos:  position{line: 136, col: 34, offset: 4640},
 						name: "COLON",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 107, col: 40, offset: 3428},
+						pos:  position{line: 136, col: 40, offset: 4646},
 						name: "Digits2",
 					},
 				},
@@ -890,15 +1210,15 @@ var g = &grammar{
 		},
 		{
 			name: "Word",
-			pos:  position{line: 108, col: 1, offset: 3437},
+			pos:  position{line: 137, col: 1, offset: 4655},
 			expr: &actionExpr{
-				pos: position{line: 108, col: 12, offset: 3448},
+				pos: position{line: 137, col: 12, offset: 4666},
 				run: (*parser).callonWord1,
 				expr: &seqExpr{
-					pos: position{line: 108, col: 12, offset: 3448},
-					exprs: []interface{}{
+					pos: position{line: 137, col: 12, offset: 4666},
+					exprs: []any{
 						&charClassMatcher{
-							pos:        position{line: 108, col: 12, offset: 3448},
+							pos:        position{line: 137, col: 12, offset: 4666},
 							val:        "[a-zA-Z_?\\\\*]",
 							chars:      []rune{'_', '?', '\\', '*'},
 							ranges:     []rune{'a', 'z', 'A', 'Z'},
@@ -906,9 +1226,9 @@ var g = &grammar{
 							inverted:   false,
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 108, col: 26, offset: 3462},
+							pos: position{line: 137, col: 26, offset: 4680},
 							expr: &charClassMatcher{
-								pos:        position{line: 108, col: 26, offset: 3462},
+								pos:        position{line: 137, col: 26, offset: 4680},
 								val:        "[a-zA-Z0-9_?\\\\*]",
 								chars:      []rune{'_', '?', '\\', '*'},
 								ranges:     []rune{'a', 'z', 'A', 'Z', '0', '9'},
@@ -922,49 +1242,49 @@ var g = &grammar{
 		},
 		{
 			name: "Number",
-			pos:  position{line: 109, col: 1, offset: 3536},
+			pos:  position{line: 138, col: 1, offset: 4754},
 			expr: &actionExpr{
-				pos: position{line: 109, col: 12, offset: 3547},
+				pos: position{line: 138, col: 12, offset: 4765},
 				run: (*parser).callonNumber1,
 				expr: &seqExpr{
-					pos: position{line: 109, col: 12, offset: 3547},
-					exprs: []interface{}{
+					pos: position{line: 138, col: 12, offset: 4765},
+					exprs: []any{
 						&choiceExpr{
-							pos: position{line: 109, col: 13, offset: 3548},
-							alternatives: []interface{}{
+							pos: position{line: 138, col: 13, offset: 4766},
+							alternatives: []any{
 								&ruleRefExpr{
-									pos:  position{line: 109, col: 13, offset: 3548},
+									pos:  position{line: 138, col: 13, offset: 4766},
 									name: "DIGIT",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 109, col: 21, offset: 3556},
+									pos:  position{line: 138, col: 21, offset: 4774},
 									name: "DOT",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 109, col: 26, offset: 3561},
+									pos:  position{line: 138, col: 26, offset: 4779},
 									name: "DASH",
 								},
 							},
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 109, col: 32, offset: 3567},
+							pos: position{line: 138, col: 32, offset: 4785},
 							expr: &choiceExpr{
-								pos: position{line: 109, col: 33, offset: 3568},
-								alternatives: []interface{}{
+								pos: position{line: 138, col: 33, offset: 4786},
+								alternatives: []any{
 									&ruleRefExpr{
-										pos:  position{line: 109, col: 33, offset: 3568},
+										pos:  position{line: 138, col: 33, offset: 4786},
 										name: "DIGIT",
 									},
 									&ruleRefExpr{
-										pos:  position{line: 109, col: 41, offset: 3576},
+										pos:  position{line: 138, col: 41, offset: 4794},
 										name: "DASH",
 									},
 									&ruleRefExpr{
-										pos:  position{line: 109, col: 48, offset: 3583},
+										pos:  position{line: 138, col: 48, offset: 4801},
 										name: "EEE",
 									},
 									&ruleRefExpr{
-										pos:  position{line: 109, col: 54, offset: 3589},
+										pos:  position{line: 138, col: 54, offset: 4807},
 										name: "DOT",
 									},
 								},
@@ -976,16 +1296,16 @@ var g = &grammar{
 		},
 		{
 			name: "Digits2",
-			pos:  position{line: 110, col: 1, offset: 3646},
+			pos:  position{line: 139, col: 1, offset: 4864},
 			expr: &seqExpr{
-				pos: position{line: 110, col: 12, offset: 3657},
-				exprs: []interface{}{
+				pos: position{line: 139, col: 12, offset: 4875},
+				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 110, col: 12, offset: 3657},
+						pos:  position{line: 139, col: 12, offset: 4875},
 						name: "DIGIT",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 110, col: 18, offset: 3663},
+						pos:  position{line: 139, col: 18, offset: 4881},
 						name: "DIGIT",
 					},
 				},
@@ -993,16 +1313,16 @@ var g = &grammar{
 		},
 		{
 			name: "Digits4",
-			pos:  position{line: 111, col: 1, offset: 3670},
+			pos:  position{line: 140, col: 1, offset: 4888},
 			expr: &seqExpr{
-				pos: position{line: 111, col: 12, offset: 3681},
-				exprs: []interface{}{
+				pos: position{line: 140, col: 12, offset: 4899},
+				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 111, col: 12, offset: 3681},
+						pos:  position{line: 140, col: 12, offset: 4899},
 						name: "Digits2",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 111, col: 20, offset: 3689},
+						pos:  position{line: 140, col: 20, offset: 4907},
 						name: "Digits2",
 					},
 				},
@@ -1010,9 +1330,9 @@ var g = &grammar{
 		},
 		{
 			name: "OPENPAREN",
-			pos:  position{line: 113, col: 1, offset: 3700},
+			pos:  position{line: 142, col: 1, offset: 4918},
 			expr: &litMatcher{
-				pos:        position{line: 113, col: 17, offset: 3716},
+				pos:        position{line: 142, col: 17, offset: 4934},
 				val:        "(",
 				ignoreCase: false,
 				want:       "\"(\"",
@@ -1020,9 +1340,9 @@ var g = &grammar{
 		},
 		{
 			name: "CLOSEPAREN",
-			pos:  position{line: 114, col: 1, offset: 3721},
+			pos:  position{line: 143, col: 1, offset: 4939},
 			expr: &litMatcher{
-				pos:        position{line: 114, col: 17, offset: 3737},
+				pos:        position{line: 143, col: 17, offset: 4955},
 				val:        ")",
 				ignoreCase: false,
 				want:       "\")\"",
@@ -1030,9 +1350,9 @@ var g = &grammar{
 		},
 		{
 			name: "OPENBRACKET",
-			pos:  position{line: 115, col: 1, offset: 3742},
+			pos:  position{line: 144, col: 1, offset: 4960},
 			expr: &litMatcher{
-				pos:        position{line: 115, col: 17, offset: 3758},
+				pos:        position{line: 144, col: 17, offset: 4976},
 				val:        "[",
 				ignoreCase: false,
 				want:       "\"[\"",
@@ -1040,9 +1360,9 @@ var g = &grammar{
 		},
 		{
 			name: "CLOSEBRACKET",
-			pos:  position{line: 116, col: 1, offset: 3763},
+			pos:  position{line: 145, col: 1, offset: 4981},
 			expr: &litMatcher{
-				pos:        position{line: 116, col: 17, offset: 3779},
+				pos:        position{line: 145, col: 17, offset: 4997},
 				val:        "]",
 				ignoreCase: false,
 				want:       "\"]\"",
@@ -1050,9 +1370,9 @@ var g = &grammar{
 		},
 		{
 			name: "DIGIT",
-			pos:  position{line: 118, col: 1, offset: 3786},
+			pos:  position{line: 147, col: 1, offset: 5004},
 			expr: &charClassMatcher{
-				pos:        position{line: 118, col: 12, offset: 3797},
+				pos:        position{line: 147, col: 12, offset: 5015},
 				val:        "[0-9]",
 				ranges:     []rune{'0', '9'},
 				ignoreCase: false,
@@ -1061,9 +1381,9 @@ var g = &grammar{
 		},
 		{
 			name: "DASH",
-			pos:  position{line: 119, col: 1, offset: 3804},
+			pos:  position{line: 148, col: 1, offset: 5022},
 			expr: &litMatcher{
-				pos:        position{line: 119, col: 12, offset: 3815},
+				pos:        position{line: 148, col: 12, offset: 5033},
 				val:        "-",
 				ignoreCase: false,
 				want:       "\"-\"",
@@ -1071,19 +1391,29 @@ var g = &grammar{
 		},
 		{
 			name: "COLON",
-			pos:  position{line: 120, col: 1, offset: 3820},
+			pos:  position{line: 149, col: 1, offset: 5038},
 			expr: &litMatcher{
-				pos:        position{line: 120, col: 12, offset: 3831},
+				pos:        position{line: 149, col: 12, offset: 5049},
 				val:        ":",
 				ignoreCase: false,
 				want:       "\":\"",
 			},
 		},
+		{
+			name: "COMMA",
+			pos:  position{line: 150, col: 1, offset: 5054},
+			expr: &litMatcher{
+				pos:        position{line: 150, col: 12, offset: 5065},
+				val:        ",",
+				ignoreCase: false,
+				want:       "\",\"",
+			},
+		},
 		{
 			name: "TILDA",
-			pos:  position{line: 121, col: 1, offset: 3836},
+			pos:  position{line: 151, col: 1, offset: 5070},
 			expr: &litMatcher{
-				pos:        position{line: 121, col: 12, offset: 3847},
+				pos:        position{line: 151, col: 12, offset: 5081},
 				val:        "~",
 				ignoreCase: false,
 				want:       "\"~\"",
@@ -1091,9 +1421,9 @@ var g = &grammar{
 		},
 		{
 			name: "DQ",
-			pos:  position{line: 122, col: 1, offset: 3852},
+			pos:  position{line: 152, col: 1, offset: 5086},
 			expr: &litMatcher{
-				pos:        position{line: 122, col: 12, offset: 3863},
+				pos:        position{line: 152, col: 12, offset: 5097},
 				val:        "\"",
 				ignoreCase: false,
 				want:       "\"\\\"\"",
@@ -1101,9 +1431,9 @@ var g = &grammar{
 		},
 		{
 			name: "TEE",
-			pos:  position{line: 123, col: 1, offset: 3868},
+			pos:  position{line: 153, col: 1, offset: 5102},
 			expr: &litMatcher{
-				pos:        position{line: 123, col: 12, offset: 3879},
+				pos:        position{line: 153, col: 12, offset: 5113},
 				val:        "T",
 				ignoreCase: false,
 				want:       "\"T\"",
@@ -1111,9 +1441,9 @@ var g = &grammar{
 		},
 		{
 			name: "ZEE",
-			pos:  position{line: 124, col: 1, offset: 3884},
+			pos:  position{line: 154, col: 1, offset: 5118},
 			expr: &litMatcher{
-				pos:        position{line: 124, col: 12, offset: 3895},
+				pos:        position{line: 154, col: 12, offset: 5129},
 				val:        "Z",
 				ignoreCase: false,
 				want:       "\"Z\"",
@@ -1121,9 +1451,9 @@ var g = &grammar{
 		},
 		{
 			name: "EEE",
-			pos:  position{line: 125, col: 1, offset: 3900},
+			pos:  position{line: 155, col: 1, offset: 5134},
 			expr: &charClassMatcher{
-				pos:        position{line: 125, col: 12, offset: 3911},
+				pos:        position{line: 155, col: 12, offset: 5145},
 				val:        "[eE]",
 				chars:      []rune{'e', 'E'},
 				ignoreCase: false,
@@ -1132,9 +1462,9 @@ var g = &grammar{
 		},
 		{
 			name: "DOT",
-			pos:  position{line: 126, col: 1, offset: 3917},
+			pos:  position{line: 156, col: 1, offset: 5151},
 			expr: &litMatcher{
-				pos:        position{line: 126, col: 12, offset: 3928},
+				pos:        position{line: 156, col: 12, offset: 5162},
 				val:        ".",
 				ignoreCase: false,
 				want:       "\".\"",
@@ -1142,18 +1472,18 @@ var g = &grammar{
 		},
 		{
 			name: "NOT",
-			pos:  position{line: 128, col: 1, offset: 3935},
+			pos:  position{line: 158, col: 1, offset: 5169},
 			expr: &choiceExpr{
-				pos: position{line: 128, col: 12, offset: 3946},
-				alternatives: []interface{}{
+				pos: position{line: 158, col: 12, offset: 5180},
+				alternatives: []any{
 					&litMatcher{
-						pos:        position{line: 128, col: 12, offset: 3946},
+						pos:        position{line: 158, col: 12, offset: 5180},
 						val:        "NOT",
 						ignoreCase: false,
 						want:       "\"NOT\"",
 					},
 					&litMatcher{
-						pos:        position{line: 128, col: 20, offset: 3954},
+						pos:        position{line: 158, col: 20, offset: 5188},
 						val:        "!",
 						ignoreCase: false,
 						want:       "\"!\"",
@@ -1163,18 +1493,18 @@ var g = &grammar{
 		},
 		{
 			name: "AND",
-			pos:  position{line: 130, col: 1, offset: 3961},
+			pos:  position{line: 160, col: 1, offset: 5195},
 			expr: &choiceExpr{
-				pos: position{line: 130, col: 12, offset: 3972},
-				alternatives: []interface{}{
+				pos: position{line: 160, col: 12, offset: 5206},
+				alternatives: []any{
 					&litMatcher{
-						pos:        position{line: 130, col: 12, offset: 3972},
+						pos:        position{line: 160, col: 12, offset: 5206},
 						val:        "AND",
 						ignoreCase: false,
 						want:       "\"AND\"",
 					},
 					&litMatcher{
-						pos:        position{line: 130, col: 20, offset: 3980},
+						pos:        position{line: 160, col: 20, offset: 5214},
 						val:        "&",
 						ignoreCase: false,
 						want:       "\"&\"",
@@ -1184,18 +1514,18 @@ var g = &grammar{
 		},
 		{
 			name: "OR",
-			pos:  position{line: 131, col: 1, offset: 3985},
+			pos:  position{line: 161, col: 1, offset: 5219},
 			expr: &choiceExpr{
-				pos: position{line: 131, col: 12, offset: 3996},
-				alternatives: []interface{}{
+				pos: position{line: 161, col: 12, offset: 5230},
+				alternatives: []any{
 					&litMatcher{
-						pos:        position{line: 131, col: 12, offset: 3996},
+						pos:        position{line: 161, col: 12, offset: 5230},
 						val:        "OR",
 						ignoreCase: false,
 						want:       "\"OR\"",
 					},
 					&litMatcher{
-						pos:        position{line: 131, col: 19, offset: 4003},
+						pos:        position{line: 161, col: 19, offset: 5237},
 						val:        "|",
 						ignoreCase: false,
 						want:       "\"|\"",
@@ -1205,32 +1535,32 @@ var g = &grammar{
 		},
 		{
 			name: "FIELDOP",
-			pos:  position{line: 133, col: 1, offset: 4010},
+			pos:  position{line: 163, col: 1, offset: 5244},
 			expr: &choiceExpr{
-				pos: position{line: 133, col: 12, offset: 4021},
-				alternatives: []interface{}{
+				pos: position{line: 163, col: 12, offset: 5255},
+				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 133, col: 12, offset: 4021},
+						pos:  position{line: 163, col: 12, offset: 5255},
 						name: "GTE",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 133, col: 18, offset: 4027},
+						pos:  position{line: 163, col: 18, offset: 5261},
 						name: "LTE",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 133, col: 24, offset: 4033},
+						pos:  position{line: 163, col: 24, offset: 5267},
 						name: "GT",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 133, col: 29, offset: 4038},
+						pos:  position{line: 163, col: 29, offset: 5272},
 						name: "LT",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 133, col: 34, offset: 4043},
+						pos:  position{line: 163, col: 34, offset: 5277},
 						name: "NE",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 133, col: 39, offset: 4048},
+						pos:  position{line: 163, col: 39, offset: 5282},
 						name: "EQ",
 					},
 				},
@@ -1238,12 +1568,12 @@ var g = &grammar{
 		},
 		{
 			name: "GTE",
-			pos:  position{line: 134, col: 1, offset: 4052},
+			pos:  position{line: 164, col: 1, offset: 5286},
 			expr: &actionExpr{
-				pos: position{line: 134, col: 12, offset: 4063},
+				pos: position{line: 164, col: 12, offset: 5297},
 				run: (*parser).callonGTE1,
 				expr: &litMatcher{
-					pos:        position{line: 134, col: 12, offset: 4063},
+					pos:        position{line: 164, col: 12, offset: 5297},
 					val:        ">=",
 					ignoreCase: false,
 					want:       "\">=\"",
@@ -1252,12 +1582,12 @@ var g = &grammar{
 		},
 		{
 			name: "LTE",
-			pos:  position{line: 135, col: 1, offset: 4142},
+			pos:  position{line: 165, col: 1, offset: 5376},
 			expr: &actionExpr{
-				pos: position{line: 135, col: 12, offset: 4153},
+				pos: position{line: 165, col: 12, offset: 5387},
 				run: (*parser).callonLTE1,
 				expr: &litMatcher{
-					pos:        position{line: 135, col: 12, offset: 4153},
+					pos:        position{line: 165, col: 12, offset: 5387},
 					val:        "<=",
 					ignoreCase: false,
 					want:       "\"<=\"",
@@ -1266,12 +1596,12 @@ var g = &grammar{
 		},
 		{
 			name: "GT",
-			pos:  position{line: 136, col: 1, offset: 4232},
+			pos:  position{line: 166, col: 1, offset: 5466},
 			expr: &actionExpr{
-				pos: position{line: 136, col: 12, offset: 4243},
+				pos: position{line: 166, col: 12, offset: 5477},
 				run: (*parser).callonGT1,
 				expr: &litMatcher{
-					pos:        position{line: 136, col: 12, offset: 4243},
+					pos:        position{line: 166, col: 12, offset: 5477},
 					val:        ">",
 					ignoreCase: false,
 					want:       "\">\"",
@@ -1280,12 +1610,12 @@ var g = &grammar{
 		},
 		{
 			name: "LT",
-			pos:  position{line: 137, col: 1, offset: 4322},
+			pos:  position{line: 167, col: 1, offset: 5556},
 			expr: &actionExpr{
-				pos: position{line: 137, col: 12, offset: 4333},
+				pos: position{line: 167, col: 12, offset: 5567},
 				run: (*parser).callonLT1,
 				expr: &litMatcher{
-					pos:        position{line: 137, col: 12, offset: 4333},
+					pos:        position{line: 167, col: 12, offset: 5567},
 					val:        "<",
 					ignoreCase: false,
 					want:       "\"<\"",
@@ -1294,12 +1624,12 @@ var g = &grammar{
 		},
 		{
 			name: "NE",
-			pos:  position{line: 138, col: 1, offset: 4412},
+			pos:  position{line: 168, col: 1, offset: 5646},
 			expr: &actionExpr{
-				pos: position{line: 138, col: 12, offset: 4423},
+				pos: position{line: 168, col: 12, offset: 5657},
 				run: (*parser).callonNE1,
 				expr: &litMatcher{
-					pos:        position{line: 138, col: 12, offset: 4423},
+					pos:        position{line: 168, col: 12, offset: 5657},
 					val:        "!=",
 					ignoreCase: false,
 					want:       "\"!=\"",
@@ -1308,12 +1638,12 @@ var g = &grammar{
 		},
 		{
 			name: "EQ",
-			pos:  position{line: 139, col: 1, offset: 4502},
+			pos:  position{line: 169, col: 1, offset: 5736},
 			expr: &actionExpr{
-				pos: position{line: 139, col: 12, offset: 4513},
+				pos: position{line: 169, col: 12, offset: 5747},
 				run: (*parser).callonEQ1,
 				expr: &litMatcher{
-					pos:        position{line: 139, col: 12, offset: 4513},
+					pos:        position{line: 169, col: 12, offset: 5747},
 					val:        "=",
 					ignoreCase: false,
 					want:       "\"=\"",
@@ -1323,11 +1653,11 @@ var g = &grammar{
 		{
 			name:        "_",
 			displayName: "\"whitespace\"",
-			pos:         position{line: 141, col: 1, offset: 4594},
+			pos:         position{line: 171, col: 1, offset: 5828},
 			expr: &oneOrMoreExpr{
-				pos: position{line: 141, col: 19, offset: 4612},
+				pos: position{line: 171, col: 19, offset: 5846},
 				expr: &charClassMatcher{
-					pos:        position{line: 141, col: 19, offset: 4612},
+					pos:        position{line: 171, col: 19, offset: 5846},
 					val:        "[ \\n\\t\\r]",
 					chars:      []rune{' ', '\n', '\t', '\r'},
 					ignoreCase: false,
@@ -1338,18 +1668,18 @@ var g = &grammar{
 	},
 }
 
-func (c *current) onInput1() (interface{}, error) {
+func (c *current) onInput1() (any, error) {
 
 	return N.Query(), nil
 }
 
-func (p *parser) callonInput1() (interface{}, error) {
+func (p *parser) callonInput1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onInput1()
 }
 
-func (c *current) onOperator2() (interface{}, error) {
+func (c *current) onOperator2() (any, error) {
 	N.NewCondition()
 	if N.CurrentNode().Operator == ANDOP {
 		return nil, errors.New("you cant mix AND and OR operator")
@@ -1358,13 +1688,13 @@ func (c *current) onOperator2() (interface{}, error) {
 	return N.CurrentNode(), nil
 }
 
-func (p *parser) callonOperator2() (interface{}, error) {
+func (p *parser) callonOperator2() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onOperator2()
 }
 
-func (c *current) onOperator4() (interface{}, error) {
+func (c *current) onOperator4() (any, error) {
 	N.NewCondition()
 	if N.CurrentNode().Operator == OROP {
 		return nil, errors.New("you cant mix AND and OR operator")
@@ -1373,46 +1703,55 @@ func (c *current) onOperator4() (interface{}, error) {
 	return N.CurrentNode(), nil
 }
 
-func (p *parser) callonOperator4() (interface{}, error) {
+func (p *parser) callonOperator4() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onOperator4()
 }
 
-func (c *current) onNotCheck1() (interface{}, error) {
+func (c *current) onNotCheck1() (any, error) {
 
 	N.CurrentCondition().Invert = true
 	return nil, nil
 }
 
-func (p *parser) callonNotCheck1() (interface{}, error) {
+func (p *parser) callonNotCheck1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onNotCheck1()
 }
 
-func (c *current) onGroupStart1() (interface{}, error) {
-	n := N.NewNode()
-	return n, nil
+func (c *current) onGroupStart1() (any, error) {
+	return N.StartGroup(false), nil
 }
 
-func (p *parser) callonGroupStart1() (interface{}, error) {
+func (p *parser) callonGroupStart1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onGroupStart1()
 }
 
-func (c *current) onNotGroupStart1() (interface{}, error) {
-	return N.CurrentNode(), nil
+func (c *current) onNotGroupStart1() (any, error) {
+	return N.StartGroup(true), nil
 }
 
-func (p *parser) callonNotGroupStart1() (interface{}, error) {
+func (p *parser) callonNotGroupStart1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onNotGroupStart1()
 }
 
-func (c *current) onKeyValue1(k, v interface{}) (interface{}, error) {
+func (c *current) onGroupSuffix1() (any, error) {
+	return N.EndGroup(), nil
+}
+
+func (p *parser) callonGroupSuffix1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onGroupSuffix1()
+}
+
+func (c *current) onKeyValue1(k, v any) (any, error) {
 
 	cd := N.CurrentCondition()
 	cd.Field = k.(string)
@@ -1420,183 +1759,262 @@ func (c *current) onKeyValue1(k, v interface{}) (interface{}, error) {
 	return cd, nil
 }
 
-func (p *parser) callonKeyValue1() (interface{}, error) {
+func (p *parser) callonKeyValue1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onKeyValue1(stack["k"], stack["v"])
 }
 
-func (c *current) onKey1() (interface{}, error) {
+func (c *current) onExists1(k any) (any, error) {
+
+	cd := N.CurrentCondition()
+	cd.Field = k.(string)
+	cd.Operator = EX
+	return cd, nil
+}
+
+func (p *parser) callonExists1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExists1(stack["k"])
+}
+
+func (c *current) onKey1() (any, error) {
 	return string(c.text), nil
 }
 
-func (p *parser) callonKey1() (interface{}, error) {
+func (p *parser) callonKey1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onKey1()
 }
 
-func (c *current) onValue7(w interface{}) (interface{}, error) {
+func (c *current) onValue10(w any) (any, error) {
 	return w, nil
 }
 
-func (p *parser) callonValue7() (interface{}, error) {
+func (p *parser) callonValue10() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onValue7(stack["w"])
+	return p.cur.onValue10(stack["w"])
 }
 
-func (c *current) onOpValue2(d interface{}) (interface{}, error) {
+func (c *current) onOpValue2(m any) (any, error) {
+	return m, nil
+}
+
+func (p *parser) callonOpValue2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onOpValue2(stack["m"])
+}
+
+func (c *current) onOpValue7(d any) (any, error) {
 	return d, nil
 }
 
-func (p *parser) callonOpValue2() (interface{}, error) {
+func (p *parser) callonOpValue7() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onOpValue2(stack["d"])
+	return p.cur.onOpValue7(stack["d"])
 }
 
-func (c *current) onOpValue7(n interface{}) (interface{}, error) {
+func (c *current) onOpValue12(n any) (any, error) {
 	return n, nil
 }
 
-func (p *parser) callonOpValue7() (interface{}, error) {
+func (p *parser) callonOpValue12() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onOpValue7(stack["n"])
+	return p.cur.onOpValue12(stack["n"])
 }
 
-func (c *current) onOpValue12(p interface{}) (interface{}, error) {
+func (c *current) onOpValue17(p any) (any, error) {
 	return p, nil
 }
 
-func (p *parser) callonOpValue12() (interface{}, error) {
+func (p *parser) callonOpValue17() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onOpValue12(stack["p"])
+	return p.cur.onOpValue17(stack["p"])
 }
 
-func (c *current) onOpValue17(w interface{}) (interface{}, error) {
+func (c *current) onOpValue22(w any) (any, error) {
 	return w, nil
 }
 
-func (p *parser) callonOpValue17() (interface{}, error) {
+func (p *parser) callonOpValue22() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onOpValue17(stack["w"])
+	return p.cur.onOpValue22(stack["w"])
 }
 
-func (c *current) onDateTime1() (interface{}, error) {
+func (c *current) onDateTime1() (any, error) {
 	return string(c.text), nil
 }
 
-func (p *parser) callonDateTime1() (interface{}, error) {
+func (p *parser) callonDateTime1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onDateTime1()
 }
 
-func (c *current) onPhrase1() (interface{}, error) {
+func (c *current) onDateMath1() (any, error) {
+	return ParseDateMath(string(c.text))
+}
+
+func (p *parser) callonDateMath1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDateMath1()
+}
+
+func (c *current) onCIValue1(v any) (any, error) {
+	N.CurrentCondition().Operator = CI
+	return v, nil
+}
+
+func (p *parser) callonCIValue1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCIValue1(stack["v"])
+}
+
+func (c *current) onInList1(f, r any) (any, error) {
+
+	N.CurrentCondition().Operator = IN
+	return toList(f, r), nil
+}
+
+func (p *parser) callonInList1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onInList1(stack["f"], stack["r"])
+}
+
+func (c *current) onPhrase1() (any, error) {
 	return string(c.text), nil
 }
 
-func (p *parser) callonPhrase1() (interface{}, error) {
+func (p *parser) callonPhrase1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onPhrase1()
 }
 
-func (c *current) onWindow1() (interface{}, error) {
+func (c *current) onWindow1(f, t any) (any, error) {
+
+	N.CurrentCondition().Operator = BT
+	return Range{From: f, To: t}, nil
+}
+
+func (p *parser) callonWindow1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onWindow1(stack["f"], stack["t"])
+}
+
+func (c *current) onWinDateTime1() (any, error) {
 	return string(c.text), nil
 }
 
-func (p *parser) callonWindow1() (interface{}, error) {
+func (p *parser) callonWinDateTime1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onWindow1()
+	return p.cur.onWinDateTime1()
 }
 
-func (c *current) onWord1() (interface{}, error) {
+func (c *current) onWinNumber1() (any, error) {
+	return strconv.ParseFloat(string(c.text), 64)
+}
+
+func (p *parser) callonWinNumber1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onWinNumber1()
+}
+
+func (c *current) onWord1() (any, error) {
 	return string(c.text), nil
 }
 
-func (p *parser) callonWord1() (interface{}, error) {
+func (p *parser) callonWord1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onWord1()
 }
 
-func (c *current) onNumber1() (interface{}, error) {
+func (c *current) onNumber1() (any, error) {
 	return strconv.ParseFloat(string(c.text), 64)
 }
 
-func (p *parser) callonNumber1() (interface{}, error) {
+func (p *parser) callonNumber1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onNumber1()
 }
 
-func (c *current) onGTE1() (interface{}, error) {
+func (c *current) onGTE1() (any, error) {
 	N.CurrentCondition().Operator = GE
 	return N.CurrentCondition(), nil
 }
 
-func (p *parser) callonGTE1() (interface{}, error) {
+func (p *parser) callonGTE1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onGTE1()
 }
 
-func (c *current) onLTE1() (interface{}, error) {
+func (c *current) onLTE1() (any, error) {
 	N.CurrentCondition().Operator = LE
 	return N.CurrentCondition(), nil
 }
 
-func (p *parser) callonLTE1() (interface{}, error) {
+func (p *parser) callonLTE1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onLTE1()
 }
 
-func (c *current) onGT1() (interface{}, error) {
+func (c *current) onGT1() (any, error) {
 	N.CurrentCondition().Operator = GT
 	return N.CurrentCondition(), nil
 }
 
-func (p *parser) callonGT1() (interface{}, error) {
+func (p *parser) callonGT1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onGT1()
 }
 
-func (c *current) onLT1() (interface{}, error) {
+func (c *current) onLT1() (any, error) {
 	N.CurrentCondition().Operator = LT
 	return N.CurrentCondition(), nil
 }
 
-func (p *parser) callonLT1() (interface{}, error) {
+func (p *parser) callonLT1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onLT1()
 }
 
-func (c *current) onNE1() (interface{}, error) {
+func (c *current) onNE1() (any, error) {
 	N.CurrentCondition().Operator = NE
 	return N.CurrentCondition(), nil
 }
 
-func (p *parser) callonNE1() (interface{}, error) {
+func (p *parser) callonNE1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onNE1()
 }
 
-func (c *current) onEQ1() (interface{}, error) {
+func (c *current) onEQ1() (any, error) {
 	N.CurrentCondition().Operator = EQ
 	return N.CurrentCondition(), nil
 }
 
-func (p *parser) callonEQ1() (interface{}, error) {
+func (p *parser) callonEQ1() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
 	return p.cur.onEQ1()
@@ -1660,18 +2078,17 @@ func Entrypoint(ruleName string) Option {
 //
 // Example usage:
 //
-//     input := "input"
-//     stats := Stats{}
-//     _, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     fmt.Println(string(b))
-//
+//	input := "input"
+//	stats := Stats{}
+//	_, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	fmt.Println(string(b))
 func Statistics(stats *Stats, choiceNoMatch string) Option {
 	return func(p *parser) Option {
 		oldStats := p.Stats
@@ -1741,7 +2158,7 @@ func Recover(b bool) Option {
 
 // GlobalStore creates an Option to set a key to a certain value in
 // the globalStore.
-func GlobalStore(key string, value interface{}) Option {
+func GlobalStore(key string, value any) Option {
 	return func(p *parser) Option {
 		old := p.cur.globalStore[key]
 		p.cur.globalStore[key] = value
@@ -1751,7 +2168,7 @@ func GlobalStore(key string, value interface{}) Option {
 
 // InitState creates an Option to set a key to a certain value in
 // the global "state" store.
-func InitState(key string, value interface{}) Option {
+func InitState(key string, value any) Option {
 	return func(p *parser) Option {
 		old := p.cur.state[key]
 		p.cur.state[key] = value
@@ -1760,7 +2177,7 @@ func InitState(key string, value interface{}) Option {
 }
 
 // ParseFile parses the file identified by filename.
-func ParseFile(filename string, opts ...Option) (i interface{}, err error) {
+func ParseFile(filename string, opts ...Option) (i any, err error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -1775,7 +2192,7 @@ func ParseFile(filename string, opts ...Option) (i interface{}, err error) {
 
 // ParseReader parses the data from r using filename as information in the
 // error messages.
-func ParseReader(filename string, r io.Reader, opts ...Option) (interface{}, error) {
+func ParseReader(filename string, r io.Reader, opts ...Option) (any, error) {
 	b, err := io.ReadAll(r)
 	if err != nil {
 		return nil, err
@@ -1786,7 +2203,7 @@ func ParseReader(filename string, r io.Reader, opts ...Option) (interface{}, err
 
 // Parse parses the data from b using filename as information in the
 // error messages.
-func Parse(filename string, b []byte, opts ...Option) (interface{}, error) {
+func Parse(filename string, b []byte, opts ...Option) (any, error) {
 	return newParser(filename, b, opts...).parse(g)
 }
 
@@ -1824,7 +2241,7 @@ type current struct {
 	globalStore storeDict
 }
 
-type storeDict map[string]interface{}
+type storeDict map[string]any
 
 // the AST types...
 
@@ -1837,30 +2254,30 @@ type rule struct {
 	pos         position
 	name        string
 	displayName string
-	expr        interface{}
+	expr        any
 }
 
 type choiceExpr struct {
 	pos          position
-	alternatives []interface{}
+	alternatives []any
 }
 
 type actionExpr struct {
 	pos  position
-	expr interface{}
-	run  func(*parser) (interface{}, error)
+	expr any
+	run  func(*parser) (any, error)
 }
 
 type recoveryExpr struct {
 	pos          position
-	expr         interface{}
-	recoverExpr  interface{}
+	expr         any
+	recoverExpr  any
 	failureLabel []string
 }
 
 type seqExpr struct {
 	pos   position
-	exprs []interface{}
+	exprs []any
 }
 
 type throwExpr struct {
@@ -1871,19 +2288,21 @@ type throwExpr struct {
 type labeledExpr struct {
 	pos   position
 	label string
-	expr  interface{}
+	expr  any
 }
 
 type expr struct {
 	pos  position
-	expr interface{}
+	expr any
 }
 
-type andExpr expr
-type notExpr expr
-type zeroOrOneExpr expr
-type zeroOrMoreExpr expr
-type oneOrMoreExpr expr
+type (
+	andExpr        expr
+	notExpr        expr
+	zeroOrOneExpr  expr
+	zeroOrMoreExpr expr
+	oneOrMoreExpr  expr
+)
 
 type ruleRefExpr struct {
 	pos  position
@@ -2024,7 +2443,7 @@ func (p *parser) setOptions(opts []Option) {
 }
 
 type resultTuple struct {
-	v   interface{}
+	v   any
 	b   bool
 	end savepoint
 }
@@ -2068,12 +2487,12 @@ type parser struct {
 	memoize bool
 	// memoization table for the packrat algorithm:
 	// map[offset in source] map[expression or rule] {value, match}
-	memo map[int]map[interface{}]resultTuple
+	memo map[int]map[any]resultTuple
 
 	// rules table, maps the rule identifier to the rule node
 	rules map[string]*rule
 	// variables stack, map of label to value
-	vstack []map[string]interface{}
+	vstack []map[string]any
 	// rule stack, allows identification of the current rule in errors
 	rstack []*rule
 
@@ -2093,7 +2512,7 @@ type parser struct {
 
 	choiceNoMatch string
 	// recovery expression stack, keeps track of the currently available recovery expression, these are traversed in reverse
-	recoveryStack []map[string]interface{}
+	recoveryStack []map[string]any
 }
 
 // push a variable set on the vstack.
@@ -2113,7 +2532,7 @@ func (p *parser) pushV() {
 		return
 	}
 
-	m = make(map[string]interface{})
+	m = make(map[string]any)
 	p.vstack[len(p.vstack)-1] = m
 }
 
@@ -2129,7 +2548,7 @@ func (p *parser) popV() {
 }
 
 // push a recovery expression with its labels to the recoveryStack
-func (p *parser) pushRecovery(labels []string, expr interface{}) {
+func (p *parser) pushRecovery(labels []string, expr any) {
 	if cap(p.recoveryStack) == len(p.recoveryStack) {
 		// create new empty slot in the stack
 		p.recoveryStack = append(p.recoveryStack, nil)
@@ -2138,7 +2557,7 @@ func (p *parser) pushRecovery(labels []string, expr interface{}) {
 		p.recoveryStack = p.recoveryStack[:len(p.recoveryStack)+1]
 	}
 
-	m := make(map[string]interface{}, len(labels))
+	m := make(map[string]any, len(labels))
 	for _, fl := range labels {
 		m[fl] = expr
 	}
@@ -2163,14 +2582,19 @@ func (p *parser) print(prefix, s string) string {
 	return s
 }
 
+func (p *parser) printIndent(mark string, s string) string {
+	return p.print(strings.Repeat(" ", p.depth)+mark, s)
+}
+
 func (p *parser) in(s string) string {
+	res := p.printIndent(">", s)
 	p.depth++
-	return p.print(strings.Repeat(" ", p.depth)+">", s)
+	return res
 }
 
 func (p *parser) out(s string) string {
 	p.depth--
-	return p.print(strings.Repeat(" ", p.depth)+"<", s)
+	return p.printIndent("<", s)
 }
 
 func (p *parser) addErr(err error) {
@@ -2258,11 +2682,11 @@ func (p *parser) restore(pt savepoint) {
 // copies of the state to allow the parser to properly restore the state in
 // the case of backtracking.
 type Cloner interface {
-	Clone() interface{}
+	Clone() any
 }
 
 var statePool = &sync.Pool{
-	New: func() interface{} { return make(storeDict) },
+	New: func() any { return make(storeDict) },
 }
 
 func (sd storeDict) Discard() {
@@ -2304,7 +2728,7 @@ func (p *parser) sliceFrom(start savepoint) []byte {
 	return p.data[start.position.offset:p.pt.position.offset]
 }
 
-func (p *parser) getMemoized(node interface{}) (resultTuple, bool) {
+func (p *parser) getMemoized(node any) (resultTuple, bool) {
 	if len(p.memo) == 0 {
 		return resultTuple{}, false
 	}
@@ -2316,13 +2740,13 @@ func (p *parser) getMemoized(node interface{}) (resultTuple, bool) {
 	return res, ok
 }
 
-func (p *parser) setMemoized(pt savepoint, node interface{}, tuple resultTuple) {
+func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
 	if p.memo == nil {
-		p.memo = make(map[int]map[interface{}]resultTuple)
+		p.memo = make(map[int]map[any]resultTuple)
 	}
 	m := p.memo[pt.offset]
 	if m == nil {
-		m = make(map[interface{}]resultTuple)
+		m = make(map[any]resultTuple)
 		p.memo[pt.offset] = m
 	}
 	m[node] = tuple
@@ -2335,7 +2759,7 @@ func (p *parser) buildRulesTable(g *grammar) {
 	}
 }
 
-func (p *parser) parse(g *grammar) (val interface{}, err error) {
+func (p *parser) parse(g *grammar) (val any, err error) {
 	if len(g.rules) == 0 {
 		p.addErr(errNoRule)
 		return nil, p.errs.err()
@@ -2371,7 +2795,7 @@ func (p *parser) parse(g *grammar) (val interface{}, err error) {
 	}
 
 	p.read() // advance to first rune
-	val, ok = p.parseRule(startRule)
+	val, ok = p.parseRuleWrap(startRule)
 	if !ok {
 		if len(*p.errs) == 0 {
 			// If parsing fails, but no errors have been recorded, the expected values
@@ -2412,36 +2836,52 @@ func listJoin(list []string, sep string, lastSep string) string {
 	}
 }
 
-func (p *parser) parseRule(rule *rule) (interface{}, bool) {
+func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
+	res, ok := p.getMemoized(rule)
+	if ok {
+		p.restore(res.end)
+		return res.v, res.b
+	}
+
+	startMark := p.pt
+	val, ok := p.parseRule(rule)
+	p.setMemoized(startMark, rule, resultTuple{val, ok, p.pt})
+
+	return val, ok
+}
+
+func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseRule " + rule.name))
 	}
+	var (
+		val       any
+		ok        bool
+		startMark = p.pt
+	)
 
 	if p.memoize {
-		res, ok := p.getMemoized(rule)
-		if ok {
-			p.restore(res.end)
-			return res.v, res.b
-		}
+		val, ok = p.parseRuleMemoize(rule)
+	} else {
+		val, ok = p.parseRule(rule)
 	}
 
-	start := p.pt
+	if ok && p.debug {
+		p.printIndent("MATCH", string(p.sliceFrom(startMark)))
+	}
+	return val, ok
+}
+
+func (p *parser) parseRule(rule *rule) (any, bool) {
 	p.rstack = append(p.rstack, rule)
 	p.pushV()
-	val, ok := p.parseExpr(rule.expr)
+	val, ok := p.parseExprWrap(rule.expr)
 	p.popV()
 	p.rstack = p.rstack[:len(p.rstack)-1]
-	if ok && p.debug {
-		p.print(strings.Repeat(" ", p.depth)+"MATCH", string(p.sliceFrom(start)))
-	}
-
-	if p.memoize {
-		p.setMemoized(start, rule, resultTuple{val, ok, p.pt})
-	}
 	return val, ok
 }
 
-func (p *parser) parseExpr(expr interface{}) (interface{}, bool) {
+func (p *parser) parseExprWrap(expr any) (any, bool) {
 	var pt savepoint
 
 	if p.memoize {
@@ -2453,12 +2893,21 @@ func (p *parser) parseExpr(expr interface{}) (interface{}, bool) {
 		pt = p.pt
 	}
 
+	val, ok := p.parseExpr(expr)
+
+	if p.memoize {
+		p.setMemoized(pt, expr, resultTuple{val, ok, p.pt})
+	}
+	return val, ok
+}
+
+func (p *parser) parseExpr(expr any) (any, bool) {
 	p.ExprCnt++
 	if p.ExprCnt > p.maxExprCnt {
 		panic(errMaxExprCnt)
 	}
 
-	var val interface{}
+	var val any
 	var ok bool
 	switch expr := expr.(type) {
 	case *actionExpr:
@@ -2500,19 +2949,16 @@ func (p *parser) parseExpr(expr interface{}) (interface{}, bool) {
 	default:
 		panic(fmt.Sprintf("unknown expression type %T", expr))
 	}
-	if p.memoize {
-		p.setMemoized(pt, expr, resultTuple{val, ok, p.pt})
-	}
 	return val, ok
 }
 
-func (p *parser) parseActionExpr(act *actionExpr) (interface{}, bool) {
+func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseActionExpr"))
 	}
 
 	start := p.pt
-	val, ok := p.parseExpr(act.expr)
+	val, ok := p.parseExprWrap(act.expr)
 	if ok {
 		p.cur.pos = start.position
 		p.cur.text = p.sliceFrom(start)
@@ -2526,12 +2972,12 @@ func (p *parser) parseActionExpr(act *actionExpr) (interface{}, bool) {
 		val = actVal
 	}
 	if ok && p.debug {
-		p.print(strings.Repeat(" ", p.depth)+"MATCH", string(p.sliceFrom(start)))
+		p.printIndent("MATCH", string(p.sliceFrom(start)))
 	}
 	return val, ok
 }
 
-func (p *parser) parseAndCodeExpr(and *andCodeExpr) (interface{}, bool) {
+func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseAndCodeExpr"))
 	}
@@ -2547,7 +2993,7 @@ func (p *parser) parseAndCodeExpr(and *andCodeExpr) (interface{}, bool) {
 	return nil, ok
 }
 
-func (p *parser) parseAndExpr(and *andExpr) (interface{}, bool) {
+func (p *parser) parseAndExpr(and *andExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseAndExpr"))
 	}
@@ -2555,7 +3001,7 @@ func (p *parser) parseAndExpr(and *andExpr) (interface{}, bool) {
 	pt := p.pt
 	state := p.cloneState()
 	p.pushV()
-	_, ok := p.parseExpr(and.expr)
+	_, ok := p.parseExprWrap(and.expr)
 	p.popV()
 	p.restoreState(state)
 	p.restore(pt)
@@ -2563,7 +3009,7 @@ func (p *parser) parseAndExpr(and *andExpr) (interface{}, bool) {
 	return nil, ok
 }
 
-func (p *parser) parseAnyMatcher(any *anyMatcher) (interface{}, bool) {
+func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseAnyMatcher"))
 	}
@@ -2579,7 +3025,7 @@ func (p *parser) parseAnyMatcher(any *anyMatcher) (interface{}, bool) {
 	return p.sliceFrom(start), true
 }
 
-func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (interface{}, bool) {
+func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseCharClassMatcher"))
 	}
@@ -2660,7 +3106,7 @@ func (p *parser) incChoiceAltCnt(ch *choiceExpr, altI int) {
 	m[alt]++
 }
 
-func (p *parser) parseChoiceExpr(ch *choiceExpr) (interface{}, bool) {
+func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseChoiceExpr"))
 	}
@@ -2672,7 +3118,7 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (interface{}, bool) {
 		state := p.cloneState()
 
 		p.pushV()
-		val, ok := p.parseExpr(alt)
+		val, ok := p.parseExprWrap(alt)
 		p.popV()
 		if ok {
 			p.incChoiceAltCnt(ch, altI)
@@ -2684,13 +3130,13 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (interface{}, bool) {
 	return nil, false
 }
 
-func (p *parser) parseLabeledExpr(lab *labeledExpr) (interface{}, bool) {
+func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseLabeledExpr"))
 	}
 
 	p.pushV()
-	val, ok := p.parseExpr(lab.expr)
+	val, ok := p.parseExprWrap(lab.expr)
 	p.popV()
 	if ok && lab.label != "" {
 		m := p.vstack[len(p.vstack)-1]
@@ -2699,7 +3145,7 @@ func (p *parser) parseLabeledExpr(lab *labeledExpr) (interface{}, bool) {
 	return val, ok
 }
 
-func (p *parser) parseLitMatcher(lit *litMatcher) (interface{}, bool) {
+func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseLitMatcher"))
 	}
@@ -2721,7 +3167,7 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (interface{}, bool) {
 	return p.sliceFrom(start), true
 }
 
-func (p *parser) parseNotCodeExpr(not *notCodeExpr) (interface{}, bool) {
+func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseNotCodeExpr"))
 	}
@@ -2737,7 +3183,7 @@ func (p *parser) parseNotCodeExpr(not *notCodeExpr) (interface{}, bool) {
 	return nil, !ok
 }
 
-func (p *parser) parseNotExpr(not *notExpr) (interface{}, bool) {
+func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseNotExpr"))
 	}
@@ -2746,7 +3192,7 @@ func (p *parser) parseNotExpr(not *notExpr) (interface{}, bool) {
 	state := p.cloneState()
 	p.pushV()
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
-	_, ok := p.parseExpr(not.expr)
+	_, ok := p.parseExprWrap(not.expr)
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	p.popV()
 	p.restoreState(state)
@@ -2755,16 +3201,16 @@ func (p *parser) parseNotExpr(not *notExpr) (interface{}, bool) {
 	return nil, !ok
 }
 
-func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (interface{}, bool) {
+func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseOneOrMoreExpr"))
 	}
 
-	var vals []interface{}
+	var vals []any
 
 	for {
 		p.pushV()
-		val, ok := p.parseExpr(expr.expr)
+		val, ok := p.parseExprWrap(expr.expr)
 		p.popV()
 		if !ok {
 			if len(vals) == 0 {
@@ -2777,19 +3223,19 @@ func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (interface{}, bool) {
 	}
 }
 
-func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (interface{}, bool) {
+func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseRecoveryExpr (" + strings.Join(recover.failureLabel, ",") + ")"))
 	}
 
 	p.pushRecovery(recover.failureLabel, recover.recoverExpr)
-	val, ok := p.parseExpr(recover.expr)
+	val, ok := p.parseExprWrap(recover.expr)
 	p.popRecovery()
 
 	return val, ok
 }
 
-func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (interface{}, bool) {
+func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseRuleRefExpr " + ref.name))
 	}
@@ -2803,20 +3249,20 @@ func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (interface{}, bool) {
 		p.addErr(fmt.Errorf("undefined rule: %s", ref.name))
 		return nil, false
 	}
-	return p.parseRule(rule)
+	return p.parseRuleWrap(rule)
 }
 
-func (p *parser) parseSeqExpr(seq *seqExpr) (interface{}, bool) {
+func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseSeqExpr"))
 	}
 
-	vals := make([]interface{}, 0, len(seq.exprs))
+	vals := make([]any, 0, len(seq.exprs))
 
 	pt := p.pt
 	state := p.cloneState()
 	for _, expr := range seq.exprs {
-		val, ok := p.parseExpr(expr)
+		val, ok := p.parseExprWrap(expr)
 		if !ok {
 			p.restoreState(state)
 			p.restore(pt)
@@ -2827,7 +3273,7 @@ func (p *parser) parseSeqExpr(seq *seqExpr) (interface{}, bool) {
 	return vals, true
 }
 
-func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (interface{}, bool) {
+func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseStateCodeExpr"))
 	}
@@ -2839,14 +3285,14 @@ func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (interface{}, bool) {
 	return nil, true
 }
 
-func (p *parser) parseThrowExpr(expr *throwExpr) (interface{}, bool) {
+func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseThrowExpr"))
 	}
 
 	for i := len(p.recoveryStack) - 1; i >= 0; i-- {
 		if recoverExpr, ok := p.recoveryStack[i][expr.label]; ok {
-			if val, ok := p.parseExpr(recoverExpr); ok {
+			if val, ok := p.parseExprWrap(recoverExpr); ok {
 				return val, ok
 			}
 		}
@@ -2855,16 +3301,16 @@ func (p *parser) parseThrowExpr(expr *throwExpr) (interface{}, bool) {
 	return nil, false
 }
 
-func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (interface{}, bool) {
+func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseZeroOrMoreExpr"))
 	}
 
-	var vals []interface{}
+	var vals []any
 
 	for {
 		p.pushV()
-		val, ok := p.parseExpr(expr.expr)
+		val, ok := p.parseExprWrap(expr.expr)
 		p.popV()
 		if !ok {
 			return vals, true
@@ -2873,13 +3319,13 @@ func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (interface{}, bool) {
 	}
 }
 
-func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (interface{}, bool) {
+func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (any, bool) {
 	if p.debug {
 		defer p.out(p.in("parseZeroOrOneExpr"))
 	}
 
 	p.pushV()
-	val, _ := p.parseExpr(expr.expr)
+	val, _ := p.parseExprWrap(expr.expr)
 	p.popV()
 	// whether it matched or not, consider it a match
 	return val, true
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Query holding the parsed query
@@ -26,6 +27,7 @@ const (
 type Node struct {
 	Operator   NodeOperator
 	Conditions []any // could be a node or a condition
+	Invert     bool  // negating the whole node
 }
 
 // FieldOperator as type
@@ -33,15 +35,25 @@ type FieldOperator string
 
 // defining field operators
 const (
-	NO FieldOperator = ""   // equal
-	EQ FieldOperator = "="  // equals
-	LT FieldOperator = "<"  // lesser than
-	GT FieldOperator = ">"  // greater than
-	LE FieldOperator = "<=" // less or equal
-	GE FieldOperator = ">=" // greater or equal
-	NE FieldOperator = "!=" // not equal
+	NO FieldOperator = ""       // equal
+	EQ FieldOperator = "="      // equals
+	LT FieldOperator = "<"      // lesser than
+	GT FieldOperator = ">"      // greater than
+	LE FieldOperator = "<="     // less or equal
+	GE FieldOperator = ">="     // greater or equal
+	NE FieldOperator = "!="     // not equal
+	CI FieldOperator = "~"      // equals case insensitive, wildcards are allowed
+	IN FieldOperator = "IN"     // one of the values of a list
+	BT FieldOperator = "[]"     // in the range, from and to inclusive
+	EX FieldOperator = "EXISTS" // the field exists
 )
 
+// Range the value of a range condition like [50~200], from and to inclusive
+type Range struct {
+	From any
+	To   any
+}
+
 // Condition as struct
 type Condition struct {
 	Field    string
@@ -64,7 +76,11 @@ func (q *Query) String() string {
 func (n *Node) String() string {
 	var b strings.Builder
 	cl := len(n.Conditions)
-	if cl > 1 {
+	if n.Invert {
+		_, _ = b.WriteString("!")
+	}
+	br := cl > 1 || n.Invert
+	if br {
 		_, _ = b.WriteString("(")
 	}
 	f := true
@@ -87,16 +103,22 @@ func (n *Node) String() string {
 		}
 		f = false
 	}
-	if cl > 1 {
+	if br {
 		_, _ = b.WriteString(")")
 	}
 	return b.String()
 }
 
 func (c *Condition) String() string {
-	op := string(c.Operator)
-	v := c.VtoS()
-	lc := fmt.Sprintf("%s:%s%s", c.Field, op, v)
+	var lc string
+	switch c.Operator {
+	case EX:
+		lc = fmt.Sprintf("EXISTS(%s)", c.Field)
+	case BT:
+		lc = fmt.Sprintf("%s:%s", c.Field, c.VtoS())
+	default:
+		lc = fmt.Sprintf("%s:%s%s", c.Field, c.Operator, c.VtoS())
+	}
 	if c.Invert {
 		lc = fmt.Sprintf(`!(%s)`, lc)
 	}
@@ -105,7 +127,12 @@ func (c *Condition) String() string {
 
 // VtoS return a formatted string from the different values
 func (c *Condition) VtoS() string {
-	switch v := c.Value.(type) {
+	return ValueToString(c.Value)
+}
+
+// ValueToString return a formatted string of a single value, a list or a range
+func ValueToString(value any) string {
+	switch v := value.(type) {
 	case string:
 		if !strings.HasPrefix(v, `"`) {
 			v = `"` + v
@@ -120,10 +147,31 @@ func (c *Condition) VtoS() string {
 		return strconv.FormatFloat(float64(v), 'f', 8, 32)
 	case float64:
 		return strconv.FormatFloat(float64(v), 'f', 8, 64)
+	case DateMath:
+		return v.String()
+	case []any:
+		vs := make([]string, len(v))
+		for i, x := range v {
+			vs[i] = ValueToString(x)
+		}
+		return "(" + strings.Join(vs, ", ") + ")"
+	case Range:
+		return fmt.Sprintf("[%s~%s]", rtoS(v.From), rtoS(v.To))
 	}
 	return ""
 }
 
+// rtoS formatting a value of a range, without quotes
+func rtoS(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return strings.Trim(v, `"`)
+	}
+	return ValueToString(value)
+}
+
 // HasWildcard checking if a condition contains a wildcard
 func (c *Condition) HasWildcard() bool {
 	switch v := c.Value.(type) {
@@ -133,3 +181,55 @@ func (c *Condition) HasWildcard() bool {
 		return false
 	}
 }
+
+// Resolved returning a copy of the query, all date math values are resolved to unix milliseconds relative to now
+func (q *Query) Resolved(now time.Time) Query {
+	return Query{
+		Sorting:   q.Sorting,
+		Condition: resolve(q.Condition, now),
+	}
+}
+
+// resolve resolving the date math values of a node/condition
+func resolve(x any, now time.Time) any {
+	switch v := x.(type) {
+	case Condition:
+		v.Value = resolveValue(v.Value, now)
+		return v
+	case *Condition:
+		c := *v
+		c.Value = resolveValue(c.Value, now)
+		return &c
+	case Node:
+		return resolveNode(v, now)
+	case *Node:
+		n := resolveNode(*v, now)
+		return &n
+	}
+	return x
+}
+
+func resolveNode(n Node, now time.Time) Node {
+	cs := make([]any, len(n.Conditions))
+	for i, c := range n.Conditions {
+		cs[i] = resolve(c, now)
+	}
+	n.Conditions = cs
+	return n
+}
+
+func resolveValue(value any, now time.Time) any {
+	switch v := value.(type) {
+	case DateMath:
+		return v.Millis(now)
+	case Range:
+		return Range{From: resolveValue(v.From, now), To: resolveValue(v.To, now)}
+	case []any:
+		vs := make([]any, len(v))
+		for i, x := range v {
+			vs[i] = resolveValue(x, now)
+		}
+		return vs
+	}
+	return value
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -231,8 +232,14 @@ func TestQParse(t *testing.T) {
 		`field1:"Willie" AND field2:<=100`,
 		`field1:"Willie" OR field2:<=100`,
 		`field1:"Willie" AND field2:<=100 AND field3:"muck"`,
-		// TODO can't mix AND and OR 		`field1:"Willie" OR field2:<=100 OR field3:"muck"`,
-		// TODO can't mix AND and OR		`(field1:"Willie" AND field2:<=100) OR field3:"muck"`,
+		`field1:"Willie" OR field2:<=100 OR field3:"muck"`,
+		`(field1:"Willie" AND field2:<=100) OR field3:"muck"`,
+		`field1:IN(1, 2, "Willie")`,
+		`field1:[50~200]`,
+		`EXISTS(field1)`,
+		`creationDate:>now-30d`,
+		`field1:~"willie"`,
+		`NOT (field1:"Willie" OR field2:<=100)`,
 	}
 	for _, s := range ss {
 		N.Reset()
@@ -265,3 +272,180 @@ func TestSingleQParse(t *testing.T) {
 	t.Logf("%s -> %s", s, q.String())
 	ast.Nil(err)
 }
+
+func parse(ast *assert.Assertions, s string) any {
+	N.Reset()
+	res, err := Parse("query", []byte(s))
+	ast.Nil(err, s)
+	q, ok := res.(Query)
+	ast.True(ok, s)
+	return q.Condition
+}
+
+func qString(x any) string {
+	switch v := x.(type) {
+	case *Condition:
+		return v.String()
+	case *Node:
+		return v.String()
+	}
+	return ""
+}
+
+func TestQRoundTrip(t *testing.T) {
+	ast := assert.New(t)
+	tests := []struct {
+		q string
+		r string
+	}{
+		{q: `field1:Willie`, r: `field1:"Willie"`},
+		{q: `field1:IN(1, 2.5, "Willie Max", abc)`, r: `field1:IN(1.00000000, 2.50000000, "Willie Max", "abc")`},
+		{q: `field1:IN( "a" ,b )`, r: `field1:IN("a", "b")`},
+		{q: `field1:INDIA`, r: `field1:"INDIA"`},
+		{q: `cash:[50~200]`, r: `cash:[50~200]`},
+		{q: `cash:[ -1.5 ~ 2e3 ]`, r: `cash:[-1.5~2000]`},
+		{q: `c:[2017-10-29T00:00:00Z~2017-10-30T00:00:00Z]`, r: `c:[2017-10-29T00:00:00Z~2017-10-30T00:00:00Z]`},
+		{q: `!c:[now-7d~now]`, r: `!(c:[now-7d~now])`},
+		{q: `EXISTS(field1)`, r: `EXISTS(field1)`},
+		{q: `EXISTS( field_1 )`, r: `EXISTS(field_1)`},
+		{q: `NOT EXISTS(field1)`, r: `!(EXISTS(field1))`},
+		{q: `creationDate:>now-30d`, r: `creationDate:>now-30d`},
+		{q: `creationDate:<=now+1h`, r: `creationDate:<=now+1h`},
+		{q: `creationDate:now`, r: `creationDate:now`},
+		{q: `field1:nowhere`, r: `field1:"nowhere"`},
+		{q: `field1:~"Willie"`, r: `field1:~"Willie"`},
+		{q: `field1:~wil*`, r: `field1:~"wil*"`},
+		{q: `NOT (field1:1 OR field2:2)`, r: `!(field1:1.00000000 OR field2:2.00000000)`},
+		{q: `!(field1:1)`, r: `!(field1:1.00000000)`},
+		{q: `(field1:1)`, r: `field1:1.00000000`},
+		{q: `(field1:1 AND field2:2) OR field3:3`, r: `((field1:1.00000000 AND field2:2.00000000) OR field3:3.00000000)`},
+		{q: `field1:1 AND (field2:2 OR field3:3)`, r: `(field1:1.00000000 AND (field2:2.00000000 OR field3:3.00000000))`},
+		{q: `((field1:1 OR field2:2) AND field3:3)`, r: `((field1:1.00000000 OR field2:2.00000000) AND field3:3.00000000)`},
+		{q: `NOT (field1:1 OR NOT (field2:2 AND EXISTS(field3)))`, r: `!(field1:1.00000000 OR !(field2:2.00000000 AND EXISTS(field3)))`},
+	}
+	for _, tc := range tests {
+		s := qString(parse(ast, tc.q))
+		ast.Equal(tc.r, s, tc.q)
+		// the string representation parses to the same query
+		ast.Equal(s, qString(parse(ast, s)), tc.q)
+	}
+}
+
+func TestQParseErrors(t *testing.T) {
+	ast := assert.New(t)
+	ss := []string{
+		`field1:1 AND field2:2 OR field3:3`,
+		`field1:IN()`,
+		`field1:IN(1, 2`,
+		`field1:[50~]`,
+		`EXISTS()`,
+		`(field1:1`,
+		`creationDate:>now-30x`,
+	}
+	for _, s := range ss {
+		N.Reset()
+		_, err := Parse("query", []byte(s))
+		ast.NotNil(err, s)
+	}
+}
+
+func TestQExtensions(t *testing.T) {
+	ast := assert.New(t)
+
+	c, ok := parse(ast, `field1:IN(1, "Willie")`).(*Condition)
+	ast.True(ok)
+	ast.Equal(IN, c.Operator)
+	ast.Equal([]any{1.0, `"Willie"`}, c.Value)
+
+	c, ok = parse(ast, `field1:[50~200]`).(*Condition)
+	ast.True(ok)
+	ast.Equal(BT, c.Operator)
+	ast.Equal(Range{From: 50.0, To: 200.0}, c.Value)
+
+	c, ok = parse(ast, `NOT EXISTS(field1)`).(*Condition)
+	ast.True(ok)
+	ast.Equal(EX, c.Operator)
+	ast.Equal("field1", c.Field)
+	ast.True(c.Invert)
+
+	c, ok = parse(ast, `field1:~wil*`).(*Condition)
+	ast.True(ok)
+	ast.Equal(CI, c.Operator)
+	ast.True(c.HasWildcard())
+
+	c, ok = parse(ast, `creationDate:>=now-2w`).(*Condition)
+	ast.True(ok)
+	ast.Equal(GE, c.Operator)
+	ast.Equal(DateMath{Amount: -2, Unit: "w"}, c.Value)
+
+	n, ok := parse(ast, `NOT (field1:1 OR field2:2) AND field3:3`).(*Node)
+	ast.True(ok)
+	ast.Equal(ANDOP, n.Operator)
+	ast.False(n.Invert)
+	ast.Equal(2, len(n.Conditions))
+	g, ok := n.Conditions[0].(*Node)
+	ast.True(ok)
+	ast.True(g.Invert)
+	ast.Equal(OROP, g.Operator)
+	ast.Equal(2, len(g.Conditions))
+	c, ok = n.Conditions[1].(*Condition)
+	ast.True(ok)
+	ast.Equal("field3", c.Field)
+}
+
+func TestQResolved(t *testing.T) {
+	ast := assert.New(t)
+	now := time.Date(2023, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	q := Query{
+		Condition: &Node{
+			Operator: ANDOP,
+			Invert:   true,
+			Conditions: []any{
+				&Condition{Field: "creationDate", Operator: GT, Value: DateMath{Amount: -1, Unit: "d"}},
+				Condition{Field: "lastAccess", Operator: BT, Value: Range{From: DateMath{Amount: -1, Unit: "h"}, To: DateMath{}}},
+				Condition{Field: "retention", Operator: IN, Value: []any{DateMath{Amount: 1, Unit: "M"}, 1.0}},
+			},
+		},
+	}
+	r := q.Resolved(now)
+	n, ok := r.Condition.(*Node)
+	ast.True(ok)
+	ast.True(n.Invert)
+	ast.Equal(now.AddDate(0, 0, -1).UnixMilli(), n.Conditions[0].(*Condition).Value)
+	ast.Equal(Range{From: now.Add(-time.Hour).UnixMilli(), To: now.UnixMilli()}, n.Conditions[1].(Condition).Value)
+	ast.Equal([]any{now.AddDate(0, 1, 0).UnixMilli(), 1.0}, n.Conditions[2].(Condition).Value)
+
+	// the original query is untouched
+	ast.Equal(DateMath{Amount: -1, Unit: "d"}, q.Condition.(*Node).Conditions[0].(*Condition).Value)
+}
+
+func TestDateMath(t *testing.T) {
+	ast := assert.New(t)
+	now := time.Date(2023, 1, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		s string
+		t time.Time
+	}{
+		{s: "now", t: now},
+		{s: "now-30s", t: now.Add(-30 * time.Second)},
+		{s: "now+5m", t: now.Add(5 * time.Minute)},
+		{s: "now-12h", t: now.Add(-12 * time.Hour)},
+		{s: "now-30d", t: now.AddDate(0, 0, -30)},
+		{s: "now-2w", t: now.AddDate(0, 0, -14)},
+		{s: "now+1M", t: now.AddDate(0, 1, 0)},
+		{s: "now-1y", t: now.AddDate(-1, 0, 0)},
+	}
+	for _, tc := range tests {
+		d, err := ParseDateMath(tc.s)
+		ast.Nil(err, tc.s)
+		ast.Equal(tc.t, d.Time(now), tc.s)
+		ast.Equal(tc.t.UnixMilli(), d.Millis(now), tc.s)
+		ast.Equal(tc.s, d.String())
+	}
+
+	for _, s := range []string{"", "then", "now-", "now-30", "now30d", "now-30x", "now-1.5d"} {
+		_, err := ParseDateMath(s)
+		ast.NotNil(err, s)
+	}
+}
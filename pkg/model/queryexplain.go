@@ -0,0 +1,17 @@
+package model
+
+import "github.com/willie68/GoBlobStore/pkg/model/query"
+
+// QueryExplain the result of explaining or validating a search query
+type QueryExplain struct {
+	Query       string             `json:"query"`
+	Valid       bool               `json:"valid"`
+	Native      bool               `json:"native"`               // the query is a native query of the index, starting with #
+	Normalized  string             `json:"normalized,omitempty"` // the parsed query as string
+	AST         any                `json:"ast,omitempty"`        // the parsed nodes and conditions
+	Errors      []query.ParseError `json:"errors,omitempty"`
+	Suggestions []string           `json:"suggestions,omitempty"`
+	Index       string             `json:"index,omitempty"`      // the index engine of the tenant
+	IndexQuery  any                `json:"indexQuery,omitempty"` // the query, the index engine would run
+	IndexError  string             `json:"indexError,omitempty"`
+}
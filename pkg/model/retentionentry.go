@@ -1,5 +1,11 @@
 package model
 
+// defining the different retention modes
+const (
+	RetentionModeCreation   = "creation"   // the retention starts at the creation date or the retention base
+	RetentionModeLastAccess = "lastaccess" // the retention starts at the last access of the blob (sliding expiration)
+)
+
 // RetentionEntry antry for the retention of a blob
 type RetentionEntry struct {
 	Filename      string `yaml:"filename" json:"filename"`
@@ -8,14 +14,25 @@ type RetentionEntry struct {
 	CreationDate  int64  `yaml:"creationDate" json:"creationDate"`
 	Retention     int64  `yaml:"retention" json:"retention"`
 	RetentionBase int64  `yaml:"retentionBase" json:"retentionBase"`
+	Mode          string `yaml:"retentionMode,omitempty" json:"retentionMode,omitempty"`
+	LastAccess    int64  `yaml:"lastAccess,omitempty" json:"lastAccess,omitempty"`
 }
 
 // GetRetentionTimestampMS getting the time stamp in ms
 func (r *RetentionEntry) GetRetentionTimestampMS() int64 {
+	base := r.CreationDate
 	if r.RetentionBase > 0 {
-		return r.RetentionBase + r.Retention*60*1000
+		base = r.RetentionBase
+	}
+	if r.IsSliding() && r.LastAccess > base {
+		base = r.LastAccess
 	}
-	return r.CreationDate + r.Retention*60*1000
+	return base + r.Retention*60*1000
+}
+
+// IsSliding the retention is calculated from the last access of the blob
+func (r *RetentionEntry) IsSliding() bool {
+	return r.Mode == RetentionModeLastAccess
 }
 
 // RetentionEntryFromBlobDescription building a retention entry from a blobdescription
@@ -27,5 +44,7 @@ func RetentionEntryFromBlobDescription(b BlobDescription) RetentionEntry {
 		Retention:     b.Retention,
 		RetentionBase: 0,
 		TenantID:      b.TenantID,
+		Mode:          b.RetentionMode,
+		LastAccess:    b.LastAccess,
 	}
 }
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionTimestamp(t *testing.T) {
+	ast := assert.New(t)
+
+	r := RetentionEntry{
+		CreationDate: 1000,
+		Retention:    1,
+		LastAccess:   5000,
+	}
+	ast.Equal(int64(61000), r.GetRetentionTimestampMS())
+
+	r.RetentionBase = 2000
+	ast.Equal(int64(62000), r.GetRetentionTimestampMS())
+
+	r.Mode = RetentionModeLastAccess
+	ast.True(r.IsSliding())
+	ast.Equal(int64(65000), r.GetRetentionTimestampMS())
+
+	r.RetentionBase = 9000
+	ast.Equal(int64(69000), r.GetRetentionTimestampMS())
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// ScrubberStatus the status of the background scrubber, it is persisted to resume the cycle after a restart
+type ScrubberStatus struct {
+	Running       bool      `json:"running"` // the scrubber is checking blobs, false outside the time windows
+	Cycle         int       `json:"cycle"`   // the number of the actual or last cycle
+	CycleStarted  time.Time `json:"cycleStarted"`
+	CycleFinished time.Time `json:"cycleFinished"` // the end of the last complete cycle
+	NextCycle     time.Time `json:"nextCycle"`
+	Tenant        string    `json:"tenant"`    // the tenant in work
+	BlobID        string    `json:"blobID"`    // the last checked blob of the tenant
+	Checked       int       `json:"checked"`   // blobs checked in the actual cycle
+	Unhealthy     int       `json:"unhealthy"` // unhealthy blobs found in the actual cycle
+	Repaired      int       `json:"repaired"`  // blobs repaired in the actual cycle
+	Failed        int       `json:"failed"`    // blobs, which couldn't be checked in the actual cycle
+	Bytes         int64     `json:"bytes"`     // bytes read in the actual cycle
+}
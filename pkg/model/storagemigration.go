@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// states of a storage migration
+const (
+	MigrationStateCopying   = "copying"   // blobs are copied to the target storage
+	MigrationStateSwitching = "switching" // all blobs are copied and verified, the tenant is switched to the target storage
+	MigrationStateFinished  = "finished"  // the tenant is working on the target storage
+	MigrationStateFailed    = "failed"    // the migration stopped with errors, the tenant is still working on both storages
+	MigrationStateCancelled = "cancelled" // the migration was cancelled, the tenant is working on the source storage
+)
+
+// StorageMigration the status of the migration of the primary storage of a tenant
+type StorageMigration struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant"`
+	Source    string    `json:"source"` // storage class of the source storage
+	Target    string    `json:"target"` // storage class of the target storage
+	State     string    `json:"state"`
+	Running   bool      `json:"running"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Pass      int       `json:"pass"`    // the actual pass, the last pass verifies, that all blobs are present in the target
+	Total     int       `json:"total"`   // blobs of the actual pass
+	Skipped   int       `json:"skipped"` // blobs of the actual pass, which are already present in the target
+	Failed    int       `json:"failed"`  // blobs of the actual pass, which couldn't be copied
+	Copied    int       `json:"copied"`  // blobs copied over all passes
+	Bytes     int64     `json:"bytes"`   // bytes copied over all passes
+	LastError string    `json:"lastError,omitempty"`
+}
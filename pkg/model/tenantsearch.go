@@ -0,0 +1,19 @@
+package model
+
+// TenantSearchHit a blob found by a search over all tenants
+type TenantSearchHit struct {
+	Tenant string `json:"tenant"`
+	BlobID string `json:"blobID"`
+}
+
+// TenantSearch the result page of a search over all tenants
+type TenantSearch struct {
+	Query   string            `json:"query"`
+	Offset  int               `json:"offset"`
+	Limit   int               `json:"limit"`
+	Shared  bool              `json:"shared"`  // all tenants were searched with one shared query of the index
+	Tenants int               `json:"tenants"` // count of searched tenants
+	Hits    []TenantSearchHit `json:"hits"`
+	More    bool              `json:"more"`             // there are more hits after this page
+	Errors  map[string]string `json:"errors,omitempty"` // the errors of the tenants, which couldn't be searched
+}